@@ -12,6 +12,7 @@ import (
 	"path"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -56,9 +57,10 @@ type Configuration struct {
 		RPCCert              string   `default:""` //File containing the certificate file
 		RPCKey               string   //File containing the certificate key
 		RPCMaxClients        int      //Max number of RPC clients for standard connections
-		RPCMaxWebsockets     int      //Max number of RPC websocket connections
+		RPCMaxWebsockets     int      `default:"25"` //Max number of RPC websocket connections
 		RPCMaxConcurrentReqs int      //Max number of concurrent RPC requests that may be processed concurrently
 		RPCQuirks            bool     //Mirror some JSON-RPC quirks of Bitcoin Core -- NOTE: Discouraged unless interoperability issues need to be worked around
+		Rest                 bool     `default:"false"` //Accept public REST requests on the RPC listeners
 	}
 	Log struct {
 		Level    string   //description:"Define level of log,include trace, debug, info, warn, error"
@@ -74,6 +76,7 @@ type Configuration struct {
 		MaxPoolSize          int64  `default:"300000000"` // Default for MaxPoolSize, maximum megabytes of mempool memory usage
 		MaxPoolExpiry        int    `default:"336"`       // Default for -mempoolexpiry, expiration time for mempool transactions in hours
 		CheckFrequency       uint64 `default:"4294967296"`
+		PersistMempool       bool   `default:"true"` // Default for -persistmempool, save the mempool on shutdown and load on restart
 	}
 	P2PNet struct {
 		ListenAddrs         []string `validate:"require" default:"1234"`
@@ -114,15 +117,20 @@ type Configuration struct {
 		IsBareMultiSigStd   bool `default:"true"`
 		//use promiscuousMempoolFlags to make more or less check of script, the type of value is uint
 		PromiscuousMempoolFlags string
-		Par                     int `default:"32"`
+		Par                     int  `default:"32"`
+		MaxSigCacheEntries      uint `default:"100000"` // Max number of verified signatures kept in the signature cache
+		MaxScriptCacheEntries   uint `default:"100000"` // Max number of verified (txid, flags) kept in the script execution cache
 	}
 	TxOut struct {
 		DustRelayFee int64 `default:"83"`
 	}
 	Chain struct {
 		AssumeValid         string
-		UtxoHashStartHeight int32 `default:"-1"`
-		UtxoHashEndHeight   int32 `default:"-1"`
+		UtxoHashStartHeight int32            `default:"-1"`
+		UtxoHashEndHeight   int32            `default:"-1"`
+		TxIndex             bool             // Maintain a full transaction index, used by the getrawtransaction rpc call
+		Prune               uint64           // Target size in MiB of the block and undo files, 0 disables pruning and 1 allows only manual pruning
+		UpgradeActivations  map[string]int64 // Activation heights or median times past of upgrades by name, regtest only
 	}
 	Mining struct {
 		BlockMinTxFee int64  // default DefaultBlockMinTxFee
@@ -140,6 +148,17 @@ type Configuration struct {
 		Enable              bool `default:"false"`
 		Broadcast           bool `default:"false"`
 		SpendZeroConfChange bool `default:"true"`
+		KeyPool             int  `default:"1000"` // Number of pre-generated keys of each keypool
+	}
+	ZMQ struct {
+		PubHashBlock    string // Enable publish hash block in <address>, e.g. tcp://127.0.0.1:28332
+		PubHashTx       string // Enable publish hash transaction in <address>
+		PubRawBlock     string // Enable publish raw block in <address>
+		PubRawTx        string // Enable publish raw transaction in <address>
+		PubHashBlockHWM int    `default:"1000"` // Outbound message high water mark of the hash block publisher
+		PubHashTxHWM    int    `default:"1000"` // Outbound message high water mark of the hash transaction publisher
+		PubRawBlockHWM  int    `default:"1000"` // Outbound message high water mark of the raw block publisher
+		PubRawTxHWM     int    `default:"1000"` // Outbound message high water mark of the raw transaction publisher
 	}
 }
 
@@ -292,19 +311,102 @@ func InitConfig(args []string) *Configuration {
 	if opts.SpendZeroConfChange == 0 {
 		config.Wallet.SpendZeroConfChange = false
 	}
+	if opts.KeyPool > 0 {
+		config.Wallet.KeyPool = opts.KeyPool
+	}
+	if opts.PersistMempool == 0 {
+		config.Mempool.PersistMempool = false
+	}
 	if opts.BanScore > 0 {
 		config.P2PNet.BanThreshold = opts.BanScore
 	}
 	if opts.MaxTimeAdjustment > 0 {
 		config.P2PNet.MaxTimeAdjustment = opts.MaxTimeAdjustment
 	}
+	if err := initPrune(config, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return nil
+	}
 	if len(opts.AssumeValid) > 0 {
 		config.Chain.AssumeValid = opts.AssumeValid
 	}
+	if opts.Rest {
+		config.RPC.Rest = true
+	}
+	if err := initUpgradeActivations(config, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return nil
+	}
+	initZMQ(config, opts)
 
 	return config
 }
 
+// initPrune applies the txindex and prune options, which cannot be enabled
+// together since a pruned node no longer has the blocks indexed.
+func initPrune(config *Configuration, opts *Opts) error {
+	if opts.TxIndex {
+		config.Chain.TxIndex = true
+	}
+	if opts.Prune > 0 {
+		config.Chain.Prune = opts.Prune
+	}
+	if config.Chain.Prune > 0 && config.Chain.TxIndex {
+		return errors.New("prune mode is incompatible with -txindex")
+	}
+	return nil
+}
+
+// initUpgradeActivations merges the <name>=<activation> upgrade activations of
+// the command line over those of the config file.
+func initUpgradeActivations(config *Configuration, opts *Opts) error {
+	if len(opts.UpgradeActivations) == 0 {
+		return nil
+	}
+	if config.Chain.UpgradeActivations == nil {
+		config.Chain.UpgradeActivations = make(map[string]int64)
+	}
+	for _, arg := range opts.UpgradeActivations {
+		pair := strings.SplitN(arg, "=", 2)
+		if len(pair) != 2 {
+			return fmt.Errorf("upgradeactivation %s must be given as <name>=<activation>", arg)
+		}
+		activation, err := strconv.ParseInt(pair[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid activation of upgradeactivation %s", arg)
+		}
+		config.Chain.UpgradeActivations[strings.ToLower(pair[0])] = activation
+	}
+	return nil
+}
+
+func initZMQ(config *Configuration, opts *Opts) {
+	if len(opts.ZMQPubHashBlock) > 0 {
+		config.ZMQ.PubHashBlock = opts.ZMQPubHashBlock
+	}
+	if len(opts.ZMQPubHashTx) > 0 {
+		config.ZMQ.PubHashTx = opts.ZMQPubHashTx
+	}
+	if len(opts.ZMQPubRawBlock) > 0 {
+		config.ZMQ.PubRawBlock = opts.ZMQPubRawBlock
+	}
+	if len(opts.ZMQPubRawTx) > 0 {
+		config.ZMQ.PubRawTx = opts.ZMQPubRawTx
+	}
+	if opts.ZMQPubHashBlockHWM > 0 {
+		config.ZMQ.PubHashBlockHWM = opts.ZMQPubHashBlockHWM
+	}
+	if opts.ZMQPubHashTxHWM > 0 {
+		config.ZMQ.PubHashTxHWM = opts.ZMQPubHashTxHWM
+	}
+	if opts.ZMQPubRawBlockHWM > 0 {
+		config.ZMQ.PubRawBlockHWM = opts.ZMQPubRawBlockHWM
+	}
+	if opts.ZMQPubRawTxHWM > 0 {
+		config.ZMQ.PubRawTxHWM = opts.ZMQPubRawTxHWM
+	}
+}
+
 func initWhitelists(config *Configuration, opts *Opts) {
 	var ip net.IP
 	config.P2PNet.Whitelists = make([]*net.IPNet, 0, len(opts.Whitelists))
@@ -201,12 +201,14 @@ func getDefaultConfiguration(args defaultArgs) *Configuration {
 			RPCCert              string `default:""`
 			RPCKey               string
 			RPCMaxClients        int
-			RPCMaxWebsockets     int
+			RPCMaxWebsockets     int `default:"25"`
 			RPCMaxConcurrentReqs int
 			RPCQuirks            bool
+			Rest                 bool `default:"false"`
 		}{
-			RPCCert: filepath.Join(defaultDataDir, "rpc.cert"),
-			RPCKey:  filepath.Join(defaultDataDir, "rpc.key"),
+			RPCCert:          filepath.Join(defaultDataDir, "rpc.cert"),
+			RPCKey:           filepath.Join(defaultDataDir, "rpc.key"),
+			RPCMaxWebsockets: 25,
 		},
 		Mempool: struct {
 			MinFeeRate           int64  //
@@ -217,11 +219,13 @@ func getDefaultConfiguration(args defaultArgs) *Configuration {
 			MaxPoolSize          int64  `default:"300000000"` // Default for MaxPoolSize, maximum megabytes of mempool memory usage
 			MaxPoolExpiry        int    `default:"336"`       // Default for -mempoolexpiry, expiration time for mempool transactions in hours
 			CheckFrequency       uint64 `default:"4294967296"`
+			PersistMempool       bool   `default:"true"` // Default for -persistmempool, save the mempool on shutdown and load on restart
 		}{
 			MaxPoolSize:        300000000,
 			CheckFrequency:     4294967296,
 			LimitAncestorCount: 50000,
 			MaxPoolExpiry:      336,
+			PersistMempool:     true,
 		},
 		P2PNet: struct {
 			ListenAddrs         []string `validate:"require" default:"1234"`
@@ -275,13 +279,17 @@ func getDefaultConfiguration(args defaultArgs) *Configuration {
 			IsBareMultiSigStd   bool `default:"true"`
 			//use promiscuousMempoolFlags to make more or less check of script, the type of value is uint
 			PromiscuousMempoolFlags string
-			Par                     int `default:"32"`
+			Par                     int  `default:"32"`
+			MaxSigCacheEntries      uint `default:"100000"`
+			MaxScriptCacheEntries   uint `default:"100000"`
 		}{
 			AcceptDataCarrier:       true,
 			MaxDatacarrierBytes:     223,
 			IsBareMultiSigStd:       true,
 			PromiscuousMempoolFlags: "",
 			Par:                     32,
+			MaxSigCacheEntries:      100000,
+			MaxScriptCacheEntries:   100000,
 		},
 		TxOut: struct {
 			DustRelayFee int64 `default:"83"`
@@ -290,6 +298,9 @@ func getDefaultConfiguration(args defaultArgs) *Configuration {
 			AssumeValid         string
 			UtxoHashStartHeight int32 `default:"-1"`
 			UtxoHashEndHeight   int32 `default:"-1"`
+			TxIndex             bool
+			Prune               uint64
+			UpgradeActivations  map[string]int64
 		}{
 			AssumeValid:         "",
 			UtxoHashStartHeight: args.UtxoHashStartHeight,
@@ -317,7 +328,18 @@ func getDefaultConfiguration(args defaultArgs) *Configuration {
 			Enable              bool `default:"false"`
 			Broadcast           bool `default:"false"`
 			SpendZeroConfChange bool `default:"true"`
-		}{Enable: false, Broadcast: false, SpendZeroConfChange: true},
+			KeyPool             int  `default:"1000"`
+		}{Enable: false, Broadcast: false, SpendZeroConfChange: true, KeyPool: 1000},
+		ZMQ: struct {
+			PubHashBlock    string
+			PubHashTx       string
+			PubRawBlock     string
+			PubRawTx        string
+			PubHashBlockHWM int `default:"1000"`
+			PubHashTxHWM    int `default:"1000"`
+			PubRawBlockHWM  int `default:"1000"`
+			PubRawTxHWM     int `default:"1000"`
+		}{PubHashBlockHWM: 1000, PubHashTxHWM: 1000, PubRawBlockHWM: 1000, PubRawTxHWM: 1000},
 	}
 }
 
@@ -439,6 +461,18 @@ func TestInitConfig2(t *testing.T) {
 	}
 }
 
+func TestInitUpgradeActivations(t *testing.T) {
+	config := &Configuration{}
+	config.Chain.UpgradeActivations = map[string]int64{"phonon": 1, "axion": 2}
+	opts := &Opts{UpgradeActivations: []string{"Axion=3", "upgrade9=4"}}
+
+	assert.Nil(t, initUpgradeActivations(config, opts))
+	assert.Equal(t, map[string]int64{"phonon": 1, "axion": 3, "upgrade9": 4}, config.Chain.UpgradeActivations)
+
+	assert.NotNil(t, initUpgradeActivations(config, &Opts{UpgradeActivations: []string{"axion"}}))
+	assert.NotNil(t, initUpgradeActivations(config, &Opts{UpgradeActivations: []string{"axion=soon"}}))
+}
+
 func TestSetUnitTestDataDir(t *testing.T) {
 	args := []string{"--testnet"}
 	Cfg = InitConfig(args)
@@ -454,3 +488,13 @@ func TestSetUnitTestDataDir(t *testing.T) {
 		t.Errorf("SetUnitTestDataDir implementation error:%v", err)
 	}
 }
+
+func TestInitPrune(t *testing.T) {
+	config := &Configuration{}
+	assert.Nil(t, initPrune(config, &Opts{Prune: 550}))
+	assert.Equal(t, uint64(550), config.Chain.Prune)
+
+	assert.NotNil(t, initPrune(config, &Opts{TxIndex: true}))
+	assert.NotNil(t, initPrune(&Configuration{}, &Opts{TxIndex: true, Prune: 1}))
+	assert.Nil(t, initPrune(&Configuration{}, &Opts{TxIndex: true}))
+}
@@ -24,17 +24,31 @@ type Opts struct {
 	Excessiveblocksize uint64   `long:"excessiveblocksize" default:"32000000" description:"excessive block size"`
 	BanScore           uint32   `long:"banscore" default:"100" description:"Threshold for disconnecting misbehaving peers"`
 
-	ReplayProtectionActivationTime int64  `long:"replayprotectionactivationtime" default:"-1"`
-	MagneticAnomalyTime            int64  `long:"magneticanomalyactivationtime" default:"-1"`
-	StopAtHeight                   int32  `long:"stopatheight" default:"-1"`
-	PromiscuousMempoolFlags        string `long:"promiscuousmempoolflags"`
-	Limitancestorcount             int    `long:"limitancestorcount" default:"50000"`
-	BlockVersion                   int32  `long:"blockversion" default:"-1" description:"regtest block version"`
-	MaxMempool                     int64  `long:"maxmempool" default:"300000000"`
-	SpendZeroConfChange            uint8  `long:"spendzeroconfchange" default:"1"`
-	MaxTimeAdjustment              uint64 `long:"maxtimeadjustment" default:"4200" description:"Maximum allowed median peer time offset adjustment. Local perspective of time may be influenced by peers forward or backward by this amount."`
-	MinimumChainWork               string `long:"minimumchainwork"`
-	AssumeValid                    string `long:"assumevalid"`
+	ReplayProtectionActivationTime int64    `long:"replayprotectionactivationtime" default:"-1"`
+	MagneticAnomalyTime            int64    `long:"magneticanomalyactivationtime" default:"-1"`
+	UpgradeActivations             []string `long:"upgradeactivation" description:"Activate the upgrade <name> at <height or median time past>, given as <name>=<activation> (regtest only)"`
+	StopAtHeight                   int32    `long:"stopatheight" default:"-1"`
+	PromiscuousMempoolFlags        string   `long:"promiscuousmempoolflags"`
+	Limitancestorcount             int      `long:"limitancestorcount" default:"50000"`
+	BlockVersion                   int32    `long:"blockversion" default:"-1" description:"regtest block version"`
+	MaxMempool                     int64    `long:"maxmempool" default:"300000000"`
+	SpendZeroConfChange            uint8    `long:"spendzeroconfchange" default:"1"`
+	KeyPool                        int      `long:"keypool" description:"Set key pool size to <n> (default: 1000)"`
+	PersistMempool                 uint8    `long:"persistmempool" default:"1" description:"Whether to save the mempool on shutdown and load on restart"`
+	MaxTimeAdjustment              uint64   `long:"maxtimeadjustment" default:"4200" description:"Maximum allowed median peer time offset adjustment. Local perspective of time may be influenced by peers forward or backward by this amount."`
+	MinimumChainWork               string   `long:"minimumchainwork"`
+	AssumeValid                    string   `long:"assumevalid"`
+	TxIndex                        bool     `long:"txindex" description:"Maintain a full transaction index, used by the getrawtransaction rpc call"`
+	Prune                          uint64   `long:"prune" description:"Reduce storage requirements by pruning old blocks, keeping block and undo files under this target size in MiB (0 = disabled, 1 = manual pruning via the pruneblockchain rpc call, >= 550 = automatic pruning)"`
+	Rest                           bool     `long:"rest" description:"Accept public REST requests"`
+	ZMQPubHashBlock                string   `long:"zmqpubhashblock" description:"Enable publish hash block in <address>"`
+	ZMQPubHashTx                   string   `long:"zmqpubhashtx" description:"Enable publish hash transaction in <address>"`
+	ZMQPubRawBlock                 string   `long:"zmqpubrawblock" description:"Enable publish raw block in <address>"`
+	ZMQPubRawTx                    string   `long:"zmqpubrawtx" description:"Enable publish raw transaction in <address>"`
+	ZMQPubHashBlockHWM             int      `long:"zmqpubhashblockhwm" description:"Set publish hash block outbound message high water mark (default: 1000)"`
+	ZMQPubHashTxHWM                int      `long:"zmqpubhashtxhwm" description:"Set publish hash transaction outbound message high water mark (default: 1000)"`
+	ZMQPubRawBlockHWM              int      `long:"zmqpubrawblockhwm" description:"Set publish raw block outbound message high water mark (default: 1000)"`
+	ZMQPubRawTxHWM                 int      `long:"zmqpubrawtxhwm" description:"Set publish raw transaction outbound message high water mark (default: 1000)"`
 }
 
 func InitArgs(args []string) (*Opts, error) {
@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/copernet/secp256k1-go/secp256k1"
+)
+
+// CompactSignatureSize is the length of a compact signature: a header byte
+// holding the recovery id and the key compression, followed by r and s, 32
+// bytes each.
+const CompactSignatureSize = 65
+
+// compactSigMagicOffset is added to the recovery id in the header byte, plus
+// 4 more when the public key is compressed.
+const compactSigMagicOffset = 27
+
+var (
+	errCompactInvalidPrivateKey = errors.New("compact signature: invalid private key")
+	errCompactInvalidHash       = errors.New("compact signature: hash must be 32 bytes")
+	errCompactInvalidSignature  = errors.New("compact signature: invalid signature")
+	errCompactRecoveryFailed    = errors.New("compact signature: public key recovery failed")
+)
+
+// SignCompact creates a compact ECDSA signature of the 32-byte hash, from
+// which RecoverCompact recovers the public key. The signature is created by
+// libsecp256k1, with a RFC6979 nonce and a low s.
+func (privateKey *PrivateKey) SignCompact(hash []byte) ([]byte, error) {
+	if len(hash) != 32 {
+		return nil, errCompactInvalidHash
+	}
+	if len(privateKey.bytes) != PrivateKeyBytesLen {
+		return nil, errCompactInvalidPrivateKey
+	}
+	if _, err := secp256k1.EcSeckeyVerify(secp256k1Context, privateKey.bytes); err != nil {
+		return nil, errCompactInvalidPrivateKey
+	}
+
+	_, recoverable, err := secp256k1.EcdsaSignRecoverable(secp256k1Context, hash, privateKey.bytes)
+	if err != nil {
+		return nil, err
+	}
+	_, rs, recID, err := secp256k1.EcdsaRecoverableSignatureSerializeCompact(secp256k1Context, recoverable)
+	if err != nil {
+		return nil, err
+	}
+
+	header := byte(compactSigMagicOffset + recID)
+	if privateKey.compressed {
+		header += 4
+	}
+	sig := make([]byte, 0, CompactSignatureSize)
+	sig = append(sig, header)
+	return append(sig, rs...), nil
+}
+
+// RecoverCompact returns the public key whose private key created the compact
+// signature sig of the 32-byte hash.
+func RecoverCompact(sig []byte, hash []byte) (*PublicKey, error) {
+	if len(hash) != 32 {
+		return nil, errCompactInvalidHash
+	}
+	if len(sig) != CompactSignatureSize {
+		return nil, errCompactInvalidSignature
+	}
+	header := int(sig[0]) - compactSigMagicOffset
+	if header < 0 || header > 7 {
+		return nil, errCompactInvalidSignature
+	}
+	recID := header & 3
+	compressed := header&4 != 0
+
+	r := new(big.Int).SetBytes(sig[1:33])
+	s := new(big.Int).SetBytes(sig[33:65])
+	if r.Sign() == 0 || r.Cmp(curveN) >= 0 || s.Sign() == 0 || s.Cmp(curveN) >= 0 {
+		return nil, errCompactInvalidSignature
+	}
+
+	_, recoverable, err := secp256k1.EcdsaRecoverableSignatureParseCompact(secp256k1Context, sig[1:], recID)
+	if err != nil {
+		return nil, errCompactInvalidSignature
+	}
+	_, secpPubKey, err := secp256k1.EcdsaRecover(secp256k1Context, recoverable, hash)
+	if err != nil {
+		return nil, errCompactRecoveryFailed
+	}
+	return &PublicKey{SecpPubKey: secpPubKey, Compressed: compressed}, nil
+}
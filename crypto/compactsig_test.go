@@ -0,0 +1,87 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/base58"
+	"github.com/stretchr/testify/assert"
+)
+
+func testMessageHash(message string) []byte {
+	var buf bytes.Buffer
+	util.WriteVarString(&buf, "Bitcoin Signed Message:\n")
+	util.WriteVarString(&buf, message)
+	return util.DoubleSha256Bytes(buf.Bytes())
+}
+
+func TestSignCompactVector(t *testing.T) {
+	InitSecp256()
+	// signmessage test vector of Bitcoin Core, with a testnet key
+	keyBytes, _, err := base58.CheckDecode("cUeKHd5orzT3mz8P9pxyREHfsWtVfgsfDjiZZBcjUBAaGk1BTj7N")
+	assert.NoError(t, err)
+	privateKey := NewPrivateKeyFromBytes(keyBytes[:PrivateKeyBytesLen], true)
+	hash := testMessageHash("This is just a test message")
+
+	sig, err := privateKey.SignCompact(hash)
+	assert.NoError(t, err)
+	assert.Equal(t, "INbVnW4e6PeRmsv2Qgu8NuopvrVjkcxob+sX8OcZG0SALhWybUjzMLPdAsXI46YZGb0KQTRii+wWIQzRpG/U+S0=",
+		base64.StdEncoding.EncodeToString(sig))
+
+	publicKey, err := RecoverCompact(sig, hash)
+	assert.NoError(t, err)
+	assert.True(t, publicKey.Compressed)
+	assert.Equal(t, privateKey.PubKey().ToBytes(), publicKey.ToBytes())
+}
+
+func TestSignCompact(t *testing.T) {
+	InitSecp256()
+	keyBytes := mustDecodeHex(t, "eaf02ca348c524e6392655ba4d29603cd1a7347d9d65cfe93ce1ebffdca22694")
+	for _, compressed := range []bool{false, true} {
+		privateKey := NewPrivateKeyFromBytes(keyBytes, compressed)
+		for i := 0; i < 16; i++ {
+			hash := util.DoubleSha256Bytes([]byte{byte(i)})
+			sig, err := privateKey.SignCompact(hash)
+			assert.NoError(t, err)
+			assert.Equal(t, CompactSignatureSize, len(sig))
+
+			publicKey, err := RecoverCompact(sig, hash)
+			assert.NoError(t, err)
+			assert.Equal(t, compressed, publicKey.Compressed)
+			assert.Equal(t, privateKey.PubKey().ToBytes(), publicKey.ToBytes())
+
+			// The signature of another message recovers another key.
+			publicKey, err = RecoverCompact(sig, util.DoubleSha256Bytes([]byte{byte(i), 0}))
+			if err == nil {
+				assert.NotEqual(t, privateKey.PubKey().ToBytes(), publicKey.ToBytes())
+			}
+		}
+	}
+}
+
+func TestRecoverCompactErrors(t *testing.T) {
+	InitSecp256()
+	hash := util.DoubleSha256Bytes([]byte("message"))
+	privateKey := NewPrivateKeyFromBytes(
+		mustDecodeHex(t, "eaf02ca348c524e6392655ba4d29603cd1a7347d9d65cfe93ce1ebffdca22694"), true)
+	sig, err := privateKey.SignCompact(hash)
+	assert.NoError(t, err)
+
+	_, err = RecoverCompact(sig[:64], hash)
+	assert.Equal(t, errCompactInvalidSignature, err)
+	_, err = RecoverCompact(sig, hash[:31])
+	assert.Equal(t, errCompactInvalidHash, err)
+
+	badHeader := append([]byte{26}, sig[1:]...)
+	_, err = RecoverCompact(badHeader, hash)
+	assert.Equal(t, errCompactInvalidSignature, err)
+
+	zeroR := append([]byte{sig[0]}, make([]byte, 32)...)
+	_, err = RecoverCompact(append(zeroR, sig[33:]...), hash)
+	assert.Equal(t, errCompactInvalidSignature, err)
+
+	_, err = NewPrivateKeyFromBytes(make([]byte, PrivateKeyBytesLen), true).SignCompact(hash)
+	assert.Equal(t, errCompactInvalidPrivateKey, err)
+}
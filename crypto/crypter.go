@@ -0,0 +1,239 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"io"
+	"time"
+
+	"github.com/copernet/copernicus/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	// WalletCryptoKeySize is the size of the AES-256 keys used to encrypt
+	// the master key and the private keys.
+	WalletCryptoKeySize = 32
+	// WalletCryptoSaltSize is the size of the salt used to derive a key from
+	// a passphrase.
+	WalletCryptoSaltSize = 8
+	// WalletCryptoIVSize is the size of the AES-CBC initialization vector.
+	WalletCryptoIVSize = aes.BlockSize
+
+	// DerivationMethodSHA512 derives keys from passphrases by iterated
+	// SHA-512 hashing, the same way as EVP_BytesToKey in Bitcoin Core.
+	DerivationMethodSHA512 = 0
+
+	// MinDeriveIterations is the minimum number of rounds used to derive a
+	// key from a passphrase.
+	MinDeriveIterations = 25000
+
+	// deriveTargetTime is how long deriving a key from a passphrase should
+	// take approximately when the number of rounds is calibrated.
+	deriveTargetTime = 100 * time.Millisecond
+
+	maxCryptedKeySize = 1024
+)
+
+var (
+	ErrInvalidPadding     = errors.New("invalid padding of encrypted data")
+	ErrPassphraseEmpty    = errors.New("passphrase can not be empty")
+	ErrPassphraseMismatch = errors.New("the passphrase entered was incorrect")
+	ErrDerivationMethod   = errors.New("unsupported key derivation method")
+)
+
+// BytesToKeySHA512AES derives an AES-256 key and initialization vector from a
+// passphrase and a salt by hashing them count times with SHA-512.
+func BytesToKeySHA512AES(salt []byte, passphrase []byte, count uint32) (key []byte, iv []byte) {
+	if count == 0 {
+		count = 1
+	}
+	h := sha512.New()
+	h.Write(passphrase)
+	h.Write(salt)
+	buf := h.Sum(nil)
+	for i := uint32(1); i < count; i++ {
+		sum := sha512.Sum512(buf)
+		buf = sum[:]
+	}
+	key = make([]byte, WalletCryptoKeySize)
+	iv = make([]byte, WalletCryptoIVSize)
+	copy(key, buf[:WalletCryptoKeySize])
+	copy(iv, buf[WalletCryptoKeySize:WalletCryptoKeySize+WalletCryptoIVSize])
+	return key, iv
+}
+
+// EncryptAES256CBC encrypts plaintext with AES-256 in CBC mode, padding it
+// as described in PKCS#7.
+func EncryptAES256CBC(key []byte, iv []byte, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padLen := aes.BlockSize - len(plaintext)%aes.BlockSize
+	data := make([]byte, len(plaintext), len(plaintext)+padLen)
+	copy(data, plaintext)
+	data = append(data, bytes.Repeat([]byte{byte(padLen)}, padLen)...)
+
+	ciphertext := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, data)
+	return ciphertext, nil
+}
+
+// DecryptAES256CBC decrypts ciphertext produced by EncryptAES256CBC.
+func DecryptAES256CBC(key []byte, iv []byte, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, ErrInvalidPadding
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	padLen := int(plaintext[len(plaintext)-1])
+	if padLen == 0 || padLen > aes.BlockSize {
+		return nil, ErrInvalidPadding
+	}
+	for _, b := range plaintext[len(plaintext)-padLen:] {
+		if int(b) != padLen {
+			return nil, ErrInvalidPadding
+		}
+	}
+	return plaintext[:len(plaintext)-padLen], nil
+}
+
+// EncryptSecret encrypts a private key with the master key.  The
+// initialization vector is derived from the public key, so each key is
+// encrypted differently.
+func EncryptSecret(masterKey []byte, secret []byte, pubKey *PublicKey) ([]byte, error) {
+	iv := util.DoubleSha256Bytes(pubKey.ToBytes())[:WalletCryptoIVSize]
+	return EncryptAES256CBC(masterKey, iv, secret)
+}
+
+// DecryptSecret decrypts a private key encrypted by EncryptSecret and checks
+// that it matches the public key.
+func DecryptSecret(masterKey []byte, cryptedSecret []byte, pubKey *PublicKey) (*PrivateKey, error) {
+	iv := util.DoubleSha256Bytes(pubKey.ToBytes())[:WalletCryptoIVSize]
+	secret, err := DecryptAES256CBC(masterKey, iv, cryptedSecret)
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) != PrivateKeyBytesLen {
+		return nil, errors.New("decrypted private key has an invalid size")
+	}
+	privateKey := NewPrivateKeyFromBytes(secret, pubKey.Compressed)
+	derived := privateKey.PubKey()
+	if derived == nil || !derived.IsEqual(pubKey) {
+		return nil, errors.New("decrypted private key does not match its public key")
+	}
+	return privateKey, nil
+}
+
+// MasterKey is the key encrypting all the private keys of a wallet,
+// itself encrypted with a key derived from the wallet passphrase.
+type MasterKey struct {
+	CryptedKey       []byte
+	Salt             []byte
+	DerivationMethod uint32
+	DeriveIterations uint32
+}
+
+// NewMasterKey generates a random master key and encrypts it with the
+// passphrase.  It returns the encrypted master key along with the plain one.
+func NewMasterKey(passphrase []byte) (*MasterKey, []byte, error) {
+	if len(passphrase) == 0 {
+		return nil, nil, ErrPassphraseEmpty
+	}
+	plainKey := make([]byte, WalletCryptoKeySize)
+	if _, err := io.ReadFull(rand.Reader, plainKey); err != nil {
+		return nil, nil, err
+	}
+	mk := &MasterKey{}
+	if err := mk.SetPassphrase(plainKey, passphrase); err != nil {
+		return nil, nil, err
+	}
+	return mk, plainKey, nil
+}
+
+// SetPassphrase encrypts the plain master key with a new passphrase, using
+// a fresh salt and a number of derivation rounds calibrated on this machine.
+func (mk *MasterKey) SetPassphrase(plainKey []byte, passphrase []byte) error {
+	if len(passphrase) == 0 {
+		return ErrPassphraseEmpty
+	}
+	salt := make([]byte, WalletCryptoSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+
+	// Calibrate the number of rounds so that deriving the key takes about
+	// deriveTargetTime, as Bitcoin Core does.
+	start := time.Now()
+	BytesToKeySHA512AES(salt, passphrase, MinDeriveIterations)
+	elapsed := time.Since(start)
+	iterations := uint32(MinDeriveIterations)
+	if elapsed > 0 {
+		calibrated := int64(MinDeriveIterations) * int64(deriveTargetTime) / int64(elapsed)
+		if calibrated > int64(iterations) && calibrated < int64(^uint32(0)) {
+			iterations = uint32(calibrated)
+		}
+	}
+
+	key, iv := BytesToKeySHA512AES(salt, passphrase, iterations)
+	cryptedKey, err := EncryptAES256CBC(key, iv, plainKey)
+	if err != nil {
+		return err
+	}
+	mk.CryptedKey = cryptedKey
+	mk.Salt = salt
+	mk.DerivationMethod = DerivationMethodSHA512
+	mk.DeriveIterations = iterations
+	return nil
+}
+
+// Decrypt returns the plain master key if the passphrase is correct.
+func (mk *MasterKey) Decrypt(passphrase []byte) ([]byte, error) {
+	if mk.DerivationMethod != DerivationMethodSHA512 {
+		return nil, ErrDerivationMethod
+	}
+	key, iv := BytesToKeySHA512AES(mk.Salt, passphrase, mk.DeriveIterations)
+	plainKey, err := DecryptAES256CBC(key, iv, mk.CryptedKey)
+	if err != nil || len(plainKey) != WalletCryptoKeySize {
+		return nil, ErrPassphraseMismatch
+	}
+	return plainKey, nil
+}
+
+func (mk *MasterKey) Serialize(w io.Writer) error {
+	if err := util.WriteVarBytes(w, mk.CryptedKey); err != nil {
+		return err
+	}
+	if err := util.WriteVarBytes(w, mk.Salt); err != nil {
+		return err
+	}
+	if err := util.WriteElements(w, mk.DerivationMethod, mk.DeriveIterations); err != nil {
+		return err
+	}
+	// Other derivation parameters, unused by the SHA-512 method.
+	return util.WriteVarBytes(w, []byte{})
+}
+
+func (mk *MasterKey) Unserialize(r io.Reader) error {
+	var err error
+	if mk.CryptedKey, err = util.ReadVarBytes(r, maxCryptedKeySize, "crypted master key"); err != nil {
+		return err
+	}
+	if mk.Salt, err = util.ReadVarBytes(r, maxCryptedKeySize, "master key salt"); err != nil {
+		return err
+	}
+	if err = util.ReadElements(r, &mk.DerivationMethod, &mk.DeriveIterations); err != nil {
+		return err
+	}
+	_, err = util.ReadVarBytes(r, maxCryptedKeySize, "master key derivation parameters")
+	return err
+}
@@ -0,0 +1,92 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBytesToKeySHA512AES(t *testing.T) {
+	salt := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	key, iv := BytesToKeySHA512AES(salt, []byte("passphrase"), 1)
+	assert.Equal(t, WalletCryptoKeySize, len(key))
+	assert.Equal(t, WalletCryptoIVSize, len(iv))
+
+	// A single round is a plain SHA-512 of the passphrase and the salt.
+	assert.Equal(t, "a7b574b8a0f40ecb0c951fd6c2ee2908e24a9c8529a8b0ed3026ba5cd86286ea",
+		hex.EncodeToString(key))
+
+	key2, iv2 := BytesToKeySHA512AES(salt, []byte("passphrase"), 2)
+	assert.NotEqual(t, key, key2)
+	assert.NotEqual(t, iv, iv2)
+}
+
+func TestAES256CBC(t *testing.T) {
+	key := bytes.Repeat([]byte{0x11}, WalletCryptoKeySize)
+	iv := bytes.Repeat([]byte{0x22}, WalletCryptoIVSize)
+
+	for _, size := range []int{0, 1, 15, 16, 32, 33} {
+		plaintext := bytes.Repeat([]byte{0xab}, size)
+		ciphertext, err := EncryptAES256CBC(key, iv, plaintext)
+		assert.Nil(t, err)
+		assert.Equal(t, (size/16+1)*16, len(ciphertext))
+
+		decrypted, err := DecryptAES256CBC(key, iv, ciphertext)
+		assert.Nil(t, err)
+		assert.Equal(t, plaintext, decrypted)
+	}
+
+	_, err := DecryptAES256CBC(key, iv, []byte{1, 2, 3})
+	assert.Equal(t, ErrInvalidPadding, err)
+}
+
+func TestEncryptSecret(t *testing.T) {
+	InitSecp256()
+	privateKey := NewPrivateKeyFromBytes(getTestPrivateKey().GetBytes(), true)
+	publicKey := privateKey.PubKey()
+	masterKey := bytes.Repeat([]byte{0x33}, WalletCryptoKeySize)
+
+	secret, err := EncryptSecret(masterKey, privateKey.GetBytes(), publicKey)
+	assert.Nil(t, err)
+	assert.NotEqual(t, privateKey.GetBytes(), secret)
+
+	decrypted, err := DecryptSecret(masterKey, secret, publicKey)
+	assert.Nil(t, err)
+	assert.Equal(t, privateKey, decrypted)
+
+	wrongKey := bytes.Repeat([]byte{0x44}, WalletCryptoKeySize)
+	_, err = DecryptSecret(wrongKey, secret, publicKey)
+	assert.NotNil(t, err)
+}
+
+func TestMasterKey(t *testing.T) {
+	_, _, err := NewMasterKey(nil)
+	assert.Equal(t, ErrPassphraseEmpty, err)
+
+	masterKey, plainKey, err := NewMasterKey([]byte("passphrase"))
+	assert.Nil(t, err)
+	assert.Equal(t, WalletCryptoKeySize, len(plainKey))
+	assert.True(t, masterKey.DeriveIterations >= MinDeriveIterations)
+
+	decrypted, err := masterKey.Decrypt([]byte("passphrase"))
+	assert.Nil(t, err)
+	assert.Equal(t, plainKey, decrypted)
+
+	_, err = masterKey.Decrypt([]byte("wrong"))
+	assert.Equal(t, ErrPassphraseMismatch, err)
+
+	var buf bytes.Buffer
+	assert.Nil(t, masterKey.Serialize(&buf))
+	loaded := &MasterKey{}
+	assert.Nil(t, loaded.Unserialize(&buf))
+	assert.Equal(t, masterKey, loaded)
+
+	assert.Nil(t, loaded.SetPassphrase(plainKey, []byte("new passphrase")))
+	_, err = loaded.Decrypt([]byte("passphrase"))
+	assert.Equal(t, ErrPassphraseMismatch, err)
+	decrypted, err = loaded.Decrypt([]byte("new passphrase"))
+	assert.Nil(t, err)
+	assert.Equal(t, plainKey, decrypted)
+}
@@ -0,0 +1,338 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/base58"
+	"github.com/copernet/secp256k1-go/secp256k1"
+	"github.com/pkg/errors"
+)
+
+const (
+	// HardenedKeyStart is the index of the first hardened child key.
+	HardenedKeyStart = 0x80000000
+
+	// MinSeedBytes and MaxSeedBytes bound the length of the seed of a
+	// master extended key, as in BIP32.
+	MinSeedBytes = 16
+	MaxSeedBytes = 64
+
+	// serializedExtendedKeyLen is the length of a serialized extended key
+	// without its checksum: version, depth, parent fingerprint, child
+	// number, chain code and key.
+	serializedExtendedKeyLen = 4 + 1 + 4 + 4 + 32 + 33
+)
+
+var (
+	ErrInvalidSeedLen       = fmt.Errorf("seed length must be between %d and %d bytes", MinSeedBytes, MaxSeedBytes)
+	ErrInvalidChild         = errors.New("the extended key at this index is invalid")
+	ErrDeriveHardFromPublic = errors.New("cannot derive a hardened key from a public key")
+	ErrDeriveBeyondMaxDepth = errors.New("cannot derive a key with more than 255 indices in its path")
+	ErrNotPrivExtKey        = errors.New("unable to create private keys from a public extended key")
+	ErrInvalidExtendedKey   = errors.New("the provided serialized extended key is invalid")
+	ErrInvalidHDKeyPath     = errors.New("invalid hierarchical deterministic key path")
+)
+
+var masterKeyHMACKey = []byte("Bitcoin seed")
+
+var (
+	activeNetHDPrivateKeyVer = [4]byte{0x04, 0x88, 0xad, 0xe4}
+	activeNetHDPublicKeyVer  = [4]byte{0x04, 0x88, 0xb2, 0x1e}
+)
+
+// InitHDKeyVersions sets the version bytes of the serialized private and
+// public extended keys of the active network.
+func InitHDKeyVersions(privateKeyVer [4]byte, publicKeyVer [4]byte) {
+	activeNetHDPrivateKeyVer = privateKeyVer
+	activeNetHDPublicKeyVer = publicKeyVer
+}
+
+// ExtendedKey is a BIP32 extended key, from which child keys are derived.
+// It holds either a private key or, once neutered, only the public key.
+type ExtendedKey struct {
+	key       []byte // 32 bytes private key or 33 bytes compressed public key
+	chainCode []byte
+	parentFP  []byte
+	depth     uint8
+	childNum  uint32
+	isPrivate bool
+}
+
+// NewMasterExtendedKey creates the master extended key of a seed.
+func NewMasterExtendedKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < MinSeedBytes || len(seed) > MaxSeedBytes {
+		return nil, ErrInvalidSeedLen
+	}
+
+	mac := hmac.New(sha512.New, masterKeyHMACKey)
+	mac.Write(seed)
+	lr := mac.Sum(nil)
+
+	keyNum := new(big.Int).SetBytes(lr[:32])
+	if keyNum.Sign() == 0 || keyNum.Cmp(curveN) >= 0 {
+		return nil, ErrInvalidChild
+	}
+	return &ExtendedKey{
+		key:       lr[:32],
+		chainCode: lr[32:],
+		parentFP:  []byte{0, 0, 0, 0},
+		isPrivate: true,
+	}, nil
+}
+
+// IsPrivate reports whether the extended key holds a private key.
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.isPrivate
+}
+
+// Depth returns the number of derivations from the master key.
+func (k *ExtendedKey) Depth() uint8 {
+	return k.depth
+}
+
+// ChildNum returns the index the key was derived at from its parent.
+func (k *ExtendedKey) ChildNum() uint32 {
+	return k.childNum
+}
+
+// ParentFingerprint returns the fingerprint of the parent key.
+func (k *ExtendedKey) ParentFingerprint() uint32 {
+	return binary.BigEndian.Uint32(k.parentFP)
+}
+
+// pubKeyBytes returns the compressed public key.
+func (k *ExtendedKey) pubKeyBytes() []byte {
+	if !k.isPrivate {
+		return k.key
+	}
+	return NewPrivateKeyFromBytes(k.key, true).PubKey().SerializeCompressed()
+}
+
+// Child derives the child extended key at index i.  Indices starting from
+// HardenedKeyStart derive hardened keys, which require a private key.
+// ErrInvalidChild is returned for the rare indices that have no valid key,
+// in which case the next index should be used.
+func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+	if k.depth == 255 {
+		return nil, ErrDeriveBeyondMaxDepth
+	}
+	isHardened := i >= HardenedKeyStart
+	if isHardened && !k.isPrivate {
+		return nil, ErrDeriveHardFromPublic
+	}
+
+	// I = HMAC-SHA512(chain code, 0x00 || key || i) for hardened keys and
+	// HMAC-SHA512(chain code, public key || i) for normal ones.
+	data := make([]byte, 0, 37)
+	if isHardened {
+		data = append(data, 0x00)
+		data = append(data, k.key...)
+	} else {
+		data = append(data, k.pubKeyBytes()...)
+	}
+	var index [4]byte
+	binary.BigEndian.PutUint32(index[:], i)
+	data = append(data, index[:]...)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	ilr := mac.Sum(nil)
+	il, childChainCode := ilr[:32], ilr[32:]
+
+	ilNum := new(big.Int).SetBytes(il)
+	if ilNum.Cmp(curveN) >= 0 || ilNum.Sign() == 0 {
+		return nil, ErrInvalidChild
+	}
+
+	var childKey []byte
+	if k.isPrivate {
+		// k_i = parse256(IL) + k_par (mod n)
+		childKey = make([]byte, PrivateKeyBytesLen)
+		copy(childKey, k.key)
+		if _, err := secp256k1.EcPrivkeyTweakAdd(secp256k1Context, childKey, il); err != nil {
+			return nil, ErrInvalidChild
+		}
+	} else {
+		// K_i = point(parse256(IL)) + K_par
+		parentKey, err := ParsePubKey(k.key)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := secp256k1.EcPubkeyTweakAdd(secp256k1Context, parentKey.SecpPubKey, il); err != nil {
+			return nil, ErrInvalidChild
+		}
+		childKey = parentKey.SerializeCompressed()
+	}
+
+	return &ExtendedKey{
+		key:       childKey,
+		chainCode: childChainCode,
+		parentFP:  util.Hash160(k.pubKeyBytes())[:4],
+		depth:     k.depth + 1,
+		childNum:  i,
+		isPrivate: k.isPrivate,
+	}, nil
+}
+
+// DerivePath derives the descendant extended key following path.
+func (k *ExtendedKey) DerivePath(path []uint32) (*ExtendedKey, error) {
+	key := k
+	for _, i := range path {
+		var err error
+		if key, err = key.Child(i); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// Neuter returns the public extended key of a private extended key.
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	if !k.isPrivate {
+		return k
+	}
+	return &ExtendedKey{
+		key:       k.pubKeyBytes(),
+		chainCode: k.chainCode,
+		parentFP:  k.parentFP,
+		depth:     k.depth,
+		childNum:  k.childNum,
+		isPrivate: false,
+	}
+}
+
+// PrivateKey returns the compressed private key of a private extended key.
+func (k *ExtendedKey) PrivateKey() (*PrivateKey, error) {
+	if !k.isPrivate {
+		return nil, ErrNotPrivExtKey
+	}
+	return NewPrivateKeyFromBytes(k.key, true), nil
+}
+
+// PublicKey returns the compressed public key of the extended key.
+func (k *ExtendedKey) PublicKey() (*PublicKey, error) {
+	return ParsePubKey(k.pubKeyBytes())
+}
+
+// String serializes the extended key as an xprv or xpub string of the
+// active network.
+func (k *ExtendedKey) String() string {
+	buf := make([]byte, 0, serializedExtendedKeyLen+4)
+	if k.isPrivate {
+		buf = append(buf, activeNetHDPrivateKeyVer[:]...)
+	} else {
+		buf = append(buf, activeNetHDPublicKeyVer[:]...)
+	}
+	buf = append(buf, k.depth)
+	buf = append(buf, k.parentFP...)
+	var childNum [4]byte
+	binary.BigEndian.PutUint32(childNum[:], k.childNum)
+	buf = append(buf, childNum[:]...)
+	buf = append(buf, k.chainCode...)
+	if k.isPrivate {
+		buf = append(buf, 0x00)
+	}
+	buf = append(buf, k.key...)
+
+	checksum := util.DoubleSha256Bytes(buf)[:4]
+	buf = append(buf, checksum...)
+	return base58.Encode(buf)
+}
+
+// ParseExtendedKey parses an xprv or xpub string of the active network.
+func ParseExtendedKey(encoded string) (*ExtendedKey, error) {
+	decoded := base58.Decode(encoded)
+	if len(decoded) != serializedExtendedKeyLen+4 {
+		return nil, ErrInvalidExtendedKey
+	}
+	payload, checksum := decoded[:serializedExtendedKeyLen], decoded[serializedExtendedKeyLen:]
+	if !bytes.Equal(util.DoubleSha256Bytes(payload)[:4], checksum) {
+		return nil, ErrInvalidExtendedKey
+	}
+
+	version := payload[:4]
+	var isPrivate bool
+	switch {
+	case bytes.Equal(version, activeNetHDPrivateKeyVer[:]):
+		isPrivate = true
+	case bytes.Equal(version, activeNetHDPublicKeyVer[:]):
+		isPrivate = false
+	default:
+		return nil, errors.New("extended key is for a different network")
+	}
+
+	k := &ExtendedKey{
+		depth:     payload[4],
+		parentFP:  append([]byte(nil), payload[5:9]...),
+		childNum:  binary.BigEndian.Uint32(payload[9:13]),
+		chainCode: append([]byte(nil), payload[13:45]...),
+		isPrivate: isPrivate,
+	}
+	keyData := payload[45:]
+	if isPrivate {
+		if keyData[0] != 0x00 {
+			return nil, ErrInvalidExtendedKey
+		}
+		keyNum := new(big.Int).SetBytes(keyData[1:])
+		if keyNum.Sign() == 0 || keyNum.Cmp(curveN) >= 0 {
+			return nil, ErrInvalidExtendedKey
+		}
+		k.key = append([]byte(nil), keyData[1:]...)
+	} else {
+		if !IsCompressedPubKey(keyData) {
+			return nil, ErrInvalidExtendedKey
+		}
+		if _, err := ParsePubKey(keyData); err != nil {
+			return nil, ErrInvalidExtendedKey
+		}
+		k.key = append([]byte(nil), keyData...)
+	}
+	return k, nil
+}
+
+// ParseHDKeyPath parses a derivation path such as m/44'/0'/0'/0/1, where
+// hardened indices are marked with ' or h.
+func ParseHDKeyPath(path string) ([]uint32, error) {
+	elems := strings.Split(path, "/")
+	if len(elems) == 0 || elems[0] != "m" {
+		return nil, ErrInvalidHDKeyPath
+	}
+	indices := make([]uint32, 0, len(elems)-1)
+	for _, elem := range elems[1:] {
+		hardened := strings.HasSuffix(elem, "'") || strings.HasSuffix(elem, "h")
+		if hardened {
+			elem = elem[:len(elem)-1]
+		}
+		index, err := strconv.ParseUint(elem, 10, 32)
+		if err != nil || index >= HardenedKeyStart {
+			return nil, ErrInvalidHDKeyPath
+		}
+		if hardened {
+			index += HardenedKeyStart
+		}
+		indices = append(indices, uint32(index))
+	}
+	return indices, nil
+}
+
+// FormatHDKeyPath formats a derivation path the way ParseHDKeyPath reads it.
+func FormatHDKeyPath(path []uint32) string {
+	var b strings.Builder
+	b.WriteString("m")
+	for _, index := range path {
+		if index >= HardenedKeyStart {
+			fmt.Fprintf(&b, "/%d'", index-HardenedKeyStart)
+		} else {
+			fmt.Fprintf(&b, "/%d", index)
+		}
+	}
+	return b.String()
+}
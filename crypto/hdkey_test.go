@@ -0,0 +1,109 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// BIP32 test vector 1.
+var hdTestVectors = []struct {
+	path string
+	xpub string
+	xprv string
+}{
+	{
+		"m",
+		"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+		"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+	},
+	{
+		"m/0'",
+		"xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+		"xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7",
+	},
+	{
+		"m/0'/1",
+		"xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+		"xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs",
+	},
+	{
+		"m/0'/1/2'/2/1000000000",
+		"xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+		"xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76",
+	},
+}
+
+func TestExtendedKeyDerivation(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterExtendedKey(seed)
+	assert.Nil(t, err)
+
+	for _, test := range hdTestVectors {
+		path, err := ParseHDKeyPath(test.path)
+		assert.Nil(t, err)
+		assert.Equal(t, test.path, FormatHDKeyPath(path))
+
+		key, err := master.DerivePath(path)
+		assert.Nil(t, err)
+		assert.Equal(t, test.xprv, key.String(), test.path)
+		assert.Equal(t, test.xpub, key.Neuter().String(), test.path)
+		assert.Equal(t, uint8(len(path)), key.Depth())
+
+		parsed, err := ParseExtendedKey(test.xprv)
+		assert.Nil(t, err)
+		assert.Equal(t, key, parsed)
+		parsed, err = ParseExtendedKey(test.xpub)
+		assert.Nil(t, err)
+		assert.Equal(t, key.Neuter(), parsed)
+	}
+}
+
+func TestExtendedKeyPublicDerivation(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, _ := NewMasterExtendedKey(seed)
+	account, _ := master.DerivePath([]uint32{HardenedKeyStart, 1})
+
+	// Normal children of the public key match those of the private key.
+	for _, i := range []uint32{0, 1, 2, 1000000000} {
+		privChild, err := account.Child(i)
+		assert.Nil(t, err)
+		pubChild, err := account.Neuter().Child(i)
+		assert.Nil(t, err)
+		assert.Equal(t, privChild.Neuter(), pubChild)
+
+		privateKey, err := privChild.PrivateKey()
+		assert.Nil(t, err)
+		publicKey, err := pubChild.PublicKey()
+		assert.Nil(t, err)
+		assert.True(t, privateKey.PubKey().IsEqual(publicKey))
+		_, err = pubChild.PrivateKey()
+		assert.Equal(t, ErrNotPrivExtKey, err)
+	}
+
+	_, err := account.Neuter().Child(HardenedKeyStart)
+	assert.Equal(t, ErrDeriveHardFromPublic, err)
+}
+
+func TestExtendedKeyErrors(t *testing.T) {
+	_, err := NewMasterExtendedKey(make([]byte, MinSeedBytes-1))
+	assert.Equal(t, ErrInvalidSeedLen, err)
+	_, err = NewMasterExtendedKey(make([]byte, MaxSeedBytes+1))
+	assert.Equal(t, ErrInvalidSeedLen, err)
+
+	// Bad checksum.
+	xprv := hdTestVectors[0].xprv
+	_, err = ParseExtendedKey(xprv[:len(xprv)-1] + "j")
+	assert.Equal(t, ErrInvalidExtendedKey, err)
+	_, err = ParseExtendedKey("xprv")
+	assert.Equal(t, ErrInvalidExtendedKey, err)
+
+	for _, path := range []string{"", "0/1", "m/", "m/a", "m/2147483648", "m/1''"} {
+		_, err := ParseHDKeyPath(path)
+		assert.Equal(t, ErrInvalidHDKeyPath, err, path)
+	}
+	path, err := ParseHDKeyPath("m/44h/0'/7")
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{HardenedKeyStart + 44, HardenedKeyStart, 7}, path)
+}
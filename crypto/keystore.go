@@ -2,9 +2,22 @@ package crypto
 
 import (
 	"github.com/copernet/copernicus/util"
+	"github.com/pkg/errors"
 	"sync"
 )
 
+var (
+	// ErrKeyStoreLocked is returned when a private key is needed from an
+	// encrypted key store which is locked.
+	ErrKeyStoreLocked = errors.New("key store is locked")
+	// ErrKeyStoreCrypted is returned when encrypting a key store which is
+	// already encrypted.
+	ErrKeyStoreCrypted = errors.New("key store is already encrypted")
+	// ErrKeyStoreNotCrypted is returned when adding encrypted keys to or
+	// unlocking a key store which is not encrypted.
+	ErrKeyStoreNotCrypted = errors.New("key store is not encrypted")
+)
+
 type KeyPair struct {
 	keyID      []byte
 	publicKey  *PublicKey
@@ -33,9 +46,23 @@ func (kd *KeyPair) GetPrivateKey() *PrivateKey {
 	return kd.privateKey
 }
 
+// CryptedKey is a private key of an encrypted key store, stored along with
+// its public key.
+type CryptedKey struct {
+	PublicKey *PublicKey
+	Secret    []byte
+}
+
+// KeyStore holds the key pairs of a wallet.  Once encrypted, the private keys
+// are only kept in memory while the key store is unlocked, while the public
+// keys remain available to tell which scripts are ours.
 type KeyStore struct {
 	sync.RWMutex
 	keys map[string]*KeyPair
+
+	useCrypto   bool
+	cryptedKeys map[string]*CryptedKey
+	masterKey   []byte
 }
 
 func NewKeyStore() *KeyStore {
@@ -44,6 +71,8 @@ func NewKeyStore() *KeyStore {
 	}
 }
 
+// AddKey adds a private key to a key store which is not encrypted.  Keys of
+// an encrypted key store are added with AddKeyCrypted.
 func (ks *KeyStore) AddKey(privateKey *PrivateKey) {
 	keyPair := NewKeyPair(privateKey)
 
@@ -82,6 +111,19 @@ func (ks *KeyStore) GetKeyPairs(pubKeyHashList [][]byte) []*KeyPair {
 	return keys
 }
 
+// GetAllKeyPairs returns all the keys of the key store.  The private keys of
+// a locked key store are nil.
+func (ks *KeyStore) GetAllKeyPairs() []*KeyPair {
+	ks.RLock()
+	defer ks.RUnlock()
+
+	keys := make([]*KeyPair, 0, len(ks.keys))
+	for _, keyPair := range ks.keys {
+		keys = append(keys, keyPair)
+	}
+	return keys
+}
+
 func (ks *KeyStore) AddKeyPairs(keys []*KeyPair) {
 	ks.Lock()
 	defer ks.Unlock()
@@ -90,3 +132,164 @@ func (ks *KeyStore) AddKeyPairs(keys []*KeyPair) {
 		ks.keys[keyPair.GetKeyID()] = keyPair
 	}
 }
+
+// IsCrypted reports whether the private keys of the key store are encrypted.
+func (ks *KeyStore) IsCrypted() bool {
+	ks.RLock()
+	defer ks.RUnlock()
+
+	return ks.useCrypto
+}
+
+// IsLocked reports whether the key store is encrypted and its private keys
+// are unavailable.
+func (ks *KeyStore) IsLocked() bool {
+	ks.RLock()
+	defer ks.RUnlock()
+
+	return ks.useCrypto && ks.masterKey == nil
+}
+
+// EncryptKeys encrypts all the private keys with the master key and returns
+// them so they can be persisted.  The key store is left unlocked.
+func (ks *KeyStore) EncryptKeys(masterKey []byte) ([]*CryptedKey, error) {
+	ks.Lock()
+	defer ks.Unlock()
+
+	if ks.useCrypto {
+		return nil, ErrKeyStoreCrypted
+	}
+
+	cryptedKeys := make(map[string]*CryptedKey, len(ks.keys))
+	for keyID, keyPair := range ks.keys {
+		secret, err := EncryptSecret(masterKey, keyPair.privateKey.GetBytes(), keyPair.publicKey)
+		if err != nil {
+			return nil, err
+		}
+		cryptedKeys[keyID] = &CryptedKey{PublicKey: keyPair.publicKey, Secret: secret}
+	}
+
+	ks.useCrypto = true
+	ks.cryptedKeys = cryptedKeys
+	ks.masterKey = append([]byte(nil), masterKey...)
+
+	keys := make([]*CryptedKey, 0, len(cryptedKeys))
+	for _, cryptedKey := range cryptedKeys {
+		keys = append(keys, cryptedKey)
+	}
+	return keys, nil
+}
+
+// AddCryptedKey adds an encrypted private key loaded from the wallet
+// database, turning the key store into an encrypted one if needed.
+func (ks *KeyStore) AddCryptedKey(publicKey *PublicKey, secret []byte) error {
+	ks.Lock()
+	defer ks.Unlock()
+
+	if !ks.useCrypto {
+		if len(ks.keys) > 0 {
+			return errors.New("key store holds unencrypted keys")
+		}
+		ks.useCrypto = true
+		ks.cryptedKeys = make(map[string]*CryptedKey)
+	}
+
+	keyPair := &KeyPair{keyID: publicKey.ToHash160(), publicKey: publicKey}
+	if ks.masterKey != nil {
+		privateKey, err := DecryptSecret(ks.masterKey, secret, publicKey)
+		if err != nil {
+			return err
+		}
+		keyPair.privateKey = privateKey
+	}
+	ks.cryptedKeys[keyPair.GetKeyID()] = &CryptedKey{PublicKey: publicKey, Secret: secret}
+	ks.keys[keyPair.GetKeyID()] = keyPair
+	return nil
+}
+
+// AddKeyCrypted encrypts a new private key with the master key and adds it
+// to an unlocked encrypted key store.  It returns the encrypted secret to be
+// persisted.
+func (ks *KeyStore) AddKeyCrypted(privateKey *PrivateKey) ([]byte, error) {
+	ks.Lock()
+	defer ks.Unlock()
+
+	if !ks.useCrypto {
+		return nil, ErrKeyStoreNotCrypted
+	}
+	if ks.masterKey == nil {
+		return nil, ErrKeyStoreLocked
+	}
+
+	keyPair := NewKeyPair(privateKey)
+	secret, err := EncryptSecret(ks.masterKey, privateKey.GetBytes(), keyPair.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	ks.cryptedKeys[keyPair.GetKeyID()] = &CryptedKey{PublicKey: keyPair.publicKey, Secret: secret}
+	ks.keys[keyPair.GetKeyID()] = keyPair
+	return secret, nil
+}
+
+// UnlockKeys decrypts the private keys with the master key.  The master key
+// is checked against every key before any of them becomes available.
+func (ks *KeyStore) UnlockKeys(masterKey []byte) error {
+	ks.Lock()
+	defer ks.Unlock()
+
+	if !ks.useCrypto {
+		return ErrKeyStoreNotCrypted
+	}
+
+	privateKeys := make(map[string]*PrivateKey, len(ks.cryptedKeys))
+	for keyID, cryptedKey := range ks.cryptedKeys {
+		privateKey, err := DecryptSecret(masterKey, cryptedKey.Secret, cryptedKey.PublicKey)
+		if err != nil {
+			return ErrPassphraseMismatch
+		}
+		privateKeys[keyID] = privateKey
+	}
+
+	for keyID, privateKey := range privateKeys {
+		ks.keys[keyID] = &KeyPair{
+			keyID:      []byte(keyID),
+			publicKey:  ks.cryptedKeys[keyID].PublicKey,
+			privateKey: privateKey,
+		}
+	}
+	ks.masterKey = append([]byte(nil), masterKey...)
+	return nil
+}
+
+// LockKeys forgets the master key and the decrypted private keys.
+func (ks *KeyStore) LockKeys() error {
+	ks.Lock()
+	defer ks.Unlock()
+
+	if !ks.useCrypto {
+		return ErrKeyStoreNotCrypted
+	}
+
+	for keyID, keyPair := range ks.keys {
+		ks.keys[keyID] = &KeyPair{keyID: keyPair.keyID, publicKey: keyPair.publicKey}
+	}
+	for i := range ks.masterKey {
+		ks.masterKey[i] = 0
+	}
+	ks.masterKey = nil
+	return nil
+}
+
+// GetMasterKey returns the master key of an unlocked encrypted key store.
+func (ks *KeyStore) GetMasterKey() ([]byte, error) {
+	ks.RLock()
+	defer ks.RUnlock()
+
+	if !ks.useCrypto {
+		return nil, ErrKeyStoreNotCrypted
+	}
+	if ks.masterKey == nil {
+		return nil, ErrKeyStoreLocked
+	}
+	return ks.masterKey, nil
+}
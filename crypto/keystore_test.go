@@ -85,3 +85,56 @@ func TestKeyStore_AddKeyPairs(t *testing.T) {
 	keyStoreNew.AddKeyPairs(keyPairs)
 	assert.Equal(t, keyStore, keyStoreNew)
 }
+
+func TestKeyStore_Encryption(t *testing.T) {
+	privateKey := NewPrivateKeyFromBytes(getTestPrivateKey().GetBytes(), true)
+	publicKey := privateKey.PubKey()
+	keyHash := publicKey.ToHash160()
+	masterKey := make([]byte, WalletCryptoKeySize)
+	masterKey[0] = 1
+
+	keyStore := NewKeyStore()
+	keyStore.AddKey(privateKey)
+	assert.False(t, keyStore.IsCrypted())
+	assert.False(t, keyStore.IsLocked())
+
+	cryptedKeys, err := keyStore.EncryptKeys(masterKey)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(cryptedKeys))
+	assert.True(t, keyStore.IsCrypted())
+	assert.False(t, keyStore.IsLocked())
+	_, err = keyStore.EncryptKeys(masterKey)
+	assert.Equal(t, ErrKeyStoreCrypted, err)
+
+	// Locked key stores only know the public keys.
+	assert.Nil(t, keyStore.LockKeys())
+	assert.True(t, keyStore.IsLocked())
+	keyPair := keyStore.GetKeyPair(keyHash)
+	assert.Equal(t, publicKey, keyPair.GetPublicKey())
+	assert.Nil(t, keyPair.GetPrivateKey())
+	_, err = keyStore.AddKeyCrypted(getTestPrivateKey())
+	assert.Equal(t, ErrKeyStoreLocked, err)
+	_, err = keyStore.GetMasterKey()
+	assert.Equal(t, ErrKeyStoreLocked, err)
+
+	wrongKey := make([]byte, WalletCryptoKeySize)
+	assert.Equal(t, ErrPassphraseMismatch, keyStore.UnlockKeys(wrongKey))
+	assert.True(t, keyStore.IsLocked())
+
+	assert.Nil(t, keyStore.UnlockKeys(masterKey))
+	assert.False(t, keyStore.IsLocked())
+	assert.Equal(t, privateKey, keyStore.GetKeyPair(keyHash).GetPrivateKey())
+
+	// Keys loaded from the database are decrypted on unlock.
+	loaded := NewKeyStore()
+	assert.Nil(t, loaded.AddCryptedKey(cryptedKeys[0].PublicKey, cryptedKeys[0].Secret))
+	assert.True(t, loaded.IsLocked())
+	assert.Nil(t, loaded.GetKeyPair(keyHash).GetPrivateKey())
+	assert.Nil(t, loaded.UnlockKeys(masterKey))
+	assert.Equal(t, privateKey, loaded.GetKeyPair(keyHash).GetPrivateKey())
+
+	plain := NewKeyStore()
+	assert.Equal(t, ErrKeyStoreNotCrypted, plain.UnlockKeys(masterKey))
+	plain.AddKey(privateKey)
+	assert.NotNil(t, plain.AddCryptedKey(cryptedKeys[0].PublicKey, cryptedKeys[0].Secret))
+}
@@ -0,0 +1,192 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/secp256k1-go/secp256k1"
+)
+
+// SchnorrSignatureSize is the length of a BCH Schnorr signature: the
+// x-coordinate of R followed by s, 32 bytes each.
+const SchnorrSignatureSize = 64
+
+var (
+	errSchnorrInvalidPrivateKey = errors.New("schnorr: invalid private key")
+	errSchnorrInvalidHash       = errors.New("schnorr: hash must be 32 bytes")
+	errSchnorrSignFailed        = errors.New("schnorr: signing failed")
+)
+
+// schnorrAlgo16 is the algorithm tag mixed into the RFC6979 nonce derivation,
+// so that a Schnorr nonce never coincides with the ECDSA nonce of the same
+// key and message.
+var schnorrAlgo16 = []byte("Schnorr+SHA256  ")
+
+var (
+	curveP, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	curveN, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+)
+
+// hasSquareY reports whether the y-coordinate of the point is a quadratic
+// residue, which is how BCH Schnorr picks one of the two points sharing R.x.
+// Only public points are given to it.
+func hasSquareY(point *secp256k1.PublicKey) bool {
+	_, serialized, err := secp256k1.EcPubkeySerialize(secp256k1Context, point, secp256k1.EcUncompressed)
+	if err != nil {
+		return false
+	}
+	return big.Jacobi(new(big.Int).SetBytes(serialized[33:65]), curveP) == 1
+}
+
+func bigToBytes32(n *big.Int) []byte {
+	buf := make([]byte, 32)
+	b := n.Bytes()
+	copy(buf[32-len(b):], b)
+	return buf
+}
+
+// schnorrChallenge computes e = sha256(R.x || compressed(P) || m) mod n.
+func schnorrChallenge(rx []byte, pubKey *PublicKey, hash []byte) *big.Int {
+	h := sha256.New()
+	h.Write(rx)
+	h.Write(pubKey.SerializeCompressed())
+	h.Write(hash)
+	e := new(big.Int).SetBytes(h.Sum(nil))
+	return e.Mod(e, curveN)
+}
+
+// rfc6979Nonces returns a generator of the successive RFC6979 HMAC-SHA256
+// nonces for the given key data, as done by libsecp256k1.
+func rfc6979Nonces(keyData []byte) func() []byte {
+	k := make([]byte, 32)
+	v := make([]byte, 32)
+	for i := range v {
+		v[i] = 0x01
+	}
+	hmacSum := func(key []byte, data ...[]byte) []byte {
+		mac := hmac.New(sha256.New, key)
+		for _, d := range data {
+			mac.Write(d)
+		}
+		return mac.Sum(nil)
+	}
+
+	k = hmacSum(k, v, []byte{0x00}, keyData)
+	v = hmacSum(k, v)
+	k = hmacSum(k, v, []byte{0x01}, keyData)
+	v = hmacSum(k, v)
+
+	retry := false
+	return func() []byte {
+		if retry {
+			k = hmacSum(k, v, []byte{0x00})
+			v = hmacSum(k, v)
+		}
+		retry = true
+		v = hmacSum(k, v)
+		return v
+	}
+}
+
+// SignSchnorr creates a 64-byte BCH Schnorr signature of the 32-byte hash.
+// The nonce is derived deterministically with RFC6979, and the operations on
+// the private key and the nonce are done in constant time by libsecp256k1.
+func (privateKey *PrivateKey) SignSchnorr(hash []byte) ([]byte, error) {
+	if len(hash) != 32 {
+		return nil, errSchnorrInvalidHash
+	}
+	if len(privateKey.bytes) != PrivateKeyBytesLen {
+		return nil, errSchnorrInvalidPrivateKey
+	}
+	if _, err := secp256k1.EcSeckeyVerify(secp256k1Context, privateKey.bytes); err != nil {
+		return nil, errSchnorrInvalidPrivateKey
+	}
+	pubKey := privateKey.PubKey()
+
+	keyData := make([]byte, 0, 32+32+len(schnorrAlgo16))
+	keyData = append(keyData, privateKey.bytes...)
+	keyData = append(keyData, hash...)
+	keyData = append(keyData, schnorrAlgo16...)
+	nextNonce := rfc6979Nonces(keyData)
+
+	var k []byte
+	var r *secp256k1.PublicKey
+	for {
+		k = append([]byte(nil), nextNonce()...)
+		var err error
+		if _, r, err = secp256k1.EcPubkeyCreate(secp256k1Context, k); err == nil {
+			break
+		}
+	}
+	if !hasSquareY(r) {
+		secp256k1.EcPrivkeyNegate(secp256k1Context, k)
+	}
+	_, serializedR, err := secp256k1.EcPubkeySerialize(secp256k1Context, r, secp256k1.EcCompressed)
+	if err != nil {
+		return nil, errSchnorrSignFailed
+	}
+	rx := serializedR[1:]
+
+	// s = k + e * d
+	e := bigToBytes32(schnorrChallenge(rx, pubKey, hash))
+	s := make([]byte, PrivateKeyBytesLen)
+	copy(s, privateKey.bytes)
+	if _, err := secp256k1.EcPrivkeyTweakMul(secp256k1Context, s, e); err != nil {
+		return nil, errSchnorrSignFailed
+	}
+	if _, err := secp256k1.EcPrivkeyTweakAdd(secp256k1Context, s, k); err != nil {
+		return nil, errSchnorrSignFailed
+	}
+
+	return append(append([]byte{}, rx...), s...), nil
+}
+
+// VerifySchnorr checks a 64-byte BCH Schnorr signature of hash.
+func (publicKey *PublicKey) VerifySchnorr(hash *util.Hash, vchSig []byte) (bool, error) {
+	if len(vchSig) != SchnorrSignatureSize || !publicKey.isValid() {
+		return false, nil
+	}
+
+	r := new(big.Int).SetBytes(vchSig[:32])
+	s := new(big.Int).SetBytes(vchSig[32:])
+	if r.Cmp(curveP) >= 0 || s.Cmp(curveN) >= 0 {
+		return false, nil
+	}
+
+	// R = sG - eP
+	e := schnorrChallenge(vchSig[:32], publicKey, hash[:])
+	terms := make([]*secp256k1.PublicKey, 0, 2)
+	if s.Sign() != 0 {
+		_, sG, err := secp256k1.EcPubkeyCreate(secp256k1Context, vchSig[32:])
+		if err != nil {
+			return false, nil
+		}
+		terms = append(terms, sG)
+	}
+	if e.Sign() != 0 {
+		_, eP, err := secp256k1.EcPubkeyParse(secp256k1Context, publicKey.SerializeUncompressed())
+		if err != nil {
+			return false, nil
+		}
+		negE := e.Sub(curveN, e)
+		if _, err := secp256k1.EcPubkeyTweakMul(secp256k1Context, eP, bigToBytes32(negE)); err != nil {
+			return false, nil
+		}
+		terms = append(terms, eP)
+	}
+	if len(terms) == 0 {
+		return false, nil
+	}
+	_, rPoint, err := secp256k1.EcPubkeyCombine(secp256k1Context, terms)
+	if err != nil || !hasSquareY(rPoint) {
+		return false, nil
+	}
+	_, serializedR, err := secp256k1.EcPubkeySerialize(secp256k1Context, rPoint, secp256k1.EcCompressed)
+	if err != nil {
+		return false, nil
+	}
+	return new(big.Int).SetBytes(serializedR[1:]).Cmp(r) == 0, nil
+}
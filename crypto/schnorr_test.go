@@ -0,0 +1,132 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/copernet/copernicus/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid hex %s: %v", s, err)
+	}
+	return b
+}
+
+// test vectors from the BCH 2019-05-15 Schnorr specification
+var schnorrVerifyTests = []struct {
+	name   string
+	pubKey string
+	msg    string
+	sig    string
+	valid  bool
+}{
+	{
+		name:   "vector 1",
+		pubKey: "0279BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798",
+		msg:    "0000000000000000000000000000000000000000000000000000000000000000",
+		sig: "787A848E71043D280C50470E8E1532B2DD5D20EE912A45DBDD2BD1DFBF187EF6" +
+			"7031A98831859DC34DFFEEDDA86831842CCD0079E1F92AF177F7F22CC1DCED05",
+		valid: true,
+	},
+	{
+		name:   "vector 2",
+		pubKey: "02DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		sig: "2A298DACAE57395A15D0795DDBFD1DCB564DA82B0F269BC70A74F8220429BA1D" +
+			"1E51A22CCEC35599B8F266912281F8365FFC2D035A230434A1A64DC59F7013FD",
+		valid: true,
+	},
+	{
+		name:   "vector 3",
+		pubKey: "03FAC2114C2FBB091527EB7C64ECB11F8021CB45E8E7809D3C0938E4B8C0E5F84B",
+		msg:    "5E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C",
+		sig: "00DA9B08172A9B6F0466A2DEFD817F2D7AB437E0D253CB5395A963866B3574BE" +
+			"00880371D01766935B92D2AB4CD5C8A2A5837EC57FED7660773A05F0DE142380",
+		valid: true,
+	},
+	{
+		name:   "negated message",
+		pubKey: "02DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		sig: "2A298DACAE57395A15D0795DDBFD1DCB564DA82B0F269BC70A74F8220429BA1D" +
+			"FA16AEE06609280A19B67A24E1977E4697712B5FD2943914ECD5F730901B4AB7",
+		valid: false,
+	},
+	{
+		name:   "r is p",
+		pubKey: "02DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		sig: "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F" +
+			"1E51A22CCEC35599B8F266912281F8365FFC2D035A230434A1A64DC59F7013FD",
+		valid: false,
+	},
+	{
+		name:   "s is n",
+		pubKey: "02DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		sig: "2A298DACAE57395A15D0795DDBFD1DCB564DA82B0F269BC70A74F8220429BA1D" +
+			"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141",
+		valid: false,
+	},
+}
+
+func TestVerifySchnorr(t *testing.T) {
+	InitSecp256()
+	for _, test := range schnorrVerifyTests {
+		pubKey, err := ParsePubKey(mustDecodeHex(t, test.pubKey))
+		assert.Nil(t, err, test.name)
+
+		var hash util.Hash
+		copy(hash[:], mustDecodeHex(t, test.msg))
+		ok, err := pubKey.VerifySchnorr(&hash, mustDecodeHex(t, test.sig))
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.valid, ok, test.name)
+	}
+}
+
+func TestSignSchnorr(t *testing.T) {
+	InitSecp256()
+	keyBytes := mustDecodeHex(t, "B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF")
+	for _, compressed := range []bool{true, false} {
+		privKey := NewPrivateKeyFromBytes(keyBytes, compressed)
+		pubKey := privKey.PubKey()
+		hash := util.Sha256Hash([]byte("schnorr"))
+
+		sig, err := privKey.SignSchnorr(hash[:])
+		assert.Nil(t, err)
+		assert.Equal(t, SchnorrSignatureSize, len(sig))
+
+		ok, _ := pubKey.VerifySchnorr(&hash, sig)
+		assert.True(t, ok)
+
+		// signing is deterministic
+		sig2, _ := privKey.SignSchnorr(hash[:])
+		assert.Equal(t, sig, sig2)
+
+		other := util.Sha256Hash([]byte("other"))
+		ok, _ = pubKey.VerifySchnorr(&other, sig)
+		assert.False(t, ok)
+
+		// an ECDSA signature is never a valid Schnorr signature
+		ecdsaSig, _ := privKey.Sign(hash[:])
+		ok, _ = pubKey.VerifySchnorr(&hash, ecdsaSig.Serialize())
+		assert.False(t, ok)
+	}
+
+	// the deterministic signature of the reference implementation
+	hash := util.DoubleSha256Hash([]byte("Very deterministic message"))
+	privKey := NewPrivateKeyFromBytes(mustDecodeHex(t, "12b004fff7f4b69ef8650e767f18f11ede158148b425660723b9f9a66e61f747"), true)
+	sig, err := privKey.SignSchnorr(hash[:])
+	assert.Nil(t, err)
+	assert.Equal(t, "2c56731ac2f7a7e7f11518fc7722a166b02438924ca9d8b4d111347b81d0717571846de67ad3d913a8fdf9d8f3f73161a4c48ae81cb183b214765feb86e255ce",
+		hex.EncodeToString(sig))
+
+	_, err = NewPrivateKeyFromBytes(make([]byte, 32), true).SignSchnorr(make([]byte, 32))
+	assert.NotNil(t, err)
+	_, err = NewPrivateKeyFromBytes(keyBytes, true).SignSchnorr(make([]byte, 20))
+	assert.NotNil(t, err)
+}
@@ -78,6 +78,24 @@ const (
 	ScriptErrIllegalForkID
 	ScriptErrMustUseForkID
 
+	/* Schnorr signatures and the CHECKMULTISIG bitfield */
+
+	ScriptErrSigBadLength
+	ScriptErrSigNonSchnorr
+	ScriptErrInvalidBitfieldSize
+	ScriptErrInvalidBitRange
+	ScriptErrInvalidBitCount
+
+	/* SigChecks limits */
+
+	ScriptErrInputSigChecks
+
+	/* Native introspection */
+
+	ScriptErrContextNotPresent
+	ScriptErrInvalidTxInputIndex
+	ScriptErrInvalidTxOutputIndex
+
 	ScriptErrErrorCount
 
 	// ScriptErrSize other errcode
@@ -196,6 +214,24 @@ func scriptErrorString(scriptError ScriptErr) string {
 		return "Signature must be zero for failed CHECK(MULTI)SIG operation"
 	case ScriptErrIllegalForkID:
 		return "Illegal use of SIGHASH_FORKID"
+	case ScriptErrSigBadLength:
+		return "Signature cannot be 65 bytes in CHECKMULTISIG"
+	case ScriptErrSigNonSchnorr:
+		return "Only Schnorr signatures allowed in this operation"
+	case ScriptErrInvalidBitfieldSize:
+		return "Bitfield of unexpected size error"
+	case ScriptErrInvalidBitRange:
+		return "Bitfield's bit out of the expected range"
+	case ScriptErrInvalidBitCount:
+		return "Bitfield's bit count mismatch"
+	case ScriptErrInputSigChecks:
+		return "Input SigChecks limit exceeded"
+	case ScriptErrContextNotPresent:
+		return "Tried to use a script context-dependent opcode but no context is available"
+	case ScriptErrInvalidTxInputIndex:
+		return "Specified transaction input index is out of range"
+	case ScriptErrInvalidTxOutputIndex:
+		return "Specified transaction output index is out of range"
 	case ScriptErrDiscourageUpgradableNops:
 		return "NOPx reserved for soft-fork upgrades"
 	case ScriptErrDiscourageUpgradableWitnessProgram:
@@ -61,6 +61,18 @@ func TestScriptErr_String(t *testing.T) {
 		// ScriptErrIllegalForkID anti replay
 		{ScriptErrIllegalForkID, "Illegal use of SIGHASH_FORKID"},
 		{ScriptErrMustUseForkID, "unknown error"},
+		/* Schnorr signatures and the CHECKMULTISIG bitfield */
+		{ScriptErrSigBadLength, "Signature cannot be 65 bytes in CHECKMULTISIG"},
+		{ScriptErrSigNonSchnorr, "Only Schnorr signatures allowed in this operation"},
+		{ScriptErrInvalidBitfieldSize, "Bitfield of unexpected size error"},
+		{ScriptErrInvalidBitRange, "Bitfield's bit out of the expected range"},
+		{ScriptErrInvalidBitCount, "Bitfield's bit count mismatch"},
+		/* SigChecks limits */
+		{ScriptErrInputSigChecks, "Input SigChecks limit exceeded"},
+		/* Native introspection */
+		{ScriptErrContextNotPresent, "Tried to use a script context-dependent opcode but no context is available"},
+		{ScriptErrInvalidTxInputIndex, "Specified transaction input index is out of range"},
+		{ScriptErrInvalidTxOutputIndex, "Specified transaction output index is out of range"},
 		{ScriptErrErrorCount, "unknown error"},
 		// ScriptErrSize other errcode
 		{ScriptErrSize, "unknown error"},
@@ -7,14 +7,18 @@ import (
 	"github.com/copernet/copernicus/crypto"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/logic/lchain"
+	"github.com/copernet/copernicus/logic/lmempool"
 	"github.com/copernet/copernicus/logic/lreindex"
 	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/logic/ltxindex"
+	"github.com/copernet/copernicus/logic/lwallet"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/mempool"
 	"github.com/copernet/copernicus/model/pow"
 	"github.com/copernet/copernicus/model/utxo"
 	"github.com/copernet/copernicus/model/wallet"
+	"github.com/copernet/copernicus/net/zmq"
 	"github.com/copernet/copernicus/persist"
 	"github.com/copernet/copernicus/persist/blkdb"
 	"github.com/copernet/copernicus/persist/db"
@@ -35,6 +39,10 @@ func appInitMain(args []string) {
 	} else if conf.Cfg.P2PNet.RegTest {
 		model.SetRegTestParams()
 	}
+	if err := model.InitUpgradeActivations(conf.Cfg.Chain.UpgradeActivations); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
 	pow.UpdateMinimumChainWork()
 
 	fmt.Println("Current data dir:\033[0;32m", conf.DataDir, "\033[0m")
@@ -83,6 +91,11 @@ func appInitMain(args []string) {
 	btd := blkdb.GetInstance()
 	persist.InitPersistGlobal(btd)
 
+	if err := disk.InitPruneState(conf.Cfg.Chain.Prune); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
 	if !chain.InitGlobalChain(btd) {
 		return
 	}
@@ -96,6 +109,7 @@ func appInitMain(args []string) {
 	}
 
 	mempool.InitMempool()
+	mempool.InitFeeEstimator()
 	crypto.InitSecp256()
 
 	wallet.InitWallet()
@@ -122,4 +136,16 @@ func appInitMain(args []string) {
     tip block index: %s
 ---------------------`, gChain.Height(), gChain.IndexMapSize(), gChain.Tip().String())
 	}
+
+	ltxindex.Init()
+	lwallet.Init()
+
+	if err := zmq.Init(); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	// Restore the mempool saved on shutdown, in the background as it
+	// validates every tx again.
+	go lmempool.LoadMempool()
 }
@@ -107,12 +107,7 @@ func CheckBlock(pblock *block.Block, checkHeader, checkMerlke bool) error {
 		return errcode.NewError(errcode.RejectInvalid, "bad-blk-length")
 	}
 
-	nMaxBlockSigOps, errSig := consensus.GetMaxBlockSigOpsCount(uint64(currentBlockSize))
-	if errSig != nil {
-		return errSig
-	}
-
-	err := ltx.CheckBlockTransactions(pblock.Txs, nMaxBlockSigOps)
+	err := ltx.CheckBlockTransactions(pblock.Txs)
 	if err != nil {
 		log.Debug("ErrorBadBlkTx: %v", err)
 		return err
@@ -148,7 +143,23 @@ func ContextualCheckBlock(b *block.Block, indexPrev *blockindex.BlockIndex) erro
 	// Enforce rule that the coinBase starts with serialized lblock height
 	err := ltx.ContextureCheckBlockTransactions(b.Txs, height, lockTimeCutoff,
 		mediaTimePast)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// The legacy sigops are limited until the phonon fork, the sigchecks
+	// are then limited while connecting the block.
+	if !model.ActiveNetParams.IsRuleEnabled(consensus.RuleSigChecks, height, mediaTimePast) {
+		nMaxBlockSigOps, err := consensus.GetMaxBlockSigOpsCount(uint64(b.EncodeSize()))
+		if err != nil {
+			return err
+		}
+		if err := ltx.CheckBlockSigOps(b.Txs, nMaxBlockSigOps); err != nil {
+			log.Debug("ErrorBadBlkSigOps: %v", err)
+			return err
+		}
+	}
+	return nil
 }
 
 // ReceivedBlockTransactions Mark a lblock as having its data received and checked (up to
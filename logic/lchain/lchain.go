@@ -19,6 +19,7 @@ import (
 
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/logic/ltxindex"
 	"github.com/copernet/copernicus/logic/lundo"
 
 	"github.com/copernet/copernicus/model/undo"
@@ -299,6 +300,8 @@ func ConnectTip(pIndexNew *blockindex.BlockIndex,
 	log.Print("bench", "debug", " - Writing chainstate: %.2fms [%.2fs]\n",
 		float64(nTime5-nTime4)*0.001, float64(gPersist.GlobalTimeChainState)*0.000001)
 
+	ltxindex.BlockConnected(blockConnecting, pIndexNew)
+
 	// Remove conflicting transactions from the mempool.;
 	mem.RemoveTxSelf(blockConnecting.Txs)
 	// Update chainActive & related variables.
@@ -347,6 +350,7 @@ func DisconnectTip(fBare bool) error {
 		}
 		utxo.GetUtxoCacheInstance().Flush()
 	}
+	ltxindex.BlockDisconnected(blk, tip)
 	// replace implement with log.Print(in C++).
 	log.Info("bench-debug - Disconnect block : %.2fms\n",
 		float64(time.Now().UnixNano()-nStart)*0.001)
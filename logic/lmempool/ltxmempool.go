@@ -9,6 +9,7 @@ import (
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lblock"
 	"github.com/copernet/copernicus/logic/ltx"
 	//"github.com/copernet/copernicus/model/consensus"
 	"github.com/copernet/copernicus/model/mempool"
@@ -27,6 +28,18 @@ func AcceptTxToMemPool(txn *tx.Tx) error {
 	return addTxToMemPool(txEntry)
 }
 
+// AcceptTxToMemPoolWithTime is AcceptTxToMemPool keeping acceptTime as the
+// time the tx entered the mempool, for txs restored from the mempool file.
+func AcceptTxToMemPoolWithTime(txn *tx.Tx, acceptTime int64) error {
+	txEntry, err := ltx.CheckTxBeforeAcceptToMemPool(txn)
+	if err != nil {
+		return err
+	}
+	txEntry.SetTime(acceptTime)
+
+	return addTxToMemPool(txEntry)
+}
+
 func addTxToMemPool(txe *mempool.TxEntry) error {
 	pool := mempool.GetInstance()
 
@@ -50,9 +63,18 @@ func addTxToMemPool(txe *mempool.TxEntry) error {
 		return err
 	}
 
+	pool.SendTxAccepted(txe)
+
+	// A tx spending mempool txs may be mined for the fees of its package
+	// rather than its own, so it is left out of fee estimation.
+	if feeEstimator := mempool.GetFeeEstimator(); feeEstimator != nil {
+		validFeeEstimate := len(ancestors) == 0 && !lblock.IsInitialBlockDownload()
+		feeEstimator.ProcessTransaction(txe, validFeeEstimate)
+	}
+
 	// TODO: simple implementation just for testing, remove this after complete wallet
-	if wallet.GetInstance().IsEnable() {
-		wallet.GetInstance().HandleRelatedMempoolTx(txe.Tx)
+	for _, w := range wallet.GetWallets() {
+		w.HandleRelatedMempoolTx(txe.Tx)
 	}
 	return nil
 }
@@ -289,10 +311,12 @@ func CheckMempool(bestHeight int32) {
 		nCountCheck := int64(len(setAncestors)) + 1
 		nSizeCheck := int64(entry.TxSize)
 		nSigOpCheck := int64(entry.SigOpCount)
+		nSigChecksCheck := int64(entry.SigChecks)
 		nFeesCheck := entry.TxFee
 		for ancestorIt := range setAncestors {
 			nSizeCheck += int64(ancestorIt.TxSize)
 			nSigOpCheck += int64(ancestorIt.SigOpCount)
+			nSigChecksCheck += int64(ancestorIt.SigChecks)
 			nFeesCheck += ancestorIt.TxFee
 		}
 		if entry.SumTxCountWithAncestors != nCountCheck {
@@ -311,6 +335,11 @@ func CheckMempool(bestHeight int32) {
 				entry.SumTxSigOpCountWithAncestors, nSigOpCheck)
 			entry.SumTxSigOpCountWithAncestors = nSigOpCheck
 		}
+		if entry.SumTxSigChecksWithAncestors != nSigChecksCheck {
+			log.Error("the txentry's ancestors sigchecks is incorrect: entry.SumTxSigChecksWithAncestors(%d), nSigChecksCheck(%d)",
+				entry.SumTxSigChecksWithAncestors, nSigChecksCheck)
+			entry.SumTxSigChecksWithAncestors = nSigChecksCheck
+		}
 		if entry.SumTxFeeWithAncestors != nFeesCheck {
 			log.Error("the txentry's ancestors feew is incorrect: entry.SumTxFeeWithAncestors(%d), nFeesCheck(%d)",
 				entry.SumTxFeeWithAncestors, nFeesCheck)
@@ -423,3 +452,15 @@ func FindOrphanTxInMemPool(hash util.Hash) *tx.Tx {
 
 	return nil
 }
+
+func GetOrphanTxs() []*tx.Tx {
+	pool := mempool.GetInstance()
+	pool.RLock()
+	defer pool.RUnlock()
+	txs := make([]*tx.Tx, 0, len(pool.OrphanTransactions))
+	for _, orphan := range pool.OrphanTransactions {
+		txs = append(txs, orphan.Tx)
+	}
+
+	return txs
+}
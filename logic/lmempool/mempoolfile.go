@@ -0,0 +1,202 @@
+package lmempool
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	// MempoolFileName is the file in the data directory the mempool is
+	// saved to across restarts.
+	MempoolFileName = "mempool.dat"
+
+	mempoolFileVersion uint64 = 1
+)
+
+// mempoolFileTx is a mempool tx as saved in the mempool file. The mempool has
+// no fee deltas yet, the field keeps the file format and is saved as zero.
+type mempoolFileTx struct {
+	tx       *tx.Tx
+	time     int64
+	feeDelta int64
+}
+
+// LoadMempool restores the mempool saved on shutdown when mempool persistence
+// is enabled, then marks the mempool as loaded.
+func LoadMempool() {
+	defer mempool.GetInstance().SetLoaded(true)
+
+	if !conf.Cfg.Mempool.PersistMempool {
+		return
+	}
+	if err := loadMempool(filepath.Join(conf.DataDir, MempoolFileName)); err != nil {
+		log.Warn("mempool: read %s failed: %v", MempoolFileName, err)
+	}
+}
+
+// DumpMempool saves the mempool txs to the data directory.
+func DumpMempool() error {
+	return dumpMempool(filepath.Join(conf.DataDir, MempoolFileName))
+}
+
+// FlushMempool saves the mempool on shutdown when mempool persistence is
+// enabled. Nothing is saved if the mempool was not loaded yet, not to replace
+// the file with a partial mempool.
+func FlushMempool() {
+	if !conf.Cfg.Mempool.PersistMempool || !mempool.GetInstance().IsLoaded() {
+		return
+	}
+	if err := DumpMempool(); err != nil {
+		log.Error("mempool: write %s failed: %v", MempoolFileName, err)
+	}
+}
+
+func loadMempool(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	txs, err := readMempool(bufio.NewReader(file))
+	if err != nil {
+		return err
+	}
+
+	pool := mempool.GetInstance()
+	expiry := int64(conf.Cfg.Mempool.MaxPoolExpiry) * 60 * 60
+	now := util.GetTimeSec()
+	var succeeded, failed, expired, alreadyThere int
+	for _, ftx := range txs {
+		if ftx.time+expiry <= now {
+			expired++
+			continue
+		}
+		if pool.FindTx(ftx.tx.GetHash()) != nil {
+			alreadyThere++
+			continue
+		}
+
+		persist.CsMain.Lock()
+		err := AcceptTxToMemPoolWithTime(ftx.tx, ftx.time)
+		persist.CsMain.Unlock()
+		if err != nil {
+			log.Debug("mempool: tx %s from %s rejected: %v", ftx.tx.GetHash(), MempoolFileName, err)
+			failed++
+			continue
+		}
+		succeeded++
+	}
+
+	log.Info("Imported mempool transactions from disk: %d succeeded, %d failed, %d expired, %d already there",
+		succeeded, failed, expired, alreadyThere)
+	return nil
+}
+
+func dumpMempool(path string) error {
+	pool := mempool.GetInstance()
+	pool.RLock()
+	entries := pool.GetAllTxEntryWithoutLock()
+	txs := make([]*mempoolFileTx, 0, len(entries))
+	ancestors := make(map[*tx.Tx]int64, len(entries))
+	for _, entry := range entries {
+		txs = append(txs, &mempoolFileTx{tx: entry.Tx, time: entry.GetTime()})
+		ancestors[entry.Tx] = entry.SumTxCountWithAncestors
+	}
+	pool.RUnlock()
+
+	// A tx has more ancestors than its parents, so that the parents are
+	// accepted first when the file is loaded.
+	sort.SliceStable(txs, func(i, j int) bool {
+		return ancestors[txs[i].tx] < ancestors[txs[j].tx]
+	})
+
+	tmpPath := path + ".new"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	err = writeMempool(w, txs)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func writeMempool(w io.Writer, txs []*mempoolFileTx) error {
+	if err := util.WriteElements(w, mempoolFileVersion, uint64(len(txs))); err != nil {
+		return err
+	}
+	for _, ftx := range txs {
+		if err := ftx.tx.Serialize(w); err != nil {
+			return err
+		}
+		if err := util.WriteElements(w, ftx.time, ftx.feeDelta); err != nil {
+			return err
+		}
+	}
+
+	// fee deltas of txs which are not in the mempool
+	return util.WriteVarInt(w, 0)
+}
+
+func readMempool(r io.Reader) ([]*mempoolFileTx, error) {
+	var version, count uint64
+	if err := util.ReadElements(r, &version, &count); err != nil {
+		return nil, err
+	}
+	if version != mempoolFileVersion {
+		return nil, fmt.Errorf("unsupported mempool file version %d", version)
+	}
+
+	txs := make([]*mempoolFileTx, 0)
+	for i := uint64(0); i < count; i++ {
+		ftx := &mempoolFileTx{tx: tx.NewTx(0, tx.DefaultVersion)}
+		if err := ftx.tx.Unserialize(r); err != nil {
+			return nil, err
+		}
+		if err := util.ReadElements(r, &ftx.time, &ftx.feeDelta); err != nil {
+			return nil, err
+		}
+		txs = append(txs, ftx)
+	}
+
+	deltas, err := util.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < deltas; i++ {
+		var hash util.Hash
+		var feeDelta int64
+		if err := util.ReadElements(r, &hash, &feeDelta); err != nil {
+			return nil, err
+		}
+	}
+
+	return txs, nil
+}
@@ -0,0 +1,58 @@
+package lmempool
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMempoolFileTx(prevHash util.Hash, time int64) *mempoolFileTx {
+	txn := tx.NewTx(0, tx.DefaultVersion)
+	txn.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(prevHash, 0), script.NewEmptyScript(), 0xffffffff))
+	txn.AddTxOut(txout.NewTxOut(1000, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+	return &mempoolFileTx{tx: txn, time: time}
+}
+
+func TestMempoolFileRoundTrip(t *testing.T) {
+	parent := newMempoolFileTx(util.Hash{1}, 1546300800)
+	child := newMempoolFileTx(parent.tx.GetHash(), 1546300900)
+	txs := []*mempoolFileTx{parent, child}
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeMempool(&buf, txs))
+
+	got, err := readMempool(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, len(txs), len(got))
+	for i := range txs {
+		assert.Equal(t, txs[i].tx.GetHash(), got[i].tx.GetHash())
+		assert.Equal(t, txs[i].time, got[i].time)
+		assert.Equal(t, int64(0), got[i].feeDelta)
+	}
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestMempoolFileBadVersion(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, util.WriteElements(&buf, mempoolFileVersion+1, uint64(0)))
+	assert.NoError(t, util.WriteVarInt(&buf, 0))
+
+	_, err := readMempool(&buf)
+	assert.Error(t, err)
+}
+
+func TestMempoolFileTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, writeMempool(&buf, []*mempoolFileTx{newMempoolFileTx(util.Hash{1}, 1546300800)}))
+
+	_, err := readMempool(bytes.NewReader(buf.Bytes()[:buf.Len()-4]))
+	assert.Error(t, err)
+}
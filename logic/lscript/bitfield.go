@@ -0,0 +1,35 @@
+package lscript
+
+import (
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/log"
+)
+
+// decodeBitfield decodes the little-endian bitfield used as the dummy element
+// of a Schnorr CHECKMULTISIG. It must be exactly (size+7)/8 bytes long and
+// must not set any bit at or above size.
+func decodeBitfield(vch []byte, size int32) (uint32, error) {
+	if size > 32 {
+		log.Debug("ScriptErrInvalidBitfieldSize")
+		return 0, errcode.New(errcode.ScriptErrInvalidBitfieldSize)
+	}
+
+	bitfieldSize := (int(size) + 7) / 8
+	if len(vch) != bitfieldSize {
+		log.Debug("ScriptErrInvalidBitfieldSize")
+		return 0, errcode.New(errcode.ScriptErrInvalidBitfieldSize)
+	}
+
+	var bitfield uint32
+	for i := 0; i < bitfieldSize; i++ {
+		bitfield |= uint32(vch[i]) << uint(8*i)
+	}
+
+	mask := uint32((uint64(1) << uint(size)) - 1)
+	if bitfield&mask != bitfield {
+		log.Debug("ScriptErrInvalidBitRange")
+		return 0, errcode.New(errcode.ScriptErrInvalidBitRange)
+	}
+
+	return bitfield, nil
+}
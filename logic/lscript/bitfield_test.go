@@ -0,0 +1,43 @@
+package lscript
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/errcode"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeBitfield(t *testing.T) {
+	tests := []struct {
+		vch      []byte
+		size     int32
+		bitfield uint32
+		err      errcode.ScriptErr
+	}{
+		{[]byte{}, 0, 0, errcode.ScriptErrOK},
+		{[]byte{0x00}, 0, 0, errcode.ScriptErrInvalidBitfieldSize},
+		{[]byte{0x01}, 1, 0x01, errcode.ScriptErrOK},
+		{[]byte{0x02}, 1, 0, errcode.ScriptErrInvalidBitRange},
+		{[]byte{0x05}, 3, 0x05, errcode.ScriptErrOK},
+		{[]byte{0x08}, 3, 0, errcode.ScriptErrInvalidBitRange},
+		{[]byte{0xff}, 8, 0xff, errcode.ScriptErrOK},
+		{[]byte{0xff}, 9, 0, errcode.ScriptErrInvalidBitfieldSize},
+		{[]byte{0xff, 0x01}, 9, 0x01ff, errcode.ScriptErrOK},
+		{[]byte{0xff, 0x02}, 9, 0, errcode.ScriptErrInvalidBitRange},
+		{[]byte{0x01, 0x00, 0x00}, 20, 0x01, errcode.ScriptErrOK},
+		{[]byte{0x00, 0x00, 0x10}, 20, 0, errcode.ScriptErrInvalidBitRange},
+		{[]byte{0x00, 0x00, 0x08}, 20, 0x080000, errcode.ScriptErrOK},
+		{[]byte{0xff, 0xff, 0xff, 0xff}, 32, 0xffffffff, errcode.ScriptErrOK},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0x00}, 33, 0, errcode.ScriptErrInvalidBitfieldSize},
+	}
+
+	for i, test := range tests {
+		bitfield, err := decodeBitfield(test.vch, test.size)
+		if test.err == errcode.ScriptErrOK {
+			assert.Nil(t, err, "test %d", i)
+			assert.Equal(t, test.bitfield, bitfield, "test %d", i)
+		} else {
+			assert.True(t, errcode.IsErrorCode(err, test.err), "test %d", i)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package lscript
+
+import (
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/util"
+)
+
+// evalIntrospection executes a native or token introspection opcode.
+// activeBytecode is the script being executed from its last OP_CODESEPARATOR,
+// and spentOutputs, when known, are the outputs spent by every input of
+// transaction.
+func evalIntrospection(stack *util.Stack, opcode byte, activeBytecode []byte, transaction *tx.Tx, nIn int,
+	spentOutputs []*txout.TxOut, fRequireMinimal bool, maxNumSize int) error {
+	if transaction == nil {
+		log.Debug("ScriptErrContextNotPresent")
+		return errcode.New(errcode.ScriptErrContextNotPresent)
+	}
+
+	switch opcode {
+	case opcodes.OP_INPUTINDEX:
+		pushScriptNum(stack, int64(nIn))
+		return nil
+	case opcodes.OP_ACTIVEBYTECODE:
+		return pushBytecode(stack, activeBytecode)
+	case opcodes.OP_TXVERSION:
+		pushScriptNum(stack, int64(transaction.GetVersion()))
+		return nil
+	case opcodes.OP_TXINPUTCOUNT:
+		pushScriptNum(stack, int64(transaction.GetInsCount()))
+		return nil
+	case opcodes.OP_TXOUTPUTCOUNT:
+		pushScriptNum(stack, int64(transaction.GetOutsCount()))
+		return nil
+	case opcodes.OP_TXLOCKTIME:
+		pushScriptNum(stack, int64(transaction.GetLockTime()))
+		return nil
+	}
+
+	// The other opcodes take the index of an input or an output.
+	if stack.Size() < 1 {
+		log.Debug("ScriptErrInvalidStackOperation")
+		return errcode.New(errcode.ScriptErrInvalidStackOperation)
+	}
+	indexNum, err := script.GetScriptNum(stack.Top(-1).([]byte), fRequireMinimal, maxNumSize)
+	if err != nil {
+		return err
+	}
+	index := indexNum.Value
+	stack.Pop()
+
+	switch opcode {
+	case opcodes.OP_UTXOVALUE, opcodes.OP_UTXOBYTECODE, opcodes.OP_OUTPOINTTXHASH,
+		opcodes.OP_OUTPOINTINDEX, opcodes.OP_INPUTBYTECODE, opcodes.OP_INPUTSEQUENCENUMBER,
+		opcodes.OP_UTXOTOKENCATEGORY, opcodes.OP_UTXOTOKENCOMMITMENT, opcodes.OP_UTXOTOKENAMOUNT:
+		if index < 0 || index >= int64(transaction.GetInsCount()) {
+			log.Debug("ScriptErrInvalidTxInputIndex")
+			return errcode.New(errcode.ScriptErrInvalidTxInputIndex)
+		}
+		txIn := transaction.GetIns()[index]
+
+		switch opcode {
+		case opcodes.OP_UTXOVALUE, opcodes.OP_UTXOBYTECODE,
+			opcodes.OP_UTXOTOKENCATEGORY, opcodes.OP_UTXOTOKENCOMMITMENT, opcodes.OP_UTXOTOKENAMOUNT:
+			if len(spentOutputs) != transaction.GetInsCount() || spentOutputs[index] == nil {
+				log.Debug("ScriptErrContextNotPresent")
+				return errcode.New(errcode.ScriptErrContextNotPresent)
+			}
+			spent := spentOutputs[index]
+			switch opcode {
+			case opcodes.OP_UTXOVALUE:
+				pushScriptNum(stack, int64(spent.GetValue()))
+				return nil
+			case opcodes.OP_UTXOBYTECODE:
+				return pushBytecode(stack, spent.GetScriptPubKey().GetData())
+			}
+			pushTokenData(stack, opcode, spent.GetTokenData())
+		case opcodes.OP_OUTPOINTTXHASH:
+			hash := txIn.PreviousOutPoint.Hash
+			stack.Push(hash.GetCloneBytes())
+		case opcodes.OP_OUTPOINTINDEX:
+			pushScriptNum(stack, int64(txIn.PreviousOutPoint.Index))
+		case opcodes.OP_INPUTBYTECODE:
+			return pushBytecode(stack, txIn.GetScriptSig().GetData())
+		case opcodes.OP_INPUTSEQUENCENUMBER:
+			pushScriptNum(stack, int64(txIn.Sequence))
+		}
+		return nil
+
+	case opcodes.OP_OUTPUTVALUE, opcodes.OP_OUTPUTBYTECODE,
+		opcodes.OP_OUTPUTTOKENCATEGORY, opcodes.OP_OUTPUTTOKENCOMMITMENT, opcodes.OP_OUTPUTTOKENAMOUNT:
+		if index < 0 || index >= int64(transaction.GetOutsCount()) {
+			log.Debug("ScriptErrInvalidTxOutputIndex")
+			return errcode.New(errcode.ScriptErrInvalidTxOutputIndex)
+		}
+		txOut := transaction.GetOuts()[index]
+		switch opcode {
+		case opcodes.OP_OUTPUTVALUE:
+			pushScriptNum(stack, int64(txOut.GetValue()))
+			return nil
+		case opcodes.OP_OUTPUTBYTECODE:
+			return pushBytecode(stack, txOut.GetScriptPubKey().GetData())
+		}
+		pushTokenData(stack, opcode, txOut.GetTokenData())
+		return nil
+	}
+
+	log.Debug("ScriptErrBadOpCode")
+	return errcode.New(errcode.ScriptErrBadOpCode)
+}
+
+// pushTokenData pushes the field of tokenData read by a token introspection
+// opcode. Outputs without token data have an empty category and commitment and
+// a zero amount. The capability of a mutable or minting NFT is appended to its
+// category.
+func pushTokenData(stack *util.Stack, opcode byte, tokenData *txout.TokenData) {
+	switch opcode {
+	case opcodes.OP_UTXOTOKENCATEGORY, opcodes.OP_OUTPUTTOKENCATEGORY:
+		if tokenData == nil {
+			stack.Push([]byte{})
+			return
+		}
+		category := tokenData.Category.GetCloneBytes()
+		if tokenData.IsMutableNFT() || tokenData.IsMintingNFT() {
+			category = append(category, tokenData.Capability)
+		}
+		stack.Push(category)
+	case opcodes.OP_UTXOTOKENCOMMITMENT, opcodes.OP_OUTPUTTOKENCOMMITMENT:
+		if tokenData == nil {
+			stack.Push([]byte{})
+			return
+		}
+		stack.Push(append([]byte{}, tokenData.Commitment...))
+	default:
+		var amount int64
+		if tokenData != nil {
+			amount = tokenData.Amount
+		}
+		pushScriptNum(stack, amount)
+	}
+}
+
+func pushScriptNum(stack *util.Stack, v int64) {
+	stack.Push(script.NewScriptNum(v).Serialize())
+}
+
+// pushBytecode pushes a copy of bytecode, as stack elements may be modified in
+// place by the bitwise opcodes.
+func pushBytecode(stack *util.Stack, bytecode []byte) error {
+	if len(bytecode) > script.MaxScriptElementSize {
+		log.Debug("ScriptErrPushSize")
+		return errcode.New(errcode.ScriptErrPushSize)
+	}
+	stack.Push(append([]byte{}, bytecode...))
+	return nil
+}
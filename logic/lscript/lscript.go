@@ -2,18 +2,49 @@ package lscript
 
 import (
 	"bytes"
+	"math/bits"
+
 	"github.com/copernet/copernicus/crypto"
 	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
 )
 
+// ScriptExecutionMetrics holds the metrics gathered while executing the
+// scripts of an input.
+type ScriptExecutionMetrics struct {
+	// SigChecks is the number of signature checks done, the checks of null
+	// signatures excluded.
+	SigChecks int
+}
+
 func VerifyScript(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *script.Script,
 	nIn int, value amount.Amount, flags uint32, scriptChecker Checker) error {
+	return VerifyScriptWithMetrics(transaction, scriptSig, scriptPubKey, nIn, value, flags, scriptChecker, nil)
+}
+
+// VerifyScriptWithMetrics is VerifyScript, also adding the sigchecks of the
+// input to metrics when it is not nil.
+func VerifyScriptWithMetrics(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *script.Script,
+	nIn int, value amount.Amount, flags uint32, scriptChecker Checker, metrics *ScriptExecutionMetrics) error {
+	return VerifyScriptWithContext(transaction, scriptSig, scriptPubKey, nIn, value, flags, scriptChecker,
+		nil, metrics)
+}
+
+// VerifyScriptWithContext is VerifyScriptWithMetrics, with the outputs spent by
+// every input of the transaction available to the native introspection
+// opcodes. spentOutputs may be nil when they are not known.
+func VerifyScriptWithContext(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *script.Script,
+	nIn int, value amount.Amount, flags uint32, scriptChecker Checker, spentOutputs []*txout.TxOut,
+	metrics *ScriptExecutionMetrics) error {
+	if metrics == nil {
+		metrics = &ScriptExecutionMetrics{}
+	}
 	if flags&script.ScriptEnableSigHashForkID == script.ScriptEnableSigHashForkID {
 		flags |= script.ScriptVerifyStrictEnc
 	}
@@ -22,12 +53,12 @@ func VerifyScript(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *sc
 		return errcode.New(errcode.ScriptErrSigPushOnly)
 	}
 	stack := util.NewStack()
-	err := EvalScript(stack, scriptSig, transaction, nIn, value, flags, scriptChecker)
+	err := evalScript(stack, scriptSig, transaction, nIn, value, flags, scriptChecker, spentOutputs, metrics)
 	if err != nil {
 		return err
 	}
 	stackCopy := stack.Copy()
-	err = EvalScript(stack, scriptPubKey, transaction, nIn, value, flags, scriptChecker)
+	err = evalScript(stack, scriptPubKey, transaction, nIn, value, flags, scriptChecker, spentOutputs, metrics)
 	if err != nil {
 		return err
 	}
@@ -41,7 +72,9 @@ func VerifyScript(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *sc
 		return errcode.New(errcode.ScriptErrEvalFalse)
 	}
 
-	if flags&script.ScriptVerifyP2SH == script.ScriptVerifyP2SH && scriptPubKey.IsPayToScriptHash() {
+	isP2SH := scriptPubKey.IsPayToScriptHash() ||
+		flags&script.ScriptEnableP2SH32 != 0 && scriptPubKey.IsPayToScriptHash32()
+	if flags&script.ScriptVerifyP2SH == script.ScriptVerifyP2SH && isP2SH {
 		if !scriptSig.IsPushOnly() {
 			log.Debug("ScriptErrScriptSigNotPushOnly")
 			return errcode.New(errcode.ScriptErrSigPushOnly)
@@ -50,7 +83,7 @@ func VerifyScript(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *sc
 		topBytes := stack.Top(-1)
 		stack.Pop()
 		scriptPubKey2 := script.NewScriptRaw(topBytes.([]byte))
-		err = EvalScript(stack, scriptPubKey2, transaction, nIn, value, flags, scriptChecker)
+		err = evalScript(stack, scriptPubKey2, transaction, nIn, value, flags, scriptChecker, spentOutputs, metrics)
 		if err != nil {
 			return err
 		}
@@ -81,11 +114,24 @@ func VerifyScript(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *sc
 			return errcode.New(errcode.ScriptErrCleanStack)
 		}
 	}
+
+	// The sigchecks of an input are limited by the length of its scriptSig,
+	// so that the density of signature checks in a transaction stays bounded.
+	if flags&script.ScriptVerifyInputSigChecks != 0 && scriptSig.Size() < 43*metrics.SigChecks-60 {
+		log.Debug("ScriptErrInputSigChecks")
+		return errcode.New(errcode.ScriptErrInputSigChecks)
+	}
 	return nil
 }
 
 func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int,
 	money amount.Amount, flags uint32, scriptChecker Checker) error {
+	return evalScript(stack, s, transaction, nIn, money, flags, scriptChecker, nil, &ScriptExecutionMetrics{})
+}
+
+func evalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int,
+	money amount.Amount, flags uint32, scriptChecker Checker, spentOutputs []*txout.TxOut,
+	metrics *ScriptExecutionMetrics) error {
 
 	if s.GetBadOpCode() {
 		log.Debug("ScriptErrBadOpCode, txid: %s, input: %d", transaction.GetHash().String(), nIn)
@@ -110,6 +156,13 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 	} else {
 		fRequireMinimal = false
 	}
+	maxNumSize := script.GetMaxNumSize(flags)
+
+	// The signatures commit to the token data of the spent output, if known.
+	var spentTokenData *txout.TokenData
+	if len(spentOutputs) > nIn && len(spentOutputs) == transaction.GetInsCount() && spentOutputs[nIn] != nil {
+		spentTokenData = spentOutputs[nIn].GetTokenData()
+	}
 
 	var fExec bool
 	stackExec := util.NewStack()
@@ -606,7 +659,7 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				scriptNum, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				scriptNum, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 
@@ -788,15 +841,18 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				bn, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
+				// The 4-byte operands can not overflow, the 8-byte ones
+				// must stay in the 64-bit script number range.
+				ok := true
 				switch e.OpValue {
 				case opcodes.OP_1ADD:
-					bn.Value += bnOne.Value
+					bn.Value, ok = script.SafeAdd(bn.Value, bnOne.Value)
 				case opcodes.OP_1SUB:
-					bn.Value -= bnOne.Value
+					bn.Value, ok = script.SafeSub(bn.Value, bnOne.Value)
 				case opcodes.OP_NEGATE:
 					bn.Value = -bn.Value
 				case opcodes.OP_ABS:
@@ -807,6 +863,10 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidOpCode")
 					return errcode.New(errcode.ScriptErrInvalidOpCode)
 				}
+				if !ok {
+					log.Debug("ScriptErrInvalidNumberRange")
+					return errcode.New(errcode.ScriptErrInvalidNumberRange)
+				}
 				stack.Pop()
 				stack.Push(bn.Serialize())
 
@@ -823,7 +883,7 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				bn, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
@@ -851,6 +911,8 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 				fallthrough
 			case opcodes.OP_SUB:
 				fallthrough
+			case opcodes.OP_MUL:
+				fallthrough
 			case opcodes.OP_DIV:
 				fallthrough
 			case opcodes.OP_MOD:
@@ -873,20 +935,23 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				bn1, err := script.GetScriptNum(vch1.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn1, err := script.GetScriptNum(vch1.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
-				bn2, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn2, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
 				bn := script.NewScriptNum(0)
+				ok := true
 				switch e.OpValue {
 				case opcodes.OP_ADD:
-					bn.Value = bn1.Value + bn2.Value
+					bn.Value, ok = script.SafeAdd(bn1.Value, bn2.Value)
 				case opcodes.OP_SUB:
-					bn.Value = bn1.Value - bn2.Value
+					bn.Value, ok = script.SafeSub(bn1.Value, bn2.Value)
+				case opcodes.OP_MUL:
+					bn.Value, ok = script.SafeMul(bn1.Value, bn2.Value)
 				case opcodes.OP_DIV:
 					// denominator must not be 0
 					if bn2.Value == 0 {
@@ -919,6 +984,10 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidOpCode")
 					return errcode.New(errcode.ScriptErrInvalidOpCode)
 				}
+				if !ok {
+					log.Debug("ScriptErrInvalidNumberRange")
+					return errcode.New(errcode.ScriptErrInvalidNumberRange)
+				}
 				stack.Pop()
 				stack.Pop()
 				stack.Push(bn.Serialize())
@@ -956,11 +1025,11 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				bn1, err := script.GetScriptNum(vch1.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn1, err := script.GetScriptNum(vch1.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
-				bn2, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn2, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
@@ -1060,15 +1129,15 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				bn1, err := script.GetScriptNum(vch1.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn1, err := script.GetScriptNum(vch1.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
-				bn2, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn2, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
-				bn3, err := script.GetScriptNum(vch3.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn3, err := script.GetScriptNum(vch3.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
@@ -1168,10 +1237,13 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 				scriptCode.FindAndDelete(vchScript)*/
 				scriptCode = scriptCode.RemoveOpcodeByData(vchSigBytes)
 
-				fSuccess, err := scriptChecker.CheckSig(transaction, vchSigBytes, vchPubkey.([]byte), scriptCode, nIn, money, flags)
+				fSuccess, err := scriptChecker.CheckSig(transaction, vchSigBytes, vchPubkey.([]byte), scriptCode, nIn, money, spentTokenData, flags)
 				if err != nil {
 					return err
 				}
+				if len(vchSigBytes) > 0 {
+					metrics.SigChecks++
+				}
 
 				if !fSuccess &&
 					(flags&script.ScriptVerifyNullFail == script.ScriptVerifyNullFail) &&
@@ -1235,8 +1307,9 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 
 				success := false
 				if len(vchSigBytes) > 0 {
+					metrics.SigChecks++
 					vchHashs := util.Sha256Hash(vchMessage.([]byte))
-					success, err = scriptChecker.VerifySignature(vchSigBytes, ppubKey, &vchHashs)
+					success, err = scriptChecker.VerifySignature(vchSigBytes, ppubKey, &vchHashs, flags)
 					if err != nil {
 						log.Debug("verify error")
 					}
@@ -1283,7 +1356,7 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 				}
 
 				// ScriptSig1 ScriptSig2...ScriptSigM M PubKey1 PubKey2...PubKey N
-				pubKeysNum, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				pubKeysNum, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					//log.Debug("ScriptErrInvalidStackOperation")
 					return err
@@ -1317,7 +1390,7 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				nSigsNum, err := script.GetScriptNum(sigsNumVch.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				nSigsNum, err := script.GetScriptNum(sigsNumVch.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					//log.Debug("ScriptErrInvalidStackOperation")
 					return err
@@ -1339,87 +1412,178 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 				// Subset of script starting at the most recent codeSeparator
 				scriptCode := script.NewScriptOps(s.ParsedOpCodes[beginCodeHash:])
 
-				// Drop the signature in pre-segwit scripts but not segwit scripts
-				for k := 0; k < int(nSigsCount); k++ {
-					vchSig := stack.Top(-iSig - k)
-					if vchSig == nil {
-						log.Debug("ScriptErrInvalidStackOperation")
-						return errcode.New(errcode.ScriptErrInvalidStackOperation)
-					}
-					scriptCode = scriptCode.RemoveOpcodeByData(vchSig.([]byte))
+				vchDummy := stack.Top(-i)
+				if vchDummy == nil {
+					log.Debug("ScriptErrInvalidStackOperation")
+					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
+
 				fSuccess := true
-				for fSuccess && nSigsCount > 0 {
-					vchSig := stack.Top(-iSig)
-					if vchSig == nil {
-						log.Debug("ScriptErrInvalidStackOperation")
-						return errcode.New(errcode.ScriptErrInvalidStackOperation)
-					}
-					vchPubkey := stack.Top(-iPubKey)
-					if vchPubkey == nil {
-						log.Debug("ScriptErrInvalidStackOperation")
-						return errcode.New(errcode.ScriptErrInvalidStackOperation)
-					}
-					// Note how this makes the exact order of
-					// pubkey/signature evaluation distinguishable by
-					// CHECKMULTISIG NOT if the STRICTENC flag is set.
-					// See the script_(in)valid tests for details.
-					err := script.CheckTransactionSignatureEncoding(vchSig.([]byte), flags)
+				if flags&script.ScriptEnableSchnorrMultisig != 0 && len(vchDummy.([]byte)) > 0 {
+					// The dummy element is a bitfield telling which pubkeys
+					// are checked, every signature must then be valid.
+					checkBits, err := decodeBitfield(vchDummy.([]byte), pubKeysCount)
 					if err != nil {
 						return err
 					}
-					err = script.CheckPubKeyEncoding(vchPubkey.([]byte), flags)
-					if err != nil {
-						return err
+					if bits.OnesCount32(checkBits) != int(nSigsCount) {
+						log.Debug("ScriptErrInvalidBitCount")
+						return errcode.New(errcode.ScriptErrInvalidBitCount)
 					}
-					fOk, err := scriptChecker.CheckSig(transaction, vchSig.([]byte), vchPubkey.([]byte), scriptCode, nIn, money, flags)
-					if err != nil {
-						return err
+
+					// Signatures and pubkeys are checked from the bottom of
+					// the stack, in script order.
+					iBottomKey := iPubKey + int(pubKeysCount) - 1
+					iBottomSig := iSig + int(nSigsCount) - 1
+					iKey := 0
+					for k := 0; k < int(nSigsCount); k++ {
+						if checkBits>>uint(iKey) == 0 {
+							// This is a sanity check and should be unreachable.
+							log.Debug("ScriptErrInvalidBitRange")
+							return errcode.New(errcode.ScriptErrInvalidBitRange)
+						}
+						// Find the next suitable key.
+						for (checkBits>>uint(iKey))&0x01 != 0x01 {
+							iKey++
+						}
+						if iKey >= int(pubKeysCount) {
+							// This is a sanity check and should be unreachable.
+							log.Debug("ScriptErrPubKeyCount")
+							return errcode.New(errcode.ScriptErrPubKeyCount)
+						}
+
+						vchSig := stack.Top(-iBottomSig + k)
+						vchPubkey := stack.Top(-iBottomKey + iKey)
+						if vchSig == nil || vchPubkey == nil {
+							log.Debug("ScriptErrInvalidStackOperation")
+							return errcode.New(errcode.ScriptErrInvalidStackOperation)
+						}
+						// Note that only pubkeys associated with a signature
+						// are checked for validity.
+						err := script.CheckTransactionSchnorrSignatureEncoding(vchSig.([]byte), flags)
+						if err != nil {
+							return err
+						}
+						err = script.CheckPubKeyEncoding(vchPubkey.([]byte), flags)
+						if err != nil {
+							return err
+						}
+						fOk, err := scriptChecker.CheckSig(transaction, vchSig.([]byte), vchPubkey.([]byte), scriptCode, nIn, money, spentTokenData, flags)
+						if err != nil {
+							return err
+						}
+						if !fOk {
+							// This also covers an empty signature, which is a
+							// NULLFAIL error as the bitfield is not empty.
+							log.Debug("ScriptErrSigNullFail")
+							return errcode.New(errcode.ScriptErrSigNullFail)
+						}
+						iKey++
 					}
-					if fOk {
-						iSig++
-						nSigsCount--
+					if checkBits>>uint(iKey) != 0 {
+						// This is a sanity check and should be unreachable.
+						log.Debug("ScriptErrInvalidBitCount")
+						return errcode.New(errcode.ScriptErrInvalidBitCount)
 					}
-					iPubKey++
-					pubKeysCount--
-					// If there are more signatures left than keys left,
-					// then too many signatures have failed. Exit early,
-					// without checking any further signatures.
-					if nSigsCount > pubKeysCount {
-						fSuccess = false
+
+					// Every signature of the bitfield mode is checked.
+					metrics.SigChecks += int(nSigsCount)
+
+					// Clean up stack of actual arguments
+					for ; i > 1; i-- {
+						stack.Pop()
 					}
-				}
-				// Clean up stack of actual arguments
-				for i > 1 {
-					// If the operation failed, we require that all
-					// signatures must be empty vector
-					if !fSuccess && (flags&script.ScriptVerifyNullFail == script.ScriptVerifyNullFail) &&
-						iKey2 == 0 && len(stack.Top(-1).([]byte)) > 0 {
-						log.Debug("ScriptErrSigNullFail")
-						return errcode.New(errcode.ScriptErrSigNullFail)
+				} else {
+					// Drop the signature in pre-segwit scripts but not segwit scripts
+					allNull := true
+					for k := 0; k < int(nSigsCount); k++ {
+						vchSig := stack.Top(-iSig - k)
+						if vchSig == nil {
+							log.Debug("ScriptErrInvalidStackOperation")
+							return errcode.New(errcode.ScriptErrInvalidStackOperation)
+						}
+						if len(vchSig.([]byte)) > 0 {
+							allNull = false
+						}
+						scriptCode = scriptCode.RemoveOpcodeByData(vchSig.([]byte))
+					}
+					// The legacy mode may check every pubkey, unless all the
+					// signatures are null.
+					if !allNull {
+						metrics.SigChecks += int(pubKeysCount)
+					}
+					for fSuccess && nSigsCount > 0 {
+						vchSig := stack.Top(-iSig)
+						if vchSig == nil {
+							log.Debug("ScriptErrInvalidStackOperation")
+							return errcode.New(errcode.ScriptErrInvalidStackOperation)
+						}
+						vchPubkey := stack.Top(-iPubKey)
+						if vchPubkey == nil {
+							log.Debug("ScriptErrInvalidStackOperation")
+							return errcode.New(errcode.ScriptErrInvalidStackOperation)
+						}
+						// Note how this makes the exact order of
+						// pubkey/signature evaluation distinguishable by
+						// CHECKMULTISIG NOT if the STRICTENC flag is set.
+						// See the script_(in)valid tests for details.
+						err := script.CheckTransactionECDSASignatureEncoding(vchSig.([]byte), flags)
+						if err != nil {
+							return err
+						}
+						err = script.CheckPubKeyEncoding(vchPubkey.([]byte), flags)
+						if err != nil {
+							return err
+						}
+						fOk, err := scriptChecker.CheckSig(transaction, vchSig.([]byte), vchPubkey.([]byte), scriptCode, nIn, money, spentTokenData, flags)
+						if err != nil {
+							return err
+						}
+						if fOk {
+							iSig++
+							nSigsCount--
+						}
+						iPubKey++
+						pubKeysCount--
+						// If there are more signatures left than keys left,
+						// then too many signatures have failed. Exit early,
+						// without checking any further signatures.
+						if nSigsCount > pubKeysCount {
+							fSuccess = false
+						}
+					}
+					// Clean up stack of actual arguments
+					for i > 1 {
+						// If the operation failed, we require that all
+						// signatures must be empty vector
+						if !fSuccess && (flags&script.ScriptVerifyNullFail == script.ScriptVerifyNullFail) &&
+							iKey2 == 0 && len(stack.Top(-1).([]byte)) > 0 {
+							log.Debug("ScriptErrSigNullFail")
+							return errcode.New(errcode.ScriptErrSigNullFail)
 
+						}
+						if iKey2 > 0 {
+							iKey2--
+						}
+						stack.Pop()
+						i--
 					}
-					if iKey2 > 0 {
-						iKey2--
+					// A bug causes CHECKMULTISIG to consume one extra
+					// argument whose contents were not checked in any way.
+					//
+					// Unfortunately this is a potential source of
+					// mutability, so optionally verify it is exactly equal
+					// to zero prior to removing it from the stack.
+					if stack.Size() < 1 {
+						log.Debug("ScriptErrInvalidStackOperation")
+						return errcode.New(errcode.ScriptErrInvalidStackOperation)
 					}
-					stack.Pop()
-					i--
-				}
-				// A bug causes CHECKMULTISIG to consume one extra
-				// argument whose contents were not checked in any way.
-				//
-				// Unfortunately this is a potential source of
-				// mutability, so optionally verify it is exactly equal
-				// to zero prior to removing it from the stack.
-				if stack.Size() < 1 {
-					log.Debug("ScriptErrInvalidStackOperation")
-					return errcode.New(errcode.ScriptErrInvalidStackOperation)
-				}
-				if flags&script.ScriptVerifyNullDummy == script.ScriptVerifyNullDummy &&
-					len(stack.Top(-1).([]byte)) > 0 {
-					log.Debug("ScriptErrSigNullDummy")
-					return errcode.New(errcode.ScriptErrSigNullDummy)
+					if flags&script.ScriptVerifyNullDummy == script.ScriptVerifyNullDummy &&
+						len(stack.Top(-1).([]byte)) > 0 {
+						log.Debug("ScriptErrSigNullDummy")
+						return errcode.New(errcode.ScriptErrSigNullDummy)
 
+					}
 				}
 
 				//pop bug byte op_0, format: op0 sig1 sig2 m pubkey1 pubk2 pubkey3 n checkmultisig
@@ -1437,6 +1601,43 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 						return errcode.New(errcode.ScriptErrCheckMultiSigVerify)
 					}
 				}
+				//
+				// Native introspection
+				//
+			case opcodes.OP_UTXOTOKENCATEGORY, opcodes.OP_UTXOTOKENCOMMITMENT, opcodes.OP_UTXOTOKENAMOUNT,
+				opcodes.OP_OUTPUTTOKENCATEGORY, opcodes.OP_OUTPUTTOKENCOMMITMENT, opcodes.OP_OUTPUTTOKENAMOUNT:
+				// The token introspection opcodes also need tokens to be
+				// enabled.
+				if flags&script.ScriptEnableTokens == 0 {
+					log.Debug("ScriptErrBadOpCode")
+					return errcode.New(errcode.ScriptErrBadOpCode)
+				}
+				fallthrough
+
+			case opcodes.OP_INPUTINDEX, opcodes.OP_ACTIVEBYTECODE, opcodes.OP_TXVERSION,
+				opcodes.OP_TXINPUTCOUNT, opcodes.OP_TXOUTPUTCOUNT, opcodes.OP_TXLOCKTIME,
+				opcodes.OP_UTXOVALUE, opcodes.OP_UTXOBYTECODE, opcodes.OP_OUTPOINTTXHASH,
+				opcodes.OP_OUTPOINTINDEX, opcodes.OP_INPUTBYTECODE, opcodes.OP_INPUTSEQUENCENUMBER,
+				opcodes.OP_OUTPUTVALUE, opcodes.OP_OUTPUTBYTECODE:
+				// Make sure this remains an error before activation
+				if flags&script.ScriptEnableNativeIntrospection == 0 {
+					log.Debug("ScriptErrBadOpCode")
+					return errcode.New(errcode.ScriptErrBadOpCode)
+				}
+
+				// The active bytecode starts after the most recent
+				// code separator.
+				activeOps := s.ParsedOpCodes[beginCodeHash:]
+				if len(activeOps) > 0 && activeOps[0].OpValue == opcodes.OP_CODESEPARATOR {
+					activeOps = activeOps[1:]
+				}
+				activeBytecode := script.NewScriptOps(activeOps).GetData()
+				err := evalIntrospection(stack, e.OpValue, activeBytecode, transaction, nIn, spentOutputs,
+					fRequireMinimal, maxNumSize)
+				if err != nil {
+					return err
+				}
+
 			case opcodes.OP_CAT:
 				// (x1 x2 -- out)
 				if stack.Size() < 2 {
@@ -1469,7 +1670,7 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 
 				vch1 := stack.Top(-2)
 				vch2 := stack.Top(-1)
-				scriptNum, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				scriptNum, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
@@ -1501,7 +1702,7 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
 				vch2 := stack.Top(-1)
-				scriptNum, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				scriptNum, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
@@ -1555,7 +1756,7 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 				vchEncode := script.MinimallyEncode(vch.([]byte))
 
 				// The resulting number must be a valid number.
-				if !script.IsMinimallyEncoded(vchEncode, script.DefaultMaxNumSize) {
+				if !script.IsMinimallyEncoded(vchEncode, int64(maxNumSize)) {
 					log.Debug("ScriptErrInvalidNumberRange")
 					return errcode.New(errcode.ScriptErrInvalidNumberRange)
 				}
@@ -121,6 +121,13 @@ var scriptFlagMap = map[string]uint32{
 	"SIGHASH_FORKID":             script.ScriptEnableSigHashForkID,
 	"REPLAY_PROTECTION":          script.ScriptEnableReplayProtection,
 	"CHECKDATASIG":               script.ScriptEnableCheckDataSig,
+	"SCHNORR":                    script.ScriptEnableSchnorr,
+	"SCHNORR_MULTISIG":           script.ScriptEnableSchnorrMultisig,
+	"INPUT_SIGCHECKS":            script.ScriptVerifyInputSigChecks,
+	"64_BIT_INTEGERS":            script.ScriptEnable64BitIntegers,
+	"NATIVE_INTROSPECTION":       script.ScriptEnableNativeIntrospection,
+	"TOKENS":                     script.ScriptEnableTokens,
+	"P2SH_32":                    script.ScriptEnableP2SH32,
 }
 
 type scriptErrChecker struct {
@@ -312,7 +319,8 @@ func doScriptJSONTest(t *testing.T, itest []interface{}, sec scriptErrChecker) (
 	trax.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(pretx.GetHash(), 0), scriptSig, script.SequenceFinal))
 	trax.AddTxOut(txout.NewTxOut(amount.Amount(nValue), script.NewScriptRaw([]byte{})))
 
-	err = VerifyScript(trax, scriptSig, scriptPubKey, 0, amount.Amount(nValue), flags, NewScriptRealChecker())
+	err = VerifyScriptWithContext(trax, scriptSig, scriptPubKey, 0, amount.Amount(nValue), flags,
+		NewScriptRealChecker(), []*txout.TxOut{pretx.GetTxOut(0)}, nil)
 
 	if err = sec.check(err, scriptErrorString); err != nil {
 		for _, v := range test {
@@ -559,3 +567,209 @@ func TestIsPushOnlyOnInvalidScripts(t *testing.T) {
 		t.Errorf("IsPushOnly should return false on invalid scripts")
 	}
 }
+
+func multisigScript(required int, keys []crypto.PrivateKey) *script.Script {
+	s := script.NewEmptyScript()
+	s.PushInt64(int64(required))
+	for _, key := range keys {
+		s.PushSingleData(key.PubKey().ToBytes())
+	}
+	s.PushInt64(int64(len(keys)))
+	s.PushOpCode(opcodes.OP_CHECKMULTISIG)
+	return s
+}
+
+func verifySigChecks(t *testing.T, name string, scriptSig, scriptPubKey *script.Script, transaction *tx.Tx,
+	flags uint32, expect int) {
+	var metrics ScriptExecutionMetrics
+	err := VerifyScriptWithMetrics(transaction, scriptSig, scriptPubKey, 0, 0, flags, NewScriptRealChecker(), &metrics)
+	if err != nil {
+		t.Errorf("%s: verify script failed: %v", name, err)
+		return
+	}
+	if metrics.SigChecks != expect {
+		t.Errorf("%s: expect %d sigchecks, actual %d", name, expect, metrics.SigChecks)
+	}
+}
+
+func TestScriptSigChecks(t *testing.T) {
+	crypto.InitSecp256()
+	var flags uint32 = script.ScriptVerifyP2SH | script.ScriptEnableCheckDataSig |
+		script.ScriptEnableSchnorr | script.ScriptEnableSchnorrMultisig
+	keys := []crypto.PrivateKey{NewPrivateKey(), NewPrivateKey(), NewPrivateKey()}
+
+	var txFrom, txTo tx.Tx
+	txFrom.AddTxOut(txout.NewTxOut(0, script.NewEmptyScript()))
+	txTo.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(txFrom.GetHash(), 0),
+		script.NewEmptyScript(), script.SequenceFinal))
+
+	// CHECKSIG counts its non-null signatures.
+	checkSig := script.NewEmptyScript()
+	checkSig.PushSingleData(keys[0].PubKey().ToBytes())
+	checkSig.PushOpCode(opcodes.OP_CHECKSIG)
+	hash, _ := tx.SignatureHash(&txTo, checkSig, uint32(crypto.SigHashAll), 0, amount.Amount(0), 0)
+	sig, _ := keys[0].Sign(hash.GetCloneBytes())
+	scriptSig := script.NewEmptyScript()
+	scriptSig.PushSingleData(append(sig.Serialize(), byte(crypto.SigHashAll)))
+	verifySigChecks(t, "checksig", scriptSig, checkSig, &txTo, flags, 1)
+
+	checkSigNot := script.NewScriptRaw(append(checkSig.GetData(), opcodes.OP_NOT))
+	nullSig := script.NewEmptyScript()
+	nullSig.PushOpCode(opcodes.OP_0)
+	verifySigChecks(t, "null checksig", nullSig, checkSigNot, &txTo, flags, 0)
+
+	// So does CHECKDATASIG.
+	message := []byte("sigchecks")
+	messageHash := util.Sha256Hash(message)
+	dataSig, _ := keys[1].Sign(messageHash[:])
+	checkDataSig := script.NewEmptyScript()
+	checkDataSig.PushSingleData(message)
+	checkDataSig.PushSingleData(keys[1].PubKey().ToBytes())
+	checkDataSig.PushOpCode(opcodes.OP_CHECKDATASIG)
+	scriptSig = script.NewEmptyScript()
+	scriptSig.PushSingleData(dataSig.Serialize())
+	verifySigChecks(t, "checkdatasig", scriptSig, checkDataSig, &txTo, flags, 1)
+
+	// The legacy CHECKMULTISIG counts every pubkey, unless all the
+	// signatures are null.
+	multisig := multisigScript(1, keys)
+	verifySigChecks(t, "legacy multisig", signMultisig(multisig, keys[2:], &txTo), multisig, &txTo, flags, 3)
+
+	multisigNot := script.NewScriptRaw(append(multisig.GetData(), opcodes.OP_NOT))
+	nullSigs := script.NewEmptyScript()
+	nullSigs.PushOpCode(opcodes.OP_0)
+	nullSigs.PushOpCode(opcodes.OP_0)
+	verifySigChecks(t, "null legacy multisig", nullSigs, multisigNot, &txTo, flags, 0)
+
+	// The Schnorr CHECKMULTISIG counts its signatures.
+	multisig = multisigScript(2, keys)
+	hash, _ = tx.SignatureHash(&txTo, multisig, uint32(crypto.SigHashAll), 0, amount.Amount(0), 0)
+	scriptSig = script.NewEmptyScript()
+	scriptSig.PushSingleData([]byte{0x05})
+	for _, key := range []crypto.PrivateKey{keys[0], keys[2]} {
+		schnorrSig, _ := key.SignSchnorr(hash.GetCloneBytes())
+		scriptSig.PushSingleData(append(schnorrSig, byte(crypto.SigHashAll)))
+	}
+	verifySigChecks(t, "schnorr multisig", scriptSig, multisig, &txTo, flags, 2)
+}
+
+func TestScriptInputSigChecks(t *testing.T) {
+	crypto.InitSecp256()
+	keys := make([]crypto.PrivateKey, script.MaxPubKeysPerMultiSig)
+	for i := range keys {
+		keys[i] = NewPrivateKey()
+	}
+	multisig := multisigScript(1, keys)
+
+	var txFrom, txTo tx.Tx
+	txFrom.AddTxOut(txout.NewTxOut(0, multisig))
+	txTo.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(txFrom.GetHash(), 0),
+		script.NewEmptyScript(), script.SequenceFinal))
+	scriptSig := signMultisig(multisig, keys[:1], &txTo)
+
+	// 20 sigchecks need a scriptSig of 800 bytes.
+	var flags uint32 = script.ScriptVerifyP2SH
+	verifySigChecks(t, "without input sigchecks", scriptSig, multisig, &txTo, flags, script.MaxPubKeysPerMultiSig)
+
+	flags |= script.ScriptVerifyInputSigChecks
+	err := VerifyScript(&txTo, scriptSig, multisig, 0, 0, flags, NewScriptRealChecker())
+	if err != errcode.New(errcode.ScriptErrInputSigChecks) {
+		t.Errorf("expect the input sigchecks error, actual %v", err)
+	}
+
+	// A single sigcheck is always allowed.
+	checkSig := script.NewEmptyScript()
+	checkSig.PushSingleData(keys[0].PubKey().ToBytes())
+	checkSig.PushOpCode(opcodes.OP_CHECKSIG)
+	hash, _ := tx.SignatureHash(&txTo, checkSig, uint32(crypto.SigHashAll), 0, amount.Amount(0), 0)
+	sig, _ := keys[0].Sign(hash.GetCloneBytes())
+	scriptSig = script.NewEmptyScript()
+	scriptSig.PushSingleData(append(sig.Serialize(), byte(crypto.SigHashAll)))
+	verifySigChecks(t, "with input sigchecks", scriptSig, checkSig, &txTo, flags, 1)
+}
+
+func TestScriptNativeIntrospectionContext(t *testing.T) {
+	flags := uint32(script.ScriptEnableNativeIntrospection | script.ScriptEnable64BitIntegers)
+	utxoValue := script.NewScriptRaw([]byte{opcodes.OP_1, opcodes.OP_UTXOVALUE})
+
+	// Without a transaction no introspection opcode can be executed.
+	stack := util.NewStack()
+	err := EvalScript(stack, script.NewScriptRaw([]byte{opcodes.OP_TXVERSION}), nil, 0, 0, flags,
+		NewScriptRealChecker())
+	if err != errcode.New(errcode.ScriptErrContextNotPresent) {
+		t.Errorf("expect the context not present error without a transaction, actual %v", err)
+	}
+
+	spentOutputs := []*txout.TxOut{
+		txout.NewTxOut(amount.Amount(1000), script.NewScriptRaw([]byte{opcodes.OP_1})),
+		txout.NewTxOut(amount.Amount(5000000000), utxoValue),
+	}
+	var txTo tx.Tx
+	for i := range spentOutputs {
+		txTo.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.Hash{}, uint32(i)),
+			script.NewEmptyScript(), script.SequenceFinal))
+	}
+	txTo.AddTxOut(txout.NewTxOut(0, script.NewEmptyScript()))
+
+	// The spent outputs are needed by the UTXO opcodes only.
+	err = VerifyScript(&txTo, script.NewEmptyScript(), utxoValue, 0, 0, flags, NewScriptRealChecker())
+	if err != errcode.New(errcode.ScriptErrContextNotPresent) {
+		t.Errorf("expect the context not present error without spent outputs, actual %v", err)
+	}
+	txInputCount := script.NewScriptRaw([]byte{opcodes.OP_TXINPUTCOUNT, opcodes.OP_2, opcodes.OP_EQUAL})
+	err = VerifyScript(&txTo, script.NewEmptyScript(), txInputCount, 0, 0, flags, NewScriptRealChecker())
+	if err != nil {
+		t.Errorf("TXINPUTCOUNT should not need the spent outputs: %v", err)
+	}
+
+	// An input reads the value of the output spent by another one, which
+	// needs 64-bit integers.
+	stack = util.NewStack()
+	err = evalScript(stack, utxoValue, &txTo, 0, 0, flags, NewScriptRealChecker(), spentOutputs,
+		&ScriptExecutionMetrics{})
+	if err != nil {
+		t.Fatalf("UTXOVALUE failed: %v", err)
+	}
+	value, err := script.GetScriptNum(stack.Top(-1).([]byte), true, script.MaxNumSize64Bit)
+	if err != nil || value.Value != 5000000000 {
+		t.Errorf("expect the UTXOVALUE 5000000000, actual %v, err %v", value, err)
+	}
+}
+
+func TestScriptTokenIntrospection(t *testing.T) {
+	flags := uint32(script.ScriptEnableNativeIntrospection | script.ScriptEnable64BitIntegers |
+		script.ScriptEnableTokens)
+	category := util.Hash{0xaa, 0xbb}
+
+	spent := txout.NewTxOut(amount.Amount(1000), script.NewScriptRaw([]byte{opcodes.OP_1}))
+	spent.SetTokenData(&txout.TokenData{Category: category, Amount: 5000000000, HasNFT: true,
+		Capability: txout.TokenCapabilityMinting, Commitment: []byte{0x01, 0x02}})
+	var txTo tx.Tx
+	txTo.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.Hash{}, 0), script.NewEmptyScript(), script.SequenceFinal))
+	out := txout.NewTxOut(0, script.NewEmptyScript())
+	out.SetTokenData(&txout.TokenData{Category: category, HasNFT: true})
+	txTo.AddTxOut(out)
+
+	tests := []struct {
+		opcode byte
+		want   []byte
+	}{
+		{opcodes.OP_UTXOTOKENCATEGORY, append(category.GetCloneBytes(), txout.TokenCapabilityMinting)},
+		{opcodes.OP_UTXOTOKENCOMMITMENT, []byte{0x01, 0x02}},
+		{opcodes.OP_UTXOTOKENAMOUNT, script.NewScriptNum(5000000000).Serialize()},
+		{opcodes.OP_OUTPUTTOKENCATEGORY, category.GetCloneBytes()},
+		{opcodes.OP_OUTPUTTOKENCOMMITMENT, []byte{}},
+		{opcodes.OP_OUTPUTTOKENAMOUNT, []byte{}},
+	}
+	for _, test := range tests {
+		stack := util.NewStack()
+		err := evalScript(stack, script.NewScriptRaw([]byte{opcodes.OP_0, test.opcode}), &txTo, 0, 0, flags,
+			NewScriptRealChecker(), []*txout.TxOut{spent}, &ScriptExecutionMetrics{})
+		if err != nil {
+			t.Fatalf("%s failed: %v", opcodes.GetOpName(int(test.opcode)), err)
+		}
+		if !bytes.Equal(stack.Top(-1).([]byte), test.want) {
+			t.Errorf("%s pushed %x, expect %x", opcodes.GetOpName(int(test.opcode)), stack.Top(-1), test.want)
+		}
+	}
+}
@@ -6,25 +6,43 @@ import (
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
 )
 
+// RealChecker verifies signatures against the spending transaction. A caching
+// checker consults the global signature cache first, and when store is set
+// remembers the signatures it successfully verified.
 type RealChecker struct {
+	cached bool
+	store  bool
 }
 
 func (src *RealChecker) CheckSig(transaction *tx.Tx, signature []byte, pubKey []byte, scriptCode *script.Script,
-	nIn int, money amount.Amount, flags uint32) (bool, error) {
+	nIn int, money amount.Amount, tokenData *txout.TokenData, flags uint32) (bool, error) {
 	if len(signature) == 0 || len(pubKey) == 0 {
 		return false, nil
 	}
 	hashType := signature[len(signature)-1]
-	txSigHash, err := tx.SignatureHash(transaction, scriptCode, uint32(hashType), nIn, money, flags)
+	txSigHash, err := tx.SignatureHashWithTokenData(transaction, scriptCode, uint32(hashType), nIn, money, tokenData, flags)
 	if err != nil {
 		return false, err
 	}
 	signature = signature[:len(signature)-1]
-	fOk := tx.CheckSig(txSigHash, signature, pubKey)
+	schnorr := isSchnorrSignature(signature, flags)
+	if src.cached && sigCache.Exists(&txSigHash, pubKey, signature, schnorr, !src.store) {
+		return true, nil
+	}
+	var fOk bool
+	if schnorr {
+		fOk = tx.CheckSchnorrSig(txSigHash, signature, pubKey)
+	} else {
+		fOk = tx.CheckSig(txSigHash, signature, pubKey)
+	}
+	if fOk && src.cached && src.store {
+		sigCache.Add(&txSigHash, pubKey, signature, schnorr)
+	}
 	log.Debug("CheckSig: txid: %s, txSigHash: %s, signature: %s, pubkey: %s, flags: %d, result: %v",
 		transaction.GetHash().String(), txSigHash.String(), hex.EncodeToString(signature),
 		hex.EncodeToString(pubKey), flags, fOk)
@@ -104,10 +122,41 @@ func (src *RealChecker) CheckSequence(sequence int64, txToSequence int64, txVers
 	return true
 }
 
-func (src *RealChecker) VerifySignature(vchSig []byte, pubKey *crypto.PublicKey, sigHash *util.Hash) (bool, error) {
-	return pubKey.Verify(sigHash, vchSig)
+func (src *RealChecker) VerifySignature(vchSig []byte, pubKey *crypto.PublicKey, sigHash *util.Hash,
+	flags uint32) (bool, error) {
+	verify := pubKey.Verify
+	schnorr := isSchnorrSignature(vchSig, flags)
+	if schnorr {
+		verify = pubKey.VerifySchnorr
+	}
+	if !src.cached {
+		return verify(sigHash, vchSig)
+	}
+
+	pubKeyBytes := pubKey.ToBytes()
+	if sigCache.Exists(sigHash, pubKeyBytes, vchSig, schnorr, !src.store) {
+		return true, nil
+	}
+	fOk, err := verify(sigHash, vchSig)
+	if fOk && err == nil && src.store {
+		sigCache.Add(sigHash, pubKeyBytes, vchSig, schnorr)
+	}
+	return fOk, err
+}
+
+// isSchnorrSignature reports whether a signature, stripped of its hashtype,
+// is to be verified as Schnorr rather than ECDSA.
+func isSchnorrSignature(vchSig []byte, flags uint32) bool {
+	return len(vchSig) == crypto.SchnorrSignatureSize && flags&script.ScriptEnableSchnorr != 0
 }
 
 func NewScriptRealChecker() *RealChecker {
 	return &RealChecker{}
 }
+
+// NewCachingScriptRealChecker returns a checker backed by the global signature
+// cache. Signatures verified by it are only added to the cache when store is
+// true; otherwise cache hits are evicted, since they will not be needed again.
+func NewCachingScriptRealChecker(store bool) *RealChecker {
+	return &RealChecker{cached: true, store: store}
+}
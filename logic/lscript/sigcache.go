@@ -0,0 +1,260 @@
+package lscript
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	// DefaultMaxSigCacheEntries is the default number of verified signatures
+	// kept in the signature cache.
+	DefaultMaxSigCacheEntries = 100000
+
+	// DefaultMaxScriptCacheEntries is the default number of (txid, flags)
+	// pairs kept in the script execution cache.
+	DefaultMaxScriptCacheEntries = 100000
+)
+
+var (
+	sigCache    *SigCache
+	scriptCache *ScriptCache
+)
+
+// CacheStats is a point-in-time snapshot of a verification cache.
+type CacheStats struct {
+	Entries    uint
+	MaxEntries uint
+	Hits       uint64
+	Misses     uint64
+}
+
+// hashCache is a bounded, concurrency-safe set of salted hashes, each one
+// holding an int value. When the set is full an arbitrary entry is evicted,
+// relying on the random iteration order of go maps. Since every key is salted
+// with a per-process nonce an attacker can not predict which entries will be
+// evicted.
+type hashCache struct {
+	sync.RWMutex
+	entries    map[util.Hash]int
+	maxEntries uint
+	nonce      util.Hash
+	hits       uint64
+	misses     uint64
+}
+
+func newHashCache(maxEntries uint) *hashCache {
+	return &hashCache{
+		entries:    make(map[util.Hash]int, maxEntries),
+		maxEntries: maxEntries,
+		nonce:      *util.GetRandHash(),
+	}
+}
+
+func (hc *hashCache) get(key util.Hash, erase bool) (int, bool) {
+	var value int
+	var found bool
+	if erase {
+		hc.Lock()
+		value, found = hc.entries[key]
+		if found {
+			delete(hc.entries, key)
+		}
+		hc.Unlock()
+	} else {
+		hc.RLock()
+		value, found = hc.entries[key]
+		hc.RUnlock()
+	}
+
+	if found {
+		atomic.AddUint64(&hc.hits, 1)
+	} else {
+		atomic.AddUint64(&hc.misses, 1)
+	}
+	return value, found
+}
+
+func (hc *hashCache) exists(key util.Hash, erase bool) bool {
+	_, found := hc.get(key, erase)
+	return found
+}
+
+func (hc *hashCache) add(key util.Hash, value int) {
+	if hc.maxEntries == 0 {
+		return
+	}
+
+	hc.Lock()
+	defer hc.Unlock()
+
+	if uint(len(hc.entries)+1) > hc.maxEntries {
+		for k := range hc.entries {
+			delete(hc.entries, k)
+			break
+		}
+	}
+	hc.entries[key] = value
+}
+
+func (hc *hashCache) stats() CacheStats {
+	hc.RLock()
+	entries := uint(len(hc.entries))
+	hc.RUnlock()
+
+	return CacheStats{
+		Entries:    entries,
+		MaxEntries: hc.maxEntries,
+		Hits:       atomic.LoadUint64(&hc.hits),
+		Misses:     atomic.LoadUint64(&hc.misses),
+	}
+}
+
+// SigCache caches signatures which have already been verified, keyed on
+// (sighash, pubkey, signature, scheme), so that a transaction accepted to the mempool
+// does not need its ECDSA checks redone when the block containing it connects.
+type SigCache struct {
+	cache *hashCache
+}
+
+// NewSigCache returns a signature cache holding at most maxEntries signatures.
+func NewSigCache(maxEntries uint) *SigCache {
+	return &SigCache{cache: newHashCache(maxEntries)}
+}
+
+// The scheme byte of a cache key keeps a signature verified as Schnorr from
+// hitting when the same bytes are later checked as ECDSA, and vice versa.
+const (
+	sigSchemeECDSA   byte = 0
+	sigSchemeSchnorr byte = 1
+)
+
+func (sc *SigCache) key(sigHash *util.Hash, pubKey []byte, signature []byte, schnorr bool) util.Hash {
+	scheme := sigSchemeECDSA
+	if schnorr {
+		scheme = sigSchemeSchnorr
+	}
+	var buf bytes.Buffer
+	buf.Grow(util.Hash256Size*2 + len(pubKey) + len(signature) + 3)
+	buf.Write(sc.cache.nonce[:])
+	buf.WriteByte(scheme)
+	buf.Write(sigHash[:])
+	buf.WriteByte(byte(len(pubKey)))
+	buf.Write(pubKey)
+	buf.WriteByte(byte(len(signature)))
+	buf.Write(signature)
+	return util.Sha256Hash(buf.Bytes())
+}
+
+// Exists reports whether the signature has already been verified. When erase
+// is true a hit also removes the entry, which is what block validation wants:
+// a signature confirmed in a block will never be checked again.
+//
+// This function is safe for concurrent access.
+func (sc *SigCache) Exists(sigHash *util.Hash, pubKey []byte, signature []byte, schnorr bool, erase bool) bool {
+	if sc == nil {
+		return false
+	}
+	return sc.cache.exists(sc.key(sigHash, pubKey, signature, schnorr), erase)
+}
+
+// Add records a successfully verified signature, schnorr telling which
+// scheme it was verified with.
+//
+// This function is safe for concurrent access.
+func (sc *SigCache) Add(sigHash *util.Hash, pubKey []byte, signature []byte, schnorr bool) {
+	if sc == nil {
+		return
+	}
+	sc.cache.add(sc.key(sigHash, pubKey, signature, schnorr), 0)
+}
+
+// Stats returns the size and hit/miss counters of the cache.
+func (sc *SigCache) Stats() CacheStats {
+	if sc == nil {
+		return CacheStats{}
+	}
+	return sc.cache.stats()
+}
+
+// ScriptCache caches the (txid, flags) pairs of transactions whose every
+// input has already passed script verification under those flags, along with
+// the sigchecks counted by the verification.
+type ScriptCache struct {
+	cache *hashCache
+}
+
+// NewScriptCache returns a script execution cache holding at most maxEntries
+// entries.
+func NewScriptCache(maxEntries uint) *ScriptCache {
+	return &ScriptCache{cache: newHashCache(maxEntries)}
+}
+
+func (sc *ScriptCache) key(txid *util.Hash, flags uint32) util.Hash {
+	var buf [util.Hash256Size*2 + 4]byte
+	copy(buf[:], sc.cache.nonce[:])
+	copy(buf[util.Hash256Size:], txid[:])
+	binary.LittleEndian.PutUint32(buf[util.Hash256Size*2:], flags)
+	return util.Sha256Hash(buf[:])
+}
+
+// Exists reports whether all the scripts of txid were already verified with
+// exactly the given flags.
+//
+// This function is safe for concurrent access.
+func (sc *ScriptCache) Exists(txid *util.Hash, flags uint32) bool {
+	if sc == nil {
+		return false
+	}
+	return sc.cache.exists(sc.key(txid, flags), false)
+}
+
+// Get returns the sigchecks of txid when all its scripts were already
+// verified with exactly the given flags.
+//
+// This function is safe for concurrent access.
+func (sc *ScriptCache) Get(txid *util.Hash, flags uint32) (int, bool) {
+	if sc == nil {
+		return 0, false
+	}
+	return sc.cache.get(sc.key(txid, flags), false)
+}
+
+// Add records that all the scripts of txid passed with the given flags,
+// counting sigChecks signature checks.
+//
+// This function is safe for concurrent access.
+func (sc *ScriptCache) Add(txid *util.Hash, flags uint32, sigChecks int) {
+	if sc == nil {
+		return
+	}
+	sc.cache.add(sc.key(txid, flags), sigChecks)
+}
+
+// Stats returns the size and hit/miss counters of the cache.
+func (sc *ScriptCache) Stats() CacheStats {
+	if sc == nil {
+		return CacheStats{}
+	}
+	return sc.cache.stats()
+}
+
+// InitScriptCaches sets up the global signature and script execution caches.
+func InitScriptCaches(maxSigCacheEntries, maxScriptCacheEntries uint) {
+	sigCache = NewSigCache(maxSigCacheEntries)
+	scriptCache = NewScriptCache(maxScriptCacheEntries)
+}
+
+// GetSigCache returns the global signature cache, nil if not initialized.
+func GetSigCache() *SigCache {
+	return sigCache
+}
+
+// GetScriptCache returns the global script execution cache, nil if not
+// initialized.
+func GetScriptCache() *ScriptCache {
+	return scriptCache
+}
@@ -0,0 +1,122 @@
+package lscript
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigCacheAddExists(t *testing.T) {
+	sc := NewSigCache(10)
+	sigHash := util.Sha256Hash([]byte("sighash"))
+	pubKey := []byte{0x02, 0x01}
+	sig := []byte{0x30, 0x01}
+
+	assert.False(t, sc.Exists(&sigHash, pubKey, sig, false, false))
+	sc.Add(&sigHash, pubKey, sig, false)
+	assert.True(t, sc.Exists(&sigHash, pubKey, sig, false, false))
+
+	// any part of the key differing is a miss
+	otherHash := util.Sha256Hash([]byte("other"))
+	assert.False(t, sc.Exists(&otherHash, pubKey, sig, false, false))
+	assert.False(t, sc.Exists(&sigHash, []byte{0x03, 0x01}, sig, false, false))
+	assert.False(t, sc.Exists(&sigHash, pubKey, sig, true, false))
+	assert.False(t, sc.Exists(&sigHash, pubKey, []byte{0x30, 0x02}, false, false))
+
+	// an erasing lookup hits once
+	assert.True(t, sc.Exists(&sigHash, pubKey, sig, false, true))
+	assert.False(t, sc.Exists(&sigHash, pubKey, sig, false, false))
+
+	stats := sc.Stats()
+	assert.Equal(t, uint(0), stats.Entries)
+	assert.Equal(t, uint(10), stats.MaxEntries)
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(6), stats.Misses)
+}
+
+func TestSigCacheBounded(t *testing.T) {
+	sc := NewSigCache(5)
+	pubKey := []byte{0x02}
+	for i := 0; i < 20; i++ {
+		sigHash := util.Sha256Hash([]byte{byte(i)})
+		sc.Add(&sigHash, pubKey, []byte{byte(i)}, false)
+		assert.True(t, sc.Stats().Entries <= 5)
+	}
+	assert.Equal(t, uint(5), sc.Stats().Entries)
+
+	// the last added entry is never the one evicted
+	sigHash := util.Sha256Hash([]byte{19})
+	assert.True(t, sc.Exists(&sigHash, pubKey, []byte{19}, false, false))
+
+	disabled := NewSigCache(0)
+	disabled.Add(&sigHash, pubKey, []byte{19}, false)
+	assert.False(t, disabled.Exists(&sigHash, pubKey, []byte{19}, false, false))
+}
+
+func TestScriptCache(t *testing.T) {
+	sc := NewScriptCache(10)
+	txid := util.Sha256Hash([]byte("tx"))
+
+	assert.False(t, sc.Exists(&txid, 1))
+	sc.Add(&txid, 1, 5)
+	assert.True(t, sc.Exists(&txid, 1))
+	assert.False(t, sc.Exists(&txid, 3))
+
+	stats := sc.Stats()
+	assert.Equal(t, uint(1), stats.Entries)
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+
+	sigChecks, ok := sc.Get(&txid, 1)
+	assert.True(t, ok)
+	assert.Equal(t, 5, sigChecks)
+	_, ok = sc.Get(&txid, 3)
+	assert.False(t, ok)
+}
+
+func TestNilCaches(t *testing.T) {
+	var sc *SigCache
+	var scc *ScriptCache
+	h := util.Hash{}
+
+	sc.Add(&h, nil, nil, false)
+	assert.False(t, sc.Exists(&h, nil, nil, false, false))
+	assert.Equal(t, CacheStats{}, sc.Stats())
+
+	scc.Add(&h, 0, 1)
+	assert.False(t, scc.Exists(&h, 0))
+	_, ok := scc.Get(&h, 0)
+	assert.False(t, ok)
+	assert.Equal(t, CacheStats{}, scc.Stats())
+}
+
+func TestCachingCheckerVerifySignature(t *testing.T) {
+	InitScriptCaches(100, 100)
+
+	keyBytes := make([]byte, 32)
+	keyBytes[31] = 1
+	privKey := crypto.NewPrivateKeyFromBytes(keyBytes, true)
+	pubKey := privKey.PubKey()
+	sigHash := util.Sha256Hash([]byte("message"))
+	sig, err := privKey.Sign(sigHash[:])
+	assert.Nil(t, err)
+	sigBytes := sig.Serialize()
+
+	ok, err := NewCachingScriptRealChecker(true).VerifySignature(sigBytes, pubKey, &sigHash, 0)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.True(t, GetSigCache().Exists(&sigHash, pubKey.ToBytes(), sigBytes, false, false))
+
+	// a non-storing checker consumes the cached entry
+	ok, _ = NewCachingScriptRealChecker(false).VerifySignature(sigBytes, pubKey, &sigHash, 0)
+	assert.True(t, ok)
+	assert.False(t, GetSigCache().Exists(&sigHash, pubKey.ToBytes(), sigBytes, false, false))
+
+	// invalid signatures are never cached
+	badHash := util.Sha256Hash([]byte("other message"))
+	ok, _ = NewCachingScriptRealChecker(true).VerifySignature(sigBytes, pubKey, &badHash, 0)
+	assert.False(t, ok)
+	assert.False(t, GetSigCache().Exists(&badHash, pubKey.ToBytes(), sigBytes, false, false))
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/model/undo"
 	"github.com/copernet/copernicus/model/utxo"
 	"github.com/copernet/copernicus/util"
@@ -38,6 +39,7 @@ type ScriptVerifyJob struct {
 	Flags                  uint32
 	ScriptChecker          lscript.Checker
 	ScriptVerifyResultChan chan ScriptVerifyResult
+	SpentOutputs           []*txout.TxOut
 }
 
 type ScriptVerifyResult struct {
@@ -45,6 +47,7 @@ type ScriptVerifyResult struct {
 	ScriptSig    *script.Script
 	ScriptPubKey *script.Script
 	InputNum     int
+	SigChecks    int
 	Err          error
 }
 
@@ -53,12 +56,18 @@ type SignError struct {
 	ErrMsg string
 }
 
-func verifyResult(j ScriptVerifyJob, err error) ScriptVerifyResult {
-	return ScriptVerifyResult{j.Tx.GetHash(), j.ScriptSig, j.ScriptPubKey, j.IputNum, err}
+func verifyResult(j ScriptVerifyJob, sigChecks int, err error) ScriptVerifyResult {
+	return ScriptVerifyResult{j.Tx.GetHash(), j.ScriptSig, j.ScriptPubKey, j.IputNum, sigChecks, err}
 }
 
 const (
 	MaxScriptVerifyJobNum = 50000
+
+	// mempoolUpgradeFlags are the script flags of upgrades which the mempool
+	// adds to its standard or promiscuous flags once they are active.
+	mempoolUpgradeFlags = script.ScriptEnableReplayProtection | script.ScriptEnableCheckDataSig |
+		script.ScriptEnableSchnorr | script.ScriptEnableSchnorrMultisig | script.ScriptEnable64BitIntegers |
+		script.ScriptEnableNativeIntrospection | script.ScriptEnableTokens | script.ScriptEnableP2SH32
 )
 
 var (
@@ -75,6 +84,8 @@ func ScriptVerifyInit() {
 		blockScriptVerifyResultChan = make(chan ScriptVerifyResult, MaxScriptVerifyJobNum)
 		txScriptVerifyResultChan = make(chan ScriptVerifyResult, MaxScriptVerifyJobNum)
 
+		lscript.InitScriptCaches(conf.Cfg.Script.MaxSigCacheEntries, conf.Cfg.Script.MaxScriptCacheEntries)
+
 		for i := 0; i < conf.Cfg.Script.Par; i++ {
 			go checkScript()
 		}
@@ -86,6 +97,14 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 		return nil, err
 	}
 
+	// The outputs carry token data from the May 2023 upgrade, before which a
+	// token prefix is part of the scriptPubKey.
+	tip := chain.GetInstance().Tip()
+	isTokensEnabled := IsTokensEnabled(tip)
+	if isTokensEnabled {
+		txn.ReadTokenData()
+	}
+
 	if model.ActiveNetParams.RequireStandard {
 		ok, reason := txn.IsStandard()
 		if !ok {
@@ -125,6 +144,11 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 	if missingInput {
 		return nil, errcode.New(errcode.TxErrNoPreviousOut)
 	}
+	if isTokensEnabled {
+		if err := ReadInputsTokenData(txn, inputCoins, GetTokenActivationHeight(tip)); err != nil {
+			return nil, err
+		}
+	}
 
 	// CLTV(CheckLockTimeVerify)
 	// Only accept BIP68 sequence locked transactions that can be mined
@@ -158,9 +182,17 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 	// sigops, making it impossible to mine. Since the coinbase transaction
 	// itself can contain sigops MAX_STANDARD_TX_SIGOPS is less than
 	// MAX_BLOCK_SIGOPS_PER_MB; we still consider this an invalid rather
-	// than merely non-standard transaction.
+	// than merely non-standard transaction. After the phonon fork the
+	// sigchecks counted by the script verification are limited instead.
+	isPhononEnabled := model.ActiveNetParams.IsRuleEnabled(consensus.RuleSigChecks, tip.Height+1, tip.GetMedianTimePast())
+
+	// Token data and P2SH32 outputs are not relayed before the May 2023
+	// upgrade, as they are only given their meaning by it.
+	if !isTokensEnabled && HasTokensOrP2SH32(txn, inputCoins) {
+		return nil, errcode.NewError(errcode.RejectNonstandard, "txn-tokens-before-activation")
+	}
 	sigOpsCount := GetTransactionSigOpCount(txn, uint32(script.StandardScriptVerifyFlags), inputCoins)
-	if uint(sigOpsCount) > tx.MaxStandardTxSigOps {
+	if !isPhononEnabled && uint(sigOpsCount) > tx.MaxStandardTxSigOps {
 		return nil, errcode.NewError(errcode.RejectNonstandard, "bad-txns-too-many-sigops")
 	}
 
@@ -173,16 +205,10 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 	//TODO: Continuously rate-limit free (really, very-low-fee) transactions.
 	//TODO: check absurdly-high-fee (nFees > nAbsurdFee)
 
-	var extraFlags uint32 = script.ScriptVerifyNone
-	tip := chain.GetInstance().Tip()
-
-	if model.IsReplayProtectionEnabled(tip.GetMedianTimePast()) {
-		extraFlags |= script.ScriptEnableReplayProtection
-	}
-
-	if model.IsMagneticAnomalyEnabled(tip.GetMedianTimePast()) {
-		extraFlags |= script.ScriptEnableCheckDataSig
-	}
+	// The upgrades active for the next block enable new opcodes and
+	// signature types, their other flags being enforced by the standard flags.
+	extraFlags := model.ActiveNetParams.GetUpgradeScriptFlags(tip.Height+1, tip.GetMedianTimePast()) &
+		mempoolUpgradeFlags
 
 	//check inputs
 	var scriptVerifyFlags = uint32(script.StandardScriptVerifyFlags)
@@ -193,11 +219,15 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 
 	// Check against previous transactions. This is done last to help
 	// prevent CPU exhaustion denial-of-service attacks.
-	err = checkInputs(txn, inputCoins, scriptVerifyFlags, txScriptVerifyResultChan)
+	sigChecks, err := checkInputs(txn, inputCoins, scriptVerifyFlags, txScriptVerifyResultChan, true, false)
 	if err != nil {
 		return nil, err
 	}
 
+	if isPhononEnabled && sigChecks > tx.MaxStandardTxSigChecks {
+		return nil, errcode.NewError(errcode.RejectNonstandard, "bad-txns-too-many-sigchecks")
+	}
+
 	// Check again against the current block tip's script verification flags
 	// to cache our script execution flags. This is, of course, useless if
 	// the next block has different script flags from the previous one, but
@@ -214,12 +244,13 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 	// invalid blocks (using TestBlockValidity), however allowing such
 	// transactions into the mempool can be exploited as a DoS attack.
 	var currentBlockScriptVerifyFlags = chain.GetInstance().GetBlockScriptFlags(tip)
-	err = checkInputs(txn, inputCoins, currentBlockScriptVerifyFlags, txScriptVerifyResultChan)
+	_, err = checkInputs(txn, inputCoins, currentBlockScriptVerifyFlags, txScriptVerifyResultChan, true, true)
 	if err != nil {
 		if ((^scriptVerifyFlags) & currentBlockScriptVerifyFlags) == 0 {
 			return nil, errcode.New(errcode.ScriptCheckInputsBug)
 		}
-		err = checkInputs(txn, inputCoins, uint32(script.MandatoryScriptVerifyFlags)|extraFlags, txScriptVerifyResultChan)
+		_, err = checkInputs(txn, inputCoins, uint32(script.MandatoryScriptVerifyFlags)|extraFlags,
+			txScriptVerifyResultChan, false, false)
 		if err != nil {
 			return nil, err
 		}
@@ -230,6 +261,7 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 
 	txEntry := mempool.NewTxentry(txn, txFee, util.GetTimeSec(),
 		chain.GetInstance().Height(), *lp, sigOpsCount, spendCoinbase)
+	txEntry.SetSigChecks(sigChecks)
 
 	return txEntry, nil
 }
@@ -260,7 +292,7 @@ func promiscuousMempoolFlags() uint32 {
 }
 
 // CheckBlockTransactions block service use these 3 func to check transactions or to apply transaction while connecting block to active chain
-func CheckBlockTransactions(txs []*tx.Tx, maxBlockSigOps uint64) error {
+func CheckBlockTransactions(txs []*tx.Tx) error {
 	txsLen := len(txs)
 	if txsLen == 0 {
 		log.Debug("block has no transactions")
@@ -270,16 +302,9 @@ func CheckBlockTransactions(txs []*tx.Tx, maxBlockSigOps uint64) error {
 	if err != nil {
 		return err
 	}
-	sigOps := txs[0].GetSigOpCountWithoutP2SH(uint32(script.StandardScriptVerifyFlags))
 
 	TxsInputOutpoint := make(map[outpoint.OutPoint]bool)
-	for i, transaction := range txs[1:] {
-		sigOps += txs[i+1].GetSigOpCountWithoutP2SH(uint32(script.StandardScriptVerifyFlags))
-		if uint64(sigOps) > maxBlockSigOps {
-			log.Debug("block has too many sigOps:%d", sigOps)
-			return errcode.NewError(errcode.RejectInvalid, "bad-blk-sigops")
-		}
-
+	for _, transaction := range txs[1:] {
 		err := transaction.CheckRegularTransactionWhenNewBlock(TxsInputOutpoint)
 		if err != nil {
 			return err
@@ -288,6 +313,20 @@ func CheckBlockTransactions(txs []*tx.Tx, maxBlockSigOps uint64) error {
 	return nil
 }
 
+// CheckBlockSigOps checks the legacy sigops count of the block transactions,
+// which is only limited before the phonon fork.
+func CheckBlockSigOps(txs []*tx.Tx, maxBlockSigOps uint64) error {
+	sigOps := 0
+	for _, transaction := range txs {
+		sigOps += transaction.GetSigOpCountWithoutP2SH(uint32(script.StandardScriptVerifyFlags))
+		if uint64(sigOps) > maxBlockSigOps {
+			log.Debug("block has too many sigOps:%d", sigOps)
+			return errcode.NewError(errcode.RejectInvalid, "bad-blk-sigops")
+		}
+	}
+	return nil
+}
+
 func ContextureCheckBlockTransactions(txs []*tx.Tx, blockHeight int32, blockLockTime, mediaTimePast int64) error {
 	txsLen := len(txs)
 	if txsLen == 0 {
@@ -301,7 +340,7 @@ func ContextureCheckBlockTransactions(txs []*tx.Tx, blockHeight int32, blockLock
 
 	var prevTx *tx.Tx
 	for _, transaction := range txs {
-		if model.IsMagneticAnomalyEnabled(mediaTimePast) {
+		if model.ActiveNetParams.IsRuleEnabled(consensus.RuleCanonicalTxOrder, blockHeight, mediaTimePast) {
 			if prevTx != nil {
 				transactionHash := transaction.GetHash()
 				prevTxHash := prevTx.GetHash()
@@ -332,6 +371,7 @@ func ApplyBlockTransactions(txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uin
 	coinsMap := utxo.NewEmptyCoinsMap()
 	utxoCache := utxo.GetUtxoCacheInstance()
 	sigOpsCount := 0
+	sigChecksCount := 0
 	var fees amount.Amount
 	bundo = undo.NewBlockUndo(0)
 
@@ -348,8 +388,24 @@ func ApplyBlockTransactions(txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uin
 		}
 	}
 
+	// The outputs carry token data from the May 2023 upgrade, and so do the
+	// coins created from then on.
+	isTokensEnabled := scriptCheckFlags&script.ScriptEnableTokens != 0
+	var tokenActivationHeight int32
+	if isTokensEnabled {
+		tokenActivationHeight = GetTokenActivationHeight(pindex.Prev)
+		for _, transaction := range txs {
+			transaction.ReadTokenData()
+		}
+	}
+
 	txUndoList := make([]*undo.TxUndo, 0, len(txs)-1)
-	isMagneticAnomalyEnabled := model.IsMagneticAnomalyEnabled(pindex.GetMedianTimePast())
+	isMagneticAnomalyEnabled := model.ActiveNetParams.IsRuleEnabled(consensus.RuleCanonicalTxOrder, pindex.Height,
+		pindex.GetMedianTimePast())
+	// The limits of the block are those of the fork enabled by its parent.
+	isPhononEnabled := pindex.Prev != nil &&
+		model.ActiveNetParams.IsRuleEnabled(consensus.RuleSigChecks, pindex.Height, pindex.Prev.GetMedianTimePast())
+	blockMaxSigChecksCount := consensus.GetMaxBlockSigChecksCount(conf.Cfg.Excessiveblocksize)
 
 	for _, ptx := range txs {
 		//pos := block.DiskTxPos{
@@ -360,7 +416,7 @@ func ApplyBlockTransactions(txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uin
 		//pos = vPos[ptx.GetHash()]
 		//pos.TxOffsetIn += ptx.EncodeSize()
 
-		if ptx.IsCoinBase() {
+		if ptx.IsCoinBase() && !isPhononEnabled {
 			// We've already checked for sigops count before P2SH in CheckBlock.
 			sigOpsCount += ptx.GetSigOpCountWithoutP2SH(scriptCheckFlags)
 		}
@@ -382,6 +438,11 @@ func ApplyBlockTransactions(txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uin
 				return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-txns-inputs-missingorspent")
 			}
 		}
+		if isTokensEnabled {
+			if err := ReadInputsTokenData(transaction, coinsMap, tokenActivationHeight); err != nil {
+				return nil, nil, err
+			}
+		}
 
 		// Check that transaction is BIP68 final BIP68 lock checks (as
 		// opposed to nLockTime checks) must be in ConnectBlock because they
@@ -392,18 +453,20 @@ func ApplyBlockTransactions(txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uin
 			return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-txns-nonfinal")
 		}
 
-		// GetTransactionSigOpCount counts 2 types of sigops:
-		// * legacy (always)
-		// * p2sh (when P2SH enabled in flags and excludes coinbase)
-		sigsCount := GetTransactionSigOpCount(transaction, scriptCheckFlags, coinsMap)
-		if sigsCount > tx.MaxTxSigOpsCounts {
-			log.Debug("transaction has too many sigops")
-			return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-txn-sigops")
-		}
-		sigOpsCount += sigsCount
-		if sigOpsCount > int(blockMaxSigOpsCount) {
-			log.Debug("block has too many sigops at %d transaction", i)
-			return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-blk-sigops")
+		if !isPhononEnabled {
+			// GetTransactionSigOpCount counts 2 types of sigops:
+			// * legacy (always)
+			// * p2sh (when P2SH enabled in flags and excludes coinbase)
+			sigsCount := GetTransactionSigOpCount(transaction, scriptCheckFlags, coinsMap)
+			if sigsCount > tx.MaxTxSigOpsCounts {
+				log.Debug("transaction has too many sigops")
+				return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-txn-sigops")
+			}
+			sigOpsCount += sigsCount
+			if sigOpsCount > int(blockMaxSigOpsCount) {
+				log.Debug("block has too many sigops at %d transaction", i)
+				return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-blk-sigops")
+			}
 		}
 
 		fee := coinsMap.GetValueIn(transaction) - transaction.GetValueOut()
@@ -411,13 +474,27 @@ func ApplyBlockTransactions(txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uin
 
 		if needCheckScript {
 			//check inputs
-			err := checkInputs(transaction, coinsMap, scriptCheckFlags, blockScriptVerifyResultChan)
+			sigChecks, err := checkInputs(transaction, coinsMap, scriptCheckFlags, blockScriptVerifyResultChan, false, false)
 			if err != nil {
 				if strings.Contains(err.Error(), "script-verify") {
 					return nil, nil, errcode.NewError(errcode.RejectInvalid, "blk-bad-inputs")
 				}
 				return nil, nil, err
 			}
+
+			// The sigchecks are counted while verifying the scripts, so
+			// they are only limited when the scripts are checked.
+			if isPhononEnabled {
+				if sigChecks > consensus.MaxTxSigChecksCount {
+					log.Debug("transaction has too many sigchecks")
+					return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-txn-sigchecks")
+				}
+				sigChecksCount += sigChecks
+				if uint64(sigChecksCount) > blockMaxSigChecksCount {
+					log.Debug("block has too many sigchecks at %d transaction", i)
+					return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-blk-sigchecks")
+				}
+			}
 		}
 
 		//update temp coinsMap
@@ -475,7 +552,7 @@ func ContextualCheckTransaction(txn *tx.Tx, nBlockHeight int32, nLockTimeCutoff,
 	//	}
 	//}
 
-	if model.IsMagneticAnomalyEnabled(mediaTimePast) {
+	if model.ActiveNetParams.IsRuleEnabled(consensus.RuleCanonicalTxOrder, nBlockHeight, mediaTimePast) {
 		txnsize := txn.SerializeSize()
 		if txnsize < consensus.MinTxSize {
 			e := fmt.Sprintf("bad-txns-undersize: tx(%d) should be equal to or greater than %d",
@@ -610,7 +687,7 @@ func AreInputsStandard(transaction *tx.Tx, coinsMap *utxo.CoinsMap) bool {
 			log.Debug("AreInputsStandard GetPubkeyType err: not StandardScriptPubKey")
 			return false
 		}
-		if pubKeyType == script.ScriptHash {
+		if pubKeyType == script.ScriptHash || pubKeyType == script.ScriptHash32 {
 			scriptSig := e.GetScriptSig()
 			stack := util.NewStack()
 			err := lscript.EvalScript(stack, scriptSig, transaction, i, amount.Amount(0), script.ScriptVerifyNone,
@@ -637,23 +714,54 @@ func AreInputsStandard(transaction *tx.Tx, coinsMap *utxo.CoinsMap) bool {
 	return true
 }
 
+// checkInputs verifies the input money and scripts of tx. Scripts already
+// verified with the same flags are skipped through the script execution cache.
+// cacheSigStore adds freshly verified signatures to the signature cache, and
+// cacheFullScriptStore records the whole transaction in the script cache once
+// every input passed.
 func checkInputs(tx *tx.Tx, tempCoinMap *utxo.CoinsMap, flags uint32,
-	scriptVerifyResultChan chan ScriptVerifyResult) error {
+	scriptVerifyResultChan chan ScriptVerifyResult, cacheSigStore bool, cacheFullScriptStore bool) (int, error) {
 	//check inputs money range
 	bestBlockHash, _ := utxo.GetUtxoCacheInstance().GetBestBlock()
 	spendHeight := chain.GetInstance().GetSpendHeight(&bestBlockHash)
 	if spendHeight == -1 {
 		log.Debug("indexMap can`t find bestblock")
-		return errcode.New(errcode.RejectInvalid)
+		return 0, errcode.New(errcode.RejectInvalid)
 	}
 
 	err := CheckInputsMoney(tx, tempCoinMap, spendHeight)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	if flags&script.ScriptEnableTokens != 0 {
+		if err := CheckTxTokens(tx, tempCoinMap); err != nil {
+			return 0, err
+		}
+	}
+
+	txHash := tx.GetHash()
+	scriptCache := lscript.GetScriptCache()
+	if sigChecks, ok := scriptCache.Get(&txHash, flags); ok {
+		log.Debug("script cache hit, txid: %s, flags: %d", txHash.String(), flags)
+		return sigChecks, nil
 	}
 
 	ins := tx.GetIns()
 	insLen := len(ins)
+	sigChecks := 0
+
+	// The native introspection opcodes may read the output spent by any
+	// input of the transaction.
+	spentOutputs := make([]*txout.TxOut, insLen)
+	for i, in := range ins {
+		coin := tempCoinMap.GetCoin(in.PreviousOutPoint)
+		if coin == nil {
+			panic("can't find coin in temp coinsmap")
+		}
+		spentOut := coin.GetTxOut()
+		spentOutputs[i] = &spentOut
+	}
 
 	batches := insLen / MaxScriptVerifyJobNum
 	reminder := insLen % MaxScriptVerifyJobNum
@@ -671,15 +779,13 @@ func checkInputs(tx *tx.Tx, tempCoinMap *utxo.CoinsMap, flags uint32,
 		for j := 0; j < jobNum; j++ {
 			index := batch*MaxScriptVerifyJobNum + j
 
-			coin := tempCoinMap.GetCoin(ins[index].PreviousOutPoint)
-			if coin == nil {
-				panic("can't find coin in temp coinsmap")
-			}
-			scriptPubKey := coin.GetScriptPubKey()
+			spentOut := spentOutputs[index]
+			scriptPubKey := spentOut.GetScriptPubKey()
 			scriptSig := ins[index].GetScriptSig()
 			log.Debug("Push Script verify job txid: %s, inex: %d", tx.GetHash().String(), index)
 			scriptVerifyJobChan <- ScriptVerifyJob{tx, scriptSig, scriptPubKey, index,
-				coin.GetAmount(), flags, lscript.NewScriptRealChecker(), scriptVerifyResultChan}
+				spentOut.GetValue(), flags, lscript.NewCachingScriptRealChecker(cacheSigStore),
+				scriptVerifyResultChan, spentOutputs}
 		}
 
 		var err error
@@ -687,6 +793,7 @@ func checkInputs(tx *tx.Tx, tempCoinMap *utxo.CoinsMap, flags uint32,
 		//drain all result from channel
 		for k := 0; k < jobNum; k++ {
 			result := <-scriptVerifyResultChan
+			sigChecks += result.SigChecks
 			if result.Err != nil {
 				log.Debug("Read script verify err result: %v, tx hash: %s, index: %d, "+
 					"len of scriptVerifyResultChan: %d", result.Err, result.TxHash.String(),
@@ -698,35 +805,42 @@ func checkInputs(tx *tx.Tx, tempCoinMap *utxo.CoinsMap, flags uint32,
 		}
 
 		if err != nil {
-			return err
+			return 0, err
 		}
 	}
 
-	return nil
+	if cacheFullScriptStore {
+		scriptCache.Add(&txHash, flags, sigChecks)
+	}
+
+	return sigChecks, nil
 }
 
 func checkScript() {
 	for {
 		j := <-scriptVerifyJobChan
 
-		err1 := lscript.VerifyScript(j.Tx, j.ScriptSig, j.ScriptPubKey, j.IputNum, j.Value, j.Flags, j.ScriptChecker)
+		var metrics lscript.ScriptExecutionMetrics
+		err1 := lscript.VerifyScriptWithContext(j.Tx, j.ScriptSig, j.ScriptPubKey, j.IputNum, j.Value, j.Flags,
+			j.ScriptChecker, j.SpentOutputs, &metrics)
 		if err1 != nil {
 
 			hasNonMandatoryFlags := (j.Flags & uint32(script.StandardNotMandatoryVerifyFlags)) != 0
 			if hasNonMandatoryFlags {
 				fallbackFlags := uint32(uint64(j.Flags) & uint64(^script.StandardNotMandatoryVerifyFlags))
-				err2 := lscript.VerifyScript(j.Tx, j.ScriptSig, j.ScriptPubKey, j.IputNum, j.Value, fallbackFlags, j.ScriptChecker)
+				err2 := lscript.VerifyScriptWithContext(j.Tx, j.ScriptSig, j.ScriptPubKey, j.IputNum, j.Value,
+					fallbackFlags, j.ScriptChecker, j.SpentOutputs, nil)
 				if err2 == nil {
-					j.ScriptVerifyResultChan <- verifyResult(j, errorNonMandatoryPass(j, err1))
+					j.ScriptVerifyResultChan <- verifyResult(j, 0, errorNonMandatoryPass(j, err1))
 					continue
 				}
 			}
 
-			j.ScriptVerifyResultChan <- verifyResult(j, errorMandatoryFailed(j, err1))
+			j.ScriptVerifyResultChan <- verifyResult(j, 0, errorMandatoryFailed(j, err1))
 			continue
 		}
 
-		j.ScriptVerifyResultChan <- verifyResult(j, nil)
+		j.ScriptVerifyResultChan <- verifyResult(j, metrics.SigChecks, nil)
 	}
 }
 
@@ -949,6 +1063,16 @@ func SignRawTransaction(transactions []*tx.Tx, redeemScripts map[outpoint.OutPoi
 	mergedTx := transactions[0]
 	hashSingle := int(hashType) & ^(crypto.SigHashAnyoneCanpay|crypto.SigHashForkID) == crypto.SigHashSingle
 
+	// The signatures commit to the token data of the outputs spent, which are
+	// also read by the native introspection opcodes.
+	spentOutputs := make([]*txout.TxOut, mergedTx.GetInsCount())
+	for index, in := range mergedTx.GetIns() {
+		if coin := coinsMap.GetCoin(in.PreviousOutPoint); coin != nil {
+			spentOut := coin.GetTxOut()
+			spentOutputs[index] = &spentOut
+		}
+	}
+
 	for index, in := range mergedTx.GetIns() {
 		coin := coinsMap.GetCoin(in.PreviousOutPoint)
 		if !isCoinValid(coin, in.PreviousOutPoint) {
@@ -962,18 +1086,19 @@ func SignRawTransaction(transactions []*tx.Tx, redeemScripts map[outpoint.OutPoi
 		scriptSig := script.NewEmptyScript()
 		scriptPubKey := coin.GetScriptPubKey()
 		value := coin.GetAmount()
+		tokenData := coin.GetTokenData()
 
 		// Only sign SIGHASH_SINGLE if there's a corresponding output
 		if !hashSingle || index < mergedTx.GetOutsCount() {
 			redeemScript := redeemScripts[*in.PreviousOutPoint]
 			// Sign what we can
-			sigData, err := mergedTx.SignStep(index, keyStore, redeemScript, hashType, scriptPubKey, value)
+			sigData, err := mergedTx.SignStep(index, keyStore, redeemScript, hashType, scriptPubKey, value, tokenData)
 			if err != nil {
 				log.Info("SignStep error:%s", err.Error())
 			} else {
 				scriptSig.PushMultData(sigData)
-				err = lscript.VerifyScript(mergedTx, scriptSig, scriptPubKey, index, value,
-					uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker())
+				err = lscript.VerifyScriptWithContext(mergedTx, scriptSig, scriptPubKey, index, value,
+					uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker(), spentOutputs, nil)
 				if err != nil {
 					scriptSig = script.NewEmptyScript()
 					log.Info("VerifyScript error:%s", err.Error())
@@ -985,7 +1110,7 @@ func SignRawTransaction(transactions []*tx.Tx, redeemScripts map[outpoint.OutPoi
 		for _, transaction := range transactions {
 			if len(transaction.GetIns()) > index {
 				scriptSig, err = CombineSignature(transaction, scriptPubKey, scriptSig,
-					transaction.GetIns()[index].GetScriptSig(), index, value,
+					transaction.GetIns()[index].GetScriptSig(), index, value, tokenData,
 					uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker())
 				if err != nil {
 					log.Info("CombineSignature error:%s", err.Error())
@@ -998,8 +1123,8 @@ func SignRawTransaction(transactions []*tx.Tx, redeemScripts map[outpoint.OutPoi
 			log.Info("UpdateInScript error:%s", err.Error())
 		}
 
-		err = lscript.VerifyScript(mergedTx, scriptSig, scriptPubKey, index, value,
-			uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker())
+		err = lscript.VerifyScriptWithContext(mergedTx, scriptSig, scriptPubKey, index, value,
+			uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker(), spentOutputs, nil)
 		if err != nil {
 			signErrors = append(signErrors, &SignError{
 				TxIn:   in,
@@ -1012,7 +1137,7 @@ func SignRawTransaction(transactions []*tx.Tx, redeemScripts map[outpoint.OutPoi
 }
 
 func CombineSignature(transaction *tx.Tx, prevPubKey *script.Script, scriptSig *script.Script,
-	txOldScriptSig *script.Script, nIn int, money amount.Amount, flags uint32,
+	txOldScriptSig *script.Script, nIn int, money amount.Amount, tokenData *txout.TokenData, flags uint32,
 	scriptChecker lscript.Checker) (*script.Script, error) {
 	if scriptSig == nil {
 		scriptSig = script.NewEmptyScript()
@@ -1056,7 +1181,7 @@ func CombineSignature(transaction *tx.Tx, prevPubKey *script.Script, scriptSig *
 				if okSigs[string(pubKey)] != nil {
 					continue
 				}
-				ok, err := scriptChecker.CheckSig(transaction, opCode.Data, pubKey, prevPubKey, nIn, money, flags)
+				ok, err := scriptChecker.CheckSig(transaction, opCode.Data, pubKey, prevPubKey, nIn, money, tokenData, flags)
 				if err == nil && ok {
 					okSigs[string(pubKey)] = opCode.Data
 					break
@@ -1095,7 +1220,7 @@ func CombineSignature(transaction *tx.Tx, prevPubKey *script.Script, scriptSig *
 		scriptSig = scriptSig.RemoveOpCodeByIndex(len(scriptSig.ParsedOpCodes) - 1)
 		txOldScriptSig = txOldScriptSig.RemoveOpCodeByIndex(len(txOldScriptSig.ParsedOpCodes) - 1)
 		scriptResult, err := CombineSignature(transaction, redeemScript, scriptSig,
-			txOldScriptSig, nIn, money, flags, scriptChecker)
+			txOldScriptSig, nIn, money, tokenData, flags, scriptChecker)
 		scriptResult.PushSingleData(redeemScript.GetData())
 		return scriptResult, err
 	}
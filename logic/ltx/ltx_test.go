@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"math/rand"
 	"os"
 	"reflect"
@@ -632,6 +633,7 @@ func check(v *Var, lockingScript *script.Script, t *testing.T) {
 		scriptSig,
 		empty,
 		0, 0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -647,6 +649,7 @@ func check(v *Var, lockingScript *script.Script, t *testing.T) {
 		empty,
 		scriptSig,
 		0, 0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -667,6 +670,7 @@ func check(v *Var, lockingScript *script.Script, t *testing.T) {
 		empty,
 		0,
 		0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -719,6 +723,7 @@ func TestCombineSignature(t *testing.T) {
 		empty,
 		0,
 		0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -778,6 +783,7 @@ func TestCombineSignature(t *testing.T) {
 		scriptSig,
 		0,
 		0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -793,6 +799,7 @@ func TestCombineSignature(t *testing.T) {
 		dummyLockingScript,
 		0,
 		0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -837,6 +844,7 @@ func TestCombineSignature(t *testing.T) {
 		scriptSig,
 		empty,
 		0, 0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -855,6 +863,7 @@ func TestCombineSignature(t *testing.T) {
 		empty,
 		scriptSig,
 		0, 0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -941,6 +950,7 @@ func TestCombineSignature(t *testing.T) {
 		partial1b,
 		0,
 		0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -956,6 +966,7 @@ func TestCombineSignature(t *testing.T) {
 		partial2a,
 		0,
 		0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -972,6 +983,7 @@ func TestCombineSignature(t *testing.T) {
 		partial1a,
 		0,
 		0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -987,6 +999,7 @@ func TestCombineSignature(t *testing.T) {
 		partial2b,
 		0,
 		0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -1002,6 +1015,7 @@ func TestCombineSignature(t *testing.T) {
 		partial1b,
 		0,
 		0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -1016,6 +1030,7 @@ func TestCombineSignature(t *testing.T) {
 		partial3a,
 		0,
 		0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -1030,6 +1045,7 @@ func TestCombineSignature(t *testing.T) {
 		partial2b,
 		0,
 		0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -1045,6 +1061,7 @@ func TestCombineSignature(t *testing.T) {
 		partial3a,
 		0,
 		0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -1054,6 +1071,43 @@ func TestCombineSignature(t *testing.T) {
 	}
 }
 
+// TestSignRawTransactionTokenData tests that the signatures commit to the
+// token data of the coin spent.
+func TestSignRawTransactionTokenData(t *testing.T) {
+	v := initVar()
+
+	p2PKHLockingScript := script.NewEmptyScript()
+	p2PKHLockingScript.PushOpCode(opcodes.OP_DUP)
+	p2PKHLockingScript.PushOpCode(opcodes.OP_HASH160)
+	p2PKHLockingScript.PushSingleData(btcutil.Hash160(v.pubKeys[0].ToBytes()))
+	p2PKHLockingScript.PushOpCode(opcodes.OP_EQUALVERIFY)
+	p2PKHLockingScript.PushOpCode(opcodes.OP_CHECKSIG)
+
+	prevOut := txout.NewTxOut(1000, p2PKHLockingScript)
+	prevOut.SetTokenData(&txout.TokenData{Category: util.Hash{1}, Amount: 10})
+	v.prevHolder.AddTxOut(prevOut)
+	v.spender.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(v.prevHolder.GetHash(), 0),
+		script.NewEmptyScript(), script.SequenceFinal))
+	v.spender.AddTxOut(txout.NewTxOut(900, p2PKHLockingScript))
+	v.coins = utxo.NewEmptyCoinsMap()
+	v.coins.AddCoin(v.spender.GetIns()[0].PreviousOutPoint, utxo.NewFreshCoin(prevOut, 1, false), true)
+
+	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
+	errs := ltx.SignRawTransaction([]*tx.Tx{&v.spender}, v.redeemScripts, v.keyStore, v.coins, hashType)
+	checkErrors(errs, t)
+
+	scriptSig := v.spender.GetIns()[0].GetScriptSig()
+	flags := uint32(script.StandardScriptVerifyFlags)
+	err := lscript.VerifyScriptWithContext(&v.spender, scriptSig, p2PKHLockingScript, 0, 1000, flags,
+		lscript.NewScriptRealChecker(), []*txout.TxOut{prevOut}, nil)
+	assert.NoError(t, err)
+
+	// Without the token data of the coin, the signature does not match.
+	err = lscript.VerifyScript(&v.spender, scriptSig, p2PKHLockingScript, 0, 1000, flags,
+		lscript.NewScriptRealChecker())
+	assert.Error(t, err)
+}
+
 // TestSignRawTransactionErrors tests the SignRawTransaction function error paths.
 func TestSignRawTransactionErrors(t *testing.T) {
 	v := initVar()
@@ -1123,6 +1177,7 @@ func TestNonStandardCombineSignature(t *testing.T) {
 		nil,
 		scriptOldSig,
 		0, 0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -1140,6 +1195,7 @@ func TestNonStandardCombineSignature(t *testing.T) {
 		scriptSig,
 		scriptOldSig,
 		0, 0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -1160,6 +1216,7 @@ func TestNonStandardCombineSignature(t *testing.T) {
 		nil,
 		scriptOldSig,
 		0, 0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -1177,6 +1234,7 @@ func TestNonStandardCombineSignature(t *testing.T) {
 		scriptSig,
 		scriptOldSig,
 		0, 0,
+		nil,
 		standardScriptVerifyFlags,
 		realChecker,
 	)
@@ -1287,6 +1345,8 @@ func Test_coinbase_tx_should_not_be_accepted_into_mempool(t *testing.T) {
 }
 
 func Test_non_standard_tx_should_not_be_accepted_into_mempool(t *testing.T) {
+	defer initTestEnv()()
+
 	model.ActiveNetParams.RequireStandard = true
 	txnWithInvalidVersion := mainNetTx(0)
 
@@ -1295,6 +1355,9 @@ func Test_non_standard_tx_should_not_be_accepted_into_mempool(t *testing.T) {
 }
 
 func Test_dust_tx_should_NOT_be_accepted_into_mempool(t *testing.T) {
+	defer initTestEnv()()
+
+	model.ActiveNetParams.RequireStandard = true
 	txn := mainNetTx(1)
 
 	givenDustRelayFeeLimits(int64(txn.GetValueOut() - 1))
@@ -1663,12 +1726,63 @@ func Test_tx_with_too_many_script_ops_should_NOT_be_accepted_into_mempool(t *tes
 	defer initTestEnv()()
 
 	blocks := generateTestBlocks(t)
+
+	// the sigops are only limited before the phonon fork
+	phononActivationTime := model.ActiveNetParams.PhononActivationTime
+	model.ActiveNetParams.PhononActivationTime = math.MaxInt64
+	defer func() {
+		model.ActiveNetParams.PhononActivationTime = phononActivationTime
+	}()
+
 	txn := txWithTooManyScriptOps(blocks[0].Txs[0].GetHash(), 0)
 	err := lmempool.AcceptTxToMemPool(txn)
 
 	assert.Equal(t, errcode.NewError(errcode.RejectNonstandard, "bad-txns-too-many-sigops"), err)
 }
 
+func checkDataSigScript(count int) *script.Script {
+	key := NewPrivateKey()
+	message := []byte("sigchecks")
+	hash := util.Sha256Hash(message)
+	sig, _ := key.Sign(hash[:])
+
+	sb := NewScriptBuilder()
+	for i := 0; i < count; i++ {
+		sb.PushBytesWithOP(sig.Serialize()).
+			PushBytesWithOP(message).
+			PushBytesWithOP(key.PubKey().ToBytes()).
+			PushOPCode(opcodes.OP_CHECKDATASIGVERIFY)
+	}
+	return sb.PushOPCode(opcodes.OP_TRUE).Script()
+}
+
+func Test_tx_sigchecks_should_be_counted_into_mempool_entry(t *testing.T) {
+	defer initTestEnv()()
+
+	blocks := generateTestBlocksWithPK(t, checkDataSigScript(1))
+	txn := makeNormalTx(blocks[0].Txs[0].GetHash())
+
+	entry, err := ltx.CheckTxBeforeAcceptToMemPool(txn)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, entry.SigChecks)
+	assert.Equal(t, int64(1), entry.SumTxSigChecksWithAncestors)
+}
+
+func Test_tx_with_too_dense_sigchecks_should_NOT_be_accepted_into_mempool(t *testing.T) {
+	defer initTestEnv()()
+
+	// 2 sigchecks need a scriptSig of at least 26 bytes
+	blocks := generateTestBlocksWithPK(t, checkDataSigScript(2))
+	txn := makeNormalTx(blocks[0].Txs[0].GetHash())
+
+	_, err := ltx.CheckTxBeforeAcceptToMemPool(txn)
+
+	expectedErr := errcode.NewError(errcode.RejectNonstandard,
+		"non-mandatory-script-verify-flag (Input SigChecks limit exceeded)")
+	assert.Equal(t, expectedErr, err)
+}
+
 func Test_tx_with_too_low_fee_should_NOT_be_accepted_into_mempool(t *testing.T) {
 	defer initTestEnv()()
 
@@ -1703,7 +1817,7 @@ func Test_block_txns__should_contains_one_coinbase_tx(t *testing.T) {
 	txn := mainNetTx(1)
 	txns := []*tx.Tx{txn}
 
-	err := ltx.CheckBlockTransactions(txns, 0)
+	err := ltx.CheckBlockTransactions(txns)
 
 	assert.Equal(t, errcode.NewError(errcode.RejectInvalid, "bad-cb-missing"), err)
 }
@@ -1723,7 +1837,7 @@ func newCoinbaseTx() *tx.Tx {
 func Test_block_txns__should_at_least_contains_one_txn(t *testing.T) {
 	txns := []*tx.Tx{}
 
-	err := ltx.CheckBlockTransactions(txns, consensus.MaxBlockSigopsPerMb)
+	err := ltx.CheckBlockTransactions(txns)
 
 	assert.Equal(t, errcode.NewError(errcode.RejectInvalid, "bad-cb-missing"), err)
 }
@@ -1732,7 +1846,7 @@ func Test_block_txns__should_contains_one_coinbase_tx__happy_case(t *testing.T)
 	coinbaseTx := newCoinbaseTx()
 	txns := []*tx.Tx{coinbaseTx}
 
-	err := ltx.CheckBlockTransactions(txns, consensus.MaxBlockSigopsPerMb)
+	err := ltx.CheckBlockTransactions(txns)
 
 	assert.NoError(t, err)
 }
@@ -1741,7 +1855,7 @@ func Test_block_txns__should_only_has_one_coinbase_tx(t *testing.T) {
 	coinbaseTx := newCoinbaseTx()
 	txns := []*tx.Tx{coinbaseTx, coinbaseTx}
 
-	err := ltx.CheckBlockTransactions(txns, consensus.MaxBlockSigopsPerMb)
+	err := ltx.CheckBlockTransactions(txns)
 
 	assert.Equal(t, errcode.NewError(errcode.RejectInvalid, "bad-tx-coinbase"), err)
 }
@@ -1755,9 +1869,13 @@ func Test_block_txns__should_not_contains_too_much_script_ops__in_total(t *testi
 	txn5 := txWithTooManyScriptOps(util.HashOne, 5)
 
 	txns := []*tx.Tx{coinbaseTx, txn1, txn2, txn3, txn4, txn5}
-	err := ltx.CheckBlockTransactions(txns, consensus.MaxBlockSigopsPerMb)
+	err := ltx.CheckBlockSigOps(txns, consensus.MaxBlockSigopsPerMb)
 
 	assert.Equal(t, errcode.NewError(errcode.RejectInvalid, "bad-blk-sigops"), err)
+
+	err = ltx.CheckBlockSigOps(txns[:4], consensus.MaxBlockSigopsPerMb)
+
+	assert.NoError(t, err)
 }
 
 func Test_block_txns__should_not_contains_duplicate_prev_outpoints(t *testing.T) {
@@ -1773,7 +1891,7 @@ func Test_block_txns__should_not_contains_duplicate_prev_outpoints(t *testing.T)
 	txOut := makeOuts()[0]
 	txn2.AddTxOut(txOut)
 	txns := []*tx.Tx{coinbaseTx, txn1, txn2}
-	err := ltx.CheckBlockTransactions(txns, consensus.MaxBlockSigopsPerMb)
+	err := ltx.CheckBlockTransactions(txns)
 
 	assert.Equal(t, errcode.NewError(errcode.RejectInvalid, "bad-txns-inputs-duplicate"), err)
 }
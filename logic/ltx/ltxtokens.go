@@ -0,0 +1,180 @@
+package ltx
+
+import (
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/consensus"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/util"
+)
+
+// CheckTxTokens checks that the tokens of the outputs of transaction are
+// created from the tokens of the coins it spends:
+//   - the fungible tokens of a category are not more than those spent, unless
+//     the category is created by the transaction
+//   - a NFT is created by a minting NFT of its category, or replaces an
+//     immutable NFT with the same commitment or a mutable NFT of its category,
+//     unless the category is created by the transaction
+//
+// A category is created by spending the output of index 0 of the transaction
+// whose hash is the category.
+func CheckTxTokens(transaction *tx.Tx, coinsMap *utxo.CoinsMap) error {
+	genesis := make(map[util.Hash]bool)
+	minting := make(map[util.Hash]bool)
+	mutables := make(map[util.Hash]int)
+	immutables := make(map[string]int)
+	amountsIn := make(map[util.Hash]int64)
+
+	for _, in := range transaction.GetIns() {
+		if in.PreviousOutPoint.Index == 0 {
+			genesis[in.PreviousOutPoint.Hash] = true
+		}
+		coin := coinsMap.GetCoin(in.PreviousOutPoint)
+		if coin == nil {
+			log.Debug("CheckTxTokens can't find coin")
+			panic("CheckTxTokens can't find coin")
+		}
+		tokenData := coin.GetTokenData()
+		if tokenData == nil {
+			continue
+		}
+		if !addTokenAmount(amountsIn, tokenData) {
+			log.Debug("CheckTxTokens input token amount out of range")
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-token-amount-outofrange")
+		}
+		switch {
+		case tokenData.IsMintingNFT():
+			minting[tokenData.Category] = true
+		case tokenData.IsMutableNFT():
+			mutables[tokenData.Category]++
+		case tokenData.IsImmutableNFT():
+			immutables[nftKey(tokenData)]++
+		}
+	}
+
+	amountsOut := make(map[util.Hash]int64)
+	var nftsFromMutable []*txout.TokenData
+	for _, out := range transaction.GetOuts() {
+		if out.HasInvalidTokenPrefix() {
+			log.Debug("CheckTxTokens output has an invalid token prefix")
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-vout-invalid-token-prefix")
+		}
+		tokenData := out.GetTokenData()
+		if tokenData == nil {
+			continue
+		}
+		if !addTokenAmount(amountsOut, tokenData) {
+			log.Debug("CheckTxTokens output token amount out of range")
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-token-amount-outofrange")
+		}
+		if !tokenData.HasNFT || genesis[tokenData.Category] || minting[tokenData.Category] {
+			continue
+		}
+		if tokenData.IsImmutableNFT() && immutables[nftKey(tokenData)] > 0 {
+			immutables[nftKey(tokenData)]--
+			continue
+		}
+		if tokenData.IsMintingNFT() {
+			log.Debug("CheckTxTokens minting NFT created without minting capability")
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-token-nft-ex-nihilo")
+		}
+		nftsFromMutable = append(nftsFromMutable, tokenData)
+	}
+
+	// Each mutable NFT spent may be replaced by one NFT of its category,
+	// once the immutable NFTs merely moved are matched.
+	for _, tokenData := range nftsFromMutable {
+		if mutables[tokenData.Category] == 0 {
+			log.Debug("CheckTxTokens NFT created without minting or mutable capability")
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-token-nft-ex-nihilo")
+		}
+		mutables[tokenData.Category]--
+	}
+
+	for category, amountOut := range amountsOut {
+		if !genesis[category] && amountOut > amountsIn[category] {
+			log.Debug("CheckTxTokens token amount of outputs more than that of inputs")
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-token-in-belowout")
+		}
+	}
+	return nil
+}
+
+// IsTokensEnabled returns whether tokens are enabled for the transactions of
+// the block after tip, those accepted to the mempool.
+func IsTokensEnabled(tip *blockindex.BlockIndex) bool {
+	return model.ActiveNetParams.IsRuleEnabled(consensus.RuleTokens, tip.Height+1, tip.GetMedianTimePast())
+}
+
+// GetTokenActivationHeight returns the height of the first block enabling
+// tokens on the chain of indexPrev, or of its next block. A block enables
+// tokens when the upgrade is active at its parent.
+func GetTokenActivationHeight(indexPrev *blockindex.BlockIndex) int32 {
+	low, high := int32(0), indexPrev.Height+1
+	for low < high {
+		mid := low + (high-low)/2
+		index := indexPrev.GetAncestor(mid)
+		if model.ActiveNetParams.IsRuleEnabled(consensus.RuleTokens, index.Height, index.GetMedianTimePast()) {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	return low + 1
+}
+
+// ReadInputsTokenData reads the token data of the coins spent by transaction
+// once tokens are enabled. A coin created before activationHeight whose
+// scriptPubKey starts with the token prefix is a pre-activation token forgery
+// output (PATFO), which cannot be spent.
+func ReadInputsTokenData(transaction *tx.Tx, coinsMap *utxo.CoinsMap, activationHeight int32) error {
+	for _, in := range transaction.GetIns() {
+		coin := coinsMap.GetCoin(in.PreviousOutPoint)
+		if coin == nil || !coin.HasTokenPrefix() || coin.IsMempoolCoin() {
+			continue
+		}
+		if coin.GetHeight() < activationHeight {
+			log.Debug("ReadInputsTokenData input created with a token prefix before the activation of tokens")
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-vin-token-created-pre-activation")
+		}
+		coin.ReadTokenData()
+	}
+	return nil
+}
+
+// HasTokensOrP2SH32 returns whether transaction spends or creates a token
+// prefix, or creates a P2SH32 output.
+func HasTokensOrP2SH32(transaction *tx.Tx, coinsMap *utxo.CoinsMap) bool {
+	for _, in := range transaction.GetIns() {
+		coin := coinsMap.GetCoin(in.PreviousOutPoint)
+		if coin != nil && coin.HasTokenPrefix() {
+			return true
+		}
+	}
+	for _, out := range transaction.GetOuts() {
+		scriptPubKey := out.GetScriptPubKey()
+		if out.HasTokenPrefix() || scriptPubKey != nil && scriptPubKey.IsPayToScriptHash32() {
+			return true
+		}
+	}
+	return false
+}
+
+// addTokenAmount adds the fungible tokens of tokenData to the amount of its
+// category, returning false on overflow.
+func addTokenAmount(amounts map[util.Hash]int64, tokenData *txout.TokenData) bool {
+	sum := amounts[tokenData.Category] + tokenData.Amount
+	if sum < amounts[tokenData.Category] {
+		return false
+	}
+	amounts[tokenData.Category] = sum
+	return true
+}
+
+func nftKey(tokenData *txout.TokenData) string {
+	return string(tokenData.Category[:]) + string(tokenData.Commitment)
+}
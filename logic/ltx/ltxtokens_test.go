@@ -0,0 +1,140 @@
+package ltx_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/util"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	tokenPrevHash = util.Hash{0x01}
+	tokenCategory = util.Hash{0x02}
+)
+
+func tokenOut(tokenData *txout.TokenData) *txout.TxOut {
+	out := txout.NewTxOut(1000, script.NewScriptRaw([]byte{opcodes.OP_TRUE}))
+	out.SetTokenData(tokenData)
+	return out
+}
+
+// tokenTx returns a transaction spending one coin of the token data of each
+// of ins, the first one at outpoint index prevIndex, and creating outs.
+func tokenTx(prevIndex uint32, ins []*txout.TokenData, outs []*txout.TokenData) (*tx.Tx, *utxo.CoinsMap) {
+	txn := tx.NewTx(0, tx.DefaultVersion)
+	coinsMap := utxo.NewEmptyCoinsMap()
+	for i, tokenData := range ins {
+		prevOut := outpoint.NewOutPoint(tokenPrevHash, prevIndex+uint32(i))
+		txn.AddTxIn(txin.NewTxIn(prevOut, script.NewEmptyScript(), script.SequenceFinal))
+		coinsMap.AddCoin(prevOut, utxo.NewFreshCoin(tokenOut(tokenData), 1, false), false)
+	}
+	for _, tokenData := range outs {
+		txn.AddTxOut(tokenOut(tokenData))
+	}
+	return txn, coinsMap
+}
+
+func TestCheckTxTokens(t *testing.T) {
+	fungible := func(category util.Hash, amount int64) *txout.TokenData {
+		return &txout.TokenData{Category: category, Amount: amount}
+	}
+	nft := func(category util.Hash, capability byte, commitment ...byte) *txout.TokenData {
+		return &txout.TokenData{Category: category, HasNFT: true, Capability: capability, Commitment: commitment}
+	}
+
+	tests := []struct {
+		name      string
+		prevIndex uint32
+		ins       []*txout.TokenData
+		outs      []*txout.TokenData
+		reason    string
+	}{
+		{"no tokens", 1, []*txout.TokenData{nil}, []*txout.TokenData{nil}, ""},
+		{"genesis", 0, []*txout.TokenData{nil},
+			[]*txout.TokenData{fungible(tokenPrevHash, 1000), nft(tokenPrevHash, txout.TokenCapabilityMinting)}, ""},
+		{"not a genesis input", 1, []*txout.TokenData{nil},
+			[]*txout.TokenData{fungible(tokenPrevHash, 1000)}, "bad-txns-token-in-belowout"},
+		{"split fungible tokens", 1, []*txout.TokenData{fungible(tokenCategory, 100)},
+			[]*txout.TokenData{fungible(tokenCategory, 60), fungible(tokenCategory, 40)}, ""},
+		{"inflate fungible tokens", 1, []*txout.TokenData{fungible(tokenCategory, 100)},
+			[]*txout.TokenData{fungible(tokenCategory, 60), fungible(tokenCategory, 41)}, "bad-txns-token-in-belowout"},
+		{"burn fungible tokens", 1, []*txout.TokenData{fungible(tokenCategory, 100)},
+			[]*txout.TokenData{nil}, ""},
+		{"move immutable nft", 1, []*txout.TokenData{nft(tokenCategory, txout.TokenCapabilityNone, 1)},
+			[]*txout.TokenData{nft(tokenCategory, txout.TokenCapabilityNone, 1)}, ""},
+		{"change immutable commitment", 1, []*txout.TokenData{nft(tokenCategory, txout.TokenCapabilityNone, 1)},
+			[]*txout.TokenData{nft(tokenCategory, txout.TokenCapabilityNone, 2)}, "bad-txns-token-nft-ex-nihilo"},
+		{"mutable nft replaced once", 1, []*txout.TokenData{nft(tokenCategory, txout.TokenCapabilityMutable)},
+			[]*txout.TokenData{nft(tokenCategory, txout.TokenCapabilityMutable, 3)}, ""},
+		{"mutable nft replaced twice", 1, []*txout.TokenData{nft(tokenCategory, txout.TokenCapabilityMutable)},
+			[]*txout.TokenData{nft(tokenCategory, txout.TokenCapabilityNone, 3),
+				nft(tokenCategory, txout.TokenCapabilityNone, 4)}, "bad-txns-token-nft-ex-nihilo"},
+		{"mutable nft creates minting", 1, []*txout.TokenData{nft(tokenCategory, txout.TokenCapabilityMutable)},
+			[]*txout.TokenData{nft(tokenCategory, txout.TokenCapabilityMinting)}, "bad-txns-token-nft-ex-nihilo"},
+		{"minting nft", 1, []*txout.TokenData{nft(tokenCategory, txout.TokenCapabilityMinting)},
+			[]*txout.TokenData{nft(tokenCategory, txout.TokenCapabilityMinting),
+				nft(tokenCategory, txout.TokenCapabilityMutable), nft(tokenCategory, txout.TokenCapabilityNone, 5)}, ""},
+		{"minting nft of another category", 1, []*txout.TokenData{nft(tokenPrevHash, txout.TokenCapabilityMinting)},
+			[]*txout.TokenData{nft(tokenCategory, txout.TokenCapabilityNone)}, "bad-txns-token-nft-ex-nihilo"},
+	}
+
+	for _, test := range tests {
+		txn, coinsMap := tokenTx(test.prevIndex, test.ins, test.outs)
+		err := ltx.CheckTxTokens(txn, coinsMap)
+		if test.reason == "" {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.Equal(t, errcode.NewError(errcode.RejectInvalid, test.reason), err, test.name)
+		}
+	}
+}
+
+func TestCheckTxTokens_InvalidTokenPrefix(t *testing.T) {
+	txn, coinsMap := tokenTx(1, []*txout.TokenData{nil}, nil)
+	txn.AddTxOut(txout.NewTxOut(1000, script.NewScriptRaw([]byte{txout.PrefixToken, opcodes.OP_TRUE})))
+
+	err := ltx.CheckTxTokens(txn, coinsMap)
+	assert.Equal(t, errcode.NewError(errcode.RejectInvalid, "bad-txns-vout-invalid-token-prefix"), err)
+	assert.True(t, ltx.HasTokensOrP2SH32(txn, coinsMap))
+}
+
+// rawTokenOut returns an output of the token data as it is decoded before
+// being read, with the token prefix left in its scriptPubKey.
+func rawTokenOut(t *testing.T, tokenData *txout.TokenData) *txout.TxOut {
+	var buf bytes.Buffer
+	assert.NoError(t, tokenOut(tokenData).Encode(&buf))
+	out := txout.NewTxOut(0, nil)
+	assert.NoError(t, out.Decode(&buf))
+	return out
+}
+
+func TestReadInputsTokenData(t *testing.T) {
+	tokenData := &txout.TokenData{Category: tokenCategory, Amount: 10}
+	prevOut := outpoint.NewOutPoint(tokenPrevHash, 0)
+	txn := tx.NewTx(0, tx.DefaultVersion)
+	txn.AddTxIn(txin.NewTxIn(prevOut, script.NewEmptyScript(), script.SequenceFinal))
+
+	coinsMap := utxo.NewEmptyCoinsMap()
+	coinsMap.AddCoin(prevOut, utxo.NewFreshCoin(rawTokenOut(t, tokenData), 100, false), false)
+	assert.True(t, ltx.HasTokensOrP2SH32(txn, coinsMap))
+	assert.Nil(t, coinsMap.GetCoin(prevOut).GetTokenData())
+
+	// A token prefix created before the activation is not token data.
+	err := ltx.ReadInputsTokenData(txn, coinsMap, 101)
+	assert.Equal(t, errcode.NewError(errcode.RejectInvalid, "bad-txns-vin-token-created-pre-activation"), err)
+	assert.Nil(t, coinsMap.GetCoin(prevOut).GetTokenData())
+
+	assert.Nil(t, ltx.ReadInputsTokenData(txn, coinsMap, 100))
+	assert.Equal(t, tokenData, coinsMap.GetCoin(prevOut).GetTokenData())
+	assert.Equal(t, []byte{opcodes.OP_TRUE}, coinsMap.GetCoin(prevOut).GetScriptPubKey().GetData())
+}
@@ -0,0 +1,184 @@
+package ltx
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lscript"
+	"github.com/copernet/copernicus/model/psbt"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/util"
+)
+
+var (
+	ErrPSBTSigHashMismatch      = errors.New("Specified sighash value does not match existing value")
+	ErrPSBTRedeemScriptMismatch = errors.New("Redeem script does not match the scriptPubKey")
+)
+
+// SignPSBTInput adds to the input nIn of p the signatures with hashType the
+// keys of keyStore can produce, then finalizes the input if it holds enough
+// signatures to be spent.  The input can only be signed once its previous
+// output, and its redeem script for a pay-to-script-hash output, are known.
+// It returns whether the input is finalized.
+func SignPSBTInput(p *psbt.PSBT, nIn int, keyStore *crypto.KeyStore, hashType uint32) (bool, error) {
+	in := p.Inputs[nIn]
+	if in.IsFinalized() {
+		return true, nil
+	}
+	if in.SigHashType != 0 && in.SigHashType != hashType {
+		return false, ErrPSBTSigHashMismatch
+	}
+	if in.UTXO == nil {
+		return false, nil
+	}
+	// The token prefix of the previous output is signed as its token data.
+	in.UTXO.ReadTokenData()
+
+	scriptPubKey := in.UTXO.GetScriptPubKey()
+	scriptCode := scriptPubKey
+	if scriptPubKey.IsPayToScriptHash() {
+		if in.RedeemScript == nil {
+			return false, nil
+		}
+		if !bytes.Equal(util.Hash160(in.RedeemScript.GetData()), scriptPubKey.GetData()[2:22]) {
+			return false, ErrPSBTRedeemScriptMismatch
+		}
+		scriptCode = in.RedeemScript
+	}
+
+	pubKeyType, pubKeys, isStandard := scriptCode.IsStandardScriptPubKey()
+	if !isStandard {
+		return false, nil
+	}
+
+	// The public keys which may sign the input.
+	var signers [][]byte
+	switch pubKeyType {
+	case script.ScriptPubkey:
+		signers = pubKeys[:1]
+	case script.ScriptPubkeyHash:
+		if keyPair := keyStore.GetKeyPair(pubKeys[0]); keyPair != nil {
+			signers = append(signers, keyPair.GetPublicKey().ToBytes())
+		}
+	case script.ScriptMultiSig:
+		signers = pubKeys[1 : len(pubKeys)-1]
+	default:
+		return false, nil
+	}
+
+	value := in.UTXO.GetValue()
+	tokenData := in.UTXO.GetTokenData()
+	for _, pubKey := range signers {
+		if _, ok := in.PartialSigs[string(pubKey)]; ok {
+			continue
+		}
+		keyPair := keyStore.GetKeyPairByPubKey(pubKey)
+		if keyPair == nil {
+			continue
+		}
+		// The key pairs of a locked key store only hold the public keys.
+		if keyPair.GetPrivateKey() == nil {
+			return false, crypto.ErrKeyStoreLocked
+		}
+		hash, err := tx.SignatureHashWithTokenData(p.Tx, scriptCode, hashType, nIn, value, tokenData,
+			script.ScriptEnableSigHashForkID)
+		if err != nil {
+			return false, err
+		}
+		signature, err := keyPair.GetPrivateKey().Sign(hash[:])
+		if err != nil {
+			return false, err
+		}
+		in.PartialSigs[string(pubKey)] = append(signature.Serialize(), byte(hashType))
+	}
+
+	sigData := psbtSigData(in, pubKeyType, pubKeys)
+	if sigData == nil {
+		return false, nil
+	}
+	if scriptPubKey.IsPayToScriptHash() {
+		sigData = append(sigData, in.RedeemScript.GetData())
+	}
+	scriptSig := script.NewEmptyScript()
+	if err := scriptSig.PushMultData(sigData); err != nil {
+		return false, err
+	}
+
+	// The previous outputs of the other inputs are only known to the native
+	// introspection opcodes when the PSBT holds them.
+	spentOutputs := make([]*txout.TxOut, len(p.Inputs))
+	for i, input := range p.Inputs {
+		if input.UTXO != nil {
+			input.UTXO.ReadTokenData()
+		}
+		spentOutputs[i] = input.UTXO
+	}
+	err := lscript.VerifyScriptWithContext(p.Tx, scriptSig, scriptPubKey, nIn, value,
+		uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker(), spentOutputs, nil)
+	if err != nil {
+		log.Info("SignPSBTInput: input %d not finalized, VerifyScript error:%s", nIn, err.Error())
+		return false, nil
+	}
+	in.Finalize(scriptSig)
+	return true, nil
+}
+
+// psbtSigData returns the items the scriptSig of the input pushes to spend
+// a script of pubKeyType, nil if the input lacks signatures.
+func psbtSigData(in *psbt.Input, pubKeyType int, pubKeys [][]byte) [][]byte {
+	switch pubKeyType {
+	case script.ScriptPubkey:
+		if sig, ok := in.PartialSigs[string(pubKeys[0])]; ok {
+			// <signature>
+			return [][]byte{sig}
+		}
+
+	case script.ScriptPubkeyHash:
+		for pubKey, sig := range in.PartialSigs {
+			if bytes.Equal(util.Hash160([]byte(pubKey)), pubKeys[0]) {
+				// <signature> <pubkey>
+				return [][]byte{sig, []byte(pubKey)}
+			}
+		}
+
+	case script.ScriptMultiSig:
+		required := int(pubKeys[0][0])
+		// <OP_0> <signature0> ... <signatureM>, in the order of the keys.
+		sigData := [][]byte{{}}
+		for _, pubKey := range pubKeys[1 : len(pubKeys)-1] {
+			if len(sigData) > required {
+				break
+			}
+			if sig, ok := in.PartialSigs[string(pubKey)]; ok {
+				sigData = append(sigData, sig)
+			}
+		}
+		if len(sigData) > required {
+			return sigData
+		}
+	}
+	return nil
+}
+
+// FinalizePSBT finalizes the inputs of p which hold enough signatures, and
+// returns whether all of them are.
+func FinalizePSBT(p *psbt.PSBT) (bool, error) {
+	keyStore := crypto.NewKeyStore()
+	complete := true
+	for i, in := range p.Inputs {
+		hashType := in.SigHashType
+		if hashType == 0 {
+			hashType = crypto.SigHashAll | crypto.SigHashForkID
+		}
+		finalized, err := SignPSBTInput(p, i, keyStore, hashType)
+		if err != nil {
+			return false, err
+		}
+		complete = complete && finalized
+	}
+	return complete, nil
+}
@@ -0,0 +1,143 @@
+package ltx_test
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/logic/lscript"
+	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/psbt"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// newMultiSigPSBT returns a partially signed transaction spending a 2-of-3
+// multisig pay-to-script-hash output of the keys of v.
+func newMultiSigPSBT(v *Var, t *testing.T) *psbt.PSBT {
+	redeemScript := script.NewEmptyScript()
+	redeemScript.PushOpCode(opcodes.OP_2)
+	for _, pubKey := range v.pubKeys {
+		redeemScript.PushSingleData(pubKey.ToBytes())
+	}
+	redeemScript.PushOpCode(opcodes.OP_3)
+	redeemScript.PushOpCode(opcodes.OP_CHECKMULTISIG)
+
+	p2shScript := script.NewEmptyScript()
+	p2shScript.PushOpCode(opcodes.OP_HASH160)
+	p2shScript.PushSingleData(util.Hash160(redeemScript.GetData()))
+	p2shScript.PushOpCode(opcodes.OP_EQUAL)
+
+	spender := tx.NewTx(0, tx.DefaultVersion)
+	spender.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.HashOne, 0), script.NewEmptyScript(),
+		script.SequenceFinal))
+	spender.AddTxOut(txout.NewTxOut(90000, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+
+	p, err := psbt.New(spender)
+	assert.NoError(t, err)
+	p.Inputs[0].UTXO = txout.NewTxOut(100000, p2shScript)
+	p.Inputs[0].RedeemScript = redeemScript
+	return p
+}
+
+func clonePSBT(p *psbt.PSBT, t *testing.T) *psbt.PSBT {
+	encoded, err := p.EncodeBase64()
+	assert.NoError(t, err)
+	clone, err := psbt.DecodeBase64(encoded)
+	assert.NoError(t, err)
+	return clone
+}
+
+func TestSignPSBTMultiSig(t *testing.T) {
+	v := initVar()
+	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
+	p := newMultiSigPSBT(v, t)
+
+	// Each signer only holds one of the keys.
+	var signed []*psbt.PSBT
+	for i := 0; i < 2; i++ {
+		keyStore := crypto.NewKeyStore()
+		keyStore.AddKey(&v.priKeys[i])
+		partial := clonePSBT(p, t)
+		finalized, err := ltx.SignPSBTInput(partial, 0, keyStore, hashType)
+		assert.NoError(t, err)
+		assert.False(t, finalized)
+		assert.Equal(t, 1, len(partial.Inputs[0].PartialSigs))
+		signed = append(signed, partial)
+	}
+
+	complete, err := ltx.FinalizePSBT(signed[0])
+	assert.NoError(t, err)
+	assert.False(t, complete)
+
+	combined, err := psbt.Combine(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(combined.Inputs[0].PartialSigs))
+	complete, err = ltx.FinalizePSBT(combined)
+	assert.NoError(t, err)
+	assert.True(t, complete)
+	assert.Equal(t, 0, len(combined.Inputs[0].PartialSigs))
+
+	final, err := combined.Extract()
+	assert.NoError(t, err)
+	err = lscript.VerifyScript(final, final.GetIns()[0].GetScriptSig(), p.Inputs[0].UTXO.GetScriptPubKey(), 0,
+		p.Inputs[0].UTXO.GetValue(), uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker())
+	assert.NoError(t, err)
+}
+
+func TestSignPSBTErrors(t *testing.T) {
+	v := initVar()
+	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
+
+	p := newMultiSigPSBT(v, t)
+	p.Inputs[0].SigHashType = uint32(crypto.SigHashNone | crypto.SigHashForkID)
+	_, err := ltx.SignPSBTInput(p, 0, v.keyStore, hashType)
+	assert.Equal(t, ltx.ErrPSBTSigHashMismatch, err)
+
+	p = newMultiSigPSBT(v, t)
+	p.Inputs[0].RedeemScript = script.NewScriptRaw([]byte{opcodes.OP_TRUE})
+	_, err = ltx.SignPSBTInput(p, 0, v.keyStore, hashType)
+	assert.Equal(t, ltx.ErrPSBTRedeemScriptMismatch, err)
+
+	// An input whose previous output is unknown cannot be signed yet.
+	p = newMultiSigPSBT(v, t)
+	p.Inputs[0].UTXO = nil
+	finalized, err := ltx.SignPSBTInput(p, 0, v.keyStore, hashType)
+	assert.NoError(t, err)
+	assert.False(t, finalized)
+	assert.Equal(t, 0, len(p.Inputs[0].PartialSigs))
+}
+
+func TestSignPSBTPubKeyHash(t *testing.T) {
+	v := initVar()
+	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
+
+	p2pkhScript := script.NewEmptyScript()
+	p2pkhScript.PushOpCode(opcodes.OP_DUP)
+	p2pkhScript.PushOpCode(opcodes.OP_HASH160)
+	p2pkhScript.PushSingleData(util.Hash160(v.pubKeys[0].ToBytes()))
+	p2pkhScript.PushOpCode(opcodes.OP_EQUALVERIFY)
+	p2pkhScript.PushOpCode(opcodes.OP_CHECKSIG)
+
+	spender := tx.NewTx(0, tx.DefaultVersion)
+	spender.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.HashOne, 0), script.NewEmptyScript(),
+		script.SequenceFinal))
+	spender.AddTxOut(txout.NewTxOut(90000, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+	p, err := psbt.New(spender)
+	assert.NoError(t, err)
+	p.Inputs[0].UTXO = txout.NewTxOut(100000, p2pkhScript)
+
+	finalized, err := ltx.SignPSBTInput(p, 0, v.keyStore, hashType)
+	assert.NoError(t, err)
+	assert.True(t, finalized)
+	final, err := p.Extract()
+	assert.NoError(t, err)
+	err = lscript.VerifyScript(final, final.GetIns()[0].GetScriptSig(), p2pkhScript, 0,
+		p.Inputs[0].UTXO.GetValue(), uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker())
+	assert.NoError(t, err)
+}
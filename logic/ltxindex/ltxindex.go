@@ -0,0 +1,215 @@
+package ltxindex
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/util"
+)
+
+var (
+	// synced is set once the background sync has caught up with the active
+	// chain, from then on the index is maintained by BlockConnected and
+	// BlockDisconnected.
+	synced int32
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+)
+
+var errReadBlock = errors.New("txindex: failed to read block from disk")
+
+// Enabled reports whether the transaction index is turned on by -txindex.
+func Enabled() bool {
+	return conf.Cfg != nil && conf.Cfg.Chain.TxIndex
+}
+
+// IsSynced reports whether the index covers the whole active chain.
+func IsSynced() bool {
+	return atomic.LoadInt32(&synced) == 1
+}
+
+// Init starts building the transaction index in the background, from the
+// block it was last synced to up to the tip of the active chain. It does
+// nothing when the index is disabled.
+func Init() {
+	if !Enabled() {
+		return
+	}
+	atomic.StoreInt32(&synced, 0)
+	quit = make(chan struct{})
+	startSync()
+}
+
+// Stop interrupts the background sync, if any, and waits for it to exit.
+func Stop() {
+	if quit == nil {
+		return
+	}
+	close(quit)
+	wg.Wait()
+	quit = nil
+}
+
+func startSync() {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		syncIndex(quit)
+	}()
+}
+
+func syncIndex(quit <-chan struct{}) {
+	log.Info("txindex: syncing transaction index with the active chain")
+	for {
+		select {
+		case <-quit:
+			log.Info("txindex: sync interrupted")
+			return
+		default:
+		}
+
+		done, err := syncNextBlock()
+		if err != nil {
+			log.Error("txindex: sync failed: %v", err)
+			return
+		}
+		if done {
+			log.Info("txindex: transaction index is synced")
+			return
+		}
+	}
+}
+
+// syncNextBlock moves the index one block closer to the tip of the active
+// chain. A best block left on a stale branch is unwound first. It returns
+// true once the index has caught up, at which point BlockConnected and
+// BlockDisconnected take over.
+func syncNextBlock() (bool, error) {
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	gChain := chain.GetInstance()
+	bestHash, err := blkdb.GetInstance().ReadTxIndexBestBlock()
+	if err != nil {
+		return false, err
+	}
+
+	var best *blockindex.BlockIndex
+	if bestHash != nil {
+		best = gChain.FindBlockIndex(*bestHash)
+	}
+	if best != nil && !gChain.Contains(best) {
+		blk, ok := disk.ReadBlockFromDisk(best, gChain.GetParams())
+		if !ok {
+			return false, errReadBlock
+		}
+		return false, eraseBlock(blk, best)
+	}
+
+	var next *blockindex.BlockIndex
+	if best == nil {
+		next = gChain.Genesis()
+	} else {
+		next = gChain.Next(best)
+	}
+	if next == nil {
+		atomic.StoreInt32(&synced, 1)
+		return true, nil
+	}
+
+	blk, ok := disk.ReadBlockFromDisk(next, gChain.GetParams())
+	if !ok {
+		return false, errReadBlock
+	}
+	return false, writeBlock(blk, next)
+}
+
+// writeBlock adds the transactions of blk to the index and marks blk as the
+// index best block. As in bitcoin core the genesis transaction is not indexed
+// since its output can never be spent.
+func writeBlock(blk *block.Block, pindex *blockindex.BlockIndex) error {
+	txIndexes := make(map[util.Hash]block.DiskTxPos, len(blk.Txs))
+	if pindex.Height > 0 {
+		blockPos := pindex.GetBlockPos()
+		offset := util.VarIntSerializeSize(uint64(len(blk.Txs)))
+		for _, txn := range blk.Txs {
+			txIndexes[txn.GetHash()] = *block.NewDiskTxPos(&blockPos, offset)
+			offset += txn.SerializeSize()
+		}
+	}
+	return blkdb.GetInstance().UpdateTxIndex(txIndexes, nil, pindex.GetBlockHash())
+}
+
+// eraseBlock removes the transactions of blk from the index and rewinds the
+// index best block to its parent.
+func eraseBlock(blk *block.Block, pindex *blockindex.BlockIndex) error {
+	txids := make([]util.Hash, 0, len(blk.Txs))
+	for _, txn := range blk.Txs {
+		txids = append(txids, txn.GetHash())
+	}
+	return blkdb.GetInstance().UpdateTxIndex(nil, txids, &blk.Header.HashPrevBlock)
+}
+
+// BlockConnected indexes the transactions of a block just connected to the
+// active chain. It must be called with persist.CsMain held.
+func BlockConnected(blk *block.Block, pindex *blockindex.BlockIndex) {
+	if !Enabled() || !IsSynced() {
+		return
+	}
+	if err := writeBlock(blk, pindex); err != nil {
+		log.Error("txindex: index block %s failed: %v", pindex.GetBlockHash(), err)
+		resync()
+	}
+}
+
+// BlockDisconnected removes the transactions of a block just disconnected
+// from the active chain. It must be called with persist.CsMain held.
+func BlockDisconnected(blk *block.Block, pindex *blockindex.BlockIndex) {
+	if !Enabled() || !IsSynced() {
+		return
+	}
+	if err := eraseBlock(blk, pindex); err != nil {
+		log.Error("txindex: unindex block %s failed: %v", pindex.GetBlockHash(), err)
+		resync()
+	}
+}
+
+// resync falls back to the background sync after the index failed to follow
+// the active chain, so that it catches up again from its recorded best block.
+func resync() {
+	atomic.StoreInt32(&synced, 0)
+	if quit != nil {
+		startSync()
+	}
+}
+
+// GetTransaction looks up a confirmed transaction in the index, and returns
+// it along with the hash of the block containing it.
+func GetTransaction(hash *util.Hash) (*tx.Tx, *util.Hash, bool) {
+	pos, err := blkdb.GetInstance().ReadTxIndex(hash)
+	if err != nil || pos == nil {
+		return nil, nil, false
+	}
+	txn, header, err := disk.ReadTxFromDisk(pos)
+	if err != nil {
+		log.Error("txindex: read tx %s from disk failed: %v", hash, err)
+		return nil, nil, false
+	}
+	if txn.GetHash() != *hash {
+		log.Error("txindex: tx %s does not match the index entry", hash)
+		return nil, nil, false
+	}
+	blockHash := header.GetHash()
+	return txn, &blockHash, true
+}
@@ -0,0 +1,222 @@
+package ltxindex
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/pow"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+	"github.com/stretchr/testify/assert"
+)
+
+func initTestEnv(t *testing.T) (dirpath string) {
+	conf.Cfg = conf.InitConfig([]string{"--regtest"})
+	conf.Cfg.Chain.TxIndex = true
+
+	unitTestDataDirPath, err := conf.SetUnitTestDataDir(conf.Cfg)
+	if err != nil {
+		t.Fatalf("init test environment failed: %s", err)
+	}
+	model.SetRegTestParams()
+
+	utxoDbCfg := &db.DBOption{
+		FilePath:  conf.DataDir + "/chainstate",
+		CacheSize: (1 << 20) * 8,
+	}
+	utxo.InitUtxoLruTip(&utxo.UtxoConfig{Do: utxoDbCfg})
+
+	blkDbCfg := &db.DBOption{
+		FilePath:  conf.DataDir + "/blocks/index",
+		CacheSize: (1 << 20) * 8,
+	}
+	blkdb.InitBlockTreeDB(&blkdb.BlockTreeDBConfig{Do: blkDbCfg})
+	persist.InitPersistGlobal(blkdb.GetInstance())
+
+	chain.InitGlobalChain(blkdb.GetInstance())
+	gChain := chain.GetInstance()
+	gChain.SetTip(nil)
+	gChain.InitLoad(make(map[util.Hash]*blockindex.BlockIndex), make([]*blockindex.BlockIndex, 0))
+
+	return unitTestDataDirPath
+}
+
+func newTestTx(prev util.Hash, value int64) *tx.Tx {
+	txn := tx.NewTx(0, tx.DefaultVersion)
+	txn.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(prev, 0), script.NewScriptRaw([]byte{0x51}), 0xffffffff))
+	txn.AddTxOut(txout.NewTxOut(amount.Amount(value), script.NewScriptRaw([]byte{0x51})))
+	return txn
+}
+
+// storeBlock writes blk to the block files and adds it to the block index,
+// grinding its nonce first so that it passes the regtest proof of work check.
+func storeBlock(t *testing.T, blk *block.Block) *blockindex.BlockIndex {
+	params := chain.GetInstance().GetParams()
+	objPow := pow.Pow{}
+	for {
+		hash := blk.Header.GetHash()
+		if objPow.CheckProofOfWork(&hash, blk.Header.Bits, params) {
+			break
+		}
+		blk.Header.Nonce++
+		blk.Header.Hash = util.Hash{}
+	}
+
+	pos := block.NewDiskBlockPos(0, 0)
+	if !disk.FindBlockPos(pos, uint32(blk.SerializeSize()+4), 0, uint64(blk.Header.Time), false) {
+		t.Fatal("find block pos failed")
+	}
+	if !disk.WriteBlockToDisk(blk, pos) {
+		t.Fatal("write block to disk failed")
+	}
+
+	bIndex := blockindex.NewBlockIndex(&blk.Header)
+	bIndex.File = pos.File
+	bIndex.DataPos = pos.Pos
+	bIndex.AddStatus(blockindex.BlockHaveData)
+	assert.Nil(t, chain.GetInstance().AddToIndexMap(bIndex))
+	return bIndex
+}
+
+func newTestBlock(prev *blockindex.BlockIndex, txs ...*tx.Tx) *block.Block {
+	blk := block.NewBlock()
+	blk.Header.Version = 1
+	blk.Header.HashPrevBlock = *prev.GetBlockHash()
+	blk.Header.Time = prev.Header.Time + 1
+	blk.Header.Bits = prev.Header.Bits
+	blk.Txs = txs
+	return blk
+}
+
+func syncAll(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		done, err := syncNextBlock()
+		assert.Nil(t, err)
+		if done {
+			return
+		}
+	}
+	t.Fatal("txindex sync does not terminate")
+}
+
+func assertIndexed(t *testing.T, txn *tx.Tx, blockHash *util.Hash) {
+	hash := txn.GetHash()
+	got, gotBlockHash, ok := GetTransaction(&hash)
+	if assert.True(t, ok, "tx %s should be indexed", hash) {
+		assert.Equal(t, hash, got.GetHash())
+		assert.Equal(t, *blockHash, *gotBlockHash)
+	}
+}
+
+func assertNotIndexed(t *testing.T, txn *tx.Tx) {
+	hash := txn.GetHash()
+	_, _, ok := GetTransaction(&hash)
+	assert.False(t, ok, "tx %s should not be indexed", hash)
+}
+
+func TestTxIndexSyncAndFollowChain(t *testing.T) {
+	defer os.RemoveAll(initTestEnv(t))
+	gChain := chain.GetInstance()
+
+	genesisBlk := gChain.GetParams().GenesisBlock
+	genesis := storeBlock(t, genesisBlk)
+	gChain.SetTip(genesis)
+
+	tx1 := newTestTx(*util.HashFromString("01"), 1)
+	tx2 := newTestTx(*util.HashFromString("02"), 2)
+	tx3 := newTestTx(*util.HashFromString("03"), 3)
+	blk1 := newTestBlock(genesis, tx1, tx2, tx3)
+	index1 := storeBlock(t, blk1)
+	gChain.SetTip(index1)
+
+	assert.False(t, IsSynced())
+	syncAll(t)
+	assert.True(t, IsSynced())
+
+	best, err := blkdb.GetInstance().ReadTxIndexBestBlock()
+	assert.Nil(t, err)
+	assert.Equal(t, index1.GetBlockHash(), best)
+	assertNotIndexed(t, genesisBlk.Txs[0])
+	assertIndexed(t, tx1, index1.GetBlockHash())
+	assertIndexed(t, tx2, index1.GetBlockHash())
+	assertIndexed(t, tx3, index1.GetBlockHash())
+
+	// once synced, connected and disconnected blocks are indexed directly
+	tx4 := newTestTx(*util.HashFromString("04"), 4)
+	blk2 := newTestBlock(index1, tx4)
+	index2 := storeBlock(t, blk2)
+	gChain.SetTip(index2)
+	BlockConnected(blk2, index2)
+	assertIndexed(t, tx4, index2.GetBlockHash())
+
+	gChain.SetTip(index1)
+	BlockDisconnected(blk2, index2)
+	assertNotIndexed(t, tx4)
+	assertIndexed(t, tx1, index1.GetBlockHash())
+	best, _ = blkdb.GetInstance().ReadTxIndexBestBlock()
+	assert.Equal(t, index1.GetBlockHash(), best)
+
+	atomic.StoreInt32(&synced, 0)
+}
+
+func TestTxIndexUnwindStaleBranch(t *testing.T) {
+	defer os.RemoveAll(initTestEnv(t))
+	gChain := chain.GetInstance()
+
+	genesis := storeBlock(t, gChain.GetParams().GenesisBlock)
+	txA := newTestTx(*util.HashFromString("0a"), 1)
+	indexA := storeBlock(t, newTestBlock(genesis, txA))
+	gChain.SetTip(indexA)
+	syncAll(t)
+	assertIndexed(t, txA, indexA.GetBlockHash())
+	atomic.StoreInt32(&synced, 0)
+
+	// while the node was stopped the chain switched to a competing branch
+	txB := newTestTx(*util.HashFromString("0b"), 2)
+	blkB := newTestBlock(genesis, txB)
+	blkB.Header.Time++
+	indexB := storeBlock(t, blkB)
+	gChain.SetTip(indexB)
+
+	syncAll(t)
+	assertNotIndexed(t, txA)
+	assertIndexed(t, txB, indexB.GetBlockHash())
+	best, _ := blkdb.GetInstance().ReadTxIndexBestBlock()
+	assert.Equal(t, indexB.GetBlockHash(), best)
+
+	atomic.StoreInt32(&synced, 0)
+}
+
+func TestTxIndexDisabled(t *testing.T) {
+	defer os.RemoveAll(initTestEnv(t))
+	conf.Cfg.Chain.TxIndex = false
+
+	assert.False(t, Enabled())
+	Init()
+	Stop()
+	assert.False(t, IsSynced())
+
+	gChain := chain.GetInstance()
+	genesis := storeBlock(t, gChain.GetParams().GenesisBlock)
+	txn := newTestTx(*util.HashFromString("0c"), 1)
+	blk := newTestBlock(genesis, txn)
+	index := storeBlock(t, blk)
+	BlockConnected(blk, index)
+	assertNotIndexed(t, txn)
+}
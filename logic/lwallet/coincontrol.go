@@ -0,0 +1,56 @@
+package lwallet
+
+import (
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+)
+
+// CoinControl holds the choices of the caller about how a transaction is
+// funded: the inputs it must spend, where the change goes and how confirmed
+// the coins spent must be.
+type CoinControl struct {
+	// ChangeScript is the script of the change output, a new key from the
+	// keypool if nil.
+	ChangeScript *script.Script
+
+	// AllowOtherInputs lets the coin selection add inputs to the selected
+	// ones when they do not cover the amount to send.
+	AllowOtherInputs bool
+
+	// MinDepth is the number of confirmations the coins picked by the coin
+	// selection must have at least.  It does not apply to selected inputs.
+	MinDepth int32
+
+	selected map[outpoint.OutPoint]struct{}
+}
+
+func NewCoinControl() *CoinControl {
+	return &CoinControl{
+		selected: make(map[outpoint.OutPoint]struct{}),
+	}
+}
+
+func (cc *CoinControl) HasSelected() bool {
+	return len(cc.selected) > 0
+}
+
+func (cc *CoinControl) IsSelected(outPoint *outpoint.OutPoint) bool {
+	_, ok := cc.selected[*outPoint]
+	return ok
+}
+
+func (cc *CoinControl) Select(outPoint *outpoint.OutPoint) {
+	cc.selected[*outPoint] = struct{}{}
+}
+
+func (cc *CoinControl) UnSelect(outPoint *outpoint.OutPoint) {
+	delete(cc.selected, *outPoint)
+}
+
+func (cc *CoinControl) ListSelected() []*outpoint.OutPoint {
+	outPoints := make([]*outpoint.OutPoint, 0, len(cc.selected))
+	for outPoint := range cc.selected {
+		outPoints = append(outPoints, outpoint.NewOutPoint(outPoint.Hash, outPoint.Index))
+	}
+	return outPoints
+}
@@ -0,0 +1,323 @@
+package lwallet
+
+import (
+	"sort"
+
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+)
+
+// minChange is the smallest change the knapsack solver tries to leave.
+const minChange = amount.Amount(amount.CENT)
+
+// bnbTotalTries bounds the number of steps of the branch and bound search.
+const bnbTotalTries = 100000
+
+// knapsackIterations is the number of random subsets tried by the knapsack
+// solver for each target.
+const knapsackIterations = 1000
+
+// The sizes of a pay-to-pubkey-hash output and of an input spending it, for
+// the change output the wallet creates by default.
+const (
+	p2pkhOutputSize = 8 + 1 + 25
+	p2pkhSpendSize  = 32 + 4 + 1 + 1 + 72 + 1 + 33 + 4
+)
+
+// CoinEligibilityFilter sets the confirmations a coin needs to be spent,
+// depending on whether the wallet sent the transaction which created it.
+type CoinEligibilityFilter struct {
+	ConfMine   int32
+	ConfTheirs int32
+}
+
+func (filter *CoinEligibilityFilter) isEligible(coin *TxnCoin) bool {
+	if coin.FromMe {
+		return coin.Depth >= filter.ConfMine
+	}
+	return coin.Depth >= filter.ConfTheirs
+}
+
+// CoinSelectionParams are the sizes and the fee rate the branch and bound
+// search needs to tell what spending a coin costs.
+type CoinSelectionParams struct {
+	UseBnB           bool
+	EffectiveFee     *util.FeeRate
+	ChangeOutputSize int
+	ChangeSpendSize  int
+	TxNoInputsSize   int
+}
+
+// inputCoin is a coin considered by the coin selection, along with its
+// value net of the fee paid to spend it.
+type inputCoin struct {
+	txnCoin        *TxnCoin
+	value          amount.Amount
+	effectiveValue amount.Amount
+}
+
+// SelectCoinsMinConf selects among the coins eligible under filter a set
+// covering targetValue.  It tries a branch and bound search for a set which
+// needs no change first, if params allow it, and falls back to the knapsack
+// solver.  The returned bool tells whether the set came from the branch and
+// bound search, in which case the excess goes to the fee.
+func SelectCoinsMinConf(targetValue amount.Amount, filter *CoinEligibilityFilter, coins []*TxnCoin,
+	params *CoinSelectionParams) ([]*TxnCoin, amount.Amount, bool) {
+
+	if params.UseBnB {
+		costOfChange := amount.Amount(params.EffectiveFee.GetFee(params.ChangeOutputSize) +
+			params.EffectiveFee.GetFee(params.ChangeSpendSize))
+		notInputFees := amount.Amount(params.EffectiveFee.GetFee(params.TxNoInputsSize))
+
+		pool := make([]*inputCoin, 0, len(coins))
+		for _, txnCoin := range coins {
+			if !filter.isEligible(txnCoin) {
+				continue
+			}
+			spendSize := estimateSpendSize(txnCoin.Coin.GetScriptPubKey())
+			if spendSize < 0 {
+				continue
+			}
+			value := txnCoin.Coin.GetAmount()
+			effectiveValue := value - amount.Amount(params.EffectiveFee.GetFee(spendSize))
+			// Coins which cost more to spend than they are worth are
+			// never selected.
+			if effectiveValue > 0 {
+				pool = append(pool, &inputCoin{txnCoin, value, effectiveValue})
+			}
+		}
+		selected, valueRet := selectCoinsBnB(pool, targetValue+notInputFees, costOfChange)
+		return toTxnCoins(selected), valueRet, true
+	}
+
+	pool := make([]*inputCoin, 0, len(coins))
+	for _, txnCoin := range coins {
+		if !filter.isEligible(txnCoin) {
+			continue
+		}
+		value := txnCoin.Coin.GetAmount()
+		pool = append(pool, &inputCoin{txnCoin, value, value})
+	}
+	selected, valueRet := knapsackSolver(pool, targetValue)
+	return toTxnCoins(selected), valueRet, false
+}
+
+// selectCoinsBnB searches depth first for the set of coins whose effective
+// value is the closest to target without exceeding it by more than
+// costOfChange, i.e. a set for which creating a change output is not worth
+// it.  The search includes the coins in descending order of effective value
+// and backtracks as soon as a branch cannot lead to a better set.
+func selectCoinsBnB(pool []*inputCoin, target amount.Amount, costOfChange amount.Amount) (
+	[]*inputCoin, amount.Amount) {
+
+	availableValue := amount.Amount(0)
+	for _, coin := range pool {
+		availableValue += coin.effectiveValue
+	}
+	if availableValue < target {
+		return nil, 0
+	}
+
+	sort.SliceStable(pool, func(i, j int) bool {
+		return pool[i].effectiveValue > pool[j].effectiveValue
+	})
+
+	// selection[i] tells whether pool[i] is included in the current branch,
+	// which goes down to depth len(selection).
+	selection := make([]bool, 0, len(pool))
+	var bestSelection []bool
+	selectedValue := amount.Amount(0)
+	bestWaste := amount.Amount(util.MaxMoney)
+
+	for try := 0; try < bnbTotalTries; try++ {
+		backtrack := false
+		if selectedValue+availableValue < target || selectedValue > target+costOfChange {
+			// The branch cannot reach the target or already overshoots it.
+			backtrack = true
+		} else if selectedValue >= target {
+			// The excess is a waste, it goes to the fee.
+			if waste := selectedValue - target; waste <= bestWaste {
+				bestSelection = append(bestSelection[:0], selection...)
+				bestWaste = waste
+			}
+			backtrack = true
+		}
+
+		if backtrack {
+			// Walk back to the last included coin and try omitting it.
+			for len(selection) > 0 && !selection[len(selection)-1] {
+				selection = selection[:len(selection)-1]
+				availableValue += pool[len(selection)].effectiveValue
+			}
+			if len(selection) == 0 {
+				// Every branch was explored.
+				break
+			}
+			selection[len(selection)-1] = false
+			selectedValue -= pool[len(selection)-1].effectiveValue
+			continue
+		}
+
+		coin := pool[len(selection)]
+		availableValue -= coin.effectiveValue
+		// Omitting a coin then including one of the same value leads to a
+		// branch which was already explored.
+		if len(selection) > 0 && !selection[len(selection)-1] &&
+			coin.effectiveValue == pool[len(selection)-1].effectiveValue {
+			selection = append(selection, false)
+		} else {
+			selection = append(selection, true)
+			selectedValue += coin.effectiveValue
+		}
+	}
+
+	if bestSelection == nil {
+		return nil, 0
+	}
+	selected := make([]*inputCoin, 0)
+	valueRet := amount.Amount(0)
+	for i, included := range bestSelection {
+		if included {
+			selected = append(selected, pool[i])
+			valueRet += pool[i].value
+		}
+	}
+	return selected, valueRet
+}
+
+// knapsackSolver selects a set of coins covering target, ideally exactly or
+// else leaving at least minChange, with a stochastic approximation of the
+// subset sum problem.  The randomness serves no security purpose, it only
+// prevents degenerate behaviours.
+func knapsackSolver(pool []*inputCoin, target amount.Amount) ([]*inputCoin, amount.Amount) {
+	var lowestLarger *inputCoin
+	applicable := make([]*inputCoin, 0, len(pool))
+	totalLower := amount.Amount(0)
+
+	for i := len(pool) - 1; i > 0; i-- {
+		j := util.GetRandInt(i + 1)
+		pool[i], pool[j] = pool[j], pool[i]
+	}
+
+	for _, coin := range pool {
+		if coin.value == target {
+			return []*inputCoin{coin}, coin.value
+		} else if coin.value < target+minChange {
+			applicable = append(applicable, coin)
+			totalLower += coin.value
+		} else if lowestLarger == nil || coin.value < lowestLarger.value {
+			lowestLarger = coin
+		}
+	}
+
+	if totalLower == target {
+		return applicable, totalLower
+	}
+	if totalLower < target {
+		if lowestLarger == nil {
+			return nil, 0
+		}
+		return []*inputCoin{lowestLarger}, lowestLarger.value
+	}
+
+	sort.SliceStable(applicable, func(i, j int) bool {
+		return applicable[i].value > applicable[j].value
+	})
+	best, bestValue := approximateBestSubset(applicable, totalLower, target, knapsackIterations)
+	if bestValue != target && totalLower >= target+minChange {
+		best, bestValue = approximateBestSubset(applicable, totalLower, target+minChange, knapsackIterations)
+	}
+
+	// The next larger coin is a better choice if it is closer to the target
+	// or if no set leaving enough change was found.
+	if lowestLarger != nil &&
+		((bestValue != target && bestValue < target+minChange) || lowestLarger.value <= bestValue) {
+		return []*inputCoin{lowestLarger}, lowestLarger.value
+	}
+
+	selected := make([]*inputCoin, 0)
+	for i, included := range best {
+		if included {
+			selected = append(selected, applicable[i])
+		}
+	}
+	return selected, bestValue
+}
+
+// approximateBestSubset returns, among random subsets of coins, the one of
+// lowest value at least target.  The coins must be in descending order of
+// value and sum up to totalLower.
+func approximateBestSubset(coins []*inputCoin, totalLower amount.Amount, target amount.Amount,
+	iterations int) ([]bool, amount.Amount) {
+
+	best := make([]bool, len(coins))
+	for i := range best {
+		best[i] = true
+	}
+	bestValue := totalLower
+
+	included := make([]bool, len(coins))
+	for rep := 0; rep < iterations && bestValue != target; rep++ {
+		for i := range included {
+			included[i] = false
+		}
+		total := amount.Amount(0)
+		reachedTarget := false
+		for pass := 0; pass < 2 && !reachedTarget; pass++ {
+			for i, coin := range coins {
+				// The first pass picks coins at random, the second one
+				// completes the subset with the coins left.
+				pick := !included[i]
+				if pass == 0 {
+					pick = util.GetRandInt(2) == 1
+				}
+				if !pick {
+					continue
+				}
+				total += coin.value
+				included[i] = true
+				if total >= target {
+					reachedTarget = true
+					if total < bestValue {
+						bestValue = total
+						copy(best, included)
+					}
+					total -= coin.value
+					included[i] = false
+				}
+			}
+		}
+	}
+	return best, bestValue
+}
+
+// estimateSpendSize returns the size of an input spending a coin locked by
+// scriptPubKey, with a signature of the maximum size, or -1 if the size
+// cannot be told in advance.
+func estimateSpendSize(scriptPubKey *script.Script) int {
+	// outpoint, script length and sequence
+	const inputOverhead = 32 + 4 + 1 + 4
+	// push of a DER encoded signature followed by the hash type
+	const maxSigPush = 1 + 72
+
+	pubKeyType, _, _ := scriptPubKey.IsStandardScriptPubKey()
+	switch pubKeyType {
+	case script.ScriptPubkey:
+		return inputOverhead + maxSigPush
+	case script.ScriptPubkeyHash:
+		return inputOverhead + maxSigPush + 1 + 33
+	}
+	return -1
+}
+
+func toTxnCoins(coins []*inputCoin) []*TxnCoin {
+	if coins == nil {
+		return nil
+	}
+	txnCoins := make([]*TxnCoin, 0, len(coins))
+	for _, coin := range coins {
+		txnCoins = append(txnCoins, coin.txnCoin)
+	}
+	return txnCoins
+}
@@ -0,0 +1,138 @@
+package lwallet
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+	"github.com/stretchr/testify/assert"
+)
+
+var p2pkhScript = script.NewScriptRaw(append(append([]byte{opcodes.OP_DUP, opcodes.OP_HASH160, 20},
+	make([]byte, 20)...), opcodes.OP_EQUALVERIFY, opcodes.OP_CHECKSIG))
+
+func newTestCoins(depth int32, fromMe bool, values ...amount.Amount) []*TxnCoin {
+	coins := make([]*TxnCoin, 0, len(values))
+	for i, value := range values {
+		coins = append(coins, &TxnCoin{
+			OutPoint: outpoint.NewOutPoint(*util.GetRandHash(), uint32(i)),
+			Coin:     utxo.NewFreshCoin(txout.NewTxOut(value, p2pkhScript), 1, false),
+			IsSafe:   true,
+			Depth:    depth,
+			FromMe:   fromMe,
+		})
+	}
+	return coins
+}
+
+func sumCoins(coins []*TxnCoin) amount.Amount {
+	total := amount.Amount(0)
+	for _, coin := range coins {
+		total += coin.Coin.GetAmount()
+	}
+	return total
+}
+
+func TestSelectCoinsBnB(t *testing.T) {
+	newPool := func(values ...amount.Amount) []*inputCoin {
+		pool := make([]*inputCoin, 0, len(values))
+		for _, value := range values {
+			pool = append(pool, &inputCoin{value: value, effectiveValue: value})
+		}
+		return pool
+	}
+
+	selected, value := selectCoinsBnB(newPool(1, 2, 3, 4), 5, 0)
+	assert.Equal(t, amount.Amount(5), value)
+	assert.Equal(t, 2, len(selected))
+
+	selected, value = selectCoinsBnB(newPool(1, 2, 3, 4), 10, 0)
+	assert.Equal(t, amount.Amount(10), value)
+	assert.Equal(t, 4, len(selected))
+
+	// The closest set within the cost of change is found.
+	selected, value = selectCoinsBnB(newPool(4, 7, 9), 10, 2)
+	assert.Equal(t, amount.Amount(11), value)
+	assert.Equal(t, 2, len(selected))
+
+	// No set within the cost of change, or not enough funds.
+	selected, _ = selectCoinsBnB(newPool(4, 7, 9), 10, 0)
+	assert.Nil(t, selected)
+	selected, _ = selectCoinsBnB(newPool(1, 2), 10, 5)
+	assert.Nil(t, selected)
+}
+
+func TestKnapsackSolver(t *testing.T) {
+	newPool := func(values ...amount.Amount) []*inputCoin {
+		pool := make([]*inputCoin, 0, len(values))
+		for _, value := range values {
+			pool = append(pool, &inputCoin{value: value, effectiveValue: value})
+		}
+		return pool
+	}
+
+	// A coin of the exact value is taken alone.
+	selected, value := knapsackSolver(newPool(minChange, 5*minChange, 3*minChange), 5*minChange)
+	assert.Equal(t, 5*minChange, value)
+	assert.Equal(t, 1, len(selected))
+
+	// The small coins adding up to the target are all taken.
+	selected, value = knapsackSolver(newPool(minChange/10, minChange/5, 5*minChange), minChange*3/10)
+	assert.Equal(t, minChange*3/10, value)
+	assert.Equal(t, 2, len(selected))
+
+	// Not enough small coins, the smallest larger coin is taken.
+	selected, value = knapsackSolver(newPool(minChange/10, 7*minChange, 5*minChange), minChange)
+	assert.Equal(t, 5*minChange, value)
+	assert.Equal(t, 1, len(selected))
+
+	// A subset of the small coins matches the target.
+	selected, value = knapsackSolver(newPool(6*minChange, 7*minChange, 8*minChange, 20*minChange), 13*minChange)
+	assert.Equal(t, 13*minChange, value)
+	assert.Equal(t, 2, len(selected))
+
+	selected, _ = knapsackSolver(newPool(minChange, minChange), 3*minChange)
+	assert.Nil(t, selected)
+}
+
+func TestSelectCoinsMinConf(t *testing.T) {
+	coins := append(newTestCoins(0, true, 5*minChange), newTestCoins(3, false, 2*minChange)...)
+	knapsack := &CoinSelectionParams{}
+
+	// Unconfirmed coins and coins with too few confirmations are ignored.
+	selected, _, _ := SelectCoinsMinConf(minChange, &CoinEligibilityFilter{ConfMine: 1, ConfTheirs: 6},
+		coins, knapsack)
+	assert.Nil(t, selected)
+
+	selected, value, bnbUsed := SelectCoinsMinConf(minChange, &CoinEligibilityFilter{ConfMine: 1, ConfTheirs: 1},
+		coins, knapsack)
+	assert.Equal(t, 2*minChange, value)
+	assert.Equal(t, 1, len(selected))
+	assert.False(t, bnbUsed)
+
+	selected, value, _ = SelectCoinsMinConf(6*minChange, &CoinEligibilityFilter{ConfMine: 0, ConfTheirs: 1},
+		coins, knapsack)
+	assert.Equal(t, 7*minChange, value)
+	assert.Equal(t, 7*minChange, sumCoins(selected))
+
+	// The branch and bound search pays the fee of the inputs it selects from
+	// their value.
+	bnb := &CoinSelectionParams{
+		UseBnB:           true,
+		EffectiveFee:     util.NewFeeRate(1000),
+		ChangeOutputSize: p2pkhOutputSize,
+		ChangeSpendSize:  p2pkhSpendSize,
+		TxNoInputsSize:   10 + p2pkhOutputSize,
+	}
+	target := 2*minChange - amount.Amount(p2pkhSpendSize+10+p2pkhOutputSize)
+	selected, value, bnbUsed = SelectCoinsMinConf(target, &CoinEligibilityFilter{ConfMine: 1, ConfTheirs: 1},
+		coins, bnb)
+	assert.Equal(t, 2*minChange, value)
+	assert.Equal(t, 1, len(selected))
+	assert.True(t, bnbUsed)
+}
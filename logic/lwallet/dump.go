@@ -0,0 +1,241 @@
+package lwallet
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/wallet"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/cashaddr"
+	"github.com/pkg/errors"
+)
+
+const dumpTimeFormat = "2006-01-02T15:04:05Z"
+
+func formatDumpTime(t int64) string {
+	return time.Unix(t, 0).UTC().Format(dumpTimeFormat)
+}
+
+func parseDumpTime(s string) int64 {
+	t, err := time.Parse(dumpTimeFormat, s)
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
+}
+
+// encodeDumpString escapes the characters of a label which would break the
+// space separated dump format.
+func encodeDumpString(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= 32 || c >= 128 || c == '%' {
+			fmt.Fprintf(&b, "%%%02x", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func decodeDumpString(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if c, err := hex.DecodeString(s[i+1 : i+3]); err == nil {
+				b.WriteByte(c[0])
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// DumpWallet writes all the private keys and scripts of the wallet to a new
+// file, in a format read by ImportWallet.  The wallet must be unlocked.
+func DumpWallet(w *wallet.Wallet, filename string) error {
+	if w.IsLocked() {
+		return crypto.ErrKeyStoreLocked
+	}
+	if _, err := os.Stat(filename); err == nil {
+		return errors.Errorf("%s already exists. If you are sure this is what you want, move it out of the way first", filename)
+	}
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+
+	params := chain.GetInstance().GetParams()
+	tip := chain.GetInstance().Tip()
+	fmt.Fprintf(writer, "# Wallet dump created by %s %d.%d.%d\n", conf.AppName,
+		conf.AppMajor, conf.AppMinor, conf.AppPatch)
+	fmt.Fprintf(writer, "# * Created on %s\n", formatDumpTime(time.Now().Unix()))
+	if tip != nil {
+		fmt.Fprintf(writer, "# * Best block at time of backup was %d (%s),\n", tip.Height, tip.GetBlockHash())
+		fmt.Fprintf(writer, "#   mined on %s\n", formatDumpTime(int64(tip.GetBlockTime())))
+	}
+	fmt.Fprintf(writer, "\n")
+
+	hdChain := w.GetHDChain()
+	if hdChain != nil {
+		masterKey, err := w.GetHDMasterKey()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(writer, "# extended private masterkey: %s\n\n", masterKey.String())
+	}
+
+	keyPoolKeys := w.GetKeyPoolKeys()
+	for _, keyPair := range w.GetAllKeyPairs() {
+		keyID := keyPair.GetPublicKey().ToHash160()
+		address, err := cashaddr.NewCashAddressPubKeyHash(keyID, params)
+		if err != nil {
+			return err
+		}
+		createTime := int64(0)
+		hdKeyPath := ""
+		meta := w.GetKeyMetadata(keyID)
+		if meta != nil {
+			createTime = meta.CreateTime
+			hdKeyPath = meta.HDKeyPath
+		}
+
+		var tag string
+		if addressBook := w.GetAddressBook(keyID); addressBook != nil {
+			tag = "label=" + encodeDumpString(addressBook.Account)
+		} else if hdKeyPath == "s" {
+			if hdChain != nil && string(hdChain.SeedID) == string(keyID) {
+				tag = "hdseed=1"
+			} else {
+				tag = "inactivehdseed=1"
+			}
+		} else if _, ok := keyPoolKeys[string(keyID)]; ok {
+			tag = "reserve=1"
+		} else {
+			tag = "change=1"
+		}
+
+		fmt.Fprintf(writer, "%s %s %s # addr=%s", keyPair.GetPrivateKey().ToString(),
+			formatDumpTime(createTime), tag, address.String())
+		if hdKeyPath != "" && hdKeyPath != "s" {
+			fmt.Fprintf(writer, " hdkeypath=%s", hdKeyPath)
+		}
+		fmt.Fprintf(writer, "\n")
+	}
+	fmt.Fprintf(writer, "\n")
+
+	for _, sc := range w.GetScripts() {
+		address, err := cashaddr.NewCashAddressScriptHash(sc.Bytes(), params)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(writer, "%s %s script=1 # addr=%s\n", hex.EncodeToString(sc.Bytes()),
+			formatDumpTime(0), address.String())
+	}
+	fmt.Fprintf(writer, "\n# End of dump\n")
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// ImportWallet adds the private keys and scripts of a file written by
+// DumpWallet.  It returns the earliest creation time of the imported keys,
+// from which the blocks need to be rescanned.  The wallet must be unlocked.
+func ImportWallet(w *wallet.Wallet, filename string) (int64, error) {
+	if w.IsLocked() {
+		return 0, crypto.ErrKeyStoreLocked
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	earliestTime := time.Now().Unix()
+	imported := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.Split(line, " ")
+		if len(fields) < 2 {
+			continue
+		}
+		createTime := parseDumpTime(fields[1])
+
+		privateKey, err := crypto.DecodePrivateKey(fields[0])
+		if err != nil {
+			// Not a key, so maybe a redeem script.
+			data, err := hex.DecodeString(fields[0])
+			if err != nil || len(fields) < 3 || fields[2] != "script=1" {
+				continue
+			}
+			sc := script.NewScriptRaw(data)
+			if w.GetScript(util.Hash160(data)) != nil {
+				log.Info("Skipping import of %s (script already present)", fields[0])
+				continue
+			}
+			if err := w.AddScript(sc); err != nil {
+				return 0, err
+			}
+			imported++
+			if createTime > 0 && createTime < earliestTime {
+				earliestTime = createTime
+			}
+			continue
+		}
+
+		keyID := privateKey.PubKey().ToHash160()
+		err = w.ImportPrivateKey(privateKey, createTime)
+		if err == wallet.ErrKeyExists {
+			log.Info("Skipping import of %x (key already present)", keyID)
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		imported++
+		if createTime < earliestTime {
+			earliestTime = createTime
+		}
+
+		for _, field := range fields[2:] {
+			if strings.HasPrefix(field, "#") {
+				break
+			}
+			if strings.HasPrefix(field, "label=") {
+				label := decodeDumpString(strings.TrimPrefix(field, "label="))
+				if err := w.SetAddressBook(keyID, label, "receive"); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	w.MarkDirty()
+
+	log.Info("imported %d keys and scripts from %s", imported, filename)
+	return earliestTime, nil
+}
@@ -0,0 +1,86 @@
+package lwallet
+
+import (
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/wallet"
+	"github.com/copernet/copernicus/util"
+	"github.com/pkg/errors"
+)
+
+var ErrImportSpendable = errors.New("The wallet already contains the private key for this address or script")
+
+// ImportPrivKey adds a private key of unknown creation time to the wallet
+// and labels its address.  Importing a key the wallet already has only
+// updates the label.
+func ImportPrivKey(w *wallet.Wallet, privateKey *crypto.PrivateKey, label string) error {
+	keyID := privateKey.PubKey().ToHash160()
+	if err := w.SetAddressBook(keyID, label, "receive"); err != nil {
+		return err
+	}
+	err := w.ImportPrivateKey(privateKey, 0)
+	if err == wallet.ErrKeyExists {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	w.MarkDirty()
+	return nil
+}
+
+// ImportScript makes the wallet watch the outputs paying to a script.  If
+// isRedeemScript is set, the script is added as a redeem script and its P2SH
+// script is watched instead.  keyHash is the hash of the address to label,
+// nil if the script has no address.
+func ImportScript(w *wallet.Wallet, sc *script.Script, keyHash []byte, label string, isRedeemScript bool) error {
+	if isRedeemScript {
+		if err := w.AddScript(sc); err != nil {
+			return err
+		}
+		keyHash = util.Hash160(sc.Bytes())
+		p2shScript, err := generateScript(opcodes.OP_HASH160, keyHash, opcodes.OP_EQUAL)
+		if err != nil {
+			return err
+		}
+		sc = p2shScript
+	}
+	if w.IsMineScript(sc) == wallet.ISMINE_SPENDABLE {
+		return ErrImportSpendable
+	}
+
+	if keyHash != nil {
+		if err := w.SetAddressBook(keyHash, label, "receive"); err != nil {
+			return err
+		}
+	}
+	if err := w.AddWatchOnly(sc); err != nil {
+		return err
+	}
+	w.MarkDirty()
+	return nil
+}
+
+// ImportPubKey makes the wallet watch the P2PKH and P2PK outputs of a
+// public key.
+func ImportPubKey(w *wallet.Wallet, pubKey *crypto.PublicKey, label string) error {
+	keyHash := pubKey.ToHash160()
+	p2pkhScript, err := getP2PKHScript(keyHash)
+	if err != nil {
+		return err
+	}
+	p2pkScript, err := generateScript(pubKey.ToBytes(), opcodes.OP_CHECKSIG)
+	if err != nil {
+		return err
+	}
+
+	if err := ImportScript(w, p2pkhScript, keyHash, label, false); err != nil {
+		return err
+	}
+	return ImportScript(w, p2pkScript, nil, label, false)
+}
+
+func IsWatchOnly(w *wallet.Wallet, sc *script.Script) bool {
+	return w.IsMineScript(sc) == wallet.ISMINE_WATCH_ONLY
+}
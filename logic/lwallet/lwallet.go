@@ -22,7 +22,6 @@ import (
 	"github.com/copernet/copernicus/model/wallet"
 	"github.com/copernet/copernicus/net/server"
 	"github.com/copernet/copernicus/net/wire"
-	"github.com/copernet/copernicus/rpc/btcjson"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
 	"github.com/copernet/copernicus/util/cashaddr"
@@ -37,16 +36,18 @@ type TxnCoin struct {
 	OutPoint *outpoint.OutPoint
 	Coin     *utxo.Coin
 	IsSafe   bool
+	Depth    int32
+	FromMe   bool
 }
 
 func IsWalletEnable() bool {
 	return wallet.GetInstance().IsEnable()
 }
 
-func GetNewAddress(account string, isLegacyAddr bool) (string, error) {
-	pubKey, err := wallet.GetInstance().GenerateNewKey()
+func GetNewAddress(w *wallet.Wallet, account string, isLegacyAddr bool) (string, error) {
+	pubKey, err := w.GetKeyFromPool(false)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 	pubKeyHash := pubKey.ToHash160()
 
@@ -65,15 +66,31 @@ func GetNewAddress(account string, isLegacyAddr bool) (string, error) {
 		address = cashAddr.String()
 	}
 
-	wallet.GetInstance().SetAddressBook(pubKeyHash, account, "receive")
+	w.SetAddressBook(pubKeyHash, account, "receive")
 
 	return address, nil
 }
 
-func GetMiningAddress() (string, error) {
-	pubKey, err := wallet.GetInstance().GetReservedKey()
+// GetRawChangeAddress returns a new address of the internal chain, for
+// receiving change.  Unlike GetNewAddress it is not added to the address
+// book.
+func GetRawChangeAddress(w *wallet.Wallet) (string, error) {
+	pubKey, err := w.GetKeyFromPool(true)
+	if err != nil {
+		return "", err
+	}
+
+	cashAddr, err := cashaddr.NewCashAddressPubKeyHash(pubKey.ToHash160(), chain.GetInstance().GetParams())
+	if err != nil {
+		return "", err
+	}
+	return cashAddr.String(), nil
+}
+
+func GetMiningAddress(w *wallet.Wallet) (string, error) {
+	pubKey, err := w.GetReservedKey(false)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
 	pubKeyHash := pubKey.ToHash160()
@@ -84,12 +101,16 @@ func GetMiningAddress() (string, error) {
 	return cashAddr.String(), nil
 }
 
-func GetKeyPair(pubKeyHash []byte) *crypto.KeyPair {
-	return wallet.GetInstance().GetKeyPair(pubKeyHash)
+func GetKeyPair(w *wallet.Wallet, pubKeyHash []byte) *crypto.KeyPair {
+	return w.GetKeyPair(pubKeyHash)
+}
+
+func GetKeyMetadata(w *wallet.Wallet, pubKeyHash []byte) *wallet.KeyMetadata {
+	return w.GetKeyMetadata(pubKeyHash)
 }
 
-func GetKeyPairs(pubKeyHashList [][]byte) []*crypto.KeyPair {
-	return wallet.GetInstance().GetKeyPairs(pubKeyHashList)
+func GetKeyPairs(w *wallet.Wallet, pubKeyHashList [][]byte) []*crypto.KeyPair {
+	return w.GetKeyPairs(pubKeyHashList)
 }
 
 func CheckFinalTx(txn *tx.Tx) bool {
@@ -97,9 +118,9 @@ func CheckFinalTx(txn *tx.Tx) bool {
 	return err == nil
 }
 
-func AvailableCoins(onlySafe bool, includeZeroValue bool) []*TxnCoin {
+func AvailableCoins(w *wallet.Wallet, onlySafe bool, includeZeroValue bool) []*TxnCoin {
 	coins := make([]*TxnCoin, 0)
-	walletTxns := wallet.GetInstance().GetWalletTxns()
+	walletTxns := w.GetWalletTxns()
 	for _, walletTx := range walletTxns {
 		txn := walletTx.Tx
 		if !CheckFinalTx(txn) {
@@ -118,20 +139,24 @@ func AvailableCoins(onlySafe bool, includeZeroValue bool) []*TxnCoin {
 			continue
 		}
 
-		isSafe := wallet.GetInstance().IsTrusted(walletTx)
+		isSafe := w.IsTrusted(walletTx)
 		if onlySafe && !isSafe {
 			continue
 		}
+		fromMe := w.GetDebitTx(walletTx, wallet.ISMINE_ALL) > 0
 
 		for index := 0; index < txn.GetOutsCount(); index++ {
 			// check coin is unspent
 			outPoint := outpoint.NewOutPoint(txHash, uint32(index))
-			coin := wallet.GetInstance().GetUnspentCoin(outPoint)
+			coin := w.GetUnspentCoin(outPoint)
 			if coin == nil {
 				continue
 			}
+			if w.IsLockedCoin(outPoint) {
+				continue
+			}
 			// check coin is mine
-			if !wallet.IsUnlockable(coin.GetScriptPubKey()) {
+			if !w.IsUnlockable(coin.GetScriptPubKey()) {
 				continue
 			}
 			// check zero value
@@ -142,32 +167,39 @@ func AvailableCoins(onlySafe bool, includeZeroValue bool) []*TxnCoin {
 				OutPoint: outPoint,
 				Coin:     coin,
 				IsSafe:   isSafe,
+				Depth:    depth,
+				FromMe:   fromMe,
 			})
 		}
 	}
 	return coins
 }
 
-func GetAccountName(keyHash []byte) string {
-	return wallet.GetInstance().GetAccountName(keyHash)
+func GetAccountName(w *wallet.Wallet, keyHash []byte) string {
+	return w.GetAccountName(keyHash)
 }
 
-func GetScript(scriptHash []byte) *script.Script {
-	return wallet.GetInstance().GetScript(scriptHash)
+func GetScript(w *wallet.Wallet, scriptHash []byte) *script.Script {
+	return w.GetScript(scriptHash)
 }
 
-func AddToWallet(txn *tx.Tx, blockhash util.Hash, extInfo map[string]string) {
-	wallet.GetInstance().AddToWallet(txn, blockhash, extInfo)
+func AddToWallet(w *wallet.Wallet, txn *tx.Tx, blockhash util.Hash, extInfo map[string]string) {
+	w.AddToWallet(txn, blockhash, extInfo)
 }
-func RemoveFromWallet(txn *tx.Tx) {
-	wallet.GetInstance().RemoveFromWallet(txn)
+func RemoveFromWallet(w *wallet.Wallet, txn *tx.Tx) {
+	w.RemoveFromWallet(txn)
 }
 
-func SetFeeRate(feePaid int64, byteSize int64) {
-	wallet.GetInstance().SetFeeRate(feePaid, byteSize)
+func SetFeeRate(w *wallet.Wallet, feePaid int64, byteSize int64) {
+	w.SetFeeRate(feePaid, byteSize)
 }
-func FundTransaction(fundTx *tx.Tx, setSubtractFeeFromOutputs *set.Set, options *btcjson.FundRawTxoptions) (
-	int, amount.Amount, error) {
+
+// FundTransaction adds inputs and, if needed, a change output to fundTx so
+// that it pays its outputs and the fee.  The inputs fundTx already has are
+// kept and must be spendable by the wallet.  It returns the position of the
+// change output, -1 if none, and the fee.
+func FundTransaction(w *wallet.Wallet, fundTx *tx.Tx, changePosInOut int, setSubtractFeeFromOutputs *set.Set,
+	lockUnspents bool, coinControl *CoinControl) (int, amount.Amount, error) {
 
 	var vecSend []*wallet.Recipient
 	for idx, out := range fundTx.GetOuts() {
@@ -178,8 +210,19 @@ func FundTransaction(fundTx *tx.Tx, setSubtractFeeFromOutputs *set.Set, options
 		}
 		vecSend = append(vecSend, &recipient)
 	}
-	changePosInOut := options.ChangePosition
-	wtx, feeOut, err := CreateTransaction(vecSend, &changePosInOut, false)
+
+	// The wallet can neither value nor sign inputs whose coins it does not
+	// hold, so external inputs are not supported.
+	coinControl.AllowOtherInputs = true
+	for _, in := range fundTx.GetIns() {
+		if w.GetUnspentCoin(in.PreviousOutPoint) == nil {
+			return 0, amount.Amount(0), errors.Errorf("Input %s is not an unspent coin of the wallet",
+				in.PreviousOutPoint.String())
+		}
+		coinControl.Select(in.PreviousOutPoint)
+	}
+
+	wtx, feeOut, err := CreateTransaction(w, vecSend, &changePosInOut, coinControl, false)
 	if err != nil {
 		return 0, amount.Amount(0), err
 	}
@@ -192,16 +235,35 @@ func FundTransaction(fundTx *tx.Tx, setSubtractFeeFromOutputs *set.Set, options
 	for idx, out := range fundTx.GetOuts() {
 		out.SetValue(wtx.GetTxOut(idx).GetValue())
 	}
-	//TODO add handle fundTxOptions
+
+	// Add new txins while keeping the original ones and their order.
+	for _, in := range wtx.GetIns() {
+		if coinControl.IsSelected(in.PreviousOutPoint) {
+			continue
+		}
+		fundTx.AddTxIn(txin.NewTxIn(in.PreviousOutPoint, script.NewEmptyScript(), in.Sequence))
+		if lockUnspents {
+			if err := w.LockCoin(in.PreviousOutPoint, false); err != nil {
+				return 0, amount.Amount(0), err
+			}
+		}
+	}
 
 	return changePosInOut, feeOut, nil
 }
-func CreateTransaction(recipients []*wallet.Recipient, changePosInOut *int, sign bool) (*tx.Tx,
-	amount.Amount, error) {
+
+func CreateTransaction(w *wallet.Wallet, recipients []*wallet.Recipient, changePosInOut *int, coinControl *CoinControl,
+	sign bool) (*tx.Tx, amount.Amount, error) {
 	if len(recipients) == 0 {
 		return nil, 0, errors.New("Transaction must have at least one recipient")
 	}
 
+	// The dummy signatures used to compute the fee need the private keys
+	// as well.
+	if w.IsLocked() {
+		return nil, 0, crypto.ErrKeyStoreLocked
+	}
+
 	value := amount.Amount(0)
 	changePosRequest := *changePosInOut
 	subtractFeeCount := 0
@@ -245,9 +307,28 @@ func CreateTransaction(recipients []*wallet.Recipient, changePosInOut *int, sign
 		lockTime = util.MaxU32(0, lockTime-uint32(util.GetRandInt(100)))
 	}
 
+	if coinControl == nil {
+		coinControl = NewCoinControl()
+	}
+	scriptChange := coinControl.ChangeScript
+
+	// The branch and bound search does not leave change, which would leave
+	// no output to take the fee from if the recipients pay it.
+	params := &CoinSelectionParams{
+		UseBnB:           subtractFeeCount == 0,
+		ChangeOutputSize: p2pkhOutputSize,
+		ChangeSpendSize:  p2pkhSpendSize,
+	}
+	if scriptChange != nil {
+		params.ChangeOutputSize = int(txout.NewTxOut(0, scriptChange).SerializeSize())
+		if spendSize := estimateSpendSize(scriptChange); spendSize >= 0 {
+			params.ChangeSpendSize = spendSize
+		}
+	}
+
 	var selectedCoins []*TxnCoin
 	txn := tx.NewTx(lockTime, tx.DefaultVersion)
-	coins := AvailableCoins(true, false)
+	coins := AvailableCoins(w, true, false)
 	feeRet := amount.Amount(0)
 	dustRelayFee := util.NewFeeRate(conf.Cfg.TxOut.DustRelayFee)
 
@@ -295,14 +376,27 @@ func CreateTransaction(recipients []*wallet.Recipient, changePosInOut *int, sign
 		}
 
 		// Choose coins to use.
-		var valueIn amount.Amount
-		selectedCoins, valueIn = selectCoins(coins, valueToSelect)
+		params.EffectiveFee = util.NewFeeRate(w.GetMinimumFee(1000))
+		params.TxNoInputsSize = int(txn.SerializeSize())
+		valueIn, bnbUsed := amount.Amount(0), false
+		selectedCoins, valueIn, bnbUsed = selectCoins(w, coins, valueToSelect, coinControl, params)
 		if selectedCoins == nil {
+			if bnbUsed {
+				// Try again with the knapsack solver.
+				params.UseBnB = false
+				continue
+			}
 			return nil, 0, errors.New("Insufficient funds")
 		}
 
 		change := valueIn - valueToSelect
-		if change > 0 {
+		if change > 0 && bnbUsed {
+			// The branch and bound search selected the coins so that the
+			// excess is less than what a change output costs, it goes
+			// to the fee.
+			*changePosInOut = -1
+			feeRet += change
+		} else if change > 0 {
 			// Fill a vout to ourself.
 			// TODO: pass in scriptChange instead of reservekey so change
 			// transaction isn't always pay-to-bitcoin-address.
@@ -316,13 +410,15 @@ func CreateTransaction(recipients []*wallet.Recipient, changePosInOut *int, sign
 			// unknown transactions that were written with keys of ours
 			// to recover post-backup change.
 
-			reservedKey, err := wallet.GetInstance().GetReservedKey()
-			if err != nil || reservedKey == nil {
-				return nil, 0, errors.New("Keypool ran out, please call keypoolrefill first")
-			}
-			scriptChange, err := getP2PKHScript(reservedKey.ToHash160())
-			if err != nil {
-				return nil, 0, err
+			if scriptChange == nil {
+				reservedKey, err := w.GetReservedKey(true)
+				if err != nil || reservedKey == nil {
+					return nil, 0, errors.New("Keypool ran out, please call keypoolrefill first")
+				}
+				scriptChange, err = getP2PKHScript(reservedKey.ToHash160())
+				if err != nil {
+					return nil, 0, err
+				}
 			}
 
 			newTxOut := txout.NewTxOut(change, scriptChange)
@@ -364,6 +460,8 @@ func CreateTransaction(recipients []*wallet.Recipient, changePosInOut *int, sign
 
 				txn.InsertTxOut(*changePosInOut, newTxOut)
 			}
+		} else {
+			*changePosInOut = -1
 		}
 
 		// Fill vin
@@ -380,7 +478,7 @@ func CreateTransaction(recipients []*wallet.Recipient, changePosInOut *int, sign
 			pubKeyHash := getPubKeyHash(txnCoin.Coin.GetScriptPubKey())
 			pubKeyHashList = append(pubKeyHashList, pubKeyHash...)
 		}
-		keyPairs := GetKeyPairs(pubKeyHashList)
+		keyPairs := GetKeyPairs(w, pubKeyHashList)
 		keyStore.AddKeyPairs(keyPairs)
 
 		// Fill in dummy signatures for fee calculation.
@@ -402,7 +500,7 @@ func CreateTransaction(recipients []*wallet.Recipient, changePosInOut *int, sign
 			txn.UpdateInScript(i, script.NewEmptyScript())
 		}
 
-		feeNeeded := amount.Amount(wallet.GetInstance().GetMinimumFee(int(txSize)))
+		feeNeeded := amount.Amount(w.GetMinimumFee(int(txSize)))
 
 		// If we made it here and we aren't even able to meet the relay fee
 		// on the next pass, give up because we must be at the maximum
@@ -448,8 +546,10 @@ func CreateTransaction(recipients []*wallet.Recipient, changePosInOut *int, sign
 			}
 		}
 
-		// Include more fee and try again.
+		// Include more fee and try again.  The effective values the branch
+		// and bound search relies on underestimated the fee.
 		feeRet = feeNeeded
+		params.UseBnB = false
 		continue
 	}
 
@@ -465,7 +565,7 @@ func CreateTransaction(recipients []*wallet.Recipient, changePosInOut *int, sign
 			pubKeyHash := getPubKeyHash(txnCoin.Coin.GetScriptPubKey())
 			pubKeyHashList = append(pubKeyHashList, pubKeyHash...)
 		}
-		keyPairs := GetKeyPairs(pubKeyHashList)
+		keyPairs := GetKeyPairs(w, pubKeyHashList)
 		keyStore.AddKeyPairs(keyPairs)
 
 		sigErrors := ltx.SignRawTransaction(txns, nil, keyStore, coinsMap, uint32(hashType))
@@ -481,25 +581,63 @@ func CreateTransaction(recipients []*wallet.Recipient, changePosInOut *int, sign
 	return txn, feeRet, nil
 }
 
-func selectCoins(coins []*TxnCoin, targetValue amount.Amount) ([]*TxnCoin, amount.Amount) {
-	var selectedCoins []*TxnCoin
-	var valueRet amount.Amount
-	// TODO:a simple implementation just for testing, not support 'preset inputs'
-	selectedCoins, valueRet = SelectCoinsMinConf(targetValue, 1, 6, 0, coins)
-	if selectedCoins != nil {
-		return selectedCoins, valueRet
+// selectCoins returns the inputs selected in coinControl along with, if
+// they do not cover targetValue and other inputs are allowed, available
+// coins picked by SelectCoinsMinConf.  The coins need one confirmation, or
+// six if they were not sent by the wallet, then one in any case and then,
+// with SpendZeroConfChange, none if they were sent by the wallet.
+func selectCoins(w *wallet.Wallet, coins []*TxnCoin, targetValue amount.Amount, coinControl *CoinControl,
+	params *CoinSelectionParams) ([]*TxnCoin, amount.Amount, bool) {
+
+	presetCoins := make([]*TxnCoin, 0)
+	presetValue := amount.Amount(0)
+	for _, outPoint := range coinControl.ListSelected() {
+		coin := w.GetUnspentCoin(outPoint)
+		if coin == nil {
+			return nil, 0, false
+		}
+		presetCoins = append(presetCoins, &TxnCoin{OutPoint: outPoint, Coin: coin})
+		presetValue += coin.GetAmount()
+	}
+	if coinControl.HasSelected() && (!coinControl.AllowOtherInputs || presetValue >= targetValue) {
+		if presetValue < targetValue {
+			return nil, 0, false
+		}
+		return presetCoins, presetValue, false
 	}
-	selectedCoins, valueRet = SelectCoinsMinConf(targetValue, 1, 1, 0, coins)
-	if selectedCoins != nil {
-		return selectedCoins, valueRet
+
+	selectionParams := *params
+	available := coins
+	if coinControl.HasSelected() {
+		// The branch and bound search does not account for the preset
+		// inputs.
+		selectionParams.UseBnB = false
+		available = make([]*TxnCoin, 0, len(coins))
+		for _, txnCoin := range coins {
+			if !coinControl.IsSelected(txnCoin.OutPoint) {
+				available = append(available, txnCoin)
+			}
+		}
 	}
+
+	filters := []*CoinEligibilityFilter{{ConfMine: 1, ConfTheirs: 6}, {ConfMine: 1, ConfTheirs: 1}}
 	if conf.Cfg.Wallet.SpendZeroConfChange {
-		selectedCoins, valueRet = SelectCoinsMinConf(targetValue, 0, 1, 6, coins)
-		if selectedCoins != nil {
-			return selectedCoins, valueRet
+		filters = append(filters, &CoinEligibilityFilter{ConfMine: 0, ConfTheirs: 1})
+	}
+	for _, filter := range filters {
+		if filter.ConfMine < coinControl.MinDepth {
+			filter.ConfMine = coinControl.MinDepth
+		}
+		if filter.ConfTheirs < coinControl.MinDepth {
+			filter.ConfTheirs = coinControl.MinDepth
+		}
+		selected, valueRet, bnbUsed := SelectCoinsMinConf(targetValue-presetValue, filter, available,
+			&selectionParams)
+		if selected != nil {
+			return append(presetCoins, selected...), presetValue + valueRet, bnbUsed
 		}
 	}
-	return nil, 0
+	return nil, 0, selectionParams.UseBnB
 }
 
 func generateScript(data ...interface{}) (*script.Script, error) {
@@ -548,75 +686,18 @@ func getPubKeyHash(scriptPubKey *script.Script) [][]byte {
 	return pubKeyHash
 }
 
-func SelectCoinsMinConf(targetValue amount.Amount, confMine int, confTheirs int,
-	maxAncestors int, coins []*TxnCoin) ([]*TxnCoin, amount.Amount) {
-	// TODO:not support 'theirs' and 'maxAncestors'
-	lowestLargerValue := amount.Amount(0)
-	totalLowerValue := amount.Amount(0)
-
-	var lowestLargerCoin *TxnCoin
-	lowerCoins := make([]*TxnCoin, 0)
-	retCoins := make([]*TxnCoin, 0)
-
-	for _, txnCoin := range coins {
-		coin := txnCoin.Coin
-		if confMine > 0 && coin.IsMempoolCoin() {
-			continue
-		}
-
-		coinValue := coin.GetAmount()
-		if coinValue == targetValue {
-			retCoins = append(retCoins, txnCoin)
-			return retCoins, coinValue
-
-		} else if coinValue > targetValue {
-			if lowestLargerValue == 0 || lowestLargerValue > coinValue {
-				lowestLargerValue = coinValue
-				lowestLargerCoin = txnCoin
-			}
-
-		} else {
-			totalLowerValue += coinValue
-			lowerCoins = append(lowerCoins, txnCoin)
-		}
-	}
-
-	if totalLowerValue < targetValue {
-		if lowestLargerValue > 0 {
-			retCoins = append(retCoins, lowestLargerCoin)
-			return retCoins, lowestLargerValue
-		}
-		return nil, 0
-
-	} else if totalLowerValue == targetValue {
-		return lowerCoins, totalLowerValue
-	}
-
-	// TODO:a simple implementation just for testing
-	retValue := amount.Amount(0)
-	for _, txnCoin := range coins {
-		coin := txnCoin.Coin
-		retValue += coin.GetAmount()
-		retCoins = append(retCoins, txnCoin)
-		if retValue >= targetValue {
-			break
-		}
-	}
-	return retCoins, retValue
-}
-
-func CommitTransaction(txNew *tx.Tx, extInfo map[string]string) error {
+func CommitTransaction(w *wallet.Wallet, txNew *tx.Tx, extInfo map[string]string) error {
 	var err error
 	txHash := txNew.GetHash()
 	log.Info("CommitTransaction:%s", txHash)
 
 	// Add tx to wallet, because if it has change it's also ours, otherwise just
 	// for transaction history.
-	AddToWallet(txNew, util.HashZero, extInfo)
+	AddToWallet(w, txNew, util.HashZero, extInfo)
 
 	// Notify that old coins are spent.
 	for _, txIn := range txNew.GetIns() {
-		wallet.GetInstance().MarkSpent(txIn.PreviousOutPoint)
+		w.MarkSpent(txIn.PreviousOutPoint)
 	}
 
 	// Track how many getdata requests our transaction gets.
@@ -631,7 +712,7 @@ func CommitTransaction(txNew *tx.Tx, extInfo map[string]string) error {
 		return err
 	}
 
-	if wallet.GetInstance().GetBroadcastTx() {
+	if w.GetBroadcastTx() {
 		txInvMsg := wire.NewInvVect(wire.InvTypeTx, &txHash)
 		_, err = server.ProcessForRPC(txInvMsg)
 		if err != nil {
@@ -642,8 +723,8 @@ func CommitTransaction(txNew *tx.Tx, extInfo map[string]string) error {
 	return err
 }
 
-func IsMine(sc *script.Script) bool {
-	return wallet.IsUnlockable(sc)
+func IsMine(w *wallet.Wallet, sc *script.Script) bool {
+	return w.IsUnlockable(sc)
 }
 
 func CreateMultiSigRedeemScript(requiredNum int, keys []string) (res *script.Script, err error) {
@@ -0,0 +1,59 @@
+package lwallet
+
+import (
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/model/psbt"
+	"github.com/copernet/copernicus/model/wallet"
+)
+
+// FillPSBT adds to p the previous outputs and the redeem scripts the wallet
+// knows of and, if sign is set, the signatures with hashType of the wallet
+// keys.  It returns whether all the inputs are finalized.
+func FillPSBT(w *wallet.Wallet, p *psbt.PSBT, hashType uint32, sign bool) (bool, error) {
+	complete := true
+	for i, txIn := range p.Tx.GetIns() {
+		in := p.Inputs[i]
+		if in.IsFinalized() {
+			continue
+		}
+
+		if in.UTXO == nil {
+			prevOut := txIn.PreviousOutPoint
+			if wtx := w.GetWalletTx(prevOut.Hash); wtx != nil && int(prevOut.Index) < wtx.GetOutsCount() {
+				in.UTXO = wtx.GetTxOut(int(prevOut.Index))
+			}
+		}
+
+		keyStore := crypto.NewKeyStore()
+		if in.UTXO != nil {
+			scriptCode := in.UTXO.GetScriptPubKey()
+			if scriptCode.IsPayToScriptHash() {
+				if in.RedeemScript == nil {
+					in.RedeemScript = GetScript(w, scriptCode.GetData()[2:22])
+				}
+				if in.RedeemScript != nil {
+					scriptCode = in.RedeemScript
+				}
+			}
+			if sign {
+				keyStore.AddKeyPairs(GetKeyPairs(w, getPubKeyHash(scriptCode)))
+			}
+		}
+
+		finalized, err := ltx.SignPSBTInput(p, i, keyStore, hashType)
+		if err != nil {
+			return false, err
+		}
+		complete = complete && finalized
+	}
+
+	for i, txOut := range p.Tx.GetOuts() {
+		out := p.Outputs[i]
+		scriptPubKey := txOut.GetScriptPubKey()
+		if out.RedeemScript == nil && scriptPubKey.IsPayToScriptHash() {
+			out.RedeemScript = GetScript(w, scriptPubKey.GetData()[2:22])
+		}
+	}
+	return complete, nil
+}
@@ -0,0 +1,115 @@
+package lwallet
+
+import (
+	"sort"
+	"time"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/wallet"
+	"github.com/copernet/copernicus/net/server"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/util"
+)
+
+// maxResendInterval is the longest time between two resends of the
+// unconfirmed wallet transactions, the actual time is random so that the
+// resends do not tell which node the transactions come from.
+const maxResendInterval = 30 * 60
+
+// resendMinAge is how long before the last block a transaction must have been
+// received to be resent, it had the chance to be mined otherwise.
+const resendMinAge = 5 * 60
+
+// lastResendTip is the tip of the active chain at the last periodic resend.
+var lastResendTip *util.Hash
+
+// startRebroadcaster periodically resends the unconfirmed wallet transactions
+// until Stop is called.
+func startRebroadcaster() {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			timer := time.NewTimer(time.Duration(util.GetRand(maxResendInterval)+1) * time.Second)
+			select {
+			case <-quit:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			resendWalletTransactions()
+		}
+	}()
+}
+
+// resendWalletTransactions resends the unconfirmed transactions old enough
+// of the loaded wallets, if a block was connected since the last time.
+func resendWalletTransactions() {
+	persist.CsMain.Lock()
+	tip := chain.GetInstance().Tip()
+	persist.CsMain.Unlock()
+	if tip == nil || (lastResendTip != nil && *lastResendTip == *tip.GetBlockHash()) {
+		return
+	}
+	lastResendTip = tip.GetBlockHash()
+
+	for _, w := range wallet.GetWallets() {
+		if !w.GetBroadcastTx() {
+			continue
+		}
+		relayed := ResendWalletTransactionsBefore(w, int64(tip.GetBlockTime())-resendMinAge)
+		if len(relayed) > 0 {
+			log.Info("ResendWalletTransactions: wallet %q rebroadcast %d unconfirmed transactions",
+				w.GetName(), len(relayed))
+		}
+	}
+}
+
+// ResendWalletTransactionsBefore relays, oldest first, the unconfirmed wallet
+// transactions received up to receivedBefore and returns their hashes.
+func ResendWalletTransactionsBefore(w *wallet.Wallet, receivedBefore int64) []util.Hash {
+	walletTxns := w.GetWalletTxns()
+	sort.Slice(walletTxns, func(i, j int) bool {
+		return walletTxns[i].TimeReceived < walletTxns[j].TimeReceived
+	})
+
+	relayed := make([]util.Hash, 0)
+	for _, wtx := range walletTxns {
+		if wtx.TimeReceived > receivedBefore {
+			break
+		}
+		if RelayWalletTransaction(wtx) {
+			relayed = append(relayed, wtx.GetHash())
+		}
+	}
+	return relayed
+}
+
+// RelayWalletTransaction announces an unconfirmed wallet transaction to the
+// peers, submitting it to the mempool again if it left it.  It returns false
+// if the transaction cannot be relayed.
+func RelayWalletTransaction(wtx *wallet.WalletTx) bool {
+	if wtx.IsCoinBase() || wtx.IsAbandoned() || wtx.GetDepthInMainChain() != 0 {
+		return false
+	}
+
+	txHash := wtx.GetHash()
+	if !wtx.InMempool() {
+		persist.CsMain.Lock()
+		err := lmempool.AcceptTxToMemPool(wtx.Tx)
+		persist.CsMain.Unlock()
+		if err != nil {
+			log.Debug("RelayWalletTransaction: tx %s rejected by mempool: %v", txHash.String(), err)
+			return false
+		}
+	}
+
+	log.Info("Relaying wtx %s", txHash.String())
+	if _, err := server.ProcessForRPC(wtx.Tx); err != nil {
+		log.Error("RelayWalletTransaction process tx %s error:%s", txHash.String(), err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,338 @@
+package lwallet
+
+import (
+	"sync"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lchain"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/wallet"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/pkg/errors"
+)
+
+// rescanTimestampWindow is how much earlier than the creation time of a key
+// a rescan starts, since block timestamps may be up to two hours ahead.
+const rescanTimestampWindow = 2 * 60 * 60
+
+// rescanProgressInterval is how often a rescan logs its progress and, when
+// it scans on from the wallet best block, records how far it got.
+const rescanProgressInterval = 60 * time.Second
+
+var (
+	ErrRescanPruned     = errors.New("Rescan is disabled in pruned mode")
+	ErrRescanInProgress = errors.New("Wallet is currently rescanning. Abort existing rescan or wait.")
+	ErrRescanAborted    = errors.New("Rescan aborted")
+)
+
+var (
+	quit chan struct{}
+	wg   sync.WaitGroup
+
+	rescansLock sync.Mutex
+	rescans     = make(map[*wallet.Wallet]*rescanState)
+)
+
+// rescanState lets only one rescan of a wallet run at a time, and lets the
+// wallet be unloaded once its rescan stopped.
+type rescanState struct {
+	// running holds a token while a rescan runs.
+	running chan struct{}
+	abort   chan struct{}
+}
+
+func getRescanState(w *wallet.Wallet) *rescanState {
+	rescansLock.Lock()
+	defer rescansLock.Unlock()
+
+	state, ok := rescans[w]
+	if !ok {
+		state = &rescanState{
+			running: make(chan struct{}, 1),
+			abort:   make(chan struct{}),
+		}
+		rescans[w] = state
+	}
+	return state
+}
+
+// Init starts the rescan of the wallets loaded at startup and resending the
+// unconfirmed wallet transactions periodically.
+func Init() {
+	if !conf.Cfg.Wallet.Enable {
+		return
+	}
+	quit = make(chan struct{})
+	startRebroadcaster()
+
+	for _, w := range wallet.GetWallets() {
+		startRescan(w)
+	}
+}
+
+// startRescan starts scanning in the background the blocks the wallet has
+// not processed yet, from its best block up to the tip of the active chain,
+// so that a rescan interrupted by a shutdown or an unload resumes where it
+// stopped.  A new wallet has no history and starts from the tip.
+func startRescan(w *wallet.Wallet) {
+	startHeight, ok := walletSyncHeight(w)
+	if !ok {
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := ScanForWalletTransactions(w, startHeight, -1); err != nil {
+			log.Error("wallet %q rescan from height %d failed: %v", w.GetName(), startHeight, err)
+		}
+	}()
+}
+
+// CreateWallet creates a new wallet named name and loads it.
+func CreateWallet(name string) (*wallet.Wallet, error) {
+	w, err := wallet.CreateWallet(name)
+	if err != nil {
+		return nil, err
+	}
+	startRescan(w)
+	return w, nil
+}
+
+// LoadWallet loads the existing wallet named name and starts scanning the
+// blocks connected since it was unloaded.
+func LoadWallet(name string) (*wallet.Wallet, error) {
+	w, err := wallet.LoadWallet(name)
+	if err != nil {
+		return nil, err
+	}
+	startRescan(w)
+	return w, nil
+}
+
+// UnloadWallet aborts the rescan of a wallet, if any, waits for it to stop
+// and unloads the wallet.
+func UnloadWallet(w *wallet.Wallet) error {
+	rescansLock.Lock()
+	state, ok := rescans[w]
+	delete(rescans, w)
+	rescansLock.Unlock()
+
+	if ok {
+		close(state.abort)
+		state.running <- struct{}{}
+	}
+	return wallet.UnloadWallet(w.GetName())
+}
+
+// Stop interrupts the rescans running, if any, and waits for the background
+// tasks to exit.
+func Stop() {
+	if quit == nil {
+		return
+	}
+	close(quit)
+	wg.Wait()
+	quit = nil
+}
+
+// walletSyncHeight returns the height of the first block of the active chain
+// the wallet has not processed, and false if it processed them all.
+func walletSyncHeight(w *wallet.Wallet) (int32, bool) {
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	gChain := chain.GetInstance()
+	if gChain.Tip() == nil {
+		return 0, false
+	}
+	locator := w.GetBestBlock()
+	if locator == nil && w.IsFirstRun() {
+		if err := w.SetBestBlock(gChain.Tip()); err != nil {
+			log.Error("wallet save best block fail. error:%s", err.Error())
+		}
+		return 0, false
+	}
+
+	startHeight := int32(0)
+	if locator != nil {
+		startHeight = lchain.FindForkInGlobalIndex(gChain, locator).Height + 1
+	}
+	return startHeight, startHeight <= gChain.Height()
+}
+
+// walletBestHeight returns the height of the last block of the active chain
+// the wallet processed, -1 if none.
+func walletBestHeight(w *wallet.Wallet) int32 {
+	locator := w.GetBestBlock()
+	if locator == nil {
+		return -1
+	}
+	return lchain.FindForkInGlobalIndex(chain.GetInstance(), locator).Height
+}
+
+// walletRescan is the state of a scan of the blocks of the active chain for
+// the transactions related to the wallet.
+type walletRescan struct {
+	w          *wallet.Wallet
+	height     int32
+	stopHeight int32
+	last       *blockindex.BlockIndex
+	found      int
+
+	// advanceBestBlock is set when the scan starts at or before the block
+	// following the wallet best block, and so may move it forward.
+	advanceBestBlock bool
+
+	progressBegin float64
+	progressEnd   float64
+}
+
+// ScanForWalletTransactions adds the transactions related to the wallet of
+// the blocks of the active chain from startHeight to stopHeight, or to the
+// tip if stopHeight is negative.  It returns the height of the last block
+// scanned, -1 if none was.
+func ScanForWalletTransactions(w *wallet.Wallet, startHeight int32, stopHeight int32) (int32, error) {
+	if disk.GetPruneState().PruneMode {
+		return -1, ErrRescanPruned
+	}
+	state := getRescanState(w)
+	select {
+	case state.running <- struct{}{}:
+	default:
+		return -1, ErrRescanInProgress
+	}
+	defer func() { <-state.running }()
+
+	stop := quit
+	s := newWalletRescan(w, startHeight, stopHeight)
+	defer w.MarkDirty()
+
+	log.Info("wallet rescan started from height %d", startHeight)
+	lastProgress := time.Now()
+	for {
+		aborted := false
+		select {
+		case <-stop:
+			aborted = true
+		case <-state.abort:
+			aborted = true
+		default:
+		}
+		if aborted {
+			s.checkpoint()
+			log.Info("wallet rescan aborted at height %d. related txns:%d", s.lastHeight(), s.found)
+			return s.lastHeight(), ErrRescanAborted
+		}
+
+		done, err := s.scanNextBlock()
+		if err != nil {
+			s.checkpoint()
+			return s.lastHeight(), err
+		}
+		if done {
+			break
+		}
+
+		if time.Since(lastProgress) >= rescanProgressInterval {
+			log.Info("still rescanning wallet. at block %d. progress=%.6f", s.lastHeight(), s.progress())
+			s.checkpoint()
+			lastProgress = time.Now()
+		}
+	}
+
+	log.Info("wallet rescan from height %d to %d done. related txns:%d", startHeight, s.lastHeight(), s.found)
+	return s.lastHeight(), nil
+}
+
+func newWalletRescan(w *wallet.Wallet, startHeight int32, stopHeight int32) *walletRescan {
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	gChain := chain.GetInstance()
+	txData := gChain.GetParams().TxData()
+	stopIndex := gChain.Tip()
+	if stopHeight >= 0 && stopHeight < gChain.Height() {
+		stopIndex = gChain.GetIndex(stopHeight)
+	}
+	return &walletRescan{
+		w:                w,
+		height:           startHeight,
+		stopHeight:       stopHeight,
+		advanceBestBlock: startHeight <= walletBestHeight(w)+1,
+		progressBegin:    lchain.GuessVerificationProgress(txData, gChain.GetIndex(startHeight)),
+		progressEnd:      lchain.GuessVerificationProgress(txData, stopIndex),
+	}
+}
+
+// scanNextBlock adds the transactions related to the wallet of the next
+// block. It returns true once the stop height or the tip is reached.
+func (s *walletRescan) scanNextBlock() (bool, error) {
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	gChain := chain.GetInstance()
+	index := gChain.GetIndex(s.height)
+	if index == nil || (s.stopHeight >= 0 && s.height > s.stopHeight) {
+		// The best block is recorded with CsMain held, so that the blocks
+		// connected from now on move it forward.
+		s.saveProgress()
+		return true, nil
+	}
+
+	blk, ok := disk.ReadBlockFromDisk(index, gChain.GetParams())
+	if !ok {
+		return false, errors.Errorf("failed to read block %d from disk", index.Height)
+	}
+	s.found += s.w.AddRelatedTxns(blk.Txs, *index.GetBlockHash())
+	s.last = index
+	s.height++
+	return false, nil
+}
+
+func (s *walletRescan) checkpoint() {
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+	s.saveProgress()
+}
+
+// saveProgress records the last block scanned as the wallet best block if
+// the scan moves it forward.  It must be called with persist.CsMain held.
+func (s *walletRescan) saveProgress() {
+	if !s.advanceBestBlock || s.last == nil || s.last.Height <= walletBestHeight(s.w) {
+		return
+	}
+	if err := s.w.SetBestBlock(s.last); err != nil {
+		log.Error("wallet save best block fail. error:%s", err.Error())
+	}
+}
+
+func (s *walletRescan) lastHeight() int32 {
+	if s.last == nil {
+		return -1
+	}
+	return s.last.Height
+}
+
+func (s *walletRescan) progress() float64 {
+	if s.last == nil || s.progressEnd <= s.progressBegin {
+		return 0
+	}
+	txData := chain.GetInstance().GetParams().TxData()
+	current := lchain.GuessVerificationProgress(txData, s.last)
+	return (current - s.progressBegin) / (s.progressEnd - s.progressBegin)
+}
+
+// RescanFromTime rescans the blocks which may hold transactions of keys
+// created at or after createTime.
+func RescanFromTime(w *wallet.Wallet, createTime int64) error {
+	startIndex := chain.GetInstance().FindEarliestAtLeast(createTime - rescanTimestampWindow)
+	if startIndex == nil {
+		return nil
+	}
+	_, err := ScanForWalletTransactions(w, startIndex.Height, -1)
+	return err
+}
@@ -16,9 +16,14 @@ import (
 	"runtime/debug"
 
 	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/logic/ltxindex"
+	"github.com/copernet/copernicus/logic/lwallet"
 	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/mempool"
 	"github.com/copernet/copernicus/net/limits"
 	"github.com/copernet/copernicus/net/server"
+	"github.com/copernet/copernicus/net/zmq"
 	"github.com/copernet/copernicus/rpc"
 	"github.com/copernet/copernicus/util"
 	"net"
@@ -76,6 +81,11 @@ func bchMain(ctx context.Context, args []string) error {
 		if !conf.Cfg.P2PNet.DisableRPC {
 			rpcServer.Stop()
 		}
+		zmq.Stop()
+		ltxindex.Stop()
+		lwallet.Stop()
+		mempool.FlushFeeEstimates()
+		lmempool.FlushMempool()
 	}()
 	go func() {
 		<-rpcServer.RequestedProcessShutdown()
@@ -2,6 +2,7 @@ package model
 
 import (
 	"errors"
+	"fmt"
 	"github.com/copernet/copernicus/conf"
 	"math/big"
 	"time"
@@ -46,6 +47,15 @@ type DNSSeed struct {
 	HasFiltering bool
 }
 
+// ASERTAnchor is the block from which the aserti3-2d difficulty adjustment
+// computes the targets of a network.  Knowing it saves looking it up in the
+// chain, which keeps working when the chain is pruned.
+type ASERTAnchor struct {
+	Height        int32
+	Bits          uint32
+	PrevBlockTime int64
+}
+
 type BitcoinParams struct {
 	consensus.Param
 	Name                     string
@@ -64,6 +74,10 @@ type BitcoinParams struct {
 	Checkpoints              []*Checkpoint
 	MineBlocksOnDemands      bool
 
+	// The anchor block of the aserti3-2d difficulty adjustment, nil if it
+	// is looked up in the chain.
+	ASERTAnchorParams *ASERTAnchor
+
 	// Enforce current block version once network has
 	// upgraded.  This is part of BIP0034.
 	BlockEnforceNumRequired uint64
@@ -141,6 +155,25 @@ var MainNetParams = BitcoinParams{
 
 		// Wed, 15 May 2019 12:00:00 UTC hard fork
 		GreatWallActivationTime: 1557921600,
+
+		// Fri, 15 Nov 2019 12:00:00 UTC hard fork
+		GravitonActivationTime: 1573819200,
+
+		// Fri, 15 May 2020 12:00:00 UTC hard fork
+		PhononActivationTime: 1589544000,
+
+		// Sun, 15 Nov 2020 12:00:00 UTC hard fork
+		AxionActivationTime: 1605441600,
+		ASERTHalfLife:       2 * 24 * 60 * 60,
+
+		// Sun, 15 May 2022 12:00:00 UTC hard fork
+		Upgrade8ActivationTime: 1652616000,
+
+		// Mon, 15 May 2023 12:00:00 UTC hard fork
+		Upgrade9ActivationTime: 1684152000,
+
+		// Wed, 15 May 2024 12:00:00 UTC hard fork
+		ReplayProtectionActivationTime: 1715774400,
 	},
 
 	Name:        "main",
@@ -194,6 +227,11 @@ var MainNetParams = BitcoinParams{
 		{556767, util.HashFromString("0000000000000000004626ff6e3b936941d341c5932ece4357eeccac44e6d56c")},
 	},
 	MineBlocksOnDemands: false,
+	ASERTAnchorParams: &ASERTAnchor{
+		Height:        661647,
+		Bits:          0x1804dafe,
+		PrevBlockTime: 1605447844,
+	},
 	// Enforce current block version once majority of the network has
 	// upgraded.
 	// 75% (750 / 1000)
@@ -215,6 +253,8 @@ var MainNetParams = BitcoinParams{
 	// BIP44 coin type used in the hierarchical deterministic path for
 	// address generation.
 	HDCoinType: 0,
+
+	PruneAfterHeight: 100000,
 }
 
 var TestNetParams = BitcoinParams{
@@ -258,6 +298,25 @@ var TestNetParams = BitcoinParams{
 		MagneticAnomalyActivationTime: 1542300000,
 		// Wed, 15 May 2019 12:00:00 UTC hard fork
 		GreatWallActivationTime: 1557921600,
+
+		// Fri, 15 Nov 2019 12:00:00 UTC hard fork
+		GravitonActivationTime: 1573819200,
+
+		// Fri, 15 May 2020 12:00:00 UTC hard fork
+		PhononActivationTime: 1589544000,
+
+		// Sun, 15 Nov 2020 12:00:00 UTC hard fork
+		AxionActivationTime: 1605441600,
+		ASERTHalfLife:       60 * 60,
+
+		// Sun, 15 May 2022 12:00:00 UTC hard fork
+		Upgrade8ActivationTime: 1652616000,
+
+		// Mon, 15 May 2023 12:00:00 UTC hard fork
+		Upgrade9ActivationTime: 1684152000,
+
+		// Wed, 15 May 2024 12:00:00 UTC hard fork
+		ReplayProtectionActivationTime: 1715774400,
 		//CashHardForkActivationTime: 1510600000,
 		GenesisHash: &TestNetGenesisHash,
 		//CashaddrPrefix: "xbctest",
@@ -290,6 +349,11 @@ var TestNetParams = BitcoinParams{
 		{1188697, util.HashFromString("0000000000170ed0918077bde7b4d36cc4c91be69fa09211f748240dabe047fb")},
 	},
 	MineBlocksOnDemands: false,
+	ASERTAnchorParams: &ASERTAnchor{
+		Height:        1421481,
+		Bits:          0x1d00ffff,
+		PrevBlockTime: 1605445400,
+	},
 	// Enforce current block version once majority of the network has
 	// upgraded.
 	// 75% (750 / 1000)
@@ -311,6 +375,7 @@ var TestNetParams = BitcoinParams{
 	// address generation.
 	HDCoinType:          1,
 	MiningRequiresPeers: true,
+	PruneAfterHeight:    1000,
 
 	chainTxData: ChainTxData{time.Unix(1483546230, 0), 12834668, 0.15},
 }
@@ -356,6 +421,25 @@ var RegressionNetParams = BitcoinParams{
 
 		// Wed, 15 May 2019 12:00:00 UTC hard fork
 		GreatWallActivationTime: 1557921600,
+
+		// Fri, 15 Nov 2019 12:00:00 UTC hard fork
+		GravitonActivationTime: 1573819200,
+
+		// Fri, 15 May 2020 12:00:00 UTC hard fork
+		PhononActivationTime: 1589544000,
+
+		// Sun, 15 Nov 2020 12:00:00 UTC hard fork
+		AxionActivationTime: 1605441600,
+		ASERTHalfLife:       2 * 24 * 60 * 60,
+
+		// Sun, 15 May 2022 12:00:00 UTC hard fork
+		Upgrade8ActivationTime: 1652616000,
+
+		// Mon, 15 May 2023 12:00:00 UTC hard fork
+		Upgrade9ActivationTime: 1684152000,
+
+		// Wed, 15 May 2024 12:00:00 UTC hard fork
+		ReplayProtectionActivationTime: 1715774400,
 	},
 
 	Name:         "regtest",
@@ -394,6 +478,8 @@ var RegressionNetParams = BitcoinParams{
 	// BIP44 coin type used in the hierarchical deterministic path for
 	// address generation.
 	HDCoinType: 1,
+
+	PruneAfterHeight: 1000,
 }
 
 var (
@@ -449,28 +535,68 @@ func mustRegister(bp *BitcoinParams) {
 
 //IsUAHFEnabled Check is UAHF has activated.
 func IsUAHFEnabled(height int32) bool {
-	return height >= ActiveNetParams.UAHFHeight
+	return ActiveNetParams.IsUpgradeActivated(consensus.UpgradeUAHF, height, 0)
 }
 
 func IsDAAEnabled(height int32) bool {
-	return height >= ActiveNetParams.DAAHeight
+	return ActiveNetParams.IsUpgradeActivated(consensus.UpgradeDAA, height, 0)
 }
 
 func IsMagneticAnomalyEnabled(mediaTimePast int64) bool {
-	activeTime := ActiveNetParams.MagneticAnomalyActivationTime
-	if conf.Args.MagneticAnomalyTime > 0 {
-		activeTime = conf.Args.MagneticAnomalyTime
-	}
-	return mediaTimePast >= activeTime
+	return ActiveNetParams.IsUpgradeActivated(consensus.UpgradeMagneticAnomaly, 0, mediaTimePast)
+}
+
+func IsGreatWallEnabled(medianTimePast int64) bool {
+	return ActiveNetParams.IsUpgradeActivated(consensus.UpgradeGreatWall, 0, medianTimePast)
+}
+
+func IsGravitonEnabled(medianTimePast int64) bool {
+	return ActiveNetParams.IsUpgradeActivated(consensus.UpgradeGraviton, 0, medianTimePast)
+}
+
+func IsPhononEnabled(medianTimePast int64) bool {
+	return ActiveNetParams.IsUpgradeActivated(consensus.UpgradePhonon, 0, medianTimePast)
+}
+
+func IsAxionEnabled(medianTimePast int64) bool {
+	return ActiveNetParams.IsUpgradeActivated(consensus.UpgradeAxion, 0, medianTimePast)
+}
+
+func IsUpgrade8Enabled(medianTimePast int64) bool {
+	return ActiveNetParams.IsUpgradeActivated(consensus.Upgrade8, 0, medianTimePast)
+}
+
+func IsUpgrade9Enabled(medianTimePast int64) bool {
+	return ActiveNetParams.IsUpgradeActivated(consensus.Upgrade9, 0, medianTimePast)
 }
 
 func IsReplayProtectionEnabled(medianTimePast int64) bool {
-	time := ActiveNetParams.GreatWallActivationTime
+	return ActiveNetParams.IsUpgradeActivated(consensus.UpgradeReplayProtection, 0, medianTimePast)
+}
+
+// InitUpgradeActivations overrides the activations of upgrades on the active
+// network with those given by name in overrides, which is only allowed on
+// regtest, and with the legacy -magneticanomalyactivationtime and
+// -replayprotectionactivationtime options.
+func InitUpgradeActivations(overrides map[string]int64) error {
+	if conf.Args.MagneticAnomalyTime > 0 {
+		ActiveNetParams.SetUpgradeActivation(consensus.UpgradeMagneticAnomaly, conf.Args.MagneticAnomalyTime)
+	}
 	if conf.Args.ReplayProtectionActivationTime > 0 {
-		time = conf.Args.ReplayProtectionActivationTime
+		ActiveNetParams.SetUpgradeActivation(consensus.UpgradeReplayProtection, conf.Args.ReplayProtectionActivationTime)
 	}
 
-	return medianTimePast >= time
+	if len(overrides) > 0 && ActiveNetParams.BitcoinNet != wire.RegTestNet {
+		return errors.New("upgrade activations can only be overridden on regtest")
+	}
+	for name, activation := range overrides {
+		upgrade, ok := consensus.GetUpgradeByName(name)
+		if !ok {
+			return fmt.Errorf("unknown upgrade %s", name)
+		}
+		ActiveNetParams.SetUpgradeActivation(upgrade.ID, activation)
+	}
+	return nil
 }
 
 func SetTestNetParams() {
@@ -489,6 +615,7 @@ func setActiveNetAddressParams() {
 		ScriptHashAddressVer: ActiveNetParams.ScriptHashAddressID,
 	})
 	crypto.InitPrivateKeyVersion(ActiveNetParams.PrivatekeyID)
+	crypto.InitHDKeyVersions(ActiveNetParams.HDPrivateKeyID, ActiveNetParams.HDPublicKeyID)
 }
 
 func GetBlockSubsidy(height int32, params *BitcoinParams) amount.Amount {
@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model/consensus"
 	"github.com/copernet/copernicus/model/script"
 	"github.com/stretchr/testify/assert"
 	"os"
@@ -114,6 +115,24 @@ func TestIsMagneticAnomalyEnabled(t *testing.T) {
 		ActiveNetParams.MagneticAnomalyActivationTime))
 }
 
+func TestIsGreatWallEnabled(t *testing.T) {
+	for _, params := range []*BitcoinParams{&MainNetParams, &TestNetParams, &RegressionNetParams} {
+		ActiveNetParams = params
+		assert.False(t, IsGreatWallEnabled(ActiveNetParams.MagneticAnomalyActivationTime))
+		assert.False(t, IsGreatWallEnabled(ActiveNetParams.GreatWallActivationTime-1))
+		assert.True(t, IsGreatWallEnabled(ActiveNetParams.GreatWallActivationTime))
+	}
+}
+
+func TestIsGravitonEnabled(t *testing.T) {
+	for _, params := range []*BitcoinParams{&MainNetParams, &TestNetParams, &RegressionNetParams} {
+		ActiveNetParams = params
+		assert.False(t, IsGravitonEnabled(ActiveNetParams.GreatWallActivationTime))
+		assert.False(t, IsGravitonEnabled(ActiveNetParams.GravitonActivationTime-1))
+		assert.True(t, IsGravitonEnabled(ActiveNetParams.GravitonActivationTime))
+	}
+}
+
 func TestIsDAAEnabled(t *testing.T) {
 	ActiveNetParams = &MainNetParams
 
@@ -137,6 +156,25 @@ func TestIsReplayProtectionEnabled(t *testing.T) {
 
 	isEnable = IsReplayProtectionEnabled(MainNetParams.MagneticAnomalyActivationTime)
 	assert.False(t, isEnable)
+
+	isEnable = IsReplayProtectionEnabled(MainNetParams.Upgrade9ActivationTime)
+	assert.False(t, isEnable)
+
+	isEnable = IsReplayProtectionEnabled(MainNetParams.ReplayProtectionActivationTime)
+	assert.True(t, isEnable)
+}
+
+func TestReplayProtectionAfterUpgrades(t *testing.T) {
+	for _, params := range []*BitcoinParams{&MainNetParams, &TestNetParams, &RegressionNetParams} {
+		replayProtection := params.GetUpgradeActivation(consensus.UpgradeReplayProtection)
+		for _, upgrade := range consensus.Upgrades {
+			if upgrade.ByHeight || upgrade.ID == consensus.UpgradeReplayProtection {
+				continue
+			}
+			assert.True(t, params.GetUpgradeActivation(upgrade.ID) < replayProtection,
+				"%s: replay protection activates before %s", params.Name, upgrade.Name)
+		}
+	}
 }
 
 func TestGetBlockSubsidy(t *testing.T) {
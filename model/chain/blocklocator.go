@@ -1,9 +1,16 @@
 package chain
 
 import (
+	"io"
+
 	"github.com/copernet/copernicus/util"
+	"github.com/pkg/errors"
 )
 
+// maxLocatorSize bounds the number of hashes of an unserialized locator. A
+// locator of the active chain has about ten plus log2(height) hashes.
+const maxLocatorSize = 101
+
 type BlockLocator struct {
 	blockHashList []util.Hash
 }
@@ -25,3 +32,32 @@ func (blt *BlockLocator) IsNull() bool {
 func (blt *BlockLocator) GetBlockHashList() []util.Hash {
 	return blt.blockHashList
 }
+
+func (blt *BlockLocator) Serialize(w io.Writer) error {
+	if err := util.WriteVarInt(w, uint64(len(blt.blockHashList))); err != nil {
+		return err
+	}
+	for i := range blt.blockHashList {
+		if _, err := blt.blockHashList[i].Serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (blt *BlockLocator) Unserialize(r io.Reader) error {
+	count, err := util.ReadVarInt(r)
+	if err != nil {
+		return err
+	}
+	if count > maxLocatorSize {
+		return errors.Errorf("too many block locator hashes: %d", count)
+	}
+	blt.blockHashList = make([]util.Hash, count)
+	for i := range blt.blockHashList {
+		if _, err := blt.blockHashList[i].Unserialize(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,10 +1,12 @@
 package chain
 
 import (
+	"bytes"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/blockindex"
 	"github.com/copernet/copernicus/util"
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -73,3 +75,26 @@ func TestChain_GetLocator(t *testing.T) {
 	}
 
 }
+
+func TestBlockLocator_Serialize(t *testing.T) {
+	hashes := []util.Hash{util.HashZero, *util.HashFromString("000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f")}
+	locator := NewBlockLocator(hashes)
+
+	buf := new(bytes.Buffer)
+	if err := locator.Serialize(buf); err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	got := &BlockLocator{}
+	if err := got.Unserialize(buf); err != nil {
+		t.Fatalf("Unserialize failed: %v", err)
+	}
+	if !reflect.DeepEqual(hashes, got.GetBlockHashList()) {
+		t.Errorf("Unserialize got %v, want %v", got.GetBlockHashList(), hashes)
+	}
+
+	buf.Reset()
+	util.WriteVarInt(buf, maxLocatorSize+1)
+	if err := got.Unserialize(buf); err == nil {
+		t.Errorf("Unserialize of an oversized locator should fail")
+	}
+}
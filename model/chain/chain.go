@@ -38,8 +38,9 @@ type Chain struct {
 
 	// The notifications field stores a slice of callbacks to be executed on
 	// certain blockchain events.
-	notificationsLock sync.RWMutex
-	notifications     []NotificationCallback
+	notificationsLock  sync.RWMutex
+	notifications      []subscription
+	lastSubscriptionID int
 
 	// the current most chainwork index of blockheader
 	pindexBestHeader     *blockindex.BlockIndex
@@ -63,6 +64,7 @@ func InitGlobalChain(btd *blkdb.BlockTreeDB) bool {
 	if globalChain == nil {
 		globalChain = NewChain()
 	}
+	disk.SetPruneChain(globalChain)
 	if len(conf.Cfg.Chain.AssumeValid) > 0 {
 		assumeValid := strings.TrimPrefix(conf.Cfg.Chain.AssumeValid, "0x")
 		hash, err := util.GetHashFromStr(assumeValid)
@@ -341,40 +343,9 @@ func (c *Chain) GetBlockScriptFlags(pindex *blockindex.BlockIndex) uint32 {
 	//	flags |= script.ScriptVerifyCheckSequenceVerify
 	//}
 
-	// If the UAHF is enabled, we start accepting replay protected txns
-	if model.IsUAHFEnabled(pindex.Height) {
-		flags |= script.ScriptVerifyStrictEnc
-		flags |= script.ScriptEnableSigHashForkID
-	}
-
-	// If the Cash HF is enabled, we start rejecting transaction that use a high
-	// s in their signature. We also make sure that signature that are supposed
-	// to fail (for instance in multisig or other forms of smart contracts) are
-	// null.
-	//if pindex.IsCashHFEnabled(param) {
-	//	flags |= script.ScriptVerifyLowS
-	//	flags |= script.ScriptVerifyNullFail
-	//}
-	if model.IsDAAEnabled(pindex.Height) {
-		flags |= script.ScriptVerifyLowS
-		flags |= script.ScriptVerifyNullFail
-	}
-
-	// When the magnetic anomaly fork is enabled, we start accepting
-	// transactions using the OP_CHECKDATASIG opcode and it's verify
-	// alternative. We also start enforcing push only signatures and
-	// clean stack.
-	if model.IsMagneticAnomalyEnabled(pindex.GetMedianTimePast()) {
-		flags |= script.ScriptEnableCheckDataSig
-		flags |= script.ScriptVerifySigPushOnly
-		flags |= script.ScriptVerifyCleanStack
-	}
-
-	// We make sure this node will have replay protection during the next hard
-	// fork.
-	if model.IsReplayProtectionEnabled(pindex.GetMedianTimePast()) {
-		flags |= script.ScriptEnableReplayProtection
-	}
+	// Then the flags of the upgrades of the upgrade registry, the UAHF and
+	// cw-144 ones activating at the height of pindex.
+	flags |= model.ActiveNetParams.GetUpgradeScriptFlags(pindex.Height, pindex.GetMedianTimePast())
 
 	return flags
 }
@@ -737,6 +708,27 @@ func (c *Chain) IndexMapSize() int {
 	return len(c.indexMap)
 }
 
+// FindEarliestAtLeast returns the earliest block of the active chain whose
+// maximum timestamp, that is its own or any of its ancestors', is at least
+// time, nil if there is none.
+func (c *Chain) FindEarliestAtLeast(time int64) *blockindex.BlockIndex {
+	i := sort.Search(len(c.active), func(i int) bool {
+		return int64(c.active[i].GetBlockTimeMax()) >= time
+	})
+	if i == len(c.active) {
+		return nil
+	}
+	return c.active[i]
+}
+
+// ForEachBlockIndex calls fn for every block index known to the chain, in no
+// particular order.
+func (c *Chain) ForEachBlockIndex(fn func(*blockindex.BlockIndex)) {
+	for _, bi := range c.indexMap {
+		fn(bi)
+	}
+}
+
 //BuildForwardTree Build forward-pointing map of the entire block tree.
 func (c *Chain) BuildForwardTree() (forward map[*blockindex.BlockIndex][]*blockindex.BlockIndex) {
 	forward = make(map[*blockindex.BlockIndex][]*blockindex.BlockIndex)
@@ -141,6 +141,45 @@ func TestChain_Simple(t *testing.T) {
 	}
 }
 
+func TestChain_FindEarliestAtLeast(t *testing.T) {
+	testDir, err := initTestEnv(t, []string{""})
+	if err != nil {
+		t.Errorf("initTestEnv Error")
+	}
+	defer os.RemoveAll(testDir)
+	defer cleanTestEnv()
+
+	tChain := GetInstance()
+	tChain.indexMap = make(map[util.Hash]*blockindex.BlockIndex)
+	tChain.active = make([]*blockindex.BlockIndex, 0)
+	tChain.branch = make([]*blockindex.BlockIndex, 0)
+	initBits := model.ActiveNetParams.PowLimitBits
+	timePerBlock := int64(model.ActiveNetParams.TargetTimePerBlock)
+
+	bIndex := make([]*blockindex.BlockIndex, 10)
+	bIndex[0] = blockindex.NewBlockIndex(&model.ActiveNetParams.GenesisBlock.Header)
+	bIndex[0].TimeMax = bIndex[0].Header.Time
+	for height := 1; height < 10; height++ {
+		bIndex[height] = getBlockIndexSimple(bIndex[height-1], timePerBlock, initBits)
+		bIndex[height].TimeMax = bIndex[height].Header.Time
+	}
+	tChain.SetTip(bIndex[9])
+
+	genesisTime := int64(bIndex[0].Header.Time)
+	if tChain.FindEarliestAtLeast(0) != bIndex[0] {
+		t.Errorf("FindEarliestAtLeast should return genesis for time 0")
+	}
+	if tChain.FindEarliestAtLeast(genesisTime+timePerBlock*3) != bIndex[3] {
+		t.Errorf("FindEarliestAtLeast should return the block with the exact time")
+	}
+	if tChain.FindEarliestAtLeast(genesisTime+timePerBlock*3+1) != bIndex[4] {
+		t.Errorf("FindEarliestAtLeast should return the next block")
+	}
+	if tChain.FindEarliestAtLeast(genesisTime+timePerBlock*10) != nil {
+		t.Errorf("FindEarliestAtLeast should return nil after the tip")
+	}
+}
+
 func TestChain_Fork(t *testing.T) {
 	testDir, err := initTestEnv(t, []string{""})
 	if err != nil {
@@ -340,6 +379,75 @@ func TestChain_GetBlockScriptFlags(t *testing.T) {
 	if flag := testChain.GetBlockScriptFlags(blockIdx[19]); flag != uint32(expect) {
 		t.Errorf("GetBlockScriptFlags wrong, flag: %d, expect: %d", flag, expect)
 	}
+
+	blockIdx = make([]*blockindex.BlockIndex, 100)
+	blockheader = block.NewBlockHeader()
+	blockheader.Time = uint32(model.ActiveNetParams.GreatWallActivationTime)
+	blockIdx[0] = blockindex.NewBlockIndex(blockheader)
+	blockIdx[0].Height = model.ActiveNetParams.DAAHeight
+	for i := 1; i < 20; i++ {
+		blockIdx[i] = getBlockIndexSimple(blockIdx[i-1], timePerBlock, initBits)
+	}
+	flag := testChain.GetBlockScriptFlags(blockIdx[19])
+	if flag&script.ScriptEnableSchnorr == 0 || flag&script.ScriptEnableSchnorrMultisig != 0 {
+		t.Errorf("GetBlockScriptFlags wrong after great wall, flag: %d", flag)
+	}
+
+	blockIdx = make([]*blockindex.BlockIndex, 100)
+	blockheader = block.NewBlockHeader()
+	blockheader.Time = uint32(model.ActiveNetParams.GravitonActivationTime)
+	blockIdx[0] = blockindex.NewBlockIndex(blockheader)
+	blockIdx[0].Height = model.ActiveNetParams.DAAHeight
+	for i := 1; i < 20; i++ {
+		blockIdx[i] = getBlockIndexSimple(blockIdx[i-1], timePerBlock, initBits)
+	}
+	flag = testChain.GetBlockScriptFlags(blockIdx[19])
+	if flag&script.ScriptEnableSchnorr == 0 || flag&script.ScriptEnableSchnorrMultisig == 0 ||
+		flag&script.ScriptEnableReplayProtection != 0 || flag&script.ScriptVerifyInputSigChecks != 0 {
+		t.Errorf("GetBlockScriptFlags wrong after graviton, flag: %d", flag)
+	}
+
+	blockIdx = make([]*blockindex.BlockIndex, 100)
+	blockheader = block.NewBlockHeader()
+	blockheader.Time = uint32(model.ActiveNetParams.PhononActivationTime)
+	blockIdx[0] = blockindex.NewBlockIndex(blockheader)
+	blockIdx[0].Height = model.ActiveNetParams.DAAHeight
+	for i := 1; i < 20; i++ {
+		blockIdx[i] = getBlockIndexSimple(blockIdx[i-1], timePerBlock, initBits)
+	}
+	flag = testChain.GetBlockScriptFlags(blockIdx[19])
+	if flag&script.ScriptEnableSchnorrMultisig == 0 || flag&script.ScriptVerifyInputSigChecks == 0 ||
+		flag&script.ScriptEnable64BitIntegers != 0 {
+		t.Errorf("GetBlockScriptFlags wrong after phonon, flag: %d", flag)
+	}
+
+	blockIdx = make([]*blockindex.BlockIndex, 100)
+	blockheader = block.NewBlockHeader()
+	blockheader.Time = uint32(model.ActiveNetParams.Upgrade8ActivationTime)
+	blockIdx[0] = blockindex.NewBlockIndex(blockheader)
+	blockIdx[0].Height = model.ActiveNetParams.DAAHeight
+	for i := 1; i < 20; i++ {
+		blockIdx[i] = getBlockIndexSimple(blockIdx[i-1], timePerBlock, initBits)
+	}
+	flag = testChain.GetBlockScriptFlags(blockIdx[19])
+	if flag&script.ScriptVerifyInputSigChecks == 0 || flag&script.ScriptEnable64BitIntegers == 0 ||
+		flag&script.ScriptEnableNativeIntrospection == 0 || flag&script.ScriptEnableTokens != 0 {
+		t.Errorf("GetBlockScriptFlags wrong after the May 2022 upgrade, flag: %d", flag)
+	}
+
+	blockIdx = make([]*blockindex.BlockIndex, 100)
+	blockheader = block.NewBlockHeader()
+	blockheader.Time = uint32(model.ActiveNetParams.Upgrade9ActivationTime)
+	blockIdx[0] = blockindex.NewBlockIndex(blockheader)
+	blockIdx[0].Height = model.ActiveNetParams.DAAHeight
+	for i := 1; i < 20; i++ {
+		blockIdx[i] = getBlockIndexSimple(blockIdx[i-1], timePerBlock, initBits)
+	}
+	flag = testChain.GetBlockScriptFlags(blockIdx[19])
+	if flag&script.ScriptEnableNativeIntrospection == 0 || flag&script.ScriptEnableTokens == 0 ||
+		flag&script.ScriptEnableP2SH32 == 0 || flag&script.ScriptEnableReplayProtection != 0 {
+		t.Errorf("GetBlockScriptFlags wrong after the May 2023 upgrade, flag: %d", flag)
+	}
 }
 
 func TestBuildForwardTree(t *testing.T) {
@@ -884,3 +992,18 @@ func TestCheckIndexAgainstCheckpoint_HaveCheckpoint(t *testing.T) {
 		t.Errorf("idx[8] should pass")
 	}
 }
+
+func TestUnsubscribe(t *testing.T) {
+	c := NewChain()
+	var first, second int
+	firstID := c.Subscribe(func(*Notification) { first++ })
+	c.Subscribe(func(*Notification) { second++ })
+
+	c.SendNotification(NTBlockConnected, nil)
+	c.Unsubscribe(firstID)
+	c.SendNotification(NTBlockConnected, nil)
+
+	if first != 1 || second != 2 {
+		t.Errorf("unsubscribed callback called, first:%d second:%d", first, second)
+	}
+}
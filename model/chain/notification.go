@@ -64,13 +64,38 @@ type Notification struct {
 	Data interface{}
 }
 
+// subscription is a callback registered by Subscribe, along with the id
+// which Unsubscribe takes to remove it.
+type subscription struct {
+	id       int
+	callback NotificationCallback
+}
+
 // Subscribe to block chain notifications. Registers a callback to be executed
 // when various events take place. See the documentation on Notification and
 // NotificationType for details on the types and contents of notifications.
-func (c *Chain) Subscribe(callback NotificationCallback) {
+// It returns an id which can be passed to Unsubscribe.
+func (c *Chain) Subscribe(callback NotificationCallback) int {
+	c.notificationsLock.Lock()
+	defer c.notificationsLock.Unlock()
+
+	c.lastSubscriptionID++
+	c.notifications = append(c.notifications, subscription{c.lastSubscriptionID, callback})
+	return c.lastSubscriptionID
+}
+
+// Unsubscribe removes the callback registered by Subscribe under id.  Once it
+// returns, the callback is not running and will not be called again.
+func (c *Chain) Unsubscribe(id int) {
 	c.notificationsLock.Lock()
-	c.notifications = append(c.notifications, callback)
-	c.notificationsLock.Unlock()
+	defer c.notificationsLock.Unlock()
+
+	for i, sub := range c.notifications {
+		if sub.id == id {
+			c.notifications = append(c.notifications[:i], c.notifications[i+1:]...)
+			return
+		}
+	}
 }
 
 // SendNotification sends a notification with the passed type and data if the
@@ -82,7 +107,7 @@ func (c *Chain) SendNotification(typ NotificationType, data interface{}) {
 	c.notificationsLock.RLock()
 	defer c.notificationsLock.RUnlock()
 
-	for _, callback := range c.notifications {
-		callback(&n)
+	for _, sub := range c.notifications {
+		sub.callback(&n)
 	}
 }
@@ -21,6 +21,14 @@ const (
 	/*MaxTxSigOpsCount allowed number of signature check operations per transaction. */
 	MaxTxSigOpsCount = 20000
 
+	// MaxTxSigChecksCount The maximum allowed number of sigchecks per transaction, after the
+	// phonon upgrade (network rule)
+	MaxTxSigChecksCount = 3000
+
+	// BlockMaxBytesMaxSigChecksRatio The ratio between the maximum allowed block size and the
+	// maximum allowed number of sigchecks in a block (network rule)
+	BlockMaxBytesMaxSigChecksRatio = 141
+
 	// CoinbaseMaturity means Coinbase transaction outputs can only be spent after this number of new
 	// blocks (network rule)
 	CoinbaseMaturity = 100
@@ -48,3 +56,10 @@ func GetMaxBlockSigOpsCount(blockSize uint64) (uint64, error) {
 	roundedUp := 1 + ((blockSize - 1) / OneMegaByte)
 	return roundedUp * MaxBlockSigopsPerMb, nil
 }
+
+// GetMaxBlockSigChecksCount Compute the maximum number of sigchecks that can be contained in a
+// block given the maximum block size as parameter. The ratio between the maximum block size and
+// the maximum sigchecks count is fixed, whatever the actual size of the block.
+func GetMaxBlockSigChecksCount(maxBlockSize uint64) uint64 {
+	return maxBlockSize / BlockMaxBytesMaxSigChecksRatio
+}
@@ -32,6 +32,26 @@ func TestGetMaxBlockSigOpsCount(t *testing.T) {
 
 }
 
+func TestGetMaxBlockSigChecksCount(t *testing.T) {
+	tests := []struct {
+		in  uint64
+		exp uint64
+	}{
+		{0, 0},
+		{140, 0},
+		{141, 1},
+		{OneMegaByte, 7092},
+		{DefaultMaxBlockSize, 226950},
+	}
+
+	for _, test := range tests {
+		actual := GetMaxBlockSigChecksCount(test.in)
+		if actual != test.exp {
+			t.Errorf("Test GetMaxBlockSigChecksCount err! Expected %d, Actual is %d", test.exp, actual)
+		}
+	}
+}
+
 func TestParam_DifficultyAdjustmentInterval(t *testing.T) {
 	param := Param{
 		TargetTimePerBlock: 60 * 10,
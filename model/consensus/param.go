@@ -55,6 +55,23 @@ type Param struct {
 	MagneticAnomalyActivationTime int64
 	// Unix time used for MTP activation of 15 May 2019 12:00:00 UTC upgrade */
 	GreatWallActivationTime int64
+	// Unix time used for MTP activation of 15 Nov 2019 12:00:00 UTC upgrade
+	GravitonActivationTime int64
+	// Unix time used for MTP activation of 15 May 2020 12:00:00 UTC upgrade,
+	// which replaces the sigops limits with sigchecks
+	PhononActivationTime int64
+	// Unix time used for MTP activation of 15 Nov 2020 12:00:00 UTC upgrade,
+	// which replaces the cw-144 difficulty adjustment with aserti3-2d
+	AxionActivationTime int64
+	// Unix time used for MTP activation of 15 May 2022 12:00:00 UTC upgrade,
+	// which enables 64-bit script integers and native introspection
+	Upgrade8ActivationTime int64
+	// Unix time used for MTP activation of 15 May 2023 12:00:00 UTC upgrade,
+	// which enables CashTokens and P2SH32
+	Upgrade9ActivationTime int64
+	// Unix time used for MTP activation of replay protection, which must be
+	// later than the activation of the last upgrade this node knows about
+	ReplayProtectionActivationTime int64
 
 	// Minimum blocks including miner confirmation of the total of 2016 blocks
 	// in a retargeting period, (nPowTargetTimespan / nPowTargetSpacing) which
@@ -73,6 +90,9 @@ type Param struct {
 	FPowNoRetargeting            bool
 	TargetTimePerBlock           time.Duration
 	TargetTimespan               time.Duration
+	// Every ASERTHalfLife seconds the chain gets behind schedule, the
+	// aserti3-2d difficulty adjustment halves the difficulty
+	ASERTHalfLife int64
 
 	// The best chain should have at least this much work.
 	MinimumChainWork util.Hash
@@ -82,6 +102,9 @@ type Param struct {
 
 	//AntiReplayOpReturnSunsetHeight int32
 	//AntiReplayOpReturnCommitment   []byte
+
+	// Activation heights or times of upgrades overriding their defaults
+	upgradeActivations map[UpgradeID]int64
 }
 
 func (pm *Param) DifficultyAdjustmentInterval() int64 {
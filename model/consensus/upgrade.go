@@ -0,0 +1,173 @@
+package consensus
+
+import (
+	"github.com/copernet/copernicus/model/script"
+)
+
+// UpgradeID identifies a network upgrade of the upgrade registry.
+type UpgradeID int
+
+const (
+	// UpgradeUAHF is the Aug 2017 fork which created Bitcoin Cash.
+	UpgradeUAHF UpgradeID = iota
+	// UpgradeDAA is the Nov 2017 upgrade to the cw-144 difficulty adjustment.
+	UpgradeDAA
+	UpgradeMagneticAnomaly
+	UpgradeGreatWall
+	UpgradeGraviton
+	UpgradePhonon
+	UpgradeAxion
+	Upgrade8
+	Upgrade9
+	// UpgradeReplayProtection makes this node incompatible with nodes not
+	// aware of the next upgrade.
+	UpgradeReplayProtection
+	// MaxUpgrades NOTE: Also add new upgrades to Upgrades
+	MaxUpgrades
+)
+
+// Rule is a set of consensus rules, other than script flags, enabled by a
+// network upgrade.
+type Rule uint32
+
+const (
+	RuleNone Rule = 0
+
+	// RuleCW144 replaces the emergency difficulty adjustment with cw-144.
+	RuleCW144 Rule = 1 << iota
+	// RuleCanonicalTxOrder requires the transactions of a block to be sorted
+	// by txid and transactions to be at least MinTxSize bytes.
+	RuleCanonicalTxOrder
+	// RuleSigChecks replaces the sigops limits with the sigchecks limits.
+	RuleSigChecks
+	// RuleASERT replaces cw-144 with the aserti3-2d difficulty adjustment.
+	RuleASERT
+	// RuleTokens allows outputs to carry tokens.
+	RuleTokens
+)
+
+// Upgrade is an entry of the upgrade registry: a network upgrade, the script
+// flags and the rules it enables.
+type Upgrade struct {
+	ID   UpgradeID
+	Name string
+	// ByHeight is whether the upgrade activates at a block height rather
+	// than at a median time past.
+	ByHeight    bool
+	ScriptFlags uint32
+	Rules       Rule
+	// activation returns the default activation height or time of the
+	// upgrade on a network.
+	activation func(p *Param) int64
+}
+
+// Upgrades is the upgrade registry, in activation order.
+var Upgrades = [MaxUpgrades]Upgrade{
+	// The UAHF starts accepting replay protected txns.
+	{UpgradeUAHF, "uahf", true,
+		script.ScriptVerifyStrictEnc | script.ScriptEnableSigHashForkID, RuleNone,
+		func(p *Param) int64 { return int64(p.UAHFHeight) }},
+	// The Nov 2017 upgrade starts rejecting high s signatures and non null
+	// failing signatures.
+	{UpgradeDAA, "daa", true,
+		script.ScriptVerifyLowS | script.ScriptVerifyNullFail, RuleCW144,
+		func(p *Param) int64 { return int64(p.DAAHeight) }},
+	// Magnetic anomaly starts accepting OP_CHECKDATASIG(VERIFY) and enforcing
+	// push only scriptSigs and clean stack.
+	{UpgradeMagneticAnomaly, "magneticanomaly", false,
+		script.ScriptEnableCheckDataSig | script.ScriptVerifySigPushOnly | script.ScriptVerifyCleanStack,
+		RuleCanonicalTxOrder,
+		func(p *Param) int64 { return p.MagneticAnomalyActivationTime }},
+	// Great wall accepts Schnorr signatures in OP_CHECK(DATA)SIG(VERIFY).
+	{UpgradeGreatWall, "greatwall", false,
+		script.ScriptEnableSchnorr, RuleNone,
+		func(p *Param) int64 { return p.GreatWallActivationTime }},
+	// Graviton accepts Schnorr signatures in the bitfield mode of
+	// OP_CHECKMULTISIG(VERIFY).
+	{UpgradeGraviton, "graviton", false,
+		script.ScriptEnableSchnorrMultisig, RuleNone,
+		func(p *Param) int64 { return p.GravitonActivationTime }},
+	// Phonon limits the sigchecks density of every input by the length of
+	// its scriptSig.
+	{UpgradePhonon, "phonon", false,
+		script.ScriptVerifyInputSigChecks, RuleSigChecks,
+		func(p *Param) int64 { return p.PhononActivationTime }},
+	{UpgradeAxion, "axion", false,
+		script.ScriptVerifyNone, RuleASERT,
+		func(p *Param) int64 { return p.AxionActivationTime }},
+	// The May 2022 upgrade makes script integers 64-bit and accepts the
+	// native introspection opcodes.
+	{Upgrade8, "upgrade8", false,
+		script.ScriptEnable64BitIntegers | script.ScriptEnableNativeIntrospection, RuleNone,
+		func(p *Param) int64 { return p.Upgrade8ActivationTime }},
+	// The May 2023 upgrade lets outputs carry tokens and pay to 32-byte
+	// script hashes.
+	{Upgrade9, "upgrade9", false,
+		script.ScriptEnableTokens | script.ScriptEnableP2SH32, RuleTokens,
+		func(p *Param) int64 { return p.Upgrade9ActivationTime }},
+	// We make sure this node will have replay protection during the next
+	// hard fork.
+	{UpgradeReplayProtection, "replayprotection", false,
+		script.ScriptEnableReplayProtection, RuleNone,
+		func(p *Param) int64 { return p.ReplayProtectionActivationTime }},
+}
+
+// GetUpgradeByName returns the upgrade of the registry named name.
+func GetUpgradeByName(name string) (*Upgrade, bool) {
+	for i := range Upgrades {
+		if Upgrades[i].Name == name {
+			return &Upgrades[i], true
+		}
+	}
+	return nil, false
+}
+
+// GetUpgradeActivation returns the activation height or time of the upgrade on
+// the network, overridden or not.
+func (pm *Param) GetUpgradeActivation(id UpgradeID) int64 {
+	if activation, ok := pm.upgradeActivations[id]; ok {
+		return activation
+	}
+	return Upgrades[id].activation(pm)
+}
+
+// SetUpgradeActivation overrides the activation height or time of the upgrade
+// on the network.
+func (pm *Param) SetUpgradeActivation(id UpgradeID, activation int64) {
+	if pm.upgradeActivations == nil {
+		pm.upgradeActivations = make(map[UpgradeID]int64)
+	}
+	pm.upgradeActivations[id] = activation
+}
+
+// IsUpgradeActivated returns whether the upgrade is active at a block of
+// height and medianTimePast.
+func (pm *Param) IsUpgradeActivated(id UpgradeID, height int32, medianTimePast int64) bool {
+	if Upgrades[id].ByHeight {
+		return int64(height) >= pm.GetUpgradeActivation(id)
+	}
+	return medianTimePast >= pm.GetUpgradeActivation(id)
+}
+
+// GetUpgradeScriptFlags returns the script flags enabled by the upgrades
+// active at a block of height and medianTimePast.
+func (pm *Param) GetUpgradeScriptFlags(height int32, medianTimePast int64) uint32 {
+	var flags uint32
+	for i := range Upgrades {
+		if pm.IsUpgradeActivated(Upgrades[i].ID, height, medianTimePast) {
+			flags |= Upgrades[i].ScriptFlags
+		}
+	}
+	return flags
+}
+
+// IsRuleEnabled returns whether one of the upgrades active at a block of
+// height and medianTimePast enables rule.
+func (pm *Param) IsRuleEnabled(rule Rule, height int32, medianTimePast int64) bool {
+	for i := range Upgrades {
+		if Upgrades[i].Rules&rule != 0 && pm.IsUpgradeActivated(Upgrades[i].ID, height, medianTimePast) {
+			return true
+		}
+	}
+	return false
+}
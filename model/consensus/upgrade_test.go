@@ -0,0 +1,127 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/model/script"
+)
+
+func TestUpgrades(t *testing.T) {
+	for i, upgrade := range Upgrades {
+		if upgrade.ID != UpgradeID(i) {
+			t.Errorf("Test Upgrades err! Upgrade %s has ID %d at index %d", upgrade.Name, upgrade.ID, i)
+		}
+		found, ok := GetUpgradeByName(upgrade.Name)
+		if !ok || found.ID != upgrade.ID {
+			t.Errorf("Test GetUpgradeByName err! Upgrade %s not found", upgrade.Name)
+		}
+	}
+	if _, ok := GetUpgradeByName("unknown"); ok {
+		t.Errorf("Test GetUpgradeByName err! Unknown upgrade found")
+	}
+}
+
+func TestParam_IsUpgradeActivated(t *testing.T) {
+	param := &Param{UAHFHeight: 100, PhononActivationTime: 1000}
+
+	tests := []struct {
+		id     UpgradeID
+		height int32
+		mtp    int64
+		exp    bool
+	}{
+		{UpgradeUAHF, 99, 2000, false},
+		{UpgradeUAHF, 100, 0, true},
+		{UpgradePhonon, 200, 999, false},
+		{UpgradePhonon, 0, 1000, true},
+	}
+
+	for _, test := range tests {
+		actual := param.IsUpgradeActivated(test.id, test.height, test.mtp)
+		if actual != test.exp {
+			t.Errorf("Test IsUpgradeActivated err! Upgrade %s at height %d and mtp %d, Expected %t, Actual is %t",
+				Upgrades[test.id].Name, test.height, test.mtp, test.exp, actual)
+		}
+	}
+
+	param.SetUpgradeActivation(UpgradePhonon, 500)
+	if param.GetUpgradeActivation(UpgradePhonon) != 500 || !param.IsUpgradeActivated(UpgradePhonon, 0, 500) {
+		t.Errorf("Test SetUpgradeActivation err! Override of phonon not applied")
+	}
+	if param.PhononActivationTime != 1000 {
+		t.Errorf("Test SetUpgradeActivation err! Default of phonon changed")
+	}
+}
+
+func TestParam_GetUpgradeScriptFlags(t *testing.T) {
+	param := &Param{
+		UAHFHeight:                    10,
+		DAAHeight:                     20,
+		MagneticAnomalyActivationTime: 100,
+		GreatWallActivationTime:       200,
+		GravitonActivationTime:        300,
+		PhononActivationTime:          400,
+		AxionActivationTime:           500,
+		Upgrade8ActivationTime:        600,
+		Upgrade9ActivationTime:        700,
+
+		ReplayProtectionActivationTime: 800,
+	}
+	uahfFlags := uint32(script.ScriptVerifyStrictEnc | script.ScriptEnableSigHashForkID)
+	daaFlags := uint32(script.ScriptVerifyLowS | script.ScriptVerifyNullFail)
+
+	tests := []struct {
+		height int32
+		mtp    int64
+		exp    uint32
+	}{
+		{9, 99, 0},
+		{10, 99, uahfFlags},
+		{20, 99, uahfFlags | daaFlags},
+		{0, 200, script.ScriptEnableCheckDataSig | script.ScriptVerifySigPushOnly | script.ScriptVerifyCleanStack |
+			script.ScriptEnableSchnorr},
+		{0, 700, script.ScriptEnableCheckDataSig | script.ScriptVerifySigPushOnly | script.ScriptVerifyCleanStack |
+			script.ScriptEnableSchnorr | script.ScriptEnableSchnorrMultisig |
+			script.ScriptVerifyInputSigChecks | script.ScriptEnable64BitIntegers |
+			script.ScriptEnableNativeIntrospection | script.ScriptEnableTokens | script.ScriptEnableP2SH32},
+		{0, 800, script.ScriptEnableCheckDataSig | script.ScriptVerifySigPushOnly | script.ScriptVerifyCleanStack |
+			script.ScriptEnableSchnorr | script.ScriptEnableSchnorrMultisig |
+			script.ScriptVerifyInputSigChecks | script.ScriptEnable64BitIntegers |
+			script.ScriptEnableNativeIntrospection | script.ScriptEnableTokens | script.ScriptEnableP2SH32 |
+			script.ScriptEnableReplayProtection},
+	}
+
+	for _, test := range tests {
+		actual := param.GetUpgradeScriptFlags(test.height, test.mtp)
+		if actual != test.exp {
+			t.Errorf("Test GetUpgradeScriptFlags err! At height %d and mtp %d, Expected %x, Actual is %x",
+				test.height, test.mtp, test.exp, actual)
+		}
+	}
+}
+
+func TestParam_IsRuleEnabled(t *testing.T) {
+	param := &Param{DAAHeight: 20, AxionActivationTime: 500, Upgrade9ActivationTime: 700}
+
+	tests := []struct {
+		rule   Rule
+		height int32
+		mtp    int64
+		exp    bool
+	}{
+		{RuleCW144, 19, 0, false},
+		{RuleCW144, 20, 0, true},
+		{RuleASERT, 20, 499, false},
+		{RuleASERT, 0, 500, true},
+		{RuleTokens, 0, 699, false},
+		{RuleTokens, 0, 700, true},
+	}
+
+	for _, test := range tests {
+		actual := param.IsRuleEnabled(test.rule, test.height, test.mtp)
+		if actual != test.exp {
+			t.Errorf("Test IsRuleEnabled err! Rule %d at height %d and mtp %d, Expected %t, Actual is %t",
+				test.rule, test.height, test.mtp, test.exp, actual)
+		}
+	}
+}
@@ -0,0 +1,605 @@
+package mempool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	// FeeEstimatesFileName is the file in the data directory the fee
+	// estimator state is saved to across restarts.
+	FeeEstimatesFileName = "fee_estimates.dat"
+
+	feeEstimatesVersion uint32 = 1
+
+	// maxFeeEstimatesBuckets and maxFeeEstimatesConfirms bound what is
+	// accepted when reading a fee estimates file.
+	maxFeeEstimatesBuckets  = 1000
+	maxFeeEstimatesConfirms = 6 * 24 * 7
+)
+
+var gFeeEstimator *FeeEstimator
+
+// InitFeeEstimator creates the fee estimator, restores its state from the
+// data directory and subscribes it to the blocks connected to the chain.
+func InitFeeEstimator() {
+	gFeeEstimator = NewFeeEstimator(*util.NewFeeRate(conf.Cfg.Mempool.MinFeeRate))
+	if err := gFeeEstimator.load(filepath.Join(conf.DataDir, FeeEstimatesFileName)); err != nil {
+		log.Warn("fee estimator: read %s failed, starting from scratch: %v", FeeEstimatesFileName, err)
+	}
+	chain.GetInstance().Subscribe(gFeeEstimator.handleBlockChainNotification)
+}
+
+// GetFeeEstimator returns the fee estimator, nil if InitFeeEstimator was not
+// called.
+func GetFeeEstimator() *FeeEstimator {
+	return gFeeEstimator
+}
+
+// FlushFeeEstimates saves the state of the fee estimator to the data directory.
+func FlushFeeEstimates() {
+	if gFeeEstimator == nil {
+		return
+	}
+	if err := gFeeEstimator.save(filepath.Join(conf.DataDir, FeeEstimatesFileName)); err != nil {
+		log.Error("fee estimator: write %s failed: %v", FeeEstimatesFileName, err)
+	}
+}
+
+// txConfirmStats tracks, per fee rate bucket, how many blocks the transactions
+// took to confirm, as exponentially decaying moving averages over the blocks.
+type txConfirmStats struct {
+	// buckets are the upper bounds of the fee rate buckets, in satoshis per kB
+	buckets []float64
+	// txCtAvg is the average number of confirmed txs per bucket
+	txCtAvg []float64
+	// confAvg[Y][X] is the average number of txs in bucket X confirmed
+	// within Y+1 blocks
+	confAvg [][]float64
+	// avg is the average fee rate sum per bucket
+	avg   []float64
+	decay float64
+
+	// counters of the block being processed, added to the moving averages
+	// once the whole block was recorded
+	curBlockConf [][]float64
+	curBlockTxCt []float64
+	curBlockVal  []float64
+
+	// unconfTxs[Y][X] is the number of mempool txs in bucket X which entered
+	// the mempool at a height equal to Y modulo maxConfirms, oldUnconfTxs
+	// counts those unconfirmed for more than maxConfirms blocks.
+	unconfTxs    [][]int
+	oldUnconfTxs []int
+}
+
+func newTxConfirmStats(buckets []float64, maxConfirms int, decay float64) *txConfirmStats {
+	s := &txConfirmStats{
+		buckets:      buckets,
+		txCtAvg:      make([]float64, len(buckets)),
+		avg:          make([]float64, len(buckets)),
+		decay:        decay,
+		curBlockTxCt: make([]float64, len(buckets)),
+		curBlockVal:  make([]float64, len(buckets)),
+		oldUnconfTxs: make([]int, len(buckets)),
+	}
+	s.confAvg = make([][]float64, maxConfirms)
+	s.curBlockConf = make([][]float64, maxConfirms)
+	s.unconfTxs = make([][]int, maxConfirms)
+	for i := 0; i < maxConfirms; i++ {
+		s.confAvg[i] = make([]float64, len(buckets))
+		s.curBlockConf[i] = make([]float64, len(buckets))
+		s.unconfTxs[i] = make([]int, len(buckets))
+	}
+	return s
+}
+
+func (s *txConfirmStats) maxConfirms() int {
+	return len(s.confAvg)
+}
+
+// bucketIndex returns the index of the lowest bucket whose upper bound is
+// not below feeRate.
+func (s *txConfirmStats) bucketIndex(feeRate float64) int {
+	i := sort.SearchFloat64s(s.buckets, feeRate)
+	if i == len(s.buckets) {
+		i--
+	}
+	return i
+}
+
+func (s *txConfirmStats) heightIndex(height int) int {
+	bins := len(s.unconfTxs)
+	return (height%bins + bins) % bins
+}
+
+// clearCurrent rolls the unconfirmed counters of the oldest height into
+// oldUnconfTxs and resets the counters of the block at height.
+func (s *txConfirmStats) clearCurrent(height int32) {
+	blockIndex := s.heightIndex(int(height))
+	for j := range s.buckets {
+		s.oldUnconfTxs[j] += s.unconfTxs[blockIndex][j]
+		s.unconfTxs[blockIndex][j] = 0
+		for i := range s.curBlockConf {
+			s.curBlockConf[i][j] = 0
+		}
+		s.curBlockTxCt[j] = 0
+		s.curBlockVal[j] = 0
+	}
+}
+
+// record adds a tx with feeRate confirmed after blocksToConfirm blocks to the
+// counters of the current block.
+func (s *txConfirmStats) record(blocksToConfirm int, feeRate float64) {
+	if blocksToConfirm < 1 {
+		return
+	}
+	bucket := s.bucketIndex(feeRate)
+	for i := blocksToConfirm; i <= len(s.curBlockConf); i++ {
+		s.curBlockConf[i-1][bucket]++
+	}
+	s.curBlockTxCt[bucket]++
+	s.curBlockVal[bucket] += feeRate
+}
+
+func (s *txConfirmStats) updateMovingAverages() {
+	for j := range s.buckets {
+		for i := range s.confAvg {
+			s.confAvg[i][j] = s.confAvg[i][j]*s.decay + s.curBlockConf[i][j]
+		}
+		s.avg[j] = s.avg[j]*s.decay + s.curBlockVal[j]
+		s.txCtAvg[j] = s.txCtAvg[j]*s.decay + s.curBlockTxCt[j]
+	}
+}
+
+func (s *txConfirmStats) newTx(height int32, feeRate float64) int {
+	bucket := s.bucketIndex(feeRate)
+	s.unconfTxs[s.heightIndex(int(height))][bucket]++
+	return bucket
+}
+
+func (s *txConfirmStats) removeTx(entryHeight int32, bestSeenHeight int32, bucket int) {
+	blocksAgo := int(bestSeenHeight - entryHeight)
+	if bestSeenHeight == 0 {
+		// no block has been seen yet
+		blocksAgo = 0
+	}
+	if blocksAgo < 0 {
+		log.Debug("fee estimator: tx removed from the mempool was added at height %d after the best seen height %d",
+			entryHeight, bestSeenHeight)
+		return
+	}
+
+	if blocksAgo >= len(s.unconfTxs) {
+		if s.oldUnconfTxs[bucket] > 0 {
+			s.oldUnconfTxs[bucket]--
+		}
+		return
+	}
+	blockIndex := s.heightIndex(int(entryHeight))
+	if s.unconfTxs[blockIndex][bucket] > 0 {
+		s.unconfTxs[blockIndex][bucket]--
+	}
+}
+
+// estimateMedianVal returns the fee rate of the median tx in the cheapest
+// range of buckets in which at least successBreakPoint of the txs confirmed
+// within confTarget blocks, combining buckets from the highest fee rate down
+// until each range has enough txs. It returns -1 if there is no such range.
+func (s *txConfirmStats) estimateMedianVal(confTarget int, sufficientTxVal float64,
+	successBreakPoint float64, bestSeenHeight int32) float64 {
+	// counters for the range of buckets being combined
+	nConf := 0.0
+	totalNum := 0.0
+	extraNum := 0
+
+	maxBucket := len(s.buckets) - 1
+	curNearBucket, bestNearBucket := maxBucket, maxBucket
+	curFarBucket, bestFarBucket := maxBucket, maxBucket
+	foundAnswer := false
+
+	for bucket := maxBucket; bucket >= 0; bucket-- {
+		curFarBucket = bucket
+		nConf += s.confAvg[confTarget-1][bucket]
+		totalNum += s.txCtAvg[bucket]
+		for confct := confTarget; confct < s.maxConfirms(); confct++ {
+			extraNum += s.unconfTxs[s.heightIndex(int(bestSeenHeight)-confct)][bucket]
+		}
+		extraNum += s.oldUnconfTxs[bucket]
+
+		// only test for success once the range has enough confirmed txs,
+		// so that every target looks at the same amount of data
+		if totalNum >= sufficientTxVal/(1-s.decay) {
+			curPct := nConf / (totalNum + float64(extraNum))
+			if curPct < successBreakPoint {
+				break
+			}
+
+			foundAnswer = true
+			nConf = 0
+			totalNum = 0
+			extraNum = 0
+			bestNearBucket = curNearBucket
+			bestFarBucket = curFarBucket
+			curNearBucket = bucket - 1
+		}
+	}
+
+	if !foundAnswer {
+		return -1
+	}
+
+	// report the average fee rate of the bucket holding the median tx of the
+	// best range, as the individual fee rates are not kept
+	minBucket, maxRangeBucket := bestFarBucket, bestNearBucket
+	txSum := 0.0
+	for j := minBucket; j <= maxRangeBucket; j++ {
+		txSum += s.txCtAvg[j]
+	}
+	if txSum == 0 {
+		return -1
+	}
+	txSum /= 2
+	for j := minBucket; j <= maxRangeBucket; j++ {
+		if s.txCtAvg[j] < txSum {
+			txSum -= s.txCtAvg[j]
+			continue
+		}
+		return s.avg[j] / s.txCtAvg[j]
+	}
+	return -1
+}
+
+func (s *txConfirmStats) write(w io.Writer) error {
+	err := util.WriteElements(w, s.decay, uint32(len(s.buckets)), uint32(s.maxConfirms()))
+	if err != nil {
+		return err
+	}
+	for _, values := range append([][]float64{s.buckets, s.avg, s.txCtAvg}, s.confAvg...) {
+		if err := binary.Write(w, binary.LittleEndian, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readTxConfirmStats(r io.Reader) (*txConfirmStats, error) {
+	var decay float64
+	var numBuckets, maxConfirms uint32
+	if err := util.ReadElements(r, &decay, &numBuckets, &maxConfirms); err != nil {
+		return nil, err
+	}
+	if decay <= 0 || decay >= 1 {
+		return nil, fmt.Errorf("decay must be between 0 and 1 (non-inclusive), got %v", decay)
+	}
+	if numBuckets <= 1 || numBuckets > maxFeeEstimatesBuckets {
+		return nil, fmt.Errorf("must have between 2 and %d fee buckets, got %d", maxFeeEstimatesBuckets, numBuckets)
+	}
+	if maxConfirms == 0 || maxConfirms > maxFeeEstimatesConfirms {
+		return nil, fmt.Errorf("must maintain estimates for between 1 and %d confirms, got %d",
+			maxFeeEstimatesConfirms, maxConfirms)
+	}
+
+	buckets := make([]float64, numBuckets)
+	if err := binary.Read(r, binary.LittleEndian, buckets); err != nil {
+		return nil, err
+	}
+	if !sort.Float64sAreSorted(buckets) {
+		return nil, errors.New("fee buckets are not sorted")
+	}
+	s := newTxConfirmStats(buckets, int(maxConfirms), decay)
+	for _, values := range append([][]float64{s.avg, s.txCtAvg}, s.confAvg...) {
+		if err := binary.Read(r, binary.LittleEndian, values); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// trackedTx is what the estimator remembers of a mempool tx until it is
+// confirmed or removed from the mempool.
+type trackedTx struct {
+	height  int32
+	feeRate float64
+	bucket  int
+}
+
+// FeeEstimator estimates the fee rate needed for a transaction to confirm
+// within a number of blocks, from how long the mempool transactions took to
+// be mined. It is safe for concurrent access.
+type FeeEstimator struct {
+	lock           sync.Mutex
+	bestSeenHeight int32
+	trackedTxs     map[util.Hash]trackedTx
+	feeStats       *txConfirmStats
+
+	// per block counts of the txs used or not for estimation, for logging
+	numTracked   int
+	numUntracked int
+}
+
+// NewFeeEstimator returns an empty fee estimator, ignoring transactions below
+// minRelayFee.
+func NewFeeEstimator(minRelayFee util.FeeRate) *FeeEstimator {
+	minTrackedFee := float64(util.MinFeeRate)
+	if float64(minRelayFee.SataoshisPerK) > minTrackedFee {
+		minTrackedFee = float64(minRelayFee.SataoshisPerK)
+	}
+	buckets := make([]float64, 0)
+	for boundary := minTrackedFee; boundary <= float64(util.MaxFee); boundary *= util.FeeSpacing {
+		buckets = append(buckets, boundary)
+	}
+	buckets = append(buckets, float64(util.InfFeeRate))
+
+	return &FeeEstimator{
+		trackedTxs: make(map[util.Hash]trackedTx),
+		feeStats:   newTxConfirmStats(buckets, int(util.MaxBlockConfirms), util.DefaultDecay),
+	}
+}
+
+// ProcessTransaction starts tracking a tx just added to the mempool. Only txs
+// entering the mempool at the best seen height are tracked, and those with
+// validFeeEstimate false, such as txs depending on other mempool txs, are
+// only counted.
+func (e *FeeEstimator) ProcessTransaction(entry *TxEntry, validFeeEstimate bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	hash := entry.Tx.GetHash()
+	if _, ok := e.trackedTxs[hash]; ok {
+		return
+	}
+	// txs accepted while processing a reorg are at an unexpected height
+	if entry.TxHeight != e.bestSeenHeight {
+		return
+	}
+	if !validFeeEstimate {
+		e.numUntracked++
+		return
+	}
+	e.numTracked++
+
+	feeRate := util.NewFeeRateWithSize(entry.TxFee, int64(entry.TxSize))
+	perK := float64(feeRate.GetFeePerK())
+	e.trackedTxs[hash] = trackedTx{
+		height:  entry.TxHeight,
+		feeRate: perK,
+		bucket:  e.feeStats.newTx(entry.TxHeight, perK),
+	}
+}
+
+// RemoveTx stops tracking a tx removed from the mempool for any reason other
+// than being mined. It returns whether the tx was tracked.
+func (e *FeeEstimator) RemoveTx(hash *util.Hash) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.removeTx(hash)
+}
+
+func (e *FeeEstimator) removeTx(hash *util.Hash) bool {
+	info, ok := e.trackedTxs[*hash]
+	if !ok {
+		return false
+	}
+	e.feeStats.removeTx(info.height, e.bestSeenHeight, info.bucket)
+	delete(e.trackedTxs, *hash)
+	return true
+}
+
+// ProcessBlock records how long the tracked txs of a block connected at
+// height took to confirm. Blocks at or below the best seen height, such as
+// those connected during a reorg, are ignored.
+func (e *FeeEstimator) ProcessBlock(height int32, txs []*tx.Tx) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if height <= e.bestSeenHeight {
+		return
+	}
+	// the counters of unconfirmed txs must be updated for the new height
+	// before recording the confirmations
+	e.bestSeenHeight = height
+	e.feeStats.clearCurrent(height)
+
+	counted := 0
+	for _, txn := range txs {
+		hash := txn.GetHash()
+		info, ok := e.trackedTxs[hash]
+		if !ok {
+			continue
+		}
+		e.feeStats.removeTx(info.height, height, info.bucket)
+		delete(e.trackedTxs, hash)
+
+		blocksToConfirm := int(height - info.height)
+		if blocksToConfirm <= 0 {
+			continue
+		}
+		e.feeStats.record(blocksToConfirm, info.feeRate)
+		counted++
+	}
+	e.feeStats.updateMovingAverages()
+
+	log.Debug("fee estimator: block at height %d, %d of %d txs counted, mempool txs tracked %d untracked %d, total tracked %d",
+		height, counted, len(txs), e.numTracked, e.numUntracked, len(e.trackedTxs))
+	e.numTracked = 0
+	e.numUntracked = 0
+}
+
+// removeMissingTxs stops tracking the txs which left the mempool before
+// height without being seen in a block, e.g. mined in blocks connected
+// without a notification.
+func (e *FeeEstimator) removeMissingTxs(height int32, pool *TxMempool) {
+	e.lock.Lock()
+	stale := make([]util.Hash, 0)
+	for hash, info := range e.trackedTxs {
+		if info.height < height {
+			stale = append(stale, hash)
+		}
+	}
+	e.lock.Unlock()
+
+	for i := range stale {
+		if pool.FindTx(stale[i]) == nil {
+			e.RemoveTx(&stale[i])
+		}
+	}
+}
+
+// EstimateFee returns the fee rate needed for a tx to be confirmed within
+// confTarget blocks, zero if there is not enough data for an estimate.
+// There is never an estimate for a target of 1 block.
+func (e *FeeEstimator) EstimateFee(confTarget int) util.FeeRate {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if confTarget <= 1 || confTarget > e.feeStats.maxConfirms() {
+		return util.FeeRate{}
+	}
+	median := e.feeStats.estimateMedianVal(confTarget, util.SufficientFeeTxs, util.MinSuccessPct, e.bestSeenHeight)
+	if median < 0 {
+		return util.FeeRate{}
+	}
+	return util.FeeRate{SataoshisPerK: int64(median)}
+}
+
+// EstimateSmartFee is like EstimateFee, but falls back to the estimates of
+// larger targets when there is not enough data for confTarget, and never
+// returns less than the mempool minimum fee rate. It also returns the target
+// the estimate was found for.
+func (e *FeeEstimator) EstimateSmartFee(confTarget int, pool *TxMempool) (util.FeeRate, int) {
+	median, answerFoundAtTarget := e.estimateSmartMedian(confTarget)
+
+	// if the mempool is limiting txs, return at least its minimum fee rate
+	if pool != nil {
+		minPoolFee := pool.GetMinFeeRate()
+		if minPoolFee.SataoshisPerK > 0 && minPoolFee.SataoshisPerK > int64(median) {
+			return minPoolFee, answerFoundAtTarget
+		}
+	}
+	if median < 0 {
+		return util.FeeRate{}, answerFoundAtTarget
+	}
+	return util.FeeRate{SataoshisPerK: int64(median)}, answerFoundAtTarget
+}
+
+func (e *FeeEstimator) estimateSmartMedian(confTarget int) (float64, int) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if confTarget <= 0 || confTarget > e.feeStats.maxConfirms() {
+		return -1, confTarget
+	}
+	// it's not possible to get reasonable estimates for a target of 1
+	if confTarget == 1 {
+		confTarget = 2
+	}
+	median := -1.0
+	for median < 0 && confTarget <= e.feeStats.maxConfirms() {
+		median = e.feeStats.estimateMedianVal(confTarget, util.SufficientFeeTxs, util.MinSuccessPct,
+			e.bestSeenHeight)
+		confTarget++
+	}
+	return median, confTarget - 1
+}
+
+// Write serializes the best seen height and the moving averages, the
+// tracked mempool txs are not saved.
+func (e *FeeEstimator) Write(w io.Writer) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if err := util.WriteElements(w, feeEstimatesVersion, e.bestSeenHeight); err != nil {
+		return err
+	}
+	return e.feeStats.write(w)
+}
+
+// Read restores the state saved by Write.
+func (e *FeeEstimator) Read(r io.Reader) error {
+	var version uint32
+	var bestSeenHeight int32
+	if err := util.ReadElements(r, &version, &bestSeenHeight); err != nil {
+		return err
+	}
+	if version > feeEstimatesVersion {
+		return fmt.Errorf("up-version (%d) fee estimate file", version)
+	}
+	feeStats, err := readTxConfirmStats(r)
+	if err != nil {
+		return err
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.bestSeenHeight = bestSeenHeight
+	e.feeStats = feeStats
+	e.trackedTxs = make(map[util.Hash]trackedTx)
+	return nil
+}
+
+func (e *FeeEstimator) load(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return e.Read(bufio.NewReader(file))
+}
+
+func (e *FeeEstimator) save(path string) error {
+	tmpPath := path + ".new"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	err = e.Write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func (e *FeeEstimator) handleBlockChainNotification(notification *chain.Notification) {
+	if notification.Type != chain.NTBlockConnected {
+		return
+	}
+	blk, ok := notification.Data.(*block.Block)
+	if !ok {
+		log.Warn("Chain connected notification is not a block.")
+		return
+	}
+
+	gChain := chain.GetInstance()
+	pindex := gChain.FindBlockIndex(blk.GetHash())
+	if pindex == nil || !gChain.Contains(pindex) {
+		return
+	}
+	e.ProcessBlock(pindex.Height, blk.Txs)
+	e.removeMissingTxs(pindex.Height, GetInstance())
+}
@@ -0,0 +1,161 @@
+package mempool
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/util"
+	"github.com/stretchr/testify/assert"
+)
+
+var feeEstimatorTxCount uint32
+
+// newFeeEstimatorEntry returns a mempool entry of a unique tx entering the
+// mempool at height and paying feePerK satoshis per kB.
+func newFeeEstimatorEntry(height int32, feePerK int64) *TxEntry {
+	feeEstimatorTxCount++
+	txn := tx.NewTx(0, tx.DefaultVersion)
+	prevOut := outpoint.NewOutPoint(util.Hash{}, feeEstimatorTxCount)
+	txn.AddTxIn(txin.NewTxIn(prevOut, script.NewScriptRaw([]byte{0x51}), 0xffffffff))
+	txn.AddTxOut(txout.NewTxOut(1000, script.NewScriptRaw([]byte{0x51})))
+	fee := feePerK * int64(txn.SerializeSize()) / 1000
+	return NewTxentry(txn, fee, 0, height, LockPoints{}, 0, false)
+}
+
+// feedFeeEstimator simulates numBlocks blocks, in each of which 10 txs paying
+// highFee enter the mempool and are mined in the next block, and 10 txs paying
+// lowFee enter the mempool and are mined 5 blocks later.
+func feedFeeEstimator(e *FeeEstimator, numBlocks int32, highFee, lowFee int64) {
+	highTxs := make(map[int32][]*tx.Tx)
+	lowTxs := make(map[int32][]*tx.Tx)
+	for height := int32(0); height <= numBlocks; height++ {
+		if height > 0 {
+			mined := append(highTxs[height-1], lowTxs[height-5]...)
+			e.ProcessBlock(height, mined)
+		}
+		for i := 0; i < 10; i++ {
+			high := newFeeEstimatorEntry(height, highFee)
+			e.ProcessTransaction(high, true)
+			highTxs[height] = append(highTxs[height], high.Tx)
+
+			low := newFeeEstimatorEntry(height, lowFee)
+			e.ProcessTransaction(low, true)
+			lowTxs[height] = append(lowTxs[height], low.Tx)
+		}
+	}
+}
+
+func assertFeeRateNear(t *testing.T, expected int64, feeRate util.FeeRate) {
+	assert.InDelta(t, float64(expected), float64(feeRate.SataoshisPerK), float64(expected)/100,
+		"fee rate %d, expected %d", feeRate.SataoshisPerK, expected)
+}
+
+func TestFeeEstimatorEstimate(t *testing.T) {
+	e := NewFeeEstimator(*util.NewFeeRate(1000))
+
+	// no estimate without data
+	assert.Equal(t, int64(0), e.EstimateFee(2).SataoshisPerK)
+	feeRate, target := e.EstimateSmartFee(2, nil)
+	assert.Equal(t, int64(0), feeRate.SataoshisPerK)
+	assert.Equal(t, int(util.MaxBlockConfirms), target)
+
+	feedFeeEstimator(e, 100, 20000, 2000)
+
+	// only the high fee txs confirm within less than 5 blocks
+	assertFeeRateNear(t, 20000, e.EstimateFee(2))
+	assertFeeRateNear(t, 20000, e.EstimateFee(4))
+	assertFeeRateNear(t, 2000, e.EstimateFee(5))
+	assertFeeRateNear(t, 2000, e.EstimateFee(10))
+
+	// targets out of range have no estimate
+	assert.Equal(t, int64(0), e.EstimateFee(1).SataoshisPerK)
+	assert.Equal(t, int64(0), e.EstimateFee(int(util.MaxBlockConfirms)+1).SataoshisPerK)
+
+	// the smart estimate of a 1 block target is the one for 2 blocks
+	feeRate, target = e.EstimateSmartFee(1, nil)
+	assertFeeRateNear(t, 20000, feeRate)
+	assert.Equal(t, 2, target)
+	feeRate, target = e.EstimateSmartFee(6, nil)
+	assertFeeRateNear(t, 2000, feeRate)
+	assert.Equal(t, 6, target)
+	feeRate, target = e.EstimateSmartFee(0, nil)
+	assert.Equal(t, int64(0), feeRate.SataoshisPerK)
+	assert.Equal(t, 0, target)
+}
+
+func TestFeeEstimatorTracking(t *testing.T) {
+	e := NewFeeEstimator(*util.NewFeeRate(1000))
+	e.ProcessBlock(10, nil)
+
+	// only txs entering the mempool at the best seen height are tracked
+	entry := newFeeEstimatorEntry(10, 5000)
+	e.ProcessTransaction(entry, true)
+	stale := newFeeEstimatorEntry(9, 5000)
+	e.ProcessTransaction(stale, true)
+	invalid := newFeeEstimatorEntry(10, 5000)
+	e.ProcessTransaction(invalid, false)
+	assert.Equal(t, 1, len(e.trackedTxs))
+
+	bucket := e.trackedTxs[entry.Tx.GetHash()].bucket
+	assert.Equal(t, 1, e.feeStats.unconfTxs[10%int(util.MaxBlockConfirms)][bucket])
+
+	hash := entry.Tx.GetHash()
+	assert.True(t, e.RemoveTx(&hash))
+	assert.False(t, e.RemoveTx(&hash))
+	assert.Equal(t, 0, e.feeStats.unconfTxs[10%int(util.MaxBlockConfirms)][bucket])
+
+	// blocks at or below the best seen height are ignored
+	entry = newFeeEstimatorEntry(10, 5000)
+	e.ProcessTransaction(entry, true)
+	e.ProcessBlock(10, []*tx.Tx{entry.Tx})
+	assert.Equal(t, 1, len(e.trackedTxs))
+	e.ProcessBlock(11, []*tx.Tx{entry.Tx})
+	assert.Equal(t, 0, len(e.trackedTxs))
+	assert.Equal(t, 1.0, e.feeStats.txCtAvg[bucket])
+}
+
+func TestFeeEstimatorReadWrite(t *testing.T) {
+	e := NewFeeEstimator(*util.NewFeeRate(1000))
+	feedFeeEstimator(e, 100, 20000, 2000)
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, e.Write(buf))
+	data := buf.Bytes()
+
+	restored := NewFeeEstimator(*util.NewFeeRate(1000))
+	assert.Nil(t, restored.Read(bytes.NewReader(data)))
+	assert.Equal(t, e.bestSeenHeight, restored.bestSeenHeight)
+	assert.Equal(t, e.feeStats.buckets, restored.feeStats.buckets)
+	for target := 2; target <= int(util.MaxBlockConfirms); target++ {
+		assert.Equal(t, e.EstimateFee(target), restored.EstimateFee(target))
+	}
+
+	dir, err := ioutil.TempDir("", "feeestimates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, FeeEstimatesFileName)
+	assert.Nil(t, e.save(path))
+	loaded := NewFeeEstimator(*util.NewFeeRate(1000))
+	assert.Nil(t, loaded.load(path))
+	assert.Equal(t, e.EstimateFee(2), loaded.EstimateFee(2))
+	// a missing file is not an error
+	assert.Nil(t, loaded.load(filepath.Join(dir, "missing.dat")))
+
+	// a truncated file is rejected and leaves the estimator untouched
+	empty := NewFeeEstimator(*util.NewFeeRate(1000))
+	assert.NotNil(t, empty.Read(bytes.NewReader(data[:len(data)/2])))
+	assert.Equal(t, int32(0), empty.bestSeenHeight)
+
+	// so is a file written by a newer version
+	newer := append([]byte{}, data...)
+	newer[0] = byte(feeEstimatesVersion + 1)
+	assert.NotNil(t, empty.Read(bytes.NewReader(newer)))
+}
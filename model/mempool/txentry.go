@@ -17,6 +17,8 @@ type TxEntry struct {
 	TxHeight int32
 	// sigOpCount sigop plus P2SH sigops count
 	SigOpCount int
+	// SigChecks sigchecks counted while verifying the tx scripts
+	SigChecks int
 	// time Local time when entering the memPool
 	time int64
 	// usageSize and total memory usage;
@@ -45,6 +47,7 @@ type StatisInformation struct {
 	SumTxCountWithAncestors      int64
 	SumTxSizeWitAncestors        int64
 	SumTxSigOpCountWithAncestors int64
+	SumTxSigChecksWithAncestors  int64
 	SumTxFeeWithAncestors        int64
 }
 
@@ -52,6 +55,10 @@ func (t *TxEntry) GetSigOpCountWithAncestors() int64 {
 	return t.SumTxSigOpCountWithAncestors
 }
 
+func (t *TxEntry) GetSigChecksWithAncestors() int64 {
+	return t.SumTxSigChecksWithAncestors
+}
+
 func (t *TxEntry) GetUsageSize() int64 {
 	return int64(t.usageSize)
 }
@@ -72,6 +79,19 @@ func (t *TxEntry) GetTime() int64 {
 	return t.time
 }
 
+// SetTime sets the time the tx entered the mempool. It must be called before
+// the entry is added to the mempool.
+func (t *TxEntry) SetTime(acceptTime int64) {
+	t.time = acceptTime
+}
+
+// SetSigChecks sets the sigchecks of the tx scripts. It must be called before
+// the entry is added to the mempool.
+func (t *TxEntry) SetSigChecks(sigChecks int) {
+	t.SigChecks = sigChecks
+	t.SumTxSigChecksWithAncestors = int64(sigChecks)
+}
+
 // UpdateParent update the tx's parent transaction.
 func (t *TxEntry) UpdateParent(parent *TxEntry, add bool) {
 	if add {
@@ -106,10 +126,11 @@ func (t *TxEntry) UpdateDescendantState(updateCount, updateSize int, updateFee i
 	t.SumTxFeeWithDescendants += updateFee
 }
 
-func (t *TxEntry) UpdateAncestorState(updateCount, updateSize, updateSigOps int, updateFee int64) {
+func (t *TxEntry) UpdateAncestorState(updateCount, updateSize, updateSigOps, updateSigChecks int, updateFee int64) {
 	t.SumTxSizeWitAncestors += int64(updateSize)
 	t.SumTxCountWithAncestors += int64(updateCount)
 	t.SumTxSigOpCountWithAncestors += int64(updateSigOps)
+	t.SumTxSigChecksWithAncestors += int64(updateSigChecks)
 	t.SumTxFeeWithAncestors += updateFee
 }
 
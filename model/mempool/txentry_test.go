@@ -43,6 +43,12 @@ func TestTxentry(t *testing.T) {
 	sigOpCount := txentry.GetSigOpCountWithAncestors()
 	assert.Equal(t, sigOpCount, int64(10))
 
+	txentry.SetSigChecks(3)
+	assert.Equal(t, txentry.SigChecks, 3)
+	assert.Equal(t, txentry.GetSigChecksWithAncestors(), int64(3))
+	txentry.UpdateAncestorState(1, 100, 1, 2, 100)
+	assert.Equal(t, txentry.GetSigChecksWithAncestors(), int64(5))
+
 	usageSize := txentry.GetUsageSize()
 	assert.Equal(t, usageSize, int64(10))
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/copernet/copernicus/conf"
@@ -94,6 +95,12 @@ type TxMempool struct {
 	rollingMinimumFeeRate        int64
 	blockSinceLastRollingFeeBump bool
 	lastRollingFeeUpdate         int64
+
+	// loaded is set once the mempool saved on shutdown was restored
+	loaded int32
+
+	notificationsLock sync.RWMutex
+	notifications     []TxAcceptedCallback
 }
 
 func (m *TxMempool) Lock() {
@@ -109,6 +116,44 @@ func (m *TxMempool) RUnlock() {
 	m.lck.RUnlock()
 }
 
+// IsLoaded returns whether the mempool saved on shutdown was restored.
+func (m *TxMempool) IsLoaded() bool {
+	return atomic.LoadInt32(&m.loaded) != 0
+}
+
+// SetLoaded records whether the mempool saved on shutdown was restored.
+func (m *TxMempool) SetLoaded(loaded bool) {
+	var value int32
+	if loaded {
+		value = 1
+	}
+	atomic.StoreInt32(&m.loaded, value)
+}
+
+// TxAcceptedCallback is used for a caller to provide a callback for
+// notifications about txs accepted into the mempool.
+type TxAcceptedCallback func(*TxEntry)
+
+// Subscribe to mempool notifications. Registers a callback to be executed for
+// every tx accepted into the mempool. The callback runs with the mempool
+// locked, so it must not block nor call back into the mempool.
+func (m *TxMempool) Subscribe(callback TxAcceptedCallback) {
+	m.notificationsLock.Lock()
+	m.notifications = append(m.notifications, callback)
+	m.notificationsLock.Unlock()
+}
+
+// SendTxAccepted notifies the subscribers that txEntry was accepted into the
+// mempool.
+func (m *TxMempool) SendTxAccepted(txEntry *TxEntry) {
+	m.notificationsLock.RLock()
+	defer m.notificationsLock.RUnlock()
+
+	for _, callback := range m.notifications {
+		callback(txEntry)
+	}
+}
+
 func (m *TxMempool) GetCheckFrequency() uint64 {
 	return conf.Cfg.Mempool.CheckFrequency
 }
@@ -472,6 +517,7 @@ func (m *TxMempool) updateForRemoveFromMempool(entriesToRemove map[*TxEntry]stru
 			modifySize := -removeIt.TxSize
 			modifyFee := -removeIt.TxFee
 			modifySigOps := -removeIt.SigOpCount
+			modifySigChecks := -removeIt.SigChecks
 
 			for dit := range setDescendants {
 				// Google's btree library use binary search and Less() to find item.However we want to do
@@ -479,7 +525,7 @@ func (m *TxMempool) updateForRemoveFromMempool(entriesToRemove map[*TxEntry]stru
 				// its key also change which looks like dead lock:(.So temporarily use delete and insert to instead.
 				m.timeSortData.Delete(dit)
 				m.txByAncestorFeeRateSort.Delete((*EntryAncestorFeeRateSort)(dit))
-				dit.UpdateAncestorState(-1, modifySize, modifySigOps, modifyFee)
+				dit.UpdateAncestorState(-1, modifySize, modifySigOps, modifySigChecks, modifyFee)
 				m.timeSortData.ReplaceOrInsert(dit)
 				m.txByAncestorFeeRateSort.ReplaceOrInsert((*EntryAncestorFeeRateSort)(dit))
 			}
@@ -592,13 +638,15 @@ func (m *TxMempool) updateEntryForAncestors(entry *TxEntry, setAncestors map[*Tx
 	updateSize := 0
 	updateFee := int64(0)
 	updateSigOpsCount := 0
+	updateSigChecks := 0
 
 	for ancestorIt := range setAncestors {
 		updateFee += ancestorIt.TxFee
 		updateSigOpsCount += ancestorIt.SigOpCount
+		updateSigChecks += ancestorIt.SigChecks
 		updateSize += ancestorIt.TxSize
 	}
-	entry.UpdateAncestorState(updateCount, updateSize, updateSigOpsCount, updateFee)
+	entry.UpdateAncestorState(updateCount, updateSize, updateSigOpsCount, updateSigChecks, updateFee)
 }
 
 // CalculateMemPoolAncestors get tx all ancestors transaction in mempool.
@@ -685,6 +733,13 @@ func (m *TxMempool) delTxentry(removeEntry *TxEntry, reason PoolRemovalReason) {
 	delete(m.poolData, removeEntry.Tx.GetHash())
 	m.timeSortData.Delete(removeEntry)
 	m.txByAncestorFeeRateSort.Delete((*EntryAncestorFeeRateSort)(removeEntry))
+
+	// mined txs are accounted for by the fee estimator when their block is
+	// connected
+	if reason != BLOCK && gFeeEstimator != nil {
+		hash := removeEntry.Tx.GetHash()
+		gFeeEstimator.RemoveTx(&hash)
+	}
 }
 
 func (m *TxMempool) TxInfoAll() []*TxMempoolInfo {
@@ -424,3 +424,17 @@ func TestTxMempool_GetCoin(t *testing.T) {
 	assert.Equal(t, out.GetValue(), coin3.GetAmount())
 	assert.Equal(t, out.GetScriptPubKey(), coin3.GetScriptPubKey())
 }
+
+func TestTxMempool_SendTxAccepted(t *testing.T) {
+	mp := NewTxMempool()
+	set := createTx()
+
+	var first, second []*TxEntry
+	mp.Subscribe(func(txe *TxEntry) { first = append(first, txe) })
+	mp.SendTxAccepted(set[0])
+	mp.Subscribe(func(txe *TxEntry) { second = append(second, txe) })
+	mp.SendTxAccepted(set[1])
+
+	assert.Equal(t, []*TxEntry{set[0], set[1]}, first)
+	assert.Equal(t, []*TxEntry{set[1]}, second)
+}
@@ -0,0 +1,129 @@
+package pow
+
+import (
+	"math/big"
+
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/consensus"
+)
+
+// getNextASERTWorkRequired Compute the next required proof of work using the
+// aserti3-2d algorithm: the target is set exponentially from how far ahead
+// or behind the ideal schedule the chain is since the anchor block, and
+// doubles (halves the difficulty) every ASERTHalfLife seconds behind.
+func (pow *Pow) getNextASERTWorkRequired(indexPrev *blockindex.BlockIndex, blHeader *block.BlockHeader,
+	params *model.BitcoinParams) uint32 {
+	if indexPrev == nil {
+		panic("This cannot handle the genesis block and early blocks in general.")
+	}
+
+	// Special difficulty rule for testnet:
+	// If the new block's timestamp is more than 2* 10 minutes then allow
+	// mining of a min-difficulty block.
+	if params.FPowAllowMinDifficultyBlocks && (blHeader.Time > indexPrev.GetBlockTime()+uint32(2*params.TargetTimePerBlock)) {
+		return BigToCompact(params.PowLimit)
+	}
+
+	var anchorHeight int32
+	var anchorBits uint32
+	var anchorParentTime int64
+	if anchor := params.ASERTAnchorParams; anchor != nil && indexPrev.Height >= anchor.Height {
+		anchorHeight = anchor.Height
+		anchorBits = anchor.Bits
+		anchorParentTime = anchor.PrevBlockTime
+	} else {
+		indexAnchor := getASERTAnchorBlock(indexPrev, params)
+		anchorHeight = indexAnchor.Height
+		anchorBits = indexAnchor.Header.Bits
+		// The time is the one of the block before the anchor, or of the
+		// anchor itself when it is the genesis block.
+		if indexAnchor.Prev != nil {
+			anchorParentTime = int64(indexAnchor.Prev.GetBlockTime())
+		} else {
+			anchorParentTime = int64(indexAnchor.GetBlockTime())
+		}
+	}
+
+	timeDiff := int64(indexPrev.GetBlockTime()) - anchorParentTime
+	heightDiff := int64(indexPrev.Height - anchorHeight)
+	nextTarget := calculateASERT(CompactToBig(anchorBits), int64(params.TargetTimePerBlock), timeDiff,
+		heightDiff, params.PowLimit, params.ASERTHalfLife)
+	return BigToCompact(nextTarget)
+}
+
+// getASERTAnchorBlock returns the first block of the chain of indexPrev whose
+// median time past reaches the activation of the aserti3-2d algorithm, that
+// is the last block whose target was set by the previous algorithm.
+func getASERTAnchorBlock(indexPrev *blockindex.BlockIndex, params *model.BitcoinParams) *blockindex.BlockIndex {
+	low, high := int32(0), indexPrev.Height
+	for low < high {
+		mid := low + (high-low)/2
+		index := indexPrev.GetAncestor(mid)
+		if params.IsRuleEnabled(consensus.RuleASERT, index.Height, index.GetMedianTimePast()) {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	return indexPrev.GetAncestor(low)
+}
+
+// calculateASERT computes the target of the block heightDiff+1 blocks after
+// the anchor block of target refTarget, with timeDiff seconds between the
+// parents of the two blocks.
+//
+// The exponential 2^((timeDiff - spacing*(heightDiff+1)) / halfLife) is
+// computed with 16 bits fixed-point integer math, approximating the
+// fractional part with a cubic polynomial, so that every implementation
+// gets exactly the same target.
+func calculateASERT(refTarget *big.Int, spacing, timeDiff, heightDiff int64, powLimit *big.Int,
+	halfLife int64) *big.Int {
+	if refTarget.Sign() <= 0 || refTarget.Cmp(powLimit) > 0 {
+		panic("the reference target should be positive and not above the pow limit")
+	}
+	if heightDiff < 0 {
+		panic("the height difference should not be negative")
+	}
+
+	// Keep the intermediate values in range: the exponent times 65536 must
+	// fit in 64 bits.
+	if d := timeDiff - spacing*heightDiff; d >= 1<<47 || d <= -(1<<47) {
+		panic("the time difference is out of range")
+	}
+
+	// The exponent in 16.16 fixed point.  Go divisions truncate toward zero
+	// and right shifts of negative values are arithmetic, as in the
+	// reference implementation.
+	exponent := ((timeDiff - spacing*(heightDiff+1)) * 65536) / halfLife
+	shifts := exponent >> 16
+	frac := uint64(uint16(exponent))
+
+	// factor = 65536 * 2^(frac/65536), with a cubic approximation whose
+	// error is below 0.013%.
+	factor := 65536 + ((195766423245049*frac + 971821376*frac*frac + 5127*frac*frac*frac + 1<<47) >> 48)
+	nextTarget := new(big.Int).Mul(refTarget, new(big.Int).SetUint64(factor))
+
+	// Multiply by 2^shifts and drop the 16 bits of the factor.
+	shifts -= 16
+	if shifts <= 0 {
+		nextTarget.Rsh(nextTarget, uint(-shifts))
+	} else {
+		// A target overflowing 256 bits is clamped to the pow limit.
+		if int64(nextTarget.BitLen())+shifts > 256 {
+			return new(big.Int).Set(powLimit)
+		}
+		nextTarget.Lsh(nextTarget, uint(shifts))
+	}
+
+	if nextTarget.Sign() == 0 {
+		// The target must never be zero, so that there always is a valid
+		// block hash.
+		return big.NewInt(1)
+	}
+	if nextTarget.Cmp(powLimit) > 0 {
+		return new(big.Int).Set(powLimit)
+	}
+	return nextTarget
+}
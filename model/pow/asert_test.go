@@ -0,0 +1,320 @@
+package pow
+
+import (
+	"bufio"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+)
+
+func TestCalculateASERT(t *testing.T) {
+	powLimit := model.MainNetParams.PowLimit
+	halfLife := int64(2 * 24 * 3600)
+	spacing := int64(600)
+	refTarget := CompactToBig(0x1804dafe)
+
+	tests := []struct {
+		name       string
+		timeDiff   int64
+		heightDiff int64
+		expect     *big.Int
+	}{
+		{"on schedule", 600, 0, refTarget},
+		{"on schedule later", 1001 * 600, 1000, refTarget},
+		{"one half life behind", 289 * 600, 0, new(big.Int).Lsh(refTarget, 1)},
+		{"two half lives behind", 2*halfLife + 101*600, 100, new(big.Int).Lsh(refTarget, 2)},
+		{"one half life ahead", 600, 288, new(big.Int).Rsh(refTarget, 1)},
+		{"ten half lives ahead", 101 * 600, 2980, new(big.Int).Rsh(refTarget, 10)},
+		{"a second behind", 601, 0, refTarget},
+	}
+	for _, test := range tests {
+		target := calculateASERT(refTarget, spacing, test.timeDiff, test.heightDiff, powLimit, halfLife)
+		if BigToCompact(target) != BigToCompact(test.expect) {
+			t.Errorf("%s: expect target %x, actual target %x", test.name,
+				BigToCompact(test.expect), BigToCompact(target))
+		}
+	}
+
+	// Half a half life behind multiplies the target by the approximation of
+	// the square root of 2.
+	target := calculateASERT(big.NewInt(1<<40), spacing, 600+halfLife/2, 0, powLimit, halfLife)
+	if target.Cmp(big.NewInt(1554811715584)) != 0 {
+		t.Errorf("expect target 1554811715584, actual target %s", target)
+	}
+
+	// The target grows with the time, and shrinks with the height.
+	prev := calculateASERT(refTarget, spacing, -halfLife, 0, powLimit, halfLife)
+	for timeDiff := -halfLife + 97; timeDiff < 3*halfLife; timeDiff += 97 {
+		next := calculateASERT(refTarget, spacing, timeDiff, 0, powLimit, halfLife)
+		if next.Cmp(prev) < 0 {
+			t.Errorf("the target decreased from %x to %x at time difference %d",
+				BigToCompact(prev), BigToCompact(next), timeDiff)
+			return
+		}
+		prev = next
+	}
+	prev = calculateASERT(refTarget, spacing, 0, 0, powLimit, halfLife)
+	for heightDiff := int64(1); heightDiff < 1000; heightDiff++ {
+		next := calculateASERT(refTarget, spacing, 0, heightDiff, powLimit, halfLife)
+		if next.Cmp(prev) > 0 {
+			t.Errorf("the target increased from %x to %x at height difference %d",
+				BigToCompact(prev), BigToCompact(next), heightDiff)
+			return
+		}
+		prev = next
+	}
+}
+
+func TestCalculateASERTBounds(t *testing.T) {
+	powLimit := model.MainNetParams.PowLimit
+	halfLife := int64(2 * 24 * 3600)
+
+	// The target never goes above the pow limit.
+	target := calculateASERT(new(big.Int).Rsh(powLimit, 1), 600, 600+2*halfLife, 0, powLimit, halfLife)
+	if target.Cmp(powLimit) != 0 {
+		t.Errorf("expect the pow limit, actual target %x", BigToCompact(target))
+	}
+	target = calculateASERT(powLimit, 600, 600+300*halfLife, 0, powLimit, halfLife)
+	if target.Cmp(powLimit) != 0 {
+		t.Errorf("expect the pow limit, actual target %x", BigToCompact(target))
+	}
+
+	// Nor below 1.
+	target = calculateASERT(big.NewInt(1), 600, 600, 288, powLimit, halfLife)
+	if target.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("expect target 1, actual target %s", target)
+	}
+	target = calculateASERT(powLimit, 600, 0, 300*288, powLimit, halfLife)
+	if target.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("expect target 1, actual target %s", target)
+	}
+}
+
+// newASERTChain returns a chain of count blocks mined every spacing seconds,
+// with the genesis block at startTime.
+func newASERTChain(count int, startTime uint32, spacing int64, bits uint32) []*blockindex.BlockIndex {
+	blocks := make([]*blockindex.BlockIndex, count)
+	blocks[0] = new(blockindex.BlockIndex)
+	blocks[0].SetNull()
+	blocks[0].Header.Time = startTime
+	blocks[0].Header.Bits = bits
+	blocks[0].ChainWork = *GetBlockProof(blocks[0])
+	for i := 1; i < count; i++ {
+		blocks[i] = getBlockIndex(blocks[i-1], spacing, bits)
+	}
+	return blocks
+}
+
+func TestPowGetNextASERTWorkRequired(t *testing.T) {
+	model.ActiveNetParams = &model.MainNetParams
+	defer func() {
+		model.ActiveNetParams = &model.MainNetParams
+	}()
+	pow := Pow{}
+	bits := uint32(0x1804dafe)
+	activation := uint32(model.MainNetParams.AxionActivationTime)
+
+	// The median time past of the block 150 is the time of the block 145,
+	// which reaches the activation.
+	blocks := newASERTChain(300, activation-145*600, 600, bits)
+	anchor := getASERTAnchorBlock(blocks[299], model.ActiveNetParams)
+	if anchor != blocks[150] {
+		t.Errorf("expect the anchor block at height 150, actual height %d", anchor.Height)
+		return
+	}
+	if getASERTAnchorBlock(blocks[150], model.ActiveNetParams) != blocks[150] {
+		t.Errorf("the anchor block should be found from itself")
+	}
+
+	blkHeaderDummy := block.BlockHeader{}
+	for i := 150; i < 300; i++ {
+		work := pow.GetNextWorkRequired(blocks[i], &blkHeaderDummy, model.ActiveNetParams)
+		if work != bits {
+			t.Errorf("the difficulty should not change on schedule at height %d, expect %x, actual %x",
+				i, bits, work)
+			return
+		}
+	}
+
+	// A block two days late doubles the target of the next one.
+	late := getBlockIndex(blocks[299], 600+2*24*3600, bits)
+	work := pow.GetNextWorkRequired(late, &blkHeaderDummy, model.ActiveNetParams)
+	expect := BigToCompact(new(big.Int).Lsh(CompactToBig(bits), 1))
+	if work != expect {
+		t.Errorf("expect the next work : %x, but actual work is %x", expect, work)
+	}
+
+	// Catching up with the schedule restores the target.
+	next := late
+	for i := 0; i < 288; i++ {
+		next = getBlockIndex(next, 0, bits)
+	}
+	work = pow.GetNextWorkRequired(next, &blkHeaderDummy, model.ActiveNetParams)
+	if work != bits {
+		t.Errorf("expect the next work : %x, but actual work is %x", bits, work)
+	}
+}
+
+func TestPowGetNextASERTWorkRequiredAnchorParams(t *testing.T) {
+	model.ActiveNetParams = &model.MainNetParams
+	pow := Pow{}
+	anchor := model.MainNetParams.ASERTAnchorParams
+
+	// The anchor of the network parameters is used, whatever the bits of
+	// the chain.
+	indexPrev := new(blockindex.BlockIndex)
+	indexPrev.SetNull()
+	indexPrev.Height = anchor.Height + 10
+	indexPrev.Header.Time = uint32(anchor.PrevBlockTime + 11*600)
+	indexPrev.Header.Bits = 0x1d00ffff
+	work := pow.getNextASERTWorkRequired(indexPrev, &block.BlockHeader{}, model.ActiveNetParams)
+	if work != anchor.Bits {
+		t.Errorf("expect the next work : %x, but actual work is %x", anchor.Bits, work)
+	}
+}
+
+func TestPowGetNextASERTWorkRequiredTestNet(t *testing.T) {
+	model.ActiveNetParams = &model.TestNetParams
+	defer func() {
+		model.ActiveNetParams = &model.MainNetParams
+	}()
+	pow := Pow{}
+	bits := uint32(0x1c0fffff)
+	limitBits := BigToCompact(model.ActiveNetParams.PowLimit)
+
+	blocks := newASERTChain(200, uint32(model.TestNetParams.AxionActivationTime)-100*600, 600, bits)
+	indexPrev := blocks[199]
+
+	// A block on schedule keeps the difficulty.
+	header := block.BlockHeader{Time: indexPrev.GetBlockTime() + 600}
+	if work := pow.GetNextWorkRequired(indexPrev, &header, model.ActiveNetParams); work != bits {
+		t.Errorf("expect the next work : %x, but actual work is %x", bits, work)
+	}
+
+	// A block more than 20 minutes after the previous one may be mined at the
+	// minimal difficulty.
+	header.Time = indexPrev.GetBlockTime() + 2*600
+	if work := pow.GetNextWorkRequired(indexPrev, &header, model.ActiveNetParams); work != bits {
+		t.Errorf("expect the next work : %x, but actual work is %x", bits, work)
+	}
+	header.Time++
+	if work := pow.GetNextWorkRequired(indexPrev, &header, model.ActiveNetParams); work != limitBits {
+		t.Errorf("expect the next work : %x, but actual work is %x", limitBits, work)
+	}
+
+	// The min-difficulty block does not lower the difficulty of the next
+	// ones, and the one hour half life of testnet applies.
+	minDifficulty := getBlockIndex(indexPrev, 2*600+1, limitBits)
+	next := getBlockIndex(minDifficulty, 3600+2*600-(2*600+1), bits)
+	header.Time = next.GetBlockTime() + 600
+	expect := BigToCompact(new(big.Int).Lsh(CompactToBig(bits), 1))
+	if work := pow.GetNextWorkRequired(next, &header, model.ActiveNetParams); work != expect {
+		t.Errorf("expect the next work : %x, but actual work is %x", expect, work)
+	}
+}
+
+// asertVector is a file of the aserti3-2d test vectors in test_data/asert,
+// named run01 to run12 as in the specification: the anchor block and, for
+// each iteration, the height and time of a block with the target of its next
+// block.
+type asertVector struct {
+	anchorHeight     int64
+	anchorParentTime int64
+	anchorBits       uint32
+	blocks           []asertVectorBlock
+}
+
+type asertVectorBlock struct {
+	height int64
+	time   int64
+	bits   uint32
+}
+
+func parseASERTVector(fileName string) (*asertVector, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	vector := new(asertVector)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		header := strings.TrimSpace(strings.TrimLeft(line, "#"))
+		switch {
+		case strings.HasPrefix(line, "##") && strings.HasPrefix(header, "anchor height:"):
+			vector.anchorHeight, err = strconv.ParseInt(strings.TrimSpace(header[len("anchor height:"):]), 10, 64)
+		case strings.HasPrefix(line, "##") && strings.HasPrefix(header, "anchor parent time:"):
+			vector.anchorParentTime, err = strconv.ParseInt(strings.TrimSpace(header[len("anchor parent time:"):]), 10, 64)
+		case strings.HasPrefix(line, "##") && strings.HasPrefix(header, "anchor nBits:"):
+			vector.anchorBits, err = parseASERTBits(strings.TrimSpace(header[len("anchor nBits:"):]))
+		case line == "" || strings.HasPrefix(line, "#"):
+		default:
+			// iteration, height, time, target
+			fields := strings.Fields(line)
+			if len(fields) != 4 {
+				return nil, strconv.ErrSyntax
+			}
+			var block asertVectorBlock
+			if block.height, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+				return nil, err
+			}
+			if block.time, err = strconv.ParseInt(fields[2], 10, 64); err != nil {
+				return nil, err
+			}
+			if block.bits, err = parseASERTBits(fields[3]); err != nil {
+				return nil, err
+			}
+			vector.blocks = append(vector.blocks, block)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return vector, scanner.Err()
+}
+
+// parseASERTBits parses a compact target in hexadecimal, with or without the
+// 0x prefix.
+func parseASERTBits(s string) (uint32, error) {
+	bits, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 32)
+	return uint32(bits), err
+}
+
+func TestCalculateASERTVectors(t *testing.T) {
+	fileNames, err := filepath.Glob(filepath.Join("test_data", "asert", "run*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fileNames) == 0 {
+		t.Fatal("the aserti3-2d test vectors are not in test_data/asert")
+	}
+
+	powLimit := model.MainNetParams.PowLimit
+	halfLife := int64(2 * 24 * 3600)
+	for _, fileName := range fileNames {
+		vector, err := parseASERTVector(fileName)
+		if err != nil {
+			t.Errorf("%s: %v", fileName, err)
+			continue
+		}
+		refTarget := CompactToBig(vector.anchorBits)
+		for _, block := range vector.blocks {
+			target := calculateASERT(refTarget, 600, block.time-vector.anchorParentTime,
+				block.height-vector.anchorHeight, powLimit, halfLife)
+			if bits := BigToCompact(target); bits != block.bits {
+				t.Errorf("%s: at height %d and time %d, expect target %x, actual target %x",
+					filepath.Base(fileName), block.height, block.time, block.bits, bits)
+				break
+			}
+		}
+	}
+}
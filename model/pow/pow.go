@@ -7,6 +7,7 @@ import (
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/block"
 	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/consensus"
 	"github.com/copernet/copernicus/util"
 )
 
@@ -23,7 +24,12 @@ func (pow *Pow) GetNextWorkRequired(indexPrev *blockindex.BlockIndex, blHeader *
 		return indexPrev.Header.Bits
 	}
 
-	if model.IsDAAEnabled(indexPrev.Height) {
+	mtp := indexPrev.GetMedianTimePast()
+	if params.IsRuleEnabled(consensus.RuleASERT, indexPrev.Height, mtp) {
+		return pow.getNextASERTWorkRequired(indexPrev, blHeader, params)
+	}
+
+	if params.IsRuleEnabled(consensus.RuleCW144, indexPrev.Height, mtp) {
 		return pow.getNextCashWorkRequired(indexPrev, blHeader, params)
 	}
 
@@ -0,0 +1,583 @@
+// Package psbt implements a partially signed transaction, a container which
+// carries an unsigned transaction along with what the signers need to sign
+// it and the signatures they produced, so that it can be passed between the
+// cosigners of a multisig spend or to an offline signer.
+//
+// The format is the one of BIP 174 without the segwit records, as used by
+// other Bitcoin Cash implementations: the previous outputs are carried in
+// full since their amount is committed to by the signatures.
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+	"github.com/pkg/errors"
+)
+
+// The types of the records of the global map.
+const (
+	GlobalUnsignedTx = 0x00
+)
+
+// The types of the records of the input maps.
+const (
+	InUTXO         = 0x00
+	InPartialSig   = 0x02
+	InSigHash      = 0x03
+	InRedeemScript = 0x04
+	InScriptSig    = 0x07
+)
+
+// The types of the records of the output maps.
+const (
+	OutRedeemScript = 0x00
+)
+
+// separator ends each map of records.
+const separator = 0x00
+
+// magic starts a serialized partially signed transaction.
+var magic = []byte{'p', 's', 'b', 't', 0xff}
+
+var (
+	ErrInvalidMagic      = errors.New("Invalid PSBT magic bytes")
+	ErrNoUnsignedTx      = errors.New("No unsigned transaction was provided")
+	ErrNotUnsignedTx     = errors.New("Unsigned tx does not have empty scriptSigs")
+	ErrDifferentTx       = errors.New("PSBTs do not refer to the same transactions")
+	ErrNotComplete       = errors.New("PSBT is not complete")
+	ErrInputsMismatch    = errors.New("Inputs provided does not match the number of inputs in transaction")
+	ErrOutputsMismatch   = errors.New("Outputs provided does not match the number of outputs in transaction")
+	ErrInvalidPubKey     = errors.New("Invalid pubkey")
+	ErrUnexpectedKeySize = errors.New("Size of key was not the expected size for the type")
+)
+
+// PSBT is a partially signed transaction.  Inputs and Outputs hold the data
+// of the inputs and outputs of Tx, in the same order.
+type PSBT struct {
+	Tx      *tx.Tx
+	Inputs  []*Input
+	Outputs []*Output
+	Unknown map[string][]byte
+}
+
+// Input is what the signers of an input know of it.  The partial signatures
+// are keyed by public key, and SigHashType is zero unless a hash type is
+// requested for the signatures.
+type Input struct {
+	UTXO           *txout.TxOut
+	PartialSigs    map[string][]byte
+	SigHashType    uint32
+	RedeemScript   *script.Script
+	FinalScriptSig *script.Script
+	Unknown        map[string][]byte
+}
+
+// Output is what the signers know of an output.
+type Output struct {
+	RedeemScript *script.Script
+	Unknown      map[string][]byte
+}
+
+// New returns a partially signed transaction of transaction, whose inputs
+// must not be signed.
+func New(transaction *tx.Tx) (*PSBT, error) {
+	for _, in := range transaction.GetIns() {
+		if in.GetScriptSig() != nil && in.GetScriptSig().Size() != 0 {
+			return nil, ErrNotUnsignedTx
+		}
+	}
+	p := &PSBT{
+		Tx:      transaction,
+		Inputs:  make([]*Input, transaction.GetInsCount()),
+		Outputs: make([]*Output, transaction.GetOutsCount()),
+		Unknown: make(map[string][]byte),
+	}
+	for i := range p.Inputs {
+		p.Inputs[i] = newInput()
+	}
+	for i := range p.Outputs {
+		p.Outputs[i] = newOutput()
+	}
+	return p, nil
+}
+
+func newInput() *Input {
+	return &Input{
+		PartialSigs: make(map[string][]byte),
+		Unknown:     make(map[string][]byte),
+	}
+}
+
+func newOutput() *Output {
+	return &Output{
+		Unknown: make(map[string][]byte),
+	}
+}
+
+// IsFinalized tells whether the input holds its final scriptSig.
+func (in *Input) IsFinalized() bool {
+	return in.FinalScriptSig != nil
+}
+
+// Finalize sets the final scriptSig of the input and drops the records only
+// needed to produce it.
+func (in *Input) Finalize(scriptSig *script.Script) {
+	in.FinalScriptSig = scriptSig
+	in.PartialSigs = make(map[string][]byte)
+	in.RedeemScript = nil
+}
+
+// IsComplete tells whether all the inputs are finalized.
+func (p *PSBT) IsComplete() bool {
+	for _, in := range p.Inputs {
+		if !in.IsFinalized() {
+			return false
+		}
+	}
+	return true
+}
+
+// GetFee returns the fee paid by the transaction, and false if the previous
+// output of an input is unknown.
+func (p *PSBT) GetFee() (amount.Amount, bool) {
+	fee := amount.Amount(0)
+	for _, in := range p.Inputs {
+		if in.UTXO == nil {
+			return 0, false
+		}
+		fee += in.UTXO.GetValue()
+	}
+	for _, out := range p.Tx.GetOuts() {
+		fee -= out.GetValue()
+	}
+	return fee, true
+}
+
+// Extract returns the signed transaction, once all the inputs are finalized.
+func (p *PSBT) Extract() (*tx.Tx, error) {
+	if !p.IsComplete() {
+		return nil, ErrNotComplete
+	}
+	transaction, err := cloneTx(p.Tx)
+	if err != nil {
+		return nil, err
+	}
+	for i, in := range p.Inputs {
+		if err := transaction.UpdateInScript(i, in.FinalScriptSig); err != nil {
+			return nil, err
+		}
+	}
+	return transaction, nil
+}
+
+// Merge adds to p the data other holds and p does not.  Both must be of the
+// same transaction.
+func (p *PSBT) Merge(other *PSBT) error {
+	if p.Tx.GetHash() != other.Tx.GetHash() {
+		return ErrDifferentTx
+	}
+	for i, in := range p.Inputs {
+		in.merge(other.Inputs[i])
+	}
+	for i, out := range p.Outputs {
+		out.merge(other.Outputs[i])
+	}
+	mergeUnknown(p.Unknown, other.Unknown)
+	return nil
+}
+
+func (in *Input) merge(other *Input) {
+	if in.UTXO == nil {
+		in.UTXO = other.UTXO
+	}
+	mergeUnknown(in.Unknown, other.Unknown)
+	if in.IsFinalized() {
+		return
+	}
+	if other.IsFinalized() {
+		in.Finalize(other.FinalScriptSig)
+		return
+	}
+
+	for pubKey, sig := range other.PartialSigs {
+		if _, ok := in.PartialSigs[pubKey]; !ok {
+			in.PartialSigs[pubKey] = sig
+		}
+	}
+	if in.SigHashType == 0 {
+		in.SigHashType = other.SigHashType
+	}
+	if in.RedeemScript == nil {
+		in.RedeemScript = other.RedeemScript
+	}
+}
+
+func (out *Output) merge(other *Output) {
+	if out.RedeemScript == nil {
+		out.RedeemScript = other.RedeemScript
+	}
+	mergeUnknown(out.Unknown, other.Unknown)
+}
+
+func mergeUnknown(unknown map[string][]byte, other map[string][]byte) {
+	for key, value := range other {
+		if _, ok := unknown[key]; !ok {
+			unknown[key] = value
+		}
+	}
+}
+
+// Combine merges partially signed transactions of the same transaction into
+// a new one.
+func Combine(psbts []*PSBT) (*PSBT, error) {
+	if len(psbts) == 0 {
+		return nil, errors.New("No PSBTs to combine")
+	}
+	var buf bytes.Buffer
+	if err := psbts[0].Serialize(&buf); err != nil {
+		return nil, err
+	}
+	merged := &PSBT{}
+	if err := merged.Unserialize(&buf); err != nil {
+		return nil, err
+	}
+	for _, p := range psbts[1:] {
+		if err := merged.Merge(p); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+// DecodeBase64 returns the partially signed transaction encoded in base64
+// by str.
+func DecodeBase64(str string) (*PSBT, error) {
+	data, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid base64")
+	}
+	reader := bytes.NewReader(data)
+	p := &PSBT{}
+	if err := p.Unserialize(reader); err != nil {
+		return nil, err
+	}
+	if reader.Len() != 0 {
+		return nil, errors.New("extra data after PSBT")
+	}
+	return p, nil
+}
+
+// EncodeBase64 returns p serialized and encoded in base64.
+func (p *PSBT) EncodeBase64() (string, error) {
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func (p *PSBT) Serialize(writer io.Writer) error {
+	if _, err := writer.Write(magic); err != nil {
+		return err
+	}
+
+	var txBuf bytes.Buffer
+	if err := p.Tx.Serialize(&txBuf); err != nil {
+		return err
+	}
+	if err := writeRecord(writer, []byte{GlobalUnsignedTx}, txBuf.Bytes()); err != nil {
+		return err
+	}
+	if err := writeUnknown(writer, p.Unknown); err != nil {
+		return err
+	}
+
+	for _, in := range p.Inputs {
+		if err := in.serialize(writer); err != nil {
+			return err
+		}
+	}
+	for _, out := range p.Outputs {
+		if err := out.serialize(writer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (in *Input) serialize(writer io.Writer) error {
+	if in.UTXO != nil {
+		var buf bytes.Buffer
+		if err := in.UTXO.Serialize(&buf); err != nil {
+			return err
+		}
+		if err := writeRecord(writer, []byte{InUTXO}, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	for _, pubKey := range sortedKeys(in.PartialSigs) {
+		key := append([]byte{InPartialSig}, pubKey...)
+		if err := writeRecord(writer, key, in.PartialSigs[pubKey]); err != nil {
+			return err
+		}
+	}
+	if in.SigHashType != 0 {
+		var value [4]byte
+		binary.LittleEndian.PutUint32(value[:], in.SigHashType)
+		if err := writeRecord(writer, []byte{InSigHash}, value[:]); err != nil {
+			return err
+		}
+	}
+	if in.RedeemScript != nil {
+		if err := writeRecord(writer, []byte{InRedeemScript}, in.RedeemScript.GetData()); err != nil {
+			return err
+		}
+	}
+	if in.FinalScriptSig != nil {
+		if err := writeRecord(writer, []byte{InScriptSig}, in.FinalScriptSig.GetData()); err != nil {
+			return err
+		}
+	}
+
+	return writeUnknown(writer, in.Unknown)
+}
+
+func (out *Output) serialize(writer io.Writer) error {
+	if out.RedeemScript != nil {
+		if err := writeRecord(writer, []byte{OutRedeemScript}, out.RedeemScript.GetData()); err != nil {
+			return err
+		}
+	}
+	return writeUnknown(writer, out.Unknown)
+}
+
+func (p *PSBT) Unserialize(reader io.Reader) error {
+	var head [5]byte
+	if _, err := io.ReadFull(reader, head[:]); err != nil {
+		return err
+	}
+	if !bytes.Equal(head[:], magic) {
+		return ErrInvalidMagic
+	}
+
+	p.Tx = nil
+	p.Unknown = make(map[string][]byte)
+	err := readMap(reader, func(key []byte, value []byte) error {
+		if key[0] != GlobalUnsignedTx {
+			return addUnknown(p.Unknown, key, value)
+		}
+		if p.Tx != nil {
+			return errors.New("Duplicate Key, unsigned tx already provided")
+		}
+		if len(key) != 1 {
+			return errors.Wrap(ErrUnexpectedKeySize, "global unsigned tx")
+		}
+		transaction := tx.NewEmptyTx()
+		if err := transaction.Unserialize(bytes.NewReader(value)); err != nil {
+			return err
+		}
+		if int(transaction.SerializeSize()) != len(value) {
+			return errors.New("extra data after the unsigned tx")
+		}
+		p.Tx = transaction
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if p.Tx == nil {
+		return ErrNoUnsignedTx
+	}
+	for _, in := range p.Tx.GetIns() {
+		if in.GetScriptSig() != nil && in.GetScriptSig().Size() != 0 {
+			return ErrNotUnsignedTx
+		}
+	}
+
+	p.Inputs = make([]*Input, p.Tx.GetInsCount())
+	for i := range p.Inputs {
+		p.Inputs[i] = newInput()
+		if err := p.Inputs[i].unserialize(reader); err != nil {
+			if isEOF(err) {
+				return ErrInputsMismatch
+			}
+			return err
+		}
+	}
+	p.Outputs = make([]*Output, p.Tx.GetOutsCount())
+	for i := range p.Outputs {
+		p.Outputs[i] = newOutput()
+		if err := p.Outputs[i].unserialize(reader); err != nil {
+			if isEOF(err) {
+				return ErrOutputsMismatch
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (in *Input) unserialize(reader io.Reader) error {
+	return readMap(reader, func(key []byte, value []byte) error {
+		switch key[0] {
+		case InUTXO:
+			if in.UTXO != nil {
+				return errors.New("Duplicate Key, input utxo already provided")
+			}
+			if len(key) != 1 {
+				return errors.Wrap(ErrUnexpectedKeySize, "input utxo")
+			}
+			utxo := txout.NewTxOut(0, nil)
+			if err := utxo.Unserialize(bytes.NewReader(value)); err != nil {
+				return err
+			}
+			in.UTXO = utxo
+
+		case InPartialSig:
+			pubKey := key[1:]
+			if len(pubKey) != 33 && len(pubKey) != 65 {
+				return errors.Wrap(ErrUnexpectedKeySize, "partial signature pubkey")
+			}
+			if _, err := crypto.ParsePubKey(pubKey); err != nil {
+				return ErrInvalidPubKey
+			}
+			if _, ok := in.PartialSigs[string(pubKey)]; ok {
+				return errors.New("Duplicate Key, input partial signature for pubkey already provided")
+			}
+			in.PartialSigs[string(pubKey)] = value
+
+		case InSigHash:
+			if in.SigHashType != 0 {
+				return errors.New("Duplicate Key, input sighash type already provided")
+			}
+			if len(key) != 1 || len(value) != 4 {
+				return errors.Wrap(ErrUnexpectedKeySize, "input sighash type")
+			}
+			in.SigHashType = binary.LittleEndian.Uint32(value)
+
+		case InRedeemScript:
+			if in.RedeemScript != nil {
+				return errors.New("Duplicate Key, input redeemScript already provided")
+			}
+			if len(key) != 1 {
+				return errors.Wrap(ErrUnexpectedKeySize, "input redeemScript")
+			}
+			in.RedeemScript = script.NewScriptRaw(value)
+
+		case InScriptSig:
+			if in.FinalScriptSig != nil {
+				return errors.New("Duplicate Key, input final scriptSig already provided")
+			}
+			if len(key) != 1 {
+				return errors.Wrap(ErrUnexpectedKeySize, "input final scriptSig")
+			}
+			in.FinalScriptSig = script.NewScriptRaw(value)
+
+		default:
+			return addUnknown(in.Unknown, key, value)
+		}
+		return nil
+	})
+}
+
+func (out *Output) unserialize(reader io.Reader) error {
+	return readMap(reader, func(key []byte, value []byte) error {
+		if key[0] != OutRedeemScript {
+			return addUnknown(out.Unknown, key, value)
+		}
+		if out.RedeemScript != nil {
+			return errors.New("Duplicate Key, output redeemScript already provided")
+		}
+		if len(key) != 1 {
+			return errors.Wrap(ErrUnexpectedKeySize, "output redeemScript")
+		}
+		out.RedeemScript = script.NewScriptRaw(value)
+		return nil
+	})
+}
+
+// readMap calls handleRecord for each record of the map read from reader,
+// up to the separator ending it.
+func readMap(reader io.Reader, handleRecord func(key []byte, value []byte) error) error {
+	for {
+		key, err := util.ReadVarBytes(reader, script.MaxMessagePayload, "psbt key")
+		if err != nil {
+			return err
+		}
+		if len(key) == 0 {
+			// The length of the separator is read as the one of an
+			// empty key.
+			return nil
+		}
+		value, err := util.ReadVarBytes(reader, script.MaxMessagePayload, "psbt value")
+		if err != nil {
+			return err
+		}
+		if err := handleRecord(key, value); err != nil {
+			return err
+		}
+	}
+}
+
+func writeRecord(writer io.Writer, key []byte, value []byte) error {
+	if err := util.WriteVarBytes(writer, key); err != nil {
+		return err
+	}
+	return util.WriteVarBytes(writer, value)
+}
+
+// writeUnknown writes the records of unknown followed by the separator.
+func writeUnknown(writer io.Writer, unknown map[string][]byte) error {
+	for _, key := range sortedKeys(unknown) {
+		if err := writeRecord(writer, []byte(key), unknown[key]); err != nil {
+			return err
+		}
+	}
+	_, err := writer.Write([]byte{separator})
+	return err
+}
+
+func addUnknown(unknown map[string][]byte, key []byte, value []byte) error {
+	if _, ok := unknown[string(key)]; ok {
+		return errors.New("Duplicate Key, key for unknown value already provided")
+	}
+	unknown[string(key)] = value
+	return nil
+}
+
+func sortedKeys(records map[string][]byte) []string {
+	keys := make([]string, 0, len(records))
+	for key := range records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isEOF(err error) bool {
+	err = errors.Cause(err)
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
+func cloneTx(transaction *tx.Tx) (*tx.Tx, error) {
+	var buf bytes.Buffer
+	if err := transaction.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	clone := tx.NewEmptyTx()
+	if err := clone.Unserialize(&buf); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
@@ -0,0 +1,176 @@
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPubKey is the compressed generator point.
+var testPubKey, _ = hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+
+func newTestTx() *tx.Tx {
+	transaction := tx.NewTx(0, tx.DefaultVersion)
+	transaction.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.HashOne, 0), script.NewEmptyScript(),
+		script.SequenceFinal))
+	transaction.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.HashOne, 1), script.NewEmptyScript(),
+		script.SequenceFinal))
+	transaction.AddTxOut(txout.NewTxOut(9000, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+	return transaction
+}
+
+func TestNew(t *testing.T) {
+	transaction := newTestTx()
+	p, err := New(transaction)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(p.Inputs))
+	assert.Equal(t, 1, len(p.Outputs))
+	assert.False(t, p.IsComplete())
+
+	transaction.UpdateInScript(0, script.NewScriptRaw([]byte{opcodes.OP_TRUE}))
+	_, err = New(transaction)
+	assert.Equal(t, ErrNotUnsignedTx, err)
+}
+
+func TestSerialize(t *testing.T) {
+	crypto.InitSecp256()
+	p, _ := New(newTestTx())
+	p.Unknown["\x0fglobal"] = []byte{1, 2}
+	p.Inputs[0].UTXO = txout.NewTxOut(10000, script.NewScriptRaw([]byte{opcodes.OP_TRUE}))
+	p.Inputs[0].PartialSigs[string(testPubKey)] = []byte{0x30, 0x41}
+	p.Inputs[0].SigHashType = 0x41
+	p.Inputs[0].RedeemScript = script.NewScriptRaw([]byte{opcodes.OP_TRUE})
+	p.Inputs[1].FinalScriptSig = script.NewScriptRaw([]byte{opcodes.OP_1})
+	p.Inputs[1].Unknown["\x0finput"] = []byte{3}
+	p.Outputs[0].RedeemScript = script.NewScriptRaw([]byte{opcodes.OP_TRUE})
+
+	encoded, err := p.EncodeBase64()
+	assert.NoError(t, err)
+	decoded, err := DecodeBase64(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, p.Tx.GetHash(), decoded.Tx.GetHash())
+	assert.Equal(t, p.Unknown, decoded.Unknown)
+	assert.True(t, p.Inputs[0].UTXO.IsEqual(decoded.Inputs[0].UTXO))
+	assert.Equal(t, p.Inputs[0].PartialSigs, decoded.Inputs[0].PartialSigs)
+	assert.Equal(t, uint32(0x41), decoded.Inputs[0].SigHashType)
+	assert.True(t, p.Inputs[0].RedeemScript.IsEqual(decoded.Inputs[0].RedeemScript))
+	assert.Nil(t, decoded.Inputs[1].UTXO)
+	assert.True(t, decoded.Inputs[1].IsFinalized())
+	assert.Equal(t, p.Inputs[1].Unknown, decoded.Inputs[1].Unknown)
+	assert.True(t, p.Outputs[0].RedeemScript.IsEqual(decoded.Outputs[0].RedeemScript))
+
+	again, err := decoded.EncodeBase64()
+	assert.NoError(t, err)
+	assert.Equal(t, encoded, again)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	crypto.InitSecp256()
+	p, _ := New(newTestTx())
+	var buf bytes.Buffer
+	assert.NoError(t, p.Serialize(&buf))
+	data := buf.Bytes()
+
+	_, err := DecodeBase64("not base64!")
+	assert.Error(t, err)
+
+	badMagic := append([]byte{'p', 's', 'b', 't', 0x00}, data[5:]...)
+	_, err = DecodeBase64(base64.StdEncoding.EncodeToString(badMagic))
+	assert.Equal(t, ErrInvalidMagic, err)
+
+	// The magic followed by an empty global map.
+	_, err = DecodeBase64(base64.StdEncoding.EncodeToString(append(append([]byte{}, magic...), separator)))
+	assert.Equal(t, ErrNoUnsignedTx, err)
+
+	// The last output map is missing.
+	_, err = DecodeBase64(base64.StdEncoding.EncodeToString(data[:len(data)-1]))
+	assert.Equal(t, ErrOutputsMismatch, err)
+
+	// The output map is missing along with the separator of the last input.
+	_, err = DecodeBase64(base64.StdEncoding.EncodeToString(data[:len(data)-2]))
+	assert.Equal(t, ErrInputsMismatch, err)
+
+	_, err = DecodeBase64(base64.StdEncoding.EncodeToString(append(data, 0)))
+	assert.Error(t, err)
+
+	// A partial signature keyed by an invalid public key.
+	p.Inputs[0].PartialSigs[string(testPubKey[:32])] = []byte{0x30}
+	encoded, _ := p.EncodeBase64()
+	_, err = DecodeBase64(encoded)
+	assert.Error(t, err)
+}
+
+func TestMergeAndCombine(t *testing.T) {
+	crypto.InitSecp256()
+	first, _ := New(newTestTx())
+	second, _ := New(newTestTx())
+	utxo := txout.NewTxOut(10000, script.NewScriptRaw([]byte{opcodes.OP_TRUE}))
+	first.Inputs[0].UTXO = utxo
+	first.Inputs[0].PartialSigs[string(testPubKey)] = []byte{0x30, 0x41}
+	second.Inputs[0].RedeemScript = script.NewScriptRaw([]byte{opcodes.OP_TRUE})
+	second.Inputs[1].FinalScriptSig = script.NewScriptRaw([]byte{opcodes.OP_1})
+	second.Outputs[0].Unknown["\x0foutput"] = []byte{4}
+
+	combined, err := Combine([]*PSBT{first, second})
+	assert.NoError(t, err)
+	assert.True(t, utxo.IsEqual(combined.Inputs[0].UTXO))
+	assert.Equal(t, 1, len(combined.Inputs[0].PartialSigs))
+	assert.NotNil(t, combined.Inputs[0].RedeemScript)
+	assert.True(t, combined.Inputs[1].IsFinalized())
+	assert.Equal(t, second.Outputs[0].Unknown, combined.Outputs[0].Unknown)
+
+	// The partially signed transactions combined are left unchanged.
+	assert.Nil(t, first.Inputs[0].RedeemScript)
+	assert.Equal(t, 0, len(first.Outputs[0].Unknown))
+
+	// A finalized input drops the records needed to finalize it.
+	first.Inputs[0].PartialSigs[string(testPubKey)] = []byte{0x30, 0x41}
+	final, _ := New(newTestTx())
+	final.Inputs[0].FinalScriptSig = script.NewScriptRaw([]byte{opcodes.OP_1})
+	assert.NoError(t, first.Merge(final))
+	assert.True(t, first.Inputs[0].IsFinalized())
+	assert.Equal(t, 0, len(first.Inputs[0].PartialSigs))
+
+	other := newTestTx()
+	other.AddTxOut(txout.NewTxOut(1, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+	otherPSBT, _ := New(other)
+	_, err = Combine([]*PSBT{first, otherPSBT})
+	assert.Equal(t, ErrDifferentTx, err)
+}
+
+func TestFeeAndExtract(t *testing.T) {
+	crypto.InitSecp256()
+	p, _ := New(newTestTx())
+	_, ok := p.GetFee()
+	assert.False(t, ok)
+	_, err := p.Extract()
+	assert.Equal(t, ErrNotComplete, err)
+
+	for _, in := range p.Inputs {
+		in.UTXO = txout.NewTxOut(5000, script.NewScriptRaw([]byte{opcodes.OP_TRUE}))
+		in.Finalize(script.NewScriptRaw([]byte{opcodes.OP_1}))
+	}
+	fee, ok := p.GetFee()
+	assert.True(t, ok)
+	assert.Equal(t, amount.Amount(1000), fee)
+
+	signed, err := p.Extract()
+	assert.NoError(t, err)
+	for _, in := range signed.GetIns() {
+		assert.Equal(t, []byte{opcodes.OP_1}, in.GetScriptSig().GetData())
+	}
+	// The unsigned transaction is left unchanged.
+	assert.Equal(t, 0, p.Tx.GetIns()[0].GetScriptSig().Size())
+}
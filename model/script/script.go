@@ -164,6 +164,42 @@ const (
 	//
 	ScriptEnableCheckDataSig = (1 << 18)
 
+	// Are Schnorr signatures enabled for OP_CHECK(DATA)SIG(VERIFY). A 64-byte
+	// signature (65 with the hashtype) is then interpreted as Schnorr.
+	//
+	ScriptEnableSchnorr = (1 << 19)
+
+	// Is the bitfield mode of OP_CHECKMULTISIG(VERIFY) enabled. A non-null
+	// dummy element then selects the checked pubkeys and all signatures must
+	// be Schnorr.
+	//
+	ScriptEnableSchnorrMultisig = (1 << 20)
+
+	// Require the scriptSig of each input to be at least as long as its
+	// sigchecks density allows: len(scriptSig) >= 43 * sigchecks - 60.
+	//
+	ScriptVerifyInputSigChecks = (1 << 21)
+
+	// Are 64-bit script integers and OP_MUL enabled. Numeric operands may
+	// then be 8 bytes long, and arithmetic results out of that range fail.
+	//
+	ScriptEnable64BitIntegers = (1 << 22)
+
+	// Are the native introspection opcodes enabled, which push the fields of
+	// the transaction and of the outputs spent by its inputs.
+	//
+	ScriptEnableNativeIntrospection = (1 << 23)
+
+	// Are CashTokens enabled. Outputs may then carry token data, which the
+	// token introspection opcodes can inspect.
+	//
+	ScriptEnableTokens = (1 << 24)
+
+	// Is pay-to-script-hash with a 32-byte hash enabled, whose scriptPubKey is
+	// OP_HASH256 [32 byte hash] OP_EQUAL.
+	//
+	ScriptEnableP2SH32 = (1 << 25)
+
 	ScriptMaxOpReturnRelay uint = 223
 )
 
@@ -175,6 +211,7 @@ const (
 	ScriptHash
 	ScriptMultiSig
 	ScriptNullData
+	ScriptHash32
 )
 
 const (
@@ -195,7 +232,7 @@ const (
 		ScriptVerifyNullDummy | ScriptVerifySigPushOnly |
 		ScriptVerifyMinmalData | ScriptVerifyDiscourageUpgradableNops |
 		ScriptVerifyCleanStack | ScriptVerifyCheckLockTimeVerify |
-		ScriptVerifyCheckSequenceVerify | ScriptVerifyNullFail
+		ScriptVerifyCheckSequenceVerify | ScriptVerifyNullFail | ScriptVerifyInputSigChecks
 
 	//StandardNotMandatoryVerifyFlags for convenience, standard but not mandatory verify flags.
 	StandardNotMandatoryVerifyFlags uint = StandardScriptVerifyFlags & (^MandatoryScriptVerifyFlags)
@@ -521,6 +558,9 @@ func (s *Script) IsStandardScriptPubKey() (pubKeyType int, pubKeys [][]byte, isS
 	if s.IsPayToScriptHash() {
 		return ScriptHash, [][]byte{s.ParsedOpCodes[1].Data}, true
 	}
+	if s.IsPayToScriptHash32() {
+		return ScriptHash32, [][]byte{s.ParsedOpCodes[1].Data}, true
+	}
 
 	// Provably prunable, data-carrying output
 	//
@@ -643,6 +683,16 @@ func (s *Script) IsPayToScriptHash() bool {
 		s.data[22] == opcodes.OP_EQUAL
 }
 
+// IsPayToScriptHash32 returns whether the script is OP_HASH256 [32 byte hash]
+// OP_EQUAL.
+func (s *Script) IsPayToScriptHash32() bool {
+	size := len(s.data)
+	return size == 35 &&
+		s.data[0] == opcodes.OP_HASH256 &&
+		s.data[1] == 0x20 &&
+		s.data[34] == opcodes.OP_EQUAL
+}
+
 func (s *Script) IsUnspendable() bool {
 	return (s.Size() > 0 && s.data[0] == opcodes.OP_RETURN) || s.Size() > MaxScriptSize
 }
@@ -895,6 +945,38 @@ func CheckTransactionSignatureEncoding(vchSig []byte, flags uint32) error {
 		return err
 	}
 
+	return checkSigHashEncoding(vchSig, flags)
+}
+
+// CheckTransactionECDSASignatureEncoding is used where only ECDSA signatures
+// are allowed, such as the legacy mode of CHECKMULTISIG: once Schnorr is
+// enabled a 65-byte signature is rejected rather than interpreted as ECDSA.
+func CheckTransactionECDSASignatureEncoding(vchSig []byte, flags uint32) error {
+	if len(vchSig) == crypto.SchnorrSignatureSize+1 && flags&ScriptEnableSchnorr != 0 {
+		log.Debug("ScriptErrSigBadLength")
+		return errcode.New(errcode.ScriptErrSigBadLength)
+	}
+
+	return CheckTransactionSignatureEncoding(vchSig, flags)
+}
+
+// CheckTransactionSchnorrSignatureEncoding is used where only Schnorr
+// signatures are allowed, such as the bitfield mode of CHECKMULTISIG.
+func CheckTransactionSchnorrSignatureEncoding(vchSig []byte, flags uint32) error {
+	vchSigLen := len(vchSig)
+	if vchSigLen == 0 {
+		return nil
+	}
+
+	if vchSigLen != crypto.SchnorrSignatureSize+1 {
+		log.Debug("ScriptErrSigNonSchnorr")
+		return errcode.New(errcode.ScriptErrSigNonSchnorr)
+	}
+
+	return checkSigHashEncoding(vchSig, flags)
+}
+
+func checkSigHashEncoding(vchSig []byte, flags uint32) error {
 	if (flags & ScriptVerifyStrictEnc) != 0 {
 		if !crypto.IsDefineHashtypeSignature(vchSig) {
 			log.Debug("ScriptErrSigHashType")
@@ -921,6 +1003,12 @@ func CheckTransactionSignatureEncoding(vchSig []byte, flags uint32) error {
 }
 
 func checkRawSignatureEncoding(vchSig []byte, flags uint32) (bool, error) {
+	// A 64-byte signature is a Schnorr signature, which has no encoding
+	// constraints beyond its size.
+	if len(vchSig) == crypto.SchnorrSignatureSize && flags&ScriptEnableSchnorr != 0 {
+		return true, nil
+	}
+
 	if ((flags & (ScriptVerifyDersig | ScriptVerifyLowS | ScriptVerifyStrictEnc)) != 0) && !crypto.
 		IsValidSignatureEncoding(vchSig) {
 		return false, errcode.New(errcode.ScriptErrSigDer)
@@ -955,8 +1043,10 @@ func CheckPubKeyEncoding(vchPubKey []byte, flags uint32) error {
 func IsOpCodeDisabled(opCode byte, flags uint32) bool {
 	switch opCode {
 	case opcodes.OP_INVERT, opcodes.OP_2MUL, opcodes.OP_2DIV,
-		opcodes.OP_MUL, opcodes.OP_LSHIFT, opcodes.OP_RSHIFT:
+		opcodes.OP_LSHIFT, opcodes.OP_RSHIFT:
 		return true
+	case opcodes.OP_MUL:
+		return flags&ScriptEnable64BitIntegers == 0
 	default:
 		return false
 	}
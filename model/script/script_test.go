@@ -367,6 +367,23 @@ func TestScript_IsStandardScriptPubKey_ScriptHash(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestScript_IsStandardScriptPubKey_ScriptHash32(t *testing.T) {
+	hash := hexToBytes("8cc6e5ebb5c2a45ea2a8fe1d42d31fb5e3b65b6a41a7b72e77d2b1d8a3b0f5e2")
+	data := append([]byte{OP_HASH256, 0x20}, hash...)
+	testScript := NewScriptRaw(append(data, OP_EQUAL))
+
+	assert.True(t, testScript.IsPayToScriptHash32())
+	assert.False(t, testScript.IsPayToScriptHash())
+
+	pubKeyType, pubKeys, isStandard := testScript.IsStandardScriptPubKey()
+	assert.Equal(t, ScriptHash32, pubKeyType)
+	assert.Equal(t, [][]byte{hash}, pubKeys)
+	assert.True(t, isStandard)
+
+	withoutEqual := NewScriptRaw(data)
+	assert.False(t, withoutEqual.IsPayToScriptHash32())
+}
+
 func TestScript_IsStandardScriptPubKey_NotStandard(t *testing.T) {
 	var wantPubKeys [][]byte
 	wantPubKeyType := ScriptNonStandard
@@ -1,12 +1,17 @@
 package script
 
 import (
+	"math"
+
 	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
 )
 
 const (
 	DefaultMaxNumSize = 4
+	// MaxNumSize64Bit is the size of the numeric operands once 64-bit
+	// script integers are enabled.
+	MaxNumSize64Bit = 8
 
 	MaxInt32 = 1<<31 - 1
 	MinInt32 = -1 << 31
@@ -139,6 +144,55 @@ func GetScriptNum(vch []byte, requireMinimal bool, maxNumSize int) (scriptNum *S
 	return
 }
 
+// GetMaxNumSize returns the maximum number of bytes of the numeric operands
+// of the script opcodes under flags.
+func GetMaxNumSize(flags uint32) int {
+	if flags&ScriptEnable64BitIntegers != 0 {
+		return MaxNumSize64Bit
+	}
+	return DefaultMaxNumSize
+}
+
+// isValidInt64 reports whether v can be encoded in 8 bytes. The most negative
+// int64 needs a 9th byte for its sign, so it is out of the script range.
+func isValidInt64(v int64) bool {
+	return v != math.MinInt64
+}
+
+// SafeAdd returns a + b, and false when the sum is out of the 64-bit script
+// number range.
+func SafeAdd(a, b int64) (int64, bool) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, false
+	}
+	return a + b, isValidInt64(a + b)
+}
+
+// SafeSub returns a - b, and false when the difference is out of the 64-bit
+// script number range.
+func SafeSub(a, b int64) (int64, bool) {
+	if !isValidInt64(b) {
+		return 0, false
+	}
+	return SafeAdd(a, -b)
+}
+
+// SafeMul returns a * b, and false when the product is out of the 64-bit
+// script number range.
+func SafeMul(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	if !isValidInt64(a) || !isValidInt64(b) {
+		return 0, false
+	}
+	product := a * b
+	if product/b != a {
+		return 0, false
+	}
+	return product, isValidInt64(product)
+}
+
 func (n *ScriptNum) ToInt32() int32 {
 	if n.Value > MaxInt32 {
 		return MaxInt32
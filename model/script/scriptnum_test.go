@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"github.com/copernet/copernicus/errcode"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
 )
 
@@ -118,6 +119,11 @@ func TestGetScriptNum(t *testing.T) {
 		{hexToBytes("00000800"), ScriptNum{524288}, DefaultMaxNumSize, false, nil},
 		{hexToBytes("00007000"), ScriptNum{7340032}, DefaultMaxNumSize, false, nil},
 		{hexToBytes("0009000100"), ScriptNum{16779520}, 5, false, nil},
+
+		// 64-bit script integers.
+		{hexToBytes("ffffffffffffff7f"), ScriptNum{9223372036854775807}, MaxNumSize64Bit, true, nil},
+		{hexToBytes("ffffffffffffffff"), ScriptNum{-9223372036854775807}, MaxNumSize64Bit, true, nil},
+		{hexToBytes("000000000000008000"), ScriptNum{0}, MaxNumSize64Bit, true, errNumOverflow},
 	}
 	for _, test := range tests {
 		value := test
@@ -354,3 +360,41 @@ func TestMinimallyEncode(t *testing.T) {
 		assert.Equal(t, value.want, result, hex.EncodeToString(value.in))
 	}
 }
+
+func TestGetMaxNumSize(t *testing.T) {
+	assert.Equal(t, DefaultMaxNumSize, GetMaxNumSize(ScriptVerifyNone))
+	assert.Equal(t, MaxNumSize64Bit, GetMaxNumSize(ScriptEnable64BitIntegers))
+}
+
+func TestSafeArithmetic(t *testing.T) {
+	const maxNum = math.MaxInt64
+	tests := []struct {
+		name   string
+		op     func(a, b int64) (int64, bool)
+		a, b   int64
+		result int64
+		ok     bool
+	}{
+		{"add", SafeAdd, 2, 3, 5, true},
+		{"add max", SafeAdd, maxNum - 1, 1, maxNum, true},
+		{"add overflow", SafeAdd, maxNum, 1, 0, false},
+		{"add to min", SafeAdd, -maxNum, -1, 0, false},
+		{"add underflow", SafeAdd, -maxNum, -maxNum, 0, false},
+		{"sub", SafeSub, 2, 3, -1, true},
+		{"sub limits", SafeSub, maxNum, maxNum, 0, true},
+		{"sub overflow", SafeSub, maxNum, -1, 0, false},
+		{"sub to min", SafeSub, -maxNum, 1, 0, false},
+		{"mul", SafeMul, -3, 7, -21, true},
+		{"mul zero", SafeMul, 0, maxNum, 0, true},
+		{"mul max", SafeMul, maxNum, -1, -maxNum, true},
+		{"mul overflow", SafeMul, 1 << 32, 1 << 32, 0, false},
+		{"mul to min", SafeMul, -(1 << 62), 2, 0, false},
+	}
+	for _, test := range tests {
+		result, ok := test.op(test.a, test.b)
+		assert.Equal(t, test.ok, ok, test.name)
+		if ok {
+			assert.Equal(t, test.result, result, test.name)
+		}
+	}
+}
@@ -71,6 +71,9 @@ const (
 	/*MaxStandardTxSigOps the maximum number of sigops we're willing to relay/mine in a single tx */
 	MaxStandardTxSigOps = uint(consensus.MaxTxSigOpsCount / 5)
 
+	/*MaxStandardTxSigChecks the maximum number of sigchecks we're willing to relay/mine in a single tx */
+	MaxStandardTxSigChecks = 3000
+
 	/*DefaultMaxMemPoolSize default for -maxMemPool, maximum megabytes of memPool memory usage */
 	//DefaultMaxMemPoolSize uint = 300
 
@@ -472,7 +475,7 @@ func (tx *Tx) GetValueOut() amount.Amount {
 }
 
 func (tx *Tx) SignStep(nIn int, keyStore *crypto.KeyStore, redeemScript *script.Script, hashType uint32,
-	scriptPubKey *script.Script, value amount.Amount) (sigData [][]byte, err error) {
+	scriptPubKey *script.Script, value amount.Amount, tokenData *txout.TokenData) (sigData [][]byte, err error) {
 	pubKeyType, pubKeys, isStandard := scriptPubKey.IsStandardScriptPubKey()
 	if !isStandard || pubKeyType == script.ScriptNonStandard || pubKeyType == script.ScriptNullData {
 		log.Debug("SignStep IsStandardScriptPubKey err")
@@ -499,7 +502,7 @@ func (tx *Tx) SignStep(nIn int, keyStore *crypto.KeyStore, redeemScript *script.
 		if keyPair == nil {
 			return nil, errors.New("private key not found")
 		}
-		signature, err := tx.signOne(scriptPubKeySign, keyPair.GetPrivateKey(), hashType, nIn, value)
+		signature, err := tx.signOne(scriptPubKeySign, keyPair.GetPrivateKey(), hashType, nIn, value, tokenData)
 		if err != nil {
 			return nil, err
 		}
@@ -512,7 +515,7 @@ func (tx *Tx) SignStep(nIn int, keyStore *crypto.KeyStore, redeemScript *script.
 		if keyPair == nil {
 			return nil, errors.New("private key not found")
 		}
-		signature, err := tx.signOne(scriptPubKeySign, keyPair.GetPrivateKey(), hashType, nIn, value)
+		signature, err := tx.signOne(scriptPubKeySign, keyPair.GetPrivateKey(), hashType, nIn, value, tokenData)
 		if err != nil {
 			return nil, err
 		}
@@ -532,7 +535,7 @@ func (tx *Tx) SignStep(nIn int, keyStore *crypto.KeyStore, redeemScript *script.
 				log.Info("Private key not found:%s", hex.EncodeToString(pubKey))
 				continue
 			}
-			signature, err := tx.signOne(scriptPubKeySign, keyPair.GetPrivateKey(), hashType, nIn, value)
+			signature, err := tx.signOne(scriptPubKeySign, keyPair.GetPrivateKey(), hashType, nIn, value, tokenData)
 			if err != nil {
 				log.Info("getSignatureData error:%s", err.Error())
 				continue
@@ -562,9 +565,14 @@ func (tx *Tx) SignStep(nIn int, keyStore *crypto.KeyStore, redeemScript *script.
 }
 
 func (tx *Tx) signOne(scriptPubKey *script.Script, privateKey *crypto.PrivateKey, hashType uint32,
-	nIn int, value amount.Amount) (signature *crypto.Signature, err error) {
+	nIn int, value amount.Amount, tokenData *txout.TokenData) (signature *crypto.Signature, err error) {
 
-	hash, err := SignatureHash(tx, scriptPubKey, hashType, nIn, value, script.ScriptEnableSigHashForkID)
+	// The key pairs of a locked key store only hold the public keys.
+	if privateKey == nil {
+		return nil, crypto.ErrKeyStoreLocked
+	}
+	hash, err := SignatureHashWithTokenData(tx, scriptPubKey, hashType, nIn, value, tokenData,
+		script.ScriptEnableSigHashForkID)
 	if err != nil {
 		return nil, err
 	}
@@ -660,6 +668,14 @@ func (tx *Tx) GetOuts() []*txout.TxOut {
 	return tx.outs
 }
 
+// ReadTokenData reads the token data of the outputs of the transaction, which
+// is done once tokens are enabled.
+func (tx *Tx) ReadTokenData() {
+	for _, out := range tx.outs {
+		out.ReadTokenData()
+	}
+}
+
 func (tx *Tx) InsertTxOut(pos int, txOut *txout.TxOut) {
 	if pos > len(tx.outs) {
 		tx.outs = append(tx.outs, txOut)
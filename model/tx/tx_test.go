@@ -832,7 +832,7 @@ func TestSignStepP2PKH(t *testing.T) {
 	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
 
 	// Single signature case:
-	sigData, err := v.spender.SignStep(0, v.keyStore, nil, hashType, scriptPubKey, 1)
+	sigData, err := v.spender.SignStep(0, v.keyStore, nil, hashType, scriptPubKey, 1, nil)
 	assert.Nil(t, err)
 	// <signature> <pubkey>
 	assert.Equal(t, len(sigData), 2)
@@ -868,7 +868,7 @@ func TestSignStepP2SH(t *testing.T) {
 	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
 
 	// Single signature case:
-	sigData, err := v.spender.SignStep(0, v.keyStore, pubKey, hashType, scriptPubKey, 1)
+	sigData, err := v.spender.SignStep(0, v.keyStore, pubKey, hashType, scriptPubKey, 1, nil)
 	assert.Nil(t, err)
 	// <signature> <redeemscript>
 	assert.Equal(t, len(sigData), 2)
@@ -902,7 +902,7 @@ func TestSignStepMultiSig(t *testing.T) {
 	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
 
 	// Multiple signature case:
-	sigData, err := v.spender.SignStep(0, v.keyStore, nil, hashType, scriptPubKey, 1)
+	sigData, err := v.spender.SignStep(0, v.keyStore, nil, hashType, scriptPubKey, 1, nil)
 	assert.Nil(t, err)
 	// <OP_0> <signature0> ... <signatureM>
 	assert.Equal(t, len(sigData), 3)
@@ -15,6 +15,14 @@ import (
 
 func SignatureHash(transaction *Tx, s *script.Script, hashType uint32, nIn int,
 	money amount.Amount, flags uint32) (result util.Hash, err error) {
+	return SignatureHashWithTokenData(transaction, s, hashType, nIn, money, nil, flags)
+}
+
+// SignatureHashWithTokenData is SignatureHash for an input spending an output
+// that carries tokenData, which may be nil. With SigHashForkID, the token
+// prefix of the spent output is signed just before the scriptCode.
+func SignatureHashWithTokenData(transaction *Tx, s *script.Script, hashType uint32, nIn int,
+	money amount.Amount, tokenData *txout.TokenData, flags uint32) (result util.Hash, err error) {
 
 	var hashBuffer bytes.Buffer
 	var sigHashAnyOneCanPay = false
@@ -75,6 +83,13 @@ func SignatureHash(transaction *Tx, s *script.Script, hashType uint32, nIn int,
 			log.Error("txSignature:Previous OutPoint encode failed: %v", err)
 			return util.HashOne, err
 		}
+		if tokenData != nil {
+			err = tokenData.Encode(&hashBuffer)
+			if err != nil {
+				log.Error("txSignature:encode tokenData failed: %v", err)
+				return util.HashOne, err
+			}
+		}
 		err = s.Serialize(&hashBuffer)
 		if err != nil {
 			log.Error("txSignature:serialize hashBuffer failed: %v", err)
@@ -321,3 +336,19 @@ func CheckSig(signHash util.Hash, vchSigIn []byte, vchPubKey []byte) bool {
 	ret := sign.Verify(signHash.GetCloneBytes(), publicKey)
 	return ret
 }
+
+func CheckSchnorrSig(signHash util.Hash, vchSigIn []byte, vchPubKey []byte) bool {
+	if len(vchPubKey) == 0 {
+		return false
+	}
+	if len(vchSigIn) != crypto.SchnorrSignatureSize {
+		return false
+	}
+	publicKey, err := crypto.ParsePubKey(vchPubKey)
+	if err != nil {
+		return false
+	}
+
+	ret, _ := publicKey.VerifySchnorr(&signHash, vchSigIn)
+	return ret
+}
@@ -2,6 +2,7 @@ package tx
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"github.com/stretchr/testify/assert"
 	"math"
@@ -770,6 +771,53 @@ func Test_SignatureHash(t *testing.T) {
 	assert.Equal(t, "29326e5bd1237ef68ea1ceaad654d08dc987f568b175aedf3054e17d307359a1", txHash.String())
 }
 
+func Test_SignatureHashWithTokenData(t *testing.T) {
+	preTestTx := testTxs[0]
+	testTx := testTxs[1]
+	scriptCode := preTestTx.tx.GetTxOut(0).GetScriptPubKey()
+	value := preTestTx.tx.GetTxOut(0).GetValue()
+	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
+	flags := uint32(script.ScriptEnableSigHashForkID)
+	tokenData := &txout.TokenData{Category: util.Hash{1}, Amount: 1000, HasNFT: true,
+		Capability: txout.TokenCapabilityMinting, Commitment: []byte{0xca, 0xfe}}
+
+	withoutToken, err := SignatureHash(&testTx.tx, scriptCode, hashType, 0, value, flags)
+	assert.NoError(t, err)
+	txHash, err := SignatureHashWithTokenData(&testTx.tx, scriptCode, hashType, 0, value, nil, flags)
+	assert.NoError(t, err)
+	assert.Equal(t, withoutToken, txHash)
+
+	// The token prefix is signed between the outpoint and the scriptCode.
+	var preimage bytes.Buffer
+	hashPrevouts := GetPreviousOutHash(&testTx.tx)
+	hashSequence := GetSequenceHash(&testTx.tx)
+	hashOutputs, _ := GetOutputsHash(testTx.tx.GetOuts())
+	in := testTx.tx.GetIns()[0]
+	assert.NoError(t, util.BinarySerializer.PutUint32(&preimage, binary.LittleEndian, uint32(testTx.tx.GetVersion())))
+	preimage.Write(hashPrevouts[:])
+	preimage.Write(hashSequence[:])
+	assert.NoError(t, in.PreviousOutPoint.Encode(&preimage))
+	assert.NoError(t, tokenData.Encode(&preimage))
+	assert.NoError(t, scriptCode.Serialize(&preimage))
+	assert.NoError(t, util.BinarySerializer.PutUint64(&preimage, binary.LittleEndian, uint64(value)))
+	assert.NoError(t, util.BinarySerializer.PutUint32(&preimage, binary.LittleEndian, in.Sequence))
+	preimage.Write(hashOutputs[:])
+	assert.NoError(t, util.BinarySerializer.PutUint32(&preimage, binary.LittleEndian, testTx.tx.GetLockTime()))
+	assert.NoError(t, util.BinarySerializer.PutUint32(&preimage, binary.LittleEndian, hashType))
+
+	txHash, err = SignatureHashWithTokenData(&testTx.tx, scriptCode, hashType, 0, value, tokenData, flags)
+	assert.NoError(t, err)
+	assert.Equal(t, util.DoubleSha256Hash(preimage.Bytes()), txHash)
+	assert.NotEqual(t, withoutToken, txHash)
+
+	// The legacy signature hash does not sign the token data.
+	withoutToken, err = SignatureHash(&testTx.tx, scriptCode, crypto.SigHashAll, 0, value, 0)
+	assert.NoError(t, err)
+	txHash, err = SignatureHashWithTokenData(&testTx.tx, scriptCode, crypto.SigHashAll, 0, value, tokenData, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, withoutToken, txHash)
+}
+
 func Test_GetPreviousOutHash(t *testing.T) {
 	h := GetPreviousOutHash(&testTxs[0].tx)
 	assert.Equal(t, "284316979cb69928ffb64d41cc0d1f4491ccc094afbaef034daaefa3bec2263a", h.String())
@@ -808,3 +856,30 @@ func Test_CheckSig(t *testing.T) {
 	}
 	assert.False(t, CheckSig(h, sigIn, privateKey.PubKey().ToBytes()))
 }
+
+func Test_CheckSchnorrSig(t *testing.T) {
+	crypto.InitSecp256()
+
+	privateKey, err := crypto.DecodePrivateKey("L4rK1yDtCWekvXuE6oXD9jCYfFNV2cWRpVuPLBcCU2z8TrisoyY1")
+	if err != nil {
+		t.Error(err)
+	}
+	pubKey := privateKey.PubKey().ToBytes()
+
+	h := util.DoubleSha256Hash([]byte{0, 1, 2})
+	sig, err := privateKey.SignSchnorr(h[:])
+	assert.NoError(t, err)
+	assert.True(t, CheckSchnorrSig(h, sig, pubKey))
+
+	assert.False(t, CheckSchnorrSig(h, sig, []byte{}))
+	assert.False(t, CheckSchnorrSig(h, sig[:63], pubKey))
+	assert.False(t, CheckSchnorrSig(h, sig, []byte{0, 1, 2}))
+
+	other := util.DoubleSha256Hash([]byte{3})
+	assert.False(t, CheckSchnorrSig(other, sig, pubKey))
+
+	// a valid ECDSA signature is not accepted
+	ecdsaSig, err := privateKey.Sign(h[:])
+	assert.NoError(t, err)
+	assert.False(t, CheckSchnorrSig(h, ecdsaSig.Serialize(), pubKey))
+}
@@ -281,9 +281,29 @@ func (tc *TxoutCompressor) Serialize(w io.Writer) error {
 	if err := util.WriteVarLenInt(w, count); err != nil {
 		return err
 	}
+	if tc.txout.tokenData != nil {
+		return tc.serializeTokenScript(w)
+	}
 	return tc.sc.Serialize(w)
 }
 
+// serializeTokenScript writes the token prefix and the scriptPubKey of an
+// output carrying token data uncompressed, as a script that is not special.
+// It is unserialized as the raw scriptPubKey field, whose token data is read
+// again when the coin is spent.
+func (tc *TxoutCompressor) serializeTokenScript(w io.Writer) error {
+	so := *tc.sc.sp
+	size := tc.txout.tokenData.EncodeSize() + uint32(so.Size()) + numSpecialScripts
+	if err := util.WriteVarLenInt(w, uint64(size)); err != nil {
+		return err
+	}
+	if err := tc.txout.tokenData.Encode(w); err != nil {
+		return err
+	}
+	_, err := w.Write(so.GetData())
+	return err
+}
+
 func (tc *TxoutCompressor) Unserialize(r io.Reader) error {
 	if tc == nil {
 		return ErrCompress
@@ -294,5 +314,6 @@ func (tc *TxoutCompressor) Unserialize(r io.Reader) error {
 		return err
 	}
 	tc.txout.value = DecompressAmount(count)
+	tc.txout.tokenData = nil
 	return tc.sc.Unserialize(r)
 }
@@ -299,3 +299,25 @@ func Test_TxoutCompressor_Unserialize(t *testing.T) {
 	txoutCompressor.Serialize(&buf)
 	assert.NoError(t, txoutCompressor.Unserialize(&buf))
 }
+
+func Test_TxoutCompressor_TokenData(t *testing.T) {
+	out := NewTxOut(1000, getTestScript())
+	out.SetTokenData(&TokenData{Category: util.Hash{1, 2, 3}, Amount: 100000, HasNFT: true,
+		Capability: TokenCapabilityMutable, Commitment: []byte{0xca, 0xfe}})
+
+	var buf bytes.Buffer
+	assert.NoError(t, NewTxoutCompressor(out).Serialize(&buf))
+
+	got := NewTxOut(0, nil)
+	assert.NoError(t, NewTxoutCompressor(got).Unserialize(&buf))
+	assert.Nil(t, got.GetTokenData())
+	got.ReadTokenData()
+	assert.True(t, out.IsEqual(got))
+	assert.Equal(t, out.GetTokenData(), got.GetTokenData())
+
+	// Reusing the output for a coin without token data drops the previous one.
+	buf.Reset()
+	assert.NoError(t, NewTxoutCompressor(NewTxOut(1000, getTestScript())).Serialize(&buf))
+	assert.NoError(t, NewTxoutCompressor(got).Unserialize(&buf))
+	assert.Nil(t, got.GetTokenData())
+}
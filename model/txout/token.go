@@ -0,0 +1,215 @@
+package txout
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	// PrefixToken is the first byte of the scriptPubKey field of an output
+	// that carries token data. The token data follows it, then the actual
+	// scriptPubKey.
+	PrefixToken = 0xef
+
+	// MaxTokenCommitmentLength is the maximum length of a NFT commitment.
+	MaxTokenCommitmentLength = 40
+)
+
+// The bits of the token bitfield. Its low nibble is the NFT capability.
+const (
+	tokenReserved      = 0x80
+	tokenHasCommitment = 0x40
+	tokenHasNFT        = 0x20
+	tokenHasAmount     = 0x10
+	tokenCapability    = 0x0f
+)
+
+// The capabilities of a NFT.
+const (
+	TokenCapabilityNone = iota
+	TokenCapabilityMutable
+	TokenCapabilityMinting
+)
+
+var ErrInvalidTokenPrefix = errors.New("invalid token prefix")
+
+// TokenData is the CashTokens data of an output: an amount of fungible tokens
+// and/or a non-fungible token (NFT), both of the token category Category.
+type TokenData struct {
+	Category util.Hash
+	// Amount is the amount of fungible tokens, 0 if there are none.
+	Amount     int64
+	HasNFT     bool
+	Capability byte
+	Commitment []byte
+}
+
+func (td *TokenData) bitfield() byte {
+	var bits byte
+	if len(td.Commitment) > 0 {
+		bits |= tokenHasCommitment
+	}
+	if td.HasNFT {
+		bits |= tokenHasNFT | td.Capability
+	}
+	if td.Amount > 0 {
+		bits |= tokenHasAmount
+	}
+	return bits
+}
+
+// IsMutableNFT returns whether the token data has a NFT which can be spent to
+// create one other NFT of its category.
+func (td *TokenData) IsMutableNFT() bool {
+	return td.HasNFT && td.Capability == TokenCapabilityMutable
+}
+
+// IsMintingNFT returns whether the token data has a NFT which can be spent to
+// create any number of tokens of its category.
+func (td *TokenData) IsMintingNFT() bool {
+	return td.HasNFT && td.Capability == TokenCapabilityMinting
+}
+
+// IsImmutableNFT returns whether the token data has a NFT without capability.
+func (td *TokenData) IsImmutableNFT() bool {
+	return td.HasNFT && td.Capability == TokenCapabilityNone
+}
+
+func (td *TokenData) CapabilityString() string {
+	switch td.Capability {
+	case TokenCapabilityMutable:
+		return "mutable"
+	case TokenCapabilityMinting:
+		return "minting"
+	default:
+		return "none"
+	}
+}
+
+// EncodeSize returns the size of the token prefix, PrefixToken included.
+func (td *TokenData) EncodeSize() uint32 {
+	size := uint32(1 + util.Hash256Size + 1)
+	if len(td.Commitment) > 0 {
+		size += util.VarIntSerializeSize(uint64(len(td.Commitment))) + uint32(len(td.Commitment))
+	}
+	if td.Amount > 0 {
+		size += util.VarIntSerializeSize(uint64(td.Amount))
+	}
+	return size
+}
+
+// Encode writes the token prefix, PrefixToken included.
+func (td *TokenData) Encode(writer io.Writer) error {
+	if _, err := writer.Write([]byte{PrefixToken}); err != nil {
+		return err
+	}
+	if _, err := td.Category.Encode(writer); err != nil {
+		return err
+	}
+	if _, err := writer.Write([]byte{td.bitfield()}); err != nil {
+		return err
+	}
+	if len(td.Commitment) > 0 {
+		if err := util.WriteVarBytes(writer, td.Commitment); err != nil {
+			return err
+		}
+	}
+	if td.Amount > 0 {
+		return util.WriteVarInt(writer, uint64(td.Amount))
+	}
+	return nil
+}
+
+// decodeTokenData reads the token prefix following PrefixToken. Only canonical
+// encodings are accepted, so that the token data encodes back to the same
+// bytes.
+func decodeTokenData(reader io.Reader) (*TokenData, error) {
+	td := &TokenData{}
+	if _, err := io.ReadFull(reader, td.Category[:]); err != nil {
+		return nil, err
+	}
+	var bits [1]byte
+	if _, err := io.ReadFull(reader, bits[:]); err != nil {
+		return nil, err
+	}
+	bitfield := bits[0]
+	td.HasNFT = bitfield&tokenHasNFT != 0
+	td.Capability = bitfield & tokenCapability
+
+	switch {
+	case bitfield&tokenReserved != 0:
+		return nil, ErrInvalidTokenPrefix
+	case bitfield&(tokenHasNFT|tokenHasAmount) == 0:
+		return nil, ErrInvalidTokenPrefix
+	case !td.HasNFT && bitfield&(tokenHasCommitment|tokenCapability) != 0:
+		return nil, ErrInvalidTokenPrefix
+	case td.Capability > TokenCapabilityMinting:
+		return nil, ErrInvalidTokenPrefix
+	}
+
+	if bitfield&tokenHasCommitment != 0 {
+		commitment, err := util.ReadVarBytes(reader, MaxTokenCommitmentLength, "token commitment")
+		if err != nil {
+			return nil, err
+		}
+		if len(commitment) == 0 {
+			return nil, ErrInvalidTokenPrefix
+		}
+		td.Commitment = commitment
+	}
+	if bitfield&tokenHasAmount != 0 {
+		amount, err := util.ReadVarInt(reader)
+		if err != nil {
+			return nil, err
+		}
+		if amount == 0 || amount > math.MaxInt64 {
+			return nil, ErrInvalidTokenPrefix
+		}
+		td.Amount = int64(amount)
+	}
+	return td, nil
+}
+
+// splitTokenPrefix splits the scriptPubKey field of an output into its token
+// data, if any, and its actual scriptPubKey. A malformed token prefix is left
+// in the scriptPubKey, which makes the output invalid once tokens are enabled.
+func splitTokenPrefix(field []byte) (*TokenData, []byte) {
+	if len(field) == 0 || field[0] != PrefixToken {
+		return nil, field
+	}
+	reader := bytes.NewReader(field[1:])
+	td, err := decodeTokenData(reader)
+	if err != nil {
+		return nil, field
+	}
+	return td, field[len(field)-reader.Len():]
+}
+
+func (td *TokenData) IsEqual(other *TokenData) bool {
+	if td == nil || other == nil {
+		return td == other
+	}
+	return td.Category.IsEqual(&other.Category) && td.Amount == other.Amount &&
+		td.HasNFT == other.HasNFT && td.Capability == other.Capability &&
+		bytes.Equal(td.Commitment, other.Commitment)
+}
+
+func (td *TokenData) Copy() *TokenData {
+	if td == nil {
+		return nil
+	}
+	newTokenData := *td
+	newTokenData.Commitment = append([]byte(nil), td.Commitment...)
+	return &newTokenData
+}
+
+func (td *TokenData) String() string {
+	return fmt.Sprintf("Category:%s Amount:%d NFT:%t Capability:%s Commitment:%s", td.Category.String(),
+		td.Amount, td.HasNFT, td.CapabilityString(), hex.EncodeToString(td.Commitment))
+}
@@ -0,0 +1,104 @@
+package txout
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/copernet/copernicus/util"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCategory = util.Hash{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc,
+	0xdd, 0xee, 0xff, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc,
+	0xdd, 0xee, 0xff, 0x00}
+
+func TestTokenData_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenData TokenData
+		bitfield  byte
+	}{
+		{"fungible", TokenData{Category: testCategory, Amount: 1}, 0x10},
+		{"max amount", TokenData{Category: testCategory, Amount: 0x7fffffffffffffff}, 0x10},
+		{"immutable nft", TokenData{Category: testCategory, HasNFT: true}, 0x20},
+		{"mutable nft", TokenData{Category: testCategory, HasNFT: true, Capability: TokenCapabilityMutable}, 0x21},
+		{"minting nft with commitment and amount", TokenData{Category: testCategory, Amount: 253, HasNFT: true,
+			Capability: TokenCapabilityMinting, Commitment: bytes.Repeat([]byte{0xab}, 40)}, 0x72},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		assert.NoError(t, test.tokenData.Encode(&buf), test.name)
+		encoded := buf.Bytes()
+		assert.Equal(t, int(test.tokenData.EncodeSize()), len(encoded), test.name)
+		assert.Equal(t, byte(PrefixToken), encoded[0], test.name)
+		assert.Equal(t, test.bitfield, encoded[33], test.name)
+
+		script := []byte{0x51}
+		tokenData, rest := splitTokenPrefix(append(encoded, script...))
+		assert.True(t, test.tokenData.IsEqual(tokenData), test.name)
+		assert.Equal(t, script, rest, test.name)
+	}
+}
+
+func TestTokenData_InvalidPrefix(t *testing.T) {
+	category := hex.EncodeToString(testCategory[:])
+	tests := []struct {
+		name   string
+		prefix string
+	}{
+		{"truncated category", "ef1122"},
+		{"missing bitfield", "ef" + category},
+		{"reserved bit", "ef" + category + "b001"},
+		{"no nft nor amount", "ef" + category + "00"},
+		{"commitment without nft", "ef" + category + "5001ab01"},
+		{"capability without nft", "ef" + category + "1101"},
+		{"invalid capability", "ef" + category + "23"},
+		{"empty commitment", "ef" + category + "6000"},
+		{"commitment too long", "ef" + category + "6029" + hex.EncodeToString(bytes.Repeat([]byte{0}, 41))},
+		{"zero amount", "ef" + category + "1000"},
+		{"non-minimal amount", "ef" + category + "10fd0100"},
+		{"amount too large", "ef" + category + "10ff0000000000000080"},
+	}
+
+	for _, test := range tests {
+		field, err := hex.DecodeString(test.prefix)
+		assert.NoError(t, err, test.name)
+
+		tokenData, rest := splitTokenPrefix(field)
+		assert.Nil(t, tokenData, test.name)
+		assert.Equal(t, field, rest, test.name)
+
+		out := NewTxOut(0, nil)
+		var buf bytes.Buffer
+		buf.Write(make([]byte, 8))
+		assert.NoError(t, util.WriteVarBytes(&buf, field), test.name)
+		assert.NoError(t, out.Decode(&buf), test.name)
+		assert.Nil(t, out.GetTokenData(), test.name)
+		assert.True(t, out.HasInvalidTokenPrefix(), test.name)
+	}
+}
+
+func TestTxOut_TokenDataRoundTrip(t *testing.T) {
+	out := NewTxOut(1000, getTestScript())
+	out.SetTokenData(&TokenData{Category: testCategory, Amount: 10, HasNFT: true, Commitment: []byte{1}})
+
+	var buf bytes.Buffer
+	assert.NoError(t, out.Encode(&buf))
+	assert.Equal(t, int(out.EncodeSize()), buf.Len())
+
+	got := NewTxOut(0, nil)
+	assert.NoError(t, got.Decode(&buf))
+	assert.Nil(t, got.GetTokenData())
+	assert.True(t, got.HasTokenPrefix())
+	assert.False(t, out.IsEqual(got))
+
+	got.ReadTokenData()
+	assert.True(t, out.IsEqual(got))
+	assert.Equal(t, getTestScript().GetData(), got.GetScriptPubKey().GetData())
+	assert.False(t, got.HasInvalidTokenPrefix())
+
+	withoutToken := NewTxOut(1000, getTestScript())
+	assert.False(t, out.IsEqual(withoutToken))
+}
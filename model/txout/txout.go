@@ -16,6 +16,7 @@ import (
 type TxOut struct {
 	value        amount.Amount
 	scriptPubKey *script.Script
+	tokenData    *TokenData
 }
 
 func (txOut *TxOut) SerializeSize() uint32 {
@@ -31,7 +32,11 @@ func (txOut *TxOut) Unserialize(reader io.Reader) error {
 }
 
 func (txOut *TxOut) EncodeSize() uint32 {
-	return 8 + txOut.scriptPubKey.EncodeSize()
+	if txOut.tokenData == nil {
+		return 8 + txOut.scriptPubKey.EncodeSize()
+	}
+	size := txOut.tokenData.EncodeSize() + uint32(txOut.scriptPubKey.Size())
+	return 8 + util.VarIntSerializeSize(uint64(size)) + size
 }
 
 func (txOut *TxOut) Encode(writer io.Writer) error {
@@ -39,22 +44,59 @@ func (txOut *TxOut) Encode(writer io.Writer) error {
 	if err != nil {
 		return err
 	}
+	if txOut.tokenData != nil {
+		return txOut.encodeTokenScriptPubKey(writer)
+	}
 	if txOut.scriptPubKey == nil {
 		return util.WriteVarInt(writer, 0)
 	}
 	return txOut.scriptPubKey.Encode(writer)
 }
 
+// encodeTokenScriptPubKey writes the scriptPubKey field of an output carrying
+// token data, which holds the token prefix followed by the scriptPubKey.
+func (txOut *TxOut) encodeTokenScriptPubKey(writer io.Writer) error {
+	var data []byte
+	if txOut.scriptPubKey != nil {
+		data = txOut.scriptPubKey.GetData()
+	}
+	size := txOut.tokenData.EncodeSize() + uint32(len(data))
+	if err := util.WriteVarInt(writer, uint64(size)); err != nil {
+		return err
+	}
+	if err := txOut.tokenData.Encode(writer); err != nil {
+		return err
+	}
+	_, err := writer.Write(data)
+	return err
+}
+
 func (txOut *TxOut) Decode(reader io.Reader) error {
 	err := util.ReadElements(reader, &txOut.value)
 	if err != nil {
 		return err
 	}
 	bytes, err := script.ReadScript(reader, script.MaxMessagePayload, "tx output script")
+	txOut.tokenData = nil
 	txOut.scriptPubKey = script.NewScriptRaw(bytes)
 	return err
 }
 
+// ReadTokenData splits the token prefix of the scriptPubKey field into the
+// token data of the output. The field is only read that way once tokens are
+// enabled: before, a scriptPubKey starting with PrefixToken is a plain script.
+// A malformed token prefix is left in the scriptPubKey.
+func (txOut *TxOut) ReadTokenData() {
+	if txOut.tokenData != nil || txOut.scriptPubKey == nil {
+		return
+	}
+	tokenData, bytes := splitTokenPrefix(txOut.scriptPubKey.GetData())
+	if tokenData != nil {
+		txOut.tokenData = tokenData
+		txOut.scriptPubKey = script.NewScriptRaw(bytes)
+	}
+}
+
 func (txOut *TxOut) IsDust(minRelayTxFee *util.FeeRate) bool {
 	return txOut.value < amount.Amount(txOut.GetDustThreshold(minRelayTxFee))
 }
@@ -128,6 +170,28 @@ func (txOut *TxOut) SetScriptPubKey(s *script.Script) {
 	txOut.scriptPubKey = s
 }
 
+// GetTokenData returns the token data of the output, nil if it carries none.
+func (txOut *TxOut) GetTokenData() *TokenData {
+	return txOut.tokenData
+}
+func (txOut *TxOut) SetTokenData(td *TokenData) {
+	txOut.tokenData = td
+}
+
+// HasInvalidTokenPrefix returns whether the scriptPubKey field of the output
+// starts with PrefixToken but no valid token data could be read from it by
+// ReadTokenData.
+func (txOut *TxOut) HasInvalidTokenPrefix() bool {
+	return txOut.scriptPubKey != nil && txOut.scriptPubKey.Size() > 0 &&
+		txOut.scriptPubKey.GetData()[0] == PrefixToken
+}
+
+// HasTokenPrefix returns whether the scriptPubKey field of the output starts
+// with PrefixToken, whether or not its token data was read.
+func (txOut *TxOut) HasTokenPrefix() bool {
+	return txOut.tokenData != nil || txOut.HasInvalidTokenPrefix()
+}
+
 // IsSpendable returns whether the TxOut can be spent or not,
 // but doesn't care whether it has already been spent or not
 
@@ -141,6 +205,7 @@ func (txOut *TxOut) IsSpendable() bool {
 func (txOut *TxOut) SetNull() {
 	txOut.value = -1
 	txOut.scriptPubKey = nil
+	txOut.tokenData = nil
 }
 
 func (txOut *TxOut) IsNull() bool {
@@ -154,6 +219,9 @@ func (txOut *TxOut) IsEqual(out *TxOut) bool {
 	if txOut.value != out.value {
 		return false
 	}
+	if !txOut.tokenData.IsEqual(out.tokenData) {
+		return false
+	}
 
 	return txOut.scriptPubKey.IsEqual(out.scriptPubKey)
 }
@@ -59,12 +59,30 @@ func (coin *Coin) GetAmount() amount.Amount {
 	return coin.txOut.GetValue()
 }
 
+// GetTokenData returns the token data of the coin, nil if it carries none.
+func (coin *Coin) GetTokenData() *txout.TokenData {
+	return coin.txOut.GetTokenData()
+}
+
+// ReadTokenData reads the token data of the coin, which is only done for coins
+// created once tokens are enabled.
+func (coin *Coin) ReadTokenData() {
+	coin.txOut.ReadTokenData()
+}
+
+// HasTokenPrefix returns whether the scriptPubKey field of the coin starts
+// with the token prefix.
+func (coin *Coin) HasTokenPrefix() bool {
+	return coin.txOut.HasTokenPrefix()
+}
+
 func (coin *Coin) DeepCopy() *Coin {
 	newCoin := Coin{height: coin.height, isCoinBase: coin.isCoinBase, dirty: coin.dirty, fresh: coin.fresh, isMempoolCoin: coin.isMempoolCoin}
 	outScript := coin.txOut.GetScriptPubKey()
 	if coin.txOut.GetScriptPubKey() != nil {
 		newOutScript := script.NewScriptRaw(outScript.GetData())
 		newOut := txout.NewTxOut(coin.txOut.GetValue(), newOutScript)
+		newOut.SetTokenData(coin.txOut.GetTokenData().Copy())
 		newCoin.txOut = *newOut
 	}
 	return &newCoin
@@ -76,6 +76,12 @@ func (ab *AddressBook) SetAddressBook(keyHash []byte, addressBookData *AddressBo
 	ab.addressBook[string(keyHash)] = addressBookData
 }
 
+func (ab *AddressBook) GetAddressBook(keyHash []byte) *AddressBookData {
+	ab.RLock()
+	defer ab.RUnlock()
+	return ab.addressBook[string(keyHash)]
+}
+
 func (ab *AddressBook) GetAccountName(keyHash []byte) string {
 	ab.RLock()
 	defer ab.RUnlock()
@@ -84,3 +90,15 @@ func (ab *AddressBook) GetAccountName(keyHash []byte) string {
 	}
 	return ""
 }
+
+// GetAddressBooks returns the entries of the address book, keyed by the
+// hash of their address.
+func (ab *AddressBook) GetAddressBooks() map[string]*AddressBookData {
+	ab.RLock()
+	defer ab.RUnlock()
+	addressBook := make(map[string]*AddressBookData, len(ab.addressBook))
+	for keyHash, data := range ab.addressBook {
+		addressBook[keyHash] = data
+	}
+	return addressBook
+}
@@ -0,0 +1,163 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/log"
+)
+
+// EncryptWallet encrypts the private keys of an unencrypted wallet with a
+// new master key protected by the passphrase.  This is also the upgrade path
+// of wallets created before encryption was supported: their unencrypted keys
+// are replaced by encrypted ones in the database.  The wallet is locked
+// afterwards, with a new HD seed and keypool.
+func (w *Wallet) EncryptWallet(passphrase []byte) error {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	if w.IsCrypted() {
+		return crypto.ErrKeyStoreCrypted
+	}
+
+	masterKey, plainKey, err := crypto.NewMasterKey(passphrase)
+	if err != nil {
+		return err
+	}
+
+	secrets := w.wdb.loadSecrets()
+	cryptedKeys, err := w.EncryptKeys(plainKey)
+	if err != nil {
+		return err
+	}
+	w.masterKey = masterKey
+	if err := w.wdb.encryptSecrets(masterKey, cryptedKeys, secrets); err != nil {
+		// The keys in memory are encrypted for the rest of the session, but
+		// the database may still hold the unencrypted ones.
+		log.Error("EncryptWallet save to db fail. error:%s", err.Error())
+		return err
+	}
+
+	log.Info("wallet encrypted. keys:%d, derive iterations:%d", len(cryptedKeys), masterKey.DeriveIterations)
+
+	// The previous seed and keypool were stored unencrypted and may be in an
+	// old backup, so derive the new keys from a new seed.
+	if w.IsHDEnabled() {
+		if err := w.setHDSeed(NewHDSeed(), true); err != nil {
+			log.Error("EncryptWallet set new HD seed fail. error:%s", err.Error())
+			return err
+		}
+	} else {
+		w.keyPoolLock.Lock()
+		err := w.flushKeyPool()
+		w.keyPoolLock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	if err := w.topUpKeyPool(0); err != nil {
+		log.Error("EncryptWallet top up keypool fail. error:%s", err.Error())
+		return err
+	}
+	return w.lockWallet()
+}
+
+// UnlockWallet makes the private keys available for the given duration,
+// after which the wallet is locked again.  Unlocking an unlocked wallet
+// only changes when it will be locked.
+func (w *Wallet) UnlockWallet(passphrase []byte, timeout time.Duration) error {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	if !w.IsCrypted() {
+		return crypto.ErrKeyStoreNotCrypted
+	}
+
+	plainKey, err := w.masterKey.Decrypt(passphrase)
+	if err != nil {
+		return err
+	}
+	if err := w.UnlockKeys(plainKey); err != nil {
+		return err
+	}
+	if err := w.topUpKeyPool(0); err != nil {
+		log.Error("UnlockWallet top up keypool fail. error:%s", err.Error())
+	}
+
+	if w.relockTimer != nil {
+		w.relockTimer.Stop()
+	}
+	w.unlockedUntil = time.Now().Add(timeout).Unix()
+	var relockTimer *time.Timer
+	relockTimer = time.AfterFunc(timeout, func() {
+		w.cryptLock.Lock()
+		defer w.cryptLock.Unlock()
+
+		// The wallet may have been unlocked again meanwhile.
+		if w.relockTimer != relockTimer {
+			return
+		}
+		if err := w.lockWallet(); err != nil {
+			log.Error("wallet relock fail. error:%s", err.Error())
+		}
+	})
+	w.relockTimer = relockTimer
+	return nil
+}
+
+// LockWallet removes the private keys from memory.
+func (w *Wallet) LockWallet() error {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	return w.lockWallet()
+}
+
+func (w *Wallet) lockWallet() error {
+	if w.relockTimer != nil {
+		w.relockTimer.Stop()
+		w.relockTimer = nil
+	}
+	w.unlockedUntil = 0
+	return w.LockKeys()
+}
+
+// ChangeWalletPassphrase encrypts the master key with a new passphrase.
+// The private keys themselves are not encrypted again and the wallet stays
+// locked or unlocked as it was.
+func (w *Wallet) ChangeWalletPassphrase(oldPassphrase []byte, newPassphrase []byte) error {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	if !w.IsCrypted() {
+		return crypto.ErrKeyStoreNotCrypted
+	}
+	if len(newPassphrase) == 0 {
+		return crypto.ErrPassphraseEmpty
+	}
+
+	plainKey, err := w.masterKey.Decrypt(oldPassphrase)
+	if err != nil {
+		return err
+	}
+
+	masterKey := *w.masterKey
+	if err := masterKey.SetPassphrase(plainKey, newPassphrase); err != nil {
+		return err
+	}
+	if err := w.wdb.saveMasterKey(&masterKey); err != nil {
+		log.Error("ChangeWalletPassphrase save to db fail. error:%s", err.Error())
+		return err
+	}
+	w.masterKey = &masterKey
+	return nil
+}
+
+// GetUnlockedUntil returns the time at which an unlocked wallet will be
+// locked again, or 0 if the wallet is locked.
+func (w *Wallet) GetUnlockedUntil() int64 {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	return w.unlockedUntil
+}
@@ -0,0 +1,116 @@
+package wallet
+
+import (
+	"io"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	// hdPurpose is the BIP44 purpose of the derivation path.
+	hdPurpose = 44
+	// hdAccount is the BIP44 account all the keys are derived for.
+	hdAccount = 0
+
+	hdExternalChain = 0
+	hdInternalChain = 1
+
+	maxHDSeedIDSize = 20
+)
+
+// HDChain is the state of the hierarchical deterministic key chain: the
+// seed the keys are derived from and the next index of the external
+// (receiving) and internal (change) chains.
+type HDChain struct {
+	SeedID          []byte
+	ExternalCounter uint32
+	InternalCounter uint32
+}
+
+func (hc *HDChain) Serialize(w io.Writer) error {
+	if err := util.WriteVarBytes(w, hc.SeedID); err != nil {
+		return err
+	}
+	return util.WriteElements(w, hc.ExternalCounter, hc.InternalCounter)
+}
+
+func (hc *HDChain) Unserialize(r io.Reader) error {
+	var err error
+	if hc.SeedID, err = util.ReadVarBytes(r, maxHDSeedIDSize, "hd seed id"); err != nil {
+		return err
+	}
+	return util.ReadElements(r, &hc.ExternalCounter, &hc.InternalCounter)
+}
+
+// hdAccountPath returns the BIP44 path of the account the keys are derived
+// for: m/44'/coin_type'/0'.
+func hdAccountPath(coinType uint32) []uint32 {
+	return []uint32{
+		crypto.HardenedKeyStart + hdPurpose,
+		crypto.HardenedKeyStart + coinType,
+		crypto.HardenedKeyStart + hdAccount,
+	}
+}
+
+// KeyMetadata records when a key was created and, for keys derived from an
+// HD seed, the seed and the derivation path.
+type KeyMetadata struct {
+	CreateTime int64
+	HDKeyPath  string
+	HDSeedID   []byte
+}
+
+func (km *KeyMetadata) Serialize(w io.Writer) error {
+	if err := util.WriteElements(w, km.CreateTime); err != nil {
+		return err
+	}
+	if err := util.WriteVarString(w, km.HDKeyPath); err != nil {
+		return err
+	}
+	return util.WriteVarBytes(w, km.HDSeedID)
+}
+
+func (km *KeyMetadata) Unserialize(r io.Reader) error {
+	var err error
+	if err = util.ReadElements(r, &km.CreateTime); err != nil {
+		return err
+	}
+	if km.HDKeyPath, err = util.ReadVarString(r); err != nil {
+		return err
+	}
+	km.HDSeedID, err = util.ReadVarBytes(r, maxHDSeedIDSize, "hd seed id")
+	return err
+}
+
+// KeyPoolEntry is a pre-generated key waiting to be handed out, either as a
+// receiving address or as a change address when Internal is set.
+type KeyPoolEntry struct {
+	Time     int64
+	PubKey   *crypto.PublicKey
+	Internal bool
+}
+
+func (kpe *KeyPoolEntry) Serialize(w io.Writer) error {
+	if err := util.WriteElements(w, kpe.Time); err != nil {
+		return err
+	}
+	if err := util.WriteVarBytes(w, kpe.PubKey.ToBytes()); err != nil {
+		return err
+	}
+	return util.WriteElements(w, kpe.Internal)
+}
+
+func (kpe *KeyPoolEntry) Unserialize(r io.Reader) error {
+	if err := util.ReadElements(r, &kpe.Time); err != nil {
+		return err
+	}
+	pubKeyBytes, err := util.ReadVarBytes(r, 65, "keypool public key")
+	if err != nil {
+		return err
+	}
+	if kpe.PubKey, err = crypto.ParsePubKey(pubKeyBytes); err != nil {
+		return err
+	}
+	return util.ReadElements(r, &kpe.Internal)
+}
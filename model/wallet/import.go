@@ -0,0 +1,51 @@
+package wallet
+
+import (
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/pkg/errors"
+)
+
+var ErrKeyExists = errors.New("the wallet already contains this key")
+
+// ImportPrivateKey adds a private key which was not generated by the wallet.
+// createTime is when the key was created, 0 if unknown.  An encrypted wallet
+// needs to be unlocked.
+func (w *Wallet) ImportPrivateKey(privateKey *crypto.PrivateKey, createTime int64) error {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	w.keyPoolLock.Lock()
+	defer w.keyPoolLock.Unlock()
+
+	keyID := privateKey.PubKey().ToHash160()
+	if w.GetKeyPair(keyID) != nil {
+		return ErrKeyExists
+	}
+	key, err := w.addNewKey(privateKey, &KeyMetadata{CreateTime: createTime})
+	if err != nil {
+		return err
+	}
+	if err := w.wdb.saveKeys([]*newKey{key}, nil, nil); err != nil {
+		log.Error("ImportPrivateKey save to db fail. error:%s", err.Error())
+		return err
+	}
+	w.keyMetadata[string(keyID)] = key.meta
+	return nil
+}
+
+// AddWatchOnly makes the wallet track the outputs paying to a script it
+// cannot spend.
+func (w *Wallet) AddWatchOnly(s *script.Script) error {
+	if w.HaveWatchOnly(s) {
+		return nil
+	}
+	w.ScriptStore.AddWatchOnly(s)
+	err := w.wdb.saveWatchOnly(s)
+	if err != nil {
+		log.Error("AddWatchOnly save to db fail. error:%s", err.Error())
+		return err
+	}
+	return nil
+}
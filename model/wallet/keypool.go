@@ -0,0 +1,410 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/pkg/errors"
+)
+
+// hdSeedKeyPath is the derivation path recorded for HD seeds.
+const hdSeedKeyPath = "s"
+
+var (
+	ErrKeyPoolRanOut = errors.New("keypool ran out")
+	ErrHaveHDSeed    = errors.New("already have this key (either as an HD seed or as a loose private key)")
+)
+
+// newKey is a key generated for the wallet and not saved yet.
+type newKey struct {
+	privateKey    *crypto.PrivateKey
+	cryptedSecret []byte
+	meta          *KeyMetadata
+}
+
+// NewHDSeed generates a random HD seed.
+func NewHDSeed() *crypto.PrivateKey {
+	return newRandomKey()
+}
+
+func newRandomKey() *crypto.PrivateKey {
+	secret := make([]byte, 32)
+	io.ReadFull(rand.Reader, secret)
+	return crypto.NewPrivateKeyFromBytes(secret, true)
+}
+
+// IsHDEnabled reports whether the keys of the wallet are derived from an HD
+// seed.
+func (w *Wallet) IsHDEnabled() bool {
+	w.keyPoolLock.Lock()
+	defer w.keyPoolLock.Unlock()
+
+	return w.hdChain != nil
+}
+
+// GetHDChain returns a copy of the state of the HD chain, or nil if the
+// wallet is not an HD wallet.
+func (w *Wallet) GetHDChain() *HDChain {
+	w.keyPoolLock.Lock()
+	defer w.keyPoolLock.Unlock()
+
+	if w.hdChain == nil {
+		return nil
+	}
+	hdChain := *w.hdChain
+	return &hdChain
+}
+
+// GetHDMasterKey returns the BIP32 master key derived from the HD seed.  The
+// wallet must be unlocked.
+func (w *Wallet) GetHDMasterKey() (*crypto.ExtendedKey, error) {
+	hdChain := w.GetHDChain()
+	if hdChain == nil {
+		return nil, errors.New("wallet is not a HD wallet")
+	}
+	return w.getHDMasterKey(hdChain)
+}
+
+func (w *Wallet) getHDMasterKey(hdChain *HDChain) (*crypto.ExtendedKey, error) {
+	seedKeyPair := w.GetKeyPair(hdChain.SeedID)
+	if seedKeyPair == nil {
+		return nil, errors.New("HD seed not found in the wallet")
+	}
+	seed := seedKeyPair.GetPrivateKey()
+	if seed == nil {
+		return nil, crypto.ErrKeyStoreLocked
+	}
+	return crypto.NewMasterExtendedKey(seed.GetBytes())
+}
+
+// GetHDAccountPath returns the BIP44 path of the account the wallet keys are
+// derived for.
+func GetHDAccountPath() []uint32 {
+	return hdAccountPath(chain.GetInstance().GetParams().HDCoinType)
+}
+
+// GetKeyMetadata returns the metadata recorded for a key of the wallet.
+func (w *Wallet) GetKeyMetadata(pubKeyHash []byte) *KeyMetadata {
+	w.keyPoolLock.Lock()
+	defer w.keyPoolLock.Unlock()
+
+	return w.keyMetadata[string(pubKeyHash)]
+}
+
+// SetHDSeed makes the wallet derive its new keys from seed.  Keys already
+// derived from the previous seed, including those of the keypool unless it
+// is flushed, remain in the wallet.
+func (w *Wallet) SetHDSeed(seed *crypto.PrivateKey, flushKeyPool bool) error {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	if err := w.setHDSeed(seed, flushKeyPool); err != nil {
+		return err
+	}
+	return w.topUpKeyPool(0)
+}
+
+func (w *Wallet) setHDSeed(seed *crypto.PrivateKey, flushKeyPool bool) error {
+	if w.IsLocked() {
+		return crypto.ErrKeyStoreLocked
+	}
+
+	w.keyPoolLock.Lock()
+	defer w.keyPoolLock.Unlock()
+
+	seedID := seed.PubKey().ToHash160()
+	if w.GetKeyPair(seedID) != nil {
+		return ErrHaveHDSeed
+	}
+	key, err := w.addNewKey(seed, &KeyMetadata{
+		CreateTime: time.Now().Unix(),
+		HDKeyPath:  hdSeedKeyPath,
+		HDSeedID:   seedID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if flushKeyPool {
+		if err := w.flushKeyPool(); err != nil {
+			return err
+		}
+	}
+
+	hdChain := &HDChain{SeedID: seedID}
+	if err := w.wdb.saveKeys([]*newKey{key}, nil, hdChain); err != nil {
+		log.Error("SetHDSeed save to db fail. error:%s", err.Error())
+		return err
+	}
+	w.keyMetadata[string(seedID)] = key.meta
+	w.hdChain = hdChain
+	return nil
+}
+
+// TopUpKeyPool fills the external and internal keypools up to size keys
+// each, or to the configured keypool size if size is 0.  The wallet must be
+// unlocked.
+func (w *Wallet) TopUpKeyPool(size int) error {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	return w.topUpKeyPool(size)
+}
+
+func (w *Wallet) topUpKeyPool(size int) error {
+	if w.IsLocked() {
+		return crypto.ErrKeyStoreLocked
+	}
+	if size <= 0 {
+		size = conf.Cfg.Wallet.KeyPool
+	}
+	if size < 1 {
+		size = 1
+	}
+
+	w.keyPoolLock.Lock()
+	defer w.keyPoolLock.Unlock()
+
+	missingExternal := size - len(w.externalKeyPool)
+	missingInternal := size - len(w.internalKeyPool)
+	if missingExternal <= 0 && missingInternal <= 0 {
+		return nil
+	}
+
+	var hdChain *HDChain
+	if w.hdChain != nil {
+		chainCopy := *w.hdChain
+		hdChain = &chainCopy
+	}
+
+	keys := make([]*newKey, 0)
+	entries := make(map[int64]*KeyPoolEntry)
+	now := time.Now().Unix()
+	maxIndex := w.maxKeyPoolIndex
+	for _, internal := range []bool{false, true} {
+		missing := missingExternal
+		if internal {
+			missing = missingInternal
+		}
+		if missing <= 0 {
+			continue
+		}
+		var chainKey *crypto.ExtendedKey
+		if hdChain != nil {
+			var err error
+			if chainKey, err = w.getHDChainKey(hdChain, internal); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < missing; i++ {
+			key, err := w.generateNewKey(hdChain, chainKey, internal)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+			maxIndex++
+			entries[maxIndex] = &KeyPoolEntry{
+				Time:     now,
+				PubKey:   key.privateKey.PubKey(),
+				Internal: internal,
+			}
+		}
+	}
+
+	if err := w.wdb.saveKeys(keys, entries, hdChain); err != nil {
+		log.Error("TopUpKeyPool save to db fail. error:%s", err.Error())
+		return err
+	}
+	for _, key := range keys {
+		w.keyMetadata[string(key.privateKey.PubKey().ToHash160())] = key.meta
+	}
+	w.addToKeyPool(entries)
+	w.maxKeyPoolIndex = maxIndex
+	w.hdChain = hdChain
+	log.Info("keypool topped up. external:%d, internal:%d", len(w.externalKeyPool), len(w.internalKeyPool))
+	return nil
+}
+
+func hdChainPath(internal bool) []uint32 {
+	if internal {
+		return append(GetHDAccountPath(), hdInternalChain)
+	}
+	return append(GetHDAccountPath(), hdExternalChain)
+}
+
+// getHDChainKey derives the extended key of the external or internal chain,
+// whose children are the keys of the wallet.
+func (w *Wallet) getHDChainKey(hdChain *HDChain, internal bool) (*crypto.ExtendedKey, error) {
+	masterKey, err := w.getHDMasterKey(hdChain)
+	if err != nil {
+		return nil, err
+	}
+	return masterKey.DerivePath(hdChainPath(internal))
+}
+
+// generateNewKey derives the next key of the external or internal chain of
+// hdChain from its chain key, or generates a random key if the wallet is not
+// an HD wallet.  The key is added to the key store and hdChain is advanced,
+// but nothing is saved.
+func (w *Wallet) generateNewKey(hdChain *HDChain, chainKey *crypto.ExtendedKey, internal bool) (*newKey, error) {
+	meta := &KeyMetadata{CreateTime: time.Now().Unix()}
+	if hdChain == nil {
+		return w.addNewKey(newRandomKey(), meta)
+	}
+
+	counter := &hdChain.ExternalCounter
+	if internal {
+		counter = &hdChain.InternalCounter
+	}
+	for {
+		if *counter >= crypto.HardenedKeyStart {
+			return nil, errors.New("HD chain exhausted")
+		}
+		index := *counter
+		*counter++
+
+		childKey, err := chainKey.Child(index)
+		if err == crypto.ErrInvalidChild {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err := childKey.PrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		if w.GetKeyPair(privateKey.PubKey().ToHash160()) != nil {
+			continue
+		}
+
+		meta.HDKeyPath = crypto.FormatHDKeyPath(append(hdChainPath(internal), index))
+		meta.HDSeedID = hdChain.SeedID
+		return w.addNewKey(privateKey, meta)
+	}
+}
+
+// addNewKey adds a private key to the key store, encrypting it if the
+// wallet is encrypted.
+func (w *Wallet) addNewKey(privateKey *crypto.PrivateKey, meta *KeyMetadata) (*newKey, error) {
+	key := &newKey{privateKey: privateKey, meta: meta}
+	if w.IsCrypted() {
+		cryptedSecret, err := w.AddKeyCrypted(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		key.cryptedSecret = cryptedSecret
+	} else {
+		w.AddKey(privateKey)
+	}
+	return key, nil
+}
+
+func (w *Wallet) addToKeyPool(entries map[int64]*KeyPoolEntry) {
+	for index, entry := range entries {
+		w.keyPool[index] = entry
+		if entry.Internal {
+			w.internalKeyPool = append(w.internalKeyPool, index)
+		} else {
+			w.externalKeyPool = append(w.externalKeyPool, index)
+		}
+		if index > w.maxKeyPoolIndex {
+			w.maxKeyPoolIndex = index
+		}
+	}
+	sortIndices(w.externalKeyPool)
+	sortIndices(w.internalKeyPool)
+}
+
+// flushKeyPool empties the keypool.  Its keys remain in the wallet.
+func (w *Wallet) flushKeyPool() error {
+	indices := make([]int64, 0, len(w.keyPool))
+	for index := range w.keyPool {
+		indices = append(indices, index)
+	}
+	if err := w.wdb.eraseKeyPool(indices); err != nil {
+		return err
+	}
+	w.keyPool = make(map[int64]*KeyPoolEntry)
+	w.externalKeyPool = nil
+	w.internalKeyPool = nil
+	return nil
+}
+
+// GetKeyFromPool takes the oldest key of the external or internal keypool,
+// topping the keypool up first if the wallet is unlocked.
+func (w *Wallet) GetKeyFromPool(internal bool) (*crypto.PublicKey, error) {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	if !w.IsLocked() {
+		if err := w.topUpKeyPool(0); err != nil {
+			return nil, err
+		}
+	}
+
+	w.keyPoolLock.Lock()
+	defer w.keyPoolLock.Unlock()
+
+	pool := &w.externalKeyPool
+	if internal {
+		pool = &w.internalKeyPool
+	}
+	if len(*pool) == 0 {
+		return nil, ErrKeyPoolRanOut
+	}
+
+	index := (*pool)[0]
+	if err := w.wdb.eraseKeyPool([]int64{index}); err != nil {
+		log.Error("GetKeyFromPool save to db fail. error:%s", err.Error())
+		return nil, err
+	}
+	entry := w.keyPool[index]
+	*pool = (*pool)[1:]
+	delete(w.keyPool, index)
+	return entry.PubKey, nil
+}
+
+// GetKeyPoolSize returns the number of keys in the external and internal
+// keypools.
+func (w *Wallet) GetKeyPoolSize() (int, int) {
+	w.keyPoolLock.Lock()
+	defer w.keyPoolLock.Unlock()
+
+	return len(w.externalKeyPool), len(w.internalKeyPool)
+}
+
+// GetKeyPoolKeys returns the keypool entries by the Hash160 of their public
+// key.
+func (w *Wallet) GetKeyPoolKeys() map[string]*KeyPoolEntry {
+	w.keyPoolLock.Lock()
+	defer w.keyPoolLock.Unlock()
+
+	keys := make(map[string]*KeyPoolEntry, len(w.keyPool))
+	for _, entry := range w.keyPool {
+		keys[string(entry.PubKey.ToHash160())] = entry
+	}
+	return keys
+}
+
+// GetOldestKeyPoolTime returns the creation time of the oldest key of the
+// external keypool, or the current time if it is empty.
+func (w *Wallet) GetOldestKeyPoolTime() int64 {
+	w.keyPoolLock.Lock()
+	defer w.keyPoolLock.Unlock()
+
+	if len(w.externalKeyPool) == 0 {
+		return time.Now().Unix()
+	}
+	return w.keyPool[w.externalKeyPool[0]].Time
+}
+
+func sortIndices(indices []int64) {
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+}
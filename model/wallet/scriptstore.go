@@ -9,12 +9,14 @@ import (
 
 type ScriptStore struct {
 	sync.RWMutex
-	scripts map[string]*script.Script
+	scripts   map[string]*script.Script
+	watchOnly map[string]*script.Script
 }
 
 func NewScriptStore() *ScriptStore {
 	return &ScriptStore{
-		scripts: make(map[string]*script.Script),
+		scripts:   make(map[string]*script.Script),
+		watchOnly: make(map[string]*script.Script),
 	}
 }
 
@@ -36,3 +38,42 @@ func (ss *ScriptStore) GetScript(scriptHash []byte) *script.Script {
 	}
 	return nil
 }
+
+func (ss *ScriptStore) GetScripts() []*script.Script {
+	ss.RLock()
+	defer ss.RUnlock()
+
+	scripts := make([]*script.Script, 0, len(ss.scripts))
+	for _, s := range ss.scripts {
+		scripts = append(scripts, s)
+	}
+	return scripts
+}
+
+// AddWatchOnly adds a script whose outputs are tracked by the wallet without
+// being spendable by it.
+func (ss *ScriptStore) AddWatchOnly(s *script.Script) {
+	ss.Lock()
+	defer ss.Unlock()
+
+	ss.watchOnly[string(s.Bytes())] = s
+}
+
+func (ss *ScriptStore) HaveWatchOnly(s *script.Script) bool {
+	ss.RLock()
+	defer ss.RUnlock()
+
+	_, ok := ss.watchOnly[string(s.Bytes())]
+	return ok
+}
+
+func (ss *ScriptStore) GetWatchOnlyScripts() []*script.Script {
+	ss.RLock()
+	defer ss.RUnlock()
+
+	scripts := make([]*script.Script, 0, len(ss.watchOnly))
+	for _, s := range ss.watchOnly {
+		scripts = append(scripts, s)
+	}
+	return scripts
+}
@@ -3,14 +3,15 @@
 package wallet
 
 import (
-	"crypto/rand"
-	"io"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/crypto"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
 	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/mempool"
 	"github.com/copernet/copernicus/model/outpoint"
@@ -21,24 +22,47 @@ import (
 	"github.com/copernet/copernicus/model/utxo"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
+	"github.com/pkg/errors"
 )
 
 type Wallet struct {
+	name         string
 	enable       bool
 	broadcastTx  bool
+	subscription int
 	reservedKeys []*crypto.PublicKey
 	txnLock      *sync.RWMutex
 	walletTxns   map[util.Hash]*WalletTx
-	lockedCoins  map[outpoint.OutPoint]struct{}
+	lockedCoins  map[outpoint.OutPoint]bool
 	payTxFee     *util.FeeRate
 	wdb          WalletDB
 
+	cryptLock     sync.Mutex
+	masterKey     *crypto.MasterKey
+	relockTimer   *time.Timer
+	unlockedUntil int64
+
+	keyPoolLock     sync.Mutex
+	hdChain         *HDChain
+	keyMetadata     map[string]*KeyMetadata
+	keyPool         map[int64]*KeyPoolEntry
+	externalKeyPool []int64
+	internalKeyPool []int64
+	maxKeyPoolIndex int64
+
+	bestBlockLock sync.Mutex
+	bestBlock     *chain.BlockLocator
+	firstRun      bool
+
 	*crypto.KeyStore
 	*ScriptStore
 	*AddressBook
 }
 
-var globalWallet *Wallet
+var (
+	ErrTxNotInWallet    = errors.New("Invalid or non-wallet transaction id")
+	ErrTxNotAbandonable = errors.New("Transaction not eligible for abandonment")
+)
 
 /**
  * If fee estimation does not have enough data to provide estimates, use this
@@ -47,52 +71,74 @@ var globalWallet *Wallet
  */
 var fallbackFee = util.NewFeeRate(20000)
 
-func InitWallet() {
-	defer func() {
-		if globalWallet == nil {
-			globalWallet = &Wallet{enable: false}
-		}
-	}()
+// DefaultTxConfirmTarget is the number of blocks the wallet targets for its
+// transactions to confirm within when estimating their fee.
+const DefaultTxConfirmTarget = 6
 
+// InitWallet loads the default wallet, creating it on the first run.
+func InitWallet() {
 	if !conf.Cfg.Wallet.Enable {
 		return
 	}
 
-	walletInstance := &Wallet{
+	walletsLock.Lock()
+	defer walletsLock.Unlock()
+	openWallet(DefaultWalletName)
+}
+
+// GetInstance returns the default wallet, or a disabled wallet if it is not
+// loaded.
+func GetInstance() *Wallet {
+	if w := GetWallet(DefaultWalletName); w != nil {
+		return w
+	}
+	return disabledWallet
+}
+
+func newWallet(name string) *Wallet {
+	return &Wallet{
+		name:        name,
 		enable:      true,
 		broadcastTx: conf.Cfg.Wallet.Broadcast,
 		txnLock:     new(sync.RWMutex),
 		walletTxns:  make(map[util.Hash]*WalletTx),
-		lockedCoins: make(map[outpoint.OutPoint]struct{}),
+		lockedCoins: make(map[outpoint.OutPoint]bool),
 		payTxFee:    util.NewFeeRate(0),
+		wdb:         WalletDB{path: walletDBPath(name)},
 	}
-
-	if err := walletInstance.Init(); err != nil {
-		return
-	}
-
-	chain.GetInstance().Subscribe(walletInstance.handleBlockChainNotification)
-
-	globalWallet = walletInstance
-}
-
-func GetInstance() *Wallet {
-	if globalWallet == nil {
-		globalWallet = &Wallet{enable: false}
-	}
-	return globalWallet
 }
 
 func (w *Wallet) Init() error {
 	w.KeyStore = crypto.NewKeyStore()
 	w.ScriptStore = NewScriptStore()
 	w.AddressBook = NewAddressBook()
+	w.keyMetadata = make(map[string]*KeyMetadata)
+	w.keyPool = make(map[int64]*KeyPoolEntry)
 
-	w.wdb.initDB()
+	if err := w.wdb.initDB(); err != nil {
+		log.Error("Load wallet fail. error:" + err.Error())
+		return err
+	}
 	if err := w.loadFromDB(); err != nil {
 		log.Error("Load wallet fail. error:" + err.Error())
+		w.wdb.Close()
 		return err
 	}
+
+	// New wallets are HD wallets.  Encrypted wallets from before HD support
+	// keep using random keys until they get a seed with sethdseed.
+	if w.hdChain == nil && !w.IsCrypted() {
+		if err := w.SetHDSeed(NewHDSeed(), false); err != nil {
+			log.Error("Set wallet HD seed fail. error:" + err.Error())
+			return err
+		}
+	}
+	if !w.IsLocked() {
+		if err := w.TopUpKeyPool(0); err != nil {
+			log.Error("Top up wallet keypool fail. error:" + err.Error())
+			return err
+		}
+	}
 	return nil
 }
 
@@ -100,13 +146,54 @@ func (w *Wallet) IsEnable() bool {
 	return w.enable
 }
 
+// GetName returns the name of the wallet, empty for the default wallet.
+func (w *Wallet) GetName() string {
+	return w.name
+}
+
 func (w *Wallet) loadFromDB() error {
-	secrets := w.wdb.loadSecrets()
-	for _, secret := range secrets {
-		privateKey := crypto.NewPrivateKeyFromBytes(secret, true)
+	privateKeys := w.wdb.loadPrivateKeys()
+	for _, privateKey := range privateKeys {
 		w.KeyStore.AddKey(privateKey)
 	}
 
+	masterKey, err := w.wdb.loadMasterKey()
+	if err != nil {
+		return err
+	}
+	w.masterKey = masterKey
+
+	cryptedKeys, err := w.wdb.loadCryptedKeys()
+	if err != nil {
+		return err
+	}
+	if len(cryptedKeys) > 0 && masterKey == nil {
+		return errors.New("wallet has encrypted keys but no master key")
+	}
+	for _, cryptedKey := range cryptedKeys {
+		if err := w.KeyStore.AddCryptedKey(cryptedKey.PublicKey, cryptedKey.Secret); err != nil {
+			return err
+		}
+	}
+
+	hdChain, err := w.wdb.loadHDChain()
+	if err != nil {
+		return err
+	}
+	w.hdChain = hdChain
+
+	keyMetadata, err := w.wdb.loadKeyMetadata()
+	if err != nil {
+		return err
+	}
+	w.keyMetadata = keyMetadata
+
+	keyPool, err := w.wdb.loadKeyPool()
+	if err != nil {
+		return err
+	}
+	w.addToKeyPool(keyPool)
+
 	scripts, err := w.wdb.loadScripts()
 	if err != nil {
 		return err
@@ -115,6 +202,14 @@ func (w *Wallet) loadFromDB() error {
 		w.ScriptStore.AddScript(sc)
 	}
 
+	watchOnly, err := w.wdb.loadWatchOnly()
+	if err != nil {
+		return err
+	}
+	for _, sc := range watchOnly {
+		w.ScriptStore.AddWatchOnly(sc)
+	}
+
 	addressBook, err := w.wdb.loadAddressBook()
 	if err != nil {
 		return err
@@ -129,30 +224,35 @@ func (w *Wallet) loadFromDB() error {
 		return err
 	}
 	for _, wtx := range transactions {
+		wtx.pwallet = w
 		w.walletTxns[wtx.Tx.GetHash()] = wtx
 	}
-	log.Info("load wallet from db successfully. keys:%v, scripts:%v, addressbook:%v, txns:%v",
-		len(secrets), len(scripts), len(addressBook), len(transactions))
-	return nil
-}
 
-func (w *Wallet) GenerateNewKey() (*crypto.PublicKey, error) {
-	secret := make([]byte, 32)
-	io.ReadFull(rand.Reader, secret)
-	privateKey := crypto.NewPrivateKeyFromBytes(secret, true)
-	w.AddKey(privateKey)
-	err := w.wdb.saveSecret(secret)
+	lockedCoins, err := w.wdb.loadLockedCoins()
 	if err != nil {
-		log.Error("GenerateNewKey save to db fail. error:%s", err.Error())
-		return nil, err
+		return err
+	}
+	for _, outPoint := range lockedCoins {
+		w.lockedCoins[*outPoint] = true
 	}
-	return privateKey.PubKey(), nil
+
+	bestBlock, err := w.wdb.loadBestBlock()
+	if err != nil {
+		return err
+	}
+	w.bestBlock = bestBlock
+	w.firstRun = len(privateKeys) == 0 && masterKey == nil && hdChain == nil &&
+		len(scripts) == 0 && len(watchOnly) == 0 && len(transactions) == 0
+	log.Info("load wallet from db successfully. keys:%v, crypted keys:%v, keypool:%v, scripts:%v, "+
+		"watch-only:%v, addressbook:%v, txns:%v", len(privateKeys), len(cryptedKeys), len(keyPool),
+		len(scripts), len(watchOnly), len(addressBook), len(transactions))
+	return nil
 }
 
-func (w *Wallet) GetReservedKey() (*crypto.PublicKey, error) {
-	// wallet function is only for testing. The keypool is not supported yet.
-	// generate new key each time
-	reservedKey, err := w.GenerateNewKey()
+// GetReservedKey takes a key from the keypool for a coinbase output or, if
+// internal is set, for a change output.
+func (w *Wallet) GetReservedKey(internal bool) (*crypto.PublicKey, error) {
+	reservedKey, err := w.GetKeyFromPool(internal)
 	if err != nil {
 		return nil, err
 	}
@@ -185,7 +285,7 @@ func (w *Wallet) AddToWallet(txn *tx.Tx, blockhash util.Hash, extInfo map[string
 	txHash := txn.GetHash()
 	log.Info("AddToWallet tx:%s", txHash.String())
 
-	walletTx := NewWalletTx(txn, blockhash, extInfo, true, "")
+	walletTx := NewWalletTx(w, txn, blockhash, extInfo, true, "")
 
 	w.txnLock.Lock()
 	defer w.txnLock.Unlock()
@@ -207,8 +307,17 @@ func (w *Wallet) addTxnsToWallet(txns []*tx.Tx, blockhash util.Hash) {
 		txHash := txn.GetHash()
 		log.Info("AddTxnsToWallet tx:%s, hash:%v", txHash.String(), blockhash.String())
 
-		walletTx := NewWalletTx(txn, blockhash, nil, true, "")
-		w.walletTxns[txHash] = walletTx
+		// A known transaction only moves to its block, so that its receive
+		// time and the info recorded when it was sent are kept.
+		walletTx, ok := w.walletTxns[txHash]
+		if ok {
+			walletTx.blockHash = blockhash
+			walletTx.blockHeight = 0
+			walletTx.MarkDirty()
+		} else {
+			walletTx = NewWalletTx(w, txn, blockhash, nil, true, "")
+			w.walletTxns[txHash] = walletTx
+		}
 		err := w.wdb.saveWalletTx(walletTx)
 		if err != nil {
 			log.Error("AddTxnsToWallet save to db fail. tx:%s, error:%s", txHash.String(), err.Error())
@@ -233,6 +342,62 @@ func (w *Wallet) RemoveFromWallet(txn *tx.Tx) error {
 	return nil
 }
 
+// AbandonTransaction marks an unconfirmed transaction which is not in the
+// mempool abandoned, along with the wallet transactions spending its outputs,
+// and frees the outputs they spend so that the wallet can spend them again.
+func (w *Wallet) AbandonTransaction(txHash util.Hash) error {
+	w.txnLock.Lock()
+	defer w.txnLock.Unlock()
+
+	origTx, ok := w.walletTxns[txHash]
+	if !ok {
+		return ErrTxNotInWallet
+	}
+	if origTx.GetDepthInMainChain() != 0 || origTx.InMempool() {
+		return ErrTxNotAbandonable
+	}
+
+	todo := []util.Hash{txHash}
+	done := make(map[util.Hash]struct{})
+	for len(todo) > 0 {
+		hash := todo[0]
+		todo = todo[1:]
+		if _, ok := done[hash]; ok {
+			continue
+		}
+		done[hash] = struct{}{}
+
+		// The transactions spending an unconfirmed transaction out of the
+		// mempool can be neither confirmed nor in the mempool.
+		wtx := w.walletTxns[hash]
+		if wtx.GetDepthInMainChain() != 0 || wtx.IsAbandoned() {
+			continue
+		}
+		wtx.setAbandoned()
+		wtx.MarkDirty()
+		if err := w.wdb.saveWalletTx(wtx); err != nil {
+			return err
+		}
+
+		for _, in := range wtx.GetIns() {
+			prev := in.PreviousOutPoint
+			if prevTx, ok := w.walletTxns[prev.Hash]; ok {
+				prevTx.MarkUnspent(int(prev.Index))
+				prevTx.MarkDirty()
+			}
+		}
+		for childHash, child := range w.walletTxns {
+			for _, in := range child.GetIns() {
+				if in.PreviousOutPoint.Hash == hash {
+					todo = append(todo, childHash)
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func (w *Wallet) GetWalletTxns() []*WalletTx {
 	walletTxns := make([]*WalletTx, 0, len(w.walletTxns))
 
@@ -282,7 +447,7 @@ func (w *Wallet) isTrustedTx(walletTx *WalletTx) bool {
 			return false
 		}
 		prevOut := prevTxn.Tx.GetTxOut(int(txIn.PreviousOutPoint.Index))
-		if !IsUnlockable(prevOut.GetScriptPubKey()) {
+		if !w.IsUnlockable(prevOut.GetScriptPubKey()) {
 			return false
 		}
 	}
@@ -327,8 +492,10 @@ func (w *Wallet) GetMinimumFee(byteSize int) int64 {
 	feeNeeded := w.payTxFee.GetFee(byteSize)
 	// User didn't set tx fee
 	if feeNeeded == 0 {
-		minFeeRate := mempool.GetInstance().GetMinFeeRate()
-		feeNeeded = minFeeRate.GetFee(byteSize)
+		if feeEstimator := mempool.GetFeeEstimator(); feeEstimator != nil {
+			feeRate, _ := feeEstimator.EstimateSmartFee(DefaultTxConfirmTarget, mempool.GetInstance())
+			feeNeeded = feeRate.GetFee(byteSize)
+		}
 
 		// ... unless we don't have enough mempool data for estimatefee, then
 		// use fallbackFee.
@@ -364,8 +531,90 @@ func (w *Wallet) MarkSpent(outPoint *outpoint.OutPoint) {
 	}
 }
 
-func IsUnlockable(scriptPubKey *script.Script) bool {
-	if globalWallet == nil || scriptPubKey == nil {
+// LockCoin excludes an outpoint from the automatic coin selection.  A
+// persistent lock is saved to the wallet database and survives restarts.
+func (w *Wallet) LockCoin(outPoint *outpoint.OutPoint, persistent bool) error {
+	w.txnLock.Lock()
+	defer w.txnLock.Unlock()
+
+	if persistent {
+		if err := w.wdb.saveLockedCoin(outPoint); err != nil {
+			return err
+		}
+	} else if w.lockedCoins[*outPoint] {
+		if err := w.wdb.eraseLockedCoin(outPoint); err != nil {
+			return err
+		}
+	}
+	w.lockedCoins[*outPoint] = persistent
+	return nil
+}
+
+func (w *Wallet) UnlockCoin(outPoint *outpoint.OutPoint) error {
+	w.txnLock.Lock()
+	defer w.txnLock.Unlock()
+
+	return w.unlockCoin(outPoint)
+}
+
+func (w *Wallet) UnlockAllCoins() error {
+	w.txnLock.Lock()
+	defer w.txnLock.Unlock()
+
+	for outPoint := range w.lockedCoins {
+		op := outPoint
+		if err := w.unlockCoin(&op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Wallet) unlockCoin(outPoint *outpoint.OutPoint) error {
+	persistent, ok := w.lockedCoins[*outPoint]
+	if !ok {
+		return nil
+	}
+	if persistent {
+		if err := w.wdb.eraseLockedCoin(outPoint); err != nil {
+			return err
+		}
+	}
+	delete(w.lockedCoins, *outPoint)
+	return nil
+}
+
+func (w *Wallet) IsLockedCoin(outPoint *outpoint.OutPoint) bool {
+	w.txnLock.RLock()
+	defer w.txnLock.RUnlock()
+
+	_, ok := w.lockedCoins[*outPoint]
+	return ok
+}
+
+// ListLockedCoins returns the locked outpoints, sorted for a stable output.
+func (w *Wallet) ListLockedCoins() []*outpoint.OutPoint {
+	w.txnLock.RLock()
+	defer w.txnLock.RUnlock()
+
+	outPoints := make([]*outpoint.OutPoint, 0, len(w.lockedCoins))
+	for outPoint := range w.lockedCoins {
+		outPoints = append(outPoints, outpoint.NewOutPoint(outPoint.Hash, outPoint.Index))
+	}
+	sort.Slice(outPoints, func(i, j int) bool {
+		if outPoints[i].Hash != outPoints[j].Hash {
+			return outPoints[i].Hash.Cmp(&outPoints[j].Hash) < 0
+		}
+		return outPoints[i].Index < outPoints[j].Index
+	})
+	return outPoints
+}
+
+// IsUnlockable reports whether the wallet holds the keys, and the redeem
+// script for a pay-to-script-hash, needed to spend a coin locked by
+// scriptPubKey.
+func (w *Wallet) IsUnlockable(scriptPubKey *script.Script) bool {
+	if !w.enable || scriptPubKey == nil {
 		return false
 	}
 
@@ -375,7 +624,7 @@ func IsUnlockable(scriptPubKey *script.Script) bool {
 	}
 
 	if pubKeyType == script.ScriptHash {
-		redeemScript := globalWallet.GetScript(pubKeys[0])
+		redeemScript := w.GetScript(pubKeys[0])
 		if redeemScript == nil {
 			return false
 		}
@@ -387,10 +636,10 @@ func IsUnlockable(scriptPubKey *script.Script) bool {
 
 	if pubKeyType == script.ScriptPubkey {
 		pubKeyHash := util.Hash160(pubKeys[0])
-		return globalWallet.GetKeyPair(pubKeyHash) != nil
+		return w.GetKeyPair(pubKeyHash) != nil
 
 	} else if pubKeyType == script.ScriptPubkeyHash {
-		return globalWallet.GetKeyPair(pubKeys[0]) != nil
+		return w.GetKeyPair(pubKeys[0]) != nil
 
 	} else if pubKeyType == script.ScriptMultiSig {
 		// Only consider transactions "mine" if we own ALL the keys
@@ -401,7 +650,7 @@ func IsUnlockable(scriptPubKey *script.Script) bool {
 		for _, pubKey := range pubKeys {
 			if len(pubKey) >= 32 {
 				pubKeyHash := util.Hash160(pubKey)
-				if globalWallet.GetKeyPair(pubKeyHash) == nil {
+				if w.GetKeyPair(pubKeyHash) == nil {
 					return false
 				}
 			}
@@ -468,11 +717,30 @@ func (w *Wallet) GetCreditOut(out *txout.TxOut, filter uint8) amount.Amount {
 	return value
 }
 
+// IsChange reports whether an output of the wallet pays to an address that
+// was not handed out for receiving, which is the case of the change.
+func (w *Wallet) IsChange(out *txout.TxOut) bool {
+	if w.IsMine(out) == ISMINE_NO {
+		return false
+	}
+	_, addresses, _, err := out.GetScriptPubKey().ExtractDestinations()
+	if err != nil || len(addresses) != 1 {
+		return true
+	}
+	return w.GetAddressBook(addresses[0].EncodeToPubKeyHash()) == nil
+}
+
 func (w *Wallet) IsMine(out *txout.TxOut) uint8 {
-	//TODO wallet add ISMINE_WATCH_ONLY
-	if IsUnlockable(out.GetScriptPubKey()) {
+	return w.IsMineScript(out.GetScriptPubKey())
+}
+
+func (w *Wallet) IsMineScript(scriptPubKey *script.Script) uint8 {
+	if w.IsUnlockable(scriptPubKey) {
 		return ISMINE_SPENDABLE
 	}
+	if scriptPubKey != nil && w.HaveWatchOnly(scriptPubKey) {
+		return ISMINE_WATCH_ONLY
+	}
 
 	return ISMINE_NO
 }
@@ -508,6 +776,74 @@ func (w *Wallet) getRelatedTxns(txns []*tx.Tx) []*tx.Tx {
 	return relatedTxns
 }
 
+// AddRelatedTxns adds the transactions of a block related to the wallet and
+// returns how many there were.
+func (w *Wallet) AddRelatedTxns(txns []*tx.Tx, blockhash util.Hash) int {
+	relatedTxns := w.getRelatedTxns(txns)
+	if len(relatedTxns) > 0 {
+		w.addTxnsToWallet(relatedTxns, blockhash)
+	}
+	return len(relatedTxns)
+}
+
+// MarkDirty forgets the cached credits and debits of the wallet
+// transactions, which change when keys or scripts are added.
+func (w *Wallet) MarkDirty() {
+	w.txnLock.RLock()
+	defer w.txnLock.RUnlock()
+
+	for _, walletTx := range w.walletTxns {
+		walletTx.MarkDirty()
+	}
+}
+
+// IsFirstRun reports whether the wallet was created by this run of the node,
+// in which case it has no history in the blocks to scan for.
+func (w *Wallet) IsFirstRun() bool {
+	return w.firstRun
+}
+
+// GetBestBlock returns the locator of the last block the wallet processed,
+// nil if the wallet has not recorded one.
+func (w *Wallet) GetBestBlock() *chain.BlockLocator {
+	w.bestBlockLock.Lock()
+	defer w.bestBlockLock.Unlock()
+	return w.bestBlock
+}
+
+// SetBestBlock records index as the last block the wallet processed, so that
+// on restart it only scans the blocks after it.
+func (w *Wallet) SetBestBlock(index *blockindex.BlockIndex) error {
+	locator := chain.GetInstance().GetLocator(index)
+
+	w.bestBlockLock.Lock()
+	defer w.bestBlockLock.Unlock()
+	if err := w.wdb.saveBestBlock(locator); err != nil {
+		return err
+	}
+	w.bestBlock = locator
+	return nil
+}
+
+func (w *Wallet) isBestBlock(hash *util.Hash) bool {
+	w.bestBlockLock.Lock()
+	defer w.bestBlockLock.Unlock()
+	if w.bestBlock == nil || w.bestBlock.IsNull() {
+		return false
+	}
+	return w.bestBlock.GetBlockHashList()[0] == *hash
+}
+
+func (w *Wallet) updateBestBlock(hash util.Hash) {
+	index := chain.GetInstance().FindBlockIndex(hash)
+	if index == nil {
+		return
+	}
+	if err := w.SetBestBlock(index); err != nil {
+		log.Error("wallet save best block fail. error:%s", err.Error())
+	}
+}
+
 func (w *Wallet) HandleRelatedMempoolTx(txe *tx.Tx) {
 	// TODO: simple implementation just for testing, remove this after complete wallet
 	txes := []*tx.Tx{txe}
@@ -533,9 +869,12 @@ func (w *Wallet) handleBlockChainNotification(notification *chain.Notification)
 		blockHash := block.GetHash()
 		log.Info("wallet process block connect event. block:%s", blockHash.String())
 
-		relatedTxns := w.getRelatedTxns(block.Txs)
-		if len(relatedTxns) > 0 {
-			w.addTxnsToWallet(relatedTxns, blockHash)
+		w.AddRelatedTxns(block.Txs, blockHash)
+
+		// The best block only follows the blocks connected on top of it,
+		// the blocks the wallet missed are left for the rescan to process.
+		if w.isBestBlock(&block.Header.HashPrevBlock) {
+			w.updateBestBlock(blockHash)
 		}
 
 	case chain.NTBlockDisconnected:
@@ -547,6 +886,10 @@ func (w *Wallet) handleBlockChainNotification(notification *chain.Notification)
 		blockHash := block.GetHash()
 		log.Info("wallet process block disconnect event. block:%s", blockHash.String())
 
+		if w.isBestBlock(&blockHash) {
+			w.updateBestBlock(block.Header.HashPrevBlock)
+		}
+
 		w.txnLock.RLock()
 		defer w.txnLock.RUnlock()
 		for _, tx := range block.Txs {
@@ -2,27 +2,63 @@ package wallet
 
 import (
 	"bytes"
+	"encoding/binary"
 	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/persist/db"
 	"github.com/copernet/copernicus/util"
+	"github.com/pkg/errors"
+	"os"
 )
 
 type WalletDB struct {
 	*db.DBWrapper
+	path string
 }
 
-func (wdb *WalletDB) initDB() {
+const walletDBCacheSize = (1 << 20) * 8
+
+// walletRecords are the prefixes of all the records of the wallet database.
+var walletRecords = []byte{
+	db.DbWalletKey,
+	db.DbWalletScript,
+	db.DbWalletAddrBook,
+	db.DbWalletTx,
+	db.DbWalletCryptedKey,
+	db.DbWalletMasterKey,
+	db.DbWalletHDChain,
+	db.DbWalletKeyMeta,
+	db.DbWalletPool,
+	db.DbWalletWatchOnly,
+	db.DbWalletBestBlock,
+	db.DbWalletLockedCoin,
+}
+
+// walletDBPath returns the directory of the database of the wallet named
+// name.  The default wallet is kept in the data directory and the others in
+// its wallets directory.
+func walletDBPath(name string) string {
+	if name == DefaultWalletName {
+		return conf.DataDir + "/wallet"
+	}
+	return conf.DataDir + "/wallets/" + name
+}
+
+func (wdb *WalletDB) initDB() error {
 	walletDbCfg := &db.DBOption{
-		FilePath:  conf.DataDir + "/wallet",
-		CacheSize: (1 << 20) * 8,
+		FilePath:  wdb.path,
+		CacheSize: walletDBCacheSize,
 		Wipe:      false,
 	}
 
 	var err error
 	if wdb.DBWrapper, err = db.NewDBWrapper(walletDbCfg); err != nil {
-		panic("init wallet DB failed..." + err.Error())
+		return errors.Wrap(err, "init wallet DB failed")
 	}
+	return nil
 }
 
 func (wdb *WalletDB) loadSecrets() [][]byte {
@@ -37,6 +73,162 @@ func (wdb *WalletDB) loadSecrets() [][]byte {
 	return secrets
 }
 
+// loadPrivateKeys loads the unencrypted private keys.  Their public key is
+// stored along to tell uncompressed keys apart, and is missing for the keys
+// of older wallets, which are all compressed.
+func (wdb *WalletDB) loadPrivateKeys() []*crypto.PrivateKey {
+	itr := wdb.Iterator(nil)
+	defer itr.Close()
+	itr.Seek([]byte{db.DbWalletKey})
+
+	privateKeys := make([]*crypto.PrivateKey, 0)
+	for ; itr.Valid() && itr.GetKey()[0] == db.DbWalletKey; itr.Next() {
+		secret := make([]byte, len(itr.GetKey())-1)
+		copy(secret, itr.GetKey()[1:])
+		compressed := len(itr.GetVal()) == 0 || crypto.IsCompressedPubKey(itr.GetVal())
+		privateKeys = append(privateKeys, crypto.NewPrivateKeyFromBytes(secret, compressed))
+	}
+	return privateKeys
+}
+
+func (wdb *WalletDB) loadCryptedKeys() ([]*crypto.CryptedKey, error) {
+	itr := wdb.Iterator(nil)
+	defer itr.Close()
+	itr.Seek([]byte{db.DbWalletCryptedKey})
+
+	cryptedKeys := make([]*crypto.CryptedKey, 0)
+	for ; itr.Valid() && itr.GetKey()[0] == db.DbWalletCryptedKey; itr.Next() {
+		pubKey, err := crypto.ParsePubKey(itr.GetKey()[1:])
+		if err != nil {
+			return nil, err
+		}
+		secret := make([]byte, len(itr.GetVal()))
+		copy(secret, itr.GetVal())
+		cryptedKeys = append(cryptedKeys, &crypto.CryptedKey{PublicKey: pubKey, Secret: secret})
+	}
+	return cryptedKeys, nil
+}
+
+func (wdb *WalletDB) loadMasterKey() (*crypto.MasterKey, error) {
+	key := []byte{db.DbWalletMasterKey}
+	if !wdb.Exists(key) {
+		return nil, nil
+	}
+	val, err := wdb.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	masterKey := &crypto.MasterKey{}
+	if err := masterKey.Unserialize(bytes.NewBuffer(val)); err != nil {
+		return nil, err
+	}
+	return masterKey, nil
+}
+
+func (wdb *WalletDB) loadHDChain() (*HDChain, error) {
+	key := []byte{db.DbWalletHDChain}
+	if !wdb.Exists(key) {
+		return nil, nil
+	}
+	val, err := wdb.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	hdChain := &HDChain{}
+	if err := hdChain.Unserialize(bytes.NewBuffer(val)); err != nil {
+		return nil, err
+	}
+	return hdChain, nil
+}
+
+// loadBestBlock loads the locator of the last block the wallet is known to
+// have processed, nil if the wallet has not recorded one yet.
+func (wdb *WalletDB) loadBestBlock() (*chain.BlockLocator, error) {
+	key := []byte{db.DbWalletBestBlock}
+	if !wdb.Exists(key) {
+		return nil, nil
+	}
+	val, err := wdb.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	locator := &chain.BlockLocator{}
+	if err := locator.Unserialize(bytes.NewBuffer(val)); err != nil {
+		return nil, err
+	}
+	return locator, nil
+}
+
+// loadLockedCoins loads the outpoints locked with lockunspent persistently.
+func (wdb *WalletDB) loadLockedCoins() ([]*outpoint.OutPoint, error) {
+	itr := wdb.Iterator(nil)
+	defer itr.Close()
+	itr.Seek([]byte{db.DbWalletLockedCoin})
+
+	outPoints := make([]*outpoint.OutPoint, 0)
+	for ; itr.Valid() && itr.GetKey()[0] == db.DbWalletLockedCoin; itr.Next() {
+		outPoint := outpoint.NewDefaultOutPoint()
+		if err := outPoint.Unserialize(bytes.NewBuffer(itr.GetKey()[1:])); err != nil {
+			return nil, err
+		}
+		outPoints = append(outPoints, outPoint)
+	}
+	return outPoints, nil
+}
+
+func (wdb *WalletDB) loadKeyMetadata() (map[string]*KeyMetadata, error) {
+	itr := wdb.Iterator(nil)
+	defer itr.Close()
+	itr.Seek([]byte{db.DbWalletKeyMeta})
+
+	keyMetadata := make(map[string]*KeyMetadata)
+	for ; itr.Valid() && itr.GetKey()[0] == db.DbWalletKeyMeta; itr.Next() {
+		meta := &KeyMetadata{}
+		if err := meta.Unserialize(bytes.NewBuffer(itr.GetVal())); err != nil {
+			return nil, err
+		}
+		keyID := util.Hash160(itr.GetKey()[1:])
+		keyMetadata[string(keyID)] = meta
+	}
+	return keyMetadata, nil
+}
+
+func (wdb *WalletDB) loadKeyPool() (map[int64]*KeyPoolEntry, error) {
+	itr := wdb.Iterator(nil)
+	defer itr.Close()
+	itr.Seek([]byte{db.DbWalletPool})
+
+	keyPool := make(map[int64]*KeyPoolEntry)
+	for ; itr.Valid() && itr.GetKey()[0] == db.DbWalletPool; itr.Next() {
+		if len(itr.GetKey()) != 9 {
+			return nil, errors.New("invalid keypool entry key")
+		}
+		entry := &KeyPoolEntry{}
+		if err := entry.Unserialize(bytes.NewBuffer(itr.GetVal())); err != nil {
+			return nil, err
+		}
+		index := int64(binary.BigEndian.Uint64(itr.GetKey()[1:]))
+		keyPool[index] = entry
+	}
+	return keyPool, nil
+}
+
+func (wdb *WalletDB) loadWatchOnly() ([]*script.Script, error) {
+	itr := wdb.Iterator(nil)
+	defer itr.Close()
+	itr.Seek([]byte{db.DbWalletWatchOnly})
+
+	scripts := make([]*script.Script, 0)
+	for ; itr.Valid() && itr.GetKey()[0] == db.DbWalletWatchOnly; itr.Next() {
+		sc := script.NewEmptyScript()
+		if err := sc.Unserialize(bytes.NewBuffer(itr.GetKey()[1:]), false); err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, sc)
+	}
+	return scripts, nil
+}
+
 func (wdb *WalletDB) loadScripts() ([]*script.Script, error) {
 	itr := wdb.Iterator(nil)
 	defer itr.Close()
@@ -86,11 +278,159 @@ func (wdb *WalletDB) loadTransactions() ([]*WalletTx, error) {
 	return txns, nil
 }
 
-func (wdb *WalletDB) saveSecret(secret []byte) error {
-	key := getDBKey(db.DbWalletKey, secret)
+func (wdb *WalletDB) saveCryptedKey(pubKey *crypto.PublicKey, secret []byte) error {
+	key := getDBKey(db.DbWalletCryptedKey, pubKey.ToBytes())
+	return wdb.Write(key, secret, true)
+}
+
+func (wdb *WalletDB) saveMasterKey(masterKey *crypto.MasterKey) error {
+	w := new(bytes.Buffer)
+	if err := masterKey.Serialize(w); err != nil {
+		return err
+	}
+	return wdb.Write([]byte{db.DbWalletMasterKey}, w.Bytes(), true)
+}
+
+// encryptSecrets atomically replaces the unencrypted private keys by their
+// encrypted form, then rewrites the database so that the unencrypted keys do
+// not linger in its files.
+func (wdb *WalletDB) encryptSecrets(masterKey *crypto.MasterKey, cryptedKeys []*crypto.CryptedKey,
+	secrets [][]byte) error {
+
+	w := new(bytes.Buffer)
+	if err := masterKey.Serialize(w); err != nil {
+		return err
+	}
+
+	batch := db.NewBatchWrapper(wdb.DBWrapper)
+	batch.Write([]byte{db.DbWalletMasterKey}, w.Bytes())
+	for _, cryptedKey := range cryptedKeys {
+		batch.Write(getDBKey(db.DbWalletCryptedKey, cryptedKey.PublicKey.ToBytes()), cryptedKey.Secret)
+	}
+	for _, secret := range secrets {
+		batch.Erase(getDBKey(db.DbWalletKey, secret))
+	}
+	if err := wdb.WriteBatch(batch, true); err != nil {
+		return err
+	}
+	return wdb.rewrite()
+}
+
+// rewrite copies the wallet records to a new database which then replaces
+// the current one.  LevelDB keeps erased keys in its files and manifest for
+// an unspecified time, which is not acceptable for private keys.
+func (wdb *WalletDB) rewrite() error {
+	path := wdb.path
+	newPath := path + ".rewrite"
+	oldPath := path + ".old"
+
+	newDB, err := db.NewDBWrapper(&db.DBOption{
+		FilePath:  newPath,
+		CacheSize: walletDBCacheSize,
+		Wipe:      true,
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := db.NewBatchWrapper(newDB)
+	for _, prefix := range walletRecords {
+		itr := wdb.Iterator(nil)
+		for itr.Seek([]byte{prefix}); itr.Valid() && itr.GetKey()[0] == prefix; itr.Next() {
+			batch.Write(itr.GetKey(), itr.GetVal())
+		}
+		itr.Close()
+	}
+	err = newDB.WriteBatch(batch, true)
+	newDB.Close()
+	if err != nil {
+		os.RemoveAll(newPath)
+		return err
+	}
+
+	wdb.Close()
+	if err := os.Rename(path, oldPath); err != nil {
+		wdb.initDB()
+		return err
+	}
+	if err := os.Rename(newPath, path); err != nil {
+		os.Rename(oldPath, path)
+		wdb.initDB()
+		return err
+	}
+	if err := wdb.initDB(); err != nil {
+		return err
+	}
+	return os.RemoveAll(oldPath)
+}
+
+// saveKeys atomically saves new keys along with their metadata, the keypool
+// entries handing them out and the state of the HD chain they come from.
+func (wdb *WalletDB) saveKeys(keys []*newKey, keyPool map[int64]*KeyPoolEntry, hdChain *HDChain) error {
+	batch := db.NewBatchWrapper(wdb.DBWrapper)
+	for _, key := range keys {
+		pubKey := key.privateKey.PubKey()
+		if key.cryptedSecret != nil {
+			batch.Write(getDBKey(db.DbWalletCryptedKey, pubKey.ToBytes()), key.cryptedSecret)
+		} else {
+			batch.Write(getDBKey(db.DbWalletKey, key.privateKey.GetBytes()), pubKey.ToBytes())
+		}
+
+		w := new(bytes.Buffer)
+		if err := key.meta.Serialize(w); err != nil {
+			return err
+		}
+		batch.Write(getDBKey(db.DbWalletKeyMeta, pubKey.ToBytes()), w.Bytes())
+	}
+	for index, entry := range keyPool {
+		w := new(bytes.Buffer)
+		if err := entry.Serialize(w); err != nil {
+			return err
+		}
+		batch.Write(getKeyPoolDBKey(index), w.Bytes())
+	}
+	if hdChain != nil {
+		w := new(bytes.Buffer)
+		if err := hdChain.Serialize(w); err != nil {
+			return err
+		}
+		batch.Write([]byte{db.DbWalletHDChain}, w.Bytes())
+	}
+	return wdb.WriteBatch(batch, true)
+}
+
+func (wdb *WalletDB) eraseKeyPool(indices []int64) error {
+	batch := db.NewBatchWrapper(wdb.DBWrapper)
+	for _, index := range indices {
+		batch.Erase(getKeyPoolDBKey(index))
+	}
+	return wdb.WriteBatch(batch, true)
+}
+
+func (wdb *WalletDB) saveBestBlock(locator *chain.BlockLocator) error {
+	w := new(bytes.Buffer)
+	if err := locator.Serialize(w); err != nil {
+		return err
+	}
+	return wdb.Write([]byte{db.DbWalletBestBlock}, w.Bytes(), true)
+}
+
+func (wdb *WalletDB) saveLockedCoin(outPoint *outpoint.OutPoint) error {
+	key, err := getLockedCoinDBKey(outPoint)
+	if err != nil {
+		return err
+	}
 	return wdb.Write(key, []byte{}, true)
 }
 
+func (wdb *WalletDB) eraseLockedCoin(outPoint *outpoint.OutPoint) error {
+	key, err := getLockedCoinDBKey(outPoint)
+	if err != nil {
+		return err
+	}
+	return wdb.Erase(key, true)
+}
+
 func (wdb *WalletDB) saveScript(sc *script.Script) error {
 	w := new(bytes.Buffer)
 	err := sc.Serialize(w)
@@ -102,6 +442,17 @@ func (wdb *WalletDB) saveScript(sc *script.Script) error {
 	return wdb.Write(key, []byte{}, true)
 }
 
+func (wdb *WalletDB) saveWatchOnly(sc *script.Script) error {
+	w := new(bytes.Buffer)
+	err := sc.Serialize(w)
+	if err != nil {
+		return err
+	}
+
+	key := getDBKey(db.DbWalletWatchOnly, w.Bytes())
+	return wdb.Write(key, []byte{}, true)
+}
+
 func (wdb *WalletDB) saveAddressBook(keyHash []byte, data *AddressBookData) error {
 	w := new(bytes.Buffer)
 	err := data.Serialize(w)
@@ -130,6 +481,20 @@ func (wdb *WalletDB) removeWalletTx(txHash *util.Hash) error {
 	return wdb.Erase(key, true)
 }
 
+func getKeyPoolDBKey(index int64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(index))
+	return getDBKey(db.DbWalletPool, buf[:])
+}
+
+func getLockedCoinDBKey(outPoint *outpoint.OutPoint) ([]byte, error) {
+	w := new(bytes.Buffer)
+	if err := outPoint.Serialize(w); err != nil {
+		return nil, err
+	}
+	return getDBKey(db.DbWalletLockedCoin, w.Bytes()), nil
+}
+
 func getDBKey(dbID byte, orgKey []byte) []byte {
 	dbKey := make([]byte, 0, len(orgKey)+1)
 	dbKey = append(dbKey, dbID)
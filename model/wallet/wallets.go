@@ -0,0 +1,129 @@
+package wallet
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/pkg/errors"
+)
+
+// DefaultWalletName is the name of the wallet loaded at startup.
+const DefaultWalletName = ""
+
+var (
+	ErrWalletNotFound    = errors.New("Requested wallet does not exist or is not loaded")
+	ErrWalletExists      = errors.New("Wallet already exists")
+	ErrWalletLoaded      = errors.New("Wallet is already loaded")
+	ErrInvalidWalletName = errors.New("Invalid wallet name")
+)
+
+var (
+	walletsLock sync.RWMutex
+	wallets     = make(map[string]*Wallet)
+
+	// disabledWallet stands for the default wallet when it is not loaded.
+	disabledWallet = &Wallet{enable: false}
+)
+
+// CreateWallet creates a new wallet named name and loads it.
+func CreateWallet(name string) (*Wallet, error) {
+	if err := checkWalletName(name); err != nil {
+		return nil, err
+	}
+
+	walletsLock.Lock()
+	defer walletsLock.Unlock()
+
+	if _, ok := wallets[name]; ok || conf.FileExists(walletDBPath(name)) {
+		return nil, ErrWalletExists
+	}
+	return openWallet(name)
+}
+
+// LoadWallet loads the existing wallet named name.
+func LoadWallet(name string) (*Wallet, error) {
+	if err := checkWalletName(name); err != nil {
+		return nil, err
+	}
+
+	walletsLock.Lock()
+	defer walletsLock.Unlock()
+
+	if _, ok := wallets[name]; ok {
+		return nil, ErrWalletLoaded
+	}
+	if !conf.FileExists(walletDBPath(name)) {
+		return nil, ErrWalletNotFound
+	}
+	return openWallet(name)
+}
+
+// UnloadWallet stops the wallet named name from following the chain and
+// closes its database.
+func UnloadWallet(name string) error {
+	walletsLock.Lock()
+	w, ok := wallets[name]
+	delete(wallets, name)
+	walletsLock.Unlock()
+	if !ok {
+		return ErrWalletNotFound
+	}
+
+	chain.GetInstance().Unsubscribe(w.subscription)
+	if w.IsCrypted() {
+		w.LockWallet()
+	}
+	w.wdb.Close()
+	return nil
+}
+
+// GetWallet returns the loaded wallet named name, nil if there is none.
+func GetWallet(name string) *Wallet {
+	walletsLock.RLock()
+	defer walletsLock.RUnlock()
+	return wallets[name]
+}
+
+// GetWallets returns the loaded wallets, sorted by name.
+func GetWallets() []*Wallet {
+	walletsLock.RLock()
+	defer walletsLock.RUnlock()
+
+	loaded := make([]*Wallet, 0, len(wallets))
+	for _, w := range wallets {
+		loaded = append(loaded, w)
+	}
+	sort.Slice(loaded, func(i, j int) bool {
+		return loaded[i].name < loaded[j].name
+	})
+	return loaded
+}
+
+// openWallet loads the wallet named name, creating it if it does not exist,
+// and subscribes it to the chain notifications.  It must be called with
+// walletsLock held.
+func openWallet(name string) (*Wallet, error) {
+	w := newWallet(name)
+	if err := w.Init(); err != nil {
+		return nil, err
+	}
+	w.subscription = chain.GetInstance().Subscribe(w.handleBlockChainNotification)
+	wallets[name] = w
+	return w, nil
+}
+
+// checkWalletName rejects the names which are not a plain directory name,
+// so that every wallet stays within the wallets directory.
+func checkWalletName(name string) error {
+	if name == DefaultWalletName {
+		return nil
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) || filepath.Base(name) != name {
+		return ErrInvalidWalletName
+	}
+	return nil
+}
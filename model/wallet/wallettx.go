@@ -5,6 +5,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/copernet/copernicus/model/blockindex"
 	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/consensus"
 	"github.com/copernet/copernicus/model/mempool"
@@ -22,9 +23,23 @@ type Recipient struct {
 	SubtractFeeFromAmount bool
 }
 
+// OutputEntry is an output of a wallet transaction, as received or sent by
+// the wallet.
+type OutputEntry struct {
+	ScriptPubKey *script.Script
+	Amount       amount.Amount
+	Vout         int
+}
+
+// abandonHash stands for the block hash of an abandoned transaction, it is
+// not the hash of any valid block.
+var abandonHash = util.HashOne
+
 type WalletTx struct {
 	*tx.Tx
 
+	pwallet *Wallet
+
 	ExtInfo map[string]string
 
 	TimeReceived int64
@@ -59,12 +74,14 @@ func NewEmptyWalletTx() *WalletTx {
 	return &WalletTx{}
 }
 
-func NewWalletTx(txn *tx.Tx, blockhash util.Hash, extInfo map[string]string, isFromMe bool, account string) *WalletTx {
+func NewWalletTx(pwallet *Wallet, txn *tx.Tx, blockhash util.Hash, extInfo map[string]string, isFromMe bool,
+	account string) *WalletTx {
 	if extInfo == nil {
 		extInfo = make(map[string]string)
 	}
 	return &WalletTx{
 		Tx:              txn,
+		pwallet:         pwallet,
 		ExtInfo:         extInfo,
 		TimeReceived:    time.Now().Unix(),
 		IsFromMe:        isFromMe,
@@ -172,6 +189,37 @@ func (wtx *WalletTx) GetDepthInMainChain() int32 {
 
 }
 
+// GetBlockIndex returns the index of the block of the active chain holding
+// the transaction, nil if it is not confirmed.
+func (wtx *WalletTx) GetBlockIndex() *blockindex.BlockIndex {
+	if wtx.GetDepthInMainChain() <= 0 {
+		return nil
+	}
+	return chain.GetInstance().GetIndex(wtx.blockHeight)
+}
+
+// GetBlocksToMaturity returns how many more blocks a coinbase transaction
+// needs before its outputs can be spent.
+func (wtx *WalletTx) GetBlocksToMaturity() int32 {
+	if !wtx.IsCoinBase() {
+		return 0
+	}
+	blocks := consensus.CoinbaseMaturity + 1 - wtx.GetDepthInMainChain()
+	if blocks < 0 {
+		return 0
+	}
+	return blocks
+}
+
+// GetTxTime returns when the transaction was received, or the time of its
+// block if it was found in the block later on, as by a rescan.
+func (wtx *WalletTx) GetTxTime() int64 {
+	if index := wtx.GetBlockIndex(); index != nil && int64(index.GetBlockTime()) < wtx.TimeReceived {
+		return int64(index.GetBlockTime())
+	}
+	return wtx.TimeReceived
+}
+
 func (wtx *WalletTx) CheckFinalForForCurrentBlock() bool {
 	lockTimeCutoff := chain.GetInstance().Tip().GetMedianTimePast()
 	height := chain.GetInstance().Height() + 1
@@ -196,7 +244,7 @@ func (wtx *WalletTx) GetAvailableCredit(useCache bool) amount.Amount {
 		if coin == nil {
 			continue
 		}
-		if IsUnlockable(coin.GetScriptPubKey()) {
+		if wtx.pwallet.IsUnlockable(coin.GetScriptPubKey()) {
 			credit += coin.GetAmount()
 		}
 	}
@@ -205,12 +253,44 @@ func (wtx *WalletTx) GetAvailableCredit(useCache bool) amount.Amount {
 	return credit
 }
 
+// MarkDirty forgets the cached credits and debits of the transaction.
+func (wtx *WalletTx) MarkDirty() {
+	wtx.availableCredit = nil
+	wtx.fDebitCached = false
+	wtx.fCreditCached = false
+	wtx.fWatchDebitCached = false
+	wtx.fWatchCreditCached = false
+}
+
 func (wtx *WalletTx) MarkSpent(index int) {
 	if index < len(wtx.spentStatus) {
 		wtx.spentStatus[index] = true
 	}
 }
 
+// MarkUnspent undoes MarkSpent, for an output whose spending transaction
+// was abandoned.
+func (wtx *WalletTx) MarkUnspent(index int) {
+	if index < len(wtx.spentStatus) {
+		wtx.spentStatus[index] = false
+	}
+}
+
+// IsAbandoned reports whether the transaction was abandoned, which lasts
+// until it is seen again in the mempool or in a block.
+func (wtx *WalletTx) IsAbandoned() bool {
+	return wtx.blockHash == abandonHash
+}
+
+func (wtx *WalletTx) setAbandoned() {
+	wtx.blockHash = abandonHash
+	wtx.blockHeight = 0
+}
+
+func (wtx *WalletTx) InMempool() bool {
+	return mempool.GetInstance().HaveTransaction(wtx.Tx)
+}
+
 func (wtx *WalletTx) GetUnspentCoin(index int) *utxo.Coin {
 	if index >= wtx.GetOutsCount() {
 		return nil
@@ -242,7 +322,7 @@ func (wtx *WalletTx) GetDebit(filter uint8) amount.Amount {
 	if len(wtx.GetIns()) == 0 {
 		return 0
 	}
-	pwallet := GetInstance()
+	pwallet := wtx.pwallet
 	var debit amount.Amount
 	if (filter & ISMINE_SPENDABLE) != 0 {
 		if wtx.fDebitCached {
@@ -274,7 +354,7 @@ func (wtx *WalletTx) GetCredit(filter uint8) amount.Amount {
 		return 0
 	}
 
-	pwallet := GetInstance()
+	pwallet := wtx.pwallet
 	var credit amount.Amount
 	if (filter & ISMINE_SPENDABLE) != 0 {
 		if wtx.fCreditCached {
@@ -298,3 +378,40 @@ func (wtx *WalletTx) GetCredit(filter uint8) amount.Amount {
 
 	return credit
 }
+
+// GetAmounts returns the outputs of the transaction received by the wallet
+// and, if the wallet funded the transaction, the outputs it sent to others
+// along with the fee it paid.
+func (wtx *WalletTx) GetAmounts(filter uint8) (received []*OutputEntry, sent []*OutputEntry, fee amount.Amount) {
+	pwallet := wtx.pwallet
+	debit := wtx.GetDebit(filter)
+	if debit > 0 {
+		fee = debit - wtx.GetValueOut()
+	}
+
+	for i, out := range wtx.GetOuts() {
+		isMine := pwallet.IsMine(out)
+		// The change of the transactions the wallet sent is neither sent
+		// nor received.
+		if debit > 0 {
+			if pwallet.IsChange(out) {
+				continue
+			}
+		} else if isMine&filter == 0 {
+			continue
+		}
+
+		entry := &OutputEntry{
+			ScriptPubKey: out.GetScriptPubKey(),
+			Amount:       out.GetValue(),
+			Vout:         i,
+		}
+		if debit > 0 {
+			sent = append(sent, entry)
+		}
+		if isMine&filter != 0 {
+			received = append(received, entry)
+		}
+	}
+	return
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/net/wire"
 	"github.com/copernet/copernicus/peer"
 	"github.com/copernet/copernicus/rpc/btcjson"
@@ -70,6 +71,7 @@ out:
 				}
 				peerFrom.SetAckReceived(true)
 				peerFrom.PushSendHeadersMsg()
+				peerFrom.PushSendCmpctMsg(false)
 				if peerFrom.Cfg.Listeners.OnVerAck != nil {
 					peerFrom.Cfg.Listeners.OnVerAck(peerFrom, data)
 				}
@@ -186,6 +188,30 @@ out:
 					peerFrom.Cfg.Listeners.OnSendHeaders(peerFrom, data)
 				}
 				msg.Done <- struct{}{}
+			case *wire.MsgSendCmpct:
+				peerFrom.SetSendCmpct(data.AnnounceUsingCmpctBlock, data.CmpctBlockVersion)
+				if peerFrom.Cfg.Listeners.OnSendCmpct != nil {
+					peerFrom.Cfg.Listeners.OnSendCmpct(peerFrom, data)
+				}
+				msg.Done <- struct{}{}
+			case *wire.MsgCmpctBlock:
+				if peerFrom.Cfg.Listeners.OnCmpctBlock != nil {
+					peerFrom.Cfg.Listeners.OnCmpctBlock(peerFrom, data, msg.Done)
+				} else {
+					msg.Done <- struct{}{}
+				}
+			case *wire.MsgGetBlockTxn:
+				if peerFrom.Cfg.Listeners.OnGetBlockTxn != nil {
+					peerFrom.Cfg.Listeners.OnGetBlockTxn(peerFrom, data, msg.Done)
+				} else {
+					msg.Done <- struct{}{}
+				}
+			case *wire.MsgBlockTxn:
+				if peerFrom.Cfg.Listeners.OnBlockTxn != nil {
+					peerFrom.Cfg.Listeners.OnBlockTxn(peerFrom, data, msg.Done)
+				} else {
+					msg.Done <- struct{}{}
+				}
 			default:
 				log.Debug("Received unhandled message of type %v "+
 					"from %v", data, data.Command())
@@ -315,6 +341,13 @@ func ProcessForRPC(message interface{}) (rsp interface{}, err error) {
 		msgHandle.RelayInventory(m, nil)
 		return nil, nil
 
+	case *tx.Tx:
+		// The tx is cached by the relayer, so that it is served to the
+		// peers even if it is not in the mempool.
+		hash := m.GetHash()
+		msgHandle.RelayInventory(wire.NewInvVect(wire.InvTypeTx, &hash), m)
+		return nil, nil
+
 	case *block.Block:
 		done := make(chan error)
 		msgHandle.HandleMinedBlock(m, done)
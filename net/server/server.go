@@ -69,6 +69,14 @@ const (
 	// increase the num in case cut out inv
 	maxBlocksToAnnounce = 8
 
+	// maxCmpctBlockDepth is the depth beyond which a block requested as a
+	// compact block is sent in full.
+	maxCmpctBlockDepth = 5
+
+	// maxBlockTxnDepth is the depth beyond which a block whose transactions
+	// are requested by a getblocktxn message is sent in full.
+	maxBlockTxnDepth = 10
+
 	BanReasonNodeMisbehaving int = 1
 	BanReasonManuallyAdded   int = 2
 
@@ -297,6 +305,12 @@ type Server struct {
 	MsgChan chan *peer.PeerMessage
 
 	txRelayer *TxRelayer
+
+	// recentBlock is the last block relayed, kept along with its cmpctblock
+	// message to answer the requests of the peers it is announced to.
+	recentBlockMtx   sync.Mutex
+	recentBlock      *block.Block
+	recentCmpctBlock *wire.MsgCmpctBlock
 }
 
 // serverPeer extends the peer to maintain state shared by the server and
@@ -603,6 +617,32 @@ func (sp *serverPeer) OnBlock(_ *peer.Peer, msg *wire.MsgBlock, buf []byte, done
 	sp.server.syncManager.QueueBlock(block, buf, sp.Peer, done)
 }
 
+// OnCmpctBlock is invoked when a peer receives a cmpctblock bitcoin message.
+// Like OnBlock, it blocks until the compact block has been processed.
+func (sp *serverPeer) OnCmpctBlock(_ *peer.Peer, msg *wire.MsgCmpctBlock, done chan<- struct{}) {
+	hash := msg.Header.GetHash()
+	sp.AddKnownInventory(wire.NewInvVect(wire.InvTypeBlock, &hash))
+
+	sp.server.syncManager.QueueCmpctBlock(msg, sp.Peer, done)
+}
+
+// OnGetBlockTxn is invoked when a peer receives a getblocktxn bitcoin message
+// and is used to deliver the requested transactions of a block.
+func (sp *serverPeer) OnGetBlockTxn(_ *peer.Peer, msg *wire.MsgGetBlockTxn, done chan<- struct{}) {
+	go func() {
+		doneChan := make(chan struct{}, 1)
+		sp.server.pushBlockTxnMsg(sp, msg, doneChan)
+		<-doneChan
+		done <- struct{}{}
+	}()
+}
+
+// OnBlockTxn is invoked when a peer receives a blocktxn bitcoin message, which
+// completes a compact block.  It blocks until the block has been processed.
+func (sp *serverPeer) OnBlockTxn(_ *peer.Peer, msg *wire.MsgBlockTxn, done chan<- struct{}) {
+	sp.server.syncManager.QueueBlockTxn(msg, sp.Peer, done)
+}
+
 // OnInv is invoked when a peer receives an inv bitcoin message and is
 // used to examine the inventory being advertised by the remote peer and react
 // accordingly.  We pass the message down to blockmanager which will call
@@ -687,6 +727,8 @@ func (sp *serverPeer) doGetData(msg *wire.MsgGetData, done chan<- struct{}) {
 			err = sp.server.pushTxMsg(sp, &iv.Hash, c, waitChan, wire.BaseEncoding)
 		case wire.InvTypeBlock:
 			err = sp.server.pushBlockMsg(sp, &iv.Hash, c, waitChan, wire.BaseEncoding)
+		case wire.InvTypeCompatedBlock:
+			err = sp.server.pushCmpctBlockMsg(sp, &iv.Hash, c, waitChan, wire.BaseEncoding)
 			// case wire.InvTypeFilteredBlock:
 			// 	err = sp.server.pushMerkleBlockMsg(sp, &iv.Hash, c, waitChan, wire.BaseEncoding)
 		default:
@@ -1201,6 +1243,108 @@ func (s *Server) pushBlockMsg(sp *serverPeer, hash *util.Hash, doneChan chan<- s
 	return nil
 }
 
+// getRecentBlock returns the last block relayed and its cmpctblock message if
+// the block hash matches, or nils otherwise.
+func (s *Server) getRecentBlock(hash *util.Hash) (*block.Block, *wire.MsgCmpctBlock) {
+	s.recentBlockMtx.Lock()
+	defer s.recentBlockMtx.Unlock()
+
+	if s.recentBlock == nil || s.recentBlock.GetHash() != *hash {
+		return nil, nil
+	}
+	return s.recentBlock, s.recentCmpctBlock
+}
+
+// pushCmpctBlockMsg sends a cmpctblock message for the provided block hash to
+// the connected peer.  A block message is sent instead when the peer does not
+// support compact blocks or when the block is too deep in the chain to be
+// rebuilt from the mempool.  An error is returned if the block hash is not
+// known.
+func (s *Server) pushCmpctBlockMsg(sp *serverPeer, hash *util.Hash, doneChan chan<- struct{},
+	waitChan <-chan struct{}, encoding wire.MessageEncoding) error {
+
+	if sp.ProtocolVersion() < wire.ShortIdsBlocksVersion {
+		return s.pushBlockMsg(sp, hash, doneChan, waitChan, encoding)
+	}
+
+	_, cmpctBlock := s.getRecentBlock(hash)
+	if cmpctBlock == nil {
+		blkIndex, send := findBlockIndex(hash)
+		if !send || !blkIndex.HasData() ||
+			chain.GetInstance().Height()-blkIndex.Height > maxCmpctBlockDepth {
+			return s.pushBlockMsg(sp, hash, doneChan, waitChan, encoding)
+		}
+
+		bl, err := lblock.GetBlockByIndex(blkIndex, s.chainParams)
+		if err != nil {
+			log.Trace("Unable to fetch requested block hash %v: %v",
+				hash, err)
+
+			if doneChan != nil {
+				doneChan <- struct{}{}
+			}
+			return err
+		}
+		nonce, err := util.RandomUint64()
+		if err != nil {
+			if doneChan != nil {
+				doneChan <- struct{}{}
+			}
+			return err
+		}
+		cmpctBlock = wire.NewMsgCmpctBlock(bl, nonce)
+	}
+
+	// Once we have fetched data wait for any previous operation to finish.
+	if waitChan != nil {
+		<-waitChan
+	}
+	sp.QueueMessageWithEncoding(cmpctBlock, doneChan, encoding)
+
+	return nil
+}
+
+// pushBlockTxnMsg sends a blocktxn message with the transactions requested by
+// a getblocktxn message to the connected peer.  The full block is sent
+// instead when it is too deep in the chain.  The done channel is always
+// signalled.
+func (s *Server) pushBlockTxnMsg(sp *serverPeer, msg *wire.MsgGetBlockTxn, doneChan chan<- struct{}) {
+	hash := &msg.BlockHash
+	bl, _ := s.getRecentBlock(hash)
+	if bl == nil {
+		blkIndex, send := findBlockIndex(hash)
+		if !send || !blkIndex.HasData() {
+			log.Debug("Peer %v requested transactions of unknown block %s", sp, hash)
+			doneChan <- struct{}{}
+			return
+		}
+		if chain.GetInstance().Height()-blkIndex.Height > maxBlockTxnDepth {
+			log.Debug("Peer %v requested transactions of old block %s, sending full block", sp, hash)
+			s.pushBlockMsg(sp, hash, doneChan, nil, wire.BaseEncoding)
+			return
+		}
+
+		var err error
+		bl, err = lblock.GetBlockByIndex(blkIndex, s.chainParams)
+		if err != nil {
+			log.Trace("Unable to fetch requested block hash %v: %v", hash, err)
+			doneChan <- struct{}{}
+			return
+		}
+	}
+
+	txs := make([]*tx.Tx, 0, len(msg.Indexes))
+	for _, index := range msg.Indexes {
+		if int(index) >= len(bl.Txs) {
+			sp.addBanScore(100, 0, "out-of-bound-tx-index")
+			doneChan <- struct{}{}
+			return
+		}
+		txs = append(txs, bl.Txs[index])
+	}
+	sp.QueueMessage(wire.NewMsgBlockTxn(hash, txs), doneChan)
+}
+
 func findBlockIndex(hash *util.Hash) (blkIndex *blockindex.BlockIndex, send bool) {
 	persist.CsMain.Lock() //to protect chain.indexMap
 	defer persist.CsMain.Unlock()
@@ -1656,6 +1800,13 @@ func (s *Server) handleRelayInvMsg(state *peerState, msg relayMsg) {
 		s.txRelayer.Cache(&msg.invVect.Hash, tx)
 	}
 
+	// A new block is first announced with cmpctblock messages to the peers
+	// asking for it, then with headers or inv messages to the others.
+	if blk, ok := msg.data.(*block.Block); ok && msg.invVect.Type == wire.InvTypeBlock {
+		s.handleRelayCmpctBlock(state, msg.invVect, blk)
+		msg.data = &blk.Header
+	}
+
 	state.forAllPeers(func(sp *serverPeer) {
 		if !sp.Connected() || !sp.VerAckReceived() {
 			return
@@ -1717,6 +1868,34 @@ func (s *Server) handleRelayInvMsg(state *peerState, msg relayMsg) {
 	})
 }
 
+// handleRelayCmpctBlock caches the block being relayed along with its
+// cmpctblock message, and sends the message to the peers in high bandwidth
+// mode.  It is invoked from the peerHandler goroutine.
+func (s *Server) handleRelayCmpctBlock(state *peerState, iv *wire.InvVect, blk *block.Block) {
+	nonce, err := util.RandomUint64()
+	if err != nil {
+		log.Error("Failed to generate cmpctblock nonce: %v", err)
+		return
+	}
+	cmpctBlock := wire.NewMsgCmpctBlock(blk, nonce)
+
+	s.recentBlockMtx.Lock()
+	s.recentBlock = blk
+	s.recentCmpctBlock = cmpctBlock
+	s.recentBlockMtx.Unlock()
+
+	state.forAllPeers(func(sp *serverPeer) {
+		if !sp.Connected() || !sp.VerAckReceived() || !sp.WantsCmpctBlocks() {
+			return
+		}
+		if sp.IsKnownInventory(iv) {
+			return
+		}
+		sp.AddKnownInventory(iv)
+		sp.QueueMessage(cmpctBlock, nil)
+	})
+}
+
 func (s *Server) handleMinedBlock(mb minedBlockMsg) {
 	s.syncManager.QueueMinedBlock(mb.block, mb.done)
 }
@@ -1947,6 +2126,9 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 			OnRead:                     sp.OnRead,
 			OnWrite:                    sp.OnWrite,
 			OnTransferMsgToBusinessPro: sp.TransferMsgToBusinessPro,
+			OnCmpctBlock:               sp.OnCmpctBlock,
+			OnGetBlockTxn:              sp.OnGetBlockTxn,
+			OnBlockTxn:                 sp.OnBlockTxn,
 
 			// Note: The reference client currently bans peers that send alerts
 			// not signed with its key.  We could verify against their key, but
@@ -2593,6 +2775,10 @@ func NewServer(chainParams *model.BitcoinParams, ts *util.MedianTime, interrupt
 	if cfg.Protocol.NoPeerBloomFilters {
 		services &^= wire.SFNodeBloom
 	}
+	// a pruned node can not serve the full block chain
+	if cfg.Chain.Prune > 0 {
+		services &^= wire.SFNodeNetwork
+	}
 
 	amgr := addrmgr.New(conf.DataDir, net.LookupIP)
 
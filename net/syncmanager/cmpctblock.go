@@ -0,0 +1,348 @@
+package syncmanager
+
+import (
+	"errors"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lblock"
+	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/logic/lmerkleroot"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/net/wire"
+	"github.com/copernet/copernicus/peer"
+	"github.com/copernet/copernicus/util"
+)
+
+// maxCmpctHighBandwidthPeers is the number of peers asked to announce new
+// blocks with cmpctblock messages (BIP0152 high bandwidth mode).
+const maxCmpctHighBandwidthPeers = 3
+
+var (
+	// errInvalidCmpctBlock means the compact block or the transactions sent
+	// to complete it are malformed, which is a protocol violation.
+	errInvalidCmpctBlock = errors.New("invalid compact block")
+
+	// errShortIDCollision means the block could not be rebuilt from its
+	// short ids, so that the full block has to be downloaded instead.
+	errShortIDCollision = errors.New("short id collision")
+)
+
+// cmpctBlockMsg packages a bitcoin cmpctblock message and the peer it came
+// from together so the block handler has access to that information.
+type cmpctBlockMsg struct {
+	cmpctBlock *wire.MsgCmpctBlock
+	peer       *peer.Peer
+	reply      chan<- struct{}
+}
+
+// blockTxnMsg packages a bitcoin blocktxn message and the peer it came from
+// together so the block handler has access to that information.
+type blockTxnMsg struct {
+	blockTxn *wire.MsgBlockTxn
+	peer     *peer.Peer
+	reply    chan<- struct{}
+}
+
+// partialBlock is a block announced by a cmpctblock message being rebuilt
+// from the prefilled transactions, the mempool and a blocktxn message.
+type partialBlock struct {
+	header   block.BlockHeader
+	hash     util.Hash
+	txs      []*tx.Tx
+	k0, k1   uint64
+	shortIDs map[uint64]int
+	collided map[int]struct{}
+}
+
+func newPartialBlock(msg *wire.MsgCmpctBlock) (*partialBlock, error) {
+	count := msg.TxCount()
+	if count == 0 {
+		return nil, errInvalidCmpctBlock
+	}
+
+	pb := &partialBlock{
+		header:   msg.Header,
+		hash:     msg.Header.GetHash(),
+		txs:      make([]*tx.Tx, count),
+		shortIDs: make(map[uint64]int, len(msg.ShortIDs)),
+		collided: make(map[int]struct{}),
+	}
+	for _, prefilled := range msg.PrefilledTxs {
+		if prefilled.Tx == nil || int(prefilled.Index) >= count {
+			return nil, errInvalidCmpctBlock
+		}
+		pb.txs[prefilled.Index] = prefilled.Tx
+	}
+
+	pb.k0, pb.k1 = msg.ShortIDKeys()
+	index := 0
+	for _, id := range msg.ShortIDs {
+		for pb.txs[index] != nil {
+			index++
+		}
+		// Two transactions of the block with the same short id can not be
+		// told apart, fall back to the full block.
+		if _, exists := pb.shortIDs[id]; exists {
+			return nil, errShortIDCollision
+		}
+		pb.shortIDs[id] = index
+		index++
+	}
+
+	return pb, nil
+}
+
+// addTx puts txn at its index in the block if its short id is one of the
+// block. A slot matched by two different transactions is left empty so that
+// it is requested from the peer.
+func (pb *partialBlock) addTx(txn *tx.Tx) {
+	hash := txn.GetHash()
+	index, ok := pb.shortIDs[wire.ShortTxID(pb.k0, pb.k1, &hash)]
+	if !ok {
+		return
+	}
+	if _, collided := pb.collided[index]; collided {
+		return
+	}
+	if prev := pb.txs[index]; prev != nil {
+		if prev.GetHash() != hash {
+			pb.txs[index] = nil
+			pb.collided[index] = struct{}{}
+		}
+		return
+	}
+	pb.txs[index] = txn
+}
+
+func (pb *partialBlock) missingIndexes() []uint32 {
+	indexes := make([]uint32, 0)
+	for i, txn := range pb.txs {
+		if txn == nil {
+			indexes = append(indexes, uint32(i))
+		}
+	}
+	return indexes
+}
+
+// fill puts the transactions of a blocktxn message at the missing indexes.
+func (pb *partialBlock) fill(txs []*tx.Tx) error {
+	missing := pb.missingIndexes()
+	if len(missing) != len(txs) {
+		return errInvalidCmpctBlock
+	}
+	for i, index := range missing {
+		if txs[i] == nil {
+			return errInvalidCmpctBlock
+		}
+		pb.txs[index] = txs[i]
+	}
+	return nil
+}
+
+// toBlock returns the rebuilt block. The merkle root is checked here, as a
+// mismatch may come from a short id collision and must not get the block
+// marked invalid by the chain.
+func (pb *partialBlock) toBlock() (*block.Block, error) {
+	if len(pb.missingIndexes()) != 0 {
+		return nil, errInvalidCmpctBlock
+	}
+
+	mutated := false
+	root := lmerkleroot.BlockMerkleRoot(pb.txs, &mutated)
+	if mutated || !root.IsEqual(&pb.header.MerkleRoot) {
+		return nil, errShortIDCollision
+	}
+
+	blk := block.NewBlock()
+	blk.Header = pb.header
+	blk.Txs = pb.txs
+	return blk, nil
+}
+
+// handleCmpctBlockMsg handles cmpctblock messages from all peers.
+func (sm *SyncManager) handleCmpctBlockMsg(cmsg *cmpctBlockMsg) {
+	peer := cmsg.peer
+	state, exists := sm.peerStates[peer]
+	if !exists {
+		log.Warn("Received cmpctblock message from unknown peer %s", peer.Addr())
+		return
+	}
+	peer.CmpctBlockReceived()
+
+	gChain := chain.GetInstance()
+	header := &cmsg.cmpctBlock.Header
+	if gChain.FindBlockIndex(header.HashPrevBlock) == nil {
+		// The block does not connect, ask for the headers leading to it.
+		if !lblock.IsInitialBlockDownload() {
+			peer.PushGetHeadersMsg(*gChain.GetLocator(gChain.GetIndexBestHeader()), &zeroHash)
+		}
+		return
+	}
+
+	hash := header.GetHash()
+	if err := sm.ProcessBlockHeadCallBack([]*block.BlockHeader{header}, nil); err != nil {
+		log.Warn("processblockheader of cmpctblock %s from %s error: %v", hash, peer.Addr(), err)
+		return
+	}
+	pindex := gChain.FindBlockIndex(hash)
+	if pindex == nil {
+		return
+	}
+
+	peer.AddKnownInventory(wire.NewInvVect(wire.InvTypeBlock, &hash))
+	peer.UpdateLastAnnouncedBlock(&hash)
+	if pindex.HasData() {
+		return
+	}
+
+	// Only the blocks building on the tip are rebuilt, the others are
+	// downloaded in full.
+	_, requested := state.requestedBlocks[hash]
+	if lblock.IsInitialBlockDownload() || pindex.Prev != gChain.Tip() {
+		if requested {
+			sm.requestFullBlock(peer, state, &hash)
+		} else {
+			sm.fetchHeaderBlocks(peer)
+		}
+		return
+	}
+
+	partial, err := newPartialBlock(cmsg.cmpctBlock)
+	if err == errInvalidCmpctBlock {
+		sm.misbehaving(peer.Addr(), 100, "invalid-cmpctblock")
+		return
+	}
+	if err != nil {
+		log.Debug("Compact block %s from %s has colliding short ids, requesting full block",
+			hash, peer.Addr())
+		sm.requestFullBlock(peer, state, &hash)
+		return
+	}
+
+	for _, entry := range mempool.GetInstance().GetAllTxEntry() {
+		partial.addTx(entry.Tx)
+	}
+	for _, txn := range lmempool.GetOrphanTxs() {
+		partial.addTx(txn)
+	}
+
+	missing := partial.missingIndexes()
+	if len(missing) == 0 {
+		sm.processCmpctBlock(peer, state, partial)
+		return
+	}
+
+	// Leave the block to the peer it was requested from, if any.
+	if waitPeer, exists := sm.requestedBlocks[hash]; exists && waitPeer != peer {
+		return
+	}
+	if !requested && len(state.requestedBlocks) >= MAX_BLOCKS_IN_TRANSIT_PER_PEER {
+		return
+	}
+
+	state.partialBlock = partial
+	sm.requestedBlocks[hash] = peer
+	state.requestedBlocks[hash] = struct{}{}
+	peer.CmpctBlockTxnRequested()
+	log.Debug("Requesting %d of %d transactions of compact block %s from %s",
+		len(missing), len(partial.txs), hash, peer.Addr())
+	peer.QueueMessage(wire.NewMsgGetBlockTxn(&hash, missing), nil)
+}
+
+// handleBlockTxnMsg handles blocktxn messages from all peers.
+func (sm *SyncManager) handleBlockTxnMsg(bmsg *blockTxnMsg) {
+	peer := bmsg.peer
+	state, exists := sm.peerStates[peer]
+	if !exists {
+		log.Warn("Received blocktxn message from unknown peer %s", peer.Addr())
+		return
+	}
+
+	hash := bmsg.blockTxn.BlockHash
+	partial := state.partialBlock
+	if partial == nil || partial.hash != hash {
+		log.Debug("Ignoring unrequested blocktxn %s from %s", hash, peer.Addr())
+		return
+	}
+	state.partialBlock = nil
+
+	if err := partial.fill(bmsg.blockTxn.Txs); err != nil {
+		delete(state.requestedBlocks, hash)
+		delete(sm.requestedBlocks, hash)
+		sm.misbehaving(peer.Addr(), 100, "invalid-blocktxn")
+		return
+	}
+
+	if pindex := chain.GetInstance().FindBlockIndex(hash); pindex != nil && pindex.HasData() {
+		delete(state.requestedBlocks, hash)
+		delete(sm.requestedBlocks, hash)
+		return
+	}
+
+	sm.processCmpctBlock(peer, state, partial)
+}
+
+// processCmpctBlock processes a complete partial block as a block received
+// from the peer, or requests the full block if it can not be rebuilt.
+func (sm *SyncManager) processCmpctBlock(peer *peer.Peer, state *peerSyncState, partial *partialBlock) {
+	hash := partial.hash
+	blk, err := partial.toBlock()
+	if err != nil {
+		log.Debug("Failed to rebuild compact block %s from %s: %v, requesting full block",
+			hash, peer.Addr(), err)
+		peer.CmpctBlockFailed()
+		sm.requestFullBlock(peer, state, &hash)
+		return
+	}
+
+	peer.CmpctBlockReconstructed()
+	sm.requestedBlocks[hash] = peer
+	state.requestedBlocks[hash] = struct{}{}
+	sm.handleBlockMsg(&blockMsg{block: blk, peer: peer})
+}
+
+func (sm *SyncManager) requestFullBlock(peer *peer.Peer, state *peerSyncState, hash *util.Hash) {
+	gdmsg := wire.NewMsgGetData()
+	gdmsg.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, hash))
+	sm.requestedBlocks[*hash] = peer
+	state.requestedBlocks[*hash] = struct{}{}
+	peer.QueueMessage(gdmsg, nil)
+}
+
+// updateCmpctHighBandwidthPeers asks the peer which provided the new tip to
+// announce the next blocks with cmpctblock messages, and the least recent of
+// such peers to stop doing so when there are too many of them.
+func (sm *SyncManager) updateCmpctHighBandwidthPeers(peer *peer.Peer) {
+	if !peer.ProvidesCmpctBlocks() {
+		return
+	}
+
+	for i, p := range sm.cmpctHighBandwidthPeers {
+		if p == peer {
+			// Move it to the back of the list.
+			copy(sm.cmpctHighBandwidthPeers[i:], sm.cmpctHighBandwidthPeers[i+1:])
+			sm.cmpctHighBandwidthPeers[len(sm.cmpctHighBandwidthPeers)-1] = peer
+			return
+		}
+	}
+
+	if len(sm.cmpctHighBandwidthPeers) >= maxCmpctHighBandwidthPeers {
+		sm.cmpctHighBandwidthPeers[0].PushSendCmpctMsg(false)
+		sm.cmpctHighBandwidthPeers = sm.cmpctHighBandwidthPeers[1:]
+	}
+	peer.PushSendCmpctMsg(true)
+	sm.cmpctHighBandwidthPeers = append(sm.cmpctHighBandwidthPeers, peer)
+}
+
+func (sm *SyncManager) removeCmpctHighBandwidthPeer(peer *peer.Peer) {
+	for i, p := range sm.cmpctHighBandwidthPeers {
+		if p == peer {
+			sm.cmpctHighBandwidthPeers = append(sm.cmpctHighBandwidthPeers[:i],
+				sm.cmpctHighBandwidthPeers[i+1:]...)
+			return
+		}
+	}
+}
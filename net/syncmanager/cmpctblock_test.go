@@ -0,0 +1,105 @@
+package syncmanager
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/logic/lmerkleroot"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/net/wire"
+	"github.com/copernet/copernicus/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCmpctTestTx(index uint32) *tx.Tx {
+	txn := tx.NewTx(0, tx.DefaultVersion)
+	prevOut := outpoint.NewOutPoint(util.Hash{0x01}, index)
+	txn.AddTxIn(txin.NewTxIn(prevOut, script.NewScriptRaw([]byte{0x51}), 0xffffffff))
+	txn.AddTxOut(txout.NewTxOut(1000, script.NewScriptRaw([]byte{0x51})))
+	return txn
+}
+
+func newCmpctTestBlock(count int) *block.Block {
+	blk := block.NewBlock()
+	for i := 0; i < count; i++ {
+		blk.Txs = append(blk.Txs, newCmpctTestTx(uint32(i)))
+	}
+	blk.Header.MerkleRoot = lmerkleroot.BlockMerkleRoot(blk.Txs, nil)
+	return blk
+}
+
+func TestPartialBlockRebuild(t *testing.T) {
+	blk := newCmpctTestBlock(5)
+	msg := wire.NewMsgCmpctBlock(blk, 1)
+
+	partial, err := newPartialBlock(msg)
+	assert.Nil(t, err)
+	assert.Equal(t, blk.GetHash(), partial.hash)
+	assert.Equal(t, []uint32{1, 2, 3, 4}, partial.missingIndexes())
+
+	// txs which are not in the block are ignored
+	partial.addTx(newCmpctTestTx(100))
+	partial.addTx(blk.Txs[2])
+	partial.addTx(blk.Txs[4])
+	assert.Equal(t, []uint32{1, 3}, partial.missingIndexes())
+	_, err = partial.toBlock()
+	assert.Equal(t, errInvalidCmpctBlock, err)
+
+	// the missing txs must all be sent
+	assert.Equal(t, errInvalidCmpctBlock, partial.fill([]*tx.Tx{blk.Txs[1]}))
+	assert.Nil(t, partial.fill([]*tx.Tx{blk.Txs[1], blk.Txs[3]}))
+
+	rebuilt, err := partial.toBlock()
+	assert.Nil(t, err)
+	assert.Equal(t, blk.GetHash(), rebuilt.GetHash())
+	assert.Equal(t, blk.Txs, rebuilt.Txs)
+}
+
+func TestPartialBlockInvalid(t *testing.T) {
+	blk := newCmpctTestBlock(3)
+
+	_, err := newPartialBlock(&wire.MsgCmpctBlock{Header: blk.Header})
+	assert.Equal(t, errInvalidCmpctBlock, err)
+
+	msg := wire.NewMsgCmpctBlock(blk, 1)
+	msg.PrefilledTxs[0].Index = 3
+	_, err = newPartialBlock(msg)
+	assert.Equal(t, errInvalidCmpctBlock, err)
+
+	msg = wire.NewMsgCmpctBlock(blk, 1)
+	msg.PrefilledTxs[0].Tx = nil
+	_, err = newPartialBlock(msg)
+	assert.Equal(t, errInvalidCmpctBlock, err)
+}
+
+func TestPartialBlockCollision(t *testing.T) {
+	blk := newCmpctTestBlock(3)
+
+	// duplicate short ids in the block fall back to a full block
+	msg := wire.NewMsgCmpctBlock(blk, 1)
+	msg.ShortIDs[1] = msg.ShortIDs[0]
+	_, err := newPartialBlock(msg)
+	assert.Equal(t, errShortIDCollision, err)
+
+	// a slot matched by two different txs is requested from the peer
+	msg = wire.NewMsgCmpctBlock(blk, 1)
+	partial, err := newPartialBlock(msg)
+	assert.Nil(t, err)
+	other := newCmpctTestTx(100)
+	otherHash := other.GetHash()
+	partial.shortIDs[wire.ShortTxID(partial.k0, partial.k1, &otherHash)] = 1
+	partial.addTx(blk.Txs[1])
+	partial.addTx(other)
+	partial.addTx(blk.Txs[1])
+	partial.addTx(blk.Txs[2])
+	assert.Equal(t, []uint32{1}, partial.missingIndexes())
+
+	// a wrong tx with a matching short id fails the merkle root check
+	assert.Nil(t, partial.fill([]*tx.Tx{other}))
+	_, err = partial.toBlock()
+	assert.Equal(t, errShortIDCollision, err)
+}
@@ -202,6 +202,8 @@ type peerSyncState struct {
 	// syncStarted indicate whether we have send a GetHeaders msg from the peer
 	// when the pindexBestHeader is 24h near to now, to fetch all possible header
 	syncStarted bool
+	// partialBlock is the compact block waiting for a blocktxn message
+	partialBlock *partialBlock
 }
 
 func (pss *peerSyncState) onStartSync(syncPeer *peer.Peer) {
@@ -277,6 +279,10 @@ type SyncManager struct {
 	syncPeer        *peer.Peer
 	peerStates      map[*peer.Peer]*peerSyncState
 
+	// cmpctHighBandwidthPeers are the peers asked to announce new blocks
+	// with cmpctblock messages, the least recent first.
+	cmpctHighBandwidthPeers []*peer.Peer
+
 	// callback for transaction And block process
 	ProcessTransactionCallBack func(*tx.Tx, map[util.Hash]struct{}, int64) ([]*tx.Tx, []util.Hash, []util.Hash, error)
 	ProcessBlockCallBack       func(*block.Block, bool) (bool, error)
@@ -469,6 +475,7 @@ func (sm *SyncManager) clearSyncPeerState(peer *peer.Peer) {
 
 	// Remove the peer from the list of candidate peers.
 	delete(sm.peerStates, peer)
+	sm.removeCmpctHighBandwidthPeer(peer)
 
 	log.Info("Lost peer %s", peer.Addr())
 
@@ -708,6 +715,12 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
+	// The peer which provided the new tip is likely to provide the next
+	// ones quickly.
+	if blkHashUpdate != nil && blkHashUpdate.IsEqual(&blockHash) && !lblock.IsInitialBlockDownload() {
+		sm.updateCmpctHighBandwidthPeers(peer)
+	}
+
 	sm.fetchHeaderBlocks(peer)
 }
 
@@ -1021,8 +1034,15 @@ func (sm *SyncManager) fetchBlocks(vToFetch *list.List, state *peerSyncState, pe
 			break
 		}
 
-		hash := *(e.Value.(*blockindex.BlockIndex).GetBlockHash())
+		pindex := e.Value.(*blockindex.BlockIndex)
+		hash := *pindex.GetBlockHash()
 		iv := wire.NewInvVect(wire.InvTypeBlock, &hash)
+		// A single block on top of the tip is requested as a compact
+		// block, which is rebuilt from the mempool.
+		if vToFetch.Len() == 1 && pindex.Prev == chain.GetInstance().Tip() &&
+			peer.ProvidesCmpctBlocks() && !lblock.IsInitialBlockDownload() {
+			iv = wire.NewInvVect(wire.InvTypeCompatedBlock, &hash)
+		}
 		gdmsg.AddInvVect(iv)
 
 		sm.requestedBlocks[hash] = peer
@@ -1326,6 +1346,14 @@ out:
 				sm.handleBlockMsg(msg)
 				msg.reply <- struct{}{}
 
+			case *cmpctBlockMsg:
+				sm.handleCmpctBlockMsg(msg)
+				msg.reply <- struct{}{}
+
+			case *blockTxnMsg:
+				sm.handleBlockTxnMsg(msg)
+				msg.reply <- struct{}{}
+
 			case *invMsg:
 				sm.handleInvMsg(msg)
 
@@ -1398,9 +1426,10 @@ func (sm *SyncManager) handleBlockchainNotification(notification *chain.Notifica
 			break
 		}
 
-		// Generate the inventory vector and relay it.
+		// Generate the inventory vector and relay it, along with the
+		// block so that it can be announced with cmpctblock messages.
 		iv := wire.NewInvVect(wire.InvTypeBlock, &block.Header.Hash)
-		sm.peerNotifier.RelayInventory(iv, &block.Header)
+		sm.peerNotifier.RelayInventory(iv, block)
 
 	// A block has been connected to the main block chain.
 	case chain.NTBlockConnected:
@@ -1494,6 +1523,32 @@ func (sm *SyncManager) QueueBlock(block *block.Block, buf []byte, peer *peer.Pee
 	sm.processBusinessChan <- &blockMsg{block: block, buf: buf, peer: peer, reply: done}
 }
 
+// QueueCmpctBlock adds the passed cmpctblock message and peer to the block
+// handling queue. Responds to the done channel argument after the message is
+// processed.
+func (sm *SyncManager) QueueCmpctBlock(cmpctBlock *wire.MsgCmpctBlock, peer *peer.Peer, done chan<- struct{}) {
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		done <- struct{}{}
+		return
+	}
+
+	sm.processBusinessChan <- &cmpctBlockMsg{cmpctBlock: cmpctBlock, peer: peer, reply: done}
+}
+
+// QueueBlockTxn adds the passed blocktxn message and peer to the block
+// handling queue. Responds to the done channel argument after the message is
+// processed.
+func (sm *SyncManager) QueueBlockTxn(blockTxn *wire.MsgBlockTxn, peer *peer.Peer, done chan<- struct{}) {
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		done <- struct{}{}
+		return
+	}
+
+	sm.processBusinessChan <- &blockTxnMsg{blockTxn: blockTxn, peer: peer, reply: done}
+}
+
 func (sm *SyncManager) QueueMessgePool(pool *wire.MsgMemPool, peer *peer.Peer, done chan<- struct{}) {
 	// Don't accept more blocks if we're shutting down.
 	if atomic.LoadInt32(&sm.shutdown) != 0 {
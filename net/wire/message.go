@@ -160,20 +160,19 @@ func makeEmptyMessage(command string) (Message, error) {
 
 	case CmdFeeFilter:
 		msg = &MsgFeeFilter{}
-		/*
-			case CmdSendCmpct:
-				msg = &MsgSendCmpct{}
 
-			case CmdCmpctBlock:
-				msg = &MsgCmpctBlock{}
+	case CmdSendCmpct:
+		msg = &MsgSendCmpct{}
 
-			case CmdGetBlockTxn:
-				msg = &MsgGetBlockTxn{}
+	case CmdCmpctBlock:
+		msg = &MsgCmpctBlock{}
 
-			case CmdBlockTxn:
-				msg = &MsgBlockTxn{}
+	case CmdGetBlockTxn:
+		msg = &MsgGetBlockTxn{}
+
+	case CmdBlockTxn:
+		msg = &MsgBlockTxn{}
 
-		*/
 	default:
 		return nil, fmt.Errorf("unhandled command [%s]", command)
 	}
@@ -0,0 +1,52 @@
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/copernet/copernicus/model/tx"
+)
+
+// TestBlockTxn tests the MsgBlockTxn API against the latest protocol version.
+func TestBlockTxn(t *testing.T) {
+	pver := ProtocolVersion
+	enc := BaseEncoding
+
+	hash := blockOne.GetHash()
+	msg := NewMsgBlockTxn(&hash, []*tx.Tx{blockOne.Txs[0]})
+	if cmd := msg.Command(); cmd != "blocktxn" {
+		t.Errorf("NewMsgBlockTxn: wrong command - got %v want %v",
+			cmd, "blocktxn")
+	}
+
+	var buf bytes.Buffer
+	if err := msg.Encode(&buf, pver, enc); err != nil {
+		t.Fatalf("encode of MsgBlockTxn failed %v err <%v>", msg, err)
+	}
+
+	readmsg := &MsgBlockTxn{}
+	if err := readmsg.Decode(bytes.NewReader(buf.Bytes()), pver, enc); err != nil {
+		t.Fatalf("decode of MsgBlockTxn failed err <%v>", err)
+	}
+	if readmsg.BlockHash != hash {
+		t.Errorf("decode of MsgBlockTxn wrong hash - got %v, want %v",
+			readmsg.BlockHash, hash)
+	}
+	if len(readmsg.Txs) != 1 || readmsg.Txs[0].GetHash() != blockOne.Txs[0].GetHash() {
+		t.Errorf("decode of MsgBlockTxn wrong transactions")
+	}
+
+	// Older protocol versions should fail since message didn't exist yet.
+	oldPver := ShortIdsBlocksVersion - 1
+	if err := msg.Encode(&bytes.Buffer{}, oldPver, enc); err == nil {
+		t.Errorf("encode of MsgBlockTxn passed for old protocol version")
+	}
+	if err := readmsg.Decode(bytes.NewReader(buf.Bytes()), oldPver, enc); err == nil {
+		t.Errorf("decode of MsgBlockTxn passed for old protocol version")
+	}
+
+	// Truncated messages are rejected.
+	if err := readmsg.Decode(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), pver, enc); err == nil {
+		t.Errorf("decode of truncated MsgBlockTxn passed")
+	}
+}
@@ -1,186 +1,218 @@
 package wire
 
-/*
 import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
-	"math"
 
+	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/util"
 )
 
-const ShortTxIDsLength = 6
+// ShortTxIDLength is the number of bytes a short transaction id is serialized
+// to in a cmpctblock message.
+const ShortTxIDLength = 6
 
-type PreFilledTransaction struct {
-	Tx    *MsgTx
-	Index uint16
+// PrefilledTx is a transaction sent in full within a cmpctblock message,
+// along with its index in the block.
+type PrefilledTx struct {
+	Index uint32
+	Tx    *tx.Tx
 }
 
+// MsgCmpctBlock implements the Message interface and represents a bitcoin
+// cmpctblock message as defined by BIP0152.  It carries a block header along
+// with the short ids of the block transactions, except those prefilled in
+// full, so that the receiver can rebuild the block from its mempool.
+//
+// The indexes of the prefilled transactions are absolute here, they are
+// differentially encoded on the wire.
+//
+// This message was not added until protocol versions starting with
+// ShortIdsBlocksVersion.
 type MsgCmpctBlock struct {
-	shortTxidk0  uint64
-	shortTxidk1  uint64
-	Nonce        uint64
-	ShortTxids   []uint64
-	PreFilledTxn []PreFilledTransaction
 	Header       block.BlockHeader
+	Nonce        uint64
+	ShortIDs     []uint64
+	PrefilledTxs []PrefilledTx
 }
 
-func NewMsgCmpctBlock(block *MsgBlock) *MsgCmpctBlock {
-	nonce, _ := util.RandomUint64()
-	shortids := make([]uint64, len(block.Txs)-1)
-	prefilledTxn := make([]PreFilledTransaction, 1)
-	header := block.Header
-
-	id0, id1, err := fillShortTxIDSelector(&header, nonce)
-	if err != nil {
-		return nil
-	}
-	prefilledTxn[0].Index = 0
-	prefilledTxn[0].Tx = (*MsgTx)(block.Txs[0])
-	for i := 1; i < len(block.Txs); i++ {
-		txhash := block.Txs[i].GetHash()
-		shortids[i-1] = getShortID(id0, id1, &txhash)
-	}
-	return &MsgCmpctBlock{
-		shortTxidk0:  id0,
-		shortTxidk1:  id1,
-		Nonce:        nonce,
-		ShortTxids:   shortids,
-		PreFilledTxn: prefilledTxn,
-		Header:       header,
-	}
+// ShortTxID returns the BIP0152 short id of the transaction hash for the
+// SipHash keys derived by MsgCmpctBlock.ShortIDKeys.
+func ShortTxID(k0, k1 uint64, hash *util.Hash) uint64 {
+	return util.SipHash(k0, k1, hash[:]) & 0xffffffffffff
 }
 
-func fillShortTxIDSelector(h *block.BlockHeader, nonce uint64) (uint64, uint64, error) {
-	bw := bytes.NewBuffer(nil)
-	if err := h.Serialize(bw); err != nil {
-		return 0, 0, err
-	}
-	if err := util.WriteElements(bw, nonce); err != nil {
-		return 0, 0, err
-	}
-	hb := util.Sha256Bytes(bw.Bytes())
-	return binary.LittleEndian.Uint64(hb[0:8]), binary.LittleEndian.Uint64(hb[8:16]), nil
+// ShortIDKeys returns the SipHash keys used to compute the short ids of the
+// message, which are taken from the sha256 of the header and the nonce.
+func (msg *MsgCmpctBlock) ShortIDKeys() (uint64, uint64) {
+	var buf bytes.Buffer
+	msg.Header.Serialize(&buf)
+	util.WriteElements(&buf, msg.Nonce)
+	sum := util.Sha256Bytes(buf.Bytes())
+	return binary.LittleEndian.Uint64(sum[0:8]), binary.LittleEndian.Uint64(sum[8:16])
 }
 
-func getShortID(id0, id1 uint64, hash *util.Hash) uint64 {
-	return util.SipHash(id0, id1, (*hash)[:]) & 0xffffffffffff
-}
-
-func (pft *PreFilledTransaction) Decode(r io.Reader, pver uint32, enc MessageEncoding) error {
-	idx, err := util.ReadVarInt(r)
-	if err != nil {
-		return err
-	}
-	if idx > math.MaxUint16 {
-		return messageError("MsgCmpctBlock.Decode", fmt.Sprintf("index overflowed 16-bits"))
-	}
-	pft.Index = uint16(idx)
-	err = pft.Tx.Decode(r, pver, enc)
-	return err
-}
-
-func (pft *PreFilledTransaction) Encode(w io.Writer, pver uint32, enc MessageEncoding) error {
-	if err := util.WriteVarInt(w, uint64(pft.Index)); err != nil {
-		return err
-	}
-	if err := pft.Tx.Encode(w, pver, enc); err != nil {
-		return err
-	}
-	return nil
+// TxCount returns the number of transactions of the block.
+func (msg *MsgCmpctBlock) TxCount() int {
+	return len(msg.ShortIDs) + len(msg.PrefilledTxs)
 }
 
+// Decode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
 func (msg *MsgCmpctBlock) Decode(r io.Reader, pver uint32, enc MessageEncoding) error {
 	if pver < ShortIdsBlocksVersion {
 		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
 			"version %d", pver)
 		return messageError("MsgCmpctBlock.Decode", str)
 	}
+
 	if err := msg.Header.Unserialize(r); err != nil {
 		return err
 	}
 	if err := util.ReadElements(r, &msg.Nonce); err != nil {
 		return err
 	}
-	shortIDSize, err := util.ReadVarInt(r)
+
+	count, err := util.ReadVarInt(r)
 	if err != nil {
 		return err
 	}
-	ids := make([]uint64, shortIDSize)
-	for i := 0; i < len(ids); i++ {
-		lsb := uint32(0)
-		msb := uint16(0)
-		if err := util.ReadElements(r, &lsb); err != nil {
-			return err
-		}
-		if err := util.ReadElements(r, &msb); err != nil {
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many short ids for message "+
+			"[count %d, max %d]", count, maxTxPerBlock)
+		return messageError("MsgCmpctBlock.Decode", str)
+	}
+	msg.ShortIDs = make([]uint64, count)
+	var shortID [8]byte
+	for i := range msg.ShortIDs {
+		if _, err := io.ReadFull(r, shortID[:ShortTxIDLength]); err != nil {
 			return err
 		}
-		ids[i] = (uint64(msb) << 32) | uint64(lsb)
+		msg.ShortIDs[i] = binary.LittleEndian.Uint64(shortID[:])
 	}
-	msg.ShortTxids = append(msg.ShortTxids, ids...)
-	pftLen, err := util.ReadVarInt(r)
+
+	count, err = util.ReadVarInt(r)
 	if err != nil {
 		return err
 	}
-	vpft := make([]PreFilledTransaction, pftLen)
-	for i := 0; i < len(vpft); i++ {
-		if err := vpft[i].Decode(r, pver, enc); err != nil {
+	if count+uint64(len(msg.ShortIDs)) > maxTxPerBlock {
+		str := fmt.Sprintf("too many prefilled transactions for message "+
+			"[count %d, max %d]", count, maxTxPerBlock-uint64(len(msg.ShortIDs)))
+		return messageError("MsgCmpctBlock.Decode", str)
+	}
+	msg.PrefilledTxs = make([]PrefilledTx, count)
+	var index uint64
+	for i := range msg.PrefilledTxs {
+		diff, err := util.ReadVarInt(r)
+		if err != nil {
 			return err
 		}
+		if i > 0 && diff < maxTxPerBlock {
+			diff += index + 1
+		}
+		if diff >= maxTxPerBlock {
+			str := fmt.Sprintf("prefilled transaction index %d "+
+				"out of range", diff)
+			return messageError("MsgCmpctBlock.Decode", str)
+		}
+		index = diff
+
+		txn := tx.NewTx(0, tx.DefaultVersion)
+		if err := txn.Unserialize(r); err != nil {
+			return err
+		}
+		msg.PrefilledTxs[i] = PrefilledTx{Index: uint32(index), Tx: txn}
 	}
-	id0, id1, err := fillShortTxIDSelector(&msg.Header, msg.Nonce)
-	if err != nil {
-		return err
-	}
-	msg.shortTxidk0 = id0
-	msg.shortTxidk1 = id1
+
 	return nil
 }
 
+// Encode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
 func (msg *MsgCmpctBlock) Encode(w io.Writer, pver uint32, enc MessageEncoding) error {
 	if pver < ShortIdsBlocksVersion {
 		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
 			"version %d", pver)
 		return messageError("MsgCmpctBlock.Encode", str)
 	}
+
 	if err := msg.Header.Serialize(w); err != nil {
 		return err
 	}
-	if err := util.WriteElements(w, &msg.Nonce); err != nil {
+	if err := util.WriteElements(w, msg.Nonce); err != nil {
 		return err
 	}
-	if err := util.WriteVarInt(w, uint64(len(msg.ShortTxids))); err != nil {
+
+	if err := util.WriteVarInt(w, uint64(len(msg.ShortIDs))); err != nil {
 		return err
 	}
-	for i := 0; i < len(msg.ShortTxids); i++ {
-		var lsb uint32
-		var msb uint16
-		lsb = uint32(msg.ShortTxids[i] & 0xffffffff)
-		msb = uint16((msg.ShortTxids[i] >> 32) & 0xffff)
-		if err := util.WriteElements(w, lsb); err != nil {
+	var shortID [8]byte
+	for _, id := range msg.ShortIDs {
+		binary.LittleEndian.PutUint64(shortID[:], id)
+		if _, err := w.Write(shortID[:ShortTxIDLength]); err != nil {
 			return err
 		}
-		if err := util.WriteElements(w, msb); err != nil {
+	}
+
+	if err := util.WriteVarInt(w, uint64(len(msg.PrefilledTxs))); err != nil {
+		return err
+	}
+	for i, prefilled := range msg.PrefilledTxs {
+		diff := prefilled.Index
+		if i > 0 {
+			prev := msg.PrefilledTxs[i-1].Index
+			if diff <= prev {
+				str := fmt.Sprintf("prefilled transaction indexes "+
+					"not ascending [%d after %d]", diff, prev)
+				return messageError("MsgCmpctBlock.Encode", str)
+			}
+			diff -= prev + 1
+		}
+		if err := util.WriteVarInt(w, uint64(diff)); err != nil {
 			return err
 		}
-	}
-	for i := 0; i < len(msg.PreFilledTxn); i++ {
-		if err := msg.PreFilledTxn[i].Encode(w, pver, enc); err != nil {
+		if err := prefilled.Tx.Serialize(w); err != nil {
 			return err
 		}
 	}
+
 	return nil
 }
 
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
 func (msg *MsgCmpctBlock) Command() string {
 	return CmdCmpctBlock
 }
 
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
 func (msg *MsgCmpctBlock) MaxPayloadLength(pver uint32) uint64 {
-	return 80 + 8 + 3 + uint64(len(msg.ShortTxids))*6 + 3 + uint64(len(msg.PreFilledTxn))*(3+MaxBlockPayload)
+	return conf.Cfg.Excessiveblocksize
+}
+
+// NewMsgCmpctBlock returns a new bitcoin cmpctblock message for the block,
+// with short ids computed from nonce and only the coinbase prefilled.
+func NewMsgCmpctBlock(blk *block.Block, nonce uint64) *MsgCmpctBlock {
+	msg := &MsgCmpctBlock{
+		Header:       blk.Header,
+		Nonce:        nonce,
+		ShortIDs:     make([]uint64, 0, len(blk.Txs)),
+		PrefilledTxs: make([]PrefilledTx, 0, 1),
+	}
+	if len(blk.Txs) == 0 {
+		return msg
+	}
+
+	msg.PrefilledTxs = append(msg.PrefilledTxs, PrefilledTx{Index: 0, Tx: blk.Txs[0]})
+	k0, k1 := msg.ShortIDKeys()
+	for _, txn := range blk.Txs[1:] {
+		hash := txn.GetHash()
+		msg.ShortIDs = append(msg.ShortIDs, ShortTxID(k0, k1, &hash))
+	}
+	return msg
 }
-*/
@@ -0,0 +1,142 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/util"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// newCmpctTestBlock returns block one with count more transactions.
+func newCmpctTestBlock(count int) *block.Block {
+	blk := &block.Block{Header: blockOne.Header, Txs: []*tx.Tx{blockOne.Txs[0]}}
+	for i := 0; i < count; i++ {
+		txn := tx.NewTx(0, tx.DefaultVersion)
+		prevOut := outpoint.NewOutPoint(util.Hash{0x01}, uint32(i))
+		txn.AddTxIn(txin.NewTxIn(prevOut, script.NewScriptRaw([]byte{0x51}), 0xffffffff))
+		txn.AddTxOut(txout.NewTxOut(1000, script.NewScriptRaw([]byte{0x51})))
+		blk.Txs = append(blk.Txs, txn)
+	}
+	return blk
+}
+
+// TestCmpctBlock tests the MsgCmpctBlock API against the latest protocol
+// version.
+func TestCmpctBlock(t *testing.T) {
+	pver := ProtocolVersion
+	enc := BaseEncoding
+
+	blk := newCmpctTestBlock(3)
+	msg := NewMsgCmpctBlock(blk, 0x1122334455667788)
+	if cmd := msg.Command(); cmd != "cmpctblock" {
+		t.Errorf("NewMsgCmpctBlock: wrong command - got %v want %v",
+			cmd, "cmpctblock")
+	}
+	if msg.TxCount() != 4 || len(msg.PrefilledTxs) != 1 || msg.PrefilledTxs[0].Index != 0 {
+		t.Fatalf("NewMsgCmpctBlock: only the coinbase should be prefilled - got %v",
+			spew.Sdump(msg.PrefilledTxs))
+	}
+
+	// The short ids are the 6 low bytes of the siphash of the tx hashes.
+	k0, k1 := msg.ShortIDKeys()
+	for i, txn := range blk.Txs[1:] {
+		hash := txn.GetHash()
+		want := util.SipHash(k0, k1, hash[:]) & 0xffffffffffff
+		if msg.ShortIDs[i] != want {
+			t.Errorf("NewMsgCmpctBlock: wrong short id %d - got %x want %x",
+				i, msg.ShortIDs[i], want)
+		}
+	}
+
+	// The keys depend on the nonce.
+	other := NewMsgCmpctBlock(blk, 0)
+	if k0o, k1o := other.ShortIDKeys(); k0o == k0 && k1o == k1 {
+		t.Errorf("ShortIDKeys: same keys for different nonces")
+	}
+
+	var buf bytes.Buffer
+	if err := msg.Encode(&buf, pver, enc); err != nil {
+		t.Fatalf("encode of MsgCmpctBlock failed %v err <%v>", msg, err)
+	}
+	// header + nonce + count + 3 short ids + count + index + coinbase
+	wantLen := 80 + 8 + 1 + 3*ShortTxIDLength + 1 + 1 + int(blk.Txs[0].SerializeSize())
+	if buf.Len() != wantLen {
+		t.Errorf("encode of MsgCmpctBlock wrong length - got %d want %d",
+			buf.Len(), wantLen)
+	}
+
+	readmsg := &MsgCmpctBlock{}
+	if err := readmsg.Decode(bytes.NewReader(buf.Bytes()), pver, enc); err != nil {
+		t.Fatalf("decode of MsgCmpctBlock failed err <%v>", err)
+	}
+	if readmsg.Header.GetHash() != blk.GetHash() || readmsg.Nonce != msg.Nonce {
+		t.Errorf("decode of MsgCmpctBlock wrong header or nonce")
+	}
+	if !reflect.DeepEqual(readmsg.ShortIDs, msg.ShortIDs) {
+		t.Errorf("decode of MsgCmpctBlock wrong short ids - got %v want %v",
+			readmsg.ShortIDs, msg.ShortIDs)
+	}
+	if len(readmsg.PrefilledTxs) != 1 ||
+		readmsg.PrefilledTxs[0].Tx.GetHash() != blk.Txs[0].GetHash() {
+		t.Errorf("decode of MsgCmpctBlock wrong prefilled transactions")
+	}
+
+	// Older protocol versions should fail since message didn't exist yet.
+	oldPver := ShortIdsBlocksVersion - 1
+	if err := msg.Encode(&bytes.Buffer{}, oldPver, enc); err == nil {
+		t.Errorf("encode of MsgCmpctBlock passed for old protocol version")
+	}
+	if err := readmsg.Decode(bytes.NewReader(buf.Bytes()), oldPver, enc); err == nil {
+		t.Errorf("decode of MsgCmpctBlock passed for old protocol version")
+	}
+}
+
+// TestCmpctBlockPrefilledIndexes tests the differential encoding of the
+// prefilled transaction indexes.
+func TestCmpctBlockPrefilledIndexes(t *testing.T) {
+	pver := ProtocolVersion
+	enc := BaseEncoding
+
+	blk := newCmpctTestBlock(3)
+	msg := &MsgCmpctBlock{
+		Header:   blk.Header,
+		ShortIDs: []uint64{1, 2},
+		PrefilledTxs: []PrefilledTx{
+			{Index: 0, Tx: blk.Txs[0]},
+			{Index: 2, Tx: blk.Txs[2]},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := msg.Encode(&buf, pver, enc); err != nil {
+		t.Fatalf("encode of MsgCmpctBlock failed %v err <%v>", msg, err)
+	}
+	// The second index is encoded as the difference minus one.
+	offset := 80 + 8 + 1 + 2*ShortTxIDLength + 1 + 1 + int(blk.Txs[0].SerializeSize())
+	if diff := buf.Bytes()[offset]; diff != 1 {
+		t.Errorf("encode of MsgCmpctBlock wrong index difference - got %d want 1", diff)
+	}
+
+	readmsg := &MsgCmpctBlock{}
+	if err := readmsg.Decode(bytes.NewReader(buf.Bytes()), pver, enc); err != nil {
+		t.Fatalf("decode of MsgCmpctBlock failed err <%v>", err)
+	}
+	if readmsg.PrefilledTxs[1].Index != 2 {
+		t.Errorf("decode of MsgCmpctBlock wrong index - got %d want 2",
+			readmsg.PrefilledTxs[1].Index)
+	}
+
+	// Indexes must be ascending.
+	msg.PrefilledTxs[1].Index = 0
+	if err := msg.Encode(&bytes.Buffer{}, pver, enc); err == nil {
+		t.Errorf("encode of MsgCmpctBlock passed with duplicate indexes")
+	}
+}
@@ -1,88 +1,120 @@
 package wire
 
-/*
 import (
 	"fmt"
 	"io"
-	"math"
 
 	"github.com/copernet/copernicus/util"
 )
 
+// MsgGetBlockTxn implements the Message interface and represents a bitcoin
+// getblocktxn message as defined by BIP0152.  It is used to request the
+// transactions of a block announced by a cmpctblock message which could not
+// be found in the mempool.
+//
+// The indexes are absolute here, they are differentially encoded on the wire.
+//
+// This message was not added until protocol versions starting with
+// ShortIdsBlocksVersion.
 type MsgGetBlockTxn struct {
 	BlockHash util.Hash
-	Indexes   []uint16
+	Indexes   []uint32
 }
 
-func (msg *MsgGetBlockTxn) Encode(w io.Writer, pver uint32, enc MessageEncoding) error {
+// Decode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) Decode(r io.Reader, pver uint32, enc MessageEncoding) error {
 	if pver < ShortIdsBlocksVersion {
 		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
 			"version %d", pver)
-		return messageError("MsgGetBlockTxn.Encode", str)
+		return messageError("MsgGetBlockTxn.Decode", str)
 	}
 
-	if err := util.WriteElements(w, msg.BlockHash); err != nil {
+	if err := util.ReadElements(r, &msg.BlockHash); err != nil {
 		return err
 	}
-	indexSize := uint64(len(msg.Indexes))
-	if err := util.WriteVarInt(w, indexSize); err != nil {
+	count, err := util.ReadVarInt(r)
+	if err != nil {
 		return err
 	}
-	for i := 0; i < len(msg.Indexes); i++ {
-		var index uint16
-		if i == 0 {
-			index = msg.Indexes[i]
-		} else {
-			index = msg.Indexes[i] - (msg.Indexes[i-1] + 1)
-		}
-		if err := util.WriteVarInt(w, uint64(index)); err != nil {
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many indexes for message "+
+			"[count %d, max %d]", count, maxTxPerBlock)
+		return messageError("MsgGetBlockTxn.Decode", str)
+	}
+
+	msg.Indexes = make([]uint32, count)
+	var index uint64
+	for i := range msg.Indexes {
+		diff, err := util.ReadVarInt(r)
+		if err != nil {
 			return err
 		}
+		if i > 0 && diff < maxTxPerBlock {
+			diff += index + 1
+		}
+		if diff >= maxTxPerBlock {
+			str := fmt.Sprintf("transaction index %d out of range", diff)
+			return messageError("MsgGetBlockTxn.Decode", str)
+		}
+		index = diff
+		msg.Indexes[i] = uint32(index)
 	}
+
 	return nil
 }
 
-func (msg *MsgGetBlockTxn) Decode(r io.Reader, pver uint32, enc MessageEncoding) error {
+// Encode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) Encode(w io.Writer, pver uint32, enc MessageEncoding) error {
 	if pver < ShortIdsBlocksVersion {
 		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
 			"version %d", pver)
-		return messageError("MsgGetBlockTxn.Decode", str)
+		return messageError("MsgGetBlockTxn.Encode", str)
 	}
-	if err := util.ReadElements(r, &msg.BlockHash); err != nil {
+
+	if err := util.WriteElements(w, &msg.BlockHash); err != nil {
 		return err
 	}
-	indexSize, err := util.ReadVarInt(r)
-	if err != nil {
+	if err := util.WriteVarInt(w, uint64(len(msg.Indexes))); err != nil {
 		return err
 	}
-	indexes := make([]uint16, indexSize)
-	for i := 0; i < len(indexes); i++ {
-		index, err := util.ReadVarInt(r)
-		if err != nil {
-			return err
+	for i, index := range msg.Indexes {
+		diff := index
+		if i > 0 {
+			prev := msg.Indexes[i-1]
+			if diff <= prev {
+				str := fmt.Sprintf("transaction indexes not "+
+					"ascending [%d after %d]", diff, prev)
+				return messageError("MsgGetBlockTxn.Encode", str)
+			}
+			diff -= prev + 1
 		}
-		if index > math.MaxUint16 {
-			return messageError("MsgGetBlockTxn.Decode", fmt.Sprintf("index overflowed 16-bits"))
-		}
-		indexes[i] = uint16(index)
-	}
-	offset := uint16(0)
-	for j := 0; j < len(indexes); j++ {
-		if uint64(indexes[j])+uint64(offset) > math.MaxUint16 {
-			return messageError("MsgGetBlockTxn.Decode", fmt.Sprintf("index overflowed 16-bits"))
+		if err := util.WriteVarInt(w, uint64(diff)); err != nil {
+			return err
 		}
-		indexes[j] += offset
-		offset = indexes[j] + 1
 	}
-	msg.Indexes = indexes
+
 	return nil
 }
 
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
 func (msg *MsgGetBlockTxn) Command() string {
 	return CmdGetBlockTxn
 }
 
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
 func (msg *MsgGetBlockTxn) MaxPayloadLength(pver uint32) uint64 {
-	return 32 + 3 + uint64(len(msg.Indexes))*3
+	return MaxProtocolMessageLength
+}
+
+// NewMsgGetBlockTxn returns a new bitcoin getblocktxn message that conforms to
+// the Message interface.  See MsgGetBlockTxn for details.
+func NewMsgGetBlockTxn(blockHash *util.Hash, indexes []uint32) *MsgGetBlockTxn {
+	return &MsgGetBlockTxn{
+		BlockHash: *blockHash,
+		Indexes:   indexes,
+	}
 }
-*/
@@ -0,0 +1,65 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/copernet/copernicus/util"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestGetBlockTxn tests the MsgGetBlockTxn API and the differential encoding
+// of its indexes.
+func TestGetBlockTxn(t *testing.T) {
+	pver := ProtocolVersion
+	enc := BaseEncoding
+
+	hash := util.Hash{0x01, 0x02}
+	msg := NewMsgGetBlockTxn(&hash, []uint32{1, 2, 5, 300})
+	if cmd := msg.Command(); cmd != "getblocktxn" {
+		t.Errorf("NewMsgGetBlockTxn: wrong command - got %v want %v",
+			cmd, "getblocktxn")
+	}
+
+	var buf bytes.Buffer
+	if err := msg.Encode(&buf, pver, enc); err != nil {
+		t.Fatalf("encode of MsgGetBlockTxn failed %v err <%v>", msg, err)
+	}
+	wantBuf := append(hash[:], 0x04, 0x01, 0x00, 0x02, 0xfd, 0x26, 0x01)
+	if !bytes.Equal(buf.Bytes(), wantBuf) {
+		t.Errorf("encode of MsgGetBlockTxn wrong bytes - got %v, want %v",
+			spew.Sdump(buf.Bytes()), spew.Sdump(wantBuf))
+	}
+
+	readmsg := &MsgGetBlockTxn{}
+	if err := readmsg.Decode(bytes.NewReader(wantBuf), pver, enc); err != nil {
+		t.Fatalf("decode of MsgGetBlockTxn failed [%v] err <%v>", wantBuf, err)
+	}
+	if !reflect.DeepEqual(readmsg, msg) {
+		t.Errorf("decode of MsgGetBlockTxn wrong msg - got %v, want %v",
+			spew.Sdump(readmsg), spew.Sdump(msg))
+	}
+
+	// Older protocol versions should fail since message didn't exist yet.
+	oldPver := ShortIdsBlocksVersion - 1
+	if err := msg.Encode(&bytes.Buffer{}, oldPver, enc); err == nil {
+		t.Errorf("encode of MsgGetBlockTxn passed for old protocol version")
+	}
+	if err := readmsg.Decode(bytes.NewReader(wantBuf), oldPver, enc); err == nil {
+		t.Errorf("decode of MsgGetBlockTxn passed for old protocol version")
+	}
+
+	// Indexes must be ascending.
+	msg = NewMsgGetBlockTxn(&hash, []uint32{2, 2})
+	if err := msg.Encode(&bytes.Buffer{}, pver, enc); err == nil {
+		t.Errorf("encode of MsgGetBlockTxn passed with duplicate indexes")
+	}
+
+	// Indexes overflowing the block size are rejected.
+	overflow := append(hash[:], 0x02, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff)
+	if err := readmsg.Decode(bytes.NewReader(overflow), pver, enc); err == nil {
+		t.Errorf("decode of MsgGetBlockTxn passed with overflowing index")
+	}
+}
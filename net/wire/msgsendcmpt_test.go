@@ -0,0 +1,73 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestSendCmpct tests the MsgSendCmpct API against the latest protocol
+// version.
+func TestSendCmpct(t *testing.T) {
+	pver := ProtocolVersion
+	enc := BaseEncoding
+
+	// Ensure the command is expected value.
+	wantCmd := "sendcmpct"
+	msg := NewMsgSendCmpct(true, CmpctBlockVersion)
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgSendCmpct: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value.
+	wantPayload := uint64(9)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Test encode with latest protocol version.
+	var buf bytes.Buffer
+	err := msg.Encode(&buf, pver, enc)
+	if err != nil {
+		t.Errorf("encode of MsgSendCmpct failed %v err <%v>", msg, err)
+	}
+	wantBuf := []byte{0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	if !bytes.Equal(buf.Bytes(), wantBuf) {
+		t.Errorf("encode of MsgSendCmpct wrong bytes - got %v, want %v",
+			spew.Sdump(buf.Bytes()), spew.Sdump(wantBuf))
+	}
+
+	// Older protocol versions should fail encode since message didn't
+	// exist yet.
+	oldPver := ShortIdsBlocksVersion - 1
+	err = msg.Encode(&bytes.Buffer{}, oldPver, enc)
+	if err == nil {
+		t.Errorf("encode of MsgSendCmpct passed for old protocol "+
+			"version %v", msg)
+	}
+
+	// Test decode with latest protocol version.
+	readmsg := NewMsgSendCmpct(false, 0)
+	err = readmsg.Decode(bytes.NewReader(wantBuf), pver, enc)
+	if err != nil {
+		t.Errorf("decode of MsgSendCmpct failed [%v] err <%v>", wantBuf, err)
+	}
+	if !reflect.DeepEqual(readmsg, msg) {
+		t.Errorf("decode of MsgSendCmpct wrong msg - got %v, want %v",
+			spew.Sdump(readmsg), spew.Sdump(msg))
+	}
+
+	// Older protocol versions should fail decode since message didn't
+	// exist yet.
+	err = readmsg.Decode(bytes.NewReader(wantBuf), oldPver, enc)
+	if err == nil {
+		t.Errorf("decode of MsgSendCmpct passed for old protocol "+
+			"version %v", msg)
+	}
+}
@@ -0,0 +1,18 @@
+/*
+Package zmq publishes block and transaction notifications over ZeroMQ.
+
+Each enabled notification is sent by a PUB socket speaking ZMTP 3.0 with the
+NULL security mechanism, implemented here in pure Go.  The messages use the
+same format as Bitcoin Core and Bitcoin ABC so existing subscribers work
+unchanged: a multipart message made of the topic, the body and a four byte
+little endian sequence number which is incremented per notifier.
+
+	hashblock  the hash of a connected block, in display byte order
+	hashtx     the hash of a transaction accepted to the mempool or connected
+	           in a block, in display byte order
+	rawblock   the serialized connected block
+	rawtx      the serialized transaction
+
+Notifiers configured with the same endpoint share a single socket.
+*/
+package zmq
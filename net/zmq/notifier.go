@@ -0,0 +1,127 @@
+package zmq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/util"
+)
+
+// The notification types, as named in the configuration and reported by the
+// getzmqnotifications RPC.
+const (
+	PubHashBlock = "pubhashblock"
+	PubHashTx    = "pubhashtx"
+	PubRawBlock  = "pubrawblock"
+	PubRawTx     = "pubrawtx"
+)
+
+// The topics of the messages sent by each notification type.
+const (
+	TopicHashBlock = "hashblock"
+	TopicHashTx    = "hashtx"
+	TopicRawBlock  = "rawblock"
+	TopicRawTx     = "rawtx"
+)
+
+var notifierTopics = map[string]string{
+	PubHashBlock: TopicHashBlock,
+	PubHashTx:    TopicHashTx,
+	PubRawBlock:  TopicRawBlock,
+	PubRawTx:     TopicRawTx,
+}
+
+// Notifier publishes one type of notification on a publisher, numbering the
+// messages it sends.
+type Notifier struct {
+	typ   string
+	topic []byte
+	pub   *Publisher
+
+	mtx      sync.Mutex
+	sequence uint32
+}
+
+func newNotifier(typ string, pub *Publisher) *Notifier {
+	return &Notifier{
+		typ:   typ,
+		topic: []byte(notifierTopics[typ]),
+		pub:   pub,
+	}
+}
+
+// Type returns the notification type, e.g. pubhashblock.
+func (n *Notifier) Type() string {
+	return n.typ
+}
+
+// Address returns the endpoint the notifier publishes on.
+func (n *Notifier) Address() string {
+	return n.pub.Address()
+}
+
+// HWM returns the outbound message high water mark of the notifier.
+func (n *Notifier) HWM() int {
+	return n.pub.HWM()
+}
+
+// send publishes body under the topic of the notifier, followed by the
+// sequence number of the message.
+func (n *Notifier) send(body []byte) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	var seq [4]byte
+	binary.LittleEndian.PutUint32(seq[:], n.sequence)
+	if err := n.pub.Publish(n.topic, body, seq[:]); err != nil {
+		log.Warn("zmq: publish %s on %s failed: %v", n.typ, n.pub.Address(), err)
+		return
+	}
+	n.sequence++
+}
+
+// NotifyBlock publishes a connected block if the notifier is for blocks.
+func (n *Notifier) NotifyBlock(blk *block.Block) {
+	switch n.typ {
+	case PubHashBlock:
+		hash := blk.GetHash()
+		n.send(reversedHash(&hash))
+	case PubRawBlock:
+		buf := bytes.NewBuffer(make([]byte, 0, blk.SerializeSize()))
+		if err := blk.Serialize(buf); err != nil {
+			log.Error("zmq: serialize block %s failed: %v", blk.GetHash(), err)
+			return
+		}
+		n.send(buf.Bytes())
+	}
+}
+
+// NotifyTransaction publishes a transaction if the notifier is for
+// transactions.
+func (n *Notifier) NotifyTransaction(txn *tx.Tx) {
+	switch n.typ {
+	case PubHashTx:
+		hash := txn.GetHash()
+		n.send(reversedHash(&hash))
+	case PubRawTx:
+		buf := bytes.NewBuffer(make([]byte, 0, txn.SerializeSize()))
+		if err := txn.Serialize(buf); err != nil {
+			log.Error("zmq: serialize tx %s failed: %v", txn.GetHash(), err)
+			return
+		}
+		n.send(buf.Bytes())
+	}
+}
+
+// reversedHash returns the bytes of hash in the order it is displayed in.
+func reversedHash(hash *util.Hash) []byte {
+	b := hash.GetCloneBytes()
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
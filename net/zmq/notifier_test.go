@@ -0,0 +1,83 @@
+package zmq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/tx"
+)
+
+func TestNotifierMessages(t *testing.T) {
+	p, err := NewPublisher("tcp://127.0.0.1:0", 0)
+	if err != nil {
+		t.Fatalf("NewPublisher failed: %v", err)
+	}
+	defer p.Close()
+
+	s := dialSubscriber(t, p, zmtpMinorVersion)
+	defer s.close()
+	s.subscribe("")
+	waitForSubscription(t, p, "")
+
+	hashTx := newNotifier(PubHashTx, p)
+	rawTx := newNotifier(PubRawTx, p)
+	hashBlock := newNotifier(PubHashBlock, p)
+	if hashTx.Type() != PubHashTx || hashTx.Address() != "tcp://127.0.0.1:0" ||
+		hashTx.HWM() != DefaultHWM {
+		t.Fatalf("unexpected notifier %s %s %d", hashTx.Type(), hashTx.Address(), hashTx.HWM())
+	}
+
+	txn := tx.NewTx(0, 1)
+	blk := block.NewBlock()
+	blk.Txs = []*tx.Tx{txn}
+
+	// Transaction notifiers ignore blocks and block notifiers ignore
+	// transactions.
+	hashTx.NotifyBlock(blk)
+	hashBlock.NotifyTransaction(txn)
+
+	for i := 0; i < 2; i++ {
+		hashTx.NotifyTransaction(txn)
+		rawTx.NotifyTransaction(txn)
+	}
+	hashBlock.NotifyBlock(blk)
+
+	var raw bytes.Buffer
+	txn.Serialize(&raw)
+	txHash := txn.GetHash()
+	blkHash := blk.GetHash()
+	tests := []struct {
+		topic string
+		body  []byte
+		seq   uint32
+	}{
+		{TopicHashTx, reversedHash(&txHash), 0},
+		{TopicRawTx, raw.Bytes(), 0},
+		{TopicHashTx, reversedHash(&txHash), 1},
+		{TopicRawTx, raw.Bytes(), 1},
+		{TopicHashBlock, reversedHash(&blkHash), 0},
+	}
+	for i, test := range tests {
+		msg := s.readMessage()
+		if len(msg) != 3 {
+			t.Fatalf("#%d: got %d parts, want 3", i, len(msg))
+		}
+		if string(msg[0]) != test.topic {
+			t.Errorf("#%d: got topic %q, want %q", i, msg[0], test.topic)
+		}
+		if !bytes.Equal(msg[1], test.body) {
+			t.Errorf("#%d: got body %x, want %x", i, msg[1], test.body)
+		}
+		if seq := binary.LittleEndian.Uint32(msg[2]); len(msg[2]) != 4 || seq != test.seq {
+			t.Errorf("#%d: got sequence %x, want %d", i, msg[2], test.seq)
+		}
+	}
+
+	// Hashes are sent in the byte order they are displayed in.
+	if hex.EncodeToString(reversedHash(&txHash)) != txHash.String() {
+		t.Errorf("hash body %x does not match %s", reversedHash(&txHash), txHash)
+	}
+}
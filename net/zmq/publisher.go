@@ -0,0 +1,402 @@
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/copernet/copernicus/log"
+)
+
+const (
+	// DefaultHWM is the default high water mark of a publisher: the number
+	// of outbound messages queued per subscriber before further messages
+	// are dropped.
+	DefaultHWM = 1000
+
+	socketTypePub = "PUB"
+
+	// handshakeTimeout bounds the time a connecting subscriber has to
+	// complete the greeting and the handshake.
+	handshakeTimeout = 10 * time.Second
+)
+
+var errPublisherClosed = errors.New("zmq: publisher closed")
+
+// Publisher is a ZMQ PUB socket bound to an endpoint.  Messages are fanned out
+// to every connected subscriber with a matching subscription and dropped for
+// subscribers whose queue is full, just as libzmq does.
+type Publisher struct {
+	address  string
+	hwm      int
+	listener net.Listener
+
+	mtx         sync.Mutex
+	subscribers map[*subscriber]struct{}
+	closed      bool
+
+	wg sync.WaitGroup
+}
+
+// NewPublisher binds a publisher to address, which is either of the form
+// tcp://host:port, where a host of * listens on all interfaces, or
+// ipc://path.
+func NewPublisher(address string, hwm int) (*Publisher, error) {
+	network, addr, err := parseEndpoint(address)
+	if err != nil {
+		return nil, err
+	}
+	if hwm <= 0 {
+		hwm = DefaultHWM
+	}
+	if network == "unix" {
+		os.Remove(addr)
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Publisher{
+		address:     address,
+		hwm:         hwm,
+		listener:    listener,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+	p.wg.Add(1)
+	go p.acceptHandler()
+	return p, nil
+}
+
+// parseEndpoint translates a ZMQ endpoint into the network and address to
+// listen on.
+func parseEndpoint(address string) (string, string, error) {
+	idx := strings.Index(address, "://")
+	if idx < 0 {
+		return "", "", fmt.Errorf("zmq: invalid endpoint %q", address)
+	}
+	transport, addr := address[:idx], address[idx+3:]
+	switch transport {
+	case "tcp":
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return "", "", fmt.Errorf("zmq: invalid endpoint %q: %v", address, err)
+		}
+		if host == "*" {
+			host = ""
+		}
+		return "tcp", net.JoinHostPort(host, port), nil
+	case "ipc":
+		if addr == "" {
+			return "", "", fmt.Errorf("zmq: invalid endpoint %q", address)
+		}
+		return "unix", addr, nil
+	default:
+		return "", "", fmt.Errorf("zmq: unsupported transport %q in endpoint %q", transport, address)
+	}
+}
+
+// Address returns the endpoint the publisher was bound to.
+func (p *Publisher) Address() string {
+	return p.address
+}
+
+// Addr returns the local network address of the publisher.
+func (p *Publisher) Addr() net.Addr {
+	return p.listener.Addr()
+}
+
+// HWM returns the high water mark of the publisher.
+func (p *Publisher) HWM() int {
+	return p.hwm
+}
+
+// Publish queues a multipart message to every subscriber whose subscriptions
+// match the first part of the message.  It never blocks.
+func (p *Publisher) Publish(parts ...[]byte) error {
+	if len(parts) == 0 {
+		return errors.New("zmq: empty message")
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.closed {
+		return errPublisherClosed
+	}
+	for s := range p.subscribers {
+		if !s.matches(parts[0]) {
+			continue
+		}
+		select {
+		case s.queue <- parts:
+		default:
+			log.Debug("zmq: dropping message to %s, high water mark reached", s.conn.RemoteAddr())
+		}
+	}
+	return nil
+}
+
+// Close unbinds the publisher and disconnects all subscribers.
+func (p *Publisher) Close() error {
+	p.mtx.Lock()
+	if p.closed {
+		p.mtx.Unlock()
+		return nil
+	}
+	p.closed = true
+	err := p.listener.Close()
+	for s := range p.subscribers {
+		s.close()
+	}
+	p.mtx.Unlock()
+
+	p.wg.Wait()
+	return err
+}
+
+func (p *Publisher) acceptHandler() {
+	defer p.wg.Done()
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			p.mtx.Lock()
+			closed := p.closed
+			p.mtx.Unlock()
+			if closed {
+				return
+			}
+			log.Warn("zmq: accept on %s failed: %v", p.address, err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		p.wg.Add(1)
+		go p.handleConn(conn)
+	}
+}
+
+// handleConn performs the handshake with a new peer and serves it until it
+// disconnects or the publisher is closed.
+func (p *Publisher) handleConn(conn net.Conn) {
+	defer p.wg.Done()
+
+	s := &subscriber{
+		conn:  conn,
+		queue: make(chan [][]byte, p.hwm),
+		quit:  make(chan struct{}),
+	}
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	r := bufio.NewReader(conn)
+	if err := s.handshake(r); err != nil {
+		log.Debug("zmq: handshake with %s failed: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	p.mtx.Lock()
+	if p.closed {
+		p.mtx.Unlock()
+		conn.Close()
+		return
+	}
+	p.subscribers[s] = struct{}{}
+	p.mtx.Unlock()
+	log.Debug("zmq: subscriber %s connected to %s", conn.RemoteAddr(), p.address)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		s.outHandler()
+	}()
+	err := s.inHandler(r)
+
+	p.mtx.Lock()
+	delete(p.subscribers, s)
+	p.mtx.Unlock()
+	s.close()
+	if err != nil && err != io.EOF {
+		log.Debug("zmq: subscriber %s disconnected: %v", conn.RemoteAddr(), err)
+	}
+}
+
+// subscriber is a peer connected to a publisher.
+type subscriber struct {
+	conn  net.Conn
+	queue chan [][]byte
+
+	// wmtx serializes writes to conn, which come from the out handler
+	// and from command replies of the in handler.
+	wmtx sync.Mutex
+
+	mtx           sync.RWMutex
+	subscriptions [][]byte
+
+	closeOnce sync.Once
+	quit      chan struct{}
+}
+
+func (s *subscriber) close() {
+	s.closeOnce.Do(func() {
+		close(s.quit)
+		s.conn.Close()
+	})
+}
+
+// handshake exchanges greetings and READY commands with the peer, which must
+// be a SUB or XSUB socket.
+func (s *subscriber) handshake(r io.Reader) error {
+	if _, err := s.conn.Write(greeting()); err != nil {
+		return err
+	}
+	g := make([]byte, greetingSize)
+	if _, err := io.ReadFull(r, g); err != nil {
+		return err
+	}
+	if err := checkGreeting(g); err != nil {
+		return err
+	}
+
+	if err := writeFrame(s.conn, flagCommand, readyCommand(socketTypePub)); err != nil {
+		return err
+	}
+	f, err := readFrame(r)
+	if err != nil {
+		return err
+	}
+	if !f.isCommand() {
+		return errors.New("zmq: expected READY command")
+	}
+	name, data, err := parseCommand(f.body)
+	if err != nil {
+		return err
+	}
+	if name == cmdError {
+		return fmt.Errorf("zmq: peer rejected handshake: %q", data)
+	}
+	if name != cmdReady {
+		return fmt.Errorf("zmq: expected READY command, got %s", name)
+	}
+	props, err := parseProperties(data)
+	if err != nil {
+		return err
+	}
+	switch peerType := props[propSocketType]; peerType {
+	case "SUB", "XSUB":
+	default:
+		reason := "invalid socket type"
+		writeFrame(s.conn, flagCommand, command(cmdError, append([]byte{byte(len(reason))}, reason...)))
+		return fmt.Errorf("zmq: incompatible socket type %q", peerType)
+	}
+	return nil
+}
+
+// inHandler reads subscription changes and commands from the peer until the
+// connection is closed.
+func (s *subscriber) inHandler(r io.Reader) error {
+	for {
+		f, err := readFrame(r)
+		if err != nil {
+			return err
+		}
+
+		if f.isCommand() {
+			name, data, err := parseCommand(f.body)
+			if err != nil {
+				return err
+			}
+			switch name {
+			case cmdSubscribe:
+				s.subscribe(data)
+			case cmdCancel:
+				s.unsubscribe(data)
+			case cmdPing:
+				// The ping carries a two byte TTL followed by the
+				// context the pong has to echo.
+				var context []byte
+				if len(data) > 2 {
+					context = data[2:]
+				}
+				s.wmtx.Lock()
+				err = writeFrame(s.conn, flagCommand, command(cmdPong, context))
+				s.wmtx.Unlock()
+				if err != nil {
+					return err
+				}
+			case cmdError:
+				return fmt.Errorf("zmq: peer error: %q", data)
+			}
+			continue
+		}
+
+		// ZMTP 3.0 subscribers send subscriptions as messages whose first
+		// byte is 1 to subscribe and 0 to unsubscribe.  Anything else is
+		// ignored, as is any multipart message.
+		if f.more() || len(f.body) == 0 {
+			continue
+		}
+		switch f.body[0] {
+		case 1:
+			s.subscribe(f.body[1:])
+		case 0:
+			s.unsubscribe(f.body[1:])
+		}
+	}
+}
+
+// outHandler writes queued messages to the peer.
+func (s *subscriber) outHandler() {
+	for {
+		select {
+		case parts := <-s.queue:
+			s.wmtx.Lock()
+			err := writeMessage(s.conn, parts)
+			s.wmtx.Unlock()
+			if err != nil {
+				s.close()
+				return
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *subscriber) subscribe(topic []byte) {
+	s.mtx.Lock()
+	s.subscriptions = append(s.subscriptions, append([]byte(nil), topic...))
+	s.mtx.Unlock()
+}
+
+// unsubscribe removes one instance of topic, subscriptions being counted like
+// libzmq does.
+func (s *subscriber) unsubscribe(topic []byte) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for i, sub := range s.subscriptions {
+		if bytes.Equal(sub, topic) {
+			s.subscriptions = append(s.subscriptions[:i], s.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+// matches returns whether a message with the passed topic frame matches one of
+// the subscriptions, which are prefixes.
+func (s *subscriber) matches(topic []byte) bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	for _, sub := range s.subscriptions {
+		if bytes.HasPrefix(topic, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// testSubscriber is a minimal ZMTP SUB socket used to exercise the publisher.
+type testSubscriber struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialSubscriber(t *testing.T, p *Publisher, minor byte) *testSubscriber {
+	conn, err := net.Dial("tcp", p.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	s := &testSubscriber{t: t, conn: conn, r: bufio.NewReader(conn)}
+
+	g := greeting()
+	g[11] = minor
+	if _, err := conn.Write(g); err != nil {
+		t.Fatalf("write greeting failed: %v", err)
+	}
+	peer := make([]byte, greetingSize)
+	if _, err := io.ReadFull(s.r, peer); err != nil {
+		t.Fatalf("read greeting failed: %v", err)
+	}
+	if err := checkGreeting(peer); err != nil {
+		t.Fatalf("bad greeting from publisher: %v", err)
+	}
+
+	if err := writeFrame(conn, flagCommand, readyCommand("SUB")); err != nil {
+		t.Fatalf("write READY failed: %v", err)
+	}
+	f := s.readFrame()
+	name, data, err := parseCommand(f.body)
+	if err != nil || name != cmdReady {
+		t.Fatalf("expected READY, got %q: %v", name, err)
+	}
+	props, err := parseProperties(data)
+	if err != nil {
+		t.Fatalf("parse properties failed: %v", err)
+	}
+	if props[propSocketType] != socketTypePub {
+		t.Fatalf("socket type: got %q, want %q", props[propSocketType], socketTypePub)
+	}
+	return s
+}
+
+func (s *testSubscriber) readFrame() *frame {
+	f, err := readFrame(s.r)
+	if err != nil {
+		s.t.Fatalf("read frame failed: %v", err)
+	}
+	return f
+}
+
+func (s *testSubscriber) readMessage() [][]byte {
+	var parts [][]byte
+	for {
+		f := s.readFrame()
+		if f.isCommand() {
+			s.t.Fatalf("unexpected command in message")
+		}
+		parts = append(parts, f.body)
+		if !f.more() {
+			return parts
+		}
+	}
+}
+
+func (s *testSubscriber) subscribe(topic string) {
+	body := append([]byte{1}, topic...)
+	if err := writeFrame(s.conn, 0, body); err != nil {
+		s.t.Fatalf("subscribe failed: %v", err)
+	}
+}
+
+func (s *testSubscriber) close() {
+	s.conn.Close()
+}
+
+// waitForSubscription waits until a subscriber of the publisher is
+// subscribed to topic, as subscriptions are applied asynchronously.
+func waitForSubscription(t *testing.T, p *Publisher, topic string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		p.mtx.Lock()
+		for sub := range p.subscribers {
+			if sub.matches([]byte(topic)) {
+				p.mtx.Unlock()
+				return
+			}
+		}
+		p.mtx.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no subscription to %s", topic)
+}
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		address string
+		network string
+		addr    string
+		valid   bool
+	}{
+		{"tcp://127.0.0.1:28332", "tcp", "127.0.0.1:28332", true},
+		{"tcp://*:28332", "tcp", ":28332", true},
+		{"tcp://[::1]:28332", "tcp", "[::1]:28332", true},
+		{"ipc:///tmp/copernicus.zmq", "unix", "/tmp/copernicus.zmq", true},
+		{"tcp://127.0.0.1", "", "", false},
+		{"127.0.0.1:28332", "", "", false},
+		{"inproc://blocks", "", "", false},
+		{"ipc://", "", "", false},
+	}
+
+	for _, test := range tests {
+		network, addr, err := parseEndpoint(test.address)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: got error %v, want valid %v", test.address, err, test.valid)
+			continue
+		}
+		if network != test.network || addr != test.addr {
+			t.Errorf("%s: got %s %s, want %s %s", test.address, network, addr,
+				test.network, test.addr)
+		}
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 255, 256, 4000} {
+		body := bytes.Repeat([]byte{0xab}, size)
+		var buf bytes.Buffer
+		if err := writeFrame(&buf, flagMore, body); err != nil {
+			t.Fatalf("size %d: write failed: %v", size, err)
+		}
+		f, err := readFrame(&buf)
+		if err != nil {
+			t.Fatalf("size %d: read failed: %v", size, err)
+		}
+		if !f.more() || f.isCommand() || !bytes.Equal(f.body, body) {
+			t.Errorf("size %d: frame did not round trip", size)
+		}
+		if (f.flags&flagLong != 0) != (size > 255) {
+			t.Errorf("size %d: unexpected long flag", size)
+		}
+	}
+}
+
+func TestPublishSubscription(t *testing.T) {
+	p, err := NewPublisher("tcp://127.0.0.1:0", 0)
+	if err != nil {
+		t.Fatalf("NewPublisher failed: %v", err)
+	}
+	defer p.Close()
+	if p.HWM() != DefaultHWM {
+		t.Errorf("HWM: got %d, want %d", p.HWM(), DefaultHWM)
+	}
+
+	s := dialSubscriber(t, p, zmtpMinorVersion)
+	defer s.close()
+	s.subscribe("hash")
+	waitForSubscription(t, p, "hash")
+
+	p.Publish([]byte("hashtx"), []byte{1, 2, 3}, []byte{0, 0, 0, 0})
+	msg := s.readMessage()
+	if len(msg) != 3 || string(msg[0]) != "hashtx" || !bytes.Equal(msg[1], []byte{1, 2, 3}) {
+		t.Fatalf("unexpected message %x", msg)
+	}
+
+	// Topics not matching the subscription prefix are filtered out, so the
+	// next message received is the matching one published after.
+	p.Publish([]byte("rawtx"), []byte{4})
+	p.Publish([]byte("hashblock"), []byte{5})
+	msg = s.readMessage()
+	if string(msg[0]) != "hashblock" {
+		t.Fatalf("got topic %q, want hashblock", msg[0])
+	}
+}
+
+func TestSubscribeCommand(t *testing.T) {
+	p, err := NewPublisher("tcp://127.0.0.1:0", 10)
+	if err != nil {
+		t.Fatalf("NewPublisher failed: %v", err)
+	}
+	defer p.Close()
+
+	// ZMTP 3.1 peers subscribe with a command and ping the publisher.
+	s := dialSubscriber(t, p, 1)
+	defer s.close()
+	if err := writeFrame(s.conn, flagCommand, command(cmdPing, []byte{0, 0, 'c', 't', 'x'})); err != nil {
+		t.Fatalf("ping failed: %v", err)
+	}
+	f := s.readFrame()
+	name, data, err := parseCommand(f.body)
+	if err != nil || name != cmdPong || string(data) != "ctx" {
+		t.Fatalf("expected PONG with context, got %q %q: %v", name, data, err)
+	}
+
+	if err := writeFrame(s.conn, flagCommand, command(cmdSubscribe, []byte("rawblock"))); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	waitForSubscription(t, p, "rawblock")
+	p.Publish([]byte("rawblock"), []byte{1}, []byte{0, 0, 0, 0})
+	msg := s.readMessage()
+	if string(msg[0]) != "rawblock" {
+		t.Fatalf("got topic %q, want rawblock", msg[0])
+	}
+}
+
+func TestRejectIncompatibleSocket(t *testing.T) {
+	p, err := NewPublisher("tcp://127.0.0.1:0", 0)
+	if err != nil {
+		t.Fatalf("NewPublisher failed: %v", err)
+	}
+	defer p.Close()
+
+	conn, err := net.Dial("tcp", p.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	conn.Write(greeting())
+	if _, err := io.ReadFull(r, make([]byte, greetingSize)); err != nil {
+		t.Fatalf("read greeting failed: %v", err)
+	}
+	writeFrame(conn, flagCommand, readyCommand("REQ"))
+	if _, err := readFrame(r); err != nil {
+		t.Fatalf("read READY failed: %v", err)
+	}
+
+	f, err := readFrame(r)
+	if err != nil {
+		t.Fatalf("read ERROR failed: %v", err)
+	}
+	if name, _, _ := parseCommand(f.body); name != cmdError {
+		t.Fatalf("got command %q, want ERROR", name)
+	}
+	if _, err := readFrame(r); err == nil {
+		t.Fatalf("connection still open after ERROR")
+	}
+}
+
+func TestPublishAfterClose(t *testing.T) {
+	p, err := NewPublisher("tcp://127.0.0.1:0", 0)
+	if err != nil {
+		t.Fatalf("NewPublisher failed: %v", err)
+	}
+	s := dialSubscriber(t, p, zmtpMinorVersion)
+	defer s.close()
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := p.Publish([]byte("hashtx")); err != errPublisherClosed {
+		t.Fatalf("got error %v, want %v", err, errPublisherClosed)
+	}
+	if _, err := readFrame(s.r); err == nil {
+		t.Fatalf("subscriber still connected after Close")
+	}
+}
@@ -0,0 +1,136 @@
+package zmq
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+)
+
+var (
+	mtx        sync.RWMutex
+	notifiers  []*Notifier
+	publishers []*Publisher
+
+	subscribeOnce sync.Once
+)
+
+// Init binds the publishers of the notifications enabled in the configuration
+// and starts relaying connected blocks and accepted transactions to them.  It
+// must be called after the chain and the mempool have been initialized.
+func Init() error {
+	cfg := conf.Cfg.ZMQ
+	enabled := []struct {
+		typ     string
+		address string
+		hwm     int
+	}{
+		{PubHashBlock, cfg.PubHashBlock, cfg.PubHashBlockHWM},
+		{PubHashTx, cfg.PubHashTx, cfg.PubHashTxHWM},
+		{PubRawBlock, cfg.PubRawBlock, cfg.PubRawBlockHWM},
+		{PubRawTx, cfg.PubRawTx, cfg.PubRawTxHWM},
+	}
+
+	var ns []*Notifier
+	var ps []*Publisher
+	byAddress := make(map[string]*Publisher)
+	for _, e := range enabled {
+		if e.address == "" {
+			continue
+		}
+		pub, ok := byAddress[e.address]
+		if !ok {
+			var err error
+			pub, err = NewPublisher(e.address, e.hwm)
+			if err != nil {
+				for _, p := range ps {
+					p.Close()
+				}
+				return fmt.Errorf("zmq: bind %s for %s failed: %v", e.address, e.typ, err)
+			}
+			byAddress[e.address] = pub
+			ps = append(ps, pub)
+		}
+		ns = append(ns, newNotifier(e.typ, pub))
+		log.Info("zmq: %s notifications publishing on %s", e.typ, e.address)
+	}
+	if len(ns) == 0 {
+		return nil
+	}
+
+	mtx.Lock()
+	notifiers = ns
+	publishers = ps
+	mtx.Unlock()
+
+	// The chain and the mempool have no way to unsubscribe, so the
+	// callbacks are only registered once and find the notifiers to use
+	// under the lock.
+	subscribeOnce.Do(func() {
+		chain.GetInstance().Subscribe(handleChainNotification)
+		mempool.GetInstance().Subscribe(handleTxAccepted)
+	})
+	return nil
+}
+
+// Stop closes the publishers, disconnecting all subscribers.
+func Stop() {
+	mtx.Lock()
+	ps := publishers
+	notifiers = nil
+	publishers = nil
+	mtx.Unlock()
+
+	for _, p := range ps {
+		if err := p.Close(); err != nil {
+			log.Warn("zmq: close %s failed: %v", p.Address(), err)
+		}
+	}
+}
+
+// ActiveNotifiers returns the enabled notifiers, in the order of their type.
+func ActiveNotifiers() []*Notifier {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	ns := make([]*Notifier, len(notifiers))
+	copy(ns, notifiers)
+	return ns
+}
+
+// handleChainNotification publishes connected blocks along with their
+// transactions.
+func handleChainNotification(notification *chain.Notification) {
+	if notification.Type != chain.NTBlockConnected {
+		return
+	}
+	blk, ok := notification.Data.(*block.Block)
+	if !ok {
+		log.Warn("zmq: block connected notification is not a block")
+		return
+	}
+
+	mtx.RLock()
+	defer mtx.RUnlock()
+	for _, n := range notifiers {
+		n.NotifyBlock(blk)
+	}
+	for _, txn := range blk.Txs {
+		for _, n := range notifiers {
+			n.NotifyTransaction(txn)
+		}
+	}
+}
+
+// handleTxAccepted publishes transactions accepted to the mempool.  It is
+// called with the mempool locked and publishing never blocks.
+func handleTxAccepted(txe *mempool.TxEntry) {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	for _, n := range notifiers {
+		n.NotifyTransaction(txe.Tx)
+	}
+}
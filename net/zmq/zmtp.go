@@ -0,0 +1,197 @@
+package zmq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ZMTP 3.0 framing, see https://rfc.zeromq.org/spec/23/.  Only the NULL
+// security mechanism is supported, which is what the notification subscribers
+// of bitcoind use.
+
+const (
+	greetingSize = 64
+
+	// zmtpMajorVersion and zmtpMinorVersion are the protocol version sent
+	// in the greeting.  Peers speaking a later minor version fall back to
+	// ours.
+	zmtpMajorVersion = 3
+	zmtpMinorVersion = 0
+
+	flagMore    = 0x01
+	flagLong    = 0x02
+	flagCommand = 0x04
+
+	// maxFrameSize bounds the frames read from subscribers, which only send
+	// subscriptions and commands.
+	maxFrameSize = 1 << 16
+
+	mechanismNull = "NULL"
+
+	cmdReady     = "READY"
+	cmdError     = "ERROR"
+	cmdSubscribe = "SUBSCRIBE"
+	cmdCancel    = "CANCEL"
+	cmdPing      = "PING"
+	cmdPong      = "PONG"
+
+	propSocketType = "Socket-Type"
+)
+
+var errBadGreeting = errors.New("zmq: invalid greeting")
+
+// greeting returns the greeting sent to peers: the signature, the version,
+// the NULL mechanism and the as-server flag.
+func greeting() []byte {
+	g := make([]byte, greetingSize)
+	g[0] = 0xff
+	g[9] = 0x7f
+	g[10] = zmtpMajorVersion
+	g[11] = zmtpMinorVersion
+	copy(g[12:32], mechanismNull)
+	return g
+}
+
+// checkGreeting validates the greeting of a peer.
+func checkGreeting(g []byte) error {
+	if len(g) != greetingSize || g[0] != 0xff || g[9] != 0x7f {
+		return errBadGreeting
+	}
+	if g[10] < zmtpMajorVersion {
+		return fmt.Errorf("zmq: unsupported protocol version %d.%d", g[10], g[11])
+	}
+	mechanism := string(bytes.TrimRight(g[12:32], "\x00"))
+	if mechanism != mechanismNull {
+		return fmt.Errorf("zmq: unsupported security mechanism %q", mechanism)
+	}
+	return nil
+}
+
+// frame is a ZMTP frame, either a message part or a command.
+type frame struct {
+	flags byte
+	body  []byte
+}
+
+func (f *frame) more() bool {
+	return f.flags&flagMore != 0
+}
+
+func (f *frame) isCommand() bool {
+	return f.flags&flagCommand != 0
+}
+
+// writeFrame writes body as a single frame with the passed flags, choosing
+// the short or long size encoding.
+func writeFrame(w io.Writer, flags byte, body []byte) error {
+	var header [9]byte
+	n := 2
+	if len(body) > 255 {
+		header[0] = flags | flagLong
+		binary.BigEndian.PutUint64(header[1:], uint64(len(body)))
+		n = 9
+	} else {
+		header[0] = flags
+		header[1] = byte(len(body))
+	}
+	if _, err := w.Write(header[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// writeMessage writes the parts of a multipart message.
+func writeMessage(w io.Writer, parts [][]byte) error {
+	for i, part := range parts {
+		var flags byte
+		if i < len(parts)-1 {
+			flags = flagMore
+		}
+		if err := writeFrame(w, flags, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFrame reads a frame of at most maxFrameSize bytes.
+func readFrame(r io.Reader) (*frame, error) {
+	var header [9]byte
+	if _, err := io.ReadFull(r, header[:2]); err != nil {
+		return nil, err
+	}
+
+	f := &frame{flags: header[0]}
+	size := uint64(header[1])
+	if f.flags&flagLong != 0 {
+		if _, err := io.ReadFull(r, header[2:]); err != nil {
+			return nil, err
+		}
+		size = binary.BigEndian.Uint64(header[1:])
+	}
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("zmq: frame of %d bytes exceeds maximum of %d", size, maxFrameSize)
+	}
+
+	f.body = make([]byte, size)
+	if _, err := io.ReadFull(r, f.body); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// command encodes a command frame body: the length prefixed name followed by
+// the data of the command.
+func command(name string, data []byte) []byte {
+	body := make([]byte, 0, 1+len(name)+len(data))
+	body = append(body, byte(len(name)))
+	body = append(body, name...)
+	return append(body, data...)
+}
+
+// parseCommand splits a command frame body into the name and data of the
+// command.
+func parseCommand(body []byte) (string, []byte, error) {
+	if len(body) == 0 || int(body[0]) > len(body)-1 {
+		return "", nil, errors.New("zmq: malformed command")
+	}
+	nameLen := int(body[0])
+	return string(body[1 : 1+nameLen]), body[1+nameLen:], nil
+}
+
+// readyCommand returns the READY command of a socket of the passed type.
+func readyCommand(socketType string) []byte {
+	var props bytes.Buffer
+	props.WriteByte(byte(len(propSocketType)))
+	props.WriteString(propSocketType)
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(socketType)))
+	props.Write(size[:])
+	props.WriteString(socketType)
+	return command(cmdReady, props.Bytes())
+}
+
+// parseProperties decodes the metadata properties of a READY command.
+func parseProperties(data []byte) (map[string]string, error) {
+	props := make(map[string]string)
+	for len(data) > 0 {
+		nameLen := int(data[0])
+		if len(data) < 1+nameLen+4 {
+			return nil, errors.New("zmq: malformed property")
+		}
+		name := string(data[1 : 1+nameLen])
+		data = data[1+nameLen:]
+		valueLen := binary.BigEndian.Uint32(data)
+		data = data[4:]
+		if uint64(len(data)) < uint64(valueLen) {
+			return nil, errors.New("zmq: malformed property")
+		}
+		props[name] = string(data[:valueLen])
+		data = data[valueLen:]
+	}
+	return props, nil
+}
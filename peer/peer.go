@@ -31,7 +31,7 @@ import (
 
 const (
 	// MaxProtocolVersion is the max protocol version the peer supports.
-	MaxProtocolVersion = wire.FeeFilterVersion
+	MaxProtocolVersion = wire.InvalidCBNoBanVersion
 
 	// minAcceptableProtocolVersion is the lowest protocol version that a
 	// connected peer may support.
@@ -208,6 +208,21 @@ type MessageListeners struct {
 	// message.
 	OnSendHeaders func(p *Peer, msg *wire.MsgSendHeaders)
 
+	// OnSendCmpct is invoked when a peer receives a sendcmpct bitcoin
+	// message.
+	OnSendCmpct func(p *Peer, msg *wire.MsgSendCmpct)
+
+	// OnCmpctBlock is invoked when a peer receives a cmpctblock bitcoin
+	// message.
+	OnCmpctBlock func(p *Peer, msg *wire.MsgCmpctBlock, done chan<- struct{})
+
+	// OnGetBlockTxn is invoked when a peer receives a getblocktxn bitcoin
+	// message.
+	OnGetBlockTxn func(p *Peer, msg *wire.MsgGetBlockTxn, done chan<- struct{})
+
+	// OnBlockTxn is invoked when a peer receives a blocktxn bitcoin message.
+	OnBlockTxn func(p *Peer, msg *wire.MsgBlockTxn, done chan<- struct{})
+
 	// OnRead is invoked when a peer receives a bitcoin message.  It
 	// consists of the number of bytes read, the message, and whether or not
 	// an error in the read occurred.  Typically, callers will opt to use
@@ -394,6 +409,15 @@ type StatsSnap struct {
 	UsesCashMagic         bool
 	MapSendBytesPerMsgCmd map[string]uint64
 	MapRecvBytesPerMsgCmd map[string]uint64
+
+	// Compact block relay (BIP0152) negotiation state and statistics.
+	CmpctVersion             uint64
+	CmpctHighBandwidthTo     bool
+	CmpctHighBandwidthFrom   bool
+	CmpctBlocksReceived      uint64
+	CmpctBlocksReconstructed uint64
+	CmpctBlockTxnRequests    uint64
+	CmpctBlocksFailed        uint64
 }
 
 // HashFunc is a function which returns a block hash, height and error
@@ -447,6 +471,12 @@ type Peer struct {
 	connected     int32
 	disconnect    int32
 
+	// Compact block reconstruction statistics, see StatsSnap.
+	cmpctBlocksReceived      uint64
+	cmpctBlocksReconstructed uint64
+	cmpctBlockTxnRequests    uint64
+	cmpctBlocksFailed        uint64
+
 	conn net.Conn
 
 	// These fields are set at creation time and never modified, so they are
@@ -465,6 +495,9 @@ type Peer struct {
 	protocolVersion      uint32 // negotiated protocol version
 	sendHeadersPreferred bool   // peer sent a sendheaders message
 	revertToInv          bool   //whether to revert to inv mode for a prefer-header-node
+	cmpctVersion         uint64 // compact block version of a supported sendcmpct
+	cmpctHighBandwidthTo bool   // peer asked for cmpctblock announcements
+	cmpctHighBandwidth   bool   // we asked for cmpctblock announcements
 	verAckReceived       bool
 	isWhitelisted        bool
 
@@ -584,6 +617,9 @@ func (p *Peer) StatsSnapshot() *StatsSnap {
 	userAgent := p.userAgent
 	services := p.services
 	protocolVersion := p.advertisedProtoVer
+	cmpctVersion := p.cmpctVersion
+	cmpctHighBandwidthTo := p.cmpctHighBandwidthTo
+	cmpctHighBandwidth := p.cmpctHighBandwidth
 	p.flagsMtx.Unlock()
 
 	// Get a copy of all relevant flags and stats.
@@ -605,6 +641,14 @@ func (p *Peer) StatsSnapshot() *StatsSnap {
 		LastPingNonce:  p.lastPingNonce,
 		LastPingMicros: p.lastPingMicros,
 		LastPingTime:   p.lastPingTime,
+
+		CmpctVersion:             cmpctVersion,
+		CmpctHighBandwidthTo:     cmpctHighBandwidthTo,
+		CmpctHighBandwidthFrom:   cmpctHighBandwidth,
+		CmpctBlocksReceived:      atomic.LoadUint64(&p.cmpctBlocksReceived),
+		CmpctBlocksReconstructed: atomic.LoadUint64(&p.cmpctBlocksReconstructed),
+		CmpctBlockTxnRequests:    atomic.LoadUint64(&p.cmpctBlockTxnRequests),
+		CmpctBlocksFailed:        atomic.LoadUint64(&p.cmpctBlocksFailed),
 	}
 
 	p.statsMtx.RUnlock()
@@ -890,6 +934,97 @@ func (p *Peer) SetPreferHeaders() {
 	p.flagsMtx.Unlock()
 }
 
+// SetSendCmpct records the compact block relay preferences the peer sent in a
+// sendcmpct message.  Messages for unsupported compact block versions are
+// ignored.
+//
+// This function is safe for concurrent access.
+func (p *Peer) SetSendCmpct(announce bool, version uint64) {
+	if version != wire.CmpctBlockVersion {
+		return
+	}
+
+	p.flagsMtx.Lock()
+	p.cmpctVersion = version
+	p.cmpctHighBandwidthTo = announce
+	p.flagsMtx.Unlock()
+}
+
+// ProvidesCmpctBlocks returns whether the peer supports compact block relay,
+// that is whether it can be asked for cmpctblock and blocktxn messages.
+//
+// This function is safe for concurrent access.
+func (p *Peer) ProvidesCmpctBlocks() bool {
+	p.flagsMtx.Lock()
+	provides := p.cmpctVersion == wire.CmpctBlockVersion
+	p.flagsMtx.Unlock()
+
+	return provides
+}
+
+// WantsCmpctBlocks returns whether the peer asked to be announced new blocks
+// with cmpctblock messages (high bandwidth mode).
+//
+// This function is safe for concurrent access.
+func (p *Peer) WantsCmpctBlocks() bool {
+	p.flagsMtx.Lock()
+	wants := p.cmpctVersion == wire.CmpctBlockVersion && p.cmpctHighBandwidthTo
+	p.flagsMtx.Unlock()
+
+	return wants
+}
+
+// AnnouncesCmpctBlocks returns whether the peer was asked to announce new
+// blocks with cmpctblock messages.
+//
+// This function is safe for concurrent access.
+func (p *Peer) AnnouncesCmpctBlocks() bool {
+	p.flagsMtx.Lock()
+	announces := p.cmpctHighBandwidth
+	p.flagsMtx.Unlock()
+
+	return announces
+}
+
+// PushSendCmpctMsg sends a sendcmpct message which tells the peer that compact
+// blocks are supported, and whether new blocks should be announced with
+// cmpctblock messages (high bandwidth mode) or not (low bandwidth mode).
+// Nothing is sent to peers which do not support compact blocks.
+func (p *Peer) PushSendCmpctMsg(announce bool) {
+	if p.ProtocolVersion() < wire.ShortIdsBlocksVersion {
+		return
+	}
+
+	p.flagsMtx.Lock()
+	p.cmpctHighBandwidth = announce
+	p.flagsMtx.Unlock()
+
+	p.QueueMessage(wire.NewMsgSendCmpct(announce, wire.CmpctBlockVersion), nil)
+}
+
+// CmpctBlockReceived records a cmpctblock message received from the peer.
+func (p *Peer) CmpctBlockReceived() {
+	atomic.AddUint64(&p.cmpctBlocksReceived, 1)
+}
+
+// CmpctBlockReconstructed records a block announced by the peer with a
+// cmpctblock message which was rebuilt without downloading it in full.
+func (p *Peer) CmpctBlockReconstructed() {
+	atomic.AddUint64(&p.cmpctBlocksReconstructed, 1)
+}
+
+// CmpctBlockTxnRequested records a getblocktxn message sent to the peer for
+// the transactions of a compact block missing from the mempool.
+func (p *Peer) CmpctBlockTxnRequested() {
+	atomic.AddUint64(&p.cmpctBlockTxnRequests, 1)
+}
+
+// CmpctBlockFailed records a compact block from the peer which could not be
+// reconstructed and was downloaded in full instead.
+func (p *Peer) CmpctBlockFailed() {
+	atomic.AddUint64(&p.cmpctBlocksFailed, 1)
+}
+
 // localVersionMsg creates a version message that can be used to send to the
 // remote peer.
 func (p *Peer) localVersionMsg() (*wire.MsgVersion, error) {
@@ -8,6 +8,7 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 
 	"encoding/hex"
+	"fmt"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/block"
 	"github.com/copernet/copernicus/model/pow"
@@ -166,6 +167,9 @@ func (blockTreeDB *BlockTreeDB) ReadTxIndex(txid *util.Hash) (*block.DiskTxPos,
 	tmp = append(tmp, db.DbTxIndex)
 	tmp = append(tmp, txid[:]...)
 	vdata, err := blockTreeDB.dbw.Read(tmp)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
 	if err != nil {
 		log.Error("Error: ReadTxIndex======%#v", err)
 		panic("Error: ReadTxIndex======")
@@ -179,6 +183,14 @@ func (blockTreeDB *BlockTreeDB) ReadTxIndex(txid *util.Hash) (*block.DiskTxPos,
 }
 
 func (blockTreeDB *BlockTreeDB) WriteTxIndex(txIndexes map[util.Hash]block.DiskTxPos) error {
+	return blockTreeDB.UpdateTxIndex(txIndexes, nil, nil)
+}
+
+// UpdateTxIndex writes the positions of txIndexes, erases the entries of
+// erased and, when bestBlock is not nil, records it as the block the index is
+// synced to, all in a single batch.
+func (blockTreeDB *BlockTreeDB) UpdateTxIndex(txIndexes map[util.Hash]block.DiskTxPos, erased []util.Hash,
+	bestBlock *util.Hash) error {
 	var batch = db.NewBatchWrapper(blockTreeDB.dbw)
 	keytmp := make([]byte, 0, 100)
 	valuetmp := make([]byte, 0, 100)
@@ -202,9 +214,36 @@ func (blockTreeDB *BlockTreeDB) WriteTxIndex(txIndexes map[util.Hash]block.DiskT
 		}
 		batch.Write(keyBuf.Bytes(), valueBuf.Bytes())
 	}
+	for _, txid := range erased {
+		key := make([]byte, 0, 1+util.Hash256Size)
+		key = append(key, db.DbTxIndex)
+		key = append(key, txid[:]...)
+		batch.Erase(key)
+	}
+	if bestBlock != nil {
+		batch.Write([]byte{db.DbTxIndexBestBlock}, bestBlock[:])
+	}
 	return blockTreeDB.dbw.WriteBatch(batch, false)
 }
 
+// ReadTxIndexBestBlock returns the hash of the last block whose transactions
+// were added to the transaction index, nil if the index was never built.
+func (blockTreeDB *BlockTreeDB) ReadTxIndexBestBlock() (*util.Hash, error) {
+	vdata, err := blockTreeDB.dbw.Read([]byte{db.DbTxIndexBestBlock})
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var hash util.Hash
+	if len(vdata) != len(hash) {
+		return nil, fmt.Errorf("blkDB: invalid txindex best block length %d", len(vdata))
+	}
+	copy(hash[:], vdata)
+	return &hash, nil
+}
+
 func (blockTreeDB *BlockTreeDB) WriteFlag(name string, value bool) error {
 	tmp := make([]byte, 0, 100)
 	tmp = append(tmp, db.DbFlag)
@@ -221,7 +260,7 @@ func (blockTreeDB *BlockTreeDB) ReadFlag(name string) bool {
 	tmp = append(tmp, name...)
 	b, err := blockTreeDB.dbw.Read(tmp)
 
-	if b[0] == '1' && err == nil {
+	if err == nil && len(b) > 0 && b[0] == '1' {
 		return true
 	}
 	return false
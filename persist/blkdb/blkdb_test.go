@@ -66,6 +66,52 @@ func TestWRTxIndex(t *testing.T) {
 	}
 }
 
+func TestUpdateTxIndex(t *testing.T) {
+	defer initBlockDB()()
+
+	best, err := GetInstance().ReadTxIndexBestBlock()
+	if err != nil || best != nil {
+		t.Errorf("txindex best block should be absent: %v, %v\n", best, err)
+	}
+	h1 := util.HashFromString("01")
+	h2 := util.HashFromString("02")
+	txpos, err := GetInstance().ReadTxIndex(h1)
+	if err != nil || txpos != nil {
+		t.Errorf("unknown tx should not be found: %v, %v\n", txpos, err)
+	}
+
+	dbpos := block.NewDiskBlockPos(1, 8)
+	txindexs := map[util.Hash]block.DiskTxPos{
+		*h1: *block.NewDiskTxPos(dbpos, 1),
+		*h2: *block.NewDiskTxPos(dbpos, 100),
+	}
+	blockHash := util.HashFromString("000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d011")
+	if err := GetInstance().UpdateTxIndex(txindexs, nil, blockHash); err != nil {
+		t.Errorf("update tx index failed: %v\n", err)
+	}
+	best, err = GetInstance().ReadTxIndexBestBlock()
+	if err != nil || !reflect.DeepEqual(best, blockHash) {
+		t.Errorf("txindex best block should be %v, got %v, %v\n", blockHash, best, err)
+	}
+
+	prevHash := util.HashFromString("00000000000001bcd6b635a1249dfbe76c0d001592a7219a36cd9bbd002c7238")
+	if err := GetInstance().UpdateTxIndex(nil, []util.Hash{*h1}, prevHash); err != nil {
+		t.Errorf("erase tx index failed: %v\n", err)
+	}
+	txpos, err = GetInstance().ReadTxIndex(h1)
+	if err != nil || txpos != nil {
+		t.Errorf("erased tx should not be found: %v, %v\n", txpos, err)
+	}
+	txpos, err = GetInstance().ReadTxIndex(h2)
+	if err != nil || !reflect.DeepEqual(txpos, block.NewDiskTxPos(dbpos, 100)) {
+		t.Errorf("tx index should be kept: %v, %v\n", txpos, err)
+	}
+	best, _ = GetInstance().ReadTxIndexBestBlock()
+	if !reflect.DeepEqual(best, prevHash) {
+		t.Errorf("txindex best block should be %v, got %v\n", prevHash, best)
+	}
+}
+
 func TestWriteFlag(t *testing.T) {
 	defer initBlockDB()()
 	//test flag: value is false
@@ -87,6 +133,11 @@ func TestWriteFlag(t *testing.T) {
 	if !res2 {
 		t.Errorf("the flag should is true: %v\n", res2)
 	}
+
+	//test flag: never written
+	if GetInstance().ReadFlag("unknown") {
+		t.Errorf("the unknown flag should is false\n")
+	}
 }
 
 func TestWriteReindexing(t *testing.T) {
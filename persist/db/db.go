@@ -30,10 +30,21 @@ const (
 	DbReindexFlag byte = 'R'
 	DbLastBlock   byte = 'l'
 
+	DbTxIndexBestBlock byte = 'T'
+
 	DbWalletKey      byte = 'W'
 	DbWalletScript   byte = 'S'
 	DbWalletAddrBook byte = 'A'
 	DbWalletTx       byte = 'X'
+
+	DbWalletCryptedKey byte = 'K'
+	DbWalletMasterKey  byte = 'M'
+	DbWalletHDChain    byte = 'H'
+	DbWalletKeyMeta    byte = 'm'
+	DbWalletPool       byte = 'p'
+	DbWalletWatchOnly  byte = 'w'
+	DbWalletBestBlock  byte = 'L'
+	DbWalletLockedCoin byte = 'u'
 )
 
 const (
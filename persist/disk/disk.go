@@ -1,11 +1,13 @@
 package disk
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
 	"os"
@@ -21,6 +23,7 @@ import (
 
 	"github.com/copernet/copernicus/model/blockindex"
 	"github.com/copernet/copernicus/model/pow"
+	"github.com/copernet/copernicus/model/tx"
 
 	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/model"
@@ -37,6 +40,59 @@ type FlushStateMode int
 
 var gps = persist.InitPruneState()
 
+const (
+	// MinDiskSpaceForBlockFiles is the minimum automatic prune target, in MiB:
+	// the last MinBlocksToKeep blocks plus their undo data, plus the
+	// preallocated chunks of the block and undo files.
+	MinDiskSpaceForBlockFiles = 550
+
+	// PruneManualOnly is the prune target value enabling pruneblockchain
+	// without any automatic pruning.
+	PruneManualOnly = 1
+
+	prunedBlockFilesFlag = "prunedblockfiles"
+)
+
+// PruneChain is the view of the block chain the pruning code needs. It is
+// implemented by the chain package, which imports this one and registers
+// itself with SetPruneChain.
+type PruneChain interface {
+	Tip() *blockindex.BlockIndex
+	ForEachBlockIndex(fn func(*blockindex.BlockIndex))
+}
+
+var pruneChain PruneChain
+
+// SetPruneChain registers the block chain whose files are pruned.
+func SetPruneChain(c PruneChain) {
+	pruneChain = c
+}
+
+// InitPruneState sets up pruning from the configured target in MiB, 0
+// disabling it and PruneManualOnly allowing only manual pruning. Whether
+// block files were already pruned is read back from the block tree db.
+func InitPruneState(pruneTargetMiB uint64) error {
+	gps = persist.InitPruneState()
+	if pruneTargetMiB == 0 {
+		return nil
+	}
+	if pruneTargetMiB != PruneManualOnly && pruneTargetMiB < MinDiskSpaceForBlockFiles {
+		return fmt.Errorf("prune configured below the minimum of %d MiB, please use a higher number",
+			MinDiskSpaceForBlockFiles)
+	}
+
+	gps.PruneMode = true
+	if pruneTargetMiB == PruneManualOnly {
+		gps.PruneTarget = math.MaxUint64
+		log.Info("Block pruning enabled, use RPC call pruneblockchain(height) to manually prune block and undo files.")
+	} else {
+		gps.PruneTarget = pruneTargetMiB * 1024 * 1024
+		log.Info("Prune configured to target %dMiB on disk for block and undo files.", pruneTargetMiB)
+	}
+	gps.HavePruned = blkdb.GetInstance().ReadFlag(prunedBlockFilesFlag)
+	return nil
+}
+
 const (
 	FlushStateNone FlushStateMode = iota
 	FlushStateIfNeeded
@@ -275,6 +331,39 @@ func ReadBlockFromDisk(pindex *blockindex.BlockIndex, param *model.BitcoinParams
 	return blk, true
 }
 
+// ReadTxFromDisk reads the transaction stored at pos, whose offset is relative
+// to the end of the header of the block containing it, and returns it along
+// with that block header.
+func ReadTxFromDisk(pos *block.DiskTxPos) (*tx.Tx, *block.BlockHeader, error) {
+	file := OpenBlockFile(pos.BlockIn, true)
+	if file == nil {
+		log.Error("ReadTxFromDisk: OpenBlockFile failed for %s", pos.BlockIn.String())
+		return nil, nil, errors.New("ErrOpenBlockFile")
+	}
+	defer file.Close()
+
+	// skip the leading block data length
+	if _, err := util.BinarySerializer.Uint32(file, binary.LittleEndian); err != nil {
+		log.Error("ReadTxFromDisk: read block file len failed for %s", pos.BlockIn.String())
+		return nil, nil, err
+	}
+	header := block.NewBlockHeader()
+	if err := header.Unserialize(file); err != nil {
+		log.Error("ReadTxFromDisk: deserialize block header failed for %s: %v", pos.BlockIn.String(), err)
+		return nil, nil, err
+	}
+	if _, err := file.Seek(int64(pos.TxOffsetIn), io.SeekCurrent); err != nil {
+		log.Error("ReadTxFromDisk: seek to tx offset %d failed for %s", pos.TxOffsetIn, pos.BlockIn.String())
+		return nil, nil, err
+	}
+	txn := tx.NewEmptyTx()
+	if err := txn.Unserialize(bufio.NewReader(file)); err != nil {
+		log.Error("ReadTxFromDisk: deserialize tx failed for %s: %v", pos.BlockIn.String(), err)
+		return nil, nil, err
+	}
+	return txn, header, nil
+}
+
 func WriteBlockToDisk(block *block.Block, pos *block.DiskBlockPos) bool {
 	// Open history file to append
 	file := OpenBlockFile(pos, false)
@@ -305,7 +394,7 @@ func WriteBlockToDisk(block *block.Block, pos *block.DiskBlockPos) bool {
 
 func FlushStateToDisk(mode FlushStateMode, nManualPruneHeight int, mempoolUsage int64, mempoolSizeMax int64) error {
 	var (
-		//params          = model.ActiveNetParams
+		params          = model.ActiveNetParams
 		setFilesToPrune = set.New()
 	)
 
@@ -324,21 +413,25 @@ func FlushStateToDisk(mode FlushStateMode, nManualPruneHeight int, mempoolUsage
 	dataBaseFlushInterval := 24 * 60 * 60
 	minBlockCoinsDBUsage := 50 * dbPeakUsageFactor
 
-	//if gps.PruneMode && (gps.CheckForPruning || nManualPruneHeight > 0) && !persist.Reindex {
-	//	FindFilesToPruneManual(setFilesToPrune, nManualPruneHeight)
-	//} else {
-	//	FindFilesToPrune(setFilesToPrune, uint64(params.PruneAfterHeight))
-	//	gps.CheckForPruning = false
-	//}
-	if !setFilesToPrune.IsEmpty() {
-		flushForPrune = true
-		if !gps.HavePruned {
-			err := blockTree.WriteFlag("prunedblockfiles", true)
-			if err != nil {
-				log.Error("write flag prunedblockfiles error: %v", err)
+	if gps.PruneMode && (gps.CheckForPruning || nManualPruneHeight > 0) && !conf.Cfg.Reindex {
+		if nManualPruneHeight > 0 {
+			if err := FindFilesToPruneManual(setFilesToPrune, int32(nManualPruneHeight)); err != nil {
 				return err
 			}
-			gps.HavePruned = true
+		} else {
+			FindFilesToPrune(setFilesToPrune, int32(params.PruneAfterHeight))
+			gps.CheckForPruning = false
+		}
+		if !setFilesToPrune.IsEmpty() {
+			flushForPrune = true
+			if !gps.HavePruned {
+				err := blockTree.WriteFlag(prunedBlockFilesFlag, true)
+				if err != nil {
+					log.Error("write flag prunedblockfiles error: %v", err)
+					return err
+				}
+				gps.HavePruned = true
+			}
 		}
 	}
 
@@ -536,6 +629,9 @@ func FindBlockPos(pos *block.DiskBlockPos, nAddSize uint32,
 		nNewChunks := (nNewSize + persist.BlockFileChunkSize - 1) / persist.BlockFileChunkSize
 		if nNewChunks > nOldChunks {
 			allocateSize := nNewChunks*persist.BlockFileChunkSize - pos.Pos
+			if gps.PruneMode {
+				gps.CheckForPruning = true
+			}
 			if CheckDiskSpace(allocateSize) {
 				file := OpenBlockFile(pos, false)
 				if file != nil {
@@ -571,7 +667,9 @@ func FindUndoPos(nFile int32, undoPos *block.DiskBlockPos, nAddSize int) error {
 	nNewChunks := (nNewSize + persist.UndoFileChunkSize - 1) / persist.UndoFileChunkSize
 
 	if nNewChunks > nOldChunks {
-
+		if gps.PruneMode {
+			gps.CheckForPruning = true
+		}
 		if CheckDiskSpace(nNewChunks*persist.UndoFileChunkSize - undoPos.Pos) {
 			file := OpenUndoFile(*undoPos, false)
 			if file != nil {
@@ -606,114 +704,124 @@ func CalculateCurrentUsage() uint64 {
 }
 
 // FindFilesToPrune calculate the block/rev files that should be deleted to remain under target
-//func FindFilesToPrune(setFilesToPrune *set.Set, nPruneAfterHeight uint64) {
-//	gPersist := persist.GetInstance()
-//	gChainActive := chain.GetInstance()
-//	if gChainActive.Tip() == nil || gps.PruneTarget == 0 {
-//		return
-//	}
-//	if uint64(gChainActive.Tip().Height) <= nPruneAfterHeight {
-//		return
-//	}
-//	nLastBlockWeCanPrune := gChainActive.Tip().Height - block.MinBlocksToKeep
-//	nCurrentUsage := CalculateCurrentUsage()
-//	// We don't check to prune until after we've allocated new space for files,
-//	// so we should leave a buffer under our target to account for another
-//	// allocation before the next pruning.
-//	nBuffer := uint64(persist.BlockFileChunkSize + persist.UndoFileChunkSize)
-//	count := 0
-//	if nCurrentUsage+nBuffer >= gps.PruneTarget {
-//		for fileNumber := 0; int32(fileNumber) < gPersist.GlobalLastBlockFile; fileNumber++ {
-//			nBytesToPrune := uint64(gPersist.GlobalBlockFileInfo[fileNumber].Size + gPersist.GlobalBlockFileInfo[fileNumber].UndoSize)
-//			if gPersist.GlobalBlockFileInfo[fileNumber].Size == 0 {
-//				continue
-//			}
-//			// are we below our target?
-//			if nCurrentUsage+nBuffer < gps.PruneTarget {
-//				break
-//			}
-//			// don't prune files that could have a block within
-//			// MIN_BLOCKS_TO_KEEP of the main chain's tip but keep scanning
-//			if gPersist.GlobalBlockFileInfo[fileNumber].HeightLast > nLastBlockWeCanPrune {
-//				continue
-//			}
-//
-//			PruneOneBlockFile(int32(fileNumber))
-//			// Queue up the files for removal
-//			setFilesToPrune.Add(fileNumber)
-//			nCurrentUsage -= nBytesToPrune
-//			count++
-//		}
-//	}
-//
-//	log.Info("prune", "Prune: target=%dMiB actual=%dMiB diff=%dMiB max_prune_height=%d removed %d blk/rev pairs\n",
-//		gps.PruneTarget/1024/1024, nCurrentUsage/1024/1024, (gps.PruneTarget-nCurrentUsage)/1024/1024, nLastBlockWeCanPrune, count)
-//}
-//
-//func FindFilesToPruneManual(setFilesToPrune *set.Set, manualPruneHeight int) {
-//	gPersist := persist.GetInstance()
-//	gChainActive := chain.GetInstance()
-//	if gps.PruneMode && manualPruneHeight <= 0 {
-//		panic("the PruneMode is false and manualPruneHeight equal zero")
-//	}
-//
-//	persist.CsLastBlockFile.Lock()
-//	defer persist.CsLastBlockFile.Unlock()
-//
-//	if gChainActive.Tip() == nil {
-//		return
-//	}
-//
-//	// last block to prune is the lesser of (user-specified height, MIN_BLOCKS_TO_KEEP from the tip)
-//	lastBlockWeCanPrune := math.Min(float64(manualPruneHeight), float64(gChainActive.Tip().Height-block.MinBlocksToKeep))
-//	count := 0
-//	for fileNumber := 0; int32(fileNumber) < gPersist.GlobalLastBlockFile; fileNumber++ {
-//		if gPersist.GlobalBlockFileInfo[fileNumber].Size == 0 || gPersist.GlobalBlockFileInfo[fileNumber].HeightLast > gPersist.GlobalLastBlockFile {
-//			continue
-//		}
-//		PruneOneBlockFile(int32(fileNumber))
-//		setFilesToPrune.Add(fileNumber)
-//		count++
-//	}
-//	log.Info("Prune (Manual): prune_height=%d removed %d blk/rev pairs\n", lastBlockWeCanPrune, count)
-//}
+func FindFilesToPrune(setFilesToPrune *set.Set, nPruneAfterHeight int32) {
+	gPersist := persist.GetInstance()
+	if pruneChain == nil || pruneChain.Tip() == nil || gps.PruneTarget == 0 {
+		return
+	}
+	tipHeight := pruneChain.Tip().Height
+	if tipHeight <= nPruneAfterHeight {
+		return
+	}
+	nLastBlockWeCanPrune := tipHeight - block.MinBlocksToKeep
+	nCurrentUsage := CalculateCurrentUsage()
+	// We don't check to prune until after we've allocated new space for files,
+	// so we should leave a buffer under our target to account for another
+	// allocation before the next pruning.
+	nBuffer := uint64(persist.BlockFileChunkSize + persist.UndoFileChunkSize)
+	count := 0
+	if nCurrentUsage+nBuffer >= gps.PruneTarget {
+		for fileNumber := int32(0); fileNumber < gPersist.GlobalLastBlockFile; fileNumber++ {
+			fileInfo := gPersist.GlobalBlockFileInfo[fileNumber]
+			nBytesToPrune := uint64(fileInfo.Size) + uint64(fileInfo.UndoSize)
+			if fileInfo.Size == 0 {
+				continue
+			}
+			// are we below our target?
+			if nCurrentUsage+nBuffer < gps.PruneTarget {
+				break
+			}
+			// don't prune files that could have a block within
+			// MinBlocksToKeep of the main chain's tip but keep scanning
+			if fileInfo.HeightLast > nLastBlockWeCanPrune {
+				continue
+			}
+
+			PruneOneBlockFile(fileNumber)
+			// Queue up the files for removal
+			setFilesToPrune.Add(fileNumber)
+			nCurrentUsage -= nBytesToPrune
+			count++
+		}
+	}
+
+	log.Info("Prune: target=%dMiB actual=%dMiB max_prune_height=%d removed %d blk/rev pairs",
+		gps.PruneTarget/1024/1024, nCurrentUsage/1024/1024, nLastBlockWeCanPrune, count)
+}
+
+// FindFilesToPruneManual calculate the block/rev files holding only blocks up
+// to manualPruneHeight, never pruning within MinBlocksToKeep of the tip
+func FindFilesToPruneManual(setFilesToPrune *set.Set, manualPruneHeight int32) error {
+	gPersist := persist.GetInstance()
+	if manualPruneHeight <= 0 {
+		return fmt.Errorf("manual prune height %d must be larger than zero", manualPruneHeight)
+	}
+	if pruneChain == nil || pruneChain.Tip() == nil {
+		return nil
+	}
+
+	// last block to prune is the lesser of (user-specified height, MinBlocksToKeep from the tip)
+	lastBlockWeCanPrune := pruneChain.Tip().Height - block.MinBlocksToKeep
+	if manualPruneHeight < lastBlockWeCanPrune {
+		lastBlockWeCanPrune = manualPruneHeight
+	}
+	count := 0
+	for fileNumber := int32(0); fileNumber < gPersist.GlobalLastBlockFile; fileNumber++ {
+		fileInfo := gPersist.GlobalBlockFileInfo[fileNumber]
+		if fileInfo.Size == 0 || fileInfo.HeightLast > lastBlockWeCanPrune {
+			continue
+		}
+		PruneOneBlockFile(fileNumber)
+		setFilesToPrune.Add(fileNumber)
+		count++
+	}
+	log.Info("Prune (Manual): prune_height=%d removed %d blk/rev pairs", lastBlockWeCanPrune, count)
+	return nil
+}
+
+// PruneBlockFilesManual prunes the block files holding only blocks up to
+// manualPruneHeight, and flushes the updated block index to disk
+func PruneBlockFilesManual(manualPruneHeight int32, mempoolUsage int64, mempoolSizeMax int64) error {
+	if manualPruneHeight <= 0 {
+		return fmt.Errorf("manual prune height %d must be larger than zero", manualPruneHeight)
+	}
+	return FlushStateToDisk(FlushStateNone, int(manualPruneHeight), mempoolUsage, mempoolSizeMax)
+}
 
 // PruneOneBlockFile prune a block file (modify associated database entries)
 func PruneOneBlockFile(fileNumber int32) {
-	bm := make(map[util.Hash]*blockindex.BlockIndex)
 	gPersist := persist.GetInstance()
-	for _, value := range bm {
-		pindex := value
-		if pindex.File == fileNumber {
-			pindex.Status &= ^blockindex.BlockHaveData
-			pindex.Status &= ^blockindex.BlockHaveUndo
-			pindex.File = 0
-			pindex.DataPos = 0
-			pindex.UndoPos = 0
-			gPersist.AddDirtyBlockIndex(pindex)
-
-			// Prune from mapBlocksUnlinked -- any block we prune would have
-			// to be downloaded again in order to consider its chain, at which
-			// point it would be considered as a candidate for
-			// mapBlocksUnlinked or setBlockIndexCandidates.
-			ranges := gPersist.GlobalMapBlocksUnlinked[pindex.Prev]
-			tmpRange := make([]*blockindex.BlockIndex, len(ranges))
-			copy(tmpRange, ranges)
-			for len(tmpRange) > 0 {
-				v := tmpRange[0]
-				tmpRange = tmpRange[1:]
-				if v == pindex {
-					tmp := make([]*blockindex.BlockIndex, len(ranges)-1)
-					for _, val := range tmpRange {
-						if val != v {
-							tmp = append(tmp, val)
-						}
-					}
-					gPersist.GlobalMapBlocksUnlinked[pindex.Prev] = tmp
-				}
+	pruneChain.ForEachBlockIndex(func(pindex *blockindex.BlockIndex) {
+		if !pindex.HasData() || pindex.File != fileNumber {
+			return
+		}
+		pindex.Status &= ^blockindex.BlockHaveData
+		pindex.Status &= ^blockindex.BlockHaveUndo
+		pindex.File = 0
+		pindex.DataPos = 0
+		pindex.UndoPos = 0
+		gPersist.AddDirtyBlockIndex(pindex)
+
+		// Prune from mapBlocksUnlinked -- any block we prune would have
+		// to be downloaded again in order to consider its chain, at which
+		// point it would be considered as a candidate for
+		// mapBlocksUnlinked or setBlockIndexCandidates.
+		unlinked, ok := gPersist.GlobalMapBlocksUnlinked[pindex.Prev]
+		if !ok {
+			return
+		}
+		remain := make([]*blockindex.BlockIndex, 0, len(unlinked))
+		for _, v := range unlinked {
+			if v != pindex {
+				remain = append(remain, v)
 			}
 		}
-	}
+		if len(remain) == 0 {
+			delete(gPersist.GlobalMapBlocksUnlinked, pindex.Prev)
+		} else {
+			gPersist.GlobalMapBlocksUnlinked[pindex.Prev] = remain
+		}
+	})
 
 	gPersist.GlobalBlockFileInfo[fileNumber].SetNull()
 	gPersist.GlobalDirtyFileInfo[fileNumber] = true
@@ -721,7 +829,7 @@ func PruneOneBlockFile(fileNumber int32) {
 
 func UnlinkPrunedFiles(setFilesToPrune *set.Set) {
 	lists := setFilesToPrune.List()
-	for key, value := range lists {
+	for _, value := range lists {
 		v := value.(int32)
 		pos := &block.DiskBlockPos{
 			File: v,
@@ -729,8 +837,21 @@ func UnlinkPrunedFiles(setFilesToPrune *set.Set) {
 		}
 		os.Remove(GetBlockPosFilename(*pos, "blk"))
 		os.Remove(GetBlockPosFilename(*pos, "rev"))
-		log.Info("Prune: %s deleted blk/rev (%05u)\n", key)
+		log.Info("Prune: deleted blk/rev (%05d)", v)
+	}
+}
+
+// GetPruneHeight returns the height of the lowest block of the active chain
+// whose data is still on disk, zero if nothing has been pruned
+func GetPruneHeight() int32 {
+	if !gps.HavePruned || pruneChain == nil || pruneChain.Tip() == nil {
+		return 0
+	}
+	pindex := pruneChain.Tip()
+	for pindex.Prev != nil && pindex.Prev.HasData() {
+		pindex = pindex.Prev
 	}
+	return pindex.Height
 }
 
 func GetPruneState() *persist.PruneState {
@@ -21,6 +21,7 @@ import (
 	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/model/undo"
 	"github.com/copernet/copernicus/model/utxo"
@@ -29,6 +30,8 @@ import (
 	"github.com/copernet/copernicus/persist/blkdb"
 	"github.com/copernet/copernicus/persist/db"
 	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+	"gopkg.in/fatih/set.v0"
 )
 
 func initTestEnv(t *testing.T) (dirpath string, err error) {
@@ -170,6 +173,39 @@ func TestWRBlockToDisk(t *testing.T) {
 	}
 }
 
+func TestReadTxFromDisk(t *testing.T) {
+	testDirPath, err := initTestEnv(t)
+	if err != nil {
+		t.Fatalf("init test environment failed: %s", err)
+	}
+	defer os.RemoveAll(testDirPath)
+
+	blk := block.NewBlock()
+	blk.Header.Time = uint32(1534822771)
+	blk.Header.Bits = 486604799
+	for i := 0; i < 3; i++ {
+		txn := tx.NewTx(uint32(i), tx.DefaultVersion)
+		txn.AddTxOut(txout.NewTxOut(amount.Amount(i), script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+		blk.Txs = append(blk.Txs, txn)
+	}
+	pos := block.NewDiskBlockPos(12, 20)
+	if !WriteBlockToDisk(blk, pos) {
+		t.Fatal("write block to disk failed, please check.")
+	}
+
+	offset := util.VarIntSerializeSize(uint64(len(blk.Txs)))
+	for _, want := range blk.Txs {
+		got, header, err := ReadTxFromDisk(block.NewDiskTxPos(pos, offset))
+		assert.Nil(t, err)
+		assert.Equal(t, want.GetHash(), got.GetHash())
+		assert.Equal(t, blk.GetHash(), header.GetHash())
+		offset += want.SerializeSize()
+	}
+
+	_, _, err = ReadTxFromDisk(block.NewDiskTxPos(pos, offset))
+	assert.NotNil(t, err)
+}
+
 func TestUndoWRToDisk(t *testing.T) {
 	testDirPath, err := initTestEnv(t)
 	if err != nil {
@@ -525,3 +561,153 @@ func TestCleanupBlockRevFiles(t *testing.T) {
 	}
 
 }
+
+type testPruneChain struct {
+	indexes []*blockindex.BlockIndex
+}
+
+func (c *testPruneChain) Tip() *blockindex.BlockIndex {
+	if len(c.indexes) == 0 {
+		return nil
+	}
+	return c.indexes[len(c.indexes)-1]
+}
+
+func (c *testPruneChain) ForEachBlockIndex(fn func(*blockindex.BlockIndex)) {
+	for _, index := range c.indexes {
+		fn(index)
+	}
+}
+
+// initPruneTestChain builds a chain of 600 blocks spread over three block
+// files: heights 0-99 in file 0, 100-199 in file 1 and the rest in file 2.
+func initPruneTestChain(t *testing.T) *testPruneChain {
+	gPersist := persist.GetInstance()
+	c := &testPruneChain{}
+	var prev *blockindex.BlockIndex
+	for height := int32(0); height < 600; height++ {
+		header := block.NewBlockHeader()
+		header.Nonce = uint32(height)
+		index := blockindex.NewBlockIndex(header)
+		index.Height = height
+		index.Prev = prev
+		switch {
+		case height < 100:
+			index.File = 0
+		case height < 200:
+			index.File = 1
+		default:
+			index.File = 2
+		}
+		index.AddStatus(blockindex.BlockHaveData | blockindex.BlockHaveUndo)
+		c.indexes = append(c.indexes, index)
+		prev = index
+	}
+
+	gPersist.GlobalBlockFileInfo = []*block.BlockFileInfo{
+		{Blocks: 100, Size: 300 << 20, UndoSize: 10 << 20, HeightFirst: 0, HeightLast: 99},
+		{Blocks: 100, Size: 300 << 20, UndoSize: 10 << 20, HeightFirst: 100, HeightLast: 199},
+		{Blocks: 400, Size: 100 << 20, UndoSize: 10 << 20, HeightFirst: 200, HeightLast: 599},
+	}
+	gPersist.GlobalLastBlockFile = 2
+
+	for file := int32(0); file < 3; file++ {
+		pos := block.DiskBlockPos{File: file}
+		for _, prefix := range []string{"blk", "rev"} {
+			f, err := os.Create(GetBlockPosFilename(pos, prefix))
+			assert.Nil(t, err)
+			f.Close()
+		}
+	}
+
+	SetPruneChain(c)
+	return c
+}
+
+func TestInitPruneState(t *testing.T) {
+	testDir, err := initTestEnv(t)
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+	defer InitPruneState(0)
+
+	assert.Nil(t, InitPruneState(0))
+	assert.False(t, GetPruneState().PruneMode)
+
+	assert.Nil(t, InitPruneState(PruneManualOnly))
+	assert.True(t, GetPruneState().PruneMode)
+	assert.Equal(t, uint64(math.MaxUint64), GetPruneState().PruneTarget)
+
+	assert.NotNil(t, InitPruneState(MinDiskSpaceForBlockFiles-1))
+
+	assert.Nil(t, InitPruneState(MinDiskSpaceForBlockFiles))
+	assert.True(t, GetPruneState().PruneMode)
+	assert.Equal(t, uint64(MinDiskSpaceForBlockFiles<<20), GetPruneState().PruneTarget)
+	assert.False(t, GetPruneState().HavePruned)
+}
+
+func TestFindFilesToPrune(t *testing.T) {
+	testDir, err := initTestEnv(t)
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+	defer SetPruneChain(nil)
+	defer InitPruneState(0)
+
+	c := initPruneTestChain(t)
+	assert.Nil(t, InitPruneState(MinDiskSpaceForBlockFiles))
+
+	// nothing is pruned until the chain is past the prune after height
+	setFilesToPrune := set.New()
+	FindFilesToPrune(setFilesToPrune, 600)
+	assert.True(t, setFilesToPrune.IsEmpty())
+
+	// 730MiB are used, dropping file 0 is enough to get under the target
+	FindFilesToPrune(setFilesToPrune, 100)
+	assert.Equal(t, []interface{}{int32(0)}, setFilesToPrune.List())
+	for _, index := range c.indexes {
+		if index.Height < 100 {
+			assert.False(t, index.HasData())
+			assert.False(t, index.HasUndo())
+			assert.Equal(t, uint32(0), index.DataPos)
+		} else {
+			assert.True(t, index.HasData())
+		}
+	}
+	assert.Equal(t, uint32(0), persist.GetInstance().GlobalBlockFileInfo[0].Size)
+	assert.True(t, persist.GetInstance().GlobalDirtyFileInfo[0])
+}
+
+func TestPruneBlockFilesManual(t *testing.T) {
+	testDir, err := initTestEnv(t)
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+	defer SetPruneChain(nil)
+	defer InitPruneState(0)
+
+	c := initPruneTestChain(t)
+	assert.Nil(t, InitPruneState(PruneManualOnly))
+	assert.Equal(t, int32(0), GetPruneHeight())
+
+	// a height from the pruneblockchain rpc is never trusted
+	mempoolSizeMax := int64(persist.DefaultMaxMemPoolSize) * 1000000
+	assert.NotNil(t, PruneBlockFilesManual(0, 0, mempoolSizeMax))
+	assert.NotNil(t, FindFilesToPruneManual(set.New(), -1))
+	assert.False(t, GetPruneState().HavePruned)
+
+	// file 1 still holds blocks above the requested height
+	assert.Nil(t, PruneBlockFilesManual(150, 0, mempoolSizeMax))
+
+	assert.True(t, GetPruneState().HavePruned)
+	assert.True(t, blkdb.GetInstance().ReadFlag("prunedblockfiles"))
+	assert.Equal(t, int32(100), GetPruneHeight())
+	assert.False(t, c.indexes[99].HasData())
+	assert.True(t, c.indexes[100].HasData())
+
+	assert.False(t, conf.FileExists(GetBlockPosFilename(block.DiskBlockPos{File: 0}, "blk")))
+	assert.False(t, conf.FileExists(GetBlockPosFilename(block.DiskBlockPos{File: 0}, "rev")))
+	assert.True(t, conf.FileExists(GetBlockPosFilename(block.DiskBlockPos{File: 1}, "blk")))
+
+	// the last MinBlocksToKeep blocks are never pruned
+	assert.Nil(t, PruneBlockFilesManual(599, 0, mempoolSizeMax))
+	assert.Equal(t, int32(200), GetPruneHeight())
+	assert.True(t, conf.FileExists(GetBlockPosFilename(block.DiskBlockPos{File: 2}, "blk")))
+}
@@ -706,6 +706,15 @@ func NewUptimeCmd() *UptimeCmd {
 	return &UptimeCmd{}
 }
 
+// GetScriptCacheInfoCmd defines the getscriptcacheinfo JSON-RPC command.
+type GetScriptCacheInfoCmd struct{}
+
+// NewGetScriptCacheInfoCmd returns a new instance which can be used to issue a
+// getscriptcacheinfo JSON-RPC command.
+func NewGetScriptCacheInfoCmd() *GetScriptCacheInfoCmd {
+	return &GetScriptCacheInfoCmd{}
+}
+
 // SignMessageWithPrivkeyCmd defines the signmessagewithprivkey JSON-RPC command.
 type SignMessageWithPrivkeyCmd struct {
 	Privkey string
@@ -795,6 +804,24 @@ func NewPruneBlockChainCmd(height int) *PruneBlockChainCmd {
 	}
 }
 
+// SaveMempoolCmd defines the savemempool JSON-RPC command.
+type SaveMempoolCmd struct{}
+
+// NewSaveMempoolCmd returns a new instance which can be used to issue a
+// savemempool JSON-RPC command.
+func NewSaveMempoolCmd() *SaveMempoolCmd {
+	return &SaveMempoolCmd{}
+}
+
+// GetZMQNotificationsCmd defines the getzmqnotifications JSON-RPC command.
+type GetZMQNotificationsCmd struct{}
+
+// NewGetZMQNotificationsCmd returns a new instance which can be used to issue
+// a getzmqnotifications JSON-RPC command.
+func NewGetZMQNotificationsCmd() *GetZMQNotificationsCmd {
+	return &GetZMQNotificationsCmd{}
+}
+
 type GetMempoolAncestorsCmd struct {
 	TxID    string `json:"txid"`
 	Verbose *bool  `json:"verbose" jsonrpcdefault:"false"`
@@ -825,6 +852,50 @@ type SignRawTransactionCmd struct {
 	SigHashType *string       `json:"sighashtype"`
 }
 
+// CombinePsbtCmd defines the combinepsbt JSON-RPC command.
+type CombinePsbtCmd struct {
+	Txs []string `json:"txs"`
+}
+
+// NewCombinePsbtCmd returns a new instance which can be used to issue a
+// combinepsbt JSON-RPC command.
+func NewCombinePsbtCmd(txs []string) *CombinePsbtCmd {
+	return &CombinePsbtCmd{
+		Txs: txs,
+	}
+}
+
+// FinalizePsbtCmd defines the finalizepsbt JSON-RPC command.
+type FinalizePsbtCmd struct {
+	Psbt    string `json:"psbt"`
+	Extract *bool  `json:"extract" jsonrpcdefault:"true"`
+}
+
+// NewFinalizePsbtCmd returns a new instance which can be used to issue a
+// finalizepsbt JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewFinalizePsbtCmd(psbt string, extract *bool) *FinalizePsbtCmd {
+	return &FinalizePsbtCmd{
+		Psbt:    psbt,
+		Extract: extract,
+	}
+}
+
+// DecodePsbtCmd defines the decodepsbt JSON-RPC command.
+type DecodePsbtCmd struct {
+	Psbt string `json:"psbt"`
+}
+
+// NewDecodePsbtCmd returns a new instance which can be used to issue a
+// decodepsbt JSON-RPC command.
+func NewDecodePsbtCmd(psbt string) *DecodePsbtCmd {
+	return &DecodePsbtCmd{
+		Psbt: psbt,
+	}
+}
+
 type SetMocktimeCmd struct {
 	Timestamp int64
 }
@@ -1006,6 +1077,7 @@ type FundRawTxoptions struct {
 	ReserveChangeKey       bool   `json:"reservechangekey" jsonrpcdefault:"true"`
 	FeeRate                int    `json:"feerate"`
 	SubtractFeeFromOutputs *[]int `json:"subtractfeefromoutputs"`
+	MinConf                int32  `json:"minconf"`
 }
 type FundRawTransactionCmd struct {
 	HexTx   string            `json:"hexstring"`
@@ -1030,6 +1102,493 @@ func NewAddMultiSigAddressCmd(num int, s []string) *AddMultiSigAddressCmd {
 	}
 }
 
+// EncryptWalletCmd defines the encryptwallet JSON-RPC command.
+type EncryptWalletCmd struct {
+	Passphrase string `json:"passphrase"`
+}
+
+// NewEncryptWalletCmd returns a new instance which can be used to issue a
+// encryptwallet JSON-RPC command.
+func NewEncryptWalletCmd(passphrase string) *EncryptWalletCmd {
+	return &EncryptWalletCmd{
+		Passphrase: passphrase,
+	}
+}
+
+// WalletPassphraseCmd defines the walletpassphrase JSON-RPC command.
+type WalletPassphraseCmd struct {
+	Passphrase string `json:"passphrase"`
+	Timeout    int64  `json:"timeout"`
+}
+
+// NewWalletPassphraseCmd returns a new instance which can be used to issue a
+// walletpassphrase JSON-RPC command.
+func NewWalletPassphraseCmd(passphrase string, timeout int64) *WalletPassphraseCmd {
+	return &WalletPassphraseCmd{
+		Passphrase: passphrase,
+		Timeout:    timeout,
+	}
+}
+
+// WalletLockCmd defines the walletlock JSON-RPC command.
+type WalletLockCmd struct{}
+
+// NewWalletLockCmd returns a new instance which can be used to issue a
+// walletlock JSON-RPC command.
+func NewWalletLockCmd() *WalletLockCmd {
+	return &WalletLockCmd{}
+}
+
+// WalletPassphraseChangeCmd defines the walletpassphrasechange JSON-RPC
+// command.
+type WalletPassphraseChangeCmd struct {
+	OldPassphrase string `json:"oldpassphrase"`
+	NewPassphrase string `json:"newpassphrase"`
+}
+
+// NewWalletPassphraseChangeCmd returns a new instance which can be used to
+// issue a walletpassphrasechange JSON-RPC command.
+func NewWalletPassphraseChangeCmd(oldPassphrase, newPassphrase string) *WalletPassphraseChangeCmd {
+	return &WalletPassphraseChangeCmd{
+		OldPassphrase: oldPassphrase,
+		NewPassphrase: newPassphrase,
+	}
+}
+
+// GetRawChangeAddressCmd defines the getrawchangeaddress JSON-RPC command.
+type GetRawChangeAddressCmd struct{}
+
+// NewGetRawChangeAddressCmd returns a new instance which can be used to issue
+// a getrawchangeaddress JSON-RPC command.
+func NewGetRawChangeAddressCmd() *GetRawChangeAddressCmd {
+	return &GetRawChangeAddressCmd{}
+}
+
+// KeyPoolRefillCmd defines the keypoolrefill JSON-RPC command.
+type KeyPoolRefillCmd struct {
+	NewSize *int `json:"newsize"`
+}
+
+// NewKeyPoolRefillCmd returns a new instance which can be used to issue a
+// keypoolrefill JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewKeyPoolRefillCmd(newSize *int) *KeyPoolRefillCmd {
+	return &KeyPoolRefillCmd{
+		NewSize: newSize,
+	}
+}
+
+// DumpMasterKeyCmd defines the dumpmasterkey JSON-RPC command.
+type DumpMasterKeyCmd struct{}
+
+// NewDumpMasterKeyCmd returns a new instance which can be used to issue a
+// dumpmasterkey JSON-RPC command.
+func NewDumpMasterKeyCmd() *DumpMasterKeyCmd {
+	return &DumpMasterKeyCmd{}
+}
+
+// SetHDSeedCmd defines the sethdseed JSON-RPC command.
+type SetHDSeedCmd struct {
+	NewKeyPool *bool   `json:"newkeypool" jsonrpcdefault:"true"`
+	Seed       *string `json:"seed"`
+}
+
+// NewSetHDSeedCmd returns a new instance which can be used to issue a
+// sethdseed JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSetHDSeedCmd(newKeyPool *bool, seed *string) *SetHDSeedCmd {
+	return &SetHDSeedCmd{
+		NewKeyPool: newKeyPool,
+		Seed:       seed,
+	}
+}
+
+// ImportPrivKeyCmd defines the importprivkey JSON-RPC command.
+type ImportPrivKeyCmd struct {
+	PrivKey string  `json:"privkey"`
+	Label   *string `json:"label" jsonrpcdefault:"\"\""`
+	Rescan  *bool   `json:"rescan" jsonrpcdefault:"true"`
+}
+
+// NewImportPrivKeyCmd returns a new instance which can be used to issue a
+// importprivkey JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewImportPrivKeyCmd(privKey string, label *string, rescan *bool) *ImportPrivKeyCmd {
+	return &ImportPrivKeyCmd{
+		PrivKey: privKey,
+		Label:   label,
+		Rescan:  rescan,
+	}
+}
+
+// DumpPrivKeyCmd defines the dumpprivkey JSON-RPC command.
+type DumpPrivKeyCmd struct {
+	Address string `json:"address"`
+}
+
+// NewDumpPrivKeyCmd returns a new instance which can be used to issue a
+// dumpprivkey JSON-RPC command.
+func NewDumpPrivKeyCmd(address string) *DumpPrivKeyCmd {
+	return &DumpPrivKeyCmd{
+		Address: address,
+	}
+}
+
+// ImportAddressCmd defines the importaddress JSON-RPC command.
+type ImportAddressCmd struct {
+	Address string  `json:"address"`
+	Label   *string `json:"label" jsonrpcdefault:"\"\""`
+	Rescan  *bool   `json:"rescan" jsonrpcdefault:"true"`
+	P2SH    *bool   `json:"p2sh" jsonrpcdefault:"false"`
+}
+
+// NewImportAddressCmd returns a new instance which can be used to issue a
+// importaddress JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewImportAddressCmd(address string, label *string, rescan *bool, p2sh *bool) *ImportAddressCmd {
+	return &ImportAddressCmd{
+		Address: address,
+		Label:   label,
+		Rescan:  rescan,
+		P2SH:    p2sh,
+	}
+}
+
+// ImportPubKeyCmd defines the importpubkey JSON-RPC command.
+type ImportPubKeyCmd struct {
+	PubKey string  `json:"pubkey"`
+	Label  *string `json:"label" jsonrpcdefault:"\"\""`
+	Rescan *bool   `json:"rescan" jsonrpcdefault:"true"`
+}
+
+// NewImportPubKeyCmd returns a new instance which can be used to issue a
+// importpubkey JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewImportPubKeyCmd(pubKey string, label *string, rescan *bool) *ImportPubKeyCmd {
+	return &ImportPubKeyCmd{
+		PubKey: pubKey,
+		Label:  label,
+		Rescan: rescan,
+	}
+}
+
+// DumpWalletCmd defines the dumpwallet JSON-RPC command.
+type DumpWalletCmd struct {
+	Filename string `json:"filename"`
+}
+
+// NewDumpWalletCmd returns a new instance which can be used to issue a
+// dumpwallet JSON-RPC command.
+func NewDumpWalletCmd(filename string) *DumpWalletCmd {
+	return &DumpWalletCmd{
+		Filename: filename,
+	}
+}
+
+// ImportWalletCmd defines the importwallet JSON-RPC command.
+type ImportWalletCmd struct {
+	Filename string `json:"filename"`
+	Rescan   *bool  `json:"rescan" jsonrpcdefault:"true"`
+}
+
+// NewImportWalletCmd returns a new instance which can be used to issue a
+// importwallet JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewImportWalletCmd(filename string, rescan *bool) *ImportWalletCmd {
+	return &ImportWalletCmd{
+		Filename: filename,
+		Rescan:   rescan,
+	}
+}
+
+// RescanBlockChainCmd defines the rescanblockchain JSON-RPC command.
+type RescanBlockChainCmd struct {
+	StartHeight *int32 `json:"start_height" jsonrpcdefault:"0"`
+	StopHeight  *int32 `json:"stop_height"`
+}
+
+// NewRescanBlockChainCmd returns a new instance which can be used to issue a
+// rescanblockchain JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewRescanBlockChainCmd(startHeight *int32, stopHeight *int32) *RescanBlockChainCmd {
+	return &RescanBlockChainCmd{
+		StartHeight: startHeight,
+		StopHeight:  stopHeight,
+	}
+}
+
+// ListTransactionsCmd defines the listtransactions JSON-RPC command.
+type ListTransactionsCmd struct {
+	Account          *string `json:"account" jsonrpcdefault:"\"*\""`
+	Count            *int    `json:"count" jsonrpcdefault:"10"`
+	From             *int    `json:"skip" jsonrpcdefault:"0"`
+	IncludeWatchOnly *bool   `json:"include_watchonly" jsonrpcdefault:"false"`
+}
+
+// NewListTransactionsCmd returns a new instance which can be used to issue a
+// listtransactions JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewListTransactionsCmd(account *string, count, from *int, includeWatchOnly *bool) *ListTransactionsCmd {
+	return &ListTransactionsCmd{
+		Account:          account,
+		Count:            count,
+		From:             from,
+		IncludeWatchOnly: includeWatchOnly,
+	}
+}
+
+// ListSinceBlockCmd defines the listsinceblock JSON-RPC command.
+type ListSinceBlockCmd struct {
+	BlockHash           *string `json:"blockhash"`
+	TargetConfirmations *int    `json:"target_confirmations" jsonrpcdefault:"1"`
+	IncludeWatchOnly    *bool   `json:"include_watchonly" jsonrpcdefault:"false"`
+	IncludeRemoved      *bool   `json:"include_removed" jsonrpcdefault:"true"`
+}
+
+// NewListSinceBlockCmd returns a new instance which can be used to issue a
+// listsinceblock JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewListSinceBlockCmd(blockHash *string, targetConfirms *int, includeWatchOnly, includeRemoved *bool) *ListSinceBlockCmd {
+	return &ListSinceBlockCmd{
+		BlockHash:           blockHash,
+		TargetConfirmations: targetConfirms,
+		IncludeWatchOnly:    includeWatchOnly,
+		IncludeRemoved:      includeRemoved,
+	}
+}
+
+// ListReceivedByAddressCmd defines the listreceivedbyaddress JSON-RPC command.
+type ListReceivedByAddressCmd struct {
+	MinConf          *int  `json:"minconf" jsonrpcdefault:"1"`
+	IncludeEmpty     *bool `json:"include_empty" jsonrpcdefault:"false"`
+	IncludeWatchOnly *bool `json:"include_watchonly" jsonrpcdefault:"false"`
+}
+
+// NewListReceivedByAddressCmd returns a new instance which can be used to
+// issue a listreceivedbyaddress JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewListReceivedByAddressCmd(minConf *int, includeEmpty, includeWatchOnly *bool) *ListReceivedByAddressCmd {
+	return &ListReceivedByAddressCmd{
+		MinConf:          minConf,
+		IncludeEmpty:     includeEmpty,
+		IncludeWatchOnly: includeWatchOnly,
+	}
+}
+
+// GetReceivedByAddressCmd defines the getreceivedbyaddress JSON-RPC command.
+type GetReceivedByAddressCmd struct {
+	Address string `json:"address"`
+	MinConf *int   `json:"minconf" jsonrpcdefault:"1"`
+}
+
+// NewGetReceivedByAddressCmd returns a new instance which can be used to
+// issue a getreceivedbyaddress JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetReceivedByAddressCmd(address string, minConf *int) *GetReceivedByAddressCmd {
+	return &GetReceivedByAddressCmd{
+		Address: address,
+		MinConf: minConf,
+	}
+}
+
+// LockUnspentCmd defines the lockunspent JSON-RPC command.
+type LockUnspentCmd struct {
+	Unlock       bool                `json:"unlock"`
+	Transactions *[]TransactionInput `json:"transactions"`
+	Persistent   *bool               `json:"persistent" jsonrpcdefault:"false"`
+}
+
+// NewLockUnspentCmd returns a new instance which can be used to issue a
+// lockunspent JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewLockUnspentCmd(unlock bool, transactions *[]TransactionInput, persistent *bool) *LockUnspentCmd {
+	return &LockUnspentCmd{
+		Unlock:       unlock,
+		Transactions: transactions,
+		Persistent:   persistent,
+	}
+}
+
+// ListLockUnspentCmd defines the listlockunspent JSON-RPC command.
+type ListLockUnspentCmd struct{}
+
+// NewListLockUnspentCmd returns a new instance which can be used to issue a
+// listlockunspent JSON-RPC command.
+func NewListLockUnspentCmd() *ListLockUnspentCmd {
+	return &ListLockUnspentCmd{}
+}
+
+// AbandonTransactionCmd defines the abandontransaction JSON-RPC command.
+type AbandonTransactionCmd struct {
+	Txid string `json:"txid"`
+}
+
+// NewAbandonTransactionCmd returns a new instance which can be used to issue
+// an abandontransaction JSON-RPC command.
+func NewAbandonTransactionCmd(txid string) *AbandonTransactionCmd {
+	return &AbandonTransactionCmd{
+		Txid: txid,
+	}
+}
+
+// ResendWalletTransactionsCmd defines the resendwallettransactions JSON-RPC
+// command.
+type ResendWalletTransactionsCmd struct{}
+
+// NewResendWalletTransactionsCmd returns a new instance which can be used to
+// issue a resendwallettransactions JSON-RPC command.
+func NewResendWalletTransactionsCmd() *ResendWalletTransactionsCmd {
+	return &ResendWalletTransactionsCmd{}
+}
+
+// CreateWalletCmd defines the createwallet JSON-RPC command.
+type CreateWalletCmd struct {
+	WalletName string `json:"wallet_name"`
+}
+
+// NewCreateWalletCmd returns a new instance which can be used to issue a
+// createwallet JSON-RPC command.
+func NewCreateWalletCmd(walletName string) *CreateWalletCmd {
+	return &CreateWalletCmd{
+		WalletName: walletName,
+	}
+}
+
+// LoadWalletCmd defines the loadwallet JSON-RPC command.
+type LoadWalletCmd struct {
+	Filename string `json:"filename"`
+}
+
+// NewLoadWalletCmd returns a new instance which can be used to issue a
+// loadwallet JSON-RPC command.
+func NewLoadWalletCmd(filename string) *LoadWalletCmd {
+	return &LoadWalletCmd{
+		Filename: filename,
+	}
+}
+
+// UnloadWalletCmd defines the unloadwallet JSON-RPC command.
+type UnloadWalletCmd struct {
+	WalletName *string `json:"wallet_name"`
+}
+
+// NewUnloadWalletCmd returns a new instance which can be used to issue an
+// unloadwallet JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewUnloadWalletCmd(walletName *string) *UnloadWalletCmd {
+	return &UnloadWalletCmd{
+		WalletName: walletName,
+	}
+}
+
+// ListWalletsCmd defines the listwallets JSON-RPC command.
+type ListWalletsCmd struct{}
+
+// NewListWalletsCmd returns a new instance which can be used to issue a
+// listwallets JSON-RPC command.
+func NewListWalletsCmd() *ListWalletsCmd {
+	return &ListWalletsCmd{}
+}
+
+// WalletCreateFundedPsbtCmd defines the walletcreatefundedpsbt JSON-RPC
+// command.
+type WalletCreateFundedPsbtCmd struct {
+	Inputs   []TransactionInput
+	Outputs  map[string]AmountType
+	LockTime *int64 `jsonrpcdefault:"0"`
+	Options  *FundRawTxoptions
+}
+
+// NewWalletCreateFundedPsbtCmd returns a new instance which can be used to
+// issue a walletcreatefundedpsbt JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewWalletCreateFundedPsbtCmd(inputs []TransactionInput, outputs map[string]AmountType, lockTime *int64,
+	options *FundRawTxoptions) *WalletCreateFundedPsbtCmd {
+
+	return &WalletCreateFundedPsbtCmd{
+		Inputs:   inputs,
+		Outputs:  outputs,
+		LockTime: lockTime,
+		Options:  options,
+	}
+}
+
+// WalletProcessPsbtCmd defines the walletprocesspsbt JSON-RPC command.
+type WalletProcessPsbtCmd struct {
+	Psbt        string  `json:"psbt"`
+	Sign        *bool   `json:"sign" jsonrpcdefault:"true"`
+	SigHashType *string `json:"sighashtype" jsonrpcdefault:"\"ALL|FORKID\""`
+}
+
+// NewWalletProcessPsbtCmd returns a new instance which can be used to issue a
+// walletprocesspsbt JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewWalletProcessPsbtCmd(psbt string, sign *bool, sigHashType *string) *WalletProcessPsbtCmd {
+	return &WalletProcessPsbtCmd{
+		Psbt:        psbt,
+		Sign:        sign,
+		SigHashType: sigHashType,
+	}
+}
+
+// SignMessageCmd defines the signmessage JSON-RPC command.
+type SignMessageCmd struct {
+	Address string
+	Message string
+}
+
+// NewSignMessageCmd returns a new instance which can be used to issue a
+// signmessage JSON-RPC command.
+func NewSignMessageCmd(address, message string) *SignMessageCmd {
+	return &SignMessageCmd{
+		Address: address,
+		Message: message,
+	}
+}
+
+// GetAddressInfoCmd defines the getaddressinfo JSON-RPC command.
+type GetAddressInfoCmd struct {
+	Address string
+}
+
+// NewGetAddressInfoCmd returns a new instance which can be used to issue a
+// getaddressinfo JSON-RPC command.
+func NewGetAddressInfoCmd(address string) *GetAddressInfoCmd {
+	return &GetAddressInfoCmd{
+		Address: address,
+	}
+}
+
 func init() {
 	// No special flags for commands in this file.
 	flags := UsageFlag(0)
@@ -1078,12 +1637,16 @@ func init() {
 	MustRegisterCmd("stop", (*StopCmd)(nil), flags)
 	MustRegisterCmd("submitblock", (*SubmitBlockCmd)(nil), flags)
 	MustRegisterCmd("uptime", (*UptimeCmd)(nil), flags)
+	MustRegisterCmd("getscriptcacheinfo", (*GetScriptCacheInfoCmd)(nil), flags)
 	MustRegisterCmd("validateaddress", (*ValidateAddressCmd)(nil), flags)
 	MustRegisterCmd("verifychain", (*VerifyChainCmd)(nil), flags)
 	MustRegisterCmd("verifymessage", (*VerifyMessageCmd)(nil), flags)
 	MustRegisterCmd("getmempoolancestors", (*GetMempoolAncestorsCmd)(nil), flags)
 	MustRegisterCmd("getmempooldescendants", (*GetMempoolDescendantsCmd)(nil), flags)
 	MustRegisterCmd("signrawtransaction", (*SignRawTransactionCmd)(nil), flags)
+	MustRegisterCmd("combinepsbt", (*CombinePsbtCmd)(nil), flags)
+	MustRegisterCmd("finalizepsbt", (*FinalizePsbtCmd)(nil), flags)
+	MustRegisterCmd("decodepsbt", (*DecodePsbtCmd)(nil), flags)
 	MustRegisterCmd("verifytxoutproof", (*VerifyTxOutProofCmd)(nil), flags)
 	MustRegisterCmd("setmocktime", (*SetMocktimeCmd)(nil), flags)
 
@@ -1096,8 +1659,11 @@ func init() {
 	MustRegisterCmd("setexcessiveblock", (*SetExcessiveBlockCmd)(nil), flags)
 	MustRegisterCmd("getexcessiveblock", (*GetExcessiveBlockCmd)(nil), flags)
 	MustRegisterCmd("pruneblockchain", (*PruneBlockChainCmd)(nil), flags)
+	MustRegisterCmd("savemempool", (*SaveMempoolCmd)(nil), flags)
+	MustRegisterCmd("getzmqnotifications", (*GetZMQNotificationsCmd)(nil), flags)
 	MustRegisterCmd("createmultisig", (*CreateMultiSigCmd)(nil), flags)
 	MustRegisterCmd("estimatefee", (*EstimateFeeCmd)(nil), flags)
+	MustRegisterCmd("estimatesmartfee", (*EstimateSmartFeeCmd)(nil), flags)
 
 	MustRegisterCmd("waitforblockheight", (*WaitForBlockHeightCmd)(nil), flags)
 	MustRegisterCmd("waitforblock", (*WaitForBlockCmd)(nil), flags)
@@ -1112,4 +1678,35 @@ func init() {
 	MustRegisterCmd("sendmany", (*SendManyCmd)(nil), flags)
 	MustRegisterCmd("fundrawtransaction", (*FundRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("addmultisigaddress", (*AddMultiSigAddressCmd)(nil), flags)
+	MustRegisterCmd("encryptwallet", (*EncryptWalletCmd)(nil), flags)
+	MustRegisterCmd("walletpassphrase", (*WalletPassphraseCmd)(nil), flags)
+	MustRegisterCmd("walletlock", (*WalletLockCmd)(nil), flags)
+	MustRegisterCmd("walletpassphrasechange", (*WalletPassphraseChangeCmd)(nil), flags)
+	MustRegisterCmd("getrawchangeaddress", (*GetRawChangeAddressCmd)(nil), flags)
+	MustRegisterCmd("keypoolrefill", (*KeyPoolRefillCmd)(nil), flags)
+	MustRegisterCmd("dumpmasterkey", (*DumpMasterKeyCmd)(nil), flags)
+	MustRegisterCmd("sethdseed", (*SetHDSeedCmd)(nil), flags)
+	MustRegisterCmd("importprivkey", (*ImportPrivKeyCmd)(nil), flags)
+	MustRegisterCmd("dumpprivkey", (*DumpPrivKeyCmd)(nil), flags)
+	MustRegisterCmd("importaddress", (*ImportAddressCmd)(nil), flags)
+	MustRegisterCmd("importpubkey", (*ImportPubKeyCmd)(nil), flags)
+	MustRegisterCmd("dumpwallet", (*DumpWalletCmd)(nil), flags)
+	MustRegisterCmd("importwallet", (*ImportWalletCmd)(nil), flags)
+	MustRegisterCmd("rescanblockchain", (*RescanBlockChainCmd)(nil), flags)
+	MustRegisterCmd("listtransactions", (*ListTransactionsCmd)(nil), flags)
+	MustRegisterCmd("listsinceblock", (*ListSinceBlockCmd)(nil), flags)
+	MustRegisterCmd("listreceivedbyaddress", (*ListReceivedByAddressCmd)(nil), flags)
+	MustRegisterCmd("getreceivedbyaddress", (*GetReceivedByAddressCmd)(nil), flags)
+	MustRegisterCmd("lockunspent", (*LockUnspentCmd)(nil), flags)
+	MustRegisterCmd("listlockunspent", (*ListLockUnspentCmd)(nil), flags)
+	MustRegisterCmd("abandontransaction", (*AbandonTransactionCmd)(nil), flags)
+	MustRegisterCmd("resendwallettransactions", (*ResendWalletTransactionsCmd)(nil), flags)
+	MustRegisterCmd("createwallet", (*CreateWalletCmd)(nil), flags)
+	MustRegisterCmd("loadwallet", (*LoadWalletCmd)(nil), flags)
+	MustRegisterCmd("unloadwallet", (*UnloadWalletCmd)(nil), flags)
+	MustRegisterCmd("listwallets", (*ListWalletsCmd)(nil), flags)
+	MustRegisterCmd("walletcreatefundedpsbt", (*WalletCreateFundedPsbtCmd)(nil), flags)
+	MustRegisterCmd("walletprocesspsbt", (*WalletProcessPsbtCmd)(nil), flags)
+	MustRegisterCmd("signmessage", (*SignMessageCmd)(nil), flags)
+	MustRegisterCmd("getaddressinfo", (*GetAddressInfoCmd)(nil), flags)
 }
@@ -970,6 +970,17 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"uptime","params":[],"id":1}`,
 			unmarshalled: &UptimeCmd{},
 		},
+		{
+			name: "getscriptcacheinfo",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getscriptcacheinfo")
+			},
+			staticCmd: func() interface{} {
+				return NewGetScriptCacheInfoCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getscriptcacheinfo","params":[],"id":1}`,
+			unmarshalled: &GetScriptCacheInfoCmd{},
+		},
 		{
 			name: "validateaddress",
 			newCmd: func() (interface{}, error) {
@@ -1094,6 +1105,28 @@ func TestChainSvrCmds(t *testing.T) {
 				Height: 123,
 			},
 		},
+		{
+			name: "savemempool",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("savemempool")
+			},
+			staticCmd: func() interface{} {
+				return NewSaveMempoolCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"savemempool","params":[],"id":1}`,
+			unmarshalled: &SaveMempoolCmd{},
+		},
+		{
+			name: "getzmqnotifications",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getzmqnotifications")
+			},
+			staticCmd: func() interface{} {
+				return NewGetZMQNotificationsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getzmqnotifications","params":[],"id":1}`,
+			unmarshalled: &GetZMQNotificationsCmd{},
+		},
 		{
 			name: "echo",
 			newCmd: func() (interface{}, error) {
@@ -1226,6 +1259,19 @@ func TestChainSvrCmds(t *testing.T) {
 				NumBlocks: 123,
 			},
 		},
+		{
+			name: "estimatesmartfee",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("estimatesmartfee", 6)
+			},
+			staticCmd: func() interface{} {
+				return NewEstimateSmartFeeCmd(6)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"estimatesmartfee","params":[6],"id":1}`,
+			unmarshalled: &EstimateSmartFeeCmd{
+				NumBlocks: 6,
+			},
+		},
 		{
 			name: "getbestblock",
 			newCmd: func() (interface{}, error) {
@@ -1482,6 +1528,677 @@ func TestChainSvrCmds(t *testing.T) {
 				SubTractFeeFrom: &[]string{"test"},
 			},
 		},
+		{
+			name: "encryptwallet",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("encryptwallet", "pass")
+			},
+			staticCmd: func() interface{} {
+				return NewEncryptWalletCmd("pass")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"encryptwallet","params":["pass"],"id":1}`,
+			unmarshalled: &EncryptWalletCmd{
+				Passphrase: "pass",
+			},
+		},
+		{
+			name: "walletpassphrase",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("walletpassphrase", "pass", 60)
+			},
+			staticCmd: func() interface{} {
+				return NewWalletPassphraseCmd("pass", 60)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"walletpassphrase","params":["pass",60],"id":1}`,
+			unmarshalled: &WalletPassphraseCmd{
+				Passphrase: "pass",
+				Timeout:    60,
+			},
+		},
+		{
+			name: "walletlock",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("walletlock")
+			},
+			staticCmd: func() interface{} {
+				return NewWalletLockCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"walletlock","params":[],"id":1}`,
+			unmarshalled: &WalletLockCmd{},
+		},
+		{
+			name: "walletpassphrasechange",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("walletpassphrasechange", "old", "new")
+			},
+			staticCmd: func() interface{} {
+				return NewWalletPassphraseChangeCmd("old", "new")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"walletpassphrasechange","params":["old","new"],"id":1}`,
+			unmarshalled: &WalletPassphraseChangeCmd{
+				OldPassphrase: "old",
+				NewPassphrase: "new",
+			},
+		},
+		{
+			name: "getrawchangeaddress",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getrawchangeaddress")
+			},
+			staticCmd: func() interface{} {
+				return NewGetRawChangeAddressCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getrawchangeaddress","params":[],"id":1}`,
+			unmarshalled: &GetRawChangeAddressCmd{},
+		},
+		{
+			name: "keypoolrefill",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("keypoolrefill")
+			},
+			staticCmd: func() interface{} {
+				return NewKeyPoolRefillCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"keypoolrefill","params":[],"id":1}`,
+			unmarshalled: &KeyPoolRefillCmd{
+				NewSize: nil,
+			},
+		},
+		{
+			name: "keypoolrefill optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("keypoolrefill", 200)
+			},
+			staticCmd: func() interface{} {
+				return NewKeyPoolRefillCmd(Int(200))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"keypoolrefill","params":[200],"id":1}`,
+			unmarshalled: &KeyPoolRefillCmd{
+				NewSize: Int(200),
+			},
+		},
+		{
+			name: "dumpmasterkey",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("dumpmasterkey")
+			},
+			staticCmd: func() interface{} {
+				return NewDumpMasterKeyCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"dumpmasterkey","params":[],"id":1}`,
+			unmarshalled: &DumpMasterKeyCmd{},
+		},
+		{
+			name: "sethdseed",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("sethdseed")
+			},
+			staticCmd: func() interface{} {
+				return NewSetHDSeedCmd(nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"sethdseed","params":[],"id":1}`,
+			unmarshalled: &SetHDSeedCmd{
+				NewKeyPool: Bool(true),
+			},
+		},
+		{
+			name: "sethdseed optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("sethdseed", false, "seed")
+			},
+			staticCmd: func() interface{} {
+				return NewSetHDSeedCmd(Bool(false), String("seed"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"sethdseed","params":[false,"seed"],"id":1}`,
+			unmarshalled: &SetHDSeedCmd{
+				NewKeyPool: Bool(false),
+				Seed:       String("seed"),
+			},
+		},
+		{
+			name: "importprivkey",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("importprivkey", "abc")
+			},
+			staticCmd: func() interface{} {
+				return NewImportPrivKeyCmd("abc", nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importprivkey","params":["abc"],"id":1}`,
+			unmarshalled: &ImportPrivKeyCmd{
+				PrivKey: "abc",
+				Label:   String(""),
+				Rescan:  Bool(true),
+			},
+		},
+		{
+			name: "importprivkey optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("importprivkey", "abc", "label", false)
+			},
+			staticCmd: func() interface{} {
+				return NewImportPrivKeyCmd("abc", String("label"), Bool(false))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importprivkey","params":["abc","label",false],"id":1}`,
+			unmarshalled: &ImportPrivKeyCmd{
+				PrivKey: "abc",
+				Label:   String("label"),
+				Rescan:  Bool(false),
+			},
+		},
+		{
+			name: "dumpprivkey",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("dumpprivkey", "1Address")
+			},
+			staticCmd: func() interface{} {
+				return NewDumpPrivKeyCmd("1Address")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"dumpprivkey","params":["1Address"],"id":1}`,
+			unmarshalled: &DumpPrivKeyCmd{
+				Address: "1Address",
+			},
+		},
+		{
+			name: "importaddress",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("importaddress", "1Address")
+			},
+			staticCmd: func() interface{} {
+				return NewImportAddressCmd("1Address", nil, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importaddress","params":["1Address"],"id":1}`,
+			unmarshalled: &ImportAddressCmd{
+				Address: "1Address",
+				Label:   String(""),
+				Rescan:  Bool(true),
+				P2SH:    Bool(false),
+			},
+		},
+		{
+			name: "importaddress optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("importaddress", "51", "label", false, true)
+			},
+			staticCmd: func() interface{} {
+				return NewImportAddressCmd("51", String("label"), Bool(false), Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importaddress","params":["51","label",false,true],"id":1}`,
+			unmarshalled: &ImportAddressCmd{
+				Address: "51",
+				Label:   String("label"),
+				Rescan:  Bool(false),
+				P2SH:    Bool(true),
+			},
+		},
+		{
+			name: "importpubkey",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("importpubkey", "031234")
+			},
+			staticCmd: func() interface{} {
+				return NewImportPubKeyCmd("031234", nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importpubkey","params":["031234"],"id":1}`,
+			unmarshalled: &ImportPubKeyCmd{
+				PubKey: "031234",
+				Label:  String(""),
+				Rescan: Bool(true),
+			},
+		},
+		{
+			name: "importpubkey optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("importpubkey", "031234", "label", false)
+			},
+			staticCmd: func() interface{} {
+				return NewImportPubKeyCmd("031234", String("label"), Bool(false))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importpubkey","params":["031234","label",false],"id":1}`,
+			unmarshalled: &ImportPubKeyCmd{
+				PubKey: "031234",
+				Label:  String("label"),
+				Rescan: Bool(false),
+			},
+		},
+		{
+			name: "dumpwallet",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("dumpwallet", "filename")
+			},
+			staticCmd: func() interface{} {
+				return NewDumpWalletCmd("filename")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"dumpwallet","params":["filename"],"id":1}`,
+			unmarshalled: &DumpWalletCmd{
+				Filename: "filename",
+			},
+		},
+		{
+			name: "importwallet",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("importwallet", "filename")
+			},
+			staticCmd: func() interface{} {
+				return NewImportWalletCmd("filename", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importwallet","params":["filename"],"id":1}`,
+			unmarshalled: &ImportWalletCmd{
+				Filename: "filename",
+				Rescan:   Bool(true),
+			},
+		},
+		{
+			name: "rescanblockchain",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("rescanblockchain")
+			},
+			staticCmd: func() interface{} {
+				return NewRescanBlockChainCmd(nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"rescanblockchain","params":[],"id":1}`,
+			unmarshalled: &RescanBlockChainCmd{
+				StartHeight: Int32(0),
+				StopHeight:  nil,
+			},
+		},
+		{
+			name: "rescanblockchain optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("rescanblockchain", 100, 200)
+			},
+			staticCmd: func() interface{} {
+				return NewRescanBlockChainCmd(Int32(100), Int32(200))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"rescanblockchain","params":[100,200],"id":1}`,
+			unmarshalled: &RescanBlockChainCmd{
+				StartHeight: Int32(100),
+				StopHeight:  Int32(200),
+			},
+		},
+		{
+			name: "listtransactions",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("listtransactions")
+			},
+			staticCmd: func() interface{} {
+				return NewListTransactionsCmd(nil, nil, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"listtransactions","params":[],"id":1}`,
+			unmarshalled: &ListTransactionsCmd{
+				Account:          String("*"),
+				Count:            Int(10),
+				From:             Int(0),
+				IncludeWatchOnly: Bool(false),
+			},
+		},
+		{
+			name: "listtransactions optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("listtransactions", "acct", 20, 1, true)
+			},
+			staticCmd: func() interface{} {
+				return NewListTransactionsCmd(String("acct"), Int(20), Int(1), Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"listtransactions","params":["acct",20,1,true],"id":1}`,
+			unmarshalled: &ListTransactionsCmd{
+				Account:          String("acct"),
+				Count:            Int(20),
+				From:             Int(1),
+				IncludeWatchOnly: Bool(true),
+			},
+		},
+		{
+			name: "listsinceblock",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("listsinceblock")
+			},
+			staticCmd: func() interface{} {
+				return NewListSinceBlockCmd(nil, nil, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"listsinceblock","params":[],"id":1}`,
+			unmarshalled: &ListSinceBlockCmd{
+				BlockHash:           nil,
+				TargetConfirmations: Int(1),
+				IncludeWatchOnly:    Bool(false),
+				IncludeRemoved:      Bool(true),
+			},
+		},
+		{
+			name: "listsinceblock optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("listsinceblock", "123", 6, true, false)
+			},
+			staticCmd: func() interface{} {
+				return NewListSinceBlockCmd(String("123"), Int(6), Bool(true), Bool(false))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"listsinceblock","params":["123",6,true,false],"id":1}`,
+			unmarshalled: &ListSinceBlockCmd{
+				BlockHash:           String("123"),
+				TargetConfirmations: Int(6),
+				IncludeWatchOnly:    Bool(true),
+				IncludeRemoved:      Bool(false),
+			},
+		},
+		{
+			name: "listreceivedbyaddress",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("listreceivedbyaddress")
+			},
+			staticCmd: func() interface{} {
+				return NewListReceivedByAddressCmd(nil, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"listreceivedbyaddress","params":[],"id":1}`,
+			unmarshalled: &ListReceivedByAddressCmd{
+				MinConf:          Int(1),
+				IncludeEmpty:     Bool(false),
+				IncludeWatchOnly: Bool(false),
+			},
+		},
+		{
+			name: "listreceivedbyaddress optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("listreceivedbyaddress", 6, true, true)
+			},
+			staticCmd: func() interface{} {
+				return NewListReceivedByAddressCmd(Int(6), Bool(true), Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"listreceivedbyaddress","params":[6,true,true],"id":1}`,
+			unmarshalled: &ListReceivedByAddressCmd{
+				MinConf:          Int(6),
+				IncludeEmpty:     Bool(true),
+				IncludeWatchOnly: Bool(true),
+			},
+		},
+		{
+			name: "getreceivedbyaddress",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getreceivedbyaddress", "1Address")
+			},
+			staticCmd: func() interface{} {
+				return NewGetReceivedByAddressCmd("1Address", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getreceivedbyaddress","params":["1Address"],"id":1}`,
+			unmarshalled: &GetReceivedByAddressCmd{
+				Address: "1Address",
+				MinConf: Int(1),
+			},
+		},
+		{
+			name: "getreceivedbyaddress optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getreceivedbyaddress", "1Address", 6)
+			},
+			staticCmd: func() interface{} {
+				return NewGetReceivedByAddressCmd("1Address", Int(6))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getreceivedbyaddress","params":["1Address",6],"id":1}`,
+			unmarshalled: &GetReceivedByAddressCmd{
+				Address: "1Address",
+				MinConf: Int(6),
+			},
+		},
+		{
+			name: "lockunspent",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("lockunspent", true)
+			},
+			staticCmd: func() interface{} {
+				return NewLockUnspentCmd(true, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"lockunspent","params":[true],"id":1}`,
+			unmarshalled: &LockUnspentCmd{
+				Unlock:     true,
+				Persistent: Bool(false),
+			},
+		},
+		{
+			name: "lockunspent optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("lockunspent", false, `[{"txid":"123","vout":1}]`, true)
+			},
+			staticCmd: func() interface{} {
+				txInputs := []TransactionInput{
+					{Txid: "123", Vout: 1},
+				}
+				return NewLockUnspentCmd(false, &txInputs, Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"lockunspent","params":[false,[{"txid":"123","vout":1,"sequence":null}],true],"id":1}`,
+			unmarshalled: &LockUnspentCmd{
+				Unlock: false,
+				Transactions: &[]TransactionInput{
+					{Txid: "123", Vout: 1},
+				},
+				Persistent: Bool(true),
+			},
+		},
+		{
+			name: "listlockunspent",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("listlockunspent")
+			},
+			staticCmd: func() interface{} {
+				return NewListLockUnspentCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"listlockunspent","params":[],"id":1}`,
+			unmarshalled: &ListLockUnspentCmd{},
+		},
+		{
+			name: "abandontransaction",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("abandontransaction", "123")
+			},
+			staticCmd: func() interface{} {
+				return NewAbandonTransactionCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"abandontransaction","params":["123"],"id":1}`,
+			unmarshalled: &AbandonTransactionCmd{
+				Txid: "123",
+			},
+		},
+		{
+			name: "resendwallettransactions",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("resendwallettransactions")
+			},
+			staticCmd: func() interface{} {
+				return NewResendWalletTransactionsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"resendwallettransactions","params":[],"id":1}`,
+			unmarshalled: &ResendWalletTransactionsCmd{},
+		},
+		{
+			name: "createwallet",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("createwallet", "w1")
+			},
+			staticCmd: func() interface{} {
+				return NewCreateWalletCmd("w1")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"createwallet","params":["w1"],"id":1}`,
+			unmarshalled: &CreateWalletCmd{
+				WalletName: "w1",
+			},
+		},
+		{
+			name: "loadwallet",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("loadwallet", "w1")
+			},
+			staticCmd: func() interface{} {
+				return NewLoadWalletCmd("w1")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"loadwallet","params":["w1"],"id":1}`,
+			unmarshalled: &LoadWalletCmd{
+				Filename: "w1",
+			},
+		},
+		{
+			name: "unloadwallet",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("unloadwallet")
+			},
+			staticCmd: func() interface{} {
+				return NewUnloadWalletCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"unloadwallet","params":[],"id":1}`,
+			unmarshalled: &UnloadWalletCmd{
+				WalletName: nil,
+			},
+		},
+		{
+			name: "unloadwallet optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("unloadwallet", "w1")
+			},
+			staticCmd: func() interface{} {
+				return NewUnloadWalletCmd(String("w1"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"unloadwallet","params":["w1"],"id":1}`,
+			unmarshalled: &UnloadWalletCmd{
+				WalletName: String("w1"),
+			},
+		},
+		{
+			name: "listwallets",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("listwallets")
+			},
+			staticCmd: func() interface{} {
+				return NewListWalletsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"listwallets","params":[],"id":1}`,
+			unmarshalled: &ListWalletsCmd{},
+		},
+		{
+			name: "combinepsbt",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("combinepsbt", []string{"psbt1", "psbt2"})
+			},
+			staticCmd: func() interface{} {
+				return NewCombinePsbtCmd([]string{"psbt1", "psbt2"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"combinepsbt","params":[["psbt1","psbt2"]],"id":1}`,
+			unmarshalled: &CombinePsbtCmd{
+				Txs: []string{"psbt1", "psbt2"},
+			},
+		},
+		{
+			name: "finalizepsbt",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("finalizepsbt", "psbt")
+			},
+			staticCmd: func() interface{} {
+				return NewFinalizePsbtCmd("psbt", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"finalizepsbt","params":["psbt"],"id":1}`,
+			unmarshalled: &FinalizePsbtCmd{
+				Psbt:    "psbt",
+				Extract: Bool(true),
+			},
+		},
+		{
+			name: "finalizepsbt optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("finalizepsbt", "psbt", false)
+			},
+			staticCmd: func() interface{} {
+				return NewFinalizePsbtCmd("psbt", Bool(false))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"finalizepsbt","params":["psbt",false],"id":1}`,
+			unmarshalled: &FinalizePsbtCmd{
+				Psbt:    "psbt",
+				Extract: Bool(false),
+			},
+		},
+		{
+			name: "decodepsbt",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("decodepsbt", "psbt")
+			},
+			staticCmd: func() interface{} {
+				return NewDecodePsbtCmd("psbt")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"decodepsbt","params":["psbt"],"id":1}`,
+			unmarshalled: &DecodePsbtCmd{
+				Psbt: "psbt",
+			},
+		},
+		{
+			name: "walletcreatefundedpsbt",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("walletcreatefundedpsbt", `[{"txid":"123","vout":1}]`, `{"456":0.0123}`)
+			},
+			staticCmd: func() interface{} {
+				txInputs := []TransactionInput{
+					{Txid: "123", Vout: 1},
+				}
+				amounts := map[string]AmountType{"456": .0123}
+				return NewWalletCreateFundedPsbtCmd(txInputs, amounts, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"walletcreatefundedpsbt","params":[[{"txid":"123","vout":1,"sequence":null}],{"456":0.0123}],"id":1}`,
+			unmarshalled: &WalletCreateFundedPsbtCmd{
+				Inputs:   []TransactionInput{{Txid: "123", Vout: 1}},
+				Outputs:  map[string]AmountType{"456": .0123},
+				LockTime: Int64(0),
+			},
+		},
+		{
+			name: "walletprocesspsbt",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("walletprocesspsbt", "psbt")
+			},
+			staticCmd: func() interface{} {
+				return NewWalletProcessPsbtCmd("psbt", nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"walletprocesspsbt","params":["psbt"],"id":1}`,
+			unmarshalled: &WalletProcessPsbtCmd{
+				Psbt:        "psbt",
+				Sign:        Bool(true),
+				SigHashType: String("ALL|FORKID"),
+			},
+		},
+		{
+			name: "walletprocesspsbt optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("walletprocesspsbt", "psbt", false, "ALL|FORKID|ANYONECANPAY")
+			},
+			staticCmd: func() interface{} {
+				return NewWalletProcessPsbtCmd("psbt", Bool(false), String("ALL|FORKID|ANYONECANPAY"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"walletprocesspsbt","params":["psbt",false,"ALL|FORKID|ANYONECANPAY"],"id":1}`,
+			unmarshalled: &WalletProcessPsbtCmd{
+				Psbt:        "psbt",
+				Sign:        Bool(false),
+				SigHashType: String("ALL|FORKID|ANYONECANPAY"),
+			},
+		},
+		{
+			name: "signmessage",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("signmessage", "1Address", "message")
+			},
+			staticCmd: func() interface{} {
+				return NewSignMessageCmd("1Address", "message")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"signmessage","params":["1Address","message"],"id":1}`,
+			unmarshalled: &SignMessageCmd{
+				Address: "1Address",
+				Message: "message",
+			},
+		},
+		{
+			name: "getaddressinfo",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getaddressinfo", "1Address")
+			},
+			staticCmd: func() interface{} {
+				return NewGetAddressInfoCmd("1Address")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddressinfo","params":["1Address"],"id":1}`,
+			unmarshalled: &GetAddressInfoCmd{
+				Address: "1Address",
+			},
+		},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -59,6 +59,8 @@ const (
 	ErrRPCWalletWrongEncState       RPCErrorCode = -15
 	ErrRPCWalletEncryptionFailed    RPCErrorCode = -16
 	ErrRPCWalletAlreadyUnlocked     RPCErrorCode = -17
+	ErrRPCWalletNotFound            RPCErrorCode = -18
+	ErrRPCWalletNotSpecified        RPCErrorCode = -19
 )
 
 // Specific Errors related to commands.  These are the ones a user of the RPC
@@ -50,6 +50,13 @@ type GetBlockVerboseResult struct {
 	NextHash      string   `json:"nextblockhash,omitempty"`
 }
 
+// GetBlockVerboseTxResult models the data from the REST block endpoint, which
+// decodes the transactions of the block instead of listing their ids.
+type GetBlockVerboseTxResult struct {
+	GetBlockVerboseResult
+	Tx []TxRawResult `json:"tx"`
+}
+
 // GetChainTxStatsResult models the data from the getchaintxstats command.
 type GetChainTxStatsResult struct {
 	FinalTime      uint32  `json:"time"`
@@ -110,6 +117,13 @@ type Bip9SoftForkDescription struct {
 	Since     int32  `json:"since"`
 }
 
+// UpgradeDescription describes the activation of a network upgrade.
+type UpgradeDescription struct {
+	Type       string `json:"type"`
+	Activation int64  `json:"activation"`
+	Active     bool   `json:"active"`
+}
+
 // GetBlockChainInfoResult models the data returned from the getblockchaininfo
 // command.
 type GetBlockChainInfoResult struct {
@@ -125,18 +139,20 @@ type GetBlockChainInfoResult struct {
 	ChainWork            string                              `json:"chainwork,omitempty"`
 	SoftForks            []*SoftForkDescription              `json:"softforks"`
 	Bip9SoftForks        map[string]*Bip9SoftForkDescription `json:"bip9_softforks"`
+	Upgrades             map[string]*UpgradeDescription      `json:"upgrades"`
 }
 
 // GetBlockTemplateResultTx models the transactions field of the
 // getblocktemplate command.
 type GetBlockTemplateResultTx struct {
-	Data    string `json:"data"`
-	TxID    string `json:"txid"`
-	Hash    string `json:"hash"`
-	Depends []int  `json:"depends"`
-	Fee     int64  `json:"fee"`
-	SigOps  int64  `json:"sigops"`
-	Weight  int64  `json:"weight"`
+	Data      string `json:"data"`
+	TxID      string `json:"txid"`
+	Hash      string `json:"hash"`
+	Depends   []int  `json:"depends"`
+	Fee       int64  `json:"fee"`
+	SigOps    int64  `json:"sigops"`
+	SigChecks int64  `json:"sigchecks"`
+	Weight    int64  `json:"weight"`
 }
 
 // GetBlockTemplateResultAux models the coinbaseaux field of the
@@ -155,6 +171,7 @@ type GetBlockTemplateResult struct {
 	Height        int64                      `json:"height"`
 	PreviousHash  string                     `json:"previousblockhash"`
 	SigOpLimit    int64                      `json:"sigoplimit,omitempty"`
+	SigCheckLimit int64                      `json:"sigchecklimit,omitempty"`
 	SizeLimit     int64                      `json:"sizelimit,omitempty"`
 	WeightLimit   int64                      `json:"weightlimit,omitempty"`
 	Transactions  []GetBlockTemplateResultTx `json:"transactions"`
@@ -216,6 +233,48 @@ type GetMempoolInfoResult struct {
 	Usage         int64   `json:"usage"`
 	MaxMempool    int     `json:"maxmempool"`
 	MempoolMinFee float64 `json:"mempoolminfee"`
+	Loaded        bool    `json:"loaded"`
+}
+
+// CacheInfoResult models the size and hit/miss counters of a verification
+// cache.
+type CacheInfoResult struct {
+	Entries    uint   `json:"entries"`
+	MaxEntries uint   `json:"maxentries"`
+	Hits       uint64 `json:"hits"`
+	Misses     uint64 `json:"misses"`
+}
+
+// GetScriptCacheInfoResult models the data returned from the
+// getscriptcacheinfo command.
+type GetScriptCacheInfoResult struct {
+	SigCache    CacheInfoResult `json:"sigcache"`
+	ScriptCache CacheInfoResult `json:"scriptcache"`
+}
+
+// UtxoResult models an unspent output returned by the REST getutxos endpoint.
+type UtxoResult struct {
+	Height       int32              `json:"height"`
+	Value        float64            `json:"value"`
+	ScriptPubKey ScriptPubKeyResult `json:"scriptPubKey"`
+}
+
+// GetUtxosResult models the data returned from the REST getutxos endpoint.
+// Bitmap holds a 1 for each requested outpoint which is unspent and a 0 for
+// the others, in the order they were requested.
+type GetUtxosResult struct {
+	ChainHeight  int32        `json:"chainHeight"`
+	ChaintipHash string       `json:"chaintipHash"`
+	Bitmap       string       `json:"bitmap"`
+	Utxos        []UtxoResult `json:"utxos"`
+}
+
+// GetZMQNotificationsResult models an active notification returned by the
+// getzmqnotifications command.
+type GetZMQNotificationsResult struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	HWM     int    `json:"hwm"`
 }
 
 // NetworksResult models the networks data from the getnetworkinfo command.
@@ -255,33 +314,40 @@ type GetNetworkInfoResult struct {
 
 // GetPeerInfoResult models the data returned from the getpeerinfo command.
 type GetPeerInfoResult struct {
-	ID              int32             `json:"id"`
-	Addr            string            `json:"addr"`
-	AddrLocal       string            `json:"addrlocal,omitempty"`
-	Services        string            `json:"services"`
-	RelayTxes       bool              `json:"relaytxes"`
-	LastSend        int64             `json:"lastsend"`
-	LastRecv        int64             `json:"lastrecv"`
-	BytesSent       uint64            `json:"bytessent"`
-	BytesRecv       uint64            `json:"bytesrecv"`
-	ConnTime        int64             `json:"conntime"`
-	TimeOffset      int64             `json:"timeoffset"`
-	PingTime        float64           `json:"pingtime,omitempty"`
-	MinPing         float64           `json:"minping,omitempty"`
-	PingWait        float64           `json:"pingwait,omitempty"`
-	Version         uint32            `json:"version"`
-	SubVer          string            `json:"subver"`
-	Inbound         bool              `json:"inbound"`
-	AddNode         bool              `json:"addnode"`
-	StartingHeight  int32             `json:"startingheight"`
-	BanScore        int32             `json:"banscore,omitempty"`
-	SyncedHeaders   int               `json:"synced_headers,omitempty"`
-	SyncedBlocks    int               `json:"synced_blocks,omitempty"`
-	Inflight        []int             `json:"inflight,omitempty"`
-	WhiteListed     bool              `json:"whitelisted"`
-	CashMagic       bool              `json:"cashmagic"`
-	BytesSendPerMsg map[string]uint64 `json:"bytessent_per_msg"`
-	BytesRecvPerMsg map[string]uint64 `json:"bytesrecv_per_msg"`
+	ID                       int32             `json:"id"`
+	Addr                     string            `json:"addr"`
+	AddrLocal                string            `json:"addrlocal,omitempty"`
+	Services                 string            `json:"services"`
+	RelayTxes                bool              `json:"relaytxes"`
+	LastSend                 int64             `json:"lastsend"`
+	LastRecv                 int64             `json:"lastrecv"`
+	BytesSent                uint64            `json:"bytessent"`
+	BytesRecv                uint64            `json:"bytesrecv"`
+	ConnTime                 int64             `json:"conntime"`
+	TimeOffset               int64             `json:"timeoffset"`
+	PingTime                 float64           `json:"pingtime,omitempty"`
+	MinPing                  float64           `json:"minping,omitempty"`
+	PingWait                 float64           `json:"pingwait,omitempty"`
+	Version                  uint32            `json:"version"`
+	SubVer                   string            `json:"subver"`
+	Inbound                  bool              `json:"inbound"`
+	AddNode                  bool              `json:"addnode"`
+	StartingHeight           int32             `json:"startingheight"`
+	BanScore                 int32             `json:"banscore"`
+	SyncedHeaders            int               `json:"synced_headers,omitempty"`
+	SyncedBlocks             int               `json:"synced_blocks,omitempty"`
+	Inflight                 []int             `json:"inflight,omitempty"`
+	WhiteListed              bool              `json:"whitelisted"`
+	CashMagic                bool              `json:"cashmagic"`
+	CmpctVersion             uint64            `json:"cmpct_version"`
+	CmpctHighBandwidthTo     bool              `json:"bip152_hb_to"`
+	CmpctHighBandwidthFrom   bool              `json:"bip152_hb_from"`
+	CmpctBlocksReceived      uint64            `json:"cmpctblocks_received"`
+	CmpctBlocksReconstructed uint64            `json:"cmpctblocks_reconstructed"`
+	CmpctBlockTxnRequests    uint64            `json:"cmpctblocks_txn_requested"`
+	CmpctBlocksFailed        uint64            `json:"cmpctblocks_failed"`
+	BytesSendPerMsg          map[string]uint64 `json:"bytessent_per_msg"`
+	BytesRecvPerMsg          map[string]uint64 `json:"bytesrecv_per_msg"`
 }
 
 // GetRawMempoolVerboseResult models the data returned from the getrawmempool
@@ -315,9 +381,23 @@ type GetTxOutResult struct {
 	Confirmations int32              `json:"confirmations"`
 	Value         string             `json:"value"`
 	ScriptPubKey  ScriptPubKeyResult `json:"scriptPubKey"`
+	TokenData     *TokenDataResult   `json:"tokenData,omitempty"`
 	Coinbase      bool               `json:"coinbase"`
 }
 
+// TokenDataResult models the token data of an output.
+type TokenDataResult struct {
+	Category string          `json:"category"`
+	Amount   string          `json:"amount"`
+	NFT      *TokenNFTResult `json:"nft,omitempty"`
+}
+
+// TokenNFTResult models the non-fungible token of an output.
+type TokenNFTResult struct {
+	Capability string `json:"capability"`
+	Commitment string `json:"commitment"`
+}
+
 // GetTxOutSetInfoResult models the data from the gettxoutsetinfo command.
 type GetTxOutSetInfoResult struct {
 	Height         int     `json:"height"`
@@ -454,6 +534,7 @@ type Vout struct {
 	Value        float64            `json:"value"`
 	N            uint32             `json:"n"`
 	ScriptPubKey ScriptPubKeyResult `json:"scriptPubKey"`
+	TokenData    *TokenDataResult   `json:"tokenData,omitempty"`
 }
 
 // GetMiningInfoResult models the data from the getmininginfo command.
@@ -469,6 +550,12 @@ type GetMiningInfoResult struct {
 	Chain                   string  `json:"chain"`
 }
 
+// EstimateSmartFeeResult models the data from the estimatesmartfee command.
+type EstimateSmartFeeResult struct {
+	FeeRate float64 `json:"feerate"`
+	Blocks  int     `json:"blocks"`
+}
+
 // GetWorkResult models the data from the getwork command.
 type GetWorkResult struct {
 	Data     string `json:"data"`
@@ -588,6 +675,47 @@ type SignRawTransactionResult struct {
 	Errors   []*SignRawTransactionError `json:"errors,omitempty"`
 }
 
+// FinalizePsbtResult models the data from the finalizepsbt command.  The
+// partially signed transaction is returned unless the signed transaction is
+// extracted.
+type FinalizePsbtResult struct {
+	Psbt     string `json:"psbt,omitempty"`
+	Hex      string `json:"hex,omitempty"`
+	Complete bool   `json:"complete"`
+}
+
+// DecodePsbtResult models the data from the decodepsbt command.
+type DecodePsbtResult struct {
+	Tx      TxRawDecodeResult  `json:"tx"`
+	Unknown map[string]string  `json:"unknown"`
+	Inputs  []DecodePsbtInput  `json:"inputs"`
+	Outputs []DecodePsbtOutput `json:"outputs"`
+	Fee     *float64           `json:"fee,omitempty"`
+}
+
+// PsbtUTXO models the previous output of an input in the decodepsbt
+// command.
+type PsbtUTXO struct {
+	Amount       float64            `json:"amount"`
+	ScriptPubKey ScriptPubKeyResult `json:"scriptPubKey"`
+}
+
+// DecodePsbtInput models the data of an input in the decodepsbt command.
+type DecodePsbtInput struct {
+	UTXO              *PsbtUTXO           `json:"utxo,omitempty"`
+	PartialSignatures map[string]string   `json:"partial_signatures,omitempty"`
+	SigHash           string              `json:"sighash,omitempty"`
+	RedeemScript      *ScriptPubKeyResult `json:"redeem_script,omitempty"`
+	FinalScriptSig    *ScriptSig          `json:"final_scriptSig,omitempty"`
+	Unknown           map[string]string   `json:"unknown,omitempty"`
+}
+
+// DecodePsbtOutput models the data of an output in the decodepsbt command.
+type DecodePsbtOutput struct {
+	RedeemScript *ScriptPubKeyResult `json:"redeem_script,omitempty"`
+	Unknown      map[string]string   `json:"unknown,omitempty"`
+}
+
 type GetChainTipsResult []ChainTipsInfo
 
 type ChainTipsInfo struct {
@@ -657,6 +785,7 @@ type GetTransactionDetailsResult struct {
 	Address           string   `json:"address,omitempty"`
 	Amount            float64  `json:"amount"`
 	Category          string   `json:"category"`
+	Label             string   `json:"label,omitempty"`
 	InvolvesWatchOnly bool     `json:"involveswatchonly,omitempty"`
 	Fee               *float64 `json:"fee,omitempty"`
 	Vout              uint32   `json:"vout"`
@@ -680,3 +809,116 @@ type FundRawTransactionResult struct {
 	Changepos int     `json:"changepos"`
 	Fee       float64 `json:"fee"`
 }
+
+// DumpWalletResult models the data returned by the dumpwallet command.
+type DumpWalletResult struct {
+	Filename string `json:"filename"`
+}
+
+// ListTransactionsResult models the data returned by the listtransactions
+// and listsinceblock commands.
+type ListTransactionsResult struct {
+	InvolvesWatchOnly bool     `json:"involvesWatchonly,omitempty"`
+	Account           string   `json:"account"`
+	Address           string   `json:"address,omitempty"`
+	Category          string   `json:"category"`
+	Amount            float64  `json:"amount"`
+	Label             string   `json:"label,omitempty"`
+	Vout              uint32   `json:"vout"`
+	Fee               *float64 `json:"fee,omitempty"`
+	Abandoned         *bool    `json:"abandoned,omitempty"`
+	Confirmations     int32    `json:"confirmations"`
+	Generated         bool     `json:"generated,omitempty"`
+	BlockHash         string   `json:"blockhash,omitempty"`
+	BlockTime         int64    `json:"blocktime,omitempty"`
+	TxID              string   `json:"txid"`
+	WalletConflicts   []string `json:"walletconflicts"`
+	Time              int64    `json:"time"`
+	TimeReceived      int64    `json:"timereceived"`
+	Comment           string   `json:"comment,omitempty"`
+	To                string   `json:"to,omitempty"`
+}
+
+// ListSinceBlockResult models the data returned by the listsinceblock
+// command.
+type ListSinceBlockResult struct {
+	Transactions []ListTransactionsResult `json:"transactions"`
+	Removed      []ListTransactionsResult `json:"removed,omitempty"`
+	LastBlock    string                   `json:"lastblock"`
+}
+
+// ListReceivedByAddressResult models the data returned by the
+// listreceivedbyaddress command.
+type ListReceivedByAddressResult struct {
+	InvolvesWatchOnly bool     `json:"involvesWatchonly,omitempty"`
+	Address           string   `json:"address"`
+	Account           string   `json:"account"`
+	Amount            float64  `json:"amount"`
+	Confirmations     int32    `json:"confirmations"`
+	Label             string   `json:"label"`
+	TxIDs             []string `json:"txids"`
+}
+
+// ListLockUnspentResult models the data of an output returned by the
+// listlockunspent command.
+type ListLockUnspentResult struct {
+	TxID string `json:"txid"`
+	Vout uint32 `json:"vout"`
+}
+
+// LoadWalletResult models the data returned by the createwallet and
+// loadwallet commands.
+type LoadWalletResult struct {
+	Name string `json:"name"`
+}
+
+// WalletCreateFundedPsbtResult models the data returned by the
+// walletcreatefundedpsbt command.
+type WalletCreateFundedPsbtResult struct {
+	Psbt      string  `json:"psbt"`
+	Fee       float64 `json:"fee"`
+	ChangePos int     `json:"changepos"`
+}
+
+// WalletProcessPsbtResult models the data returned by the walletprocesspsbt
+// command.
+type WalletProcessPsbtResult struct {
+	Psbt     string `json:"psbt"`
+	Complete bool   `json:"complete"`
+}
+
+// GetAddressInfoResult models the data returned by the getaddressinfo
+// command.
+type GetAddressInfoResult struct {
+	Address       string   `json:"address"`
+	ScriptPubKey  string   `json:"scriptPubKey"`
+	IsMine        bool     `json:"ismine"`
+	IsWatchOnly   bool     `json:"iswatchonly"`
+	IsScript      bool     `json:"isscript"`
+	IsChange      bool     `json:"ischange"`
+	Script        string   `json:"script,omitempty"`
+	Hex           string   `json:"hex,omitempty"`
+	PubKeys       []string `json:"pubkeys,omitempty"`
+	SigsRequired  int32    `json:"sigsrequired,omitempty"`
+	PubKey        string   `json:"pubkey,omitempty"`
+	IsCompressed  *bool    `json:"iscompressed,omitempty"`
+	Label         string   `json:"label"`
+	TimeStamp     int64    `json:"timestamp,omitempty"`
+	HDKeyPath     string   `json:"hdkeypath,omitempty"`
+	HDMasterKeyID string   `json:"hdmasterkeyid,omitempty"`
+}
+
+// RescanBlockChainResult models the data returned by the rescanblockchain
+// command.
+type RescanBlockChainResult struct {
+	StartHeight int32 `json:"start_height"`
+	StopHeight  int32 `json:"stop_height"`
+}
+
+// DumpMasterKeyResult models the data returned by the dumpmasterkey command.
+type DumpMasterKeyResult struct {
+	HDSeedID    string `json:"hdseedid"`
+	MasterKey   string `json:"hdmasterkey"`
+	AccountPath string `json:"accountpath"`
+	AccountXPub string `json:"accountxpub"`
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2014-2017 The btcsuite developers
+// Copyright (c) 2015-2017 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// TestWsNtfns tests all of the websocket notifications marshal and unmarshal
+// into valid results include handling of optional fields being omitted in the
+// marshalled command, while optional fields with defaults have the default
+// assigned on unmarshalled commands.
+func TestWsNtfns(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		newNtfn      func() (interface{}, error)
+		staticNtfn   func() interface{}
+		marshalled   string
+		unmarshalled interface{}
+	}{
+		{
+			name: "blockconnected",
+			newNtfn: func() (interface{}, error) {
+				return NewCmd("blockconnected", "123", 100000, 123456789)
+			},
+			staticNtfn: func() interface{} {
+				return NewBlockConnectedNtfn("123", 100000, 123456789)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"blockconnected","params":["123",100000,123456789],"id":null}`,
+			unmarshalled: &BlockConnectedNtfn{
+				Hash:   "123",
+				Height: 100000,
+				Time:   123456789,
+			},
+		},
+		{
+			name: "blockdisconnected",
+			newNtfn: func() (interface{}, error) {
+				return NewCmd("blockdisconnected", "123", 100000, 123456789)
+			},
+			staticNtfn: func() interface{} {
+				return NewBlockDisconnectedNtfn("123", 100000, 123456789)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"blockdisconnected","params":["123",100000,123456789],"id":null}`,
+			unmarshalled: &BlockDisconnectedNtfn{
+				Hash:   "123",
+				Height: 100000,
+				Time:   123456789,
+			},
+		},
+		{
+			name: "filteredblockconnected",
+			newNtfn: func() (interface{}, error) {
+				return NewCmd("filteredblockconnected", 100000, "header", []string{"tx0", "tx1"})
+			},
+			staticNtfn: func() interface{} {
+				return NewFilteredBlockConnectedNtfn(100000, "header", []string{"tx0", "tx1"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"filteredblockconnected","params":[100000,"header",["tx0","tx1"]],"id":null}`,
+			unmarshalled: &FilteredBlockConnectedNtfn{
+				Height:        100000,
+				Header:        "header",
+				SubscribedTxs: []string{"tx0", "tx1"},
+			},
+		},
+		{
+			name: "recvtx",
+			newNtfn: func() (interface{}, error) {
+				return NewCmd("recvtx", "001122", `{"height":100000,"hash":"123","index":0,"time":12345678}`)
+			},
+			staticNtfn: func() interface{} {
+				blockDetails := BlockDetails{
+					Height: 100000,
+					Hash:   "123",
+					Index:  0,
+					Time:   12345678,
+				}
+				return NewRecvTxNtfn("001122", &blockDetails)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"recvtx","params":["001122",{"height":100000,"hash":"123","index":0,"time":12345678}],"id":null}`,
+			unmarshalled: &RecvTxNtfn{
+				HexTx: "001122",
+				Block: &BlockDetails{
+					Height: 100000,
+					Hash:   "123",
+					Index:  0,
+					Time:   12345678,
+				},
+			},
+		},
+		{
+			name: "txaccepted",
+			newNtfn: func() (interface{}, error) {
+				return NewCmd("txaccepted", "123", 1.5)
+			},
+			staticNtfn: func() interface{} {
+				return NewTxAcceptedNtfn("123", 1.5)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"txaccepted","params":["123",1.5],"id":null}`,
+			unmarshalled: &TxAcceptedNtfn{
+				TxID:   "123",
+				Amount: 1.5,
+			},
+		},
+		{
+			name: "relevanttxaccepted",
+			newNtfn: func() (interface{}, error) {
+				return NewCmd("relevanttxaccepted", "001122")
+			},
+			staticNtfn: func() interface{} {
+				return NewRelevantTxAcceptedNtfn("001122")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"relevanttxaccepted","params":["001122"],"id":null}`,
+			unmarshalled: &RelevantTxAcceptedNtfn{
+				Transaction: "001122",
+			},
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Marshal the command as created by the new static command
+		// creation function.
+		marshalled, err := MarshalCmd(nil, test.staticNtfn())
+		if err != nil {
+			t.Errorf("MarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+
+		if !bytes.Equal(marshalled, []byte(test.marshalled)) {
+			t.Errorf("Test #%d (%s) unexpected marshalled data - "+
+				"got %s, want %s", i, test.name, marshalled,
+				test.marshalled)
+			t.Errorf("\n%s\n%s", marshalled, test.marshalled)
+			continue
+		}
+
+		// Ensure the command is created without error via the generic
+		// new command creation function.
+		ntfn, err := test.newNtfn()
+		if err != nil {
+			t.Errorf("Test #%d (%s) unexpected NewCmd error: %v ",
+				i, test.name, err)
+		}
+
+		// Marshal the command as created by the generic new command
+		// creation function.
+		marshalled, err = MarshalCmd(nil, ntfn)
+		if err != nil {
+			t.Errorf("MarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+
+		if !bytes.Equal(marshalled, []byte(test.marshalled)) {
+			t.Errorf("Test #%d (%s) unexpected marshalled data - "+
+				"got %s, want %s", i, test.name, marshalled,
+				test.marshalled)
+			continue
+		}
+
+		var request Request
+		if err := json.Unmarshal(marshalled, &request); err != nil {
+			t.Errorf("Test #%d (%s) unexpected error while "+
+				"unmarshalling JSON-RPC request: %v", i,
+				test.name, err)
+			continue
+		}
+
+		ntfn, err = UnmarshalCmd(&request)
+		if err != nil {
+			t.Errorf("UnmarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(ntfn, test.unmarshalled) {
+			t.Errorf("Test #%d (%s) unexpected unmarshalled notification "+
+				"- got %s, want %s", i, test.name,
+				fmt.Sprintf("(%T) %+[1]v", ntfn),
+				fmt.Sprintf("(%T) %+[1]v\n", test.unmarshalled))
+			continue
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 type helpCacher struct {
 	sync.Mutex
 	usage      string
+	wsUsage    string
 	methodHelp map[string]helpDescInfo
 }
 
@@ -32,6 +33,8 @@ const (
 	RawTransactionsCmd = "RawTransactions"
 	UtilCmd            = "Util"
 	WalletCmd          = "Wallet"
+	WebsocketCmd       = "Websocket"
+	ZMQCmd             = "Zmq"
 )
 
 var allMethodHelp = map[string]helpDescInfo{
@@ -52,6 +55,7 @@ var allMethodHelp = map[string]helpDescInfo{
 	"gettxout":              {BlockChainCmd, gettxoutDesc},
 	"gettxoutsetinfo":       {BlockChainCmd, gettxoutsetinfoDesc},
 	"pruneblockchain":       {BlockChainCmd, pruneblockchainDesc},
+	"savemempool":           {BlockChainCmd, savemempoolDesc},
 	"verifychain":           {BlockChainCmd, verifychainDesc},
 	"preciousblock":         {BlockChainCmd, preciousblockDesc},
 	"gettxoutproof":         {BlockChainCmd, gettxoutproofDesc},
@@ -84,14 +88,34 @@ var allMethodHelp = map[string]helpDescInfo{
 	"decodescript":         {RawTransactionsCmd, decodescriptDesc},
 	"sendrawtransaction":   {RawTransactionsCmd, sendrawtransactionDesc},
 	"signrawtransaction":   {RawTransactionsCmd, signrawtransactionDesc},
+	"combinepsbt":          {RawTransactionsCmd, combinepsbtDesc},
+	"finalizepsbt":         {RawTransactionsCmd, finalizepsbtDesc},
+	"decodepsbt":           {RawTransactionsCmd, decodepsbtDesc},
 
 	"getinfo": {ControlCmd, getinfoDesc},
 	"help":    {ControlCmd, helpDesc},
 	"stop":    {ControlCmd, stopDesc},
 	"uptime":  {ControlCmd, uptimeDesc},
 
-	"validateaddress": {UtilCmd, validateaddressDesc},
-	"createmultisig":  {UtilCmd, createmultisigDesc},
+	"getscriptcacheinfo": {ControlCmd, getscriptcacheinfoDesc},
+
+	"validateaddress":  {UtilCmd, validateaddressDesc},
+	"createmultisig":   {UtilCmd, createmultisigDesc},
+	"estimatefee":      {UtilCmd, estimatefeeDesc},
+	"estimatesmartfee": {UtilCmd, estimatesmartfeeDesc},
+
+	"verifymessage":          {UtilCmd, verifymessageDesc},
+	"signmessagewithprivkey": {UtilCmd, signmessagewithprivkeyDesc},
+
+	"loadtxfilter":              {WebsocketCmd, loadtxfilterDesc},
+	"notifyblocks":              {WebsocketCmd, notifyblocksDesc},
+	"notifynewtransactions":     {WebsocketCmd, notifynewtransactionsDesc},
+	"notifyreceived":            {WebsocketCmd, notifyreceivedDesc},
+	"stopnotifyblocks":          {WebsocketCmd, stopnotifyblocksDesc},
+	"stopnotifynewtransactions": {WebsocketCmd, stopnotifynewtransactionsDesc},
+	"stopnotifyreceived":        {WebsocketCmd, stopnotifyreceivedDesc},
+
+	"getzmqnotifications": {ZMQCmd, getzmqnotificationsDesc},
 
 	"getexcessiveblock":  {DebugCmd, getexcessiveblockDesc},
 	"setexcessiveblock":  {DebugCmd, setexcessiveblockDesc},
@@ -99,15 +123,50 @@ var allMethodHelp = map[string]helpDescInfo{
 	"waitforblock":       {DebugCmd, waitforblockDesc},
 	"echo":               {DebugCmd, echoDesc},
 
-	"getnewaddress":      {WalletCmd, getnewaddressDesc},
-	"listunspent":        {WalletCmd, listunspentDesc},
-	"settxfee":           {WalletCmd, settxfeeDesc},
-	"sendtoaddress":      {WalletCmd, sendtoaddressDesc},
-	"getbalance":         {WalletCmd, getbalanceDesc},
-	"gettransaction":     {WalletCmd, gettransactionDesc},
-	"sendmany":           {WalletCmd, sendmanyDesc},
-	"fundrawtransaction": {WalletCmd, fundrawtransactionDesc},
-	"addmultisigaddress": {WalletCmd, addmultisigaddressDesc},
+	"getnewaddress":          {WalletCmd, getnewaddressDesc},
+	"listunspent":            {WalletCmd, listunspentDesc},
+	"settxfee":               {WalletCmd, settxfeeDesc},
+	"sendtoaddress":          {WalletCmd, sendtoaddressDesc},
+	"getbalance":             {WalletCmd, getbalanceDesc},
+	"gettransaction":         {WalletCmd, gettransactionDesc},
+	"sendmany":               {WalletCmd, sendmanyDesc},
+	"fundrawtransaction":     {WalletCmd, fundrawtransactionDesc},
+	"addmultisigaddress":     {WalletCmd, addmultisigaddressDesc},
+	"encryptwallet":          {WalletCmd, encryptwalletDesc},
+	"walletpassphrase":       {WalletCmd, walletpassphraseDesc},
+	"walletlock":             {WalletCmd, walletlockDesc},
+	"walletpassphrasechange": {WalletCmd, walletpassphrasechangeDesc},
+	"getrawchangeaddress":    {WalletCmd, getrawchangeaddressDesc},
+	"keypoolrefill":          {WalletCmd, keypoolrefillDesc},
+	"dumpmasterkey":          {WalletCmd, dumpmasterkeyDesc},
+	"sethdseed":              {WalletCmd, sethdseedDesc},
+	"importprivkey":          {WalletCmd, importprivkeyDesc},
+	"dumpprivkey":            {WalletCmd, dumpprivkeyDesc},
+	"importaddress":          {WalletCmd, importaddressDesc},
+	"importpubkey":           {WalletCmd, importpubkeyDesc},
+	"dumpwallet":             {WalletCmd, dumpwalletDesc},
+	"importwallet":           {WalletCmd, importwalletDesc},
+	"rescanblockchain":       {WalletCmd, rescanblockchainDesc},
+	"listtransactions":       {WalletCmd, listtransactionsDesc},
+	"listsinceblock":         {WalletCmd, listsinceblockDesc},
+	"listreceivedbyaddress":  {WalletCmd, listreceivedbyaddressDesc},
+	"getreceivedbyaddress":   {WalletCmd, getreceivedbyaddressDesc},
+	"lockunspent":            {WalletCmd, lockunspentDesc},
+	"listlockunspent":        {WalletCmd, listlockunspentDesc},
+
+	"abandontransaction":       {WalletCmd, abandontransactionDesc},
+	"resendwallettransactions": {WalletCmd, resendwallettransactionsDesc},
+
+	"createwallet": {WalletCmd, createwalletDesc},
+	"loadwallet":   {WalletCmd, loadwalletDesc},
+	"unloadwallet": {WalletCmd, unloadwalletDesc},
+	"listwallets":  {WalletCmd, listwalletsDesc},
+
+	"walletcreatefundedpsbt": {WalletCmd, walletcreatefundedpsbtDesc},
+	"walletprocesspsbt":      {WalletCmd, walletprocesspsbtDesc},
+
+	"signmessage":    {WalletCmd, signmessageDesc},
+	"getaddressinfo": {WalletCmd, getaddressinfoDesc},
 }
 
 // rpcMethodHelp returns an RPC help string for the provided method.
@@ -133,8 +192,12 @@ func (c *helpCacher) rpcUsage(includeWebsockets bool) (string, error) {
 	defer c.Unlock()
 
 	// Return the cached usage if it is available.
-	if c.usage != "" {
-		return c.usage, nil
+	cachedUsage := &c.usage
+	if includeWebsockets {
+		cachedUsage = &c.wsUsage
+	}
+	if *cachedUsage != "" {
+		return *cachedUsage, nil
 	}
 
 	// Generate a list of one-line usage for every command.
@@ -144,6 +207,9 @@ func (c *helpCacher) rpcUsage(includeWebsockets bool) (string, error) {
 		if info.category == DebugCmd {
 			continue
 		}
+		if !includeWebsockets && info.category == WebsocketCmd {
+			continue
+		}
 		if !conf.Cfg.Wallet.Enable && info.category == WalletCmd {
 			continue
 		}
@@ -166,10 +232,10 @@ func (c *helpCacher) rpcUsage(includeWebsockets bool) (string, error) {
 
 	for _, category := range categories {
 		sort.Strings(*usageTexts[category])
-		c.usage += "--- " + category + " ---\n" +
+		*cachedUsage += "--- " + category + " ---\n" +
 			strings.Join(*usageTexts[category], "\n") + "\n\n"
 	}
-	return c.usage, nil
+	return *cachedUsage, nil
 }
 
 // newHelpCacher returns a new instance of a help cacher which provides help and
@@ -100,6 +100,17 @@ var (
 		"        \"since\": xx            (numeric) height of the first " +
 		"block to which the status applies\n" +
 		"     }\n" +
+		"  },\n" +
+		"  \"upgrades\": {                (object) status of network " +
+		"upgrades\n" +
+		"     \"xxxx\" : {                (string) name of the upgrade\n" +
+		"        \"type\": \"xxxx\",      (string) \"height\" or \"mtp\", " +
+		"what the upgrade activates at\n" +
+		"        \"activation\": xx,      (numeric) the activation height " +
+		"or median time past of the upgrade\n" +
+		"        \"active\": xx           (boolean) whether the upgrade " +
+		"rules apply to the next block\n" +
+		"     }\n" +
 		"  }\n" +
 		"}\n" +
 		"\nExamples:\n" +
@@ -450,8 +461,10 @@ var (
 		"the mempool\n" +
 		"  \"maxmempool\": xxxxx,         (numeric) Maximum memory usage " +
 		"for the mempool\n" +
-		"  \"mempoolminfee\": xxxxx       (numeric) Minimum fee for tx to " +
+		"  \"mempoolminfee\": xxxxx,      (numeric) Minimum fee for tx to " +
 		"be accepted\n" +
+		"  \"loaded\": true|false         (boolean) True if the mempool is " +
+		"fully loaded\n" +
 		"}\n" +
 		"\nExamples:\n" +
 		HelpExampleCli("getmempoolinfo") +
@@ -577,6 +590,12 @@ var (
 		HelpExampleCli("pruneblockchain", "1000") +
 		HelpExampleRPC("pruneblockchain", "1000")
 
+	savemempoolDesc = "savemempool\n" +
+		"\nDumps the mempool to disk.\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("savemempool") +
+		HelpExampleRPC("savemempool")
+
 	verifychainDesc = "verifychain ( checklevel nblocks )\n" +
 		"\nVerifies blockchain database.\n" +
 		"\nArguments:\n" +
@@ -783,6 +802,8 @@ var (
 		"cost, as counted for purposes of block limits; if key is not " +
 		"present, sigop cost is unknown and clients MUST NOT assume it is " +
 		"zero\n" +
+		"         \"sigchecks\" : n,             (numeric) total sigchecks, " +
+		"as counted for purposes of block limits after the phonon upgrade\n" +
 		"         \"required\" : true|false      (boolean) if provided and " +
 		"true, this transaction must be in the final block\n" +
 		"      }\n" +
@@ -813,6 +834,8 @@ var (
 		"nonces\n" +
 		"  \"sigoplimit\" : n,                 (numeric) limit of sigops " +
 		"in blocks\n" +
+		"  \"sigchecklimit\" : n,              (numeric) limit of sigchecks " +
+		"in blocks\n" +
 		"  \"sizelimit\" : n,                  (numeric) limit of block " +
 		"size\n" +
 		"  \"curtime\" : ttt,                  (numeric) current timestamp " +
@@ -950,6 +973,20 @@ var (
 		"    ],\n" +
 		"    \"whitelisted\": true|false, (boolean) Whether the peer is " +
 		"whitelisted\n" +
+		"    \"cmpct_version\": n,        (numeric) The compact block " +
+		"version negotiated with the peer, 0 if none\n" +
+		"    \"bip152_hb_to\": true|false, (boolean) Whether we announce " +
+		"new blocks to the peer with cmpctblock messages\n" +
+		"    \"bip152_hb_from\": true|false, (boolean) Whether the peer " +
+		"announces new blocks to us with cmpctblock messages\n" +
+		"    \"cmpctblocks_received\": n, (numeric) The cmpctblock " +
+		"messages received from the peer\n" +
+		"    \"cmpctblocks_reconstructed\": n, (numeric) The compact " +
+		"blocks rebuilt successfully\n" +
+		"    \"cmpctblocks_txn_requested\": n, (numeric) The compact " +
+		"blocks which needed a getblocktxn round trip\n" +
+		"    \"cmpctblocks_failed\": n,   (numeric) The compact blocks " +
+		"which fell back to a full block download\n" +
 		"    \"bytessent_per_msg\": {\n" +
 		"       \"addr\": n,              (numeric) The total bytes sent " +
 		"aggregated by message type\n" +
@@ -1452,6 +1489,133 @@ var (
 		HelpExampleCli("signrawtransaction", "\"myhex\"") +
 		HelpExampleRPC("signrawtransaction", "\"myhex\"")
 
+	combinepsbtDesc = "combinepsbt [\"psbt\",...]\n" +
+		"\nCombine multiple partially signed Bitcoin transactions into one " +
+		"transaction.\n" +
+		"Implements the Combiner role.\n" +
+		"\nArguments:\n" +
+		"1. \"txs\"                   (string) A json array of base64 " +
+		"strings of partially signed transactions\n" +
+		"    [\n" +
+		"      \"psbt\"             (string) A base64 string of a PSBT\n" +
+		"      ,...\n" +
+		"    ]\n" +
+		"\nResult:\n" +
+		"  \"psbt\"          (string) The base64-encoded partially signed " +
+		"transaction\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("combinepsbt", "[\"mybase64_1\", \"mybase64_2\", \"mybase64_3\"]")
+
+	finalizepsbtDesc = "finalizepsbt \"psbt\" ( extract )\n" +
+		"Finalize the inputs of a PSBT. If the transaction is fully " +
+		"signed, it will produce a\n" +
+		"network serialized transaction which can be broadcast with " +
+		"sendrawtransaction. Otherwise a PSBT will be\n" +
+		"created which has the final_scriptSig fields filled for inputs " +
+		"that are complete.\n" +
+		"Implements the Finalizer and Extractor roles.\n" +
+		"\nArguments:\n" +
+		"1. \"psbt\"                 (string) A base64 string of a PSBT\n" +
+		"2. \"extract\"              (boolean, optional, default=true) If " +
+		"true and the transaction is complete,\n" +
+		"                             extract and return the complete " +
+		"transaction in normal network serialization instead of the PSBT.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"psbt\" : \"value\",          (string) The base64-encoded " +
+		"partially signed transaction if not extracted\n" +
+		"  \"hex\" : \"value\",           (string) The hex-encoded " +
+		"network transaction if extracted\n" +
+		"  \"complete\" : true|false,   (boolean) If the transaction has a " +
+		"complete set of signatures\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("finalizepsbt", "\"psbt\"")
+
+	decodepsbtDesc = "decodepsbt \"psbt\"\n" +
+		"\nReturn a JSON object representing the serialized, " +
+		"base64-encoded partially signed Bitcoin transaction.\n" +
+		"\nArguments:\n" +
+		"1. \"psbt\"            (string, required) The PSBT base64 string\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"tx\" : {                   (json object) The decoded network-" +
+		"serialized unsigned transaction.\n" +
+		"    ...                      The layout is the same as the output " +
+		"of decoderawtransaction.\n" +
+		"  },\n" +
+		"  \"unknown\" : {                (json object) The unknown global " +
+		"fields\n" +
+		"    \"key\" : \"value\"            (key-value pair) An unknown " +
+		"key-value pair\n" +
+		"     ...\n" +
+		"  },\n" +
+		"  \"inputs\" : [                 (array of json objects)\n" +
+		"    {\n" +
+		"      \"utxo\" : {            (json object, optional) Transaction " +
+		"output for UTXOs\n" +
+		"        \"amount\" : x.xxx,           (numeric) The value in " +
+		"BCH\n" +
+		"        \"scriptPubKey\" : {          (json object)\n" +
+		"          \"asm\" : \"asm\",            (string) The asm\n" +
+		"          \"hex\" : \"hex\",            (string) The hex\n" +
+		"          \"type\" : \"pubkeyhash\",    (string) The type, eg " +
+		"'pubkeyhash'\n" +
+		"          \"address\" : \"address\"     (string) Bitcoin address " +
+		"if there is one\n" +
+		"        }\n" +
+		"      },\n" +
+		"      \"partial_signatures\" : {             (json object, " +
+		"optional)\n" +
+		"        \"pubkey\" : \"signature\",           (string) The public " +
+		"key and signature that corresponds to it.\n" +
+		"        ,...\n" +
+		"      }\n" +
+		"      \"sighash\" : \"type\",                  (string, optional) " +
+		"The sighash type to be used\n" +
+		"      \"redeem_script\" : {       (json object, optional)\n" +
+		"          \"asm\" : \"asm\",            (string) The asm\n" +
+		"          \"hex\" : \"hex\",            (string) The hex\n" +
+		"          \"type\" : \"pubkeyhash\",    (string) The type, eg " +
+		"'pubkeyhash'\n" +
+		"        }\n" +
+		"      \"final_scriptSig\" : {       (json object, optional)\n" +
+		"          \"asm\" : \"asm\",            (string) The asm\n" +
+		"          \"hex\" : \"hex\",            (string) The hex\n" +
+		"        }\n" +
+		"      \"unknown\" : {                (json object) The unknown " +
+		"input fields\n" +
+		"        \"key\" : \"value\"            (key-value pair) An " +
+		"unknown key-value pair\n" +
+		"         ...\n" +
+		"      },\n" +
+		"    }\n" +
+		"    ,...\n" +
+		"  ]\n" +
+		"  \"outputs\" : [                 (array of json objects)\n" +
+		"    {\n" +
+		"      \"redeem_script\" : {       (json object, optional)\n" +
+		"          \"asm\" : \"asm\",            (string) The asm\n" +
+		"          \"hex\" : \"hex\",            (string) The hex\n" +
+		"          \"type\" : \"pubkeyhash\",    (string) The type, eg " +
+		"'pubkeyhash'\n" +
+		"        }\n" +
+		"      \"unknown\" : {                (json object) The unknown " +
+		"global fields\n" +
+		"        \"key\" : \"value\"            (key-value pair) An " +
+		"unknown key-value pair\n" +
+		"         ...\n" +
+		"      },\n" +
+		"    }\n" +
+		"    ,...\n" +
+		"  ]\n" +
+		"  \"fee\" : fee                      (numeric, optional) The " +
+		"transaction fee paid if all UTXOs slots in the PSBT have been " +
+		"filled.\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("decodepsbt", "\"psbt\"")
+
 	gettxoutproofDesc = "gettxoutproof [\"txid\",...] ( blockhash )\n" +
 		"\nReturns a hex-encoded proof that \"txid\" was included in a " +
 		"block.\n" +
@@ -1571,6 +1735,39 @@ var (
 		HelpExampleCli("validateaddress", "\"1PSSGeFHDnKNxiEyFrD1wcEaHr9hrQDDWc\"") +
 		HelpExampleRPC("validateaddress", "\"1PSSGeFHDnKNxiEyFrD1wcEaHr9hrQDDWc\"")
 
+	verifymessageDesc = "verifymessage \"address\" \"signature\" " +
+		"\"message\"\n" +
+		"\nVerify a signed message\n" +
+		"\nArguments:\n" +
+		"1. \"address\"         (string, required) The bitcoin address to " +
+		"use for the signature, in the legacy or the cash address format.\n" +
+		"2. \"signature\"       (string, required) The signature provided " +
+		"by the signer in base 64 encoding (see signmessage).\n" +
+		"3. \"message\"         (string, required) The message that was " +
+		"signed.\n" +
+		"\nResult:\n" +
+		"true|false   (boolean) If the signature is verified or not.\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("verifymessage", "\"1D1ZrZNe3JUo7ZycKEYQQiQAWd9y54F4XX\"", "\"signature\"",
+			"\"my message\"") +
+		HelpExampleRPC("verifymessage", "\"1D1ZrZNe3JUo7ZycKEYQQiQAWd9y54F4XX\"", "\"signature\"",
+			"\"my message\"")
+
+	signmessagewithprivkeyDesc = "signmessagewithprivkey \"privkey\" " +
+		"\"message\"\n" +
+		"\nSign a message with the private key of an address\n" +
+		"\nArguments:\n" +
+		"1. \"privkey\"         (string, required) The private key to sign " +
+		"the message with.\n" +
+		"2. \"message\"         (string, required) The message to create a " +
+		"signature of.\n" +
+		"\nResult:\n" +
+		"\"signature\"          (string) The signature of the message " +
+		"encoded in base 64\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("signmessagewithprivkey", "\"privkey\"", "\"my message\"") +
+		HelpExampleRPC("signmessagewithprivkey", "\"privkey\"", "\"my message\"")
+
 	createmultisigDesc = "createmultisig nrequired [\"key\",...]\n" +
 		"\nCreates a multi-signature address with n signature of m keys " +
 		"required.\n" +
@@ -1600,6 +1797,43 @@ var (
 		HelpExampleRPC("createmultisig", "2",
 			"\"[\\\"16sSauSf5pF2UkUwvKGq4qjNRzBZYqgEL5\\\",\\\"171sgjn4YtPu27adkKGrdDwzRTxnRkBfKV\\\"]\"")
 
+	estimatefeeDesc = "estimatefee nblocks\n" +
+		"\nEstimates the approximate fee per kilobyte needed for a " +
+		"transaction to begin confirmation within nblocks blocks.\n" +
+		"\nArguments:\n" +
+		"1. nblocks     (numeric, required)\n" +
+		"\nResult:\n" +
+		"n              (numeric) estimated fee-per-kilobyte\n" +
+		"\nA negative value is returned if not enough transactions and " +
+		"blocks have been observed to make an estimate.\n" +
+		"-1 is always returned for nblocks == 1 as it is impossible to " +
+		"calculate a fee that is high enough to get reliably included in " +
+		"the next block.\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("estimatefee", "6") +
+		HelpExampleRPC("estimatefee", "6")
+
+	estimatesmartfeeDesc = "estimatesmartfee nblocks\n" +
+		"\nEstimates the approximate fee per kilobyte needed for a " +
+		"transaction to begin confirmation within nblocks blocks if " +
+		"possible and return the number of blocks for which the estimate " +
+		"is valid.\n" +
+		"\nArguments:\n" +
+		"1. nblocks     (numeric, required)\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"feerate\" : x.x,     (numeric) estimate fee-per-kilobyte (in BCH)\n" +
+		"  \"blocks\" : n         (numeric) block number where estimate " +
+		"was found\n" +
+		"}\n" +
+		"\nA negative value is returned if not enough transactions and " +
+		"blocks have been observed to make an estimate for any number of " +
+		"blocks.\n" +
+		"However it will not return a value below the mempool reject fee.\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("estimatesmartfee", "6") +
+		HelpExampleRPC("estimatesmartfee", "6")
+
 	echoDesc = "echo \"message\" ...\n" +
 		"\nSimply echo back the input arguments. This command is for testing."
 
@@ -1611,6 +1845,40 @@ var (
 		"\nExamples:\n" +
 		HelpExampleCli("uptime") +
 		HelpExampleRPC("uptime")
+
+	getscriptcacheinfoDesc = "getscriptcacheinfo\n" +
+		"\nReturns the state of the signature and script execution caches.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"sigcache\": {              (json object) verified signatures\n" +
+		"    \"entries\": xxxxx,         (numeric) Current number of entries\n" +
+		"    \"maxentries\": xxxxx,      (numeric) Maximum number of entries\n" +
+		"    \"hits\": xxxxx,            (numeric) Lookups found in the cache\n" +
+		"    \"misses\": xxxxx           (numeric) Lookups not found in the cache\n" +
+		"  },\n" +
+		"  \"scriptcache\": {           (json object) verified (txid, flags) pairs, " +
+		"same fields as sigcache\n" +
+		"    ...\n" +
+		"  }\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("getscriptcacheinfo") +
+		HelpExampleRPC("getscriptcacheinfo")
+
+	getzmqnotificationsDesc = "getzmqnotifications\n" +
+		"\nReturns information about the active ZeroMQ notifications.\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  {                        (json object)\n" +
+		"    \"type\": \"pubhashtx\",   (string) Type of notification\n" +
+		"    \"address\": \"...\",      (string) Address of the publisher\n" +
+		"    \"hwm\": n                 (numeric) Outbound message high water mark\n" +
+		"  },\n" +
+		"  ...\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("getzmqnotifications") +
+		HelpExampleRPC("getzmqnotifications")
 )
 
 // wallet
@@ -1967,6 +2235,8 @@ var (
 		"                              If no outputs are specified here, " +
 		"the sender pays the fee.\n" +
 		"                                  [vout_index,...]\n" +
+		"     \"minconf\"                (numeric, optional, default 0) " +
+		"Only add inputs with at least this many confirmations\n" +
 		"   }\n" +
 		"                         for backward compatibility: passing in a " +
 		"true instead of an object will result in " +
@@ -2017,4 +2287,782 @@ var (
 		"\nAs json rpc call\n" +
 		HelpExampleRPC("addmultisigaddress", "2",
 			"\"[\\\"16sSauSf5pF2UkUwvKGq4qjNRzBZYqgEL5\\\",\\\"171sgjn4YtPu27adkKGrdDwzRTxnRkBfKV\\\"]\"")
+
+	encryptwalletDesc = "encryptwallet \"passphrase\"\n" +
+		"\nEncrypts the wallet with 'passphrase'. This is for first time " +
+		"encryption.\n" +
+		"After this, any calls that interact with private keys such as " +
+		"sending or signing \n" +
+		"will require the passphrase to be set prior the making these " +
+		"calls.\n" +
+		"Use the walletpassphrase call for this, and then walletlock " +
+		"call.\n" +
+		"If the wallet is already encrypted, use the " +
+		"walletpassphrasechange call.\n" +
+		"Unencrypted private keys of existing wallets are replaced by " +
+		"encrypted ones.\n" +
+		"\nArguments:\n" +
+		"1. \"passphrase\"    (string) The pass phrase to encrypt the " +
+		"wallet with. It must be at least 1 character, but should be " +
+		"long.\n" +
+		"\nExamples:\n" +
+		"\nEncrypt your wallet\n" +
+		HelpExampleCli("encryptwallet", "\"my pass phrase\"") +
+		"\nNow set the passphrase to use the wallet, such as for signing " +
+		"or sending bitcoin\n" +
+		HelpExampleCli("walletpassphrase", "\"my pass phrase\"", "60") +
+		"\nNow we can do something like send\n" +
+		HelpExampleCli("sendtoaddress", "\"address\"", "0.1") +
+		"\nNow lock the wallet again by removing the passphrase\n" +
+		HelpExampleCli("walletlock") +
+		"\nAs a json rpc call\n" +
+		HelpExampleRPC("encryptwallet", "\"my pass phrase\"")
+
+	walletpassphraseDesc = "walletpassphrase \"passphrase\" timeout\n" +
+		"\nStores the wallet decryption key in memory for 'timeout' " +
+		"seconds.\n" +
+		"This is needed prior to performing transactions related to " +
+		"private keys such as sending bitcoins\n" +
+		"\nArguments:\n" +
+		"1. \"passphrase\"     (string, required) The wallet passphrase\n" +
+		"2. timeout            (numeric, required) The time to keep the " +
+		"decryption key in seconds. Limited to at most 100000000 (~3 " +
+		"years).\n" +
+		"\nNote:\n" +
+		"Issuing the walletpassphrase command while the wallet is already " +
+		"unlocked will set a new unlock\n" +
+		"time that overrides the old one.\n" +
+		"\nExamples:\n" +
+		"\nUnlock the wallet for 60 seconds\n" +
+		HelpExampleCli("walletpassphrase", "\"my pass phrase\"", "60") +
+		"\nLock the wallet again (before 60 seconds)\n" +
+		HelpExampleCli("walletlock") +
+		"\nAs json rpc call\n" +
+		HelpExampleRPC("walletpassphrase", "\"my pass phrase\"", "60")
+
+	walletlockDesc = "walletlock\n" +
+		"\nRemoves the wallet encryption key from memory, locking the " +
+		"wallet.\n" +
+		"After calling this method, you will need to call " +
+		"walletpassphrase again\n" +
+		"before being able to call any methods which require the wallet " +
+		"to be unlocked.\n" +
+		"\nExamples:\n" +
+		"\nSet the passphrase for 2 minutes to perform a transaction\n" +
+		HelpExampleCli("walletpassphrase", "\"my pass phrase\"", "120") +
+		"\nPerform a send (requires passphrase set)\n" +
+		HelpExampleCli("sendtoaddress", "\"1M72Sfpbz1BPpXFHz9m3CdqATR44Jvaydd\"", "1.0") +
+		"\nClear the passphrase since we are done before 2 minutes is " +
+		"up\n" +
+		HelpExampleCli("walletlock") +
+		"\nAs json rpc call\n" +
+		HelpExampleRPC("walletlock")
+
+	walletpassphrasechangeDesc = "walletpassphrasechange \"oldpassphrase\" \"newpassphrase\"\n" +
+		"\nChanges the wallet passphrase from 'oldpassphrase' to " +
+		"'newpassphrase'.\n" +
+		"\nArguments:\n" +
+		"1. \"oldpassphrase\"      (string) The current passphrase\n" +
+		"2. \"newpassphrase\"      (string) The new passphrase\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("walletpassphrasechange", "\"old one\"", "\"new one\"") +
+		HelpExampleRPC("walletpassphrasechange", "\"old one\"", "\"new one\"")
+
+	getrawchangeaddressDesc = "getrawchangeaddress\n" +
+		"\nReturns a new Bitcoin address, for receiving change.\n" +
+		"This is for use with raw transactions, NOT normal use.\n" +
+		"\nResult:\n" +
+		"\"address\"    (string) The address\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("getrawchangeaddress") +
+		HelpExampleRPC("getrawchangeaddress")
+
+	keypoolrefillDesc = "keypoolrefill ( newsize )\n" +
+		"\nFills the keypool.\n" +
+		"Requires wallet passphrase to be set with walletpassphrase call " +
+		"if the wallet is encrypted.\n" +
+		"\nArguments\n" +
+		"1. newsize     (numeric, optional, default=1000) The new keypool " +
+		"size\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("keypoolrefill") +
+		HelpExampleRPC("keypoolrefill")
+
+	dumpmasterkeyDesc = "dumpmasterkey\n" +
+		"\nReveals the BIP32 master private key the wallet keys are derived " +
+		"from, along with the extended public key of the BIP44 account.\n" +
+		"Requires wallet passphrase to be set with walletpassphrase call " +
+		"if the wallet is encrypted.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"hdseedid\": \"hex\",      (string) The Hash160 of the HD seed\n" +
+		"  \"hdmasterkey\": \"xprv\",  (string) The extended master private key\n" +
+		"  \"accountpath\": \"path\",  (string) The derivation path of the account\n" +
+		"  \"accountxpub\": \"xpub\",  (string) The extended public key of the account\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("dumpmasterkey") +
+		HelpExampleRPC("dumpmasterkey")
+
+	sethdseedDesc = "sethdseed ( newkeypool \"seed\" )\n" +
+		"\nSet or generate a new HD wallet seed. New keys are derived from " +
+		"the new seed, keys derived from the previous seed remain in the " +
+		"wallet.\n" +
+		"Note that you will need to MAKE A NEW BACKUP of your wallet after " +
+		"setting the HD wallet seed.\n" +
+		"Requires wallet passphrase to be set with walletpassphrase call " +
+		"if the wallet is encrypted.\n" +
+		"\nArguments:\n" +
+		"1. \"newkeypool\"    (boolean, optional, default=true) Whether to " +
+		"flush old unused addresses, including change addresses, from the " +
+		"keypool and regenerate it.\n" +
+		"                   If true, the next address from getnewaddress " +
+		"and change address from getrawchangeaddress will be from this new " +
+		"seed.\n" +
+		"                   If false, addresses from the existing keypool " +
+		"will be used until it has been depleted.\n" +
+		"2. \"seed\"          (string, optional) The WIF private key to use " +
+		"as the new HD seed; if not provided a random seed will be used.\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("sethdseed") +
+		HelpExampleCli("sethdseed", "false") +
+		HelpExampleCli("sethdseed", "true", "\"wifkey\"") +
+		HelpExampleRPC("sethdseed", "true", "\"wifkey\"")
+
+	importprivkeyDesc = "importprivkey \"privkey\" ( \"label\" ) ( rescan )\n" +
+		"\nAdds a private key (as returned by dumpprivkey) to your wallet.\n" +
+		"Requires wallet passphrase to be set with walletpassphrase call " +
+		"if the wallet is encrypted.\n" +
+		"\nArguments:\n" +
+		"1. \"privkey\"          (string, required) The private key (see dumpprivkey)\n" +
+		"2. \"label\"            (string, optional, default=\"\") An optional label\n" +
+		"3. rescan               (boolean, optional, default=true) Rescan the wallet for transactions\n" +
+		"\nNote: This call can take minutes to complete if rescan is true.\n" +
+		"\nExamples:\n" +
+		"\nDump a private key\n" +
+		HelpExampleCli("dumpprivkey", "\"myaddress\"") +
+		"\nImport the private key with rescan\n" +
+		HelpExampleCli("importprivkey", "\"mykey\"") +
+		"\nImport using a label and without rescan\n" +
+		HelpExampleCli("importprivkey", "\"mykey\"", "\"testing\"", "false") +
+		HelpExampleRPC("importprivkey", "\"mykey\"", "\"testing\"", "false")
+
+	dumpprivkeyDesc = "dumpprivkey \"address\"\n" +
+		"\nReveals the private key corresponding to 'address'.\n" +
+		"Then the importprivkey can be used with this output\n" +
+		"Requires wallet passphrase to be set with walletpassphrase call " +
+		"if the wallet is encrypted.\n" +
+		"\nArguments:\n" +
+		"1. \"address\"   (string, required) The bitcoin address for the private key\n" +
+		"\nResult:\n" +
+		"\"key\"                (string) The private key\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("dumpprivkey", "\"myaddress\"") +
+		HelpExampleCli("importprivkey", "\"mykey\"") +
+		HelpExampleRPC("dumpprivkey", "\"myaddress\"")
+
+	importaddressDesc = "importaddress \"address\" ( \"label\" rescan p2sh )\n" +
+		"\nAdds a script (in hex) or address that can be watched as if it " +
+		"were in your wallet but cannot be used to spend.\n" +
+		"\nArguments:\n" +
+		"1. \"script\"           (string, required) The hex-encoded script (or address)\n" +
+		"2. \"label\"            (string, optional, default=\"\") An optional label\n" +
+		"3. rescan               (boolean, optional, default=true) Rescan the wallet for transactions\n" +
+		"4. p2sh                 (boolean, optional, default=false) Add the P2SH version of the script as well\n" +
+		"\nNote: This call can take minutes to complete if rescan is true.\n" +
+		"If you have the full public key, you should call importpubkey " +
+		"instead of this.\n" +
+		"\nNote: If you import a non-standard raw script in hex form, " +
+		"outputs sending to it will be treated as change, and not show up " +
+		"in many RPCs.\n" +
+		"\nExamples:\n" +
+		"\nImport a script with rescan\n" +
+		HelpExampleCli("importaddress", "\"myscript\"") +
+		"\nImport using a label without rescan\n" +
+		HelpExampleCli("importaddress", "\"myscript\"", "\"testing\"", "false") +
+		HelpExampleRPC("importaddress", "\"myscript\"", "\"testing\"", "false")
+
+	importpubkeyDesc = "importpubkey \"pubkey\" ( \"label\" rescan )\n" +
+		"\nAdds a public key (in hex) that can be watched as if it were in " +
+		"your wallet but cannot be used to spend.\n" +
+		"\nArguments:\n" +
+		"1. \"pubkey\"           (string, required) The hex-encoded public key\n" +
+		"2. \"label\"            (string, optional, default=\"\") An optional label\n" +
+		"3. rescan               (boolean, optional, default=true) Rescan the wallet for transactions\n" +
+		"\nNote: This call can take minutes to complete if rescan is true.\n" +
+		"\nExamples:\n" +
+		"\nImport a public key with rescan\n" +
+		HelpExampleCli("importpubkey", "\"mypubkey\"") +
+		"\nImport using a label without rescan\n" +
+		HelpExampleCli("importpubkey", "\"mypubkey\"", "\"testing\"", "false") +
+		HelpExampleRPC("importpubkey", "\"mypubkey\"", "\"testing\"", "false")
+
+	dumpwalletDesc = "dumpwallet \"filename\"\n" +
+		"\nDumps all wallet keys in a human-readable format to a " +
+		"server-side file. This does not allow overwriting existing files.\n" +
+		"Requires wallet passphrase to be set with walletpassphrase call " +
+		"if the wallet is encrypted.\n" +
+		"\nArguments:\n" +
+		"1. \"filename\"    (string, required) The filename with path " +
+		"(either absolute or relative to the node working directory)\n" +
+		"\nResult:\n" +
+		"{                           (json object)\n" +
+		"  \"filename\" : \"path\",  (string) The filename with full absolute path\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("dumpwallet", "\"test\"") +
+		HelpExampleRPC("dumpwallet", "\"test\"")
+
+	importwalletDesc = "importwallet \"filename\" ( rescan )\n" +
+		"\nImports keys from a wallet dump file (see dumpwallet).\n" +
+		"Requires wallet passphrase to be set with walletpassphrase call " +
+		"if the wallet is encrypted.\n" +
+		"\nArguments:\n" +
+		"1. \"filename\"    (string, required) The wallet file\n" +
+		"2. rescan          (boolean, optional, default=true) Rescan the " +
+		"blocks from the earliest key time\n" +
+		"\nExamples:\n" +
+		"\nDump the wallet\n" +
+		HelpExampleCli("dumpwallet", "\"test\"") +
+		"\nImport the wallet\n" +
+		HelpExampleCli("importwallet", "\"test\"") +
+		"\nImport using the json rpc call\n" +
+		HelpExampleRPC("importwallet", "\"test\"")
+
+	rescanblockchainDesc = "rescanblockchain (\"start_height\") (\"stop_height\")\n" +
+		"\nRescan the local blockchain for wallet related transactions.\n" +
+		"The progress is reported in the log while the rescan runs.\n" +
+		"\nArguments:\n" +
+		"1. \"start_height\"    (numeric, optional, default=0) block height " +
+		"where the rescan should start\n" +
+		"2. \"stop_height\"     (numeric, optional) the last block height " +
+		"that should be scanned. If none is provided it will rescan up to " +
+		"the tip at return time of this call.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"start_height\"     (numeric) The block height where the rescan started (the requested height or 0)\n" +
+		"  \"stop_height\"      (numeric) The height of the last rescanned block.\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("rescanblockchain", "100000", "120000") +
+		HelpExampleRPC("rescanblockchain", "100000", "120000")
+
+	listtransactionsDesc = "listtransactions ( \"account\" count skip include_watchonly)\n" +
+		"\nReturns up to 'count' most recent transactions skipping the first " +
+		"'skip' transactions for account 'account'.\n" +
+		"\nArguments:\n" +
+		"1. \"account\"    (string, optional, default=\"*\") The label of " +
+		"the transactions to list, \"*\" lists them all.\n" +
+		"2. count          (numeric, optional, default=10) The number of transactions to return\n" +
+		"3. skip           (numeric, optional, default=0) The number of transactions to skip\n" +
+		"4. include_watchonly (bool, optional, default=false) Include transactions to watch-only addresses (see 'importaddress')\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  {\n" +
+		"    \"account\":\"accountname\",       (string) DEPRECATED. The account name associated with the transaction.\n" +
+		"    \"address\":\"address\",    (string) The bitcoin address of the transaction.\n" +
+		"    \"category\":\"send|receive|generate|immature|orphan\", (string) The transaction category.\n" +
+		"    \"amount\": x.xxx,          (numeric) The amount in " + util.CurrencyUnit + ". This is negative for the 'send' category, and is positive\n" +
+		"                                        for the 'receive' category,\n" +
+		"    \"label\": \"label\",       (string) A comment for the address/transaction, if any\n" +
+		"    \"vout\": n,                (numeric) the vout value\n" +
+		"    \"fee\": x.xxx,             (numeric) The amount of the fee in " + util.CurrencyUnit + ". This is negative and only available for the\n" +
+		"                                         'send' category of transactions.\n" +
+		"    \"confirmations\": n,       (numeric) The number of confirmations for the transaction.\n" +
+		"    \"blockhash\": \"hashvalue\", (string) The block hash containing the transaction.\n" +
+		"    \"blocktime\": xxx,         (numeric) The block time in seconds since epoch (1 Jan 1970 GMT).\n" +
+		"    \"txid\": \"transactionid\", (string) The transaction id.\n" +
+		"    \"time\": xxx,              (numeric) The transaction time in seconds since epoch (Jan 1 1970 GMT).\n" +
+		"    \"timereceived\": xxx,      (numeric) The time received in seconds since epoch (Jan 1 1970 GMT).\n" +
+		"    \"comment\": \"...\",       (string) If a comment is associated with the transaction.\n" +
+		"    \"to\": \"...\",            (string) If a comment to is associated with the transaction.\n" +
+		"  }\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		"\nList the most recent 10 transactions in the systems\n" +
+		HelpExampleCli("listtransactions") +
+		"\nList transactions 100 to 120\n" +
+		HelpExampleCli("listtransactions", "\"*\"", "20", "100") +
+		"\nAs a json rpc call\n" +
+		HelpExampleRPC("listtransactions", "\"*\"", "20", "100")
+
+	listsinceblockDesc = "listsinceblock ( \"blockhash\" target_confirmations include_watchonly include_removed )\n" +
+		"\nGet all transactions in blocks since block [blockhash], or all " +
+		"transactions if omitted.\n" +
+		"If \"blockhash\" is no longer a part of the main chain, transactions " +
+		"from the fork point onward are included.\n" +
+		"Additionally, if include_removed is set, transactions affecting the " +
+		"wallet which were removed are returned in the \"removed\" array.\n" +
+		"\nArguments:\n" +
+		"1. \"blockhash\"            (string, optional) The block hash to list transactions since\n" +
+		"2. target_confirmations:    (numeric, optional, default=1) Return " +
+		"the nth block hash from the main chain. e.g. 1 would mean the best " +
+		"block hash. Note: this is not used as a filter, but only affects " +
+		"[lastblock] in the return value\n" +
+		"3. include_watchonly:       (bool, optional, default=false) Include transactions to watch-only addresses (see 'importaddress')\n" +
+		"4. include_removed:         (bool, optional, default=true) Show " +
+		"transactions that were removed due to a reorg in the \"removed\" " +
+		"array\n" +
+		"                                                           (not " +
+		"guaranteed to work on pruned nodes)\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"transactions\": [\n" +
+		"    \"account\":\"accountname\",       (string) DEPRECATED. The account name associated with the transaction.\n" +
+		"    \"address\":\"address\",    (string) The bitcoin address of the transaction.\n" +
+		"    \"category\":\"send|receive|generate|immature|orphan\", (string) The transaction category.\n" +
+		"    \"amount\": x.xxx,          (numeric) The amount in " + util.CurrencyUnit + ". This is negative for the 'send' category, and is positive\n" +
+		"                                        for the 'receive' category,\n" +
+		"    \"label\": \"label\",       (string) A comment for the address/transaction, if any\n" +
+		"    \"vout\": n,                (numeric) the vout value\n" +
+		"    \"fee\": x.xxx,             (numeric) The amount of the fee in " + util.CurrencyUnit + ". This is negative and only available for the\n" +
+		"                                         'send' category of transactions.\n" +
+		"    \"confirmations\": n,       (numeric) The number of confirmations for the transaction.\n" +
+		"    \"blockhash\": \"hashvalue\", (string) The block hash containing the transaction.\n" +
+		"    \"blocktime\": xxx,         (numeric) The block time in seconds since epoch (1 Jan 1970 GMT).\n" +
+		"    \"txid\": \"transactionid\", (string) The transaction id.\n" +
+		"    \"time\": xxx,              (numeric) The transaction time in seconds since epoch (Jan 1 1970 GMT).\n" +
+		"    \"timereceived\": xxx,      (numeric) The time received in seconds since epoch (Jan 1 1970 GMT).\n" +
+		"    \"comment\": \"...\",       (string) If a comment is associated with the transaction.\n" +
+		"    \"to\": \"...\",            (string) If a comment to is associated with the transaction.\n" +
+		"  ],\n" +
+		"  \"removed\": [\n" +
+		"    <structure is the same as \"transactions\" above, only present if include_removed=true>\n" +
+		"    Note: transactions that were readded in the active chain will " +
+		"appear as-is in this array, and may thus have a positive " +
+		"confirmation count.\n" +
+		"  ],\n" +
+		"  \"lastblock\": \"lastblockhash\"     (string) The hash of the " +
+		"block (target_confirmations-1) from the best block on the main " +
+		"chain. This is typically used to feed back into listsinceblock the " +
+		"next time you call it. So you would generally use a " +
+		"target_confirmations of say 6, so you will be continually " +
+		"re-notified of transactions until they've reached 6 confirmations " +
+		"plus any new ones\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("listsinceblock") +
+		HelpExampleCli("listsinceblock", "\"000000000000000bacf66f7497b7dc45ef753ee9a7d38571037cdb1a57f663ad\"", "6") +
+		HelpExampleRPC("listsinceblock", "\"000000000000000bacf66f7497b7dc45ef753ee9a7d38571037cdb1a57f663ad\"", "6")
+
+	listreceivedbyaddressDesc = "listreceivedbyaddress ( minconf include_empty include_watchonly)\n" +
+		"\nList balances by receiving address.\n" +
+		"\nArguments:\n" +
+		"1. minconf           (numeric, optional, default=1) The minimum " +
+		"number of confirmations before payments are included.\n" +
+		"2. include_empty     (bool, optional, default=false) Whether to " +
+		"include addresses that haven't received any payments.\n" +
+		"3. include_watchonly (bool, optional, default=false) Whether to " +
+		"include watch-only addresses (see 'importaddress').\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  {\n" +
+		"    \"involvesWatchonly\" : true,        (bool) Only returned if imported addresses were involved in transaction\n" +
+		"    \"address\" : \"receivingaddress\",  (string) The receiving address\n" +
+		"    \"account\" : \"accountname\",       (string) DEPRECATED. The account of the receiving address. The default account is \"\".\n" +
+		"    \"amount\" : x.xxx,                  (numeric) The total amount in " + util.CurrencyUnit + " received by the address\n" +
+		"    \"confirmations\" : n,               (numeric) The number of confirmations of the most recent transaction included\n" +
+		"    \"label\" : \"label\",               (string) A comment for the address/transaction, if any\n" +
+		"    \"txids\": [\n" +
+		"       n,                                (numeric) The ids of transactions received with the address \n" +
+		"       ...\n" +
+		"    ]\n" +
+		"  }\n" +
+		"  ,...\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("listreceivedbyaddress") +
+		HelpExampleCli("listreceivedbyaddress", "6", "true") +
+		HelpExampleRPC("listreceivedbyaddress", "6", "true", "true")
+
+	getreceivedbyaddressDesc = "getreceivedbyaddress \"address\" ( minconf )\n" +
+		"\nReturns the total amount received by the given address in " +
+		"transactions with at least minconf confirmations.\n" +
+		"\nArguments:\n" +
+		"1. \"address\"         (string, required) The bitcoin address for transactions.\n" +
+		"2. minconf             (numeric, optional, default=1) Only include " +
+		"transactions confirmed at least this many times.\n" +
+		"\nResult:\n" +
+		"amount   (numeric) The total amount in " + util.CurrencyUnit +
+		" received at this address.\n" +
+		"\nExamples:\n" +
+		"\nThe amount from transactions with at least 1 confirmation\n" +
+		HelpExampleCli("getreceivedbyaddress", "\"1D1ZrZNe3JUo7ZycKEYQQiQAWd9y54F4XX\"") +
+		"\nThe amount including unconfirmed transactions, zero confirmations\n" +
+		HelpExampleCli("getreceivedbyaddress", "\"1D1ZrZNe3JUo7ZycKEYQQiQAWd9y54F4XX\"", "0") +
+		"\nThe amount with at least 6 confirmations\n" +
+		HelpExampleCli("getreceivedbyaddress", "\"1D1ZrZNe3JUo7ZycKEYQQiQAWd9y54F4XX\"", "6") +
+		"\nAs a json rpc call\n" +
+		HelpExampleRPC("getreceivedbyaddress", "\"1D1ZrZNe3JUo7ZycKEYQQiQAWd9y54F4XX\"", "6")
+
+	lockunspentDesc = "lockunspent unlock ([{\"txid\":\"txid\",\"vout\":n},...]) ( persistent )\n" +
+		"\nUpdates list of temporarily unspendable outputs.\n" +
+		"Temporarily lock (unlock=false) or unlock (unlock=true) " +
+		"specified transaction outputs.\n" +
+		"If no transaction outputs are specified when unlocking then all " +
+		"current locked transaction outputs are unlocked.\n" +
+		"A locked transaction output will not be chosen by automatic coin " +
+		"selection, when spending bitcoins.\n" +
+		"Locks are stored in memory only, unless persistent is set, and " +
+		"are cleared when the node stops.\n" +
+		"Also see the listunspent call\n" +
+		"\nArguments:\n" +
+		"1. unlock            (boolean, required) Whether to unlock (true) " +
+		"or lock (false) the specified transactions\n" +
+		"2. \"transactions\"  (string, optional) A json array of objects. " +
+		"Each object the txid (string) vout (numeric)\n" +
+		"     [           (json array of json objects)\n" +
+		"       {\n" +
+		"         \"txid\":\"id\",    (string) The transaction id\n" +
+		"         \"vout\": n         (numeric) The output number\n" +
+		"       }\n" +
+		"       ,...\n" +
+		"     ]\n" +
+		"3. persistent        (boolean, optional, default=false) Whether " +
+		"to also write the locks to the wallet database, so that they " +
+		"are kept across restarts\n" +
+		"\nResult:\n" +
+		"true|false    (boolean) Whether the command was successful or " +
+		"not\n" +
+		"\nExamples:\n" +
+		"\nList the unspent transactions\n" +
+		HelpExampleCli("listunspent") +
+		"\nLock an unspent transaction\n" +
+		HelpExampleCli("lockunspent", "false",
+			"\"[{\\\"txid\\\":\\\"a08e6907dbbd3d809776dbfc5d82e371b764ed838b5655e72f463568df1aadf0\\\",\\\"vout\\\":1}]\"") +
+		"\nList the locked transactions\n" +
+		HelpExampleCli("listlockunspent") +
+		"\nUnlock the transaction again\n" +
+		HelpExampleCli("lockunspent", "true",
+			"\"[{\\\"txid\\\":\\\"a08e6907dbbd3d809776dbfc5d82e371b764ed838b5655e72f463568df1aadf0\\\",\\\"vout\\\":1}]\"") +
+		"\nAs a json rpc call\n" +
+		HelpExampleRPC("lockunspent", "false",
+			"\"[{\\\"txid\\\":\\\"a08e6907dbbd3d809776dbfc5d82e371b764ed838b5655e72f463568df1aadf0\\\",\\\"vout\\\":1}]\"")
+
+	listlockunspentDesc = "listlockunspent\n" +
+		"\nReturns list of temporarily unspendable outputs.\n" +
+		"See the lockunspent call to lock and unlock transactions for " +
+		"spending.\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  {\n" +
+		"    \"txid\" : \"transactionid\",     (string) The transaction id " +
+		"locked\n" +
+		"    \"vout\" : n                      (numeric) The vout value\n" +
+		"  }\n" +
+		"  ,...\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		"\nList the unspent transactions\n" +
+		HelpExampleCli("listunspent") +
+		"\nLock an unspent transaction\n" +
+		HelpExampleCli("lockunspent", "false",
+			"\"[{\\\"txid\\\":\\\"a08e6907dbbd3d809776dbfc5d82e371b764ed838b5655e72f463568df1aadf0\\\",\\\"vout\\\":1}]\"") +
+		"\nList the locked transactions\n" +
+		HelpExampleCli("listlockunspent") +
+		"\nAs a json rpc call\n" +
+		HelpExampleRPC("listlockunspent")
+
+	abandontransactionDesc = "abandontransaction \"txid\"\n" +
+		"\nMark in-wallet transaction <txid> as abandoned\n" +
+		"This will mark this transaction and all its in-wallet descendants " +
+		"as abandoned which will allow\n" +
+		"for their inputs to be respent.  It can be used to replace " +
+		"\"stuck\" or evicted transactions.\n" +
+		"It only works on transactions which are not included in a block " +
+		"and are not currently in the mempool.\n" +
+		"It has no effect on transactions which are already abandoned.\n" +
+		"\nArguments:\n" +
+		"1. \"txid\"    (string, required) The transaction id\n" +
+		"\nResult:\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("abandontransaction", "\"1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d\"") +
+		HelpExampleRPC("abandontransaction", "\"1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d\"")
+
+	resendwallettransactionsDesc = "resendwallettransactions\n" +
+		"Immediately re-broadcast unconfirmed wallet transactions to all " +
+		"peers.\n" +
+		"Intended only for testing; the wallet code periodically " +
+		"re-broadcasts\n" +
+		"automatically.\n" +
+		"Returns array of transaction ids that were re-broadcast.\n"
+
+	createwalletDesc = "createwallet \"wallet_name\"\n" +
+		"\nCreates and loads a new wallet.\n" +
+		"\nArguments:\n" +
+		"1. \"wallet_name\"    (string, required) The name for the new wallet. " +
+		"It is kept in the wallets directory of the data directory.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"name\" :    <wallet_name>,        (string) The wallet name if " +
+		"created successfully.\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("createwallet", "\"testwallet\"") +
+		HelpExampleRPC("createwallet", "\"testwallet\"")
+
+	loadwalletDesc = "loadwallet \"filename\"\n" +
+		"\nLoads a wallet from a wallet directory of the wallets directory.\n" +
+		"\nNote that all wallet command-line options used when starting " +
+		"the node will be\n" +
+		"applied to the new wallet (eg -walletbroadcast).\n" +
+		"\nArguments:\n" +
+		"1. \"filename\"    (string, required) The wallet name.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"name\" :    <wallet_name>,        (string) The wallet name if " +
+		"loaded successfully.\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("loadwallet", "\"test.dat\"") +
+		HelpExampleRPC("loadwallet", "\"test.dat\"")
+
+	unloadwalletDesc = "unloadwallet ( \"wallet_name\" )\n" +
+		"Unloads the wallet referenced by the request endpoint otherwise " +
+		"unloads the wallet specified in the argument.\n" +
+		"Specifying the wallet name on a wallet endpoint is invalid." +
+		"\nArguments:\n" +
+		"1. \"wallet_name\"    (string, optional) The name of the wallet " +
+		"to unload.\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("unloadwallet", "wallet_name") +
+		HelpExampleRPC("unloadwallet", "wallet_name")
+
+	listwalletsDesc = "listwallets\n" +
+		"Returns a list of currently loaded wallets.\n" +
+		"For full information on the wallet, use \"getwalletinfo\"\n" +
+		"\nResult:\n" +
+		"[                         (json array of strings)\n" +
+		"  \"walletname\"            (string) the wallet name\n" +
+		"   ...\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("listwallets") +
+		HelpExampleRPC("listwallets")
+
+	walletcreatefundedpsbtDesc = "walletcreatefundedpsbt [{\"txid\":\"id\"," +
+		"\"vout\":n},...] {\"address\":amount,\"data\":\"hex\",...} " +
+		"( locktime ) ( options )\n" +
+		"\nCreates and funds a transaction in the Partially Signed " +
+		"Transaction format. Inputs will be added if supplied inputs are " +
+		"not enough\n" +
+		"Implements the Creator and Updater roles.\n" +
+		"\nArguments:\n" +
+		"1. \"inputs\"                (array, required) A json array of " +
+		"json objects\n" +
+		"     [\n" +
+		"       {\n" +
+		"         \"txid\":\"id\",      (string, required) The " +
+		"transaction id\n" +
+		"         \"vout\":n,         (numeric, required) The output " +
+		"number\n" +
+		"         \"sequence\":n      (numeric, optional) The sequence " +
+		"number\n" +
+		"       } \n" +
+		"       ,...\n" +
+		"     ]\n" +
+		"2. \"outputs\"               (object, required) a json object " +
+		"with outputs\n" +
+		"    {\n" +
+		"      \"address\": x.xxx,    (numeric or string, required) The " +
+		"key is the bitcoin address, the numeric value (can be string) " +
+		"is the BCH amount\n" +
+		"      \"data\": \"hex\"      (string, required) The key is " +
+		"\"data\", the value is hex encoded data\n" +
+		"      ,...\n" +
+		"    }\n" +
+		"3. locktime                  (numeric, optional, default=0) Raw " +
+		"locktime. Non-0 value also locktime-activates inputs\n" +
+		"4. options                 (object, optional)\n" +
+		"   {\n" +
+		"     \"changeAddress\"          (string, optional, default pool " +
+		"address) The bitcoin address to receive the change\n" +
+		"     \"changePosition\"         (numeric, optional, default " +
+		"random) The index of the change output\n" +
+		"     \"lockUnspents\"           (boolean, optional, default " +
+		"false) Lock selected unspent outputs\n" +
+		"     \"subtractFeeFromOutputs\" (array, optional) A json array " +
+		"of integers.\n" +
+		"                              The fee will be equally deducted " +
+		"from the amount of each specified output.\n" +
+		"                              The outputs are specified by their " +
+		"zero-based index, before any change output is added.\n" +
+		"     \"minconf\"                (numeric, optional, default 1) " +
+		"Only use coins with at least this many confirmations\n" +
+		"   }\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"psbt\": \"value\",        (string)  The resulting raw " +
+		"transaction (base64-encoded string)\n" +
+		"  \"fee\":       n,         (numeric) Fee in BCH the resulting " +
+		"transaction pays\n" +
+		"  \"changepos\": n          (numeric) The position of the added " +
+		"change output, or -1\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("walletcreatefundedpsbt", "\"[{\\\"txid\\\":\\\"myid\\\"," +
+			"\\\"vout\\\":0}]\" \"{\\\"data\\\":\\\"00010203\\\"}\"")
+
+	walletprocesspsbtDesc = "walletprocesspsbt \"psbt\" ( sign " +
+		"\"sighashtype\" )\n" +
+		"\nUpdate a PSBT with input information from our wallet and then " +
+		"sign inputs that we can sign for.\n" +
+		"\nArguments:\n" +
+		"1. \"psbt\"                      (string, required) The " +
+		"transaction base64 string\n" +
+		"2. sign                          (boolean, optional, " +
+		"default=true) Also sign the transaction when updating\n" +
+		"3. \"sighashtype\"            (string, optional, " +
+		"default=ALL|FORKID) The signature hash type to sign with if not " +
+		"specified by the PSBT. Must be one of\n" +
+		"       \"ALL|FORKID\"\n" +
+		"       \"NONE|FORKID\"\n" +
+		"       \"SINGLE|FORKID\"\n" +
+		"       \"ALL|FORKID|ANYONECANPAY\"\n" +
+		"       \"NONE|FORKID|ANYONECANPAY\"\n" +
+		"       \"SINGLE|FORKID|ANYONECANPAY\"\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"psbt\" : \"value\",          (string) The base64-encoded " +
+		"partially signed transaction\n" +
+		"  \"complete\" : true|false,   (boolean) If the transaction has a " +
+		"complete set of signatures\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("walletprocesspsbt", "\"psbt\"")
+
+	signmessageDesc = "signmessage \"address\" \"message\"\n" +
+		"\nSign a message with the private key of an address" +
+		"\nRequires wallet passphrase to be set with walletpassphrase " +
+		"call if the wallet is encrypted.\n" +
+		"\nArguments:\n" +
+		"1. \"address\"         (string, required) The bitcoin address to " +
+		"use for the private key.\n" +
+		"2. \"message\"         (string, required) The message to create a " +
+		"signature of.\n" +
+		"\nResult:\n" +
+		"\"signature\"          (string) The signature of the message " +
+		"encoded in base 64\n" +
+		"\nExamples:\n" +
+		"\nUnlock the wallet for 30 seconds\n" +
+		HelpExampleCli("walletpassphrase", "\"mypassphrase\"", "30") +
+		"\nCreate the signature\n" +
+		HelpExampleCli("signmessage", "\"1D1ZrZNe3JUo7ZycKEYQQiQAWd9y54F4XX\"", "\"my message\"") +
+		"\nVerify the signature\n" +
+		HelpExampleCli("verifymessage", "\"1D1ZrZNe3JUo7ZycKEYQQiQAWd9y54F4XX\"", "\"signature\"",
+			"\"my message\"") +
+		"\nAs json rpc\n" +
+		HelpExampleRPC("signmessage", "\"1D1ZrZNe3JUo7ZycKEYQQiQAWd9y54F4XX\"", "\"my message\"")
+
+	getaddressinfoDesc = "getaddressinfo \"address\"\n" +
+		"\nReturn information about the given bitcoin address. Some " +
+		"information requires the address to be in the wallet.\n" +
+		"\nArguments:\n" +
+		"1. \"address\"                    (string, required) The bitcoin " +
+		"address to get the information of.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"address\" : \"address\",        (string) The bitcoin address " +
+		"validated\n" +
+		"  \"scriptPubKey\" : \"hex\",       (string) The hex encoded " +
+		"scriptPubKey generated by the address\n" +
+		"  \"ismine\" : true|false,        (boolean) If the address is " +
+		"yours or not\n" +
+		"  \"iswatchonly\" : true|false,   (boolean) If the address is " +
+		"watchonly\n" +
+		"  \"isscript\" : true|false,      (boolean) If the key is a " +
+		"script\n" +
+		"  \"ischange\" : true|false,      (boolean) If the address was " +
+		"used for change output\n" +
+		"  \"script\" : \"type\"             (string, optional) The output " +
+		"script type. Only if \"isscript\" is true and the redeemscript is " +
+		"known. Possible types: nonstandard, pubkey, pubkeyhash, " +
+		"scripthash, multisig, nulldata\n" +
+		"  \"hex\" : \"hex\",                (string, optional) The " +
+		"redeemscript for the p2sh address\n" +
+		"  \"pubkeys\"                     (string, optional) Array of " +
+		"pubkeys associated with the known redeemscript (only if " +
+		"\"script\" is \"multisig\")\n" +
+		"    [\n" +
+		"      \"pubkey\"\n" +
+		"      ,...\n" +
+		"    ]\n" +
+		"  \"sigsrequired\" : xxxxx        (numeric, optional) Number of " +
+		"signatures required to spend multisig output (only if \"script\" " +
+		"is \"multisig\")\n" +
+		"  \"pubkey\" : \"publickeyhex\",    (string, optional) The hex " +
+		"value of the raw public key, for single-key addresses\n" +
+		"  \"iscompressed\" : true|false,  (boolean, optional) If the " +
+		"pubkey is compressed\n" +
+		"  \"label\" :  \"label\"         (string) The label associated " +
+		"with the address, \"\" is the default label\n" +
+		"  \"timestamp\" : timestamp,      (number, optional) The " +
+		"creation time of the key if available in seconds since epoch (Jan " +
+		"1 1970 GMT)\n" +
+		"  \"hdkeypath\" : \"keypath\"       (string, optional) The HD " +
+		"keypath if the key is HD and available\n" +
+		"  \"hdmasterkeyid\" : \"<hash160>\" (string, optional) The " +
+		"Hash160 of the HD master pubkey\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("getaddressinfo", "\"1PSSGeFHDnKNxiEyFrD1wcEaHr9hrQDDWc\"") +
+		HelpExampleRPC("getaddressinfo", "\"1PSSGeFHDnKNxiEyFrD1wcEaHr9hrQDDWc\"")
+
+	notifyblocksDesc = "notifyblocks\n" +
+		"\nRequest notifications for whenever a block is connected or " +
+		"disconnected from the main (best) chain.\n" +
+		"Websocket only. Notifications are sent as blockconnected and " +
+		"blockdisconnected, and as filteredblockconnected with the " +
+		"matching txs once a tx filter is loaded with loadtxfilter.\n"
+
+	stopnotifyblocksDesc = "stopnotifyblocks\n" +
+		"\nCancel registered notifications for whenever a block is " +
+		"connected or disconnected from the main (best) chain.\n" +
+		"Websocket only.\n"
+
+	notifynewtransactionsDesc = "notifynewtransactions ( verbose )\n" +
+		"\nSend either a txaccepted or a txacceptedverbose notification " +
+		"when a new transaction is accepted into the mempool.\n" +
+		"Websocket only.\n" +
+		"\nArguments:\n" +
+		"1. verbose    (boolean, optional, default=false) Specifies " +
+		"which type of notification to receive. If verbose is true, " +
+		"then the caller receives txacceptedverbose, otherwise the " +
+		"caller receives txaccepted\n"
+
+	stopnotifynewtransactionsDesc = "stopnotifynewtransactions\n" +
+		"\nStop sending either a txaccepted or a txacceptedverbose " +
+		"notification when a new transaction is accepted into the mempool.\n" +
+		"Websocket only.\n"
+
+	notifyreceivedDesc = "notifyreceived [\"address\",...]\n" +
+		"\nSend a recvtx notification when a transaction added to mempool " +
+		"or appears in a newly-attached block contains a txout pkScript " +
+		"sending to any of the passed addresses.\n" +
+		"Websocket only.\n" +
+		"\nArguments:\n" +
+		"1. \"addresses\"    (string, required) A json array of addresses " +
+		"to watch, legacy or cash addresses\n"
+
+	stopnotifyreceivedDesc = "stopnotifyreceived [\"address\",...]\n" +
+		"\nCancel registered receive notifications for each passed " +
+		"address.\n" +
+		"Websocket only.\n" +
+		"\nArguments:\n" +
+		"1. \"addresses\"    (string, required) A json array of addresses " +
+		"to stop watching\n"
+
+	loadtxfilterDesc = "loadtxfilter reload [\"address\",...] [{\"hash\":\"txid\",\"index\":n},...]\n" +
+		"\nLoad, add to, or reload a websocket client's transaction filter " +
+		"for mempool transactions and new blocks.\n" +
+		"Websocket only. Matching mempool txs are sent as " +
+		"relevanttxaccepted notifications. Outputs paying to a filtered " +
+		"address are added to the filter, so their spends match too.\n" +
+		"\nArguments:\n" +
+		"1. reload         (boolean, required) Load a new filter instead " +
+		"of adding data to an existing one\n" +
+		"2. \"addresses\"    (string, required) A json array of addresses " +
+		"to add to the filter\n" +
+		"3. \"outpoints\"    (string, required) A json array of unspent " +
+		"outpoints to add to the filter\n"
 )
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
+	"github.com/copernet/copernicus/crypto"
 	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/logic/lblock"
@@ -20,6 +21,7 @@ import (
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/model/versionbits"
+	"github.com/copernet/copernicus/model/wallet"
 	"github.com/copernet/copernicus/net/server"
 	"github.com/copernet/copernicus/persist"
 	"github.com/copernet/copernicus/rpc/btcjson"
@@ -36,7 +38,8 @@ var miningHandlers = map[string]commandHandler{
 	"submitblock":       handleSubmitBlock,
 	"generatetoaddress": handleGenerateToAddress,
 	"generate":          handleGenerate,
-	//"estimatefee":       handleEstimateFee,
+	"estimatefee":       handleEstimateFee,
+	"estimatesmartfee":  handleEstimateSmartFee,
 }
 
 func GetNetworkHashPS(lookup int32, height int32) float64 {
@@ -253,6 +256,7 @@ func blockTemplateResult(bt *mining.BlockTemplate, s *set.Set, maxVersionVb uint
 		indexInTemplate := i - 1
 		entry.Fee = int64(blocktemplate.TxFees[indexInTemplate])
 		entry.SigOps = int64(blocktemplate.TxSigOpsCount[indexInTemplate])
+		entry.SigChecks = int64(blocktemplate.TxSigChecks[indexInTemplate])
 
 		transactions = append(transactions, entry)
 	}
@@ -265,6 +269,7 @@ func blockTemplateResult(bt *mining.BlockTemplate, s *set.Set, maxVersionVb uint
 	coinbaseValue := bt.Block.Txs[0].GetTxOut(0).GetValue()
 	target := pow.CompactToBig(bt.Block.Header.Bits)
 	maxSigOps, _ := consensus.GetMaxBlockSigOpsCount(consensus.DefaultMaxBlockSize)
+	maxSigChecks := consensus.GetMaxBlockSigChecksCount(consensus.DefaultMaxBlockSize)
 	return &btcjson.GetBlockTemplateResult{
 		Capabilities:  []string{"proposal"},
 		Version:       bt.Block.Header.Version,
@@ -278,11 +283,12 @@ func blockTemplateResult(bt *mining.BlockTemplate, s *set.Set, maxVersionVb uint
 		Mutable:       mutable,
 		NonceRange:    "00000000ffffffff",
 		// FIXME: Allow for mining block greater than 1M.
-		SigOpLimit: int64(maxSigOps),
-		SizeLimit:  consensus.DefaultMaxBlockSize,
-		CurTime:    int64(bt.Block.Header.Time),
-		Bits:       fmt.Sprintf("%08x", bt.Block.Header.Bits),
-		Height:     int64(indexPrev.Height) + 1,
+		SigOpLimit:    int64(maxSigOps),
+		SigCheckLimit: int64(maxSigChecks),
+		SizeLimit:     consensus.DefaultMaxBlockSize,
+		CurTime:       int64(bt.Block.Header.Time),
+		Bits:          fmt.Sprintf("%08x", bt.Block.Header.Bits),
+		Height:        int64(indexPrev.Height) + 1,
 	}, nil
 }
 
@@ -464,7 +470,13 @@ func handleGenerate(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 		}
 	}
 
-	addr, err := lwallet.GetMiningAddress()
+	addr, err := lwallet.GetMiningAddress(wallet.GetInstance())
+	if err == wallet.ErrKeyPoolRanOut {
+		return nil, keypoolRanOutRPCError
+	}
+	if err == crypto.ErrKeyStoreLocked {
+		return nil, walletUnlockNeededRPCError
+	}
 	if err != nil {
 		log.Info("GetMiningAddress error:%s", err.Error())
 		return nil, btcjson.ErrRPCInternal
@@ -540,7 +552,7 @@ func generateBlocks(scriptPubKey *script.Script, generate int, maxTries uint64,
 
 		// TODO: simple implementation just for testing
 		if lwallet.IsWalletEnable() {
-			lwallet.AddToWallet(bt.Block.Txs[0], bt.Block.GetHash(), nil)
+			lwallet.AddToWallet(wallet.GetInstance(), bt.Block.Txs[0], bt.Block.GetHash(), nil)
 		}
 	}
 
@@ -554,26 +566,47 @@ func createBlockForCPUMining(ba *mining.BlockAssembler, scriptPK *script.Script,
 }
 
 // handleEstimateFee handles estimatefee commands.
-//func handleEstimateFee(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-//	c := cmd.(*btcjson.EstimateFeeCmd)
-//
-//	if s.cfg.FeeEstimator == nil {
-//		return nil, errors.New("Fee estimation disabled")
-//	}
-//
-//	if c.NumBlocks <= 0 {
-//		return -1.0, errors.New("Parameter NumBlocks must be positive")
-//	}
-//
-//	feeRate, err := s.cfg.FeeEstimator.EstimateFee(uint32(c.NumBlocks))
-//
-//	if err != nil {
-//		return -1.0, err
-//	}
-//
-//	// Convert to satoshis per kb.
-//	return float64(feeRate), nil
-//}
+func handleEstimateFee(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.EstimateFeeCmd)
+
+	feeEstimator := mempool.GetFeeEstimator()
+	if feeEstimator == nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCMisc, "Fee estimation disabled")
+	}
+
+	numBlocks := c.NumBlocks
+	if numBlocks < 1 {
+		numBlocks = 1
+	}
+
+	feeRate := feeEstimator.EstimateFee(int(numBlocks))
+	if feeRate.SataoshisPerK == 0 {
+		return -1.0, nil
+	}
+
+	return valueFromAmount(feeRate.GetFeePerK()), nil
+}
+
+// handleEstimateSmartFee handles estimatesmartfee commands.
+func handleEstimateSmartFee(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.EstimateSmartFeeCmd)
+
+	feeEstimator := mempool.GetFeeEstimator()
+	if feeEstimator == nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCMisc, "Fee estimation disabled")
+	}
+
+	feeRate, answerFoundAtTarget := feeEstimator.EstimateSmartFee(int(c.NumBlocks), mempool.GetInstance())
+	result := &btcjson.EstimateSmartFeeResult{
+		FeeRate: -1.0,
+		Blocks:  answerFoundAtTarget,
+	}
+	if feeRate.SataoshisPerK != 0 {
+		result.FeeRate = valueFromAmount(feeRate.GetFeePerK())
+	}
+
+	return result, nil
+}
 
 func registerMiningRPCCommands() {
 	for name, handler := range miningHandlers {
@@ -3,7 +3,6 @@ package rpc
 import (
 	"bytes"
 	"encoding/base64"
-	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"strconv"
@@ -11,15 +10,16 @@ import (
 
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/logic/lscript"
 	"github.com/copernet/copernicus/logic/lwallet"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/wallet"
 	"github.com/copernet/copernicus/net/server"
 	"github.com/copernet/copernicus/net/wire"
 	"github.com/copernet/copernicus/rpc/btcjson"
 	"github.com/copernet/copernicus/service"
 	"github.com/copernet/copernicus/util"
-	"github.com/copernet/copernicus/util/base58"
 	"github.com/copernet/copernicus/util/cashaddr"
 )
 
@@ -43,6 +43,7 @@ var miscHandlers = map[string]commandHandler{
 	"stop":                   handleStop,
 	"version":                handleVersion,
 	"uptime":                 handleUptime,
+	"getscriptcacheinfo":     handleGetScriptCacheInfo,
 }
 
 // handleUptime implements the uptime command.
@@ -50,6 +51,22 @@ func handleUptime(s *Server, cmd interface{}, closeChan <-chan struct{}) (interf
 	return util.GetTimeSec() - s.cfg.StartupTime, nil
 }
 
+func handleGetScriptCacheInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	return &btcjson.GetScriptCacheInfoResult{
+		SigCache:    cacheInfoResult(lscript.GetSigCache().Stats()),
+		ScriptCache: cacheInfoResult(lscript.GetScriptCache().Stats()),
+	}, nil
+}
+
+func cacheInfoResult(stats lscript.CacheStats) btcjson.CacheInfoResult {
+	return btcjson.CacheInfoResult{
+		Entries:    stats.Entries,
+		MaxEntries: stats.MaxEntries,
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+	}
+}
+
 func handleGetInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	best := chain.GetInstance().Tip()
 	var height int32
@@ -106,18 +123,26 @@ func handleValidateAddress(s *Server, cmd interface{}, closeChan <-chan struct{}
 	result.ScriptPubKey = hex.EncodeToString(scriptPubKey.GetData())
 
 	if lwallet.IsWalletEnable() {
+		w := wallet.GetInstance()
 		addrType, keyHash, _ := decodeAddress(c.Address)
 
-		result.IsMine = lwallet.IsMine(scriptPubKey)
-		result.IsWatchOnly = false // TODO:NOT support watch-only yet
-		result.Account = lwallet.GetAccountName(keyHash)
+		result.IsMine = lwallet.IsMine(w, scriptPubKey)
+		result.IsWatchOnly = lwallet.IsWatchOnly(w, scriptPubKey)
+		result.Account = lwallet.GetAccountName(w, keyHash)
 		result.IsScript = addrType == cashaddr.P2SH
 		if result.IsMine && !result.IsScript {
-			keyPair := lwallet.GetKeyPair(keyHash)
+			keyPair := lwallet.GetKeyPair(w, keyHash)
 			if keyPair != nil {
 				result.PubKey = keyPair.GetPublicKey().ToHexString()
 				result.IsCompressed = keyPair.GetPublicKey().Compressed
 			}
+			if meta := lwallet.GetKeyMetadata(w, keyHash); meta != nil {
+				result.TimeStamp = uint32(meta.CreateTime)
+				result.HDKeyPath = meta.HDKeyPath
+				if len(meta.HDSeedID) > 0 {
+					result.HDMasterKeyID = hex.EncodeToString(meta.HDSeedID)
+				}
+			}
 		}
 	}
 
@@ -128,85 +153,54 @@ func handleCreatemultisig(s *Server, cmd interface{}, closeChan <-chan struct{})
 	return nil, nil
 }
 
-func handleVerifyMessage(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	/*	c := cmd.(*btcjson.VerifyMessageCmd)
-
-		addr, err := bitaddr.AddressFromString(c.Address)
-		if err != nil {
-			return nil, btcjson.RPCError{
-				Code:    btcjson.ErrRPCType,
-				Message: "Invalid address",
-			}
-		}
+// strMessageMagic prefixes the signed messages, so that the signature of a
+// message can never be that of a transaction.
+const strMessageMagic = "Bitcoin Signed Message:\n"
 
-		hash160 := addr.EncodeToPubKeyHash()
-		if hash160 == nil {
-			return nil, btcjson.RPCError{
-				Code:    btcjson.ErrRPCType,
-				Message: "Address does not refer to key",
-			}
-		}
+// messageHash returns the hash signed by the signature of message.
+func messageHash(message string) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, len(strMessageMagic)+len(message)+10))
+	util.WriteVarString(buf, strMessageMagic)
+	util.WriteVarString(buf, message)
+	return util.DoubleSha256Bytes(buf.Bytes())
+}
 
-		data := []byte(strMessageMagic + c.Message)
-		hash := chainhash.DoubleHashB(data)
-		originBytes, err := base64.StdEncoding.DecodeString(c.Signature)
-		if err != nil {
-			return nil, btcjson.RPCError{
-				Code:    btcjson.RPCInvalidAddressOrKey,
-				Message: "Malformed base64 encoding",
-			}
-		}
+func handleVerifyMessage(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.VerifyMessageCmd)
 
-		var pk crypto.PublicKey
-		pk, wasCompressed, err := RecoverCompact(curveInstance, originBytes, hash) // todo realise
-		if err != nil {
-			return false, nil
-		}
+	addrType, keyHash, rpcErr := decodeAddress(c.Address)
+	if rpcErr != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCType, "Invalid address")
+	}
+	if addrType != cashaddr.P2PKH {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCType, "Address does not refer to key")
+	}
 
-		pubKeyBytes := pk.ToBytes()
-		addr2, err := bitaddr.AddressFromPublicKey(pubKeyBytes)
-		if err != nil {
-			return nil, btcjson.RPCError{
-				Code:    btcjson.RPCInvalidAddressOrKey,
-				Message: "Invalid Public Key encoding",
-			}
-		}
+	signature, err := base64.StdEncoding.DecodeString(c.Signature)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Malformed base64 encoding")
+	}
 
-		return bytes.Equal(addr2.EncodeToPubKeyHash(), hash160), nil*/ //todo open
-	return nil, nil
+	pubKey, err := crypto.RecoverCompact(signature, messageHash(c.Message))
+	if err != nil {
+		return false, nil
+	}
+	return bytes.Equal(util.Hash160(pubKey.ToBytes()), keyHash), nil
 }
 
 func handleSignMessageWithPrivkey(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.SignMessageWithPrivkeyCmd)
 
-	bs, _, err := base58.CheckDecode(c.Privkey)
+	privKey, err := crypto.DecodePrivateKey(c.Privkey)
 	if err != nil {
-		return nil, btcjson.RPCError{
-			Code:    btcjson.RPCInvalidAddressOrKey,
-			Message: "Invalid private key",
-		}
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Invalid private key")
 	}
-	privKey := crypto.PrivateKeyFromBytes(bs)
 
-	buf := bytes.NewBuffer(make([]byte, 0, 4+len(c.Message)))
-	err = util.BinarySerializer.PutUint32(buf, binary.LittleEndian, uint32(chain.GetInstance().GetParams().BitcoinNet))
+	signature, err := privKey.SignCompact(messageHash(c.Message))
 	if err != nil {
-		return nil, btcjson.RPCError{
-			Code:    btcjson.RPCInternalError,
-			Message: "serialize BitcoinNet error",
-		}
-	}
-	buf.Write([]byte(c.Message))
-
-	originBytes := util.DoubleSha256Bytes(buf.Bytes())
-	signature, err := privKey.Sign(originBytes)
-	if err != nil {
-		return nil, btcjson.RPCError{
-			Code:    btcjson.RPCInvalidAddressOrKey,
-			Message: "Sign failed",
-		}
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Sign failed")
 	}
-	return base64.StdEncoding.EncodeToString(signature.Serialize()), nil
+	return base64.StdEncoding.EncodeToString(signature), nil
 }
 
 func handleSetMocktime(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
@@ -71,32 +71,39 @@ func handleGetPeerInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (i
 	for _, item := range peers {
 		statsSnap := item.ToPeer().StatsSnapshot()
 		info := &btcjson.GetPeerInfoResult{
-			ID:              statsSnap.ID,
-			Addr:            statsSnap.Addr,
-			AddrLocal:       item.ToPeer().LocalAddr().String(),
-			Services:        fmt.Sprintf("%016x", uint64(statsSnap.Services)),
-			RelayTxes:       !item.IsTxRelayDisabled(),
-			LastSend:        statsSnap.LastSend.Unix(),
-			LastRecv:        statsSnap.LastRecv.Unix(),
-			BytesSent:       statsSnap.BytesSent,
-			BytesRecv:       statsSnap.BytesRecv,
-			ConnTime:        statsSnap.ConnTime.Unix(),
-			TimeOffset:      statsSnap.TimeOffset,
-			PingTime:        float64(statsSnap.LastPingMicros),
-			MinPing:         statsSnap.MingPing,
-			Version:         statsSnap.Version,
-			SubVer:          statsSnap.UserAgent,
-			Inbound:         statsSnap.Inbound,
-			AddNode:         statsSnap.AddNode,
-			StartingHeight:  statsSnap.StartingHeight,
-			BanScore:        int32(item.BanScore()), // TODO
-			SyncedHeaders:   statsSnap.SyncedHeaders,
-			SyncedBlocks:    statsSnap.SyncedBlocks,
-			Inflight:        statsSnap.Inflight,
-			WhiteListed:     statsSnap.WhiteListed,
-			CashMagic:       statsSnap.UsesCashMagic,
-			BytesSendPerMsg: statsSnap.MapSendBytesPerMsgCmd,
-			BytesRecvPerMsg: statsSnap.MapRecvBytesPerMsgCmd,
+			ID:                       statsSnap.ID,
+			Addr:                     statsSnap.Addr,
+			AddrLocal:                item.ToPeer().LocalAddr().String(),
+			Services:                 fmt.Sprintf("%016x", uint64(statsSnap.Services)),
+			RelayTxes:                !item.IsTxRelayDisabled(),
+			LastSend:                 statsSnap.LastSend.Unix(),
+			LastRecv:                 statsSnap.LastRecv.Unix(),
+			BytesSent:                statsSnap.BytesSent,
+			BytesRecv:                statsSnap.BytesRecv,
+			ConnTime:                 statsSnap.ConnTime.Unix(),
+			TimeOffset:               statsSnap.TimeOffset,
+			PingTime:                 float64(statsSnap.LastPingMicros),
+			MinPing:                  statsSnap.MingPing,
+			Version:                  statsSnap.Version,
+			SubVer:                   statsSnap.UserAgent,
+			Inbound:                  statsSnap.Inbound,
+			AddNode:                  statsSnap.AddNode,
+			StartingHeight:           statsSnap.StartingHeight,
+			BanScore:                 int32(item.BanScore()),
+			SyncedHeaders:            statsSnap.SyncedHeaders,
+			SyncedBlocks:             statsSnap.SyncedBlocks,
+			Inflight:                 statsSnap.Inflight,
+			WhiteListed:              statsSnap.WhiteListed,
+			CashMagic:                statsSnap.UsesCashMagic,
+			CmpctVersion:             statsSnap.CmpctVersion,
+			CmpctHighBandwidthTo:     statsSnap.CmpctHighBandwidthTo,
+			CmpctHighBandwidthFrom:   statsSnap.CmpctHighBandwidthFrom,
+			CmpctBlocksReceived:      statsSnap.CmpctBlocksReceived,
+			CmpctBlocksReconstructed: statsSnap.CmpctBlocksReconstructed,
+			CmpctBlockTxnRequests:    statsSnap.CmpctBlockTxnRequests,
+			CmpctBlocksFailed:        statsSnap.CmpctBlocksFailed,
+			BytesSendPerMsg:          statsSnap.MapSendBytesPerMsgCmd,
+			BytesRecvPerMsg:          statsSnap.MapRecvBytesPerMsgCmd,
 		}
 		if item.ToPeer().LastPingNonce() != 0 {
 			wait := float64(time.Since(statsSnap.LastPingTime).Nanoseconds())
@@ -16,12 +16,14 @@ import (
 	"github.com/copernet/copernicus/logic/lmempool"
 	"github.com/copernet/copernicus/logic/lmerkleblock"
 	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/logic/ltxindex"
 	"github.com/copernet/copernicus/logic/lwallet"
 	"github.com/copernet/copernicus/model/blockindex"
 	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/mempool"
 	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/psbt"
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/model/txin"
@@ -43,6 +45,9 @@ var rawTransactionHandlers = map[string]commandHandler{
 	"decodescript":         handleDecodeScript,         // complete
 	"sendrawtransaction":   handleSendRawTransaction,   // complete
 	"signrawtransaction":   handleSignRawTransaction,   // partial complete
+	"combinepsbt":          handleCombinePsbt,          // complete
+	"finalizepsbt":         handleFinalizePsbt,         // complete
+	"decodepsbt":           handleDecodePsbt,           // complete
 	"gettxoutproof":        handleGetTxoutProof,        // complete
 	"verifytxoutproof":     handleVerifyTxoutProof,     // complete
 }
@@ -141,6 +146,11 @@ func getVinList(tx *tx.Tx) []btcjson.Vin {
 
 // getVoutList returns a slice of JSON objects for the outputs of the passed transaction.
 func getVoutList(tx *tx.Tx) []btcjson.Vout {
+	// The token prefix of the outputs is only read as such once tokens are
+	// enabled, as it is by the mempool.
+	if ltx.IsTokensEnabled(chain.GetInstance().Tip()) {
+		tx.ReadTokenData()
+	}
 	voutList := make([]btcjson.Vout, tx.GetOutsCount())
 	for i := 0; i < tx.GetOutsCount(); i++ {
 		out := tx.GetTxOut(i)
@@ -149,11 +159,31 @@ func getVoutList(tx *tx.Tx) []btcjson.Vout {
 			Value:        valueFromAmount(int64(out.GetValue())),
 			N:            uint32(i),
 			ScriptPubKey: *scriptPubKeyJSON,
+			TokenData:    TokenDataToJSON(out.GetTokenData()),
 		}
 	}
 	return voutList
 }
 
+// TokenDataToJSON returns the JSON object of the token data of an output, nil
+// if it carries none.
+func TokenDataToJSON(td *txout.TokenData) *btcjson.TokenDataResult {
+	if td == nil {
+		return nil
+	}
+	result := &btcjson.TokenDataResult{
+		Category: td.Category.String(),
+		Amount:   strconv.FormatInt(td.Amount, 10),
+	}
+	if td.HasNFT {
+		result.NFT = &btcjson.TokenNFTResult{
+			Capability: td.CapabilityString(),
+			Commitment: hex.EncodeToString(td.Commitment),
+		}
+	}
+	return result
+}
+
 func ScriptToAsmStr(s *script.Script, attemptSighashDecode bool) string {
 	var str string
 	for _, scriptOpcodes := range s.ParsedOpCodes {
@@ -253,6 +283,8 @@ func GetTxnOutputType(sType int) string {
 		return "multisig"
 	case script.ScriptNullData:
 		return "nulldata"
+	case script.ScriptHash32:
+		return "scripthash32"
 	default:
 		return "unknown"
 	}
@@ -264,10 +296,11 @@ func GetTransaction(hash *util.Hash, allowSlow bool) (*tx.Tx, *util.Hash, bool)
 		return entry.Tx, nil, true
 	}
 
-	/* TODO: NOT support txindex yet
-	if chain.GTxIndex {
-		chain.GBlockTree.ReadTxIndex(hash)
-	}*/
+	if ltxindex.Enabled() {
+		if txn, hashBlock, ok := ltxindex.GetTransaction(hash); ok {
+			return txn, hashBlock, true
+		}
+	}
 
 	if !allowSlow {
 		return nil, nil, false
@@ -296,13 +329,36 @@ func GetTransaction(hash *util.Hash, allowSlow bool) (*tx.Tx, *util.Hash, bool)
 func handleCreateRawTransaction(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.CreateRawTransactionCmd)
 
+	transaction, rpcErr := constructTransaction(c.Inputs, c.Outputs, c.LockTime)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := transaction.Serialize(buf)
+	if err != nil {
+		log.Error("rawTransaction:serialize tx failed: %v", err)
+		return "", btcjson.ErrRPCInternal
+	}
+
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// constructTransaction returns the unsigned transaction spending inputs to
+// outputs, as createrawtransaction describes them.
+func constructTransaction(inputs []btcjson.TransactionInput, outputs map[string]btcjson.AmountType,
+	lockTimeParam *int64) (*tx.Tx, *btcjson.RPCError) {
+
 	lockTime := uint32(0)
-	if c.LockTime != nil && (*c.LockTime < 0 || *c.LockTime > int64(script.SequenceFinal)) {
-		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "LockTime out of range")
+	if lockTimeParam != nil {
+		if *lockTimeParam < 0 || *lockTimeParam > int64(script.SequenceFinal) {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "LockTime out of range")
+		}
+		lockTime = uint32(*lockTimeParam)
 	}
 	transaction := tx.NewTx(lockTime, tx.DefaultVersion)
 
-	for _, input := range c.Inputs {
+	for _, input := range inputs {
 		txIn, rpcErr := createRawTxInput(&input, lockTime)
 		if rpcErr != nil {
 			return nil, rpcErr
@@ -310,22 +366,14 @@ func handleCreateRawTransaction(s *Server, cmd interface{}, closeChan <-chan str
 		transaction.AddTxIn(txIn)
 	}
 
-	for address, cost := range c.Outputs {
+	for address, cost := range outputs {
 		txOut, rpcErr := createRawTxOutput(address, cost)
 		if rpcErr != nil {
 			return nil, rpcErr
 		}
 		transaction.AddTxOut(txOut)
 	}
-
-	buf := bytes.NewBuffer(nil)
-	err := transaction.Serialize(buf)
-	if err != nil {
-		log.Error("rawTransaction:serialize tx failed: %v", err)
-		return "", btcjson.ErrRPCInternal
-	}
-
-	return hex.EncodeToString(buf.Bytes()), nil
+	return transaction, nil
 }
 
 func createRawTxInput(input *btcjson.TransactionInput, lockTime uint32) (*txin.TxIn, *btcjson.RPCError) {
@@ -651,24 +699,29 @@ func handleSignRawTransaction(s *Server, cmd interface{}, closeChan <-chan struc
 	if rpcErr != nil {
 		return nil, rpcErr
 	}
+	// The token prefix of the coins is signed as their token data once tokens
+	// are enabled.
+	if ltx.IsTokensEnabled(chain.GetInstance().Tip()) {
+		for _, in := range mergedTx.GetIns() {
+			if coin := coinsMap.GetCoin(in.PreviousOutPoint); coin != nil {
+				coin.ReadTokenData()
+			}
+		}
+	}
 
 	keyStore, rpcErr := getKeys(c.PrivKeys, coinsMap, redeemScripts)
 	if rpcErr != nil {
 		return nil, rpcErr
 	}
 
-	hashType := crypto.SigHashAll | crypto.SigHashForkID
+	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
 	if c.SigHashType != nil {
-		var ok bool
-		if hashType, ok = mapSigHashValues[*c.SigHashType]; !ok {
-			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Invalid sighash param")
-		}
-		if hashType&crypto.SigHashForkID == 0 {
-			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Signature must use SIGHASH_FORKID")
+		if hashType, rpcErr = parseSigHashType(*c.SigHashType); rpcErr != nil {
+			return nil, rpcErr
 		}
 	}
 
-	signErrors := ltx.SignRawTransaction(txVariants, redeemScripts, keyStore, coinsMap, uint32(hashType))
+	signErrors := ltx.SignRawTransaction(txVariants, redeemScripts, keyStore, coinsMap, hashType)
 	errors := make([]*btcjson.SignRawTransactionError, 0, len(signErrors))
 	for _, signErr := range signErrors {
 		errors = append(errors, TxInErrorToJSON(signErr.TxIn, signErr.ErrMsg))
@@ -688,6 +741,19 @@ func handleSignRawTransaction(s *Server, cmd interface{}, closeChan <-chan struc
 	}, err
 }
 
+// parseSigHashType returns the hash type named by sigHashType, which must
+// include SIGHASH_FORKID.
+func parseSigHashType(sigHashType string) (uint32, *btcjson.RPCError) {
+	hashType, ok := mapSigHashValues[sigHashType]
+	if !ok {
+		return 0, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Invalid sighash param")
+	}
+	if hashType&crypto.SigHashForkID == 0 {
+		return 0, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Signature must use SIGHASH_FORKID")
+	}
+	return uint32(hashType), nil
+}
+
 func getCoins(txIns []*txin.TxIn, prevTxs *[]btcjson.RawTxInput) (*utxo.CoinsMap,
 	map[outpoint.OutPoint]*script.Script, *btcjson.RPCError) {
 	coinsMap := utxo.NewEmptyCoinsMap()
@@ -790,6 +856,9 @@ func getKeys(privateKeys *[]string, coinsMap *utxo.CoinsMap,
 			keyStore.AddKey(privateKey)
 		}
 	} else if lwallet.IsWalletEnable() {
+		if rpcErr := ensureWalletIsUnlocked(wallet.GetInstance()); rpcErr != nil {
+			return nil, rpcErr
+		}
 		pubKeyHashList := make([][]byte, 0)
 		for _, coin := range coinsMap.GetMap() {
 			pubKeyHash := getPubKeyHash(coin.GetScriptPubKey())
@@ -799,7 +868,7 @@ func getKeys(privateKeys *[]string, coinsMap *utxo.CoinsMap,
 			pubKeyHash := getPubKeyHash(redeemScript)
 			pubKeyHashList = append(pubKeyHashList, pubKeyHash...)
 		}
-		keyPairs := lwallet.GetKeyPairs(pubKeyHashList)
+		keyPairs := lwallet.GetKeyPairs(wallet.GetInstance(), pubKeyHashList)
 		keyStore.AddKeyPairs(keyPairs)
 	}
 	return keyStore, nil
@@ -815,6 +884,175 @@ func TxInErrorToJSON(in *txin.TxIn, errorMessage string) *btcjson.SignRawTransac
 	}
 }
 
+func handleCombinePsbt(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.CombinePsbtCmd)
+
+	if len(c.Txs) == 0 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Parameter 'txs' cannot be empty")
+	}
+	psbts := make([]*psbt.PSBT, 0, len(c.Txs))
+	for _, str := range c.Txs {
+		p, rpcErr := decodePsbt(str)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		psbts = append(psbts, p)
+	}
+
+	merged, err := psbt.Combine(psbts)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, err.Error())
+	}
+	return encodePsbt(merged)
+}
+
+func handleFinalizePsbt(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.FinalizePsbtCmd)
+
+	p, rpcErr := decodePsbt(c.Psbt)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	complete, err := ltx.FinalizePSBT(p)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCDeserialization, err.Error())
+	}
+
+	if complete && *c.Extract {
+		transaction, err := p.Extract()
+		if err != nil {
+			log.Error("rawTransaction:extract psbt failed: %v", err)
+			return nil, btcjson.ErrRPCInternal
+		}
+		buf := bytes.NewBuffer(nil)
+		if err := transaction.Serialize(buf); err != nil {
+			log.Error("rawTransaction:serialize tx failed: %v", err)
+			return nil, btcjson.ErrRPCInternal
+		}
+		return &btcjson.FinalizePsbtResult{
+			Hex:      hex.EncodeToString(buf.Bytes()),
+			Complete: true,
+		}, nil
+	}
+
+	encoded, rpcErr := encodePsbt(p)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return &btcjson.FinalizePsbtResult{
+		Psbt:     encoded,
+		Complete: complete,
+	}, nil
+}
+
+func handleDecodePsbt(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.DecodePsbtCmd)
+
+	p, rpcErr := decodePsbt(c.Psbt)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	txHash := p.Tx.GetHash()
+	result := &btcjson.DecodePsbtResult{
+		Tx: btcjson.TxRawDecodeResult{
+			Txid:     txHash.String(),
+			Hash:     txHash.String(),
+			Size:     p.Tx.SerializeSize(),
+			Version:  p.Tx.GetVersion(),
+			Locktime: p.Tx.GetLockTime(),
+			Vin:      getVinList(p.Tx),
+			Vout:     getVoutList(p.Tx),
+		},
+		Unknown: psbtUnknownToJSON(p.Unknown),
+		Inputs:  make([]btcjson.DecodePsbtInput, 0, len(p.Inputs)),
+		Outputs: make([]btcjson.DecodePsbtOutput, 0, len(p.Outputs)),
+	}
+
+	for _, in := range p.Inputs {
+		input := btcjson.DecodePsbtInput{}
+		if in.UTXO != nil {
+			input.UTXO = &btcjson.PsbtUTXO{
+				Amount:       valueFromAmount(int64(in.UTXO.GetValue())),
+				ScriptPubKey: *ScriptPubKeyToJSON(in.UTXO.GetScriptPubKey(), true),
+			}
+		}
+		if len(in.PartialSigs) > 0 {
+			input.PartialSignatures = make(map[string]string, len(in.PartialSigs))
+			for pubKey, sig := range in.PartialSigs {
+				input.PartialSignatures[hex.EncodeToString([]byte(pubKey))] = hex.EncodeToString(sig)
+			}
+		}
+		if in.SigHashType != 0 {
+			input.SigHash = sigHashTypeToStr(in.SigHashType)
+		}
+		if in.RedeemScript != nil {
+			input.RedeemScript = ScriptPubKeyToJSON(in.RedeemScript, true)
+		}
+		if in.FinalScriptSig != nil {
+			input.FinalScriptSig = &btcjson.ScriptSig{
+				Asm: ScriptToAsmStr(in.FinalScriptSig, true),
+				Hex: hex.EncodeToString(in.FinalScriptSig.GetData()),
+			}
+		}
+		if len(in.Unknown) > 0 {
+			input.Unknown = psbtUnknownToJSON(in.Unknown)
+		}
+		result.Inputs = append(result.Inputs, input)
+	}
+
+	for _, out := range p.Outputs {
+		output := btcjson.DecodePsbtOutput{}
+		if out.RedeemScript != nil {
+			output.RedeemScript = ScriptPubKeyToJSON(out.RedeemScript, true)
+		}
+		if len(out.Unknown) > 0 {
+			output.Unknown = psbtUnknownToJSON(out.Unknown)
+		}
+		result.Outputs = append(result.Outputs, output)
+	}
+
+	if fee, ok := p.GetFee(); ok {
+		value := valueFromAmount(int64(fee))
+		result.Fee = &value
+	}
+	return result, nil
+}
+
+func decodePsbt(str string) (*psbt.PSBT, *btcjson.RPCError) {
+	p, err := psbt.DecodeBase64(str)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCDeserialization, "TX decode failed "+err.Error())
+	}
+	return p, nil
+}
+
+func encodePsbt(p *psbt.PSBT) (string, *btcjson.RPCError) {
+	encoded, err := p.EncodeBase64()
+	if err != nil {
+		log.Error("rawTransaction:serialize psbt failed: %v", err)
+		return "", btcjson.ErrRPCInternal
+	}
+	return encoded, nil
+}
+
+func psbtUnknownToJSON(unknown map[string][]byte) map[string]string {
+	result := make(map[string]string, len(unknown))
+	for key, value := range unknown {
+		result[hex.EncodeToString([]byte(key))] = hex.EncodeToString(value)
+	}
+	return result
+}
+
+func sigHashTypeToStr(hashType uint32) string {
+	for desc, value := range mapSigHashValues {
+		if uint32(value) == hashType {
+			return desc
+		}
+	}
+	return strconv.FormatUint(uint64(hashType), 10)
+}
+
 func handleGetTxoutProof(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetTxOutProofCmd)
 
@@ -28,6 +28,7 @@ func registerAllRPCCommands() {
 	registerMiscRPCCommands()
 	registerNetRPCCommands()
 	registerRawTransactionRPCCommands()
+	registerZMQRPCCommands()
 	if conf.Cfg.Wallet.Enable {
 		registerWalletRPCCommands()
 	}
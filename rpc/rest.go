@@ -0,0 +1,444 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	// maxGetUtxosOutpoints is the maximum number of outpoints which can be
+	// queried by a single getutxos request.
+	maxGetUtxosOutpoints = 15
+
+	// maxRestHeaders is the maximum number of headers returned by a single
+	// headers request.
+	maxRestHeaders = 2000
+
+	// restMempoolHeight is the height reported for outputs of mempool
+	// transactions, as bitcoind does.
+	restMempoolHeight = 0x7fffffff
+)
+
+type restFormat int
+
+const (
+	restFormatUndef restFormat = iota
+	restFormatBinary
+	restFormatHex
+	restFormatJSON
+)
+
+var restFormats = map[string]restFormat{
+	"bin":  restFormatBinary,
+	"hex":  restFormatHex,
+	"json": restFormatJSON,
+}
+
+const restAvailableFormats = ".bin, .hex, .json"
+
+type restHandler func(w http.ResponseWriter, param string)
+
+// restHandlers maps the path prefixes of the REST interface to their
+// handlers.  Longer prefixes must come before the prefixes they extend.
+var restHandlers = []struct {
+	prefix  string
+	handler restHandler
+}{
+	{"/rest/tx/", restTx},
+	{"/rest/block/notxdetails/", restBlockNoTxDetails},
+	{"/rest/block/", restBlock},
+	{"/rest/chaininfo", restChainInfo},
+	{"/rest/mempool/info", restMempoolInfo},
+	{"/rest/mempool/contents", restMempoolContents},
+	{"/rest/headers/", restHeaders},
+	{"/rest/getutxos", restGetUtxos},
+}
+
+// restHandler serves the unauthenticated, read only REST interface.
+func (s *Server) restHandler(w http.ResponseWriter, r *http.Request) {
+	r.Close = true
+	w.Header().Set("Connection", "close")
+
+	// Limit the number of connections to max allowed.
+	if s.limitConnections(w, r.RemoteAddr) {
+		return
+	}
+	s.incrementClients()
+	defer s.decrementClients()
+
+	if r.Method != http.MethodGet {
+		restError(w, http.StatusMethodNotAllowed, "Only GET requests are supported")
+		return
+	}
+
+	for _, h := range restHandlers {
+		if strings.HasPrefix(r.URL.Path, h.prefix) {
+			h.handler(w, r.URL.Path[len(h.prefix):])
+			return
+		}
+	}
+	restError(w, http.StatusNotFound, "Not found")
+}
+
+func restError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(status)
+	io.WriteString(w, message+"\r\n")
+}
+
+// parseRestFormat splits the format extension off param.
+func parseRestFormat(param string) (string, restFormat) {
+	idx := strings.LastIndex(param, ".")
+	if idx < 0 {
+		return param, restFormatUndef
+	}
+	format, ok := restFormats[param[idx+1:]]
+	if !ok {
+		return param, restFormatUndef
+	}
+	return param[:idx], format
+}
+
+// restRespond writes the reply in the requested format, raw being the binary
+// serialization of the reply and result its JSON object.
+func restRespond(w http.ResponseWriter, format restFormat, raw []byte, result interface{}) {
+	switch format {
+	case restFormatBinary:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+
+	case restFormatHex:
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, hex.EncodeToString(raw)+"\n")
+
+	case restFormatJSON:
+		reply, err := json.Marshal(result)
+		if err != nil {
+			log.Error("Failed to marshal REST reply: %v", err)
+			restError(w, http.StatusInternalServerError, "Failed to marshal reply")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(reply)
+		io.WriteString(w, "\n")
+
+	default:
+		restError(w, http.StatusNotFound, "output format not found (available: "+restAvailableFormats+")")
+	}
+}
+
+// restRespondJSON writes the reply of an endpoint only available as JSON.
+func restRespondJSON(w http.ResponseWriter, param string, result func() (interface{}, error)) {
+	if _, format := parseRestFormat(param); format != restFormatJSON {
+		restError(w, http.StatusNotFound, "output format not found (available: json)")
+		return
+	}
+	reply, err := result()
+	if err != nil {
+		restError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	restRespond(w, restFormatJSON, nil, reply)
+}
+
+func restTx(w http.ResponseWriter, param string) {
+	hashStr, format := parseRestFormat(param)
+	hash, err := util.GetHashFromStr(hashStr)
+	if err != nil {
+		restError(w, http.StatusBadRequest, "Invalid hash: "+hashStr)
+		return
+	}
+
+	txn, hashBlock, ok := GetTransaction(hash, true)
+	if !ok {
+		restError(w, http.StatusNotFound, hashStr+" not found")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := txn.Serialize(&buf); err != nil {
+		restError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var result interface{}
+	if format == restFormatJSON {
+		txReply, rpcErr := getTxRawResult(txn, hashBlock, hex.EncodeToString(buf.Bytes()))
+		if rpcErr != nil {
+			restError(w, http.StatusInternalServerError, rpcErr.Message)
+			return
+		}
+		result = txReply
+	}
+	restRespond(w, format, buf.Bytes(), result)
+}
+
+func restBlock(w http.ResponseWriter, param string) {
+	restBlockCommon(w, param, true)
+}
+
+func restBlockNoTxDetails(w http.ResponseWriter, param string) {
+	restBlockCommon(w, param, false)
+}
+
+func restBlockCommon(w http.ResponseWriter, param string, txDetails bool) {
+	hashStr, format := parseRestFormat(param)
+	hash, err := util.GetHashFromStr(hashStr)
+	if err != nil {
+		restError(w, http.StatusBadRequest, "Invalid hash: "+hashStr)
+		return
+	}
+
+	blk, blockIndex, rpcErr := readBlock(hash)
+	if rpcErr != nil {
+		restError(w, http.StatusNotFound, hashStr+" not found: "+rpcErr.Message)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := blk.Serialize(&buf); err != nil {
+		restError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var result interface{}
+	if format == restFormatJSON {
+		blockReply := blockToJSON(blk, blockIndex)
+		result = blockReply
+		if txDetails {
+			txs := make([]btcjson.TxRawResult, 0, len(blk.Txs))
+			for _, txn := range blk.Txs {
+				var txBuf bytes.Buffer
+				txn.Serialize(&txBuf)
+				txReply, rpcErr := getTxRawResult(txn, hash, hex.EncodeToString(txBuf.Bytes()))
+				if rpcErr != nil {
+					restError(w, http.StatusInternalServerError, rpcErr.Message)
+					return
+				}
+				txs = append(txs, *txReply)
+			}
+			result = &btcjson.GetBlockVerboseTxResult{
+				GetBlockVerboseResult: *blockReply,
+				Tx:                    txs,
+			}
+		}
+	}
+	restRespond(w, format, buf.Bytes(), result)
+}
+
+// restHeaders serves /rest/headers/<count>/<hash>.<ext>, the headers of the
+// active chain starting with the passed block.
+func restHeaders(w http.ResponseWriter, param string) {
+	param, format := parseRestFormat(param)
+	path := strings.Split(param, "/")
+	if len(path) != 2 {
+		restError(w, http.StatusBadRequest, "No header count specified. Use /rest/headers/<count>/<hash>.<ext>.")
+		return
+	}
+
+	count, err := strconv.Atoi(path[0])
+	if err != nil || count < 1 || count > maxRestHeaders {
+		restError(w, http.StatusBadRequest, "Header count out of range: "+path[0])
+		return
+	}
+	hash, err := util.GetHashFromStr(path[1])
+	if err != nil {
+		restError(w, http.StatusBadRequest, "Invalid hash: "+path[1])
+		return
+	}
+
+	gChain := chain.GetInstance()
+	var buf bytes.Buffer
+	headers := make([]*btcjson.GetBlockHeaderVerboseResult, 0, count)
+	blockIndex := gChain.FindBlockIndex(*hash)
+	for blockIndex != nil && gChain.Contains(blockIndex) && len(headers) < count {
+		if err := blockIndex.Header.Serialize(&buf); err != nil {
+			restError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		headers = append(headers, blockHeaderToJSON(blockIndex))
+		blockIndex = gChain.Next(blockIndex)
+	}
+	restRespond(w, format, buf.Bytes(), headers)
+}
+
+func restChainInfo(w http.ResponseWriter, param string) {
+	restRespondJSON(w, param, func() (interface{}, error) {
+		return handleGetBlockChainInfo(nil, nil, nil)
+	})
+}
+
+func restMempoolInfo(w http.ResponseWriter, param string) {
+	restRespondJSON(w, param, func() (interface{}, error) {
+		return handleGetMempoolInfo(nil, nil, nil)
+	})
+}
+
+func restMempoolContents(w http.ResponseWriter, param string) {
+	restRespondJSON(w, param, func() (interface{}, error) {
+		verbose := true
+		return handleGetRawMempool(nil, &btcjson.GetRawMempoolCmd{Verbose: &verbose}, nil)
+	})
+}
+
+// restGetUtxos serves /rest/getutxos[/checkmempool]/<txid>-<n>/....<ext>,
+// looking the passed outpoints up in the utxo set and, with checkmempool, in
+// the mempool as well.
+func restGetUtxos(w http.ResponseWriter, param string) {
+	param, format := parseRestFormat(param)
+
+	var path []string
+	for _, p := range strings.Split(param, "/") {
+		if p != "" {
+			path = append(path, p)
+		}
+	}
+	checkMempool := len(path) > 0 && path[0] == "checkmempool"
+	if checkMempool {
+		path = path[1:]
+	}
+
+	if len(path) == 0 {
+		restError(w, http.StatusBadRequest, "Error: empty request")
+		return
+	}
+	if len(path) > maxGetUtxosOutpoints {
+		restError(w, http.StatusBadRequest, fmt.Sprintf(
+			"Error: max outpoints exceeded (max: %d, tried: %d)", maxGetUtxosOutpoints, len(path)))
+		return
+	}
+
+	outPoints := make([]*outpoint.OutPoint, 0, len(path))
+	for _, p := range path {
+		idx := strings.LastIndex(p, "-")
+		if idx < 0 {
+			restError(w, http.StatusBadRequest, "Parse error")
+			return
+		}
+		hash, err := util.GetHashFromStr(p[:idx])
+		if err != nil {
+			restError(w, http.StatusBadRequest, "Parse error")
+			return
+		}
+		n, err := strconv.ParseUint(p[idx+1:], 10, 32)
+		if err != nil {
+			restError(w, http.StatusBadRequest, "Parse error")
+			return
+		}
+		outPoints = append(outPoints, outpoint.NewOutPoint(*hash, uint32(n)))
+	}
+	if format == restFormatUndef {
+		restError(w, http.StatusNotFound, "output format not found (available: "+restAvailableFormats+")")
+		return
+	}
+
+	coinView := utxo.GetUtxoCacheInstance()
+	pool := mempool.GetInstance()
+	bitmap := make([]byte, (len(outPoints)+7)/8)
+	bitmapStr := make([]byte, 0, len(outPoints))
+	coins := make([]*utxo.Coin, 0, len(outPoints))
+	for i, out := range outPoints {
+		coin := coinView.GetCoin(out)
+		if checkMempool {
+			if coin == nil {
+				coin = pool.GetCoin(out)
+			}
+			if coin != nil && pool.HasSpentOut(out) {
+				coin = nil
+			}
+		}
+
+		if coin == nil || coin.IsSpent() {
+			bitmapStr = append(bitmapStr, '0')
+			continue
+		}
+		bitmap[i/8] |= 1 << uint(i%8)
+		bitmapStr = append(bitmapStr, '1')
+		coins = append(coins, coin)
+	}
+
+	gChain := chain.GetInstance()
+	tip := gChain.Tip()
+	tipHash := tip.GetBlockHash()
+
+	var raw []byte
+	var result *btcjson.GetUtxosResult
+	if format == restFormatJSON {
+		result = &btcjson.GetUtxosResult{
+			ChainHeight:  gChain.Height(),
+			ChaintipHash: tipHash.String(),
+			Bitmap:       string(bitmapStr),
+			Utxos:        make([]btcjson.UtxoResult, 0, len(coins)),
+		}
+		for _, coin := range coins {
+			result.Utxos = append(result.Utxos, btcjson.UtxoResult{
+				Height:       restCoinHeight(coin),
+				Value:        valueFromAmount(int64(coin.GetAmount())),
+				ScriptPubKey: *ScriptPubKeyToJSON(coin.GetScriptPubKey(), true),
+			})
+		}
+	} else {
+		var buf bytes.Buffer
+		err := serializeUtxos(&buf, gChain.Height(), tipHash, bitmap, coins)
+		if err != nil {
+			restError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		raw = buf.Bytes()
+	}
+	restRespond(w, format, raw, result)
+}
+
+func restCoinHeight(coin *utxo.Coin) int32 {
+	if coin.IsMempoolCoin() {
+		return restMempoolHeight
+	}
+	return coin.GetHeight()
+}
+
+// serializeUtxos writes a getutxos reply in the binary format of bitcoind: the
+// chain height, the tip hash, the bitmap and the outputs, each preceded by a
+// dummy tx version and its height.
+func serializeUtxos(w io.Writer, height int32, tipHash *util.Hash, bitmap []byte, coins []*utxo.Coin) error {
+	if err := binary.Write(w, binary.LittleEndian, height); err != nil {
+		return err
+	}
+	if _, err := tipHash.Serialize(w); err != nil {
+		return err
+	}
+	if err := util.WriteVarBytes(w, bitmap); err != nil {
+		return err
+	}
+	if err := util.WriteVarInt(w, uint64(len(coins))); err != nil {
+		return err
+	}
+	for _, coin := range coins {
+		err := util.WriteElements(w, uint32(0), uint32(restCoinHeight(coin)))
+		if err != nil {
+			return err
+		}
+		txOut := coin.GetTxOut()
+		if err := txOut.Serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/logic/lchain"
 	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/logic/ltx"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/block"
 	"github.com/copernet/copernicus/model/blockindex"
@@ -31,6 +32,10 @@ import (
 	"gopkg.in/fatih/set.v0"
 )
 
+// timestampWindow is the margin, in seconds, by which a block timestamp may
+// precede the one of an earlier block, used when pruning by timestamp.
+const timestampWindow = 2 * 60 * 60
+
 var blockchainHandlers = map[string]commandHandler{
 	"getblockchaininfo":     handleGetBlockChainInfo,
 	"getbestblockhash":      handleGetBestBlockHash,      // complete
@@ -49,6 +54,7 @@ var blockchainHandlers = map[string]commandHandler{
 	"gettxout":              handleGetTxOut,              // complete
 	"gettxoutsetinfo":       handleGetTxoutSetInfo,
 	"pruneblockchain":       handlePruneBlockChain, //complete
+	"savemempool":           handleSaveMempool,     //complete
 	"verifychain":           handleVerifyChain,     //complete
 	"preciousblock":         handlePreciousblock,   //complete
 
@@ -74,14 +80,19 @@ func handleGetBlockChainInfo(s *Server, cmd interface{}, closeChan <-chan struct
 		MedianTime:           tip.GetMedianTimePast(),
 		VerificationProgress: lchain.GuessVerificationProgress(params.TxData(), tip),
 		ChainWork:            fmt.Sprintf("%064x", &tip.ChainWork),
-		Pruned:               false,
+		Pruned:               disk.GetPruneState().PruneMode,
 		Bip9SoftForks:        make(map[string]*btcjson.Bip9SoftForkDescription),
+		Upgrades:             make(map[string]*btcjson.UpgradeDescription),
 	}
 
 	// Next, populate the response with information describing the current
 	// status of soft-forks deployed via the super-majority block
 	// signalling mechanism.
 
+	if chainInfo.Pruned {
+		chainInfo.PruneHeight = disk.GetPruneHeight()
+	}
+
 	height := tip.Height
 	chainInfo.SoftForks = version234Status(height, params)
 
@@ -116,6 +127,20 @@ func handleGetBlockChainInfo(s *Server, cmd interface{}, closeChan <-chan struct
 		}
 	}
 
+	// The upgrades of the upgrade registry are active if their rules apply
+	// to the next block.
+	for _, upgrade := range consensus.Upgrades {
+		upgradeType := "mtp"
+		if upgrade.ByHeight {
+			upgradeType = "height"
+		}
+		chainInfo.Upgrades[upgrade.Name] = &btcjson.UpgradeDescription{
+			Type:       upgradeType,
+			Activation: params.GetUpgradeActivation(upgrade.ID),
+			Active:     params.IsUpgradeActivated(upgrade.ID, height+1, tip.GetMedianTimePast()),
+		}
+	}
+
 	return chainInfo, nil
 }
 
@@ -196,9 +221,29 @@ func handleGetBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 		return nil, rpcDecodeHexError(c.Hash)
 	}
 
+	blk, blockIndex, rpcErr := readBlock(hash)
+	if rpcErr != nil {
+		return false, rpcErr
+	}
+
+	if c.Verbose != nil && !*c.Verbose {
+		blkBuf := bytes.NewBuffer(nil)
+		blk.Serialize(blkBuf)
+		strHex := hex.EncodeToString(blkBuf.Bytes())
+		return strHex, nil
+	}
+
+	blockReply := blockToJSON(blk, blockIndex)
+
+	return blockReply, nil
+}
+
+// readBlock loads the block with the passed hash from disk, along with its
+// index.
+func readBlock(hash *util.Hash) (*block.Block, *blockindex.BlockIndex, *btcjson.RPCError) {
 	blockIndex := chain.GetInstance().FindBlockIndex(*hash)
 	if blockIndex == nil {
-		return false, &btcjson.RPCError{
+		return nil, nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCInvalidAddressOrKey,
 			Message: "Block not found",
 		}
@@ -206,7 +251,7 @@ func handleGetBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 
 	pruneState := disk.GetPruneState()
 	if pruneState.HavePruned && !blockIndex.HasData() && blockIndex.TxCount > 0 {
-		return false, &btcjson.RPCError{
+		return nil, nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCMisc,
 			Message: "Block not available (pruned data)",
 		}
@@ -214,22 +259,13 @@ func handleGetBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 
 	blk, ret := disk.ReadBlockFromDisk(blockIndex, chain.GetInstance().GetParams())
 	if !ret {
-		return false, &btcjson.RPCError{
+		return nil, nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCMisc,
 			Message: "Block not found on disk",
 		}
 	}
 
-	if c.Verbose != nil && !*c.Verbose {
-		blkBuf := bytes.NewBuffer(nil)
-		blk.Serialize(blkBuf)
-		strHex := hex.EncodeToString(blkBuf.Bytes())
-		return strHex, nil
-	}
-
-	blockReply := blockToJSON(blk, blockIndex)
-
-	return blockReply, nil
+	return blk, blockIndex, nil
 }
 
 func blockToJSON(blk *block.Block, blockIndex *blockindex.BlockIndex) *btcjson.GetBlockVerboseResult {
@@ -321,6 +357,12 @@ func handleGetBlockHeader(s *Server, cmd interface{}, closeChan <-chan struct{})
 		return hex.EncodeToString(headerBuf.Bytes()), nil
 	}
 
+	return blockHeaderToJSON(blockIndex), nil
+}
+
+// blockHeaderToJSON converts the header of the passed block index to a
+// verbose header JSON object.
+func blockHeaderToJSON(blockIndex *blockindex.BlockIndex) *btcjson.GetBlockHeaderVerboseResult {
 	confirmations := int32(-1)
 	// Only report confirmations if the block is on the main chain
 	if chain.GetInstance().Contains(blockIndex) {
@@ -339,7 +381,7 @@ func handleGetBlockHeader(s *Server, cmd interface{}, closeChan <-chan struct{})
 	}
 
 	blockHeaderReply := &btcjson.GetBlockHeaderVerboseResult{
-		Hash:          c.Hash,
+		Hash:          blockIndex.GetBlockHash().String(),
 		Confirmations: uint64(confirmations),
 		Height:        blockIndex.Height,
 		Version:       blockIndex.Header.Version,
@@ -354,7 +396,7 @@ func handleGetBlockHeader(s *Server, cmd interface{}, closeChan <-chan struct{})
 		PreviousHash:  previousblockhash,
 		NextHash:      nextblockhash,
 	}
-	return blockHeaderReply, nil
+	return blockHeaderReply
 }
 
 func handleGetChainTips(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
@@ -649,6 +691,7 @@ func handleGetMempoolInfo(s *Server, cmd interface{}, closeChan <-chan struct{})
 		Usage:         pool.GetPoolUsage(),
 		MaxMempool:    int(conf.Cfg.Mempool.MaxPoolSize),
 		MempoolMinFee: valueFromAmount(pool.GetMinFeeRate().SataoshisPerK),
+		Loaded:        pool.IsLoaded(),
 	}
 	return ret, nil
 }
@@ -735,6 +778,13 @@ func handleGetTxOut(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 	confirmations := int32(0)
 	if !coin.IsMempoolCoin() {
 		confirmations = index.Height - coin.GetHeight() + 1
+
+		// The token prefix of a coin created once tokens are enabled is
+		// only read when it is spent.
+		if coin.HasTokenPrefix() && coin.GetHeight() >= ltx.GetTokenActivationHeight(index) {
+			coin = coin.DeepCopy()
+			coin.ReadTokenData()
+		}
 	}
 
 	amountValue := valueFromAmount(int64(coin.GetAmount()))
@@ -744,6 +794,7 @@ func handleGetTxOut(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 		Confirmations: confirmations,
 		Value:         strconv.FormatFloat(amountValue, 'f', -1, 64),
 		ScriptPubKey:  *scriptPubKeyJSON,
+		TokenData:     TokenDataToJSON(coin.GetTokenData()),
 		Coinbase:      coin.IsCoinBase(),
 	}
 
@@ -782,64 +833,82 @@ func handleGetTxoutSetInfo(s *Server, cmd interface{}, closeChan <-chan struct{}
 	return reply, nil
 }
 
-func getPrunMode() (bool, error) {
-	/*	pruneArg := util.GetArg("-prune", 0)
-		if pruneArg < 0 {
-			return false, errors.New("Prune cannot be configured with a negative value")
-		}*/ // todo open
-	return true, nil
+func handleSaveMempool(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if !mempool.GetInstance().IsLoaded() {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The mempool was not loaded yet",
+		}
+	}
+
+	if err := lmempool.DumpMempool(); err != nil {
+		log.Error("savemempool: %v", err)
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Unable to dump mempool to disk",
+		}
+	}
+	return nil, nil
 }
 
 func handlePruneBlockChain(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	/*	pruneMode, err := getPrunMode()
-
-		if err != nil && !pruneMode {
-			return false, &btcjson.RPCError{
-				Code:    btcjson.ErrRPCType,
-				Message: fmt.Sprintf("Cannot prune blocks because node is not in prune mode."),
-			}
+	if !disk.GetPruneState().PruneMode {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Cannot prune blocks because node is not in prune mode.",
 		}
+	}
 
-		c := cmd.(*btcjson.PruneBlockChainCmd)
-		height := c.Height
-		if *height < 0 {
-			return false, &btcjson.RPCError{
-				Code:    btcjson.ErrRPCType,
-				Message: fmt.Sprintf("Negative block height."),
-			}
-		}
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
 
-		if *height > 1000000000 {
-			index := chain.GetInstance().FindEarliestAtLeast(int64(*height - 72000))
-			if index != nil {
-				return false, &btcjson.RPCError{
-					Code:    btcjson.ErrRPCType,
-					Message: fmt.Sprintf("Could not find block with at least the specified timestamp."),
-				}
-			}
-			height = &index.Height
+	c := cmd.(*btcjson.PruneBlockChainCmd)
+	height := c.Height
+	if height <= 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Block height must be larger than zero.",
 		}
+	}
 
-		h := *height
-		chainHeight := chain.GetInstance().Height()
-		if chainHeight < consensus.ActiveNetParams.PruneAfterHeight {
-			return false, &btcjson.RPCError{
-				Code:    btcjson.ErrRPCMisc,
-				Message: fmt.Sprintf("Blockchain is too short for pruning."),
-			}
-		} else if h > chainHeight {
-			return false, &btcjson.RPCError{
+	gChain := chain.GetInstance()
+	// Height value more than a billion is too high to be a block height, and
+	// too low to be a block time (corresponds to timestamp from Sep 2001).
+	if height > 1000000000 {
+		// Add a 2 hour buffer to include blocks which might have had old
+		// timestamps
+		index := gChain.FindEarliestAtLeast(int64(height) - timestampWindow)
+		if index == nil {
+			return nil, &btcjson.RPCError{
 				Code:    btcjson.ErrRPCInvalidParameter,
-				Message: fmt.Sprintf("Blockchain is shorter than the attempted prune height."),
+				Message: "Could not find block with at least the specified timestamp.",
 			}
-		} /*else if h > chainHeight - MIN_BLOCKS_TO_KEEP {
-			h = chainHeight - MIN_BLOCKS_TO_KEEP
 		}
+		height = int(index.Height)
+	}
 
-		chain.PruneBlockFilesManual(*height)
-		return uint64(*height), nil*/ // todo realise
+	chainHeight := int(gChain.Height())
+	if chainHeight < gChain.GetParams().PruneAfterHeight {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Blockchain is too short for pruning.",
+		}
+	} else if height > chainHeight {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Blockchain is shorter than the attempted prune height.",
+		}
+	} else if height > chainHeight-int(block.MinBlocksToKeep) {
+		log.Debug("Attempt to prune blocks close to the tip. Retaining the minimum number of blocks.")
+		height = chainHeight - int(block.MinBlocksToKeep)
+	}
 
-	return nil, nil
+	mempoolUsage := mempool.GetInstance().GetPoolUsage()
+	mempoolSizeMax := int64(persist.DefaultMaxMemPoolSize) * 1000000
+	if err := disk.PruneBlockFilesManual(int32(height), mempoolUsage, mempoolSizeMax); err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCDatabase, err.Error())
+	}
+	return height, nil
 }
 
 // handleVerifyChain implements the verifychain command.
@@ -22,9 +22,12 @@ import (
 
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
 	"github.com/copernet/copernicus/net/server"
 	"github.com/copernet/copernicus/rpc/btcjson"
 	"github.com/copernet/copernicus/util"
+	"github.com/gorilla/websocket"
 )
 
 const (
@@ -34,6 +37,10 @@ const (
 	rpcAuthTimeoutSeconds = 10
 )
 
+// websocketUpgrader upgrades the connections to the /ws endpoint, using the
+// default size for read/write buffers.
+var websocketUpgrader = websocket.Upgrader{}
+
 func internalRPCError(errStr, context string) *btcjson.RPCError {
 	logStr := errStr
 	if context != "" {
@@ -68,6 +75,7 @@ type Server struct {
 	requestProcessShutdown chan struct{}
 	quit                   chan int
 	timeSource             *util.MedianTime
+	ntfnMgr                *wsNotificationManager
 }
 
 func (s *Server) httpStatusLine(req *http.Request, code int) string {
@@ -133,6 +141,8 @@ func (s *Server) Stop() error {
 			return err
 		}
 	}
+	s.ntfnMgr.Shutdown()
+	s.ntfnMgr.WaitForShutdown()
 	close(s.quit)
 	s.wg.Wait()
 	log.Info("RPC server shutdown complete")
@@ -209,9 +219,16 @@ type parsedRPCCmd struct {
 	method string
 	cmd    interface{}
 	err    *btcjson.RPCError
+
+	// wallet is the name of the wallet the request is routed to, nil if it
+	// is not routed to one.
+	wallet *string
 }
 
 func (s *Server) standardCmdResult(cmd *parsedRPCCmd, closeChan <-chan struct{}) (interface{}, error) {
+	if handler, ok := walletHandlers[cmd.method]; ok {
+		return withRequestWallet(handler, cmd.wallet)(s, cmd.cmd, closeChan)
+	}
 	handler, ok := rpcHandlers[cmd.method]
 	if ok {
 		return handler(s, cmd.cmd, closeChan)
@@ -322,22 +339,11 @@ func (s *Server) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin boo
 	var responseID interface{}
 	var jsonErr error
 	var result interface{}
-	var request btcjson.Request
-	var jsonParamRequest btcjson.JSONParamRequest
-	var jsonParams *map[string]json.RawMessage
-	if err := json.Unmarshal(body, &request); err != nil {
-		if err = json.Unmarshal(body, &jsonParamRequest); err != nil {
-			jsonErr = &btcjson.RPCError{
-				Code:    btcjson.ErrRPCParse.Code,
-				Message: "Failed to parse request: " + err.Error(),
-			}
-		} else {
-			jsonParams = &jsonParamRequest.Params
-			request = btcjson.Request{
-				Jsonrpc: jsonParamRequest.Jsonrpc,
-				Method:  jsonParamRequest.Method,
-				ID:      jsonParamRequest.ID,
-			}
+	request, jsonParams, err := parseRequest(body)
+	if err != nil {
+		jsonErr = &btcjson.RPCError{
+			Code:    btcjson.ErrRPCParse.Code,
+			Message: "Failed to parse request: " + err.Error(),
 		}
 	}
 	if jsonErr == nil {
@@ -370,7 +376,11 @@ func (s *Server) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin boo
 		//}
 
 		if jsonErr == nil {
-			parsedCmd := parseCmd(&request, jsonParams)
+			parsedCmd := parseCmd(request, jsonParams)
+			if strings.HasPrefix(r.URL.Path, walletURIPrefix) {
+				walletName := strings.TrimPrefix(r.URL.Path, walletURIPrefix)
+				parsedCmd.wallet = &walletName
+			}
 			if parsedCmd.err != nil {
 				jsonErr = parsedCmd.err
 			} else {
@@ -419,6 +429,7 @@ func (s *Server) Start() {
 	}
 
 	log.Info("Starting RPC server")
+	s.ntfnMgr.Start()
 	rpcServeMux := http.NewServeMux()
 	httpServer := &http.Server{
 		Handler: rpcServeMux,
@@ -448,6 +459,30 @@ func (s *Server) Start() {
 		s.jsonRPCRead(w, r, isAdmin)
 	})
 
+	// Websocket endpoint.
+	rpcServeMux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		_, isAdmin, err := s.checkAuth(r, true)
+		if err != nil {
+			jsonAuthFail(w)
+			return
+		}
+
+		// Attempt to upgrade the connection to a websocket connection.
+		ws, err := websocketUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			if _, ok := err.(websocket.HandshakeError); !ok {
+				log.Error("Unexpected websocket error: %v", err)
+			}
+			return
+		}
+		s.WebsocketHandler(ws, r.RemoteAddr, isAdmin)
+	})
+
+	// Unauthenticated REST endpoints, if enabled.
+	if conf.Cfg.RPC.Rest {
+		rpcServeMux.HandleFunc("/rest/", s.restHandler)
+	}
+
 	for _, listener := range s.cfg.Listeners {
 		s.wg.Add(1)
 		go func(listener net.Listener) {
@@ -617,6 +652,7 @@ func NewServer(config *ServerConfig, ts *util.MedianTime) (*Server, error) {
 		requestProcessShutdown: make(chan struct{}, 1),
 		quit:                   make(chan int),
 		timeSource:             ts,
+		ntfnMgr:                newWsNotificationManager(),
 	}
 	if conf.Cfg.RPC.RPCUser != "" && conf.Cfg.RPC.RPCPass != "" {
 		login := conf.Cfg.RPC.RPCUser + ":" + conf.Cfg.RPC.RPCPass
@@ -628,7 +664,8 @@ func NewServer(config *ServerConfig, ts *util.MedianTime) (*Server, error) {
 		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
 		rpc.limitauthsha = sha256.Sum256([]byte(auth))
 	}
-	//rpc.cfg.Chain.Subscribe(rpc.handleBlockchainNotification)  // todo open
+	chain.GetInstance().Subscribe(rpc.handleBlockchainNotification)
+	mempool.GetInstance().Subscribe(rpc.ntfnMgr.NotifyMempoolTx)
 
 	return &rpc, nil
 }
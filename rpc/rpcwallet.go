@@ -2,24 +2,45 @@ package rpc
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/crypto"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/logic/lwallet"
+	"github.com/copernet/copernicus/model/blockindex"
 	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/psbt"
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/model/wallet"
+	"github.com/copernet/copernicus/persist/disk"
 	"github.com/copernet/copernicus/rpc/btcjson"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
 	"github.com/copernet/copernicus/util/cashaddr"
 	"github.com/pkg/errors"
 	"gopkg.in/fatih/set.v0"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"time"
 )
 
-var walletHandlers = map[string]commandHandler{
+// walletURIPrefix is the path of the requests routed to a wallet, followed
+// by its name.
+const walletURIPrefix = "/wallet/"
+
+// walletCommandHandler handles a wallet RPC for the wallet the request is
+// routed to.
+type walletCommandHandler func(*Server, *wallet.Wallet, interface{}, <-chan struct{}) (interface{}, error)
+
+var walletHandlers = map[string]walletCommandHandler{
 	"getnewaddress":      handleGetNewAddress,
 	"listunspent":        handleListUnspent,
 	"settxfee":           handleSetTxFee,
@@ -29,6 +50,52 @@ var walletHandlers = map[string]commandHandler{
 	"sendmany":           handleSendMany,
 	"addmultisigaddress": handleAddMultiSigAddress,
 	"fundrawtransaction": handleFundRawTransaction,
+
+	"encryptwallet":          handleEncryptWallet,
+	"walletpassphrase":       handleWalletPassphrase,
+	"walletlock":             handleWalletLock,
+	"walletpassphrasechange": handleWalletPassphraseChange,
+
+	"getrawchangeaddress": handleGetRawChangeAddress,
+	"keypoolrefill":       handleKeyPoolRefill,
+	"dumpmasterkey":       handleDumpMasterKey,
+	"sethdseed":           handleSetHDSeed,
+
+	"importprivkey": handleImportPrivKey,
+	"dumpprivkey":   handleDumpPrivKey,
+	"importaddress": handleImportAddress,
+	"importpubkey":  handleImportPubKey,
+	"dumpwallet":    handleDumpWallet,
+	"importwallet":  handleImportWallet,
+
+	"rescanblockchain": handleRescanBlockChain,
+
+	"listtransactions":      handleListTransactions,
+	"listsinceblock":        handleListSinceBlock,
+	"listreceivedbyaddress": handleListReceivedByAddress,
+	"getreceivedbyaddress":  handleGetReceivedByAddress,
+
+	"lockunspent":     handleLockUnspent,
+	"listlockunspent": handleListLockUnspent,
+
+	"abandontransaction":       handleAbandonTransaction,
+	"resendwallettransactions": handleResendWalletTransactions,
+
+	"unloadwallet": handleUnloadWallet,
+
+	"walletcreatefundedpsbt": handleWalletCreateFundedPsbt,
+	"walletprocesspsbt":      handleWalletProcessPsbt,
+
+	"signmessage":    handleSignMessage,
+	"getaddressinfo": handleGetAddressInfo,
+}
+
+// walletLoaderHandlers manage the loaded wallets, they are not routed to a
+// wallet.
+var walletLoaderHandlers = map[string]commandHandler{
+	"createwallet": handleCreateWallet,
+	"loadwallet":   handleLoadWallet,
+	"listwallets":  handleListWallets,
 }
 
 var walletDisableRPCError = &btcjson.RPCError{
@@ -36,15 +103,88 @@ var walletDisableRPCError = &btcjson.RPCError{
 	Message: "Method not found (wallet method is disabled because no wallet is loaded)",
 }
 
-func handleGetNewAddress(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	if !lwallet.IsWalletEnable() {
+var walletUnlockNeededRPCError = &btcjson.RPCError{
+	Code:    btcjson.ErrRPCWalletUnlockNeeded,
+	Message: "Error: Please enter the wallet passphrase with walletpassphrase first.",
+}
+
+var keypoolRanOutRPCError = &btcjson.RPCError{
+	Code:    btcjson.ErrRPCWalletKeypoolRanOut,
+	Message: "Error: Keypool ran out, please call keypoolrefill first",
+}
+
+var walletNotFoundRPCError = &btcjson.RPCError{
+	Code:    btcjson.ErrRPCWalletNotFound,
+	Message: "Requested wallet does not exist or is not loaded",
+}
+
+var walletNotSpecifiedRPCError = &btcjson.RPCError{
+	Code:    btcjson.ErrRPCWalletNotSpecified,
+	Message: "Wallet file not specified (must request wallet RPC through /wallet/<filename> uri-path).",
+}
+
+// maxWalletUnlockTime caps the timeout of walletpassphrase, which is about
+// three years.
+const maxWalletUnlockTime = 100000000
+
+// withRequestWallet adapts a wallet RPC handler to the wallet named
+// walletName or, if it is nil, to the only wallet loaded.
+func withRequestWallet(handler walletCommandHandler, walletName *string) commandHandler {
+	return func(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+		w, err := getWalletForRequest(walletName, cmd)
+		if err != nil {
+			return nil, err
+		}
+		return handler(s, w, cmd, closeChan)
+	}
+}
+
+func getWalletForRequest(walletName *string, cmd interface{}) (*wallet.Wallet, *btcjson.RPCError) {
+	// unloadwallet may name the wallet in its parameters rather than in the
+	// request path.
+	if c, ok := cmd.(*btcjson.UnloadWalletCmd); ok && c.WalletName != nil {
+		if walletName != nil && *walletName != *c.WalletName {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				"RPC endpoint wallet and wallet_name parameter specify different wallets")
+		}
+		walletName = c.WalletName
+	}
+
+	if walletName != nil {
+		if w := wallet.GetWallet(*walletName); w != nil {
+			return w, nil
+		}
+		return nil, walletNotFoundRPCError
+	}
+
+	wallets := wallet.GetWallets()
+	switch len(wallets) {
+	case 0:
 		return nil, walletDisableRPCError
+	case 1:
+		return wallets[0], nil
 	}
+	return nil, walletNotSpecifiedRPCError
+}
+
+func ensureWalletIsUnlocked(w *wallet.Wallet) *btcjson.RPCError {
+	if w.IsLocked() {
+		return walletUnlockNeededRPCError
+	}
+	return nil
+}
 
+func handleGetNewAddress(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetNewAddressCmd)
 
 	account := *c.Account
-	address, err := lwallet.GetNewAddress(account, false)
+	address, err := lwallet.GetNewAddress(w, account, false)
+	if err == wallet.ErrKeyPoolRanOut {
+		return nil, keypoolRanOutRPCError
+	}
+	if err == crypto.ErrKeyStoreLocked {
+		return nil, walletUnlockNeededRPCError
+	}
 	if err != nil {
 		log.Info("GetNewAddress error:%s", err.Error())
 		return nil, btcjson.ErrRPCInternal
@@ -53,11 +193,7 @@ func handleGetNewAddress(s *Server, cmd interface{}, closeChan <-chan struct{})
 	return address, nil
 }
 
-func handleListUnspent(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	if !lwallet.IsWalletEnable() {
-		return nil, walletDisableRPCError
-	}
-
+func handleListUnspent(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.ListUnspentCmd)
 
 	minDepth := *c.MinConf
@@ -80,7 +216,7 @@ func handleListUnspent(s *Server, cmd interface{}, closeChan <-chan struct{}) (i
 
 	results := make([]*btcjson.ListUnspentResult, 0)
 
-	coins := lwallet.AvailableCoins(!includeUnsafe, true)
+	coins := lwallet.AvailableCoins(w, !includeUnsafe, true)
 	for _, txnCoin := range coins {
 		depth := int32(0)
 		if !txnCoin.Coin.IsMempoolCoin() {
@@ -117,11 +253,11 @@ func handleListUnspent(s *Server, cmd interface{}, closeChan <-chan struct{}) (i
 			Safe:          txnCoin.IsSafe,
 		}
 
-		if account := lwallet.GetAccountName(keyHash); account != "" {
+		if account := lwallet.GetAccountName(w, keyHash); account != "" {
 			unspentInfo.Account = account
 		}
 		if scriptType == script.ScriptHash {
-			if redeemScript := lwallet.GetScript(keyHash); redeemScript != nil {
+			if redeemScript := lwallet.GetScript(w, keyHash); redeemScript != nil {
 				scriptHexString := hex.EncodeToString(redeemScript.Bytes())
 				unspentInfo.RedeemScript = scriptHexString
 			}
@@ -131,11 +267,7 @@ func handleListUnspent(s *Server, cmd interface{}, closeChan <-chan struct{}) (i
 	return results, nil
 }
 
-func handleSetTxFee(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	if !lwallet.IsWalletEnable() {
-		return nil, walletDisableRPCError
-	}
-
+func handleSetTxFee(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.SetTxFeeCmd)
 
 	feePaid, rpcErr := amountFromValue(c.Amount)
@@ -143,18 +275,18 @@ func handleSetTxFee(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 		return false, rpcErr
 	}
 
-	lwallet.SetFeeRate(int64(feePaid), 1000)
+	lwallet.SetFeeRate(w, int64(feePaid), 1000)
 
 	return true, nil
 }
 
-func handleSendToAddress(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	if !lwallet.IsWalletEnable() {
-		return nil, walletDisableRPCError
-	}
-
+func handleSendToAddress(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.SendToAddressCmd)
 
+	if rpcErr := ensureWalletIsUnlocked(w); rpcErr != nil {
+		return nil, rpcErr
+	}
+
 	scriptPubKey, rpcErr := getStandardScriptPubKey(c.Address, nil)
 	if rpcErr != nil {
 		return nil, rpcErr
@@ -177,7 +309,7 @@ func handleSendToAddress(s *Server, cmd interface{}, closeChan <-chan struct{})
 
 	subtractFeeFromAmount := *c.SubtractFeeFromAmount
 
-	txn, rpcErr := sendMoney(scriptPubKey, value, subtractFeeFromAmount, extInfo)
+	txn, rpcErr := sendMoney(w, scriptPubKey, value, subtractFeeFromAmount, extInfo)
 	if rpcErr != nil {
 		return false, rpcErr
 	}
@@ -185,32 +317,25 @@ func handleSendToAddress(s *Server, cmd interface{}, closeChan <-chan struct{})
 	return txHash.String(), nil
 }
 
-func handleGetBalance(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	if !lwallet.IsWalletEnable() {
-		return nil, walletDisableRPCError
-	}
+func handleGetBalance(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	//TODO add Confirmation
-	balance := wallet.GetInstance().GetBalance()
+	balance := w.GetBalance()
 
 	return balance.ToBTC(), nil
 }
-func handleGetTransaction(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	if !lwallet.IsWalletEnable() {
-		return nil, walletDisableRPCError
-	}
+func handleGetTransaction(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetTransactionCmd)
-	pwallet := wallet.GetInstance()
 	txHash, err := util.GetHashFromStr(c.Txid)
 	if err != nil {
 		return nil, errors.New("Tx Hash is err")
 	}
-	wtx := pwallet.GetWalletTx(*txHash)
+	wtx := w.GetWalletTx(*txHash)
 	if wtx == nil {
 		return nil, errors.New("Invalid or non-wallet transaction id")
 	}
 
 	ret := &btcjson.GetTransactionResult{}
-	filter := wallet.ISMINE_SPENDABLE
+	filter := walletFilter(*c.IncludeWatchOnly)
 	credit := wtx.GetCredit(filter)
 	debit := wtx.GetDebit(filter)
 	net := credit - debit
@@ -242,13 +367,23 @@ func handleGetTransaction(s *Server, cmd interface{}, closeChan <-chan struct{})
 	ret.Hex = strHex
 
 	// Fill GetTransactionDetailsResult
+	ret.Details = make([]btcjson.GetTransactionDetailsResult, 0)
+	for _, entry := range listTransactionEntries(w, wtx, "*", 0, filter) {
+		ret.Details = append(ret.Details, btcjson.GetTransactionDetailsResult{
+			Account:           entry.Account,
+			Address:           entry.Address,
+			Amount:            entry.Amount,
+			Category:          entry.Category,
+			Label:             entry.Label,
+			InvolvesWatchOnly: entry.InvolvesWatchOnly,
+			Fee:               entry.Fee,
+			Vout:              entry.Vout,
+		})
+	}
 
 	return ret, nil
 }
-func handleFundRawTransaction(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	if !lwallet.IsWalletEnable() {
-		return nil, walletDisableRPCError
-	}
+func handleFundRawTransaction(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.FundRawTransactionCmd)
 	b, _ := hex.DecodeString(c.HexTx)
 	ubuf := bytes.NewBuffer(b)
@@ -257,61 +392,97 @@ func handleFundRawTransaction(s *Server, cmd interface{}, closeChan <-chan struc
 		return nil, rpcDecodeHexError(c.HexTx)
 	}
 
-	if txn.GetOutsCount() == 0 {
-		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "TX must have at least one output")
+	pos, feeOut, rpcErr := fundTransaction(w, &txn, c.Options)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	sbuf := bytes.NewBuffer(nil)
+	if err := txn.Serialize(sbuf); err != nil {
+		log.Error("rawTransaction:serialize tx failed: %v", err)
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+	return &btcjson.FundRawTransactionResult{
+		Hex:       hex.EncodeToString(sbuf.Bytes()),
+		Changepos: pos,
+		Fee:       feeOut.ToBTC(),
+	}, nil
+}
+
+// fundTransaction adds to fundTx the inputs and the change output paying its
+// outputs and the fee, with the fundrawtransaction options.  It returns the
+// position of the change output and the fee.
+func fundTransaction(w *wallet.Wallet, fundTx *tx.Tx, options *btcjson.FundRawTxoptions) (int, amount.Amount,
+	*btcjson.RPCError) {
+
+	if fundTx.GetOutsCount() == 0 {
+		return 0, 0, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "TX must have at least one output")
 	}
 	setSubtractFeeFromOutputs := set.New()
-	if c.Options == nil {
-		c.Options = &btcjson.FundRawTxoptions{
+	if options == nil {
+		options = &btcjson.FundRawTxoptions{
 			IncludeWatching:  false,
 			LockUnspents:     false,
 			ReserveChangeKey: true,
 			ChangePosition:   0,
 		}
 	} else {
-		changePosition := c.Options.ChangePosition
-		if changePosition != -1 && (changePosition < 0 || changePosition > txn.GetOutsCount()) {
-			return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "changePosition out of bounds")
+		changePosition := options.ChangePosition
+		if changePosition != -1 && (changePosition < 0 || changePosition > fundTx.GetOutsCount()) {
+			return 0, 0, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "changePosition out of bounds")
+		}
+
+		if options.MinConf < 0 {
+			return 0, 0, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "Invalid minconf")
 		}
 
-		subtractFeeFromOutputs := *c.Options.SubtractFeeFromOutputs
+		subtractFeeFromOutputs := make([]int, 0)
+		if options.SubtractFeeFromOutputs != nil {
+			subtractFeeFromOutputs = *options.SubtractFeeFromOutputs
+		}
 		for _, pos := range subtractFeeFromOutputs {
 			if setSubtractFeeFromOutputs.Has(pos) {
-				return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter,
+				return 0, 0, btcjson.NewRPCError(btcjson.RPCInvalidParameter,
 					fmt.Sprintf("Invalid parameter, duplicated position: %d", pos))
 			}
 			if pos < 0 {
-				return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter,
+				return 0, 0, btcjson.NewRPCError(btcjson.RPCInvalidParameter,
 					fmt.Sprintf("Invalid parameter, duplicated position: %d", pos))
 			}
-			if pos >= txn.GetOutsCount() {
-				return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter,
+			if pos >= fundTx.GetOutsCount() {
+				return 0, 0, btcjson.NewRPCError(btcjson.RPCInvalidParameter,
 					fmt.Sprintf("Invalid parameter, position too large: %d", pos))
 			}
 			setSubtractFeeFromOutputs.Add(pos)
 		}
 	}
-	pos, feeOut, err := lwallet.FundTransaction(&txn, setSubtractFeeFromOutputs, c.Options)
-	if err != nil {
-		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	coinControl := lwallet.NewCoinControl()
+	coinControl.MinDepth = options.MinConf
+	if options.ChangeAddress != "" {
+		changeScript, rpcErr := getStandardScriptPubKey(options.ChangeAddress, nil)
+		if rpcErr != nil {
+			return 0, 0, btcjson.NewRPCError(btcjson.RPCInvalidParameter,
+				"changeAddress must be a valid bitcoin address")
+		}
+		coinControl.ChangeScript = changeScript
 	}
 
-	sbuf := bytes.NewBuffer(nil)
-	if err := txn.Serialize(sbuf); err != nil {
-		log.Error("rawTransaction:serialize tx failed: %v", err)
-		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	pos, feeOut, err := lwallet.FundTransaction(w, fundTx, options.ChangePosition, setSubtractFeeFromOutputs,
+		options.LockUnspents, coinControl)
+	if err == crypto.ErrKeyStoreLocked {
+		return 0, 0, walletUnlockNeededRPCError
 	}
-	return &btcjson.FundRawTransactionResult{
-		Hex:       hex.EncodeToString(sbuf.Bytes()),
-		Changepos: pos,
-		Fee:       feeOut.ToBTC(),
-	}, nil
+	if err != nil {
+		return 0, 0, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+
+	return pos, feeOut, nil
 }
 
-func sendMoney(scriptPubKey *script.Script, value amount.Amount, subtractFeeFromAmount bool,
+func sendMoney(w *wallet.Wallet, scriptPubKey *script.Script, value amount.Amount, subtractFeeFromAmount bool,
 	extInfo map[string]string) (*tx.Tx, *btcjson.RPCError) {
 
-	curBalance := wallet.GetInstance().GetBalance()
+	curBalance := w.GetBalance()
 
 	// Check amount
 	if value <= 0 {
@@ -331,7 +502,10 @@ func sendMoney(scriptPubKey *script.Script, value amount.Amount, subtractFeeFrom
 		SubtractFeeFromAmount: subtractFeeFromAmount,
 	}
 	changePosRet := -1
-	txn, feeRequired, err := lwallet.CreateTransaction(recipients, &changePosRet, true)
+	txn, feeRequired, err := lwallet.CreateTransaction(w, recipients, &changePosRet, nil, true)
+	if err == crypto.ErrKeyStoreLocked {
+		return nil, walletUnlockNeededRPCError
+	}
 	if err != nil {
 		if !subtractFeeFromAmount && value+feeRequired > curBalance {
 			errMsg := fmt.Sprintf("Error: This transaction requires a "+
@@ -341,7 +515,7 @@ func sendMoney(scriptPubKey *script.Script, value amount.Amount, subtractFeeFrom
 		return nil, btcjson.NewRPCError(btcjson.RPCWalletError, err.Error())
 	}
 
-	err = lwallet.CommitTransaction(txn, extInfo)
+	err = lwallet.CommitTransaction(w, txn, extInfo)
 	if err != nil {
 		errMsg := "Error: The transaction was rejected! Reason given: " + err.Error()
 		return nil, btcjson.NewRPCError(btcjson.RPCWalletError, errMsg)
@@ -349,13 +523,13 @@ func sendMoney(scriptPubKey *script.Script, value amount.Amount, subtractFeeFrom
 	return txn, nil
 }
 
-func handleSendMany(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	if !lwallet.IsWalletEnable() {
-		return nil, walletDisableRPCError
-	}
-
+func handleSendMany(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.SendManyCmd)
 
+	if rpcErr := ensureWalletIsUnlocked(w); rpcErr != nil {
+		return nil, rpcErr
+	}
+
 	// TODO: check Peer-to-peer connection
 
 	strAccount := c.FromAccount
@@ -421,18 +595,21 @@ func handleSendMany(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 
 	// Check funds
 	// TODO: GetLeagacybalance
-	balance := wallet.GetInstance().GetBalance()
+	balance := w.GetBalance()
 	if totalAmount > balance {
 		return nil, btcjson.NewRPCError(btcjson.RPCWalletInsufficientFunds, "Account has insufficient funds")
 	}
 
+	coinControl := lwallet.NewCoinControl()
+	coinControl.MinDepth = *c.MinConf
+
 	changePosRet := -1
-	txn, feeRequired, err := lwallet.CreateTransaction(recipients, &changePosRet, true)
+	txn, feeRequired, err := lwallet.CreateTransaction(w, recipients, &changePosRet, coinControl, true)
 	if err != nil || feeRequired+totalAmount > balance {
 		return nil, btcjson.NewRPCError(btcjson.RPCWalletInsufficientFunds, err.Error())
 	}
 
-	err = lwallet.CommitTransaction(txn, walletTx.ExtInfo)
+	err = lwallet.CommitTransaction(w, txn, walletTx.ExtInfo)
 	if err != nil {
 		errMsg := "Error: The transaction was rejected! Reason given: " + err.Error()
 		return nil, btcjson.NewRPCError(btcjson.RPCWalletError, errMsg)
@@ -441,10 +618,7 @@ func handleSendMany(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 	return txn.GetHash().String(), nil
 }
 
-func handleAddMultiSigAddress(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	if !lwallet.IsWalletEnable() {
-		return nil, walletDisableRPCError
-	}
+func handleAddMultiSigAddress(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.AddMultiSigAddressCmd)
 	num := c.RequiredNum
 	keys := c.Keys
@@ -473,15 +647,1090 @@ func handleAddMultiSigAddress(s *Server, cmd interface{}, closeChan <-chan struc
 		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, err.Error())
 	}
 
-	pwallet := wallet.GetInstance()
-	pwallet.AddScript(inner)
-	pwallet.SetAddressBook(innerHash, "", "send")
+	w.AddScript(inner)
+	w.SetAddressBook(innerHash, "", "send")
 	return addr.String(), nil
 
 }
 
-func registerWalletRPCCommands() {
-	for name, handler := range walletHandlers {
+func handleEncryptWallet(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.EncryptWalletCmd)
+
+	if w.IsCrypted() {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWalletWrongEncState,
+			"Error: running with an encrypted wallet, but encryptwallet was called.")
+	}
+	if len(c.Passphrase) == 0 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "passphrase can not be empty")
+	}
+
+	if err := w.EncryptWallet([]byte(c.Passphrase)); err != nil {
+		log.Error("EncryptWallet error:%s", err.Error())
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWalletEncryptionFailed,
+			"Error: Failed to encrypt the wallet.")
+	}
+
+	return "wallet encrypted; the wallet is now locked. Use walletpassphrase to unlock it.", nil
+}
+
+func handleWalletPassphrase(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.WalletPassphraseCmd)
+
+	if !w.IsCrypted() {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWalletWrongEncState,
+			"Error: running with an unencrypted wallet, but walletpassphrase was called.")
+	}
+	if len(c.Passphrase) == 0 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "passphrase can not be empty")
+	}
+	if c.Timeout < 0 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Timeout cannot be negative.")
+	}
+	timeout := c.Timeout
+	if timeout > maxWalletUnlockTime {
+		timeout = maxWalletUnlockTime
+	}
+
+	err := w.UnlockWallet([]byte(c.Passphrase), time.Duration(timeout)*time.Second)
+	if err == crypto.ErrPassphraseMismatch {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWalletPassphraseIncorrect,
+			"Error: The wallet passphrase entered was incorrect.")
+	}
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+
+	return nil, nil
+}
+
+func handleWalletLock(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if !w.IsCrypted() {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWalletWrongEncState,
+			"Error: running with an unencrypted wallet, but walletlock was called.")
+	}
+
+	if err := w.LockWallet(); err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+
+	return nil, nil
+}
+
+func handleWalletPassphraseChange(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.WalletPassphraseChangeCmd)
+
+	if !w.IsCrypted() {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWalletWrongEncState,
+			"Error: running with an unencrypted wallet, but walletpassphrasechange was called.")
+	}
+	if len(c.OldPassphrase) == 0 || len(c.NewPassphrase) == 0 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "passphrase can not be empty")
+	}
+
+	err := w.ChangeWalletPassphrase([]byte(c.OldPassphrase), []byte(c.NewPassphrase))
+	if err == crypto.ErrPassphraseMismatch {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWalletPassphraseIncorrect,
+			"Error: The wallet passphrase entered was incorrect.")
+	}
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+
+	return nil, nil
+}
+
+func handleGetRawChangeAddress(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	address, err := lwallet.GetRawChangeAddress(w)
+	if err == wallet.ErrKeyPoolRanOut {
+		return nil, keypoolRanOutRPCError
+	}
+	if err != nil {
+		log.Info("GetRawChangeAddress error:%s", err.Error())
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+
+	return address, nil
+}
+
+func handleKeyPoolRefill(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.KeyPoolRefillCmd)
+
+	newSize := 0
+	if c.NewSize != nil {
+		if *c.NewSize < 0 {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				"Invalid parameter, expected valid size.")
+		}
+		newSize = *c.NewSize
+	}
+
+	if rpcErr := ensureWalletIsUnlocked(w); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if err := w.TopUpKeyPool(newSize); err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, "Error refreshing keypool.")
+	}
+
+	return nil, nil
+}
+
+func handleDumpMasterKey(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	hdChain := w.GetHDChain()
+	if hdChain == nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, "Error: wallet is not a HD wallet.")
+	}
+	if rpcErr := ensureWalletIsUnlocked(w); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	masterKey, err := w.GetHDMasterKey()
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+	accountPath := wallet.GetHDAccountPath()
+	accountKey, err := masterKey.DerivePath(accountPath)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+
+	return &btcjson.DumpMasterKeyResult{
+		HDSeedID:    hex.EncodeToString(hdChain.SeedID),
+		MasterKey:   masterKey.String(),
+		AccountPath: crypto.FormatHDKeyPath(accountPath),
+		AccountXPub: accountKey.Neuter().String(),
+	}, nil
+}
+
+func handleSetHDSeed(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SetHDSeedCmd)
+
+	if rpcErr := ensureWalletIsUnlocked(w); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	seed := wallet.NewHDSeed()
+	if c.Seed != nil {
+		privateKey, err := crypto.DecodePrivateKey(*c.Seed)
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Invalid private key")
+		}
+		seed = crypto.NewPrivateKeyFromBytes(privateKey.GetBytes(), true)
+	}
+
+	err := w.SetHDSeed(seed, *c.NewKeyPool)
+	if err == wallet.ErrHaveHDSeed {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
+			"Already have this key (either as an HD seed or as a loose private key)")
+	}
+	if err == crypto.ErrKeyStoreLocked {
+		return nil, walletUnlockNeededRPCError
+	}
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+
+	return nil, nil
+}
+
+// rescanWallet rescans the blocks from startHeight after keys or scripts
+// were imported.
+func rescanWallet(w *wallet.Wallet, startHeight int32) *btcjson.RPCError {
+	if _, err := lwallet.ScanForWalletTransactions(w, startHeight, -1); err != nil {
+		return rescanRPCError(err)
+	}
+	return nil
+}
+
+func rescanRPCError(err error) *btcjson.RPCError {
+	switch err {
+	case lwallet.ErrRescanInProgress, lwallet.ErrRescanPruned:
+		return btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	case lwallet.ErrRescanAborted:
+		return btcjson.NewRPCError(btcjson.ErrRPCMisc, "Rescan aborted.")
+	}
+	log.Error("wallet rescan error:%s", err.Error())
+	return btcjson.NewRPCError(btcjson.ErrRPCWallet, "Rescan failed: "+err.Error())
+}
+
+func ensureRescanIsPossible(rescan bool) *btcjson.RPCError {
+	if rescan && disk.GetPruneState().PruneMode {
+		return btcjson.NewRPCError(btcjson.ErrRPCWallet, lwallet.ErrRescanPruned.Error())
+	}
+	return nil
+}
+
+func handleImportPrivKey(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ImportPrivKeyCmd)
+
+	if rpcErr := ensureWalletIsUnlocked(w); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := ensureRescanIsPossible(*c.Rescan); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	privateKey, err := crypto.DecodePrivateKey(c.PrivKey)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Invalid private key encoding")
+	}
+
+	if err := lwallet.ImportPrivKey(w, privateKey, *c.Label); err != nil {
+		log.Error("ImportPrivKey error:%s", err.Error())
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, "Error adding key to wallet")
+	}
+
+	if *c.Rescan {
+		if rpcErr := rescanWallet(w, 0); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+	return nil, nil
+}
+
+func handleDumpPrivKey(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.DumpPrivKeyCmd)
+
+	if rpcErr := ensureWalletIsUnlocked(w); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	addrType, keyHash, rpcErr := decodeAddress(c.Address)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if addrType != cashaddr.P2PKH {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCType, "Address does not refer to a key")
+	}
+
+	keyPair := lwallet.GetKeyPair(w, keyHash)
+	if keyPair == nil || keyPair.GetPrivateKey() == nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet,
+			fmt.Sprintf("Private key for address %s is not known", c.Address))
+	}
+
+	return keyPair.GetPrivateKey().ToString(), nil
+}
+
+func handleImportAddress(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ImportAddressCmd)
+
+	if rpcErr := ensureRescanIsPossible(*c.Rescan); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	var err error
+	if scriptPubKey, rpcErr := getStandardScriptPubKey(c.Address, nil); rpcErr == nil {
+		if *c.P2SH {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
+				"Cannot use the p2sh flag with an address - use a script instead")
+		}
+		_, keyHash, _ := decodeAddress(c.Address)
+		err = lwallet.ImportScript(w, scriptPubKey, keyHash, *c.Label, false)
+	} else if data, hexErr := hex.DecodeString(c.Address); hexErr == nil && len(data) > 0 {
+		err = lwallet.ImportScript(w, script.NewScriptRaw(data), nil, *c.Label, *c.P2SH)
+	} else {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Invalid Bitcoin address or script")
+	}
+	if err == lwallet.ErrImportSpendable {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+	if err != nil {
+		log.Error("ImportAddress error:%s", err.Error())
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, "Error adding address to wallet")
+	}
+
+	if *c.Rescan {
+		if rpcErr := rescanWallet(w, 0); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+	return nil, nil
+}
+
+func handleImportPubKey(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ImportPubKeyCmd)
+
+	if rpcErr := ensureRescanIsPossible(*c.Rescan); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	data, err := hex.DecodeString(c.PubKey)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Pubkey must be a hex string")
+	}
+	pubKey, err := crypto.ParsePubKey(data)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Pubkey is not a valid public key")
+	}
+
+	err = lwallet.ImportPubKey(w, pubKey, *c.Label)
+	if err == lwallet.ErrImportSpendable {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+	if err != nil {
+		log.Error("ImportPubKey error:%s", err.Error())
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, "Error adding address to wallet")
+	}
+
+	if *c.Rescan {
+		if rpcErr := rescanWallet(w, 0); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+	return nil, nil
+}
+
+func handleDumpWallet(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.DumpWalletCmd)
+
+	if rpcErr := ensureWalletIsUnlocked(w); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	filename, err := filepath.Abs(c.Filename)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, err.Error())
+	}
+	if err := lwallet.DumpWallet(w, filename); err != nil {
+		log.Error("DumpWallet error:%s", err.Error())
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, err.Error())
+	}
+
+	return &btcjson.DumpWalletResult{Filename: filename}, nil
+}
+
+func handleImportWallet(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ImportWalletCmd)
+
+	if rpcErr := ensureWalletIsUnlocked(w); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := ensureRescanIsPossible(*c.Rescan); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	earliestTime, err := lwallet.ImportWallet(w, c.Filename)
+	if os.IsNotExist(err) || os.IsPermission(err) {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Cannot open wallet dump file")
+	}
+	if err != nil {
+		log.Error("ImportWallet error:%s", err.Error())
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, "Error adding some keys/scripts to wallet")
+	}
+
+	if *c.Rescan {
+		if err := lwallet.RescanFromTime(w, earliestTime); err != nil {
+			return nil, rescanRPCError(err)
+		}
+	}
+	return nil, nil
+}
+
+func handleRescanBlockChain(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.RescanBlockChainCmd)
+
+	if rpcErr := ensureRescanIsPossible(true); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	tipHeight := chain.GetInstance().Height()
+	startHeight := *c.StartHeight
+	if startHeight < 0 || startHeight > tipHeight {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Invalid start_height")
+	}
+	stopHeight := int32(-1)
+	if c.StopHeight != nil {
+		stopHeight = *c.StopHeight
+		if stopHeight < 0 || stopHeight > tipHeight {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Invalid stop_height")
+		}
+		if stopHeight < startHeight {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				"stop_height must be greater than start_height")
+		}
+	}
+
+	lastHeight, err := lwallet.ScanForWalletTransactions(w, startHeight, stopHeight)
+	if err != nil {
+		return nil, rescanRPCError(err)
+	}
+
+	return &btcjson.RescanBlockChainResult{
+		StartHeight: startHeight,
+		StopHeight:  lastHeight,
+	}, nil
+}
+
+// walletAddress returns the address a script pays to along with the hash the
+// address book is keyed by, or an empty address if it has none.  Pay to
+// pubkey scripts are shown as the address of the key.
+func walletAddress(scriptPubKey *script.Script) (string, []byte) {
+	params := chain.GetInstance().GetParams()
+	sType, pubKeys, isStandard := scriptPubKey.IsStandardScriptPubKey()
+	if !isStandard {
+		return "", nil
+	}
+	switch sType {
+	case script.ScriptPubkey:
+		keyHash := util.Hash160(pubKeys[0])
+		if addr, err := cashaddr.NewCashAddressPubKeyHash(keyHash, params); err == nil {
+			return addr.String(), keyHash
+		}
+	case script.ScriptPubkeyHash:
+		if addr, err := cashaddr.NewCashAddressPubKeyHash(pubKeys[0], params); err == nil {
+			return addr.String(), pubKeys[0]
+		}
+	case script.ScriptHash:
+		if addr, err := cashaddr.NewCashAddressScriptHashFromHash(pubKeys[0], params); err == nil {
+			return addr.String(), pubKeys[0]
+		}
+	}
+	return "", nil
+}
+
+func walletFilter(includeWatchOnly bool) uint8 {
+	if includeWatchOnly {
+		return wallet.ISMINE_SPENDABLE | wallet.ISMINE_WATCH_ONLY
+	}
+	return wallet.ISMINE_SPENDABLE
+}
+
+// sortedWalletTxns returns the wallet transactions from the oldest to the
+// newest.
+func sortedWalletTxns(w *wallet.Wallet) []*wallet.WalletTx {
+	walletTxns := w.GetWalletTxns()
+	times := make(map[*wallet.WalletTx]int64, len(walletTxns))
+	for _, wtx := range walletTxns {
+		times[wtx] = wtx.GetTxTime()
+	}
+	sort.Slice(walletTxns, func(i, j int) bool {
+		ti, tj := times[walletTxns[i]], times[walletTxns[j]]
+		if ti != tj {
+			return ti < tj
+		}
+		hi, hj := walletTxns[i].GetHash(), walletTxns[j].GetHash()
+		return hi.Cmp(&hj) < 0
+	})
+	return walletTxns
+}
+
+// listTransactionEntries returns the entries of a wallet transaction: one per
+// output sent if the wallet sent it, and one per output received once it has
+// minDepth confirmations.  Only the entries of the account label are kept,
+// unless account is "*".
+func listTransactionEntries(w *wallet.Wallet, wtx *wallet.WalletTx, account string, minDepth int32,
+	filter uint8) []btcjson.ListTransactionsResult {
+
+	received, sent, fee := wtx.GetAmounts(filter)
+	depth := wtx.GetDepthInMainChain()
+	allAccounts := account == "*"
+
+	entries := make([]btcjson.ListTransactionsResult, 0, len(received)+len(sent))
+	newEntry := func(out *wallet.OutputEntry) btcjson.ListTransactionsResult {
+		address, keyHash := walletAddress(out.ScriptPubKey)
+		entry := btcjson.ListTransactionsResult{
+			InvolvesWatchOnly: w.IsMineScript(out.ScriptPubKey)&wallet.ISMINE_WATCH_ONLY != 0,
+			Address:           address,
+			Vout:              uint32(out.Vout),
+		}
+		if keyHash != nil {
+			if data := w.GetAddressBook(keyHash); data != nil {
+				entry.Label = data.Account
+			}
+		}
+		walletTxToJSON(wtx, depth, &entry)
+		return entry
+	}
+
+	if len(sent) > 0 && (allAccounts || account == wtx.FromAccount) {
+		feeValue := valueFromAmount(int64(-fee))
+		abandoned := wtx.IsAbandoned()
+		for _, out := range sent {
+			entry := newEntry(out)
+			entry.Account = wtx.FromAccount
+			entry.Category = "send"
+			entry.Amount = valueFromAmount(int64(-out.Amount))
+			entry.Fee = &feeValue
+			entry.Abandoned = &abandoned
+			entries = append(entries, entry)
+		}
+	}
+
+	if len(received) > 0 && depth >= minDepth {
+		for _, out := range received {
+			entry := newEntry(out)
+			if !allAccounts && entry.Label != account {
+				continue
+			}
+			entry.Account = entry.Label
+			switch {
+			case !wtx.IsCoinBase():
+				entry.Category = "receive"
+			case depth < 1:
+				entry.Category = "orphan"
+			case wtx.GetBlocksToMaturity() > 0:
+				entry.Category = "immature"
+			default:
+				entry.Category = "generate"
+			}
+			entry.Amount = valueFromAmount(int64(out.Amount))
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// walletTxToJSON fills the fields of an entry which describe the wallet
+// transaction itself.
+func walletTxToJSON(wtx *wallet.WalletTx, depth int32, entry *btcjson.ListTransactionsResult) {
+	entry.Confirmations = depth
+	entry.Generated = wtx.IsCoinBase()
+	if index := wtx.GetBlockIndex(); index != nil {
+		entry.BlockHash = index.GetBlockHash().String()
+		entry.BlockTime = int64(index.GetBlockTime())
+	}
+	entry.TxID = wtx.GetHash().String()
+	entry.WalletConflicts = []string{}
+	entry.Time = wtx.GetTxTime()
+	entry.TimeReceived = wtx.TimeReceived
+	entry.Comment = wtx.ExtInfo["comment"]
+	entry.To = wtx.ExtInfo["to"]
+}
+
+func handleListTransactions(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ListTransactionsCmd)
+
+	count, from := *c.Count, *c.From
+	if count < 0 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Negative count")
+	}
+	if from < 0 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Negative from")
+	}
+	filter := walletFilter(*c.IncludeWatchOnly)
+
+	// Collect the entries from the newest transaction back, until there
+	// are enough to skip from and return count of them.
+	entries := make([]btcjson.ListTransactionsResult, 0)
+	walletTxns := sortedWalletTxns(w)
+	for i := len(walletTxns) - 1; i >= 0 && len(entries) < count+from; i-- {
+		entries = append(entries, listTransactionEntries(w, walletTxns[i], *c.Account, 0, filter)...)
+	}
+
+	if from > len(entries) {
+		from = len(entries)
+	}
+	if count > len(entries)-from {
+		count = len(entries) - from
+	}
+	entries = entries[from : from+count]
+
+	// Return the oldest entry first.
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+func handleListSinceBlock(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ListSinceBlockCmd)
+
+	targetConfirms := int32(*c.TargetConfirmations)
+	if targetConfirms < 1 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Invalid parameter")
+	}
+	filter := walletFilter(*c.IncludeWatchOnly)
+
+	gChain := chain.GetInstance()
+
+	// The transactions of the blocks after the fork of the given block
+	// with the active chain are listed. Those of the blocks between the
+	// fork and the given block, if it is not in the active chain any more,
+	// are removed.
+	var altIndex *blockindex.BlockIndex
+	depth := int32(-1)
+	if c.BlockHash != nil && *c.BlockHash != "" {
+		blockHash, err := util.GetHashFromStr(*c.BlockHash)
+		if err != nil {
+			return nil, rpcDecodeHexError(*c.BlockHash)
+		}
+		altIndex = gChain.FindBlockIndex(*blockHash)
+		if altIndex == nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Block not found")
+		}
+		forkIndex := gChain.FindFork(altIndex)
+		depth = gChain.Height() + 1 - forkIndex.Height
+	}
+
+	transactions := make([]btcjson.ListTransactionsResult, 0)
+	for _, wtx := range sortedWalletTxns(w) {
+		if depth == -1 || wtx.GetDepthInMainChain() < depth {
+			transactions = append(transactions, listTransactionEntries(w, wtx, "*", 0, filter)...)
+		}
+	}
+
+	var removed []btcjson.ListTransactionsResult
+	if *c.IncludeRemoved {
+		removed = make([]btcjson.ListTransactionsResult, 0)
+		for index := altIndex; index != nil && !gChain.Contains(index); index = index.Prev {
+			blk, ok := disk.ReadBlockFromDisk(index, gChain.GetParams())
+			if !ok {
+				return nil, btcjson.NewRPCError(btcjson.ErrRPCDatabase, "Can't read block from disk")
+			}
+			for _, txn := range blk.Txs {
+				if wtx := w.GetWalletTx(txn.GetHash()); wtx != nil {
+					removed = append(removed, listTransactionEntries(w, wtx, "*", -100000000, filter)...)
+				}
+			}
+		}
+	}
+
+	lastBlockHash := util.HashZero
+	if lastIndex := gChain.GetIndex(gChain.Height() + 1 - targetConfirms); lastIndex != nil {
+		lastBlockHash = *lastIndex.GetBlockHash()
+	}
+
+	return &btcjson.ListSinceBlockResult{
+		Transactions: transactions,
+		Removed:      removed,
+		LastBlock:    lastBlockHash.String(),
+	}, nil
+}
+
+// receivedTally sums what an address received.
+type receivedTally struct {
+	address       string
+	amount        amount.Amount
+	confirmations int32
+	txids         []string
+	watchOnly     bool
+}
+
+// tallyReceived sums by address the outputs the wallet received in the
+// transactions with at least minDepth confirmations.  Coinbase transactions
+// are left out, as in bitcoin core.
+func tallyReceived(w *wallet.Wallet, minDepth int32, filter uint8) map[string]*receivedTally {
+	tallies := make(map[string]*receivedTally)
+	for _, wtx := range w.GetWalletTxns() {
+		if wtx.IsCoinBase() || !lwallet.CheckFinalTx(wtx.Tx) {
+			continue
+		}
+		depth := wtx.GetDepthInMainChain()
+		if depth < minDepth {
+			continue
+		}
+		for _, out := range wtx.GetOuts() {
+			isMine := w.IsMine(out)
+			if isMine&filter == 0 {
+				continue
+			}
+			address, keyHash := walletAddress(out.GetScriptPubKey())
+			if keyHash == nil {
+				continue
+			}
+			tally, ok := tallies[string(keyHash)]
+			if !ok {
+				tally = &receivedTally{address: address, confirmations: math.MaxInt32}
+				tallies[string(keyHash)] = tally
+			}
+			tally.amount += out.GetValue()
+			if depth < tally.confirmations {
+				tally.confirmations = depth
+			}
+			tally.txids = append(tally.txids, wtx.GetHash().String())
+			if isMine&wallet.ISMINE_WATCH_ONLY != 0 {
+				tally.watchOnly = true
+			}
+		}
+	}
+	return tallies
+}
+
+func handleListReceivedByAddress(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ListReceivedByAddressCmd)
+
+	tallies := tallyReceived(w, int32(*c.MinConf), walletFilter(*c.IncludeWatchOnly))
+
+	// The addresses of the address book are listed, those which received
+	// nothing only if include_empty is set.
+	results := make([]*btcjson.ListReceivedByAddressResult, 0)
+	params := chain.GetInstance().GetParams()
+	for keyHash, data := range w.GetAddressBooks() {
+		result := &btcjson.ListReceivedByAddressResult{
+			Account: data.Account,
+			Label:   data.Account,
+			TxIDs:   []string{},
+		}
+		if tally, ok := tallies[keyHash]; ok {
+			result.InvolvesWatchOnly = tally.watchOnly
+			result.Address = tally.address
+			result.Amount = valueFromAmount(int64(tally.amount))
+			result.Confirmations = tally.confirmations
+			result.TxIDs = tally.txids
+		} else if *c.IncludeEmpty {
+			var addr fmt.Stringer
+			var err error
+			if lwallet.GetScript(w, []byte(keyHash)) != nil {
+				addr, err = cashaddr.NewCashAddressScriptHashFromHash([]byte(keyHash), params)
+			} else {
+				addr, err = cashaddr.NewCashAddressPubKeyHash([]byte(keyHash), params)
+			}
+			if err != nil {
+				continue
+			}
+			result.Address = addr.String()
+		} else {
+			continue
+		}
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Address < results[j].Address
+	})
+	return results, nil
+}
+
+func handleGetReceivedByAddress(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetReceivedByAddressCmd)
+
+	scriptPubKey, rpcErr := getStandardScriptPubKey(c.Address, nil)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if !lwallet.IsMine(w, scriptPubKey) {
+		return valueFromAmount(0), nil
+	}
+
+	minDepth := int32(*c.MinConf)
+	received := amount.Amount(0)
+	for _, wtx := range w.GetWalletTxns() {
+		if wtx.IsCoinBase() || !lwallet.CheckFinalTx(wtx.Tx) {
+			continue
+		}
+		if wtx.GetDepthInMainChain() < minDepth {
+			continue
+		}
+		for _, out := range wtx.GetOuts() {
+			if out.GetScriptPubKey().IsEqual(scriptPubKey) {
+				received += out.GetValue()
+			}
+		}
+	}
+	return valueFromAmount(int64(received)), nil
+}
+
+func handleLockUnspent(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.LockUnspentCmd)
+
+	if c.Transactions == nil {
+		if c.Unlock {
+			if err := w.UnlockAllCoins(); err != nil {
+				return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+			}
+		}
+		return true, nil
+	}
+
+	// Check all the outputs before locking or unlocking any of them.
+	outPoints := make([]*outpoint.OutPoint, 0, len(*c.Transactions))
+	for _, input := range *c.Transactions {
+		txHash, err := util.GetHashFromStr(input.Txid)
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				"Invalid parameter, expected hex txid")
+		}
+		wtx := w.GetWalletTx(*txHash)
+		if wtx == nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				"Invalid parameter, unknown transaction")
+		}
+		if int(input.Vout) >= wtx.Tx.GetOutsCount() {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				"Invalid parameter, vout index out of bounds")
+		}
+		outPoint := outpoint.NewOutPoint(*txHash, input.Vout)
+		if w.GetUnspentCoin(outPoint) == nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				"Invalid parameter, expected unspent output")
+		}
+		isLocked := w.IsLockedCoin(outPoint)
+		if c.Unlock && !isLocked {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				"Invalid parameter, expected locked output")
+		}
+		if !c.Unlock && isLocked {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				"Invalid parameter, output already locked")
+		}
+		outPoints = append(outPoints, outPoint)
+	}
+
+	for _, outPoint := range outPoints {
+		var err error
+		if c.Unlock {
+			err = w.UnlockCoin(outPoint)
+		} else {
+			err = w.LockCoin(outPoint, *c.Persistent)
+		}
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+		}
+	}
+	return true, nil
+}
+
+func handleListLockUnspent(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	outPoints := w.ListLockedCoins()
+	results := make([]*btcjson.ListLockUnspentResult, 0, len(outPoints))
+	for _, outPoint := range outPoints {
+		results = append(results, &btcjson.ListLockUnspentResult{
+			TxID: outPoint.Hash.String(),
+			Vout: outPoint.Index,
+		})
+	}
+	return results, nil
+}
+
+func handleAbandonTransaction(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.AbandonTransactionCmd)
+
+	txHash, err := util.GetHashFromStr(c.Txid)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.Txid)
+	}
+	err = w.AbandonTransaction(*txHash)
+	if err == wallet.ErrTxNotInWallet || err == wallet.ErrTxNotAbandonable {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, err.Error())
+	}
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+	return nil, nil
+}
+
+func handleResendWalletTransactions(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if !w.GetBroadcastTx() {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet,
+			"Error: Wallet transaction broadcasting is disabled with -walletbroadcast")
+	}
+
+	relayed := lwallet.ResendWalletTransactionsBefore(w, time.Now().Unix())
+	txids := make([]string, 0, len(relayed))
+	for _, txHash := range relayed {
+		txids = append(txids, txHash.String())
+	}
+	return txids, nil
+}
+
+func handleCreateWallet(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if !conf.Cfg.Wallet.Enable {
+		return nil, walletDisableRPCError
+	}
+	c := cmd.(*btcjson.CreateWalletCmd)
+
+	w, err := lwallet.CreateWallet(c.WalletName)
+	if err != nil {
+		return nil, walletLoaderRPCError(c.WalletName, err)
+	}
+	return &btcjson.LoadWalletResult{Name: w.GetName()}, nil
+}
+
+func handleLoadWallet(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if !conf.Cfg.Wallet.Enable {
+		return nil, walletDisableRPCError
+	}
+	c := cmd.(*btcjson.LoadWalletCmd)
+
+	w, err := lwallet.LoadWallet(c.Filename)
+	if err != nil {
+		return nil, walletLoaderRPCError(c.Filename, err)
+	}
+	return &btcjson.LoadWalletResult{Name: w.GetName()}, nil
+}
+
+func handleUnloadWallet(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if err := lwallet.UnloadWallet(w); err != nil {
+		return nil, walletLoaderRPCError(w.GetName(), err)
+	}
+	return nil, nil
+}
+
+func handleListWallets(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if !conf.Cfg.Wallet.Enable {
+		return nil, walletDisableRPCError
+	}
+
+	names := make([]string, 0)
+	for _, w := range wallet.GetWallets() {
+		names = append(names, w.GetName())
+	}
+	return names, nil
+}
+
+func walletLoaderRPCError(name string, err error) *btcjson.RPCError {
+	switch err {
+	case wallet.ErrWalletNotFound:
+		return btcjson.NewRPCError(btcjson.ErrRPCWalletNotFound, fmt.Sprintf("Wallet %s not found.", name))
+	case wallet.ErrWalletExists:
+		return btcjson.NewRPCError(btcjson.ErrRPCWallet, fmt.Sprintf("Wallet %s already exists.", name))
+	case wallet.ErrWalletLoaded:
+		return btcjson.NewRPCError(btcjson.ErrRPCWallet, fmt.Sprintf("Wallet %s is already loaded.", name))
+	case wallet.ErrInvalidWalletName:
+		return btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, fmt.Sprintf("Invalid wallet name: %q", name))
+	}
+	return btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+}
+
+func handleWalletCreateFundedPsbt(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.WalletCreateFundedPsbtCmd)
+
+	transaction, rpcErr := constructTransaction(c.Inputs, c.Outputs, c.LockTime)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	changePos, fee, rpcErr := fundTransaction(w, transaction, c.Options)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	p, err := psbt.New(transaction)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
+	if _, err := lwallet.FillPSBT(w, p, hashType, false); err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
+	}
+
+	encoded, rpcErr := encodePsbt(p)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return &btcjson.WalletCreateFundedPsbtResult{
+		Psbt:      encoded,
+		Fee:       fee.ToBTC(),
+		ChangePos: changePos,
+	}, nil
+}
+
+func handleWalletProcessPsbt(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.WalletProcessPsbtCmd)
+
+	p, rpcErr := decodePsbt(c.Psbt)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	hashType, rpcErr := parseSigHashType(*c.SigHashType)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if *c.Sign {
+		if rpcErr := ensureWalletIsUnlocked(w); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+
+	complete, err := lwallet.FillPSBT(w, p, hashType, *c.Sign)
+	if err == crypto.ErrKeyStoreLocked {
+		return nil, walletUnlockNeededRPCError
+	}
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCDeserialization, err.Error())
+	}
+
+	encoded, rpcErr := encodePsbt(p)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return &btcjson.WalletProcessPsbtResult{
+		Psbt:     encoded,
+		Complete: complete,
+	}, nil
+}
+
+func handleSignMessage(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SignMessageCmd)
+
+	if rpcErr := ensureWalletIsUnlocked(w); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	addrType, keyHash, rpcErr := decodeAddress(c.Address)
+	if rpcErr != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCType, "Invalid address")
+	}
+	if addrType != cashaddr.P2PKH {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCType, "Address does not refer to key")
+	}
+
+	keyPair := lwallet.GetKeyPair(w, keyHash)
+	if keyPair == nil || keyPair.GetPrivateKey() == nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, "Private key not available")
+	}
+
+	signature, err := keyPair.GetPrivateKey().SignCompact(messageHash(c.Message))
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Sign failed")
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+func handleGetAddressInfo(s *Server, w *wallet.Wallet, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressInfoCmd)
+
+	scriptPubKey, rpcErr := getStandardScriptPubKey(c.Address, nil)
+	if rpcErr != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Invalid address")
+	}
+	addrType, keyHash, _ := decodeAddress(c.Address)
+
+	result := &btcjson.GetAddressInfoResult{
+		Address:      c.Address,
+		ScriptPubKey: hex.EncodeToString(scriptPubKey.GetData()),
+		IsMine:       lwallet.IsMine(w, scriptPubKey),
+		IsWatchOnly:  lwallet.IsWatchOnly(w, scriptPubKey),
+		IsScript:     addrType == cashaddr.P2SH,
+		IsChange:     w.IsChange(txout.NewTxOut(0, scriptPubKey)),
+		Label:        lwallet.GetAccountName(w, keyHash),
+	}
+
+	if result.IsScript {
+		// The redeem script of a pay-to-script-hash address the wallet knows.
+		if redeemScript := lwallet.GetScript(w, keyHash); redeemScript != nil {
+			scriptType, pubKeys, _ := redeemScript.IsStandardScriptPubKey()
+			result.Script = GetTxnOutputType(scriptType)
+			result.Hex = hex.EncodeToString(redeemScript.GetData())
+			if scriptType == script.ScriptMultiSig {
+				result.SigsRequired = int32(pubKeys[0][0])
+				for _, pubKey := range pubKeys[1 : len(pubKeys)-1] {
+					result.PubKeys = append(result.PubKeys, hex.EncodeToString(pubKey))
+				}
+			}
+		}
+		return result, nil
+	}
+
+	if keyPair := lwallet.GetKeyPair(w, keyHash); keyPair != nil {
+		compressed := keyPair.GetPublicKey().Compressed
+		result.PubKey = keyPair.GetPublicKey().ToHexString()
+		result.IsCompressed = &compressed
+	}
+	if meta := lwallet.GetKeyMetadata(w, keyHash); meta != nil {
+		result.TimeStamp = meta.CreateTime
+		result.HDKeyPath = meta.HDKeyPath
+		if len(meta.HDSeedID) > 0 {
+			result.HDMasterKeyID = hex.EncodeToString(meta.HDSeedID)
+		}
+	}
+	return result, nil
+}
+
+func registerWalletRPCCommands() {
+	for name, handler := range walletHandlers {
+		appendCommand(name, withRequestWallet(handler, nil))
+	}
+	for name, handler := range walletLoaderHandlers {
 		appendCommand(name, handler)
 	}
 }
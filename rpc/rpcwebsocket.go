@@ -0,0 +1,1340 @@
+package rpc
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/cashaddr"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// websocketSendBufferSize is the number of elements the send channel
+	// can queue before blocking.  Note that this only applies to requests
+	// handled directly in the websocket client input handler or the async
+	// handler since notifications have their own queuing mechanism
+	// independent of the send channel buffer.
+	websocketSendBufferSize = 50
+)
+
+// timeZeroVal is simply the zero value for a time.Time and is used to avoid
+// creating multiple instances.
+var timeZeroVal time.Time
+
+// wsCommandHandler describes a callback function used to handle a specific
+// command.
+type wsCommandHandler func(*wsClient, interface{}) (interface{}, error)
+
+// wsHandlers maps RPC command strings to appropriate websocket handler
+// functions.  This is set by init because help references wsHandlers and thus
+// causes a dependency loop.
+var wsHandlers map[string]wsCommandHandler
+var wsHandlersBeforeInit = map[string]wsCommandHandler{
+	"help":                      handleWebsocketHelp,
+	"loadtxfilter":              handleLoadTxFilter,
+	"notifyblocks":              handleNotifyBlocks,
+	"notifynewtransactions":     handleNotifyNewTransactions,
+	"notifyreceived":            handleNotifyReceived,
+	"stopnotifyblocks":          handleStopNotifyBlocks,
+	"stopnotifynewtransactions": handleStopNotifyNewTransactions,
+	"stopnotifyreceived":        handleStopNotifyReceived,
+}
+
+// WebsocketHandler handles a new websocket client by creating a new wsClient,
+// starting it, and blocking until the connection closes.  Since it blocks, it
+// must be run in a separate goroutine.  It should be invoked from the websocket
+// server handler which runs each new connection in a new goroutine thereby
+// satisfying the requirement.
+func (s *Server) WebsocketHandler(conn *websocket.Conn, remoteAddr string, isAdmin bool) {
+	// Clear the read deadline that was set before the websocket hijacked
+	// the connection.
+	conn.SetReadDeadline(timeZeroVal)
+
+	// Limit max number of websocket clients.
+	log.Info("New websocket client %s", remoteAddr)
+	if s.ntfnMgr.NumClients()+1 > conf.Cfg.RPC.RPCMaxWebsockets {
+		log.Info("Max websocket clients exceeded [%d] - "+
+			"disconnecting client %s", conf.Cfg.RPC.RPCMaxWebsockets,
+			remoteAddr)
+		conn.Close()
+		return
+	}
+
+	client := newWebsocketClient(s, conn, remoteAddr, isAdmin)
+	s.ntfnMgr.AddClient(client)
+	client.Start()
+	client.WaitForShutdown()
+	s.ntfnMgr.RemoveClient(client)
+	log.Info("Disconnected websocket client %s", remoteAddr)
+}
+
+// wsNotificationManager is a connection and notification manager used for
+// websockets.  It allows websocket clients to register for notifications they
+// are interested in.  When an event happens elsewhere in the code such as
+// transactions being added to the memory pool or block connects/disconnects,
+// the notification manager is provided with the relevant details needed to
+// figure out which websocket clients need to be notified based on what they
+// have registered for and notifies them accordingly.  It is also used to keep
+// track of all connected websocket clients.
+type wsNotificationManager struct {
+	// queueNotification queues a notification for handling.
+	queueNotification chan interface{}
+
+	// notificationMsgs feeds notificationHandler with notifications
+	// and client (un)registeration requests from a queue as well as
+	// registeration and unregisteration requests from clients.
+	notificationMsgs chan interface{}
+
+	// Access channel for current number of connected clients.
+	numClients chan int
+
+	// Shutdown handling
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// queueHandler manages a queue of empty interfaces, reading from in and
+// sending the oldest unsent to out.  This handler stops when either of the
+// in or quit channels are closed, and closes out before returning, without
+// waiting to send any variables still remaining in the queue.
+func queueHandler(in <-chan interface{}, out chan<- interface{}, quit <-chan struct{}) {
+	var q []interface{}
+	var dequeue chan<- interface{}
+	skipQueue := out
+	var next interface{}
+out:
+	for {
+		select {
+		case n, ok := <-in:
+			if !ok {
+				// Sender closed input channel.
+				break out
+			}
+
+			// Either send to out immediately if skipQueue is
+			// non-nil (queue is empty) and reader is ready,
+			// or append to the queue and send later.
+			select {
+			case skipQueue <- n:
+			default:
+				q = append(q, n)
+				dequeue = out
+				skipQueue = nil
+				next = q[0]
+			}
+
+		case dequeue <- next:
+			copy(q, q[1:])
+			q[len(q)-1] = nil // avoid leak
+			q = q[:len(q)-1]
+			if len(q) == 0 {
+				dequeue = nil
+				skipQueue = out
+			} else {
+				next = q[0]
+			}
+
+		case <-quit:
+			break out
+		}
+	}
+	close(out)
+}
+
+// queueHandler maintains a queue of notifications and notification handler
+// control messages.
+func (m *wsNotificationManager) queueHandler() {
+	queueHandler(m.queueNotification, m.notificationMsgs, m.quit)
+	m.wg.Done()
+}
+
+// queue hands a notification over to the queue handler, without blocking once
+// the manager is shut down.
+func (m *wsNotificationManager) queue(n interface{}) {
+	select {
+	case m.queueNotification <- n:
+	case <-m.quit:
+	}
+}
+
+// Notification types
+type notificationBlockConnected struct {
+	block  *block.Block
+	height int32
+}
+type notificationBlockDisconnected struct {
+	block  *block.Block
+	height int32
+}
+type notificationTxAcceptedByMempool tx.Tx
+
+// Notification control requests
+type notificationRegisterClient wsClient
+type notificationUnregisterClient wsClient
+type notificationRegisterBlocks wsClient
+type notificationUnregisterBlocks wsClient
+type notificationRegisterNewMempoolTxs wsClient
+type notificationUnregisterNewMempoolTxs wsClient
+type notificationRegisterAddr struct {
+	wsc  *wsClient
+	keys []string
+}
+type notificationUnregisterAddr struct {
+	wsc  *wsClient
+	keys []string
+}
+
+// NotifyBlockConnected passes a block newly-connected to the best chain
+// to the notification manager for block and transaction notification
+// processing.
+func (m *wsNotificationManager) NotifyBlockConnected(blk *block.Block, height int32) {
+	m.queue(&notificationBlockConnected{block: blk, height: height})
+}
+
+// NotifyBlockDisconnected passes a block disconnected from the best chain
+// to the notification manager for block notification processing.
+func (m *wsNotificationManager) NotifyBlockDisconnected(blk *block.Block, height int32) {
+	m.queue(&notificationBlockDisconnected{block: blk, height: height})
+}
+
+// NotifyMempoolTx passes a transaction accepted by mempool to the
+// notification manager for transaction notification processing.
+func (m *wsNotificationManager) NotifyMempoolTx(txEntry *mempool.TxEntry) {
+	m.queue((*notificationTxAcceptedByMempool)(txEntry.Tx))
+}
+
+// notificationHandler reads notifications and control messages from the queue
+// handler and processes one at a time.
+func (m *wsNotificationManager) notificationHandler() {
+	// clients is a map of all currently connected websocket clients.
+	clients := make(map[chan struct{}]*wsClient)
+
+	// Maps used to hold lists of websocket clients to be notified on
+	// certain events.  Each websocket client also keeps maps for the events
+	// which have multiple triggers to make removal from these lists on
+	// connection close less horrendously expensive.
+	//
+	// Where possible, the quit channel is used as the unique id for a client
+	// since it is quite a bit more efficient than using the entire struct.
+	blockNotifications := make(map[chan struct{}]*wsClient)
+	txNotifications := make(map[chan struct{}]*wsClient)
+	watchedAddrs := make(map[string]map[chan struct{}]*wsClient)
+
+out:
+	for {
+		select {
+		case n, ok := <-m.notificationMsgs:
+			if !ok {
+				// queueHandler quit.
+				break out
+			}
+			switch n := n.(type) {
+			case *notificationBlockConnected:
+				if len(blockNotifications) != 0 {
+					m.notifyBlockConnected(blockNotifications, n.block, n.height)
+					m.notifyFilteredBlockConnected(blockNotifications, n.block, n.height)
+				}
+				if len(watchedAddrs) != 0 {
+					for i, txn := range n.block.Txs {
+						details := blockDetails(n.block, n.height, i)
+						m.notifyForTxOuts(watchedAddrs, txn, details)
+					}
+				}
+
+			case *notificationBlockDisconnected:
+				if len(blockNotifications) != 0 {
+					m.notifyBlockDisconnected(blockNotifications, n.block, n.height)
+				}
+
+			case *notificationTxAcceptedByMempool:
+				txn := (*tx.Tx)(n)
+				if len(txNotifications) != 0 {
+					m.notifyForNewTx(txNotifications, txn)
+				}
+				m.notifyRelevantTxAccepted(clients, txn)
+				if len(watchedAddrs) != 0 {
+					m.notifyForTxOuts(watchedAddrs, txn, nil)
+				}
+
+			case *notificationRegisterBlocks:
+				wsc := (*wsClient)(n)
+				blockNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterBlocks:
+				wsc := (*wsClient)(n)
+				delete(blockNotifications, wsc.quit)
+
+			case *notificationRegisterClient:
+				wsc := (*wsClient)(n)
+				clients[wsc.quit] = wsc
+
+			case *notificationUnregisterClient:
+				wsc := (*wsClient)(n)
+				// Remove any requests made by the client as well as
+				// the client itself.
+				delete(blockNotifications, wsc.quit)
+				delete(txNotifications, wsc.quit)
+				for key := range wsc.addrRequests {
+					removeAddrRequest(watchedAddrs, wsc, key)
+				}
+				delete(clients, wsc.quit)
+
+			case *notificationRegisterAddr:
+				for _, key := range n.keys {
+					addAddrRequest(watchedAddrs, n.wsc, key)
+				}
+
+			case *notificationUnregisterAddr:
+				for _, key := range n.keys {
+					removeAddrRequest(watchedAddrs, n.wsc, key)
+				}
+
+			case *notificationRegisterNewMempoolTxs:
+				wsc := (*wsClient)(n)
+				txNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterNewMempoolTxs:
+				wsc := (*wsClient)(n)
+				delete(txNotifications, wsc.quit)
+
+			default:
+				log.Warn("Unhandled notification type")
+			}
+
+		case m.numClients <- len(clients):
+
+		case <-m.quit:
+			// RPC server shutting down.
+			break out
+		}
+	}
+
+	for _, c := range clients {
+		c.Disconnect()
+	}
+	m.wg.Done()
+}
+
+// NumClients returns the number of clients actively being served.
+func (m *wsNotificationManager) NumClients() (n int) {
+	select {
+	case n = <-m.numClients:
+	case <-m.quit: // Use default n (0) if server has shut down.
+	}
+	return
+}
+
+// RegisterBlockUpdates requests block update notifications to the passed
+// websocket client.
+func (m *wsNotificationManager) RegisterBlockUpdates(wsc *wsClient) {
+	m.queue((*notificationRegisterBlocks)(wsc))
+}
+
+// UnregisterBlockUpdates removes block update notifications for the passed
+// websocket client.
+func (m *wsNotificationManager) UnregisterBlockUpdates(wsc *wsClient) {
+	m.queue((*notificationUnregisterBlocks)(wsc))
+}
+
+// RegisterNewMempoolTxsUpdates requests notifications to the passed websocket
+// client when new transactions are added to the memory pool.
+func (m *wsNotificationManager) RegisterNewMempoolTxsUpdates(wsc *wsClient) {
+	m.queue((*notificationRegisterNewMempoolTxs)(wsc))
+}
+
+// UnregisterNewMempoolTxsUpdates removes notifications to the passed websocket
+// client when new transaction are added to the memory pool.
+func (m *wsNotificationManager) UnregisterNewMempoolTxsUpdates(wsc *wsClient) {
+	m.queue((*notificationUnregisterNewMempoolTxs)(wsc))
+}
+
+// RegisterTxOutAddressRequests requests notifications to the passed websocket
+// client when a transaction output pays to one of the passed addresses.
+func (m *wsNotificationManager) RegisterTxOutAddressRequests(wsc *wsClient, keys []string) {
+	m.queue(&notificationRegisterAddr{wsc: wsc, keys: keys})
+}
+
+// UnregisterTxOutAddressRequests removes a request from the passed websocket
+// client to be notified when a transaction pays to one of the passed
+// addresses.
+func (m *wsNotificationManager) UnregisterTxOutAddressRequests(wsc *wsClient, keys []string) {
+	m.queue(&notificationUnregisterAddr{wsc: wsc, keys: keys})
+}
+
+// AddClient adds the passed websocket client to the notification manager.
+func (m *wsNotificationManager) AddClient(wsc *wsClient) {
+	m.queue((*notificationRegisterClient)(wsc))
+}
+
+// RemoveClient removes the passed websocket client and all notifications
+// registered for it.
+func (m *wsNotificationManager) RemoveClient(wsc *wsClient) {
+	m.queue((*notificationUnregisterClient)(wsc))
+}
+
+// Start starts the goroutines required for the manager to queue and process
+// websocket client notifications.
+func (m *wsNotificationManager) Start() {
+	m.wg.Add(2)
+	go m.queueHandler()
+	go m.notificationHandler()
+}
+
+// WaitForShutdown blocks until all notification manager goroutines have
+// finished.
+func (m *wsNotificationManager) WaitForShutdown() {
+	m.wg.Wait()
+}
+
+// Shutdown shuts down the manager, stopping the notification queue and
+// notification handler goroutines.
+func (m *wsNotificationManager) Shutdown() {
+	close(m.quit)
+}
+
+// newWsNotificationManager returns a new notification manager ready for use.
+// See wsNotificationManager for more details.
+func newWsNotificationManager() *wsNotificationManager {
+	return &wsNotificationManager{
+		queueNotification: make(chan interface{}),
+		notificationMsgs:  make(chan interface{}),
+		numClients:        make(chan int),
+		quit:              make(chan struct{}),
+	}
+}
+
+// notifyBlockConnected notifies websocket clients that have registered for
+// block updates when a block is connected to the main chain.
+func (*wsNotificationManager) notifyBlockConnected(clients map[chan struct{}]*wsClient,
+	blk *block.Block, height int32) {
+
+	hash := blk.GetHash()
+	ntfn := btcjson.NewBlockConnectedNtfn(hash.String(), height, int64(blk.Header.Time))
+	marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
+	if err != nil {
+		log.Error("Failed to marshal block connected notification: %v", err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// notifyFilteredBlockConnected notifies websocket clients that have registered
+// for block updates and loaded a tx filter when a block is connected to the
+// main chain.
+func (*wsNotificationManager) notifyFilteredBlockConnected(clients map[chan struct{}]*wsClient,
+	blk *block.Block, height int32) {
+
+	var headerBuf bytes.Buffer
+	if err := blk.Header.Serialize(&headerBuf); err != nil {
+		log.Error("Failed to serialize header for filtered block connected notification: %v", err)
+		return
+	}
+	header := hex.EncodeToString(headerBuf.Bytes())
+
+	for _, wsc := range clients {
+		wsc.Lock()
+		filter := wsc.filterData
+		wsc.Unlock()
+		if filter == nil {
+			continue
+		}
+
+		subscribedTxs := make([]string, 0)
+		for _, txn := range blk.Txs {
+			if filter.matchAndUpdate(txn) {
+				txHex, err := txHexString(txn)
+				if err != nil {
+					log.Error("Failed to serialize tx %s: %v", txn.GetHash(), err)
+					continue
+				}
+				subscribedTxs = append(subscribedTxs, txHex)
+			}
+		}
+
+		ntfn := btcjson.NewFilteredBlockConnectedNtfn(height, header, subscribedTxs)
+		marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
+		if err != nil {
+			log.Error("Failed to marshal filtered block connected notification: %v", err)
+			return
+		}
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// notifyBlockDisconnected notifies websocket clients that have registered for
+// block updates when a block is disconnected from the main chain (due to a
+// reorganize).
+func (*wsNotificationManager) notifyBlockDisconnected(clients map[chan struct{}]*wsClient,
+	blk *block.Block, height int32) {
+
+	hash := blk.GetHash()
+	ntfn := btcjson.NewBlockDisconnectedNtfn(hash.String(), height, int64(blk.Header.Time))
+	marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
+	if err != nil {
+		log.Error("Failed to marshal block disconnected notification: %v", err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// notifyForNewTx notifies websocket clients that have registered for updates
+// when a new transaction is added to the memory pool.
+func (*wsNotificationManager) notifyForNewTx(clients map[chan struct{}]*wsClient, txn *tx.Tx) {
+	txHash := txn.GetHash()
+	amount := valueFromAmount(int64(txn.GetValueOut()))
+	ntfn := btcjson.NewTxAcceptedNtfn(txHash.String(), amount)
+	marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
+	if err != nil {
+		log.Error("Failed to marshal tx notification: %v", err)
+		return
+	}
+
+	var verboseNtfn *btcjson.TxAcceptedVerboseNtfn
+	var marshalledJSONVerbose []byte
+	for _, wsc := range clients {
+		wsc.Lock()
+		verbose := wsc.verboseTxUpdates
+		wsc.Unlock()
+		if !verbose {
+			wsc.QueueNotification(marshalledJSON)
+			continue
+		}
+
+		if verboseNtfn == nil {
+			txHex, err := txHexString(txn)
+			if err != nil {
+				log.Error("Failed to serialize tx %s: %v", txHash, err)
+				return
+			}
+			rawTx, rpcErr := getTxRawResult(txn, nil, txHex)
+			if rpcErr != nil {
+				return
+			}
+			verboseNtfn = btcjson.NewTxAcceptedVerboseNtfn(*rawTx)
+			marshalledJSONVerbose, err = btcjson.MarshalCmd(nil, verboseNtfn)
+			if err != nil {
+				log.Error("Failed to marshal verbose tx notification: %v", err)
+				return
+			}
+		}
+		wsc.QueueNotification(marshalledJSONVerbose)
+	}
+}
+
+// notifyRelevantTxAccepted notifies websocket clients which loaded a tx filter
+// when a transaction matching it is added to the memory pool.
+func (*wsNotificationManager) notifyRelevantTxAccepted(clients map[chan struct{}]*wsClient, txn *tx.Tx) {
+	var marshalledJSON []byte
+	for _, wsc := range clients {
+		wsc.Lock()
+		filter := wsc.filterData
+		wsc.Unlock()
+		if filter == nil || !filter.matchAndUpdate(txn) {
+			continue
+		}
+
+		if marshalledJSON == nil {
+			txHex, err := txHexString(txn)
+			if err != nil {
+				log.Error("Failed to serialize tx %s: %v", txn.GetHash(), err)
+				return
+			}
+			ntfn := btcjson.NewRelevantTxAcceptedNtfn(txHex)
+			marshalledJSON, err = btcjson.MarshalCmd(nil, ntfn)
+			if err != nil {
+				log.Error("Failed to marshal relevant tx notification: %v", err)
+				return
+			}
+		}
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// notifyForTxOuts notifies websocket clients that have registered for
+// notifications when a transaction pays to one of their addresses.  details is
+// nil for transactions of the memory pool.
+func (*wsNotificationManager) notifyForTxOuts(watchedAddrs map[string]map[chan struct{}]*wsClient,
+	txn *tx.Tx, details *btcjson.BlockDetails) {
+
+	var marshalledJSON []byte
+	notified := make(map[chan struct{}]struct{})
+	for _, out := range txn.GetOuts() {
+		for _, key := range scriptAddressKeys(out.GetScriptPubKey()) {
+			for quit, wsc := range watchedAddrs[key] {
+				if _, ok := notified[quit]; ok {
+					continue
+				}
+
+				if marshalledJSON == nil {
+					txHex, err := txHexString(txn)
+					if err != nil {
+						log.Error("Failed to serialize tx %s: %v", txn.GetHash(), err)
+						return
+					}
+					ntfn := btcjson.NewRecvTxNtfn(txHex, details)
+					marshalledJSON, err = btcjson.MarshalCmd(nil, ntfn)
+					if err != nil {
+						log.Error("Failed to marshal recvtx notification: %v", err)
+						return
+					}
+				}
+				wsc.QueueNotification(marshalledJSON)
+				notified[quit] = struct{}{}
+			}
+		}
+	}
+}
+
+// addAddrRequest adds the websocket client wsc to the address to client set
+// watchedAddrs so wsc will be notified for any mempool or block transaction
+// outputs spending to the address.
+func addAddrRequest(watchedAddrs map[string]map[chan struct{}]*wsClient, wsc *wsClient, key string) {
+	cmap, ok := watchedAddrs[key]
+	if !ok {
+		cmap = make(map[chan struct{}]*wsClient)
+		watchedAddrs[key] = cmap
+	}
+	cmap[wsc.quit] = wsc
+	wsc.addrRequests[key] = struct{}{}
+}
+
+// removeAddrRequest removes the websocket client wsc from the address to
+// client set watchedAddrs so it will no longer receive notification updates
+// for any transaction outputs send to the address.
+func removeAddrRequest(watchedAddrs map[string]map[chan struct{}]*wsClient, wsc *wsClient, key string) {
+	delete(wsc.addrRequests, key)
+
+	cmap, ok := watchedAddrs[key]
+	if !ok {
+		return
+	}
+	delete(cmap, wsc.quit)
+	if len(cmap) == 0 {
+		delete(watchedAddrs, key)
+	}
+}
+
+// blockDetails returns the details of the tx at txIndex of blk for recvtx
+// notifications.
+func blockDetails(blk *block.Block, height int32, txIndex int) *btcjson.BlockDetails {
+	hash := blk.GetHash()
+	return &btcjson.BlockDetails{
+		Height: height,
+		Hash:   hash.String(),
+		Index:  txIndex,
+		Time:   int64(blk.Header.Time),
+	}
+}
+
+// txHexString returns the serialized transaction encoded as a hex string.
+func txHexString(txn *tx.Tx) (string, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, txn.SerializeSize()))
+	if err := txn.Serialize(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// addressKey returns the key identifying an address in the watched address
+// sets, the same for its legacy and cash address encoding.
+func addressKey(addrType cashaddr.AddressType, hash []byte) string {
+	return string(append([]byte{byte(addrType)}, hash...))
+}
+
+// decodeAddressKey returns the key of the passed legacy or cash address.
+func decodeAddressKey(address string) (string, *btcjson.RPCError) {
+	addrType, hash, rpcErr := decodeAddress(address)
+	if rpcErr != nil {
+		return "", rpcErr
+	}
+	return addressKey(addrType, hash), nil
+}
+
+// scriptAddressKeys returns the keys of the addresses the passed script pays
+// to.  A pay-to-pubkey script matches the address of the pubkey.
+func scriptAddressKeys(scriptPubKey *script.Script) []string {
+	_, addrs, _, err := scriptPubKey.ExtractDestinations()
+	if err != nil || len(addrs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		addrType := cashaddr.P2PKH
+		if addr.GetVersion() == script.AddressVerScript() {
+			addrType = cashaddr.P2SH
+		}
+		keys = append(keys, addressKey(addrType, addr.EncodeToPubKeyHash()))
+	}
+	return keys
+}
+
+// wsClientFilter tracks relevant addresses for each websocket client for
+// loadtxfilter.  It is modified as transactions are matched: the outpoints of
+// outputs paying to a relevant address are added, and spent outpoints are
+// removed.
+//
+// NOTE: This extension was ported from github.com/decred/dcrd
+type wsClientFilter struct {
+	mu sync.Mutex
+
+	addrs   map[string]struct{}
+	unspent map[outpoint.OutPoint]struct{}
+}
+
+// newWSClientFilter creates a new, empty wsClientFilter struct to be used
+// for a websocket client.
+func newWSClientFilter(addresses []string, unspentOutPoints []outpoint.OutPoint) (*wsClientFilter, *btcjson.RPCError) {
+	filter := &wsClientFilter{
+		addrs:   make(map[string]struct{}, len(addresses)),
+		unspent: make(map[outpoint.OutPoint]struct{}, len(unspentOutPoints)),
+	}
+
+	for _, s := range addresses {
+		key, rpcErr := decodeAddressKey(s)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		filter.addrs[key] = struct{}{}
+	}
+	for _, op := range unspentOutPoints {
+		filter.unspent[op] = struct{}{}
+	}
+
+	return filter, nil
+}
+
+// matchAndUpdate returns whether txn spends an outpoint or pays to an address
+// of the filter, and updates the outpoints of the filter.
+func (f *wsClientFilter) matchAndUpdate(txn *tx.Tx) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	matched := false
+	if !txn.IsCoinBase() {
+		for _, in := range txn.GetIns() {
+			if _, ok := f.unspent[*in.PreviousOutPoint]; ok {
+				delete(f.unspent, *in.PreviousOutPoint)
+				matched = true
+			}
+		}
+	}
+
+	txHash := txn.GetHash()
+	for i, out := range txn.GetOuts() {
+		for _, key := range scriptAddressKeys(out.GetScriptPubKey()) {
+			if _, ok := f.addrs[key]; ok {
+				f.unspent[*outpoint.NewOutPoint(txHash, uint32(i))] = struct{}{}
+				matched = true
+				break
+			}
+		}
+	}
+
+	return matched
+}
+
+// wsResponse houses a message to send to a connected websocket client as
+// well as a channel to reply on when the message is sent.
+type wsResponse struct {
+	msg      []byte
+	doneChan chan bool
+}
+
+// wsClient provides an abstraction for handling a websocket client.  The
+// overall data flow is split into 3 main goroutines, a possible 4th goroutine
+// for long-running operations (only started if request is made), and a
+// websocket manager which is used to allow things such as broadcasting
+// requested notifications to all connected websocket clients.   Inbound
+// messages are read via the inHandler goroutine and generally dispatched to
+// their own handler.  However, certain potentially long-running operations such
+// as rescans, are sent to the asyncHander goroutine and are limited to one at a
+// time.  There are two outbound message types - one for responding to client
+// requests and another for async notifications.  Responses to client requests
+// use SendMessage which employs a buffered channel thereby limiting the number
+// of outstanding requests that can be made.  Notifications are sent via
+// QueueNotification which implements a queue via notificationQueueHandler to
+// ensure sending notifications from other subsystems can't block.  Ultimately,
+// all messages are sent via the outHandler.
+type wsClient struct {
+	sync.Mutex
+
+	// server is the RPC server that is servicing the client.
+	server *Server
+
+	// conn is the underlying websocket connection.
+	conn *websocket.Conn
+
+	// disconnected indicated whether or not the websocket client is
+	// disconnected.
+	disconnected bool
+
+	// addr is the remote address of the client.
+	addr string
+
+	// isAdmin specifies whether a client may change the state of the server;
+	// false means its access is only to the limited set of RPC calls.
+	isAdmin bool
+
+	// verboseTxUpdates specifies whether a client has requested verbose
+	// information about all new transactions.
+	verboseTxUpdates bool
+
+	// addrRequests is a set of addresses the caller has requested to be
+	// notified about.  It is maintained here so all requests can be removed
+	// when a wallet disconnects.  Owned by the notification manager.
+	addrRequests map[string]struct{}
+
+	// filterData is the tx filter loaded by loadtxfilter, nil until then.
+	filterData *wsClientFilter
+
+	// Networking infrastructure.
+	ntfnChan chan []byte
+	sendChan chan wsResponse
+	quit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// parseRequest parses a JSON-RPC request with either positional or named
+// params.  jsonParams is nil for positional params.
+func parseRequest(msg []byte) (*btcjson.Request, *map[string]json.RawMessage, error) {
+	var request btcjson.Request
+	if err := json.Unmarshal(msg, &request); err == nil {
+		return &request, nil, nil
+	}
+
+	var jsonParamRequest btcjson.JSONParamRequest
+	if err := json.Unmarshal(msg, &jsonParamRequest); err != nil {
+		return nil, nil, err
+	}
+	request = btcjson.Request{
+		Jsonrpc: jsonParamRequest.Jsonrpc,
+		Method:  jsonParamRequest.Method,
+		ID:      jsonParamRequest.ID,
+	}
+	return &request, &jsonParamRequest.Params, nil
+}
+
+// inHandler handles all incoming messages for the websocket connection.  It
+// must be run as a goroutine.
+func (c *wsClient) inHandler() {
+out:
+	for {
+		// Break out of the loop once the quit channel has been closed.
+		// Use a non-blocking select here so we fall through otherwise.
+		select {
+		case <-c.quit:
+			break out
+		default:
+		}
+
+		_, msg, err := c.conn.ReadMessage()
+		if err != nil {
+			// Log the error if it's not due to disconnecting.
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Debug("Websocket receive error from %s: %v", c.addr, err)
+			}
+			break out
+		}
+
+		request, jsonParams, err := parseRequest(msg)
+		if err != nil {
+			jsonErr := &btcjson.RPCError{
+				Code:    btcjson.ErrRPCParse.Code,
+				Message: "Failed to parse request: " + err.Error(),
+			}
+			reply, err := createMarshalledReply(nil, nil, jsonErr)
+			if err != nil {
+				log.Error("Failed to marshal parse failure reply: %v", err)
+				continue
+			}
+			c.SendMessage(reply, nil)
+			continue
+		}
+
+		// Requests with no ID (notifications) must not have a response
+		// per the JSON-RPC spec.
+		if request.ID == nil {
+			continue
+		}
+
+		cmd := parseCmd(request, jsonParams)
+		if cmd.err != nil {
+			reply, err := createMarshalledReply(cmd.id, nil, cmd.err)
+			if err != nil {
+				log.Error("Failed to marshal parse failure reply: %v", err)
+				continue
+			}
+			c.SendMessage(reply, nil)
+			continue
+		}
+
+		log.Trace(">ws rpc:: %s from %s", cmd.method, c.addr)
+		var result interface{}
+		if wsHandler, ok := wsHandlers[cmd.method]; ok {
+			result, err = wsHandler(c, cmd.cmd)
+		} else {
+			result, err = c.server.standardCmdResult(cmd, c.quit)
+		}
+		log.Trace("<ws rpc:: %s from %s", cmd.method, c.addr)
+
+		reply, err := createMarshalledReply(cmd.id, result, err)
+		if err != nil {
+			log.Error("Failed to marshal reply for <%s> command: %v", cmd.method, err)
+			continue
+		}
+		c.SendMessage(reply, nil)
+	}
+
+	// Ensure the connection is closed.
+	c.Disconnect()
+	c.wg.Done()
+	log.Trace("Websocket client input handler done for %s", c.addr)
+}
+
+// notificationQueueHandler handles the queuing of outgoing notifications for
+// the websocket client.  This runs as a muxer for various sources of input to
+// ensure that queuing up notifications to be sent will not block.  Otherwise,
+// slow clients could bog down the other systems (such as the mempool or block
+// manager) which are queuing the data.  The data is passed on to outHandler to
+// actually be written.  It must be run as a goroutine.
+func (c *wsClient) notificationQueueHandler() {
+	ntfnSentChan := make(chan bool, 1) // nonblocking sync
+
+	// pendingNtfns is used as a queue for notifications that are ready to
+	// be sent once there are no outstanding notifications currently being
+	// sent.  The waiting flag is used over simply checking for items in the
+	// pending list to ensure cleanup knows what has and hasn't been sent
+	// to the outHandler.  Currently no special cleanup is needed, however
+	// if something like a done channel is added to notifications in the
+	// future, not knowing what has and hasn't been sent to the outHandler
+	// (and thus who should respond to the done channel) would be
+	// problematic without using this approach.
+	pendingNtfns := list.New()
+	waiting := false
+out:
+	for {
+		select {
+		// This channel is notified when a message is being queued to
+		// be sent across the network socket.  It will either send the
+		// message immediately if a send is not already in progress, or
+		// queue the message to be sent once the other pending messages
+		// are sent.
+		case msg := <-c.ntfnChan:
+			if !waiting {
+				c.SendMessage(msg, ntfnSentChan)
+			} else {
+				pendingNtfns.PushBack(msg)
+			}
+			waiting = true
+
+		// This channel is notified when a notification has been sent
+		// across the network socket.
+		case <-ntfnSentChan:
+			// No longer waiting if there are no more messages in
+			// the pending messages queue.
+			next := pendingNtfns.Front()
+			if next == nil {
+				waiting = false
+				continue
+			}
+
+			// Notify the outHandler about the next item to
+			// asynchronously send.
+			msg := pendingNtfns.Remove(next).([]byte)
+			c.SendMessage(msg, ntfnSentChan)
+
+		case <-c.quit:
+			break out
+		}
+	}
+
+	// Drain any wait channels before exiting so nothing is left waiting
+	// around to send.
+cleanup:
+	for {
+		select {
+		case <-c.ntfnChan:
+		case <-ntfnSentChan:
+		default:
+			break cleanup
+		}
+	}
+	c.wg.Done()
+	log.Trace("Websocket client notification queue handler done for %s", c.addr)
+}
+
+// outHandler handles all outgoing messages for the websocket connection.  It
+// must be run as a goroutine.  It uses a buffered channel to serialize output
+// messages while allowing the sender to continue running asynchronously.  It
+// must be run as a goroutine.
+func (c *wsClient) outHandler() {
+out:
+	for {
+		// Send any messages ready for send until the quit channel is
+		// closed.
+		select {
+		case r := <-c.sendChan:
+			err := c.conn.WriteMessage(websocket.TextMessage, r.msg)
+			if err != nil {
+				c.Disconnect()
+				break out
+			}
+			if r.doneChan != nil {
+				r.doneChan <- true
+			}
+
+		case <-c.quit:
+			break out
+		}
+	}
+
+	// Drain any wait channels before exiting so nothing is left waiting
+	// around to send.
+cleanup:
+	for {
+		select {
+		case r := <-c.sendChan:
+			if r.doneChan != nil {
+				r.doneChan <- false
+			}
+		default:
+			break cleanup
+		}
+	}
+	c.wg.Done()
+	log.Trace("Websocket client output handler done for %s", c.addr)
+}
+
+// SendMessage sends the passed json to the websocket client.  It is backed
+// by a buffered channel, so it will not block until the send channel is full.
+// Note however that QueueNotification must be used for sending async
+// notifications instead of the this function.  This approach allows a limit to
+// the number of outstanding requests a client can make without preventing or
+// blocking on async notifications.
+func (c *wsClient) SendMessage(marshalledJSON []byte, doneChan chan bool) {
+	// Don't send the message if disconnected.
+	if c.Disconnected() {
+		if doneChan != nil {
+			doneChan <- false
+		}
+		return
+	}
+
+	c.sendChan <- wsResponse{msg: marshalledJSON, doneChan: doneChan}
+}
+
+// ErrClientQuit describes the error where a client send is not processed due
+// to the client having already been disconnected or dropped.
+var ErrClientQuit = errors.New("client quit")
+
+// QueueNotification queues the passed notification to be sent to the websocket
+// client.  This function, as the name implies, is only intended for
+// notifications since it has additional logic to prevent other subsystems, such
+// as the memory pool and block manager, from blocking even when the send
+// channel is full.
+//
+// If the client is in the process of shutting down, this function returns
+// ErrClientQuit.  This is intended to be checked by long-running notification
+// handlers to stop processing if there is no more work needed to be done.
+func (c *wsClient) QueueNotification(marshalledJSON []byte) error {
+	// Don't queue the message if disconnected.
+	if c.Disconnected() {
+		return ErrClientQuit
+	}
+
+	select {
+	case c.ntfnChan <- marshalledJSON:
+	case <-c.quit:
+		return ErrClientQuit
+	}
+	return nil
+}
+
+// Disconnected returns whether or not the websocket client is disconnected.
+func (c *wsClient) Disconnected() bool {
+	c.Lock()
+	isDisconnected := c.disconnected
+	c.Unlock()
+
+	return isDisconnected
+}
+
+// Disconnect disconnects the websocket client.
+func (c *wsClient) Disconnect() {
+	c.Lock()
+	defer c.Unlock()
+
+	// Nothing to do if already disconnected.
+	if c.disconnected {
+		return
+	}
+
+	log.Trace("Disconnecting websocket client %s", c.addr)
+	close(c.quit)
+	c.conn.Close()
+	c.disconnected = true
+}
+
+// Start begins processing input and output messages.
+func (c *wsClient) Start() {
+	log.Trace("Starting websocket client %s", c.addr)
+
+	// Start processing input and output.
+	c.wg.Add(3)
+	go c.inHandler()
+	go c.notificationQueueHandler()
+	go c.outHandler()
+}
+
+// WaitForShutdown blocks until the websocket client goroutines are stopped
+// and the connection is closed.
+func (c *wsClient) WaitForShutdown() {
+	c.wg.Wait()
+}
+
+// newWebsocketClient returns a new websocket client given the notification
+// manager, websocket connection, remote address, and whether or not the client
+// has already been authenticated (via HTTP Basic access authentication).  The
+// returned client is ready to start.  Once started, the client will process
+// incoming and outgoing messages in separate goroutines complete with queuing
+// and asynchrous handling for long-running operations.
+func newWebsocketClient(server *Server, conn *websocket.Conn, remoteAddr string, isAdmin bool) *wsClient {
+	return &wsClient{
+		conn:         conn,
+		addr:         remoteAddr,
+		isAdmin:      isAdmin,
+		server:       server,
+		addrRequests: make(map[string]struct{}),
+		ntfnChan:     make(chan []byte, 1), // nonblocking sync
+		sendChan:     make(chan wsResponse, websocketSendBufferSize),
+		quit:         make(chan struct{}),
+	}
+}
+
+// handleWebsocketHelp implements the help command for websocket connections.
+func handleWebsocketHelp(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.HelpCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	// Provide a usage overview of all commands when no specific command
+	// was specified.
+	var command string
+	if cmd.Command != nil {
+		command = *cmd.Command
+	}
+	if command == "" {
+		usage, err := wsc.server.helpCacher.rpcUsage(true)
+		if err != nil {
+			context := "Failed to generate RPC usage"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		return usage, nil
+	}
+
+	// Check that the command asked for is supported and implemented.
+	// Search the list of websocket handlers as well as the main list of
+	// handlers since help should only be provided for those cases.
+	valid := true
+	if _, ok := rpcHandlers[command]; !ok {
+		if _, ok := wsHandlers[command]; !ok {
+			valid = false
+		}
+	}
+	if !valid {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Unknown command: " + command,
+		}
+	}
+
+	// Get the help for the command.
+	help, err := wsc.server.helpCacher.rpcMethodHelp(command)
+	if err != nil {
+		context := "Failed to generate help"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	return help, nil
+}
+
+// handleLoadTxFilter implements the loadtxfilter command extension for
+// websocket connections.
+//
+// NOTE: This extension is ported from github.com/decred/dcrd
+func handleLoadTxFilter(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*btcjson.LoadTxFilterCmd)
+
+	outPoints := make([]outpoint.OutPoint, len(cmd.OutPoints))
+	for i := range cmd.OutPoints {
+		hash, err := util.GetHashFromStr(cmd.OutPoints[i].Hash)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: err.Error(),
+			}
+		}
+		outPoints[i] = outpoint.OutPoint{
+			Hash:  *hash,
+			Index: cmd.OutPoints[i].Index,
+		}
+	}
+
+	filter, rpcErr := newWSClientFilter(cmd.Addresses, outPoints)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	wsc.Lock()
+	defer wsc.Unlock()
+
+	if cmd.Reload || wsc.filterData == nil {
+		wsc.filterData = filter
+		return nil, nil
+	}
+
+	wsc.filterData.mu.Lock()
+	for key := range filter.addrs {
+		wsc.filterData.addrs[key] = struct{}{}
+	}
+	for op := range filter.unspent {
+		wsc.filterData.unspent[op] = struct{}{}
+	}
+	wsc.filterData.mu.Unlock()
+
+	return nil, nil
+}
+
+// handleNotifyBlocks implements the notifyblocks command extension for
+// websocket connections.
+func handleNotifyBlocks(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.RegisterBlockUpdates(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyBlocks implements the stopnotifyblocks command extension for
+// websocket connections.
+func handleStopNotifyBlocks(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterBlockUpdates(wsc)
+	return nil, nil
+}
+
+// handleNotifyNewTransations implements the notifynewtransactions command
+// extension for websocket connections.
+func handleNotifyNewTransactions(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.NotifyNewTransactionsCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	wsc.Lock()
+	wsc.verboseTxUpdates = cmd.Verbose != nil && *cmd.Verbose
+	wsc.Unlock()
+	wsc.server.ntfnMgr.RegisterNewMempoolTxsUpdates(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyNewTransations implements the stopnotifynewtransactions
+// command extension for websocket connections.
+func handleStopNotifyNewTransactions(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterNewMempoolTxsUpdates(wsc)
+	return nil, nil
+}
+
+// handleNotifyReceived implements the notifyreceived command extension for
+// websocket connections.
+func handleNotifyReceived(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.NotifyReceivedCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	// Decode addresses to validate input, but the strings slice is used
+	// directly if these are all ok.
+	keys, rpcErr := decodeAddressKeys(cmd.Addresses)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	wsc.server.ntfnMgr.RegisterTxOutAddressRequests(wsc, keys)
+	return nil, nil
+}
+
+// handleStopNotifyReceived implements the stopnotifyreceived command extension
+// for websocket connections.
+func handleStopNotifyReceived(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.StopNotifyReceivedCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	keys, rpcErr := decodeAddressKeys(cmd.Addresses)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	wsc.server.ntfnMgr.UnregisterTxOutAddressRequests(wsc, keys)
+	return nil, nil
+}
+
+// decodeAddressKeys returns the keys of the passed addresses, or an error if
+// any of them is invalid.
+func decodeAddressKeys(addresses []string) ([]string, *btcjson.RPCError) {
+	keys := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		key, rpcErr := decodeAddressKey(address)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// handleBlockchainNotification handles notifications from blockchain.  It does
+// things such as notify websocket clients of connected and disconnected blocks.
+func (s *Server) handleBlockchainNotification(notification *chain.Notification) {
+	switch notification.Type {
+	case chain.NTBlockConnected, chain.NTBlockDisconnected:
+		blk, ok := notification.Data.(*block.Block)
+		if !ok {
+			log.Warn("Chain notification is not a block.")
+			break
+		}
+
+		height := int32(-1)
+		if index := chain.GetInstance().FindBlockIndex(blk.GetHash()); index != nil {
+			height = index.Height
+		}
+
+		if notification.Type == chain.NTBlockConnected {
+			s.ntfnMgr.NotifyBlockConnected(blk, height)
+		} else {
+			s.ntfnMgr.NotifyBlockDisconnected(blk, height)
+		}
+	}
+}
+
+func init() {
+	wsHandlers = wsHandlersBeforeInit
+}
@@ -0,0 +1,29 @@
+package rpc
+
+import (
+	"github.com/copernet/copernicus/net/zmq"
+	"github.com/copernet/copernicus/rpc/btcjson"
+)
+
+var zmqHandlers = map[string]commandHandler{
+	"getzmqnotifications": handleGetZMQNotifications,
+}
+
+func handleGetZMQNotifications(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	notifiers := zmq.ActiveNotifiers()
+	result := make([]btcjson.GetZMQNotificationsResult, 0, len(notifiers))
+	for _, n := range notifiers {
+		result = append(result, btcjson.GetZMQNotificationsResult{
+			Type:    n.Type(),
+			Address: n.Address(),
+			HWM:     n.HWM(),
+		})
+	}
+	return result, nil
+}
+
+func registerZMQRPCCommands() {
+	for name, handler := range zmqHandlers {
+		appendCommand(name, handler)
+	}
+}
@@ -55,6 +55,7 @@ type BlockTemplate struct {
 	Block         *block.Block
 	TxFees        []amount.Amount
 	TxSigOpsCount []int
+	TxSigChecks   []int
 }
 
 func newBlockTemplate() *BlockTemplate {
@@ -62,6 +63,7 @@ func newBlockTemplate() *BlockTemplate {
 		Block:         block.NewBlock(),
 		TxFees:        make([]amount.Amount, 0),
 		TxSigOpsCount: make([]int, 0),
+		TxSigChecks:   make([]int, 0),
 	}
 }
 
@@ -73,11 +75,14 @@ type BlockAssembler struct {
 	blockSize             uint64
 	blockTx               uint64
 	blockSigOps           uint64
+	blockSigChecks        uint64
 	fees                  amount.Amount
 	inBlock               map[util.Hash]struct{}
 	height                int32
 	lockTimeCutoff        int64
 	chainParams           *model.BitcoinParams
+	// isPhononEnabled the block is limited by its sigchecks instead of its sigops
+	isPhononEnabled bool
 }
 
 func NewBlockAssembler(params *model.BitcoinParams) *BlockAssembler {
@@ -95,17 +100,22 @@ func (ba *BlockAssembler) resetBlockAssembler() {
 	// Reserve space for coinbase tx.
 	ba.blockSize = 1000
 	ba.blockSigOps = 100
+	ba.blockSigChecks = 0
 
 	// These counters do not include coinbase tx.
 	ba.blockTx = 0
 	ba.fees = 0
 }
 
-func (ba *BlockAssembler) testPackage(packageSize uint64, packageSigOps int64, add *tx.Tx) bool {
+func (ba *BlockAssembler) testPackage(packageSize uint64, packageSigOps, packageSigChecks int64, add *tx.Tx) bool {
 	blockSizeWithPackage := ba.blockSize + packageSize
 	if blockSizeWithPackage >= ba.maxGeneratedBlockSize {
 		return false
 	}
+	if ba.isPhononEnabled {
+		maxSigChecks := consensus.GetMaxBlockSigChecksCount(ba.maxGeneratedBlockSize)
+		return ba.blockSigChecks+uint64(packageSigChecks) < maxSigChecks
+	}
 	maxSigOps, errSig := consensus.GetMaxBlockSigOpsCount(blockSizeWithPackage)
 	if errSig != nil {
 		log.Error("testPackage err :%v", errSig)
@@ -121,9 +131,11 @@ func (ba *BlockAssembler) addToBlock(te *mempool.TxEntry) {
 	ba.bt.Block.Txs = append(ba.bt.Block.Txs, te.Tx)
 	ba.bt.TxFees = append(ba.bt.TxFees, amount.Amount(te.TxFee))
 	ba.bt.TxSigOpsCount = append(ba.bt.TxSigOpsCount, te.SigOpCount)
+	ba.bt.TxSigChecks = append(ba.bt.TxSigChecks, te.SigChecks)
 	ba.blockSize += uint64(te.TxSize)
 	ba.blockTx++
 	ba.blockSigOps += uint64(te.SigOpCount)
+	ba.blockSigChecks += uint64(te.SigChecks)
 	ba.fees += amount.Amount(te.TxFee)
 	ba.inBlock[te.Tx.GetHash()] = struct{}{}
 }
@@ -229,6 +241,7 @@ func (ba *BlockAssembler) addPackageTxs(sortRecord map[util.Hash]int) int {
 		packageSize := entry.SumTxSizeWitAncestors
 		packageFee := entry.SumTxFeeWithAncestors
 		packageSigOps := entry.SumTxSigOpCountWithAncestors
+		packageSigChecks := entry.SumTxSigChecksWithAncestors
 
 		// deal with several different mining strategies
 		isEnd := false
@@ -249,7 +262,7 @@ func (ba *BlockAssembler) addPackageTxs(sortRecord map[util.Hash]int) int {
 			break
 		}
 
-		if !ba.testPackage(uint64(packageSize), packageSigOps, nil) {
+		if !ba.testPackage(uint64(packageSize), packageSigOps, packageSigChecks, nil) {
 			consecutiveFailed++
 			if consecutiveFailed > maxConsecutiveFailures &&
 				ba.blockSize > ba.maxGeneratedBlockSize-1000 {
@@ -310,6 +323,8 @@ func (ba *BlockAssembler) CreateNewBlock(scriptPubKey, scriptSig *script.Script)
 	ba.bt.TxFees = append(ba.bt.TxFees, -1)
 	ba.bt.TxSigOpsCount = make([]int, 0, 100000)
 	ba.bt.TxSigOpsCount = append(ba.bt.TxSigOpsCount, -1)
+	ba.bt.TxSigChecks = make([]int, 0, 100000)
+	ba.bt.TxSigChecks = append(ba.bt.TxSigChecks, -1)
 
 	indexPrev := chain.GetInstance().Tip()
 
@@ -330,6 +345,8 @@ func (ba *BlockAssembler) CreateNewBlock(scriptPubKey, scriptSig *script.Script)
 	}
 	ba.bt.Block.Header.Time = uint32(util.GetAdjustedTimeSec())
 	ba.maxGeneratedBlockSize = computeMaxGeneratedBlockSize()
+	ba.isPhononEnabled = model.ActiveNetParams.IsRuleEnabled(consensus.RuleSigChecks, indexPrev.Height+1,
+		indexPrev.GetMedianTimePast())
 	lockTimeCutoff := indexPrev.GetMedianTimePast()
 	if tx.StandardLockTimeVerifyFlags&consensus.LocktimeMedianTimePast != 0 {
 		ba.lockTimeCutoff = lockTimeCutoff
@@ -339,7 +356,8 @@ func (ba *BlockAssembler) CreateNewBlock(scriptPubKey, scriptSig *script.Script)
 	sortRecord := make(map[util.Hash]int)
 	descendantsUpdated := ba.addPackageTxs(sortRecord)
 
-	if model.IsMagneticAnomalyEnabled(indexPrev.GetMedianTimePast()) {
+	if model.ActiveNetParams.IsRuleEnabled(consensus.RuleCanonicalTxOrder, indexPrev.Height+1,
+		indexPrev.GetMedianTimePast()) {
 		// If magnetic anomaly is enabled, we make sure transaction are
 		// canonically ordered.
 		sort.Sort(sortTxs(ba.bt.Block.Txs[1:]))
@@ -347,14 +365,18 @@ func (ba *BlockAssembler) CreateNewBlock(scriptPubKey, scriptSig *script.Script)
 		sortTxFees[0] = ba.bt.TxFees[0]
 		sortTxSigOpCosts := make([]int, len(ba.bt.TxSigOpsCount))
 		sortTxSigOpCosts[0] = ba.bt.TxSigOpsCount[0]
+		sortTxSigChecks := make([]int, len(ba.bt.TxSigChecks))
+		sortTxSigChecks[0] = ba.bt.TxSigChecks[0]
 		for i, tmpTx := range ba.bt.Block.Txs[1:] {
 			offset := sortRecord[tmpTx.GetHash()]
 			sortTxFees[i+1] = ba.bt.TxFees[offset]
 			sortTxSigOpCosts[i+1] = ba.bt.TxSigOpsCount[offset]
+			sortTxSigChecks[i+1] = ba.bt.TxSigChecks[offset]
 		}
 
 		ba.bt.TxFees = sortTxFees
 		ba.bt.TxSigOpsCount = sortTxSigOpCosts
+		ba.bt.TxSigChecks = sortTxSigChecks
 	}
 	time1 := util.GetTimeMicroSec()
 
@@ -382,8 +404,8 @@ func (ba *BlockAssembler) CreateNewBlock(scriptPubKey, scriptSig *script.Script)
 	ba.bt.TxFees[0] = -1 * ba.fees // coinbase's fee item is equal to tx fee sum for negative value
 
 	serializeSize := ba.bt.Block.SerializeSize()
-	log.Info("CreateNewBlock(): total size: %d txs: %d fees: %d sigops %d\n",
-		serializeSize, ba.blockTx, ba.fees, ba.blockSigOps)
+	log.Info("CreateNewBlock(): total size: %d txs: %d fees: %d sigops %d sigchecks %d\n",
+		serializeSize, ba.blockTx, ba.fees, ba.blockSigOps, ba.blockSigChecks)
 
 	// Fill in header.
 	if indexPrev == nil {
@@ -397,6 +419,8 @@ func (ba *BlockAssembler) CreateNewBlock(scriptPubKey, scriptSig *script.Script)
 	ba.bt.Block.Header.Nonce = 0
 
 	ba.bt.TxSigOpsCount[0] = ba.bt.Block.Txs[0].GetSigOpCountWithoutP2SH(uint32(script.StandardScriptVerifyFlags))
+	// The coinbase scripts are never executed.
+	ba.bt.TxSigChecks[0] = 0
 
 	//check the validity of the block
 	if err := TestBlockValidity(ba.bt.Block, indexPrev, false, false); err != nil {
@@ -464,6 +488,7 @@ func (ba *BlockAssembler) updatePackagesForAdded(txSet mapcontainer.MapContainer
 				item.SumTxSizeWitAncestors -= entry.SumTxSizeWitAncestors
 				item.SumTxFeeWithAncestors -= entry.SumTxFeeWithAncestors
 				item.SumTxSigOpCountWithAncestors -= entry.SumTxSigOpCountWithAncestors
+				item.SumTxSigChecksWithAncestors -= entry.SumTxSigChecksWithAncestors
 				// insert the modified one
 				txSet.ReplaceOrInsert(item)
 			case sortByFeeRate:
@@ -474,6 +499,7 @@ func (ba *BlockAssembler) updatePackagesForAdded(txSet mapcontainer.MapContainer
 				item.SumTxSizeWitAncestors -= entry.SumTxSizeWitAncestors
 				item.SumTxFeeWithAncestors -= entry.SumTxFeeWithAncestors
 				item.SumTxSigOpCountWithAncestors -= entry.SumTxSigOpCountWithAncestors
+				item.SumTxSigChecksWithAncestors -= entry.SumTxSigChecksWithAncestors
 				// insert the modified one
 				txSet.ReplaceOrInsert(item)
 			}